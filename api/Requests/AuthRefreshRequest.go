@@ -0,0 +1,16 @@
+package requests
+
+import "github.com/SamuelLeutner/fetch-student-data/config"
+
+// AuthRefreshRequest selects the Jacad instance whose token
+// POST /api/v1/admin/auth/refresh renews.
+type AuthRefreshRequest struct {
+	Tenant string `query:"tenant" doc:"Jacad profile to re-authenticate; empty uses the default instance."`
+}
+
+// Validate checks the request against its tags and the configured tenants.
+func (r *AuthRefreshRequest) Validate(cfg *config.Config) ValidationErrors {
+	errs := validateTags(r)
+	validateTenant(&errs, r.Tenant, cfg)
+	return errs
+}
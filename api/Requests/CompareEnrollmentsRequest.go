@@ -0,0 +1,35 @@
+package requests
+
+import "time"
+
+// CompareEnrollmentsRequest compares the enrollments of two periods.
+type CompareEnrollmentsRequest struct {
+	PeriodA         int    `query:"periodA" required:"true" min:"0" doc:"Earlier academic period (idPeriodoLetivo)."`
+	PeriodB         int    `query:"periodB" required:"true" min:"0" doc:"Later academic period, compared against periodA."`
+	StatusMatricula string `query:"statusMatricula" doc:"Enrollment status filter applied to both periods; several comma-separated statuses are fetched with one query each."`
+	OrgId           int    `query:"orgId" doc:"Compare only this organization's enrollments."`
+	Write           bool   `query:"write" doc:"Also write the comparison to 'Comparação <A> x <B>' tabs; without it the comparison is only returned."`
+	BypassCache     bool   `query:"bypassCache" doc:"Fetch fresh Jacad responses instead of cached ones."`
+	Tenant          string `query:"tenant" doc:"Jacad profile to fetch from; empty uses the default instance."`
+	TimeoutMinutes  int    `query:"timeoutMinutes" min:"0" doc:"Override the fetch timeout, up to MAX_FETCH_TIMEOUT; it covers both periods."`
+}
+
+// PeriodRequest returns the enrollment fetch of one of the compared periods.
+func (r *CompareEnrollmentsRequest) PeriodRequest(period int) *FetchEnrollmentsRequest {
+	return &FetchEnrollmentsRequest{
+		IdPeriodoLetivo: period,
+		StatusMatricula: r.StatusMatricula,
+		OrgId:           r.OrgId,
+		BypassCache:     r.BypassCache,
+		Tenant:          r.Tenant,
+	}
+}
+
+// Timeout returns the requested timeout capped at max, or def when none was
+// requested.
+func (r *CompareEnrollmentsRequest) Timeout(def, max time.Duration) time.Duration {
+	if r.TimeoutMinutes <= 0 {
+		return def
+	}
+	return min(time.Duration(r.TimeoutMinutes)*time.Minute, max)
+}
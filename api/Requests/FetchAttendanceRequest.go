@@ -0,0 +1,29 @@
+package requests
+
+import "time"
+
+type FetchAttendanceRequest struct {
+	IdTurma         int    `query:"idTurma" required:"true" doc:"Class whose attendance is fetched."`
+	IdPeriodoLetivo int    `query:"idPeriodoLetivo" doc:"Academic period of the class; empty lets Jacad pick the class's current period."`
+	DataInicio      string `query:"dataInicio" doc:"Keep class meetings on or after this date (YYYY-MM-DD)."`
+	DataFim         string `query:"dataFim" doc:"Keep class meetings on or before this date (YYYY-MM-DD)."`
+	DryRun          bool   `query:"dryRun" doc:"Fetch and map rows without writing them."`
+	PreviewRows     int    `query:"previewRows" min:"0" doc:"Rows returned in the dry-run preview."`
+	BypassCache     bool   `query:"bypassCache" doc:"Fetch fresh Jacad responses instead of cached ones."`
+	Tenant          string `query:"tenant" doc:"Jacad profile to fetch from; empty uses the default instance."`
+}
+
+// DateRange parses the meeting date bounds. Zero times mean the bound is not
+// set; to is the start of the day after dataFim.
+func (r *FetchAttendanceRequest) DateRange() (from, to time.Time, err error) {
+	if from, err = parseDateBound("dataInicio", r.DataInicio); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if to, err = parseDateBound("dataFim", r.DataFim); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,12 @@
+package requests
+
+type FetchCandidatesRequest struct {
+	// IdEdital fetches a single process notice; 0 fetches every notice with a
+	// status in EditalStatus.
+	IdEdital    int    `query:"idEdital" min:"0" doc:"Process notice whose inscriptions are fetched; empty fetches every open notice."`
+	OrgId       int    `query:"orgId" doc:"Keep only the notices of this organization."`
+	DryRun      bool   `query:"dryRun" doc:"Fetch and map rows without writing them."`
+	PreviewRows int    `query:"previewRows" min:"0" doc:"Rows returned in the dry-run preview."`
+	BypassCache bool   `query:"bypassCache" doc:"Fetch fresh Jacad responses instead of cached ones."`
+	Tenant      string `query:"tenant" doc:"Jacad profile to fetch from; empty uses the default instance."`
+}
@@ -0,0 +1,13 @@
+package requests
+
+type FetchClassesRequest struct {
+	IdPeriodoLetivo int `query:"idPeriodoLetivo" required:"true" doc:"Academic period whose classes are fetched."`
+	// StatusMatricula restricts which enrollments are counted per class.
+	StatusMatricula string `query:"statusMatricula" doc:"Count only enrollments with this status."`
+	// OrgId keeps only the classes of one organization; 0 keeps all.
+	OrgId       int    `query:"orgId" doc:"Keep only the classes of this organization."`
+	DryRun      bool   `query:"dryRun" doc:"Fetch and map rows without writing them."`
+	PreviewRows int    `query:"previewRows" min:"0" doc:"Rows returned in the dry-run preview."`
+	BypassCache bool   `query:"bypassCache" doc:"Fetch fresh Jacad responses instead of cached ones."`
+	Tenant      string `query:"tenant" doc:"Jacad profile to fetch from; empty uses the default instance."`
+}
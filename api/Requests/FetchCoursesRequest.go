@@ -0,0 +1,10 @@
+package requests
+
+type FetchCoursesRequest struct {
+	// OrgId keeps only the courses of one organization; 0 keeps all.
+	OrgId       int    `query:"orgId" doc:"Keep only the courses of this organization."`
+	DryRun      bool   `query:"dryRun" doc:"Fetch and map rows without writing them."`
+	PreviewRows int    `query:"previewRows" min:"0" doc:"Rows returned in the dry-run preview."`
+	BypassCache bool   `query:"bypassCache" doc:"Fetch fresh Jacad responses instead of cached ones."`
+	Tenant      string `query:"tenant" doc:"Jacad profile to fetch from; empty uses the default instance."`
+}
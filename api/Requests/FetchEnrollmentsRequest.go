@@ -1,7 +1,310 @@
 package requests
 
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+const (
+	// SyncModeIncremental still fetches every page, since Jacad has no
+	// updated-since filter, but only writes the enrollments whose rows
+	// differ from the sheet's, matched on idMatricula. Writers that cannot
+	// read sheets back fall back to the enrollments registered since the
+	// last sync, which misses status changes to older ones and removals.
+	SyncModeFull        = "full"
+	SyncModeIncremental = "incremental"
+
+	// WriteModeAtomic fetches every page before writing; WriteModeStream
+	// appends each batch to the sheet as soon as it is fetched.
+	WriteModeAtomic = "atomic"
+	WriteModeStream = "stream"
+
+	GroupByNone         = "none"
+	GroupByOrganization = "organization"
+	GroupByCourse       = "course"
+	GroupByStatus       = "status"
+)
+
+var GroupByOptions = []string{GroupByNone, GroupByOrganization, GroupByCourse, GroupByStatus}
+
+func IsValidGroupBy(groupBy string) bool {
+	for _, option := range GroupByOptions {
+		if groupBy == option {
+			return true
+		}
+	}
+	return false
+}
+
+// The doc tags describe each parameter in the OpenAPI spec; the required,
+// enum and min tags are enforced by Validate and documented there too.
 type FetchEnrollmentsRequest struct {
-	OrgId           int    `query:"orgId"`
-	IdPeriodoLetivo int    `query:"idPeriodoLetivo"`
-	StatusMatricula string `query:"statusMatricula"`
+	OrgId           int    `query:"orgId" json:"orgId,omitempty" doc:"Write only this organization's sheet."`
+	OrgIds          string `query:"orgIds" json:"orgIds,omitempty" doc:"Comma-separated organization IDs, or 'all' for every configured organization."`
+	IdPeriodoLetivo int    `query:"idPeriodoLetivo" json:"idPeriodoLetivo,omitempty" min:"0" doc:"Academic period to fetch."`
+	StatusMatricula string `query:"statusMatricula" json:"statusMatricula,omitempty" doc:"Enrollment status filter passed to Jacad; several comma-separated statuses, e.g. ATIVA,TRANCADA, are fetched with one query each."`
+	Mode            string `query:"mode" json:"mode,omitempty" enum:"full,incremental" doc:"Rewrite every sheet or only upsert enrollments whose rows differ from the sheet's."`
+	DryRun          bool   `query:"dryRun" json:"dryRun,omitempty" doc:"Fetch and map rows without writing them."`
+	PreviewRows     int    `query:"previewRows" json:"previewRows,omitempty" min:"0" doc:"Rows per sheet returned in the dry-run preview."`
+	Resume          bool   `query:"resume" json:"resume,omitempty" doc:"Continue from the last checkpoint of an interrupted fetch."`
+	WriteMode       string `query:"writeMode" json:"writeMode,omitempty" enum:"atomic,stream" doc:"Write after fetching every page, or append each batch as it arrives."`
+	GroupBy         string `query:"groupBy" json:"groupBy,omitempty" enum:"none,organization,course,status" doc:"Split rows into one sheet per group."`
+	SplitByStatus   bool   `query:"splitByStatus" json:"splitByStatus,omitempty" doc:"Write each requested status to its own tab, named as if it were fetched alone, instead of one combined sheet."`
+	BypassCache     bool   `query:"bypassCache" json:"bypassCache,omitempty" doc:"Fetch fresh Jacad responses instead of cached ones."`
+	Tenant          string `query:"tenant" json:"tenant,omitempty" doc:"Jacad profile to fetch from; empty uses the default instance."`
+	// Preset names a configured parameter set that fills the parameters the
+	// request leaves unset; see ApplyPreset.
+	Preset string `query:"preset" json:"preset,omitempty" doc:"Configured preset, from PRESETS_FILE, supplying the organizations, status, period, sheet name and modes this request leaves unset."`
+	// Anonymize applies the configured redaction policy to PII columns.
+	Anonymize bool `query:"anonymize" json:"anonymize,omitempty" doc:"Apply the configured redaction policy to PII columns."`
+	// Fields is a comma-separated list of enrollment fields to write, in order.
+	// Empty writes the configured column mapping.
+	Fields string `query:"fields" json:"fields,omitempty" doc:"Comma-separated enrollment fields to write, in order, e.g. aluno,ra,curso,status."`
+	// DeltaReport diffs each sheet against its previous contents before
+	// overwriting it and writes the changes to a "Changes <date>" tab.
+	DeltaReport bool `query:"deltaReport" json:"deltaReport,omitempty" doc:"Write added, removed and status-changed enrollments to a 'Changes <date>' tab."`
+	SummaryTab  bool `query:"summaryTab" json:"summaryTab,omitempty" doc:"Write enrollment counts per course, status, unidade física and month to a 'Resumo' tab."`
+	// Snapshot writes each sheet to a tab dated with the run day instead of
+	// overwriting it, and prunes snapshots past SNAPSHOT_RETENTION_DAYS.
+	Snapshot bool `query:"snapshot" json:"snapshot,omitempty" doc:"Write each sheet to a new tab suffixed with today's date, e.g. 'Matrículas EAD 2024-08-01', keeping earlier snapshots up to SNAPSHOT_RETENTION_DAYS."`
+	// NewSpreadsheet writes the job to a spreadsheet created for it in the
+	// configured Drive folder instead of the shared spreadsheets.
+	NewSpreadsheet bool `query:"newSpreadsheet" json:"newSpreadsheet,omitempty" doc:"Create a new spreadsheet in the configured Drive folder and write this job to it."`
+	// SpreadsheetId and SheetName override where the job writes. The
+	// spreadsheet must be SPREADSHEET_ID or in SPREADSHEET_ALLOWLIST.
+	SpreadsheetId string `query:"spreadsheetId" json:"spreadsheetId,omitempty" doc:"Write every sheet of this job to this spreadsheet, which must be SPREADSHEET_ID or listed in SPREADSHEET_ALLOWLIST."`
+	SheetName     string `query:"sheetName" json:"sheetName,omitempty" doc:"Sheet name, or sheet name template with the SHEET_NAME_TEMPLATE placeholders, replacing the configured ones. A job writing several sheets needs a placeholder such as {org} or {group}."`
+	// SortBy orders the rows of each sheet, which would otherwise vary with
+	// the order concurrent page batches complete in.
+	SortBy string `query:"sortBy" json:"sortBy,omitempty" doc:"Comma-separated enrollment fields rows are sorted by before writing, e.g. organizacao,curso,aluno; prefix a field with - to sort it descending. Defaults to idMatricula. Not applied with writeMode 'stream' nor to the streamed CSV export."`
+	// PageSize overrides the server's Jacad page size, within its bounds.
+	PageSize int `query:"pageSize" json:"pageSize,omitempty" min:"0" doc:"Jacad page size for this fetch; smaller pages avoid timeouts on heavy periods. 0 uses the server default."`
+	// StartPage, EndPage and MaxPages fetch only a slice of each Jacad query's
+	// pages, counted from 0; EndPage is exclusive and 0 leaves it open.
+	StartPage int `query:"startPage" json:"startPage,omitempty" min:"0" doc:"First Jacad page to fetch, counted from 0."`
+	EndPage   int `query:"endPage" json:"endPage,omitempty" min:"0" doc:"Stop before this Jacad page; 0 fetches up to the last page."`
+	MaxPages  int `query:"maxPages" json:"maxPages,omitempty" min:"0" doc:"Fetch at most this many pages from startPage; 0 sets no limit."`
+	// TimeoutMinutes overrides the server's default fetch timeout, up to its maximum.
+	TimeoutMinutes int `query:"timeoutMinutes" json:"timeoutMinutes,omitempty" min:"0" doc:"Override the default fetch timeout, capped at the server maximum."`
+	// The list filters below are set in the POST body. Each combination of
+	// period and status becomes its own Jacad query and the results are merged;
+	// the dataMatricula range is applied to the merged enrollments.
+	IdsPeriodoLetivo  []int    `json:"idsPeriodoLetivo,omitempty" doc:"Academic periods to fetch, merged with idPeriodoLetivo."`
+	StatusesMatricula []string `json:"statusesMatricula,omitempty" doc:"Enrollment statuses to fetch, merged with statusMatricula."`
+	// DataMatriculaFrom and DataMatriculaTo are inclusive YYYY-MM-DD dates.
+	DataMatriculaFrom string `query:"dataMatriculaFrom" json:"dataMatriculaFrom,omitempty" doc:"Keep enrollments made on or after this date (YYYY-MM-DD)."`
+	DataMatriculaTo   string `query:"dataMatriculaTo" json:"dataMatriculaTo,omitempty" doc:"Keep enrollments made on or before this date (YYYY-MM-DD)."`
+}
+
+// Timeout returns the effective timeout for the request: TimeoutMinutes when
+// set, capped at max, otherwise def. Negative values are rejected by Validate.
+func (r *FetchEnrollmentsRequest) Timeout(def, max time.Duration) time.Duration {
+	if r.TimeoutMinutes <= 0 {
+		return def
+	}
+	return min(time.Duration(r.TimeoutMinutes)*time.Minute, max)
+}
+
+// ApplyPreset fills the parameters the request leaves unset from the preset it
+// names, if any. Organizations, periods and statuses set on the request, in
+// any of their forms, replace the preset's.
+func (r *FetchEnrollmentsRequest) ApplyPreset(cfg *config.Config) error {
+	if r.Preset == "" {
+		return nil
+	}
+	preset, ok := cfg.Presets[r.Preset]
+	if !ok {
+		if names := cfg.PresetNames(); len(names) > 0 {
+			return fmt.Errorf("must be one of %s, got '%s'", strings.Join(names, ", "), r.Preset)
+		}
+		return fmt.Errorf("unknown preset '%s': no PRESETS_FILE is configured", r.Preset)
+	}
+	if r.OrgId == 0 && r.OrgIds == "" {
+		r.OrgIds = preset.OrgIds
+	}
+	if r.IdPeriodoLetivo == 0 && len(r.IdsPeriodoLetivo) == 0 {
+		r.IdPeriodoLetivo = preset.IdPeriodoLetivo
+	}
+	if r.StatusMatricula == "" && len(r.StatusesMatricula) == 0 {
+		r.StatusMatricula = preset.StatusMatricula
+	}
+	for _, field := range []struct {
+		value  *string
+		preset string
+	}{
+		{&r.SheetName, preset.SheetName},
+		{&r.GroupBy, preset.GroupBy},
+		{&r.Mode, preset.Mode},
+		{&r.WriteMode, preset.WriteMode},
+	} {
+		if *field.value == "" {
+			*field.value = field.preset
+		}
+	}
+	return nil
+}
+
+// ParseFields splits the fields parameter, dropping blanks and duplicates.
+func (r *FetchEnrollmentsRequest) ParseFields() []string {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(r.Fields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		seen[field] = true
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// ParseOrgIDs parses the comma-separated orgIds parameter. It reports all=true
+// when the caller asked for every configured organization.
+func (r *FetchEnrollmentsRequest) ParseOrgIDs() (ids []int, all bool, err error) {
+	value := strings.TrimSpace(r.OrgIds)
+	if value == "" {
+		return nil, false, nil
+	}
+	if strings.EqualFold(value, "all") {
+		return nil, true, nil
+	}
+
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid organization id '%s' in orgIds", part)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, false, nil
+}
+
+// PeriodIDs merges idPeriodoLetivo and idsPeriodoLetivo, dropping duplicates.
+// It returns nil when no period is set.
+func (r *FetchEnrollmentsRequest) PeriodIDs() []int {
+	var ids []int
+	for _, id := range append([]int{r.IdPeriodoLetivo}, r.IdsPeriodoLetivo...) {
+		if id != 0 && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Statuses merges statusMatricula and statusesMatricula, splitting
+// comma-separated values and dropping blanks and duplicates. It returns nil
+// when no status is set.
+func (r *FetchEnrollmentsRequest) Statuses() []string {
+	var statuses []string
+	for _, value := range append([]string{r.StatusMatricula}, r.StatusesMatricula...) {
+		for _, status := range strings.Split(value, ",") {
+			status = strings.TrimSpace(status)
+			if status != "" && !slices.Contains(statuses, status) {
+				statuses = append(statuses, status)
+			}
+		}
+	}
+	return statuses
+}
+
+// PeriodLabel and StatusLabel name the requested periods and statuses in
+// sheet names, e.g. "2024,2025".
+func (r *FetchEnrollmentsRequest) PeriodLabel() string {
+	ids := r.PeriodIDs()
+	if len(ids) == 0 {
+		return "0"
+	}
+	labels := make([]string, len(ids))
+	for i, id := range ids {
+		labels[i] = strconv.Itoa(id)
+	}
+	return strings.Join(labels, ",")
+}
+
+func (r *FetchEnrollmentsRequest) StatusLabel() string {
+	return strings.Join(r.Statuses(), ",")
+}
+
+// QueryCount is how many Jacad queries the request expands to.
+func (r *FetchEnrollmentsRequest) QueryCount() int {
+	return max(len(r.PeriodIDs()), 1) * max(len(r.Statuses()), 1)
+}
+
+// PageRange returns the slice of pages to fetch of each Jacad query, from
+// start up to end exclusive; end 0 means up to the last page.
+func (r *FetchEnrollmentsRequest) PageRange() (start, end int) {
+	start, end = r.StartPage, r.EndPage
+	if r.MaxPages > 0 && (end == 0 || start+r.MaxPages < end) {
+		end = start + r.MaxPages
+	}
+	return start, end
+}
+
+// PartialPages reports whether the request fetches only a slice of the pages.
+func (r *FetchEnrollmentsRequest) PartialPages() bool {
+	start, end := r.PageRange()
+	return start > 0 || end > 0
+}
+
+// NewSpreadsheetConflict names the option set alongside newSpreadsheet that a
+// fresh spreadsheet cannot honour, since it has no earlier data to build on,
+// or returns "" when there is none.
+func (r *FetchEnrollmentsRequest) NewSpreadsheetConflict() string {
+	switch {
+	case r.Mode == SyncModeIncremental:
+		return fmt.Sprintf("mode '%s'", SyncModeIncremental)
+	case r.Resume:
+		return "resume"
+	case r.DeltaReport:
+		return "deltaReport"
+	}
+	return ""
+}
+
+// WritesSeveralSheets reports whether the request can write more than one
+// sheet: several organizations, groups or statuses.
+func (r *FetchEnrollmentsRequest) WritesSeveralSheets() bool {
+	ids, all, _ := r.ParseOrgIDs()
+	return all || len(ids) > 1 || (r.GroupBy != "" && r.GroupBy != GroupByNone) || r.SplitByStatus
+}
+
+// DataMatriculaRange parses the dataMatricula bounds. Zero times mean the
+// bound is not set; to is the start of the day after dataMatriculaTo.
+func (r *FetchEnrollmentsRequest) DataMatriculaRange() (from, to time.Time, err error) {
+	if from, err = parseDateBound("dataMatriculaFrom", r.DataMatriculaFrom); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if to, err = parseDateBound("dataMatriculaTo", r.DataMatriculaTo); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+	return from, to, nil
+}
+
+// parseDateBound parses a YYYY-MM-DD query value; empty means unset.
+func parseDateBound(field, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s '%s': expected YYYY-MM-DD", field, value)
+	}
+	return t, nil
 }
@@ -0,0 +1,38 @@
+package requests
+
+import (
+	"fmt"
+	"time"
+)
+
+// ListJobsRequest filters the job history.
+type ListJobsRequest struct {
+	Since string `query:"since" doc:"Only jobs started at or after this time, as RFC 3339 or YYYY-MM-DD."`
+	Job   string `query:"job" enum:"fetch-enrollments,fetch-courses,fetch-classes,fetch-candidates,fetch-attendance" doc:"Only jobs of this kind."`
+	Sheet string `query:"sheet" doc:"Only jobs that wrote this sheet."`
+	Limit int    `query:"limit" min:"0" doc:"Maximum jobs returned, newest first; 0 uses the default of 100."`
+}
+
+// SinceTime parses Since; dates without a time start at local midnight.
+func (r *ListJobsRequest) SinceTime() (time.Time, error) {
+	if r.Since == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, r.Since); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", r.Since, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be RFC 3339 or YYYY-MM-DD, got '%s'", r.Since)
+	}
+	return t, nil
+}
+
+// Validate checks the request against its tags and parses since.
+func (r *ListJobsRequest) Validate() ValidationErrors {
+	errs := validateTags(r)
+	if _, err := r.SinceTime(); err != nil {
+		errs.add("since", "%s", err)
+	}
+	return errs
+}
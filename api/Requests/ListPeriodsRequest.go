@@ -0,0 +1,21 @@
+package requests
+
+import "github.com/SamuelLeutner/fetch-student-data/config"
+
+// ListPeriodsRequest selects the periods listed by GET /api/v1/periods.
+type ListPeriodsRequest struct {
+	IdOrg       int    `query:"idOrg" doc:"Only periods of this organization."`
+	BypassCache bool   `query:"bypassCache" doc:"Fetch fresh Jacad responses instead of cached ones."`
+	Tenant      string `query:"tenant" doc:"Jacad profile to list from; empty uses the default instance."`
+}
+
+// Validate checks the request against its tags and the configured
+// organizations.
+func (r *ListPeriodsRequest) Validate(cfg *config.Config) ValidationErrors {
+	errs := validateTags(r)
+	validateTenant(&errs, r.Tenant, cfg)
+	if r.IdOrg != 0 && !knownOrg(cfg, r.IdOrg) {
+		errs.add("idOrg", "unknown organization id %d", r.IdOrg)
+	}
+	return errs
+}
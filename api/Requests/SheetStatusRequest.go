@@ -0,0 +1,23 @@
+package requests
+
+import "time"
+
+// SheetStatusRequest tunes the stale-sheet report.
+type SheetStatusRequest struct {
+	StaleAfterMinutes int  `query:"staleAfterMinutes" min:"0" doc:"Flag sheets not refreshed for this many minutes; 0 uses SHEET_STALE_AFTER."`
+	StaleOnly         bool `query:"staleOnly" doc:"List only the stale sheets."`
+}
+
+// StaleAfter returns StaleAfterMinutes as a duration, or def when it is not
+// set.
+func (r *SheetStatusRequest) StaleAfter(def time.Duration) time.Duration {
+	if r.StaleAfterMinutes <= 0 {
+		return def
+	}
+	return time.Duration(r.StaleAfterMinutes) * time.Minute
+}
+
+// Validate checks the request against its tags.
+func (r *SheetStatusRequest) Validate() ValidationErrors {
+	return validateTags(r)
+}
@@ -0,0 +1,269 @@
+package requests
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+// maxSheetNameLength is the longest tab name Google Sheets accepts.
+const maxSheetNameLength = 100
+
+// FieldError describes one invalid query parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors lists every invalid parameter of a request.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fmt.Sprintf("%s: %s", fieldErr.Field, fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationErrors) add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Add records a problem found outside the request's own Validate, such as an
+// unknown enrollment field.
+func (e *ValidationErrors) Add(field string, err error) {
+	e.add(field, "%s", err)
+}
+
+// validateTags checks the required, enum and min tags of a request struct's
+// query fields.
+func validateTags(v interface{}) ValidationErrors {
+	var errs ValidationErrors
+	value := reflect.Indirect(reflect.ValueOf(v))
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("query")
+		if name == "" || name == "-" {
+			continue
+		}
+		fieldValue := value.Field(i)
+
+		if field.Tag.Get("required") == "true" && fieldValue.IsZero() {
+			errs.add(name, "is required")
+			continue
+		}
+		if enum := field.Tag.Get("enum"); enum != "" && !fieldValue.IsZero() {
+			options := strings.Split(enum, ",")
+			if !slices.Contains(options, fmt.Sprint(fieldValue.Interface())) {
+				errs.add(name, "must be one of %s, got '%v'", strings.Join(options, ", "), fieldValue.Interface())
+			}
+		}
+		if minTag := field.Tag.Get("min"); minTag != "" && fieldValue.CanInt() {
+			if min, err := strconv.ParseInt(minTag, 10, 64); err == nil && fieldValue.Int() < min {
+				errs.add(name, "must be at least %d, got %d", min, fieldValue.Int())
+			}
+		}
+	}
+	return errs
+}
+
+// validateStatus checks the status in field against the configured enrollment
+// statuses.
+func validateStatus(errs *ValidationErrors, field, status string, cfg *config.Config) {
+	if status == "" || len(cfg.EnrollmentStatuses) == 0 {
+		return
+	}
+	if !slices.Contains(cfg.EnrollmentStatuses, status) {
+		errs.add(field, "must be one of %s, got '%s'", strings.Join(cfg.EnrollmentStatuses, ", "), status)
+	}
+}
+
+// validateTenant checks tenant against the configured Jacad profiles.
+func validateTenant(errs *ValidationErrors, tenant string, cfg *config.Config) {
+	if _, err := cfg.JacadProfile(tenant); err == nil {
+		return
+	}
+	if names := cfg.TenantNames(); len(names) > 0 {
+		errs.add("tenant", "must be one of %s, got '%s'", strings.Join(names, ", "), tenant)
+	} else {
+		errs.add("tenant", "unknown tenant '%s': no JACAD_PROFILES are configured", tenant)
+	}
+}
+
+func knownOrg(cfg *config.Config, id int) bool {
+	_, ok := cfg.Organizations.ByID(id)
+	return ok
+}
+
+// Validate checks the request against its tags, the configured statuses and
+// organizations, and the combinations of modes the fetch supports.
+func (r *FetchEnrollmentsRequest) Validate(cfg *config.Config) ValidationErrors {
+	errs := validateTags(r)
+	validateTenant(&errs, r.Tenant, cfg)
+	for _, status := range strings.Split(r.StatusMatricula, ",") {
+		validateStatus(&errs, "statusMatricula", strings.TrimSpace(status), cfg)
+	}
+	for _, status := range r.StatusesMatricula {
+		validateStatus(&errs, "statusesMatricula", status, cfg)
+	}
+	for _, id := range r.IdsPeriodoLetivo {
+		if id <= 0 {
+			errs.add("idsPeriodoLetivo", "must be positive, got %d", id)
+		}
+	}
+	validateDateRange(&errs, "dataMatriculaFrom", r.DataMatriculaFrom, "dataMatriculaTo", r.DataMatriculaTo)
+	if r.PageSize != 0 && (r.PageSize < cfg.MinPageSize || r.PageSize > cfg.MaxPageSize) {
+		errs.add("pageSize", "must be between %d and %d, got %d", cfg.MinPageSize, cfg.MaxPageSize, r.PageSize)
+	}
+	if r.Resume && r.QueryCount() > 1 {
+		errs.add("resume", "is only supported for a single period and status")
+	}
+	if r.EndPage != 0 && r.EndPage <= r.StartPage {
+		errs.add("endPage", "must be greater than startPage, got %d", r.EndPage)
+	}
+	if r.PartialPages() && (r.Resume || r.Mode == SyncModeIncremental || r.DeltaReport) {
+		errs.add("startPage", "a page range cannot be combined with resume, mode '%s' or deltaReport", SyncModeIncremental)
+	}
+
+	if r.OrgId != 0 && !knownOrg(cfg, r.OrgId) {
+		errs.add("orgId", "unknown organization id %d", r.OrgId)
+	}
+	if ids, _, err := r.ParseOrgIDs(); err != nil {
+		errs.add("orgIds", "%s", err)
+	} else {
+		for _, id := range ids {
+			if !knownOrg(cfg, id) {
+				errs.add("orgIds", "unknown organization id %d", id)
+			}
+		}
+	}
+
+	if r.WriteMode == WriteModeStream && r.Mode == SyncModeIncremental {
+		errs.add("writeMode", "'%s' is only supported with mode '%s'", WriteModeStream, SyncModeFull)
+	}
+	if r.SortBy != "" && r.WriteMode == WriteModeStream {
+		errs.add("sortBy", "is not supported with writeMode '%s'", WriteModeStream)
+	}
+	if r.SplitByStatus && r.GroupBy != "" && r.GroupBy != GroupByNone {
+		errs.add("splitByStatus", "cannot be combined with groupBy '%s'", r.GroupBy)
+	}
+	if r.DeltaReport && (r.Mode == SyncModeIncremental || r.WriteMode == WriteModeStream) {
+		errs.add("deltaReport", "is only supported with mode '%s' and writeMode '%s'", SyncModeFull, WriteModeAtomic)
+	}
+	if r.Snapshot && (r.Mode == SyncModeIncremental || r.DeltaReport) {
+		errs.add("snapshot", "cannot be combined with mode '%s' or deltaReport", SyncModeIncremental)
+	}
+	if cfg.UsesWriter("parquet") && (r.Mode == SyncModeIncremental || r.WriteMode == WriteModeStream) {
+		errs.add("mode", "the parquet writer only supports mode '%s' with writeMode '%s'", SyncModeFull, WriteModeAtomic)
+	}
+	if r.NewSpreadsheet {
+		switch {
+		case cfg.DriveFolderID == "" || cfg.Writer != "sheets":
+			errs.add("newSpreadsheet", "requires the sheets writer and DRIVE_FOLDER_ID")
+		case r.NewSpreadsheetConflict() != "":
+			errs.add("newSpreadsheet", "is not supported with %s", r.NewSpreadsheetConflict())
+		}
+	}
+	if r.SpreadsheetId != "" {
+		switch {
+		case cfg.Writer != "sheets":
+			errs.add("spreadsheetId", "requires the sheets writer")
+		case r.NewSpreadsheet:
+			errs.add("spreadsheetId", "cannot be combined with newSpreadsheet")
+		case !cfg.SpreadsheetAllowed(r.SpreadsheetId):
+			errs.add("spreadsheetId", "'%s' is not SPREADSHEET_ID nor listed in SPREADSHEET_ALLOWLIST", r.SpreadsheetId)
+		}
+	}
+	if r.SheetName != "" {
+		if err := config.CheckSheetNameTemplate(r.SheetName); err != nil {
+			errs.add("sheetName", "%s", err)
+		} else if utf8.RuneCountInString(r.SheetName) > maxSheetNameLength {
+			errs.add("sheetName", "must be at most %d characters", maxSheetNameLength)
+		} else if r.WritesSeveralSheets() && !strings.Contains(r.SheetName, "{") {
+			errs.add("sheetName", "needs a placeholder such as {org} or {group} when the job writes several sheets")
+		}
+	}
+	return errs
+}
+
+// Validate checks the request against its tags and the configured
+// organizations.
+func (r *FetchCoursesRequest) Validate(cfg *config.Config) ValidationErrors {
+	errs := validateTags(r)
+	validateTenant(&errs, r.Tenant, cfg)
+	if r.OrgId != 0 && !knownOrg(cfg, r.OrgId) {
+		errs.add("orgId", "unknown organization id %d", r.OrgId)
+	}
+	return errs
+}
+
+// Validate checks the request against its tags, the configured statuses and
+// organizations.
+func (r *FetchClassesRequest) Validate(cfg *config.Config) ValidationErrors {
+	errs := validateTags(r)
+	validateTenant(&errs, r.Tenant, cfg)
+	validateStatus(&errs, "statusMatricula", r.StatusMatricula, cfg)
+	if r.OrgId != 0 && !knownOrg(cfg, r.OrgId) {
+		errs.add("orgId", "unknown organization id %d", r.OrgId)
+	}
+	return errs
+}
+
+// Validate checks the request against its tags and the configured
+// organizations.
+func (r *FetchCandidatesRequest) Validate(cfg *config.Config) ValidationErrors {
+	errs := validateTags(r)
+	validateTenant(&errs, r.Tenant, cfg)
+	if r.OrgId != 0 && !knownOrg(cfg, r.OrgId) {
+		errs.add("orgId", "unknown organization id %d", r.OrgId)
+	}
+	return errs
+}
+
+// Validate checks the request against its tags and its date range.
+func (r *FetchAttendanceRequest) Validate(cfg *config.Config) ValidationErrors {
+	errs := validateTags(r)
+	validateTenant(&errs, r.Tenant, cfg)
+	validateDateRange(&errs, "dataInicio", r.DataInicio, "dataFim", r.DataFim)
+	return errs
+}
+
+// Validate checks the request against its tags, the configured statuses and
+// organizations, and that it compares two different periods.
+func (r *CompareEnrollmentsRequest) Validate(cfg *config.Config) ValidationErrors {
+	errs := validateTags(r)
+	validateTenant(&errs, r.Tenant, cfg)
+	for _, status := range r.PeriodRequest(r.PeriodA).Statuses() {
+		validateStatus(&errs, "statusMatricula", status, cfg)
+	}
+	if r.PeriodA != 0 && r.PeriodA == r.PeriodB {
+		errs.add("periodB", "must differ from periodA")
+	}
+	if r.OrgId != 0 && !knownOrg(cfg, r.OrgId) {
+		errs.add("orgId", "unknown organization id %d", r.OrgId)
+	}
+	return errs
+}
+
+// validateDateRange checks a pair of YYYY-MM-DD bounds, either of which may be
+// empty.
+func validateDateRange(errs *ValidationErrors, fromField, fromValue, toField, toValue string) {
+	from, fromErr := parseDateBound(fromField, fromValue)
+	if fromErr != nil {
+		errs.add(fromField, "must be a YYYY-MM-DD date, got '%s'", fromValue)
+	}
+	to, toErr := parseDateBound(toField, toValue)
+	if toErr != nil {
+		errs.add(toField, "must be a YYYY-MM-DD date, got '%s'", toValue)
+	}
+	if fromErr == nil && toErr == nil && !from.IsZero() && !to.IsZero() && from.After(to) {
+		errs.add(fromField, "must not be after %s", toField)
+	}
+}
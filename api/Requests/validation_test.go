@@ -0,0 +1,197 @@
+package requests
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+func fields(errs ValidationErrors) []string {
+	names := make([]string, len(errs))
+	for i, err := range errs {
+		names[i] = err.Field
+	}
+	return names
+}
+
+func TestFetchEnrollmentsRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request FetchEnrollmentsRequest
+		writer  string
+		drive   string
+		allow   []string
+		want    []string
+	}{
+		{name: "empty request", want: []string{}},
+		{name: "valid request", request: FetchEnrollmentsRequest{IdPeriodoLetivo: 42, StatusMatricula: "ATIVA", OrgIds: "20,17", PageSize: 100}, want: []string{}},
+		{name: "unknown enum value", request: FetchEnrollmentsRequest{Mode: "partial", WriteMode: "batch"}, want: []string{"mode", "writeMode"}},
+		{name: "negative numbers", request: FetchEnrollmentsRequest{IdPeriodoLetivo: -1, PreviewRows: -5}, want: []string{"idPeriodoLetivo", "previewRows"}},
+		{name: "unknown status", request: FetchEnrollmentsRequest{StatusMatricula: "PENDENTE", StatusesMatricula: []string{"ATIVA", "X"}}, want: []string{"statusMatricula", "statusesMatricula"}},
+		{name: "comma-separated statuses", request: FetchEnrollmentsRequest{StatusMatricula: "ATIVA, TRANCADA", SplitByStatus: true}, want: []string{}},
+		{name: "unknown status in list", request: FetchEnrollmentsRequest{StatusMatricula: "ATIVA,PENDENTE"}, want: []string{"statusMatricula"}},
+		{name: "split by status with grouping", request: FetchEnrollmentsRequest{StatusMatricula: "ATIVA,TRANCADA", SplitByStatus: true, GroupBy: GroupByCourse}, want: []string{"splitByStatus"}},
+		{name: "invalid period in list", request: FetchEnrollmentsRequest{IdsPeriodoLetivo: []int{1, 0}}, want: []string{"idsPeriodoLetivo"}},
+		{name: "unknown tenant", request: FetchEnrollmentsRequest{Tenant: "colegio"}, want: []string{"tenant"}},
+		{name: "unknown organizations", request: FetchEnrollmentsRequest{OrgId: 999, OrgIds: "20,abc"}, want: []string{"orgId", "orgIds"}},
+		{name: "organization not configured", request: FetchEnrollmentsRequest{OrgIds: "20,999"}, want: []string{"orgIds"}},
+		{name: "page size out of bounds", request: FetchEnrollmentsRequest{PageSize: 5000}, want: []string{"pageSize"}},
+		{name: "reversed date range", request: FetchEnrollmentsRequest{DataMatriculaFrom: "2025-03-01", DataMatriculaTo: "2025-02-01"}, want: []string{"dataMatriculaFrom"}},
+		{name: "malformed start date", request: FetchEnrollmentsRequest{DataMatriculaFrom: "01/03/2025", DataMatriculaTo: "2025-02-01"}, want: []string{"dataMatriculaFrom"}},
+		{name: "malformed end date", request: FetchEnrollmentsRequest{DataMatriculaTo: "2025-02-30"}, want: []string{"dataMatriculaTo"}},
+		{name: "negative timeout", request: FetchEnrollmentsRequest{TimeoutMinutes: -1}, want: []string{"timeoutMinutes"}},
+		{name: "same-day date range", request: FetchEnrollmentsRequest{DataMatriculaFrom: "2025-03-01", DataMatriculaTo: "2025-03-01"}, want: []string{}},
+		{name: "resume with several queries", request: FetchEnrollmentsRequest{Resume: true, IdsPeriodoLetivo: []int{1, 2}}, want: []string{"resume"}},
+		{name: "stream with incremental", request: FetchEnrollmentsRequest{WriteMode: WriteModeStream, Mode: SyncModeIncremental}, want: []string{"writeMode"}},
+		{name: "delta report with stream", request: FetchEnrollmentsRequest{WriteMode: WriteModeStream, DeltaReport: true}, want: []string{"deltaReport"}},
+		{name: "snapshot with incremental", request: FetchEnrollmentsRequest{Snapshot: true, Mode: SyncModeIncremental}, want: []string{"snapshot"}},
+		{name: "snapshot with delta report", request: FetchEnrollmentsRequest{Snapshot: true, DeltaReport: true}, want: []string{"snapshot"}},
+		{name: "page range", request: FetchEnrollmentsRequest{StartPage: 2, EndPage: 5, MaxPages: 1}, want: []string{}},
+		{name: "end page before start page", request: FetchEnrollmentsRequest{StartPage: 3, EndPage: 3}, want: []string{"endPage"}},
+		{name: "page range with resume", request: FetchEnrollmentsRequest{MaxPages: 2, Resume: true}, want: []string{"startPage"}},
+		{name: "page range with incremental", request: FetchEnrollmentsRequest{StartPage: 1, Mode: SyncModeIncremental}, want: []string{"startPage"}},
+		{name: "negative max pages", request: FetchEnrollmentsRequest{MaxPages: -1}, want: []string{"maxPages"}},
+		{name: "sort with stream", request: FetchEnrollmentsRequest{WriteMode: WriteModeStream, SortBy: "curso"}, want: []string{"sortBy"}},
+		{name: "parquet with incremental", writer: "parquet", request: FetchEnrollmentsRequest{Mode: SyncModeIncremental}, want: []string{"mode"}},
+		{name: "new spreadsheet without a Drive folder", request: FetchEnrollmentsRequest{NewSpreadsheet: true}, want: []string{"newSpreadsheet"}},
+		{name: "new spreadsheet with resume", drive: "folder", request: FetchEnrollmentsRequest{NewSpreadsheet: true, Resume: true}, want: []string{"newSpreadsheet"}},
+		{name: "new spreadsheet with incremental mode", drive: "folder", request: FetchEnrollmentsRequest{NewSpreadsheet: true, Mode: SyncModeIncremental}, want: []string{"newSpreadsheet"}},
+		{name: "new spreadsheet", drive: "folder", request: FetchEnrollmentsRequest{NewSpreadsheet: true}, want: []string{}},
+		{name: "allowed spreadsheet", allow: []string{"other"}, request: FetchEnrollmentsRequest{SpreadsheetId: "other"}, want: []string{}},
+		{name: "spreadsheet not in allowlist", allow: []string{"other"}, request: FetchEnrollmentsRequest{SpreadsheetId: "unknown"}, want: []string{"spreadsheetId"}},
+		{name: "spreadsheet with new spreadsheet", drive: "folder", allow: []string{"other"}, request: FetchEnrollmentsRequest{SpreadsheetId: "other", NewSpreadsheet: true}, want: []string{"spreadsheetId"}},
+		{name: "spreadsheet with csv writer", writer: "csv", allow: []string{"other"}, request: FetchEnrollmentsRequest{SpreadsheetId: "other"}, want: []string{"spreadsheetId"}},
+		{name: "sheet name", request: FetchEnrollmentsRequest{SheetName: "Matrículas {date}"}, want: []string{}},
+		{name: "sheet name with unknown placeholder", request: FetchEnrollmentsRequest{SheetName: "{{.Year}}"}, want: []string{"sheetName"}},
+		{name: "literal sheet name for several sheets", request: FetchEnrollmentsRequest{OrgIds: "20,17", SheetName: "Matrículas"}, want: []string{"sheetName"}},
+		{name: "sheet name for several sheets", request: FetchEnrollmentsRequest{OrgIds: "20,17", SheetName: "Matrículas {org}"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Defaults()
+			cfg.Writer = "sheets"
+			if tt.writer != "" {
+				cfg.Writer = tt.writer
+			}
+			cfg.DriveFolderID = tt.drive
+			cfg.SpreadsheetAllowlist = tt.allow
+
+			errs := tt.request.Validate(&cfg)
+			if got := fields(errs); !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v (%v)", got, tt.want, errs)
+			}
+		})
+	}
+}
+
+func TestFetchClassesRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request FetchClassesRequest
+		want    []string
+	}{
+		{name: "missing required period", want: []string{"idPeriodoLetivo"}},
+		{name: "valid request", request: FetchClassesRequest{IdPeriodoLetivo: 42, OrgId: 20}, want: []string{}},
+		{name: "unknown organization and status", request: FetchClassesRequest{IdPeriodoLetivo: 42, OrgId: 999, StatusMatricula: "X"}, want: []string{"statusMatricula", "orgId"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Defaults()
+			errs := tt.request.Validate(&cfg)
+			if got := fields(errs); !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v (%v)", got, tt.want, errs)
+			}
+		})
+	}
+}
+
+func TestFetchAttendanceRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request FetchAttendanceRequest
+		want    []string
+	}{
+		{name: "missing required class", want: []string{"idTurma"}},
+		{name: "valid request", request: FetchAttendanceRequest{IdTurma: 7, DataInicio: "2025-02-01", DataFim: "2025-06-30"}, want: []string{}},
+		{name: "malformed date", request: FetchAttendanceRequest{IdTurma: 7, DataFim: "30/06/2025"}, want: []string{"dataFim"}},
+		{name: "reversed range", request: FetchAttendanceRequest{IdTurma: 7, DataInicio: "2025-06-30", DataFim: "2025-02-01"}, want: []string{"dataInicio"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Defaults()
+			errs := tt.request.Validate(&cfg)
+			if got := fields(errs); !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v (%v)", got, tt.want, errs)
+			}
+		})
+	}
+}
+
+func TestCompareEnrollmentsRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request CompareEnrollmentsRequest
+		want    []string
+	}{
+		{name: "missing periods", want: []string{"periodA", "periodB"}},
+		{name: "valid request", request: CompareEnrollmentsRequest{PeriodA: 86, PeriodB: 87}, want: []string{}},
+		{name: "same period", request: CompareEnrollmentsRequest{PeriodA: 87, PeriodB: 87}, want: []string{"periodB"}},
+		{name: "unknown organization", request: CompareEnrollmentsRequest{PeriodA: 86, PeriodB: 87, OrgId: -1}, want: []string{"orgId"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Defaults()
+			errs := tt.request.Validate(&cfg)
+			if got := fields(errs); !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v (%v)", got, tt.want, errs)
+			}
+		})
+	}
+}
+
+func TestNewSpreadsheetConflict(t *testing.T) {
+	tests := []struct {
+		name    string
+		request FetchEnrollmentsRequest
+		want    string
+	}{
+		{name: "full mode", request: FetchEnrollmentsRequest{Mode: SyncModeFull}, want: ""},
+		{name: "incremental mode", request: FetchEnrollmentsRequest{Mode: SyncModeIncremental}, want: "mode 'incremental'"},
+		{name: "resume", request: FetchEnrollmentsRequest{Resume: true}, want: "resume"},
+		{name: "delta report", request: FetchEnrollmentsRequest{DeltaReport: true}, want: "deltaReport"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.request.NewSpreadsheetConflict(); got != tt.want {
+				t.Errorf("NewSpreadsheetConflict() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPreset(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Presets = map[string]config.Preset{
+		"ead-ativas-2024-2": {OrgIds: "20", StatusMatricula: "ATIVA", IdPeriodoLetivo: 87, SheetName: "EAD {status}", Mode: SyncModeIncremental},
+	}
+
+	r := FetchEnrollmentsRequest{Preset: "ead-ativas-2024-2", StatusesMatricula: []string{"TRANCADA"}, Mode: SyncModeFull}
+	if err := r.ApplyPreset(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := FetchEnrollmentsRequest{Preset: "ead-ativas-2024-2", OrgIds: "20", IdPeriodoLetivo: 87, StatusesMatricula: []string{"TRANCADA"}, SheetName: "EAD {status}", Mode: SyncModeFull}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("ApplyPreset() = %+v, want %+v", r, want)
+	}
+
+	unknown := FetchEnrollmentsRequest{Preset: "ead-2023"}
+	if err := unknown.ApplyPreset(&cfg); err == nil {
+		t.Error("ApplyPreset accepted an unknown preset")
+	}
+}
@@ -0,0 +1,87 @@
+// Package apierror defines the body of every error the HTTP API answers, so
+// clients can rely on one shape whichever handler or middleware failed.
+package apierror
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+)
+
+// Response is the error envelope. Code is a stable, machine-readable name of
+// the failure, e.g. "not_found"; Message is meant for people.
+type Response struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+	// JobID names the job that failed, when there was one.
+	JobID string `json:"jobId,omitempty"`
+	// Errors lists the invalid parameters of a 422 response.
+	Errors requests.ValidationErrors `json:"errors,omitempty"`
+	// Result holds what a failed job managed before it failed.
+	Result interface{} `json:"result,omitempty"`
+}
+
+var codes = map[int]string{
+	fiber.StatusBadRequest:          "invalid_request",
+	fiber.StatusUnauthorized:        "unauthorized",
+	fiber.StatusForbidden:           "forbidden",
+	fiber.StatusNotFound:            "not_found",
+	fiber.StatusMethodNotAllowed:    "method_not_allowed",
+	fiber.StatusRequestTimeout:      "timeout",
+	fiber.StatusConflict:            "conflict",
+	fiber.StatusUnprocessableEntity: "validation_failed",
+	fiber.StatusTooManyRequests:     "rate_limited",
+	fiber.StatusInternalServerError: "internal",
+	fiber.StatusServiceUnavailable:  "unavailable",
+	fiber.StatusGatewayTimeout:      "timeout",
+}
+
+// Code returns the code of responses with status, e.g. "not_found" for 404.
+func Code(status int) string {
+	if code, ok := codes[status]; ok {
+		return code
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// Send answers with status and resp, filling in its code when unset and the
+// request ID.
+func Send(c fiber.Ctx, status int, resp Response) error {
+	if resp.Code == "" {
+		resp.Code = Code(status)
+	}
+	resp.RequestID = requestid.FromContext(c)
+	return c.Status(status).JSON(resp)
+}
+
+// Handler is the app's fiber.ErrorHandler: errors handlers return instead of
+// answering, unknown routes and recovered panics are answered with the
+// envelope too.
+func Handler(c fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	message := "Internal server error"
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		status, message = fiberErr.Code, fiberErr.Message
+	}
+	resp := Response{Message: message}
+	if status >= fiber.StatusInternalServerError {
+		slog.Error("Unhandled API error", "method", c.Method(), "path", c.Path(), "requestId", requestid.FromContext(c), "error", err)
+		resp.Details = err.Error()
+	}
+	return Send(c, status, resp)
+}
+
+// LogPanic is the recover middleware's stack trace handler: it logs the
+// panic with its stack before Handler answers 500.
+func LogPanic(c fiber.Ctx, recovered any) {
+	slog.Error("Recovered from panic in API handler", "method", c.Method(), "path", c.Path(), "requestId", requestid.FromContext(c), "panic", recovered, "stack", string(debug.Stack()))
+}
@@ -0,0 +1,77 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/recover"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+)
+
+func TestHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: Handler})
+	app.Use(requestid.New())
+	app.Use(recover.New(recover.Config{EnableStackTrace: true, StackTraceHandler: LogPanic}))
+	app.Get("/panic", func(c fiber.Ctx) error { panic("boom") })
+	app.Get("/conflict", func(c fiber.Ctx) error { return fiber.NewError(fiber.StatusConflict, "Job already running") })
+	app.Get("/sent", func(c fiber.Ctx) error {
+		return Send(c, fiber.StatusUnprocessableEntity, Response{Message: "Invalid parameters", JobID: "job-1"})
+	})
+
+	tests := []struct {
+		name    string
+		target  string
+		status  int
+		code    string
+		message string
+		details string
+		jobID   string
+	}{
+		{name: "panic", target: "/panic", status: 500, code: "internal", message: "Internal server error", details: "boom"},
+		{name: "unknown route", target: "/missing", status: 404, code: "not_found", message: "Cannot GET /missing"},
+		{name: "fiber error", target: "/conflict", status: 409, code: "conflict", message: "Job already running"},
+		{name: "handler response", target: "/sent", status: 422, code: "validation_failed", message: "Invalid parameters", jobID: "job-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			var body Response
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("body is not JSON: %v", err)
+			}
+			if body.Code != tt.code || body.Message != tt.message || body.Details != tt.details || body.JobID != tt.jobID {
+				t.Errorf("body = %+v, want code %q, message %q, details %q, jobId %q", body, tt.code, tt.message, tt.details, tt.jobID)
+			}
+			if body.RequestID == "" || body.RequestID != resp.Header.Get(fiber.HeaderXRequestID) {
+				t.Errorf("requestId = %q, want the X-Request-ID header %q", body.RequestID, resp.Header.Get(fiber.HeaderXRequestID))
+			}
+		})
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{status: 404, want: "not_found"},
+		{status: 429, want: "rate_limited"},
+		{status: 502, want: "bad_gateway"},
+	}
+	for _, tt := range tests {
+		if got := Code(tt.status); got != tt.want {
+			t.Errorf("Code(%d) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"slices"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/api/openapi"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+)
+
+// apiOperations documents every route registered by SetupRouter. Keep it in
+// sync when adding routes.
+func apiOperations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Path: "/healthz", Tag: "probes", Public: true, Raw: true,
+			Summary: "Liveness probe",
+			Result: struct {
+				Status string `json:"status"`
+			}{},
+		},
+		{
+			Path: "/readyz", Tag: "probes", Public: true, Raw: true,
+			Summary:     "Readiness probe",
+			Description: "Returns 503 with status not_ready or shutting_down when the server should not receive traffic.",
+			Result: struct {
+				Status    string                 `json:"status"`
+				Checks    []services.CheckResult `json:"checks"`
+				CheckedAt time.Time              `json:"checkedAt,omitempty"`
+			}{},
+		},
+		{
+			Path: "/metrics", Tag: "probes", Public: true,
+			Summary:     "Prometheus metrics",
+			ContentType: "text/plain",
+		},
+		{
+			Path: "/debug/pprof/:profile", Tag: "probes", Admin: true,
+			Summary:     "Go runtime profiles from net/http/pprof, e.g. heap, allocs or goroutine",
+			Description: "Only served with PPROF_ENABLED, which requires API_KEYS, and only to keys with the admin scope. /debug/pprof/ lists the profiles; profile and trace take a seconds parameter.",
+			PathParams:  map[string]string{"profile": "Profile name, e.g. heap, allocs, goroutine, profile or trace."},
+			ContentType: "application/octet-stream",
+		},
+		{
+			Path: "/api/v1/ping", Tag: "probes", Raw: true,
+			Summary: "Check that the API is reachable and the credentials are accepted",
+			Result: struct {
+				Status  string `json:"status"`
+				Message string `json:"message"`
+			}{},
+		},
+		{
+			Path: "/api/v1/fetch-enrollments", Tag: "fetch",
+			Summary:     "Fetch enrollments and write them to the configured writer",
+			Description: "The job ID is returned in the X-Job-ID header; follow the job at /api/v1/jobs/{id} and /api/v1/jobs/{id}/events. When MAX_CONCURRENT_JOBS jobs are running the job is queued and the call answers 202 at once with jobId and its queue position; the job then runs in the background.",
+			Query:       &requests.FetchEnrollmentsRequest{},
+			Result:      services.FetchResult{},
+			Queued:      jobs.JobStatus{},
+		},
+		{
+			Method: "POST", Path: "/api/v1/fetch-enrollments", Tag: "fetch",
+			Summary:     "Fetch enrollments with list filters from a JSON body",
+			Description: "Accepts every query parameter of the GET route as a body field, plus idsPeriodoLetivo and statusesMatricula. Each combination of period and status is fetched with its own Jacad query and the results are merged.",
+			Body:        &requests.FetchEnrollmentsRequest{},
+			Result:      services.FetchResult{},
+			Queued:      jobs.JobStatus{},
+		},
+		{
+			Path: "/api/v1/fetch-courses", Tag: "fetch",
+			Summary: "Fetch the course catalog and write it to its own sheet",
+			Query:   &requests.FetchCoursesRequest{},
+			Result:  services.SheetResult{},
+		},
+		{
+			Path: "/api/v1/fetch-classes", Tag: "fetch",
+			Summary: "Fetch the classes of a period with their enrollment counts",
+			Query:   &requests.FetchClassesRequest{},
+			Result:  services.ClassesResult{},
+		},
+		{
+			Path: "/api/v1/periods", Tag: "fetch",
+			Summary:     "List the periodos letivos with open process notices",
+			Description: "Periods are found through the process notices with a status in EDITAL_STATUS and listed newest first, one entry per organization and period.",
+			Query:       &requests.ListPeriodsRequest{},
+			Result:      []services.PeriodSummary{},
+		},
+		{
+			Path: "/api/v1/presets", Tag: "fetch",
+			Summary:     "List the fetch-enrollments presets, keyed by name",
+			Description: "Presets are read from PRESETS_FILE. A fetch or export with preset=<name> takes the preset's parameters for those it leaves unset.",
+			Result:      map[string]config.Preset{},
+		},
+		{
+			Path: "/api/v1/fetch-candidates", Tag: "fetch",
+			Summary:     "Fetch the inscriptions of selective process notices, one sheet per edital",
+			Description: "Without idEdital, every process notice with a status in EDITAL_STATUS is fetched. A notice that fails is reported in its result and does not stop the others.",
+			Query:       &requests.FetchCandidatesRequest{},
+			Result:      services.CandidatesResult{},
+		},
+		{
+			Path: "/api/v1/fetch-attendance", Tag: "fetch",
+			Summary:     "Fetch the attendance of a class and write it with students as rows and dates as columns",
+			Description: "Each date cell is P (present), F (absent), counts of both when the class met more than once that day, or blank when the student has no record.",
+			Query:       &requests.FetchAttendanceRequest{},
+			Result:      services.AttendanceResult{},
+		},
+		{
+			Path: "/api/v1/export/enrollments.xlsx", Tag: "export",
+			Summary:     "Download enrollments as an Excel workbook, one sheet per organization",
+			Query:       &requests.FetchEnrollmentsRequest{},
+			ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		},
+		{
+			Path: "/api/v1/export/enrollments.csv", Tag: "export",
+			Summary:     "Stream enrollments as CSV",
+			Query:       &requests.FetchEnrollmentsRequest{},
+			ContentType: "text/csv",
+		},
+		{
+			Path: "/api/v1/compare-enrollments", Tag: "fetch",
+			Summary:     "Compare the students enrolled in two periods",
+			Description: "Lists the students retained from periodA, new in periodB and dropped out, and the change per course. Students are matched by idAluno, or by RA when it is missing. With write the comparison is also written to a 'Comparação <A> x <B>' tab and its students to a ' - Alunos' tab.",
+			Query:       &requests.CompareEnrollmentsRequest{},
+			Result:      services.EnrollmentComparison{},
+		},
+		{
+			Path: "/api/v1/config", Tag: "config",
+			Summary:     "Show the effective configuration with secrets redacted",
+			Description: "Settings are layered from the built-in defaults, the CONFIG_PROFILE profile, the CONFIG_FILE YAML file and the environment. Fields are keyed by their Go names.",
+			Result:      map[string]interface{}{},
+		},
+		{
+			Method: "POST", Path: "/api/v1/admin/reload", Tag: "config", Admin: true,
+			Summary:     "Reload the organizations from ORGANIZATIONS_SOURCE",
+			Description: "Organizations come from the built-in list, the ORGANIZATIONS_FILE YAML or JSON file, or the Jacad organizations endpoint. ORG_SPREADSHEETS is applied on top. On failure the current organizations are kept. Only served with API_KEYS.",
+			Result:      []config.Organization{},
+		},
+		{
+			Method: "POST", Path: "/api/v1/admin/auth/refresh", Tag: "config", Admin: true,
+			Summary:     "Discard the cached Jacad token and log in again",
+			Description: "Use it when USER_TOKEN was rotated on the Jacad side and requests keep failing with 401s. The old token is discarded even when the login fails. Only served with API_KEYS.",
+			Query:       &requests.AuthRefreshRequest{},
+			Result:      services.AuthRefresh{},
+		},
+		{
+			Path: "/api/v1/jobs", Tag: "jobs",
+			Summary:     "List finished jobs, newest first",
+			Description: "Every fetch job is recorded with its parameters, outcome and the sheets it wrote, so the last refresh of a sheet can be audited.",
+			Query:       &requests.ListJobsRequest{},
+			Result:      []services.JobRecord{},
+		},
+		{
+			Path: "/api/v1/queue", Tag: "jobs",
+			Summary:     "List the running and queued jobs",
+			Description: "Running jobs come longest running first and queued ones in the order they will start, each with the route and parameters that started it. startsImmediately tells whether a fetch started now would run straight away or wait behind them.",
+			Result:      jobs.QueueStatus{},
+		},
+		{
+			Path: "/api/v1/jobs/:id", Tag: "jobs", Raw: true,
+			Summary:     "Report whether a job is queued, with its queue position, or running",
+			Description: "job is omitted for recently finished fetches; progress is omitted for jobs that have not reported any yet.",
+			PathParams:  map[string]string{"id": "Job ID from the X-Job-ID header of the fetch or export call."},
+			Result: struct {
+				Job      *jobs.JobStatus         `json:"job,omitempty"`
+				Progress *services.ProgressEvent `json:"progress,omitempty"`
+			}{},
+		},
+		{
+			Method: "DELETE", Path: "/api/v1/jobs/:id", Tag: "jobs",
+			Summary:     "Cancel a queued or running job",
+			Description: "Answers once the job has stopped, so no sheet is written after the response; rows it had fetched but not written are dropped and its history record, returned as result, is marked cancelled with the progress it reached. Answers 202 when the job is still stopping after 30s.",
+			PathParams:  map[string]string{"id": "Job ID from the X-Job-ID header of the fetch or export call."},
+			Result:      services.JobRecord{},
+		},
+		{
+			Path: "/api/v1/jobs/:id/events", Tag: "jobs",
+			Summary:     "Stream a fetch job's progress as Server-Sent Events",
+			Description: "Each event's data is a JSON ProgressEvent. The stream ends with a done or failed event.",
+			PathParams:  map[string]string{"id": "Job ID from the X-Job-ID header of the fetch call."},
+			ContentType: "text/event-stream",
+		},
+		{
+			Method: "POST", Path: "/api/v1/jobs/:id/retry-failed-pages", Tag: "jobs",
+			Summary:     "Refetch the pages a fetch-enrollments job lost and merge their rows into its sheets",
+			Description: "Rows are upserted by idMatricula. The job's history record keeps the pages that fail again, so the call can be repeated.",
+			PathParams:  map[string]string{"id": "Job ID from the X-Job-ID header of the fetch call."},
+			Result:      services.RetryResult{},
+		},
+		{
+			Path: "/api/v1/sheets/status", Tag: "jobs",
+			Summary:     "Report when each sheet was last refreshed and flag the stale ones",
+			Description: "Refreshes are read from the AUDIT_SHEET tab when jobs write one with the sheets writer, otherwise from the job history. Sheets are listed stalest first; a sheet is stale when its last refresh is older than staleAfterMinutes or SHEET_STALE_AFTER.",
+			Query:       &requests.SheetStatusRequest{},
+			Result:      services.SheetStatusReport{},
+		},
+		{
+			Path: "/api/v1/openapi.json", Tag: "docs", Public: true, Raw: true,
+			Summary: "This OpenAPI document",
+			Result:  map[string]interface{}{},
+		},
+		{
+			Path: "/api/v1/docs", Tag: "docs", Public: true,
+			Summary:     "Swagger UI for this document",
+			ContentType: "text/html",
+		},
+	}
+}
+
+// buildSpec returns the OpenAPI document for the router. Without API keys the
+// admin routes are not registered, so they are left out.
+func buildSpec(secured bool) map[string]interface{} {
+	operations := apiOperations()
+	if !secured {
+		operations = slices.DeleteFunc(operations, func(op openapi.Operation) bool { return op.Admin })
+	}
+	spec := openapi.Build("fetch-student-data API", "1.0.0", operations, secured)
+	// ProgressEvent is only sent inside SSE frames; register it so clients
+	// can still look up its shape.
+	openapi.AddSchema(spec, services.ProgressEvent{})
+	return spec
+}
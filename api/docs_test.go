@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+)
+
+// TestSpecDocumentsEveryRoute keeps apiOperations in sync with SetupRouter,
+// with and without API keys.
+func TestSpecDocumentsEveryRoute(t *testing.T) {
+	for _, keys := range []*services.APIKeyRegistry{
+		services.NewAPIKeyRegistry(nil),
+		services.NewAPIKeyRegistry([]config.APIKey{{Name: "ops", Key: "secret", Admin: true}}),
+	} {
+		cfg := config.Defaults()
+		client := services.NewJacadClient(&cfg, services.NewFakeSheetWriter(), nil, nil, nil)
+		app := SetupRouter(client, &cfg, jobs.NewTracker(1), services.NewReadinessProbe(client, time.Minute, time.Second), keys)
+
+		paths := buildSpec(keys.Enabled())["paths"].(map[string]interface{})
+		routes := make(map[string]bool)
+		for _, route := range app.GetRoutes(true) {
+			if route.Method == http.MethodHead {
+				continue
+			}
+			path := strings.ReplaceAll(route.Path, ":id", "{id}")
+			routes[route.Method+" "+path] = true
+			methods, _ := paths[path].(map[string]interface{})
+			if _, ok := methods[strings.ToLower(route.Method)]; !ok {
+				t.Errorf("secured=%v: %s %s is not documented", keys.Enabled(), route.Method, route.Path)
+			}
+		}
+		for path, methods := range paths {
+			if strings.HasPrefix(path, "/debug/pprof") {
+				continue
+			}
+			for method := range methods.(map[string]interface{}) {
+				if !routes[strings.ToUpper(method)+" "+path] {
+					t.Errorf("secured=%v: %s %s is documented but not registered", keys.Enabled(), strings.ToUpper(method), path)
+				}
+			}
+		}
+	}
+}
+
+func TestDocsAreServedWithoutAPIKey(t *testing.T) {
+	cfg := config.Defaults()
+	client := services.NewJacadClient(&cfg, services.NewFakeSheetWriter(), nil, nil, nil)
+	keys := services.NewAPIKeyRegistry([]config.APIKey{{Name: "ops", Key: "secret"}})
+	app := SetupRouter(client, &cfg, jobs.NewTracker(1), services.NewReadinessProbe(client, time.Minute, time.Second), keys)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var spec struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			SecuritySchemes map[string]interface{} `json:"securitySchemes"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || spec.OpenAPI != "3.0.3" || spec.Components.SecuritySchemes["apiKey"] == nil {
+		t.Errorf("openapi.json = %d %+v, want 200 with the apiKey scheme", resp.StatusCode, spec)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/docs", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("docs = %d %s, want 200 text/html", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("ping without a key = %d, want 401", resp.StatusCode)
+	}
+}
@@ -0,0 +1,192 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/api/grpcapi/pb"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func fetchEnrollmentsRequest(in *pb.FetchEnrollmentsRequest) *requests.FetchEnrollmentsRequest {
+	params := &requests.FetchEnrollmentsRequest{
+		OrgId:             int(in.GetOrgId()),
+		OrgIds:            in.GetOrgIds(),
+		IdPeriodoLetivo:   int(in.GetIdPeriodoLetivo()),
+		StatusMatricula:   in.GetStatusMatricula(),
+		Mode:              in.GetMode(),
+		DryRun:            in.GetDryRun(),
+		PreviewRows:       int(in.GetPreviewRows()),
+		Resume:            in.GetResume(),
+		WriteMode:         in.GetWriteMode(),
+		GroupBy:           in.GetGroupBy(),
+		BypassCache:       in.GetBypassCache(),
+		Tenant:            in.GetTenant(),
+		Anonymize:         in.GetAnonymize(),
+		Fields:            in.GetFields(),
+		DeltaReport:       in.GetDeltaReport(),
+		SummaryTab:        in.GetSummaryTab(),
+		NewSpreadsheet:    in.GetNewSpreadsheet(),
+		PageSize:          int(in.GetPageSize()),
+		TimeoutMinutes:    int(in.GetTimeoutMinutes()),
+		StatusesMatricula: in.GetStatusesMatricula(),
+		DataMatriculaFrom: in.GetDataMatriculaFrom(),
+		DataMatriculaTo:   in.GetDataMatriculaTo(),
+	}
+	for _, id := range in.GetIdsPeriodoLetivo() {
+		params.IdsPeriodoLetivo = append(params.IdsPeriodoLetivo, int(id))
+	}
+	return params
+}
+
+func fetchResultMessage(result *services.FetchResult) (*pb.FetchResult, error) {
+	out := &pb.FetchResult{
+		Mode:              result.Mode,
+		WriteMode:         result.WriteMode,
+		DryRun:            result.DryRun,
+		Anonymized:        result.Anonymized,
+		Tenant:            result.Tenant,
+		TotalFetched:      int32(result.TotalFetched),
+		FailedBatches:     int32(result.FailedBatches),
+		FailedPages:       failedPageMessages(result.FailedPages),
+		DuplicatesDropped: int32(result.DuplicatesDropped),
+		OutsideDateRange:  int32(result.OutsideDateRange),
+		TimeoutSeconds:    int32(result.TimeoutSeconds),
+		CircuitBreaker:    result.CircuitBreaker,
+		DeltaSheet:        result.DeltaSheet,
+		DeltaError:        result.DeltaError,
+		SummarySheet:      result.SummarySheet,
+		SummaryError:      result.SummaryError,
+	}
+	if result.Spreadsheet != nil {
+		out.Spreadsheet = &pb.CreatedSpreadsheet{Id: result.Spreadsheet.ID, Url: result.Spreadsheet.URL}
+	}
+	if summary := result.Summary; summary != nil {
+		out.Summary = &pb.EnrollmentSummary{
+			Total:           int32(summary.Total),
+			ByCourse:        counts(summary.ByCourse),
+			ByStatus:        counts(summary.ByStatus),
+			ByUnidadeFisica: counts(summary.ByUnidadeFisica),
+			ByMonth:         counts(summary.ByMonth),
+		}
+	}
+	for _, org := range result.Organizations {
+		summary := &pb.OrgSummary{
+			OrgId:      int32(org.OrgID),
+			OrgName:    org.OrgName,
+			Sheet:      org.Sheet,
+			Group:      org.Group,
+			Rows:       int32(org.Rows),
+			Error:      org.Error,
+			Resumed:    org.Resumed,
+			DeltaError: org.DeltaError,
+		}
+		if org.Delta != nil {
+			summary.Delta = &pb.DeltaCounts{Added: int32(org.Delta.Added), Removed: int32(org.Delta.Removed), StatusChanged: int32(org.Delta.StatusChanged)}
+		}
+		for _, row := range org.Preview {
+			preview, err := structMessage(row)
+			if err != nil {
+				return nil, err
+			}
+			summary.Preview = append(summary.Preview, preview)
+		}
+		out.Organizations = append(out.Organizations, summary)
+	}
+	return out, nil
+}
+
+func failedPageMessages(pages []services.FailedPage) []*pb.FailedPage {
+	var out []*pb.FailedPage
+	for _, page := range pages {
+		out = append(out, &pb.FailedPage{Query: page.Query, PageSize: int32(page.PageSize), Page: int32(page.Page)})
+	}
+	return out
+}
+
+func counts(values map[string]int) map[string]int32 {
+	out := make(map[string]int32, len(values))
+	for key, value := range values {
+		out[key] = int32(value)
+	}
+	return out
+}
+
+// structMessage converts v through its JSON encoding, so values such as dates
+// look the same as in the HTTP API.
+func structMessage(v interface{}) (*structpb.Struct, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(fields)
+}
+
+func jobStatusMessage(status jobs.JobStatus) *pb.JobStatus {
+	return &pb.JobStatus{
+		Id:       status.ID,
+		State:    status.State,
+		Position: int32(status.Position),
+		Queued:   int32(status.Queued),
+		Running:  int32(status.Running),
+	}
+}
+
+func progressMessage(event services.ProgressEvent) *pb.ProgressEvent {
+	out := &pb.ProgressEvent{
+		JobId:        event.JobID,
+		Stage:        event.Stage,
+		PagesFetched: int32(event.PagesFetched),
+		TotalPages:   int32(event.TotalPages),
+		RowsFetched:  int32(event.RowsFetched),
+		RowsWritten:  int32(event.RowsWritten),
+		EtaSeconds:   event.ETASeconds,
+		Error:        event.Error,
+		StartedAt:    timestamp(event.StartedAt),
+		UpdatedAt:    timestamp(event.UpdatedAt),
+	}
+	if event.Deadline != nil {
+		out.Deadline = timestamp(*event.Deadline)
+	}
+	return out
+}
+
+func jobRecordMessage(record services.JobRecord) (*pb.JobRecord, error) {
+	out := &pb.JobRecord{
+		Id:          record.ID,
+		Job:         record.Job,
+		Tenant:      record.Tenant,
+		Status:      record.Status,
+		StartedAt:   timestamp(record.StartedAt),
+		FinishedAt:  timestamp(record.FinishedAt),
+		RowsFetched: int32(record.RowsFetched),
+		RowsWritten: int32(record.RowsWritten),
+		Sheets:      record.Sheets,
+		Failures:    record.Failures,
+		FailedPages: failedPageMessages(record.FailedPages),
+		Error:       record.Error,
+	}
+	if len(record.Params) > 0 {
+		params, err := structMessage(record.Params)
+		if err != nil {
+			return nil, err
+		}
+		out.Params = params
+	}
+	return out, nil
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,74 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	metadataRequestID     = "x-request-id"
+	metadataAPIKey        = "x-api-key"
+	metadataAuthorization = "authorization"
+	metadataJobID         = "x-job-id"
+)
+
+// requestIDInterceptor tags each call with the x-request-id metadata, or a
+// new ID, and returns it in the response header. The ID is the job ID used by
+// GetJobStatus and the job history.
+func requestIDInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	requestID := firstMetadata(ctx, metadataRequestID)
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(metadataRequestID, requestID)); err != nil {
+		logging.FromContext(ctx).Warn("gRPC: Failed to set the request ID header", "error", err)
+	}
+	ctx = logging.WithRequestID(ctx, requestID)
+	logging.FromContext(ctx).Debug("gRPC: Call received", "method", info.FullMethod)
+	return handler(ctx, req)
+}
+
+// apiKeyInterceptor authenticates calls like the HTTP API key middleware,
+// drawing on the same per-key rate limits. Calls are not signed, so the
+// server refuses to start when API_REQUIRE_HMAC is set.
+func apiKeyInterceptor(keys *services.APIKeyRegistry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		provided := firstMetadata(ctx, metadataAPIKey)
+		if provided == "" {
+			if auth := firstMetadata(ctx, metadataAuthorization); strings.HasPrefix(auth, "Bearer ") {
+				provided = strings.TrimPrefix(auth, "Bearer ")
+			}
+		}
+		if provided == "" {
+			return nil, status.Error(codes.Unauthenticated, "missing API key")
+		}
+
+		entry := keys.Find(provided)
+		if entry == nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid API key")
+		}
+		if !entry.Allow() {
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit of %d requests per minute exceeded for API key '%s'", entry.RequestsPerMinute, entry.Name)
+		}
+		return handler(ctx, req)
+	}
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
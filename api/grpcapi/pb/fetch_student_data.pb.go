@@ -0,0 +1,1602 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: fetch_student_data.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type FetchEnrollmentsRequest struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	OrgId             int32                  `protobuf:"varint,1,opt,name=org_id,json=orgId,proto3" json:"org_id,omitempty"`
+	OrgIds            string                 `protobuf:"bytes,2,opt,name=org_ids,json=orgIds,proto3" json:"org_ids,omitempty"`
+	IdPeriodoLetivo   int32                  `protobuf:"varint,3,opt,name=id_periodo_letivo,json=idPeriodoLetivo,proto3" json:"id_periodo_letivo,omitempty"`
+	StatusMatricula   string                 `protobuf:"bytes,4,opt,name=status_matricula,json=statusMatricula,proto3" json:"status_matricula,omitempty"`
+	Mode              string                 `protobuf:"bytes,5,opt,name=mode,proto3" json:"mode,omitempty"`
+	DryRun            bool                   `protobuf:"varint,6,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	PreviewRows       int32                  `protobuf:"varint,7,opt,name=preview_rows,json=previewRows,proto3" json:"preview_rows,omitempty"`
+	Resume            bool                   `protobuf:"varint,8,opt,name=resume,proto3" json:"resume,omitempty"`
+	WriteMode         string                 `protobuf:"bytes,9,opt,name=write_mode,json=writeMode,proto3" json:"write_mode,omitempty"`
+	GroupBy           string                 `protobuf:"bytes,10,opt,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"`
+	BypassCache       bool                   `protobuf:"varint,11,opt,name=bypass_cache,json=bypassCache,proto3" json:"bypass_cache,omitempty"`
+	Tenant            string                 `protobuf:"bytes,12,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Anonymize         bool                   `protobuf:"varint,13,opt,name=anonymize,proto3" json:"anonymize,omitempty"`
+	Fields            string                 `protobuf:"bytes,14,opt,name=fields,proto3" json:"fields,omitempty"`
+	DeltaReport       bool                   `protobuf:"varint,15,opt,name=delta_report,json=deltaReport,proto3" json:"delta_report,omitempty"`
+	SummaryTab        bool                   `protobuf:"varint,16,opt,name=summary_tab,json=summaryTab,proto3" json:"summary_tab,omitempty"`
+	NewSpreadsheet    bool                   `protobuf:"varint,17,opt,name=new_spreadsheet,json=newSpreadsheet,proto3" json:"new_spreadsheet,omitempty"`
+	PageSize          int32                  `protobuf:"varint,18,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	TimeoutMinutes    int32                  `protobuf:"varint,19,opt,name=timeout_minutes,json=timeoutMinutes,proto3" json:"timeout_minutes,omitempty"`
+	IdsPeriodoLetivo  []int32                `protobuf:"varint,20,rep,packed,name=ids_periodo_letivo,json=idsPeriodoLetivo,proto3" json:"ids_periodo_letivo,omitempty"`
+	StatusesMatricula []string               `protobuf:"bytes,21,rep,name=statuses_matricula,json=statusesMatricula,proto3" json:"statuses_matricula,omitempty"`
+	DataMatriculaFrom string                 `protobuf:"bytes,22,opt,name=data_matricula_from,json=dataMatriculaFrom,proto3" json:"data_matricula_from,omitempty"`
+	DataMatriculaTo   string                 `protobuf:"bytes,23,opt,name=data_matricula_to,json=dataMatriculaTo,proto3" json:"data_matricula_to,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *FetchEnrollmentsRequest) Reset() {
+	*x = FetchEnrollmentsRequest{}
+	mi := &file_fetch_student_data_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FetchEnrollmentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FetchEnrollmentsRequest) ProtoMessage() {}
+
+func (x *FetchEnrollmentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FetchEnrollmentsRequest.ProtoReflect.Descriptor instead.
+func (*FetchEnrollmentsRequest) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FetchEnrollmentsRequest) GetOrgId() int32 {
+	if x != nil {
+		return x.OrgId
+	}
+	return 0
+}
+
+func (x *FetchEnrollmentsRequest) GetOrgIds() string {
+	if x != nil {
+		return x.OrgIds
+	}
+	return ""
+}
+
+func (x *FetchEnrollmentsRequest) GetIdPeriodoLetivo() int32 {
+	if x != nil {
+		return x.IdPeriodoLetivo
+	}
+	return 0
+}
+
+func (x *FetchEnrollmentsRequest) GetStatusMatricula() string {
+	if x != nil {
+		return x.StatusMatricula
+	}
+	return ""
+}
+
+func (x *FetchEnrollmentsRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *FetchEnrollmentsRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *FetchEnrollmentsRequest) GetPreviewRows() int32 {
+	if x != nil {
+		return x.PreviewRows
+	}
+	return 0
+}
+
+func (x *FetchEnrollmentsRequest) GetResume() bool {
+	if x != nil {
+		return x.Resume
+	}
+	return false
+}
+
+func (x *FetchEnrollmentsRequest) GetWriteMode() string {
+	if x != nil {
+		return x.WriteMode
+	}
+	return ""
+}
+
+func (x *FetchEnrollmentsRequest) GetGroupBy() string {
+	if x != nil {
+		return x.GroupBy
+	}
+	return ""
+}
+
+func (x *FetchEnrollmentsRequest) GetBypassCache() bool {
+	if x != nil {
+		return x.BypassCache
+	}
+	return false
+}
+
+func (x *FetchEnrollmentsRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *FetchEnrollmentsRequest) GetAnonymize() bool {
+	if x != nil {
+		return x.Anonymize
+	}
+	return false
+}
+
+func (x *FetchEnrollmentsRequest) GetFields() string {
+	if x != nil {
+		return x.Fields
+	}
+	return ""
+}
+
+func (x *FetchEnrollmentsRequest) GetDeltaReport() bool {
+	if x != nil {
+		return x.DeltaReport
+	}
+	return false
+}
+
+func (x *FetchEnrollmentsRequest) GetSummaryTab() bool {
+	if x != nil {
+		return x.SummaryTab
+	}
+	return false
+}
+
+func (x *FetchEnrollmentsRequest) GetNewSpreadsheet() bool {
+	if x != nil {
+		return x.NewSpreadsheet
+	}
+	return false
+}
+
+func (x *FetchEnrollmentsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *FetchEnrollmentsRequest) GetTimeoutMinutes() int32 {
+	if x != nil {
+		return x.TimeoutMinutes
+	}
+	return 0
+}
+
+func (x *FetchEnrollmentsRequest) GetIdsPeriodoLetivo() []int32 {
+	if x != nil {
+		return x.IdsPeriodoLetivo
+	}
+	return nil
+}
+
+func (x *FetchEnrollmentsRequest) GetStatusesMatricula() []string {
+	if x != nil {
+		return x.StatusesMatricula
+	}
+	return nil
+}
+
+func (x *FetchEnrollmentsRequest) GetDataMatriculaFrom() string {
+	if x != nil {
+		return x.DataMatriculaFrom
+	}
+	return ""
+}
+
+func (x *FetchEnrollmentsRequest) GetDataMatriculaTo() string {
+	if x != nil {
+		return x.DataMatriculaTo
+	}
+	return ""
+}
+
+type FailedPage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         map[string]string      `protobuf:"bytes,1,rep,name=query,proto3" json:"query,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	PageSize      int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	Page          int32                  `protobuf:"varint,3,opt,name=page,proto3" json:"page,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FailedPage) Reset() {
+	*x = FailedPage{}
+	mi := &file_fetch_student_data_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FailedPage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FailedPage) ProtoMessage() {}
+
+func (x *FailedPage) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FailedPage.ProtoReflect.Descriptor instead.
+func (*FailedPage) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FailedPage) GetQuery() map[string]string {
+	if x != nil {
+		return x.Query
+	}
+	return nil
+}
+
+func (x *FailedPage) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *FailedPage) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+type DeltaCounts struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Added         int32                  `protobuf:"varint,1,opt,name=added,proto3" json:"added,omitempty"`
+	Removed       int32                  `protobuf:"varint,2,opt,name=removed,proto3" json:"removed,omitempty"`
+	StatusChanged int32                  `protobuf:"varint,3,opt,name=status_changed,json=statusChanged,proto3" json:"status_changed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeltaCounts) Reset() {
+	*x = DeltaCounts{}
+	mi := &file_fetch_student_data_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeltaCounts) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeltaCounts) ProtoMessage() {}
+
+func (x *DeltaCounts) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeltaCounts.ProtoReflect.Descriptor instead.
+func (*DeltaCounts) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *DeltaCounts) GetAdded() int32 {
+	if x != nil {
+		return x.Added
+	}
+	return 0
+}
+
+func (x *DeltaCounts) GetRemoved() int32 {
+	if x != nil {
+		return x.Removed
+	}
+	return 0
+}
+
+func (x *DeltaCounts) GetStatusChanged() int32 {
+	if x != nil {
+		return x.StatusChanged
+	}
+	return 0
+}
+
+type CreatedSpreadsheet struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreatedSpreadsheet) Reset() {
+	*x = CreatedSpreadsheet{}
+	mi := &file_fetch_student_data_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreatedSpreadsheet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatedSpreadsheet) ProtoMessage() {}
+
+func (x *CreatedSpreadsheet) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatedSpreadsheet.ProtoReflect.Descriptor instead.
+func (*CreatedSpreadsheet) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CreatedSpreadsheet) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CreatedSpreadsheet) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+type EnrollmentSummary struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Total           int32                  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	ByCourse        map[string]int32       `protobuf:"bytes,2,rep,name=by_course,json=byCourse,proto3" json:"by_course,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	ByStatus        map[string]int32       `protobuf:"bytes,3,rep,name=by_status,json=byStatus,proto3" json:"by_status,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	ByUnidadeFisica map[string]int32       `protobuf:"bytes,4,rep,name=by_unidade_fisica,json=byUnidadeFisica,proto3" json:"by_unidade_fisica,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	ByMonth         map[string]int32       `protobuf:"bytes,5,rep,name=by_month,json=byMonth,proto3" json:"by_month,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *EnrollmentSummary) Reset() {
+	*x = EnrollmentSummary{}
+	mi := &file_fetch_student_data_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnrollmentSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnrollmentSummary) ProtoMessage() {}
+
+func (x *EnrollmentSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnrollmentSummary.ProtoReflect.Descriptor instead.
+func (*EnrollmentSummary) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *EnrollmentSummary) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *EnrollmentSummary) GetByCourse() map[string]int32 {
+	if x != nil {
+		return x.ByCourse
+	}
+	return nil
+}
+
+func (x *EnrollmentSummary) GetByStatus() map[string]int32 {
+	if x != nil {
+		return x.ByStatus
+	}
+	return nil
+}
+
+func (x *EnrollmentSummary) GetByUnidadeFisica() map[string]int32 {
+	if x != nil {
+		return x.ByUnidadeFisica
+	}
+	return nil
+}
+
+func (x *EnrollmentSummary) GetByMonth() map[string]int32 {
+	if x != nil {
+		return x.ByMonth
+	}
+	return nil
+}
+
+type OrgSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrgId         int32                  `protobuf:"varint,1,opt,name=org_id,json=orgId,proto3" json:"org_id,omitempty"`
+	OrgName       string                 `protobuf:"bytes,2,opt,name=org_name,json=orgName,proto3" json:"org_name,omitempty"`
+	Sheet         string                 `protobuf:"bytes,3,opt,name=sheet,proto3" json:"sheet,omitempty"`
+	Group         string                 `protobuf:"bytes,4,opt,name=group,proto3" json:"group,omitempty"`
+	Rows          int32                  `protobuf:"varint,5,opt,name=rows,proto3" json:"rows,omitempty"`
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	Resumed       bool                   `protobuf:"varint,7,opt,name=resumed,proto3" json:"resumed,omitempty"`
+	Delta         *DeltaCounts           `protobuf:"bytes,8,opt,name=delta,proto3" json:"delta,omitempty"`
+	DeltaError    string                 `protobuf:"bytes,9,opt,name=delta_error,json=deltaError,proto3" json:"delta_error,omitempty"`
+	Preview       []*structpb.Struct     `protobuf:"bytes,10,rep,name=preview,proto3" json:"preview,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrgSummary) Reset() {
+	*x = OrgSummary{}
+	mi := &file_fetch_student_data_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrgSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrgSummary) ProtoMessage() {}
+
+func (x *OrgSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrgSummary.ProtoReflect.Descriptor instead.
+func (*OrgSummary) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *OrgSummary) GetOrgId() int32 {
+	if x != nil {
+		return x.OrgId
+	}
+	return 0
+}
+
+func (x *OrgSummary) GetOrgName() string {
+	if x != nil {
+		return x.OrgName
+	}
+	return ""
+}
+
+func (x *OrgSummary) GetSheet() string {
+	if x != nil {
+		return x.Sheet
+	}
+	return ""
+}
+
+func (x *OrgSummary) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *OrgSummary) GetRows() int32 {
+	if x != nil {
+		return x.Rows
+	}
+	return 0
+}
+
+func (x *OrgSummary) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *OrgSummary) GetResumed() bool {
+	if x != nil {
+		return x.Resumed
+	}
+	return false
+}
+
+func (x *OrgSummary) GetDelta() *DeltaCounts {
+	if x != nil {
+		return x.Delta
+	}
+	return nil
+}
+
+func (x *OrgSummary) GetDeltaError() string {
+	if x != nil {
+		return x.DeltaError
+	}
+	return ""
+}
+
+func (x *OrgSummary) GetPreview() []*structpb.Struct {
+	if x != nil {
+		return x.Preview
+	}
+	return nil
+}
+
+type FetchResult struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Mode              string                 `protobuf:"bytes,1,opt,name=mode,proto3" json:"mode,omitempty"`
+	WriteMode         string                 `protobuf:"bytes,2,opt,name=write_mode,json=writeMode,proto3" json:"write_mode,omitempty"`
+	DryRun            bool                   `protobuf:"varint,3,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	Anonymized        bool                   `protobuf:"varint,4,opt,name=anonymized,proto3" json:"anonymized,omitempty"`
+	Tenant            string                 `protobuf:"bytes,5,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	TotalFetched      int32                  `protobuf:"varint,6,opt,name=total_fetched,json=totalFetched,proto3" json:"total_fetched,omitempty"`
+	FailedBatches     int32                  `protobuf:"varint,7,opt,name=failed_batches,json=failedBatches,proto3" json:"failed_batches,omitempty"`
+	FailedPages       []*FailedPage          `protobuf:"bytes,8,rep,name=failed_pages,json=failedPages,proto3" json:"failed_pages,omitempty"`
+	DuplicatesDropped int32                  `protobuf:"varint,9,opt,name=duplicates_dropped,json=duplicatesDropped,proto3" json:"duplicates_dropped,omitempty"`
+	OutsideDateRange  int32                  `protobuf:"varint,10,opt,name=outside_date_range,json=outsideDateRange,proto3" json:"outside_date_range,omitempty"`
+	TimeoutSeconds    int32                  `protobuf:"varint,11,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	CircuitBreaker    string                 `protobuf:"bytes,12,opt,name=circuit_breaker,json=circuitBreaker,proto3" json:"circuit_breaker,omitempty"`
+	DeltaSheet        string                 `protobuf:"bytes,13,opt,name=delta_sheet,json=deltaSheet,proto3" json:"delta_sheet,omitempty"`
+	DeltaError        string                 `protobuf:"bytes,14,opt,name=delta_error,json=deltaError,proto3" json:"delta_error,omitempty"`
+	Spreadsheet       *CreatedSpreadsheet    `protobuf:"bytes,15,opt,name=spreadsheet,proto3" json:"spreadsheet,omitempty"`
+	Summary           *EnrollmentSummary     `protobuf:"bytes,16,opt,name=summary,proto3" json:"summary,omitempty"`
+	SummarySheet      string                 `protobuf:"bytes,17,opt,name=summary_sheet,json=summarySheet,proto3" json:"summary_sheet,omitempty"`
+	SummaryError      string                 `protobuf:"bytes,18,opt,name=summary_error,json=summaryError,proto3" json:"summary_error,omitempty"`
+	Organizations     []*OrgSummary          `protobuf:"bytes,19,rep,name=organizations,proto3" json:"organizations,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *FetchResult) Reset() {
+	*x = FetchResult{}
+	mi := &file_fetch_student_data_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FetchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FetchResult) ProtoMessage() {}
+
+func (x *FetchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FetchResult.ProtoReflect.Descriptor instead.
+func (*FetchResult) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *FetchResult) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *FetchResult) GetWriteMode() string {
+	if x != nil {
+		return x.WriteMode
+	}
+	return ""
+}
+
+func (x *FetchResult) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *FetchResult) GetAnonymized() bool {
+	if x != nil {
+		return x.Anonymized
+	}
+	return false
+}
+
+func (x *FetchResult) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *FetchResult) GetTotalFetched() int32 {
+	if x != nil {
+		return x.TotalFetched
+	}
+	return 0
+}
+
+func (x *FetchResult) GetFailedBatches() int32 {
+	if x != nil {
+		return x.FailedBatches
+	}
+	return 0
+}
+
+func (x *FetchResult) GetFailedPages() []*FailedPage {
+	if x != nil {
+		return x.FailedPages
+	}
+	return nil
+}
+
+func (x *FetchResult) GetDuplicatesDropped() int32 {
+	if x != nil {
+		return x.DuplicatesDropped
+	}
+	return 0
+}
+
+func (x *FetchResult) GetOutsideDateRange() int32 {
+	if x != nil {
+		return x.OutsideDateRange
+	}
+	return 0
+}
+
+func (x *FetchResult) GetTimeoutSeconds() int32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+func (x *FetchResult) GetCircuitBreaker() string {
+	if x != nil {
+		return x.CircuitBreaker
+	}
+	return ""
+}
+
+func (x *FetchResult) GetDeltaSheet() string {
+	if x != nil {
+		return x.DeltaSheet
+	}
+	return ""
+}
+
+func (x *FetchResult) GetDeltaError() string {
+	if x != nil {
+		return x.DeltaError
+	}
+	return ""
+}
+
+func (x *FetchResult) GetSpreadsheet() *CreatedSpreadsheet {
+	if x != nil {
+		return x.Spreadsheet
+	}
+	return nil
+}
+
+func (x *FetchResult) GetSummary() *EnrollmentSummary {
+	if x != nil {
+		return x.Summary
+	}
+	return nil
+}
+
+func (x *FetchResult) GetSummarySheet() string {
+	if x != nil {
+		return x.SummarySheet
+	}
+	return ""
+}
+
+func (x *FetchResult) GetSummaryError() string {
+	if x != nil {
+		return x.SummaryError
+	}
+	return ""
+}
+
+func (x *FetchResult) GetOrganizations() []*OrgSummary {
+	if x != nil {
+		return x.Organizations
+	}
+	return nil
+}
+
+type GetJobStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobStatusRequest) Reset() {
+	*x = GetJobStatusRequest{}
+	mi := &file_fetch_student_data_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobStatusRequest) ProtoMessage() {}
+
+func (x *GetJobStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetJobStatusRequest) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetJobStatusRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type JobStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	State         string                 `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Position      int32                  `protobuf:"varint,3,opt,name=position,proto3" json:"position,omitempty"`
+	Queued        int32                  `protobuf:"varint,4,opt,name=queued,proto3" json:"queued,omitempty"`
+	Running       int32                  `protobuf:"varint,5,opt,name=running,proto3" json:"running,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobStatus) Reset() {
+	*x = JobStatus{}
+	mi := &file_fetch_student_data_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobStatus) ProtoMessage() {}
+
+func (x *JobStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobStatus.ProtoReflect.Descriptor instead.
+func (*JobStatus) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *JobStatus) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *JobStatus) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *JobStatus) GetPosition() int32 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *JobStatus) GetQueued() int32 {
+	if x != nil {
+		return x.Queued
+	}
+	return 0
+}
+
+func (x *JobStatus) GetRunning() int32 {
+	if x != nil {
+		return x.Running
+	}
+	return 0
+}
+
+type ProgressEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Stage         string                 `protobuf:"bytes,2,opt,name=stage,proto3" json:"stage,omitempty"`
+	PagesFetched  int32                  `protobuf:"varint,3,opt,name=pages_fetched,json=pagesFetched,proto3" json:"pages_fetched,omitempty"`
+	TotalPages    int32                  `protobuf:"varint,4,opt,name=total_pages,json=totalPages,proto3" json:"total_pages,omitempty"`
+	RowsFetched   int32                  `protobuf:"varint,5,opt,name=rows_fetched,json=rowsFetched,proto3" json:"rows_fetched,omitempty"`
+	RowsWritten   int32                  `protobuf:"varint,6,opt,name=rows_written,json=rowsWritten,proto3" json:"rows_written,omitempty"`
+	EtaSeconds    float64                `protobuf:"fixed64,7,opt,name=eta_seconds,json=etaSeconds,proto3" json:"eta_seconds,omitempty"`
+	Deadline      *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	Error         string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProgressEvent) Reset() {
+	*x = ProgressEvent{}
+	mi := &file_fetch_student_data_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProgressEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProgressEvent) ProtoMessage() {}
+
+func (x *ProgressEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProgressEvent.ProtoReflect.Descriptor instead.
+func (*ProgressEvent) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ProgressEvent) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *ProgressEvent) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *ProgressEvent) GetPagesFetched() int32 {
+	if x != nil {
+		return x.PagesFetched
+	}
+	return 0
+}
+
+func (x *ProgressEvent) GetTotalPages() int32 {
+	if x != nil {
+		return x.TotalPages
+	}
+	return 0
+}
+
+func (x *ProgressEvent) GetRowsFetched() int32 {
+	if x != nil {
+		return x.RowsFetched
+	}
+	return 0
+}
+
+func (x *ProgressEvent) GetRowsWritten() int32 {
+	if x != nil {
+		return x.RowsWritten
+	}
+	return 0
+}
+
+func (x *ProgressEvent) GetEtaSeconds() float64 {
+	if x != nil {
+		return x.EtaSeconds
+	}
+	return 0
+}
+
+func (x *ProgressEvent) GetDeadline() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Deadline
+	}
+	return nil
+}
+
+func (x *ProgressEvent) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ProgressEvent) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *ProgressEvent) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type GetJobStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Job           *JobStatus             `protobuf:"bytes,1,opt,name=job,proto3" json:"job,omitempty"`
+	Progress      *ProgressEvent         `protobuf:"bytes,2,opt,name=progress,proto3" json:"progress,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobStatusResponse) Reset() {
+	*x = GetJobStatusResponse{}
+	mi := &file_fetch_student_data_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobStatusResponse) ProtoMessage() {}
+
+func (x *GetJobStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetJobStatusResponse) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetJobStatusResponse) GetJob() *JobStatus {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+func (x *GetJobStatusResponse) GetProgress() *ProgressEvent {
+	if x != nil {
+		return x.Progress
+	}
+	return nil
+}
+
+type ListJobsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Since         string                 `protobuf:"bytes,1,opt,name=since,proto3" json:"since,omitempty"`
+	Job           string                 `protobuf:"bytes,2,opt,name=job,proto3" json:"job,omitempty"`
+	Sheet         string                 `protobuf:"bytes,3,opt,name=sheet,proto3" json:"sheet,omitempty"`
+	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsRequest) Reset() {
+	*x = ListJobsRequest{}
+	mi := &file_fetch_student_data_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsRequest) ProtoMessage() {}
+
+func (x *ListJobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsRequest.ProtoReflect.Descriptor instead.
+func (*ListJobsRequest) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListJobsRequest) GetSince() string {
+	if x != nil {
+		return x.Since
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetJob() string {
+	if x != nil {
+		return x.Job
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetSheet() string {
+	if x != nil {
+		return x.Sheet
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type JobRecord struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Job           string                 `protobuf:"bytes,2,opt,name=job,proto3" json:"job,omitempty"`
+	Params        *structpb.Struct       `protobuf:"bytes,3,opt,name=params,proto3" json:"params,omitempty"`
+	Tenant        string                 `protobuf:"bytes,4,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Status        string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt    *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	RowsFetched   int32                  `protobuf:"varint,8,opt,name=rows_fetched,json=rowsFetched,proto3" json:"rows_fetched,omitempty"`
+	RowsWritten   int32                  `protobuf:"varint,9,opt,name=rows_written,json=rowsWritten,proto3" json:"rows_written,omitempty"`
+	Sheets        []string               `protobuf:"bytes,10,rep,name=sheets,proto3" json:"sheets,omitempty"`
+	Failures      []string               `protobuf:"bytes,11,rep,name=failures,proto3" json:"failures,omitempty"`
+	FailedPages   []*FailedPage          `protobuf:"bytes,12,rep,name=failed_pages,json=failedPages,proto3" json:"failed_pages,omitempty"`
+	Error         string                 `protobuf:"bytes,13,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobRecord) Reset() {
+	*x = JobRecord{}
+	mi := &file_fetch_student_data_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobRecord) ProtoMessage() {}
+
+func (x *JobRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobRecord.ProtoReflect.Descriptor instead.
+func (*JobRecord) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *JobRecord) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *JobRecord) GetJob() string {
+	if x != nil {
+		return x.Job
+	}
+	return ""
+}
+
+func (x *JobRecord) GetParams() *structpb.Struct {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *JobRecord) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *JobRecord) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *JobRecord) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *JobRecord) GetFinishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return nil
+}
+
+func (x *JobRecord) GetRowsFetched() int32 {
+	if x != nil {
+		return x.RowsFetched
+	}
+	return 0
+}
+
+func (x *JobRecord) GetRowsWritten() int32 {
+	if x != nil {
+		return x.RowsWritten
+	}
+	return 0
+}
+
+func (x *JobRecord) GetSheets() []string {
+	if x != nil {
+		return x.Sheets
+	}
+	return nil
+}
+
+func (x *JobRecord) GetFailures() []string {
+	if x != nil {
+		return x.Failures
+	}
+	return nil
+}
+
+func (x *JobRecord) GetFailedPages() []*FailedPage {
+	if x != nil {
+		return x.FailedPages
+	}
+	return nil
+}
+
+func (x *JobRecord) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ListJobsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jobs          []*JobRecord           `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsResponse) Reset() {
+	*x = ListJobsResponse{}
+	mi := &file_fetch_student_data_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsResponse) ProtoMessage() {}
+
+func (x *ListJobsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fetch_student_data_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsResponse.ProtoReflect.Descriptor instead.
+func (*ListJobsResponse) Descriptor() ([]byte, []int) {
+	return file_fetch_student_data_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListJobsResponse) GetJobs() []*JobRecord {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+var File_fetch_student_data_proto protoreflect.FileDescriptor
+
+const file_fetch_student_data_proto_rawDesc = "" +
+	"\n" +
+	"\x18fetch_student_data.proto\x12\x13fetchstudentdata.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9f\x06\n" +
+	"\x17FetchEnrollmentsRequest\x12\x15\n" +
+	"\x06org_id\x18\x01 \x01(\x05R\x05orgId\x12\x17\n" +
+	"\aorg_ids\x18\x02 \x01(\tR\x06orgIds\x12*\n" +
+	"\x11id_periodo_letivo\x18\x03 \x01(\x05R\x0fidPeriodoLetivo\x12)\n" +
+	"\x10status_matricula\x18\x04 \x01(\tR\x0fstatusMatricula\x12\x12\n" +
+	"\x04mode\x18\x05 \x01(\tR\x04mode\x12\x17\n" +
+	"\adry_run\x18\x06 \x01(\bR\x06dryRun\x12!\n" +
+	"\fpreview_rows\x18\a \x01(\x05R\vpreviewRows\x12\x16\n" +
+	"\x06resume\x18\b \x01(\bR\x06resume\x12\x1d\n" +
+	"\n" +
+	"write_mode\x18\t \x01(\tR\twriteMode\x12\x19\n" +
+	"\bgroup_by\x18\n" +
+	" \x01(\tR\agroupBy\x12!\n" +
+	"\fbypass_cache\x18\v \x01(\bR\vbypassCache\x12\x16\n" +
+	"\x06tenant\x18\f \x01(\tR\x06tenant\x12\x1c\n" +
+	"\tanonymize\x18\r \x01(\bR\tanonymize\x12\x16\n" +
+	"\x06fields\x18\x0e \x01(\tR\x06fields\x12!\n" +
+	"\fdelta_report\x18\x0f \x01(\bR\vdeltaReport\x12\x1f\n" +
+	"\vsummary_tab\x18\x10 \x01(\bR\n" +
+	"summaryTab\x12'\n" +
+	"\x0fnew_spreadsheet\x18\x11 \x01(\bR\x0enewSpreadsheet\x12\x1b\n" +
+	"\tpage_size\x18\x12 \x01(\x05R\bpageSize\x12'\n" +
+	"\x0ftimeout_minutes\x18\x13 \x01(\x05R\x0etimeoutMinutes\x12,\n" +
+	"\x12ids_periodo_letivo\x18\x14 \x03(\x05R\x10idsPeriodoLetivo\x12-\n" +
+	"\x12statuses_matricula\x18\x15 \x03(\tR\x11statusesMatricula\x12.\n" +
+	"\x13data_matricula_from\x18\x16 \x01(\tR\x11dataMatriculaFrom\x12*\n" +
+	"\x11data_matricula_to\x18\x17 \x01(\tR\x0fdataMatriculaTo\"\xb9\x01\n" +
+	"\n" +
+	"FailedPage\x12@\n" +
+	"\x05query\x18\x01 \x03(\v2*.fetchstudentdata.v1.FailedPage.QueryEntryR\x05query\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x12\n" +
+	"\x04page\x18\x03 \x01(\x05R\x04page\x1a8\n" +
+	"\n" +
+	"QueryEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"d\n" +
+	"\vDeltaCounts\x12\x14\n" +
+	"\x05added\x18\x01 \x01(\x05R\x05added\x12\x18\n" +
+	"\aremoved\x18\x02 \x01(\x05R\aremoved\x12%\n" +
+	"\x0estatus_changed\x18\x03 \x01(\x05R\rstatusChanged\"6\n" +
+	"\x12CreatedSpreadsheet\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\"\x82\x05\n" +
+	"\x11EnrollmentSummary\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x05R\x05total\x12Q\n" +
+	"\tby_course\x18\x02 \x03(\v24.fetchstudentdata.v1.EnrollmentSummary.ByCourseEntryR\bbyCourse\x12Q\n" +
+	"\tby_status\x18\x03 \x03(\v24.fetchstudentdata.v1.EnrollmentSummary.ByStatusEntryR\bbyStatus\x12g\n" +
+	"\x11by_unidade_fisica\x18\x04 \x03(\v2;.fetchstudentdata.v1.EnrollmentSummary.ByUnidadeFisicaEntryR\x0fbyUnidadeFisica\x12N\n" +
+	"\bby_month\x18\x05 \x03(\v23.fetchstudentdata.v1.EnrollmentSummary.ByMonthEntryR\abyMonth\x1a;\n" +
+	"\rByCourseEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\x1a;\n" +
+	"\rByStatusEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\x1aB\n" +
+	"\x14ByUnidadeFisicaEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\x1a:\n" +
+	"\fByMonthEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"\xba\x02\n" +
+	"\n" +
+	"OrgSummary\x12\x15\n" +
+	"\x06org_id\x18\x01 \x01(\x05R\x05orgId\x12\x19\n" +
+	"\borg_name\x18\x02 \x01(\tR\aorgName\x12\x14\n" +
+	"\x05sheet\x18\x03 \x01(\tR\x05sheet\x12\x14\n" +
+	"\x05group\x18\x04 \x01(\tR\x05group\x12\x12\n" +
+	"\x04rows\x18\x05 \x01(\x05R\x04rows\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x12\x18\n" +
+	"\aresumed\x18\a \x01(\bR\aresumed\x126\n" +
+	"\x05delta\x18\b \x01(\v2 .fetchstudentdata.v1.DeltaCountsR\x05delta\x12\x1f\n" +
+	"\vdelta_error\x18\t \x01(\tR\n" +
+	"deltaError\x121\n" +
+	"\apreview\x18\n" +
+	" \x03(\v2\x17.google.protobuf.StructR\apreview\"\xb0\x06\n" +
+	"\vFetchResult\x12\x12\n" +
+	"\x04mode\x18\x01 \x01(\tR\x04mode\x12\x1d\n" +
+	"\n" +
+	"write_mode\x18\x02 \x01(\tR\twriteMode\x12\x17\n" +
+	"\adry_run\x18\x03 \x01(\bR\x06dryRun\x12\x1e\n" +
+	"\n" +
+	"anonymized\x18\x04 \x01(\bR\n" +
+	"anonymized\x12\x16\n" +
+	"\x06tenant\x18\x05 \x01(\tR\x06tenant\x12#\n" +
+	"\rtotal_fetched\x18\x06 \x01(\x05R\ftotalFetched\x12%\n" +
+	"\x0efailed_batches\x18\a \x01(\x05R\rfailedBatches\x12B\n" +
+	"\ffailed_pages\x18\b \x03(\v2\x1f.fetchstudentdata.v1.FailedPageR\vfailedPages\x12-\n" +
+	"\x12duplicates_dropped\x18\t \x01(\x05R\x11duplicatesDropped\x12,\n" +
+	"\x12outside_date_range\x18\n" +
+	" \x01(\x05R\x10outsideDateRange\x12'\n" +
+	"\x0ftimeout_seconds\x18\v \x01(\x05R\x0etimeoutSeconds\x12'\n" +
+	"\x0fcircuit_breaker\x18\f \x01(\tR\x0ecircuitBreaker\x12\x1f\n" +
+	"\vdelta_sheet\x18\r \x01(\tR\n" +
+	"deltaSheet\x12\x1f\n" +
+	"\vdelta_error\x18\x0e \x01(\tR\n" +
+	"deltaError\x12I\n" +
+	"\vspreadsheet\x18\x0f \x01(\v2'.fetchstudentdata.v1.CreatedSpreadsheetR\vspreadsheet\x12@\n" +
+	"\asummary\x18\x10 \x01(\v2&.fetchstudentdata.v1.EnrollmentSummaryR\asummary\x12#\n" +
+	"\rsummary_sheet\x18\x11 \x01(\tR\fsummarySheet\x12#\n" +
+	"\rsummary_error\x18\x12 \x01(\tR\fsummaryError\x12E\n" +
+	"\rorganizations\x18\x13 \x03(\v2\x1f.fetchstudentdata.v1.OrgSummaryR\rorganizations\"%\n" +
+	"\x13GetJobStatusRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x7f\n" +
+	"\tJobStatus\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05state\x18\x02 \x01(\tR\x05state\x12\x1a\n" +
+	"\bposition\x18\x03 \x01(\x05R\bposition\x12\x16\n" +
+	"\x06queued\x18\x04 \x01(\x05R\x06queued\x12\x18\n" +
+	"\arunning\x18\x05 \x01(\x05R\arunning\"\xad\x03\n" +
+	"\rProgressEvent\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x14\n" +
+	"\x05stage\x18\x02 \x01(\tR\x05stage\x12#\n" +
+	"\rpages_fetched\x18\x03 \x01(\x05R\fpagesFetched\x12\x1f\n" +
+	"\vtotal_pages\x18\x04 \x01(\x05R\n" +
+	"totalPages\x12!\n" +
+	"\frows_fetched\x18\x05 \x01(\x05R\vrowsFetched\x12!\n" +
+	"\frows_written\x18\x06 \x01(\x05R\vrowsWritten\x12\x1f\n" +
+	"\veta_seconds\x18\a \x01(\x01R\n" +
+	"etaSeconds\x126\n" +
+	"\bdeadline\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\bdeadline\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"started_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\x88\x01\n" +
+	"\x14GetJobStatusResponse\x120\n" +
+	"\x03job\x18\x01 \x01(\v2\x1e.fetchstudentdata.v1.JobStatusR\x03job\x12>\n" +
+	"\bprogress\x18\x02 \x01(\v2\".fetchstudentdata.v1.ProgressEventR\bprogress\"e\n" +
+	"\x0fListJobsRequest\x12\x14\n" +
+	"\x05since\x18\x01 \x01(\tR\x05since\x12\x10\n" +
+	"\x03job\x18\x02 \x01(\tR\x03job\x12\x14\n" +
+	"\x05sheet\x18\x03 \x01(\tR\x05sheet\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\"\xda\x03\n" +
+	"\tJobRecord\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03job\x18\x02 \x01(\tR\x03job\x12/\n" +
+	"\x06params\x18\x03 \x01(\v2\x17.google.protobuf.StructR\x06params\x12\x16\n" +
+	"\x06tenant\x18\x04 \x01(\tR\x06tenant\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"started_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12;\n" +
+	"\vfinished_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"finishedAt\x12!\n" +
+	"\frows_fetched\x18\b \x01(\x05R\vrowsFetched\x12!\n" +
+	"\frows_written\x18\t \x01(\x05R\vrowsWritten\x12\x16\n" +
+	"\x06sheets\x18\n" +
+	" \x03(\tR\x06sheets\x12\x1a\n" +
+	"\bfailures\x18\v \x03(\tR\bfailures\x12B\n" +
+	"\ffailed_pages\x18\f \x03(\v2\x1f.fetchstudentdata.v1.FailedPageR\vfailedPages\x12\x14\n" +
+	"\x05error\x18\r \x01(\tR\x05error\"F\n" +
+	"\x10ListJobsResponse\x122\n" +
+	"\x04jobs\x18\x01 \x03(\v2\x1e.fetchstudentdata.v1.JobRecordR\x04jobs2\xb4\x02\n" +
+	"\x10FetchStudentData\x12b\n" +
+	"\x10FetchEnrollments\x12,.fetchstudentdata.v1.FetchEnrollmentsRequest\x1a .fetchstudentdata.v1.FetchResult\x12c\n" +
+	"\fGetJobStatus\x12(.fetchstudentdata.v1.GetJobStatusRequest\x1a).fetchstudentdata.v1.GetJobStatusResponse\x12W\n" +
+	"\bListJobs\x12$.fetchstudentdata.v1.ListJobsRequest\x1a%.fetchstudentdata.v1.ListJobsResponseB<Z:github.com/SamuelLeutner/fetch-student-data/api/grpcapi/pbb\x06proto3"
+
+var (
+	file_fetch_student_data_proto_rawDescOnce sync.Once
+	file_fetch_student_data_proto_rawDescData []byte
+)
+
+func file_fetch_student_data_proto_rawDescGZIP() []byte {
+	file_fetch_student_data_proto_rawDescOnce.Do(func() {
+		file_fetch_student_data_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_fetch_student_data_proto_rawDesc), len(file_fetch_student_data_proto_rawDesc)))
+	})
+	return file_fetch_student_data_proto_rawDescData
+}
+
+var file_fetch_student_data_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_fetch_student_data_proto_goTypes = []any{
+	(*FetchEnrollmentsRequest)(nil), // 0: fetchstudentdata.v1.FetchEnrollmentsRequest
+	(*FailedPage)(nil),              // 1: fetchstudentdata.v1.FailedPage
+	(*DeltaCounts)(nil),             // 2: fetchstudentdata.v1.DeltaCounts
+	(*CreatedSpreadsheet)(nil),      // 3: fetchstudentdata.v1.CreatedSpreadsheet
+	(*EnrollmentSummary)(nil),       // 4: fetchstudentdata.v1.EnrollmentSummary
+	(*OrgSummary)(nil),              // 5: fetchstudentdata.v1.OrgSummary
+	(*FetchResult)(nil),             // 6: fetchstudentdata.v1.FetchResult
+	(*GetJobStatusRequest)(nil),     // 7: fetchstudentdata.v1.GetJobStatusRequest
+	(*JobStatus)(nil),               // 8: fetchstudentdata.v1.JobStatus
+	(*ProgressEvent)(nil),           // 9: fetchstudentdata.v1.ProgressEvent
+	(*GetJobStatusResponse)(nil),    // 10: fetchstudentdata.v1.GetJobStatusResponse
+	(*ListJobsRequest)(nil),         // 11: fetchstudentdata.v1.ListJobsRequest
+	(*JobRecord)(nil),               // 12: fetchstudentdata.v1.JobRecord
+	(*ListJobsResponse)(nil),        // 13: fetchstudentdata.v1.ListJobsResponse
+	nil,                             // 14: fetchstudentdata.v1.FailedPage.QueryEntry
+	nil,                             // 15: fetchstudentdata.v1.EnrollmentSummary.ByCourseEntry
+	nil,                             // 16: fetchstudentdata.v1.EnrollmentSummary.ByStatusEntry
+	nil,                             // 17: fetchstudentdata.v1.EnrollmentSummary.ByUnidadeFisicaEntry
+	nil,                             // 18: fetchstudentdata.v1.EnrollmentSummary.ByMonthEntry
+	(*structpb.Struct)(nil),         // 19: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil),   // 20: google.protobuf.Timestamp
+}
+var file_fetch_student_data_proto_depIdxs = []int32{
+	14, // 0: fetchstudentdata.v1.FailedPage.query:type_name -> fetchstudentdata.v1.FailedPage.QueryEntry
+	15, // 1: fetchstudentdata.v1.EnrollmentSummary.by_course:type_name -> fetchstudentdata.v1.EnrollmentSummary.ByCourseEntry
+	16, // 2: fetchstudentdata.v1.EnrollmentSummary.by_status:type_name -> fetchstudentdata.v1.EnrollmentSummary.ByStatusEntry
+	17, // 3: fetchstudentdata.v1.EnrollmentSummary.by_unidade_fisica:type_name -> fetchstudentdata.v1.EnrollmentSummary.ByUnidadeFisicaEntry
+	18, // 4: fetchstudentdata.v1.EnrollmentSummary.by_month:type_name -> fetchstudentdata.v1.EnrollmentSummary.ByMonthEntry
+	2,  // 5: fetchstudentdata.v1.OrgSummary.delta:type_name -> fetchstudentdata.v1.DeltaCounts
+	19, // 6: fetchstudentdata.v1.OrgSummary.preview:type_name -> google.protobuf.Struct
+	1,  // 7: fetchstudentdata.v1.FetchResult.failed_pages:type_name -> fetchstudentdata.v1.FailedPage
+	3,  // 8: fetchstudentdata.v1.FetchResult.spreadsheet:type_name -> fetchstudentdata.v1.CreatedSpreadsheet
+	4,  // 9: fetchstudentdata.v1.FetchResult.summary:type_name -> fetchstudentdata.v1.EnrollmentSummary
+	5,  // 10: fetchstudentdata.v1.FetchResult.organizations:type_name -> fetchstudentdata.v1.OrgSummary
+	20, // 11: fetchstudentdata.v1.ProgressEvent.deadline:type_name -> google.protobuf.Timestamp
+	20, // 12: fetchstudentdata.v1.ProgressEvent.started_at:type_name -> google.protobuf.Timestamp
+	20, // 13: fetchstudentdata.v1.ProgressEvent.updated_at:type_name -> google.protobuf.Timestamp
+	8,  // 14: fetchstudentdata.v1.GetJobStatusResponse.job:type_name -> fetchstudentdata.v1.JobStatus
+	9,  // 15: fetchstudentdata.v1.GetJobStatusResponse.progress:type_name -> fetchstudentdata.v1.ProgressEvent
+	19, // 16: fetchstudentdata.v1.JobRecord.params:type_name -> google.protobuf.Struct
+	20, // 17: fetchstudentdata.v1.JobRecord.started_at:type_name -> google.protobuf.Timestamp
+	20, // 18: fetchstudentdata.v1.JobRecord.finished_at:type_name -> google.protobuf.Timestamp
+	1,  // 19: fetchstudentdata.v1.JobRecord.failed_pages:type_name -> fetchstudentdata.v1.FailedPage
+	12, // 20: fetchstudentdata.v1.ListJobsResponse.jobs:type_name -> fetchstudentdata.v1.JobRecord
+	0,  // 21: fetchstudentdata.v1.FetchStudentData.FetchEnrollments:input_type -> fetchstudentdata.v1.FetchEnrollmentsRequest
+	7,  // 22: fetchstudentdata.v1.FetchStudentData.GetJobStatus:input_type -> fetchstudentdata.v1.GetJobStatusRequest
+	11, // 23: fetchstudentdata.v1.FetchStudentData.ListJobs:input_type -> fetchstudentdata.v1.ListJobsRequest
+	6,  // 24: fetchstudentdata.v1.FetchStudentData.FetchEnrollments:output_type -> fetchstudentdata.v1.FetchResult
+	10, // 25: fetchstudentdata.v1.FetchStudentData.GetJobStatus:output_type -> fetchstudentdata.v1.GetJobStatusResponse
+	13, // 26: fetchstudentdata.v1.FetchStudentData.ListJobs:output_type -> fetchstudentdata.v1.ListJobsResponse
+	24, // [24:27] is the sub-list for method output_type
+	21, // [21:24] is the sub-list for method input_type
+	21, // [21:21] is the sub-list for extension type_name
+	21, // [21:21] is the sub-list for extension extendee
+	0,  // [0:21] is the sub-list for field type_name
+}
+
+func init() { file_fetch_student_data_proto_init() }
+func file_fetch_student_data_proto_init() {
+	if File_fetch_student_data_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_fetch_student_data_proto_rawDesc), len(file_fetch_student_data_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   19,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_fetch_student_data_proto_goTypes,
+		DependencyIndexes: file_fetch_student_data_proto_depIdxs,
+		MessageInfos:      file_fetch_student_data_proto_msgTypes,
+	}.Build()
+	File_fetch_student_data_proto = out.File
+	file_fetch_student_data_proto_goTypes = nil
+	file_fetch_student_data_proto_depIdxs = nil
+}
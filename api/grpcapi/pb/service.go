@@ -0,0 +1,144 @@
+// Package pb holds the protobuf messages of the gRPC API, generated from
+// fetch_student_data.proto with protoc-gen-go, and the FetchStudentData
+// service descriptor and client.
+package pb
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative fetch_student_data.proto
+
+const (
+	FetchStudentData_FetchEnrollments_FullMethodName = "/fetchstudentdata.v1.FetchStudentData/FetchEnrollments"
+	FetchStudentData_GetJobStatus_FullMethodName     = "/fetchstudentdata.v1.FetchStudentData/GetJobStatus"
+	FetchStudentData_ListJobs_FullMethodName         = "/fetchstudentdata.v1.FetchStudentData/ListJobs"
+)
+
+type FetchStudentDataClient interface {
+	FetchEnrollments(ctx context.Context, in *FetchEnrollmentsRequest, opts ...grpc.CallOption) (*FetchResult, error)
+	GetJobStatus(ctx context.Context, in *GetJobStatusRequest, opts ...grpc.CallOption) (*GetJobStatusResponse, error)
+	ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error)
+}
+
+type fetchStudentDataClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFetchStudentDataClient(cc grpc.ClientConnInterface) FetchStudentDataClient {
+	return &fetchStudentDataClient{cc}
+}
+
+func (c *fetchStudentDataClient) FetchEnrollments(ctx context.Context, in *FetchEnrollmentsRequest, opts ...grpc.CallOption) (*FetchResult, error) {
+	out := new(FetchResult)
+	if err := c.cc.Invoke(ctx, FetchStudentData_FetchEnrollments_FullMethodName, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fetchStudentDataClient) GetJobStatus(ctx context.Context, in *GetJobStatusRequest, opts ...grpc.CallOption) (*GetJobStatusResponse, error) {
+	out := new(GetJobStatusResponse)
+	if err := c.cc.Invoke(ctx, FetchStudentData_GetJobStatus_FullMethodName, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fetchStudentDataClient) ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error) {
+	out := new(ListJobsResponse)
+	if err := c.cc.Invoke(ctx, FetchStudentData_ListJobs_FullMethodName, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FetchStudentDataServer is implemented by the gRPC API. Embed
+// UnimplementedFetchStudentDataServer to stay compatible with new RPCs.
+type FetchStudentDataServer interface {
+	FetchEnrollments(context.Context, *FetchEnrollmentsRequest) (*FetchResult, error)
+	GetJobStatus(context.Context, *GetJobStatusRequest) (*GetJobStatusResponse, error)
+	ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error)
+	mustEmbedUnimplementedFetchStudentDataServer()
+}
+
+type UnimplementedFetchStudentDataServer struct{}
+
+func (UnimplementedFetchStudentDataServer) FetchEnrollments(context.Context, *FetchEnrollmentsRequest) (*FetchResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FetchEnrollments not implemented")
+}
+
+func (UnimplementedFetchStudentDataServer) GetJobStatus(context.Context, *GetJobStatusRequest) (*GetJobStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJobStatus not implemented")
+}
+
+func (UnimplementedFetchStudentDataServer) ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListJobs not implemented")
+}
+
+func (UnimplementedFetchStudentDataServer) mustEmbedUnimplementedFetchStudentDataServer() {}
+
+func RegisterFetchStudentDataServer(s grpc.ServiceRegistrar, srv FetchStudentDataServer) {
+	s.RegisterService(&FetchStudentData_ServiceDesc, srv)
+}
+
+func _FetchStudentData_FetchEnrollments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FetchEnrollmentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FetchStudentDataServer).FetchEnrollments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: FetchStudentData_FetchEnrollments_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FetchStudentDataServer).FetchEnrollments(ctx, req.(*FetchEnrollmentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FetchStudentData_GetJobStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FetchStudentDataServer).GetJobStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: FetchStudentData_GetJobStatus_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FetchStudentDataServer).GetJobStatus(ctx, req.(*GetJobStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FetchStudentData_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FetchStudentDataServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: FetchStudentData_ListJobs_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FetchStudentDataServer).ListJobs(ctx, req.(*ListJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var FetchStudentData_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "fetchstudentdata.v1.FetchStudentData",
+	HandlerType: (*FetchStudentDataServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "FetchEnrollments", Handler: _FetchStudentData_FetchEnrollments_Handler},
+		{MethodName: "GetJobStatus", Handler: _FetchStudentData_GetJobStatus_Handler},
+		{MethodName: "ListJobs", Handler: _FetchStudentData_ListJobs_Handler},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "fetch_student_data.proto",
+}
@@ -0,0 +1,163 @@
+// Package grpcapi serves the fetch-enrollments and jobs operations of the
+// HTTP API over gRPC, for internal services that prefer it. It shares the
+// JacadClient, configuration and job tracker with the HTTP server.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/api/grpcapi/pb"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const defaultJobHistoryLimit = 100
+
+type server struct {
+	pb.UnimplementedFetchStudentDataServer
+	client    *services.JacadClient
+	appConfig *config.Config
+	tracker   *jobs.Tracker
+}
+
+// NewServer returns a gRPC server with the FetchStudentData service
+// registered. Calls are authenticated with the configured API keys, sent as
+// x-api-key or "authorization: Bearer <key>" metadata, and share their rate
+// limits with the HTTP API through keys. gRPC calls are not signed, so
+// NewServer fails when API_REQUIRE_HMAC is set.
+func NewServer(client *services.JacadClient, appConfig *config.Config, tracker *jobs.Tracker, keys *services.APIKeyRegistry) (*grpc.Server, error) {
+	if appConfig.APIRequireHMAC {
+		return nil, fmt.Errorf("API_REQUIRE_HMAC is not supported by the gRPC API")
+	}
+	interceptors := []grpc.UnaryServerInterceptor{requestIDInterceptor}
+	if keys.Enabled() {
+		interceptors = append(interceptors, apiKeyInterceptor(keys))
+	} else {
+		logging.FromContext(context.Background()).Warn("API_KEYS is not set. The gRPC API is not protected by authentication.")
+	}
+
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	pb.RegisterFetchStudentDataServer(s, &server{client: client, appConfig: appConfig, tracker: tracker})
+	return s, nil
+}
+
+func (s *server) FetchEnrollments(ctx context.Context, in *pb.FetchEnrollmentsRequest) (*pb.FetchResult, error) {
+	logger := logging.FromContext(ctx)
+	params := fetchEnrollmentsRequest(in)
+
+	errs := params.Validate(s.appConfig)
+	if err := services.ValidateEnrollmentFields(params.ParseFields(), s.appConfig.Columns); err != nil {
+		errs.Add("fields", err)
+	}
+	if _, err := services.ParseEnrollmentSort(params.SortBy); err != nil {
+		errs.Add("sortBy", err)
+	}
+	if len(errs) > 0 {
+		logger.Warn("gRPC: Rejecting invalid fetch request", "error", errs)
+		return nil, status.Error(codes.InvalidArgument, errs.Error())
+	}
+	timeout := params.Timeout(s.appConfig.FetchTimeout, s.appConfig.MaxFetchTimeout)
+
+	pending, err := s.tracker.Enqueue(jobs.WithDescription(ctx, jobs.Description{Kind: "gRPC FetchEnrollments", Params: params}))
+	if err != nil {
+		logger.Warn("gRPC: Rejecting fetch request", "error", err)
+		return nil, jobStartFailed(err)
+	}
+	// Send the job ID before waiting for a slot so clients can follow a
+	// queued job through GetJobStatus.
+	if err := grpc.SendHeader(ctx, metadata.Pairs(metadataJobID, pending.ID)); err != nil {
+		logger.Warn("gRPC: Failed to send job ID header", "jobId", pending.ID, "error", err)
+	}
+	jobCtx, jobDone, err := pending.Wait()
+	if err != nil {
+		logger.Warn("gRPC: Rejecting fetch request", "error", err)
+		return nil, jobStartFailed(err)
+	}
+	defer jobDone()
+
+	jobCtx, cancel := context.WithTimeout(jobCtx, timeout)
+	defer cancel()
+
+	logger.Info("gRPC: Starting enrollment fetch operation", "idPeriodoLetivo", params.PeriodLabel(), "statusMatricula", params.StatusLabel(), "queries", params.QueryCount(), "timeout", timeout.String())
+	result, err := s.client.FetchEnrollmentsFiltered(jobCtx, params)
+	if err != nil {
+		logger.Error("gRPC: Error during enrollment fetch", "error", err)
+		if jobCtx.Err() != nil {
+			return nil, status.Errorf(codes.DeadlineExceeded, "fetch operation timed out or was cancelled (timeout %s): %s", timeout, err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to fetch enrollments: %s", err)
+	}
+	result.TimeoutSeconds = int(timeout.Seconds())
+
+	out, err := fetchResultMessage(result)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode the fetch result: %s", err)
+	}
+	return out, nil
+}
+
+func (s *server) GetJobStatus(ctx context.Context, in *pb.GetJobStatusRequest) (*pb.GetJobStatusResponse, error) {
+	jobStatus, tracked := s.tracker.Status(in.GetId())
+	progress, reported := s.client.Progress().Latest(in.GetId())
+	if !tracked && !reported {
+		return nil, status.Errorf(codes.NotFound, "no queued, running or recently finished job with id '%s'", in.GetId())
+	}
+
+	out := &pb.GetJobStatusResponse{}
+	if tracked {
+		out.Job = jobStatusMessage(jobStatus)
+	}
+	if reported {
+		out.Progress = progressMessage(progress)
+	}
+	return out, nil
+}
+
+func (s *server) ListJobs(ctx context.Context, in *pb.ListJobsRequest) (*pb.ListJobsResponse, error) {
+	params := &requests.ListJobsRequest{Since: in.GetSince(), Job: in.GetJob(), Sheet: in.GetSheet(), Limit: int(in.GetLimit())}
+	if errs := params.Validate(); len(errs) > 0 {
+		return nil, status.Error(codes.InvalidArgument, errs.Error())
+	}
+	if s.client.History == nil {
+		return &pb.ListJobsResponse{}, nil
+	}
+
+	since, _ := params.SinceTime()
+	limit := params.Limit
+	if limit == 0 {
+		limit = defaultJobHistoryLimit
+	}
+	records, err := s.client.History.List(services.JobHistoryFilter{Since: since, Job: params.Job, Sheet: params.Sheet, Limit: limit})
+	if err != nil {
+		logging.FromContext(ctx).Error("gRPC: Error reading job history", "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to read job history: %s", err)
+	}
+
+	out := &pb.ListJobsResponse{Jobs: make([]*pb.JobRecord, 0, len(records))}
+	for _, record := range records {
+		job, err := jobRecordMessage(record)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to encode job '%s': %s", record.ID, err)
+		}
+		out.Jobs = append(out.Jobs, job)
+	}
+	return out, nil
+}
+
+// jobStartFailed maps tracker errors like the HTTP handlers do: unavailable
+// while the server drains, cancelled when the caller left the queue.
+func jobStartFailed(err error) error {
+	if errors.Is(err, jobs.ErrShuttingDown) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Errorf(codes.Canceled, "request was cancelled while waiting in the job queue: %s", err)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+)
+
+// CreateAuthRefreshHandler discards the cached Jacad token and logs in again,
+// so jobs stop failing with 401s after USER_TOKEN is rotated on the Jacad
+// side.
+func CreateAuthRefreshHandler(client *services.JacadClient, appConfig *config.Config) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		params := new(requests.AuthRefreshRequest)
+		requestCtx := logging.WithRequestID(c.Context(), requestid.FromContext(c))
+		logger := logging.FromContext(requestCtx)
+
+		if err := c.Bind().Query(params); err != nil {
+			logger.Warn("Handler: Error parsing query params", "error", err)
+			return apierror.Send(c, fiber.StatusBadRequest, apierror.Response{
+				Message: "Invalid query params",
+				Details: err.Error(),
+			})
+		}
+		if errs := params.Validate(appConfig); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		logger.Info("Handler: Refreshing Jacad token", "tenant", params.Tenant)
+		refresh, err := client.RefreshAuthToken(services.WithTenant(requestCtx, params.Tenant))
+		if err != nil {
+			logger.Error("Handler: Error refreshing Jacad token", "error", err)
+			return apierror.Send(c, fiber.StatusInternalServerError, apierror.Response{
+				Message: "Failed to authenticate with Jacad",
+				Details: err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"message": "Jacad token refreshed",
+			"result":  refresh,
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+)
+
+// cancelAckTimeout is how long a cancel call waits for the job to stop before
+// answering that it is still stopping.
+const cancelAckTimeout = 30 * time.Second
+
+// CreateCancelJobHandler cancels a queued or running job. It answers 200 once
+// the job has stopped, so no sheet is written after that, with the job's
+// history record when there is one; a job still stopping after
+// cancelAckTimeout is answered with 202.
+func CreateCancelJobHandler(tracker *jobs.Tracker, history services.JobHistoryStore) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		jobID := c.Params("id")
+		logger := logging.FromContext(logging.WithRequestID(c.Context(), requestid.FromContext(c))).With("jobId", jobID)
+
+		finished, ok := tracker.Cancel(jobID)
+		if !ok {
+			return apierror.Send(c, fiber.StatusNotFound, apierror.Response{
+				Message: "Job not found",
+				Details: fmt.Sprintf("no queued or running job with id '%s'", jobID),
+			})
+		}
+		logger.Info("Handler: Cancelling job")
+
+		timer := time.NewTimer(cancelAckTimeout)
+		defer timer.Stop()
+		select {
+		case <-finished:
+		case <-timer.C:
+			logger.Warn("Handler: Job is still stopping after cancellation", "timeout", cancelAckTimeout.String())
+			return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+				"message": "Cancellation requested. The job is still stopping; follow it at /api/v1/jobs/" + jobID + ".",
+				"jobId":   jobID,
+			})
+		case <-c.Context().Done():
+			return c.Context().Err()
+		}
+
+		logger.Info("Handler: Job cancelled")
+		response := fiber.Map{
+			"message": "Job cancelled",
+			"jobId":   jobID,
+		}
+		if history != nil {
+			record, err := history.Find(jobID)
+			if err != nil {
+				logger.Warn("Handler: Failed to read cancelled job from history", "error", err)
+			} else if record != nil {
+				response["result"] = record
+			}
+		}
+		return c.JSON(response)
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+)
+
+// CreateCompareEnrollmentsHandler fetches two periods and returns their
+// comparison, writing it to the sheets too when write is set.
+func CreateCompareEnrollmentsHandler(client *services.JacadClient, appConfig *config.Config, tracker *jobs.Tracker) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		params := new(requests.CompareEnrollmentsRequest)
+		requestCtx := logging.WithRequestID(c.Context(), requestid.FromContext(c))
+		logger := logging.FromContext(requestCtx)
+
+		if err := c.Bind().Query(params); err != nil {
+			logger.Warn("Handler: Error parsing query params", "error", err)
+			return apierror.Send(c, fiber.StatusBadRequest, apierror.Response{
+				Message: "Invalid query params",
+				Details: err.Error(),
+			})
+		}
+
+		if errs := params.Validate(appConfig); len(errs) > 0 {
+			logger.Warn("Handler: Rejecting invalid comparison request", "error", errs)
+			return validationFailed(c, errs)
+		}
+
+		timeout := params.Timeout(appConfig.FetchTimeout, appConfig.MaxFetchTimeout)
+
+		jobCtx, jobDone, err := startJob(c, tracker, requestCtx, params)
+		if err != nil {
+			logger.Warn("Handler: Rejecting comparison request", "error", err)
+			return jobStartFailed(c, err)
+		}
+		defer jobDone()
+
+		ctx, cancel := context.WithTimeout(jobCtx, timeout)
+		defer cancel()
+
+		logger.Info("Handler: Starting enrollment comparison", "periodA", params.PeriodA, "periodB", params.PeriodB)
+		comparison, err := client.CompareEnrollments(ctx, params)
+		if err != nil {
+			if ctx.Err() != nil {
+				logger.Warn("Handler: Comparison cancelled (timeout/client disconnect)", "error", err)
+				return apierror.Send(c, fiber.StatusRequestTimeout, apierror.Response{
+					Message: "Comparison timed out or was cancelled by client",
+					Details: err.Error(),
+				})
+			}
+			logger.Error("Handler: Error during enrollment comparison", "error", err)
+			return apierror.Send(c, fiber.StatusInternalServerError, apierror.Response{
+				Message: "Failed to compare enrollments",
+				Details: err.Error(),
+				Result:  comparison,
+			})
+		}
+
+		if !params.Write {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"message": "Enrollments compared. No sheets were written.",
+				"result":  comparison,
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Enrollments compared and written to sheets successfully!",
+			"result":  comparison,
+		})
+	}
+}
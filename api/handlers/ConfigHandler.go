@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/gofiber/fiber/v3"
+)
+
+// CreateConfigHandler reports the effective configuration with secrets
+// redacted.
+func CreateConfigHandler(appConfig *config.Config) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"message": "Effective configuration",
+			"result":  appConfig.Redacted(),
+		})
+	}
+}
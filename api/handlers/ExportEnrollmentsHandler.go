@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// CreateExportEnrollmentsXLSXHandler runs the filtered fetch and returns the
+// enrollments as an Excel workbook, one tab per organization. Nothing is
+// written to Google Sheets.
+func CreateExportEnrollmentsXLSXHandler(client *services.JacadClient, appConfig *config.Config, tracker *jobs.Tracker) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		params := new(requests.FetchEnrollmentsRequest)
+		requestCtx := logging.WithRequestID(c.Context(), requestid.FromContext(c))
+		logger := logging.FromContext(requestCtx)
+
+		if err := c.Bind().Query(params); err != nil {
+			logger.Warn("Handler: Error parsing query params", "error", err)
+			return apierror.Send(c, fiber.StatusBadRequest, apierror.Response{
+				Message: "Invalid query params",
+				Details: err.Error(),
+			})
+		}
+
+		if errs := validateEnrollmentRequest(params, appConfig); len(errs) > 0 {
+			logger.Warn("Handler: Rejecting invalid export request", "error", errs)
+			return validationFailed(c, errs)
+		}
+
+		timeout := params.Timeout(appConfig.FetchTimeout, appConfig.MaxFetchTimeout)
+
+		jobCtx, jobDone, err := startJob(c, tracker, requestCtx, params)
+		if err != nil {
+			logger.Warn("Handler: Rejecting export request", "error", err)
+			return jobStartFailed(c, err)
+		}
+		defer jobDone()
+
+		ctx, cancel := context.WithTimeout(jobCtx, timeout)
+		defer cancel()
+
+		logger.Info("Handler: Starting enrollment export", "format", "xlsx", "idPeriodoLetivo", params.IdPeriodoLetivo)
+		sheets, err := client.ExportEnrollments(ctx, params)
+		if err != nil {
+			if ctx.Err() != nil {
+				logger.Warn("Handler: Export cancelled (timeout/client disconnect)", "error", err)
+				return apierror.Send(c, fiber.StatusRequestTimeout, apierror.Response{
+					Message: "Export timed out or was cancelled by client",
+					Details: err.Error(),
+				})
+			}
+			logger.Error("Handler: Error during enrollment export", "error", err)
+			return apierror.Send(c, fiber.StatusInternalServerError, apierror.Response{
+				Message: "Failed to export enrollments",
+				Details: err.Error(),
+			})
+		}
+
+		var buf bytes.Buffer
+		if err := services.WriteXLSX(&buf, sheets); err != nil {
+			logger.Error("Handler: Error encoding xlsx export", "error", err)
+			return apierror.Send(c, fiber.StatusInternalServerError, apierror.Response{
+				Message: "Failed to build Excel workbook",
+				Details: err.Error(),
+			})
+		}
+
+		filename := fmt.Sprintf("matriculas-%d-%s.xlsx", params.IdPeriodoLetivo, time.Now().Format("20060102-150405"))
+		c.Set(fiber.HeaderContentType, xlsxContentType)
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+		logger.Info("Handler: Export completed. Sending workbook.", "sheets", len(sheets), "bytes", buf.Len())
+		return c.Status(fiber.StatusOK).Send(buf.Bytes())
+	}
+}
+
+// CreateExportEnrollmentsCSVHandler streams the filtered enrollments as CSV
+// using chunked transfer, writing each batch as it arrives from Jacad. Once
+// the header row is sent the status can no longer change, so failures after
+// that point end the response early and are only logged.
+func CreateExportEnrollmentsCSVHandler(client *services.JacadClient, appConfig *config.Config, tracker *jobs.Tracker) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		params := new(requests.FetchEnrollmentsRequest)
+		requestCtx := logging.WithRequestID(c.Context(), requestid.FromContext(c))
+		logger := logging.FromContext(requestCtx)
+
+		if err := c.Bind().Query(params); err != nil {
+			logger.Warn("Handler: Error parsing query params", "error", err)
+			return apierror.Send(c, fiber.StatusBadRequest, apierror.Response{
+				Message: "Invalid query params",
+				Details: err.Error(),
+			})
+		}
+
+		if errs := validateEnrollmentRequest(params, appConfig); len(errs) > 0 {
+			logger.Warn("Handler: Rejecting invalid export request", "error", errs)
+			return validationFailed(c, errs)
+		}
+
+		timeout := params.Timeout(appConfig.FetchTimeout, appConfig.MaxFetchTimeout)
+
+		jobCtx, jobDone, err := startJob(c, tracker, requestCtx, params)
+		if err != nil {
+			logger.Warn("Handler: Rejecting export request", "error", err)
+			return jobStartFailed(c, err)
+		}
+
+		filename := fmt.Sprintf("matriculas-%d-%s.csv", params.IdPeriodoLetivo, time.Now().Format("20060102-150405"))
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+		logger.Info("Handler: Starting enrollment export", "format", "csv", "idPeriodoLetivo", params.IdPeriodoLetivo)
+		return c.Status(fiber.StatusOK).SendStreamWriter(func(w *bufio.Writer) {
+			defer jobDone()
+
+			ctx, cancel := context.WithTimeout(jobCtx, timeout)
+			defer cancel()
+
+			rows, err := client.WriteEnrollmentsCSV(ctx, params, w)
+			if err != nil {
+				logger.Error("Handler: Streamed CSV export ended early", "rows", rows, "error", err)
+				return
+			}
+			logger.Info("Handler: Streamed CSV export completed", "rows", rows)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestExportEnrollmentsCSV(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.PageSize = 50
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	client := services.NewJacadClient(&cfg, services.NewFakeSheetWriter(), nil, nil, nil)
+	app := fiber.New()
+	app.Get("/export/enrollments.csv", CreateExportEnrollmentsCSVHandler(client, &cfg, jobs.NewTracker(1)))
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/export/enrollments.csv?idPeriodoLetivo=87&statusMatricula=ATIVA&orgIds=20,17", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != fiber.StatusOK || !strings.HasPrefix(resp.Header.Get(fiber.HeaderContentType), "text/csv") {
+		t.Fatalf("response = %d %s, want 200 text/csv", resp.StatusCode, resp.Header.Get(fiber.HeaderContentType))
+	}
+	if disposition := resp.Header.Get(fiber.HeaderContentDisposition); !strings.Contains(disposition, `filename="matriculas-87-`) {
+		t.Errorf("Content-Disposition = %q, want a matriculas-87 attachment", disposition)
+	}
+	if resp.Header.Get(HeaderJobID) == "" {
+		t.Error("export did not report its job id")
+	}
+	if lines := strings.Count(string(body), "\n"); lines != 121 {
+		t.Errorf("CSV has %d lines, want the header and 120 enrollments", lines)
+	}
+}
+
+func TestExportEnrollmentsCSVRejectsInvalidRequest(t *testing.T) {
+	cfg := config.Defaults()
+	client := services.NewJacadClient(&cfg, services.NewFakeSheetWriter(), nil, nil, nil)
+	app := fiber.New()
+	app.Get("/export/enrollments.csv", CreateExportEnrollmentsCSVHandler(client, &cfg, jobs.NewTracker(1)))
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/export/enrollments.csv?mode=sometimes", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", resp.StatusCode)
+	}
+}
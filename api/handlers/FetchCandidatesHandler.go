@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+)
+
+func CreateFetchCandidatesHandler(candidates *services.CandidatesService, appConfig *config.Config, tracker *jobs.Tracker) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		params := new(requests.FetchCandidatesRequest)
+		requestCtx := logging.WithRequestID(c.Context(), requestid.FromContext(c))
+		logger := logging.FromContext(requestCtx)
+
+		if err := c.Bind().Query(params); err != nil {
+			logger.Warn("Handler: Error parsing query params", "error", err)
+			return apierror.Send(c, fiber.StatusBadRequest, apierror.Response{
+				Message: "Invalid query params",
+				Details: err.Error(),
+			})
+		}
+
+		if errs := params.Validate(appConfig); len(errs) > 0 {
+			logger.Warn("Handler: Rejecting invalid candidates request", "error", errs)
+			return validationFailed(c, errs)
+		}
+
+		jobCtx, jobDone, err := startJob(c, tracker, requestCtx, params)
+		if err != nil {
+			logger.Warn("Handler: Rejecting candidates request", "error", err)
+			return jobStartFailed(c, err)
+		}
+		defer jobDone()
+
+		ctx, cancel := context.WithTimeout(jobCtx, appConfig.FetchTimeout)
+		defer cancel()
+
+		logger.Info("Handler: Starting candidate fetch")
+		result, err := candidates.FetchCandidates(ctx, params)
+		if err != nil {
+			logger.Error("Handler: Error during candidate fetch", "error", err)
+			return apierror.Send(c, fiber.StatusInternalServerError, apierror.Response{
+				Message: "Failed to fetch candidates",
+				Details: err.Error(),
+				Result:  result,
+			})
+		}
+
+		if params.DryRun {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"message": "Dry run completed. No sheets were written.",
+				"result":  result,
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Candidates fetched and written to sheets successfully!",
+			"result":  result,
+		})
+	}
+}
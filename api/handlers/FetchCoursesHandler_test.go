@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestFetchCourses(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(30)})
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	writer := services.NewFakeSheetWriter()
+	client := services.NewJacadClient(&cfg, writer, nil, nil, nil)
+	app := fiber.New()
+	app.Get("/fetch-courses", CreateFetchCoursesHandler(services.NewCoursesService(client), &cfg, jobs.NewTracker(1)))
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantRows   int
+	}{
+		{name: "one organization", query: "?orgId=17", wantStatus: fiber.StatusOK, wantRows: 2},
+		{name: "unknown organization", query: "?orgId=4242", wantStatus: fiber.StatusUnprocessableEntity},
+		{name: "unknown tenant", query: "?tenant=nowhere", wantStatus: fiber.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/fetch-courses"+tt.query, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != fiber.StatusOK {
+				return
+			}
+			var body struct {
+				Result services.SheetResult `json:"result"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Result.Sheet != cfg.CoursesSheet || body.Result.Rows != tt.wantRows {
+				t.Errorf("result = %+v, want %d rows in %q", body.Result, tt.wantRows, cfg.CoursesSheet)
+			}
+			if sheet, _ := writer.Sheet(cfg.CoursesSheet); len(sheet.Rows) != tt.wantRows {
+				t.Errorf("sheet holds %d rows, want %d", len(sheet.Rows), tt.wantRows)
+			}
+		})
+	}
+}
@@ -2,72 +2,220 @@ package handlers
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"time"
 
 	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
 	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
 	"github.com/SamuelLeutner/fetch-student-data/services"
 	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
 )
 
-func CreateFetchEnrollmentsHandler(client *services.JacadClient, appConfig *config.Config) fiber.Handler {
+type fetchOutcome struct {
+	result *services.FetchResult
+	err    error
+}
+
+// CreateFetchEnrollmentsHandler serves GET requests with query parameters and
+// POST requests with a JSON body, which also accepts the list filters. The
+// server-generated job ID is returned in the X-Job-ID header. A job that can
+// start right away is awaited and answered with its result; one that has to
+// wait for a free slot is answered at once with 202 and its queue position,
+// and runs in the background, followed through /jobs/:id.
+func CreateFetchEnrollmentsHandler(client *services.JacadClient, appConfig *config.Config, tracker *jobs.Tracker) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		params := new(requests.FetchEnrollmentsRequest)
+		requestCtx := logging.WithRequestID(c.Context(), requestid.FromContext(c))
+		logger := logging.FromContext(requestCtx)
+
+		if c.Method() == fiber.MethodPost {
+			if err := c.Bind().JSON(params); err != nil {
+				logger.Warn("Handler: Error parsing request body", "error", err)
+				return apierror.Send(c, fiber.StatusBadRequest, apierror.Response{
+					Message: "Invalid request body",
+					Details: err.Error(),
+				})
+			}
+		} else if err := c.Bind().Query(params); err != nil {
+			logger.Warn("Handler: Error parsing query params", "error", err)
+			return apierror.Send(c, fiber.StatusBadRequest, apierror.Response{
+				Message: "Invalid query params",
+				Details: err.Error(),
+			})
+		}
+
+		if errs := validateEnrollmentRequest(params, appConfig); len(errs) > 0 {
+			logger.Warn("Handler: Rejecting invalid fetch request", "error", errs)
+			return validationFailed(c, errs)
+		}
 
-		if err := c.Bind().Query(params); err != nil {
-			log.Printf("Handler: Error parsing request body: %v", err)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"message": "Invalid query params",
-				"details": err.Error(),
+		timeout := params.Timeout(appConfig.FetchTimeout, appConfig.MaxFetchTimeout)
+
+		// The job may outlive the request when it is queued, so it does not
+		// inherit the request's cancellation.
+		pending, err := tracker.Enqueue(jobs.WithDescription(context.WithoutCancel(requestCtx), jobDescription(c, params)))
+		if err != nil {
+			logger.Warn("Handler: Rejecting fetch request", "error", err)
+			return jobStartFailed(c, err)
+		}
+		c.Set(HeaderJobID, pending.ID)
+		logger = logger.With("jobId", pending.ID)
+
+		if pending.Position > 0 {
+			go runQueuedFetch(client, tracker, pending, params, timeout, jobRetryPolicy(appConfig))
+			status, _ := tracker.Status(pending.ID)
+			return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+				"message": "Fetch queued. Follow it at /api/v1/jobs/" + pending.ID + ".",
+				"jobId":   pending.ID,
+				"job":     status,
 			})
 		}
 
-		ctx, cancel := context.WithTimeout(c.Context(), 10*time.Minute)
+		jobCtx, jobDone, err := pending.Wait()
+		if err != nil {
+			logger.Warn("Handler: Rejecting fetch request", "error", err)
+			return jobStartFailed(c, err)
+		}
+
+		ctx, cancel := context.WithTimeout(jobCtx, timeout)
 		defer cancel()
 
-		log.Printf("Handler: Starting enrollment fetch operation for PeriodoLetivo %d...", params.IdPeriodoLetivo)
-		errChan := make(chan error, 1)
+		logger.Info("Handler: Starting enrollment fetch operation", "idPeriodoLetivo", params.PeriodLabel(), "statusMatricula", params.StatusLabel(), "queries", params.QueryCount(), "timeout", timeout.String())
+		resultChan := make(chan fetchOutcome, 1)
 
 		go func() {
-			log.Println("Handler Goroutine: Starting client.FetchEnrollmentsFiltered...")
-			err := client.FetchEnrollmentsFiltered(ctx, params)
-			log.Println("Handler Goroutine: client.FetchEnrollmentsFiltered finished.")
-			errChan <- err
+			defer jobDone()
+			logger.Debug("Handler Goroutine: Starting client.FetchEnrollmentsFiltered...")
+			result, err := client.FetchEnrollmentsFiltered(ctx, params)
+			if result != nil {
+				result.TimeoutSeconds = int(timeout.Seconds())
+			}
+			logger.Debug("Handler Goroutine: client.FetchEnrollmentsFiltered finished.")
+			resultChan <- fetchOutcome{result: result, err: err}
 		}()
 
 		select {
 		case <-ctx.Done():
-			log.Printf("Handler: Context cancelled during fetch (timeout/client disconnect): %v", ctx.Err())
+			if errors.Is(context.Cause(ctx), jobs.ErrCancelled) {
+				return apierror.Send(c, fiber.StatusConflict, apierror.Response{
+					Message: "Fetch operation was cancelled through the jobs API",
+					Details: context.Cause(ctx).Error(),
+					JobID:   pending.ID,
+				})
+			}
+			logger.Warn("Handler: Context cancelled during fetch (timeout/client disconnect)", "error", ctx.Err())
 
 			select {
-			case fetchErr := <-errChan:
-				if fetchErr != nil {
-					log.Printf("Handler: Fetch goroutine finished with error: %v", fetchErr)
-					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-						"message": "Fetch operation was cancelled and ended with error",
-						"details": fetchErr.Error(),
+			case outcome := <-resultChan:
+				if outcome.err != nil {
+					logger.Error("Handler: Fetch goroutine finished with error", "error", outcome.err)
+					return apierror.Send(c, fiber.StatusInternalServerError, apierror.Response{
+						Message: "Fetch operation was cancelled and ended with error",
+						Details: outcome.err.Error(),
+						JobID:   pending.ID,
+						Result:  outcome.result,
 					})
 				}
 			default:
 			}
-			return c.Status(fiber.StatusRequestTimeout).JSON(fiber.Map{
-				"message": "Fetch operation timed out or was cancelled by client",
-				"details": ctx.Err().Error(),
+			return apierror.Send(c, fiber.StatusRequestTimeout, apierror.Response{
+				Message: "Fetch operation timed out or was cancelled by client",
+				Details: fmt.Sprintf("%s (timeout %s)", ctx.Err(), timeout),
+				JobID:   pending.ID,
 			})
-		case fetchErr := <-errChan:
-			if fetchErr != nil {
-				log.Printf("Handler: Error during enrollment fetch: %v", fetchErr)
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"message": "Failed to fetch enrollments",
-					"details": fetchErr.Error(),
+		case outcome := <-resultChan:
+			if outcome.err != nil {
+				logger.Error("Handler: Error during enrollment fetch", "error", outcome.err)
+				return apierror.Send(c, fiber.StatusInternalServerError, apierror.Response{
+					Message: "Failed to fetch enrollments",
+					Details: outcome.err.Error(),
+					JobID:   pending.ID,
+					Result:  outcome.result,
+				})
+			}
+
+			if params.DryRun {
+				logger.Info("Handler: Dry run completed successfully. Sending preview response.")
+				return c.Status(fiber.StatusOK).JSON(fiber.Map{
+					"message": "Dry run completed. No sheets were written.",
+					"jobId":   pending.ID,
+					"result":  outcome.result,
 				})
 			}
 
-			log.Println("Handler: Enrollment fetch completed successfully. Sending OK response.")
+			logger.Info("Handler: Enrollment fetch completed successfully. Sending OK response.")
 			return c.Status(fiber.StatusOK).JSON(fiber.Map{
 				"message": "Enrollments fetched and written to sheet successfully!",
+				"jobId":   pending.ID,
+				"result":  outcome.result,
 			})
 		}
 	}
 }
+
+// jobRetryPolicy is the retry policy of queued fetches.
+func jobRetryPolicy(appConfig *config.Config) jobs.RetryPolicy {
+	return jobs.RetryPolicy{
+		MaxAttempts: appConfig.JobRetryMaxAttempts,
+		Backoff:     appConfig.JobRetryBackoff,
+		MaxBackoff:  appConfig.JobRetryMaxBackoff,
+	}
+}
+
+// runQueuedFetch waits for pending's slot and runs the fetch with no caller
+// waiting; its outcome is reported through progress, history and
+// notifications. A failed fetch is enqueued again as a new job under retry,
+// and only its last failure is notified. Cancelling the failed job while it
+// waits out its backoff, or shutting down, drops the retry.
+func runQueuedFetch(client *services.JacadClient, tracker *jobs.Tracker, pending *jobs.Pending, params *requests.FetchEnrollmentsRequest, timeout time.Duration, retry jobs.RetryPolicy) {
+	attempt := jobs.Attempt{Number: 1, Max: retry.MaxAttempts, FirstID: pending.ID}
+	for {
+		jobCtx, willRetry := runFetchAttempt(client, pending, params, timeout, attempt)
+		if !willRetry {
+			return
+		}
+
+		delay := retry.Delay(attempt.Number)
+		logger := logging.FromContext(jobCtx)
+		logger.Warn("Handler: Queued enrollment fetch failed. Retrying later.", "attempt", attempt.Number, "maxAttempts", attempt.Max, "retryIn", delay.String())
+		if err := tracker.WaitRetry(pending.ID, delay); err != nil {
+			logger.Warn("Handler: Dropping the retry of a failed fetch", "firstJobId", attempt.FirstID, "reason", err)
+			return
+		}
+
+		var err error
+		if pending, err = tracker.Enqueue(context.WithoutCancel(jobCtx)); err != nil {
+			logger.Error("Handler: Could not enqueue the retry of a failed fetch", "firstJobId", attempt.FirstID, "error", err)
+			return
+		}
+		attempt.Number++
+		logger.Info("Handler: Retry of failed fetch enqueued", "firstJobId", attempt.FirstID, "retryJobId", pending.ID, "attempt", attempt.Number)
+	}
+}
+
+// runFetchAttempt runs one attempt of a queued fetch and reports whether it
+// failed in a way retry allows to be tried again.
+func runFetchAttempt(client *services.JacadClient, pending *jobs.Pending, params *requests.FetchEnrollmentsRequest, timeout time.Duration, attempt jobs.Attempt) (context.Context, bool) {
+	jobCtx, jobDone, err := pending.Wait()
+	if err != nil {
+		logging.FromContext(jobCtx).Warn("Queued fetch did not start", "jobId", pending.ID, "error", err)
+		return nil, false
+	}
+	defer jobDone()
+
+	ctx, cancel := context.WithTimeout(jobs.WithAttempt(jobCtx, attempt), timeout)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+	logger.Info("Handler: Starting queued enrollment fetch", "idPeriodoLetivo", params.PeriodLabel(), "statusMatricula", params.StatusLabel(), "timeout", timeout.String(), "attempt", attempt.Number)
+	if _, err := client.FetchEnrollmentsFiltered(ctx, params); err != nil {
+		logger.Error("Handler: Error during queued enrollment fetch", "error", err)
+		return jobCtx, attempt.WillRetry(ctx, err)
+	}
+	logger.Info("Handler: Queued enrollment fetch completed")
+	return jobCtx, false
+}
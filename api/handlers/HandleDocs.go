@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// swaggerUIVersion pins the Swagger UI assets loaded from the CDN.
+const swaggerUIVersion = "5.17.14"
+
+// CreateOpenAPIHandler serves the prebuilt OpenAPI document as JSON.
+func CreateOpenAPIHandler(spec map[string]interface{}) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return c.JSON(spec)
+	}
+}
+
+// CreateDocsHandler serves a Swagger UI page rendering the spec at specURL.
+func CreateDocsHandler(specURL string) fiber.Handler {
+	page := fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>fetch-student-data API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@%[1]s/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@%[1]s/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: %[2]q, dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`, swaggerUIVersion, specURL)
+
+	return func(c fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.SendString(page)
+	}
+}
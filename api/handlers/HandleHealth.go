@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+)
+
+// HandleHealthz is the liveness probe: it only confirms the process serves HTTP.
+func HandleHealthz(c fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"status": "ok",
+	})
+}
+
+// CreateReadyzHandler reports the cached readiness checks and fails while the
+// server is draining so no new traffic is routed to it.
+func CreateReadyzHandler(probe *services.ReadinessProbe, tracker *jobs.Tracker) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if tracker.Draining() {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"status": "shutting_down",
+			})
+		}
+
+		ready, checks, checkedAt := probe.Status()
+		response := fiber.Map{
+			"status": "ready",
+			"checks": checks,
+		}
+		if !checkedAt.IsZero() {
+			response["checkedAt"] = checkedAt
+		}
+		if !ready {
+			response["status"] = "not_ready"
+			return c.Status(fiber.StatusServiceUnavailable).JSON(response)
+		}
+		return c.JSON(response)
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+)
+
+// newReadyzApp serves /readyz from a probe of a client of server that logs
+// in with userToken.
+func newReadyzApp(t *testing.T, server *jacadmock.Server, userToken string) (*fiber.App, *services.ReadinessProbe, *jobs.Tracker) {
+	t.Helper()
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = userToken
+	cfg.RetryDelay = 0
+	client := services.NewJacadClient(&cfg, services.NewDiscardWriter(), nil, nil, nil)
+	probe := services.NewReadinessProbe(client, time.Hour, 5*time.Second)
+	tracker := jobs.NewTracker(1)
+
+	app := fiber.New()
+	app.Get("/healthz", HandleHealthz)
+	app.Get("/readyz", CreateReadyzHandler(probe, tracker))
+	return app, probe, tracker
+}
+
+// runProbe starts probe and waits for its first run.
+func runProbe(t *testing.T, probe *services.ReadinessProbe) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go probe.Run(ctx)
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if _, _, checkedAt := probe.Status(); !checkedAt.IsZero() {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("readiness probe did not run")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func getStatus(t *testing.T, app *fiber.App, path string) (int, string) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body.Status
+}
+
+func TestReadyz(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{})
+	defer server.Close()
+
+	app, probe, tracker := newReadyzApp(t, server, server.UserToken)
+	if code, status := getStatus(t, app, "/healthz"); code != fiber.StatusOK || status != "ok" {
+		t.Errorf("/healthz = %d %q, want 200 ok", code, status)
+	}
+	if code, status := getStatus(t, app, "/readyz"); code != fiber.StatusServiceUnavailable || status != "not_ready" {
+		t.Errorf("/readyz before the first check = %d %q, want 503 not_ready", code, status)
+	}
+
+	runProbe(t, probe)
+	if code, status := getStatus(t, app, "/readyz"); code != fiber.StatusOK || status != "ready" {
+		t.Errorf("/readyz = %d %q, want 200 ready", code, status)
+	}
+
+	if err := tracker.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if code, status := getStatus(t, app, "/readyz"); code != fiber.StatusServiceUnavailable || status != "shutting_down" {
+		t.Errorf("/readyz while draining = %d %q, want 503 shutting_down", code, status)
+	}
+	if code, _ := getStatus(t, app, "/healthz"); code != fiber.StatusOK {
+		t.Errorf("/healthz while draining = %d, want 200", code)
+	}
+}
+
+func TestReadyzFailsWhenJacadRejectsLogin(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{})
+	defer server.Close()
+
+	app, probe, _ := newReadyzApp(t, server, "wrong-token")
+	runProbe(t, probe)
+	if code, status := getStatus(t, app, "/readyz"); code != fiber.StatusServiceUnavailable || status != "not_ready" {
+		t.Errorf("/readyz = %d %q, want 503 not_ready", code, status)
+	}
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"bytes"
+
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/metrics"
+	"github.com/gofiber/fiber/v3"
+)
+
+func HandleMetrics(c fiber.Ctx) error {
+	var buf bytes.Buffer
+	if err := metrics.Default.WriteText(&buf); err != nil {
+		return apierror.Send(c, fiber.StatusInternalServerError, apierror.Response{
+			Message: "Failed to render metrics",
+			Details: err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	return c.Send(buf.Bytes())
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+)
+
+// sseHeartbeatInterval keeps idle connections open through proxies.
+const sseHeartbeatInterval = 15 * time.Second
+
+// CreateJobEventsHandler streams a fetch job's progress as Server-Sent Events.
+// The job ID is returned in the X-Job-ID header, and the body when queued, of
+// the fetch call. Each update is a "progress" event; the stream ends with a
+// "done" or "failed" event.
+func CreateJobEventsHandler(hub *services.ProgressHub) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		jobID := c.Params("id")
+		events, unsubscribe, ok := hub.Subscribe(jobID)
+		if !ok {
+			return apierror.Send(c, fiber.StatusNotFound, apierror.Response{
+				Message: "Job not found",
+				Details: fmt.Sprintf("no running or recently finished job with id '%s'", jobID),
+			})
+		}
+
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		c.Set(fiber.HeaderConnection, "keep-alive")
+		c.Set("X-Accel-Buffering", "no")
+
+		return c.Status(fiber.StatusOK).SendStreamWriter(func(w *bufio.Writer) {
+			defer unsubscribe()
+
+			heartbeat := time.NewTicker(sseHeartbeatInterval)
+			defer heartbeat.Stop()
+
+			for {
+				select {
+				case event, open := <-events:
+					if !open {
+						return
+					}
+					if err := writeProgressEvent(w, event); err != nil {
+						return
+					}
+				case <-heartbeat.C:
+					if _, err := w.WriteString(": keep-alive\n\n"); err != nil {
+						return
+					}
+					if err := w.Flush(); err != nil {
+						return
+					}
+				}
+			}
+		})
+	}
+}
+
+func writeProgressEvent(w *bufio.Writer, event services.ProgressEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	name := "progress"
+	if event.Finished() {
+		name = event.Stage
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestJobEvents(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(100)})
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	client := services.NewJacadClient(&cfg, services.NewFakeSheetWriter(), nil, nil, nil)
+	ctx := logging.WithJobID(context.Background(), "job-1")
+	if _, err := client.FetchEnrollmentsFiltered(ctx, &requests.FetchEnrollmentsRequest{IdPeriodoLetivo: 87, DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Get("/jobs/:id/events", CreateJobEventsHandler(client.Progress()))
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/jobs/job-1/events", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get(fiber.HeaderContentType) != "text/event-stream" {
+		t.Fatalf("response = %d %s, want 200 text/event-stream", resp.StatusCode, resp.Header.Get(fiber.HeaderContentType))
+	}
+	if !strings.HasPrefix(string(body), "event: done\ndata: {") || !strings.Contains(string(body), `"jobId":"job-1"`) {
+		t.Errorf("stream = %q, want the job's done event", body)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/jobs/unknown/events", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("unknown job status = %d, want 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+)
+
+// HeaderJobID carries the server-generated ID of the job a request started.
+const HeaderJobID = "X-Job-ID"
+
+// CreateJobStatusHandler reports whether a job is queued, with its position,
+// or running, with its latest progress. Recently finished fetches only report
+// their final progress. The job ID is the one returned in the X-Job-ID header
+// of the call that started it.
+func CreateJobStatusHandler(tracker *jobs.Tracker, hub *services.ProgressHub) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		jobID := c.Params("id")
+		status, tracked := tracker.Status(jobID)
+		progress, reported := hub.Latest(jobID)
+		if !tracked && !reported {
+			return apierror.Send(c, fiber.StatusNotFound, apierror.Response{
+				Message: "Job not found",
+				Details: fmt.Sprintf("no queued, running or recently finished job with id '%s'", jobID),
+			})
+		}
+
+		response := fiber.Map{}
+		if tracked {
+			response["job"] = status
+		}
+		if reported {
+			response["progress"] = progress
+		}
+		return c.JSON(response)
+	}
+}
+
+// startJob enqueues a job listed with the request's route and params, returns
+// its ID in the X-Job-ID header and waits for a slot; see
+// jobs.Tracker.Enqueue.
+func startJob(c fiber.Ctx, tracker *jobs.Tracker, ctx context.Context, params any) (context.Context, func(), error) {
+	pending, err := tracker.Enqueue(jobs.WithDescription(ctx, jobDescription(c, params)))
+	if err != nil {
+		return nil, nil, err
+	}
+	c.Set(HeaderJobID, pending.ID)
+	return pending.Wait()
+}
+
+// jobDescription lists a job in GET /queue under the route that started it.
+func jobDescription(c fiber.Ctx, params any) jobs.Description {
+	return jobs.Description{Kind: c.Method() + " " + c.Route().Path, Params: params}
+}
+
+// jobStartFailed responds to a job the tracker did not start: 503 while the
+// server drains, 409 when the job was cancelled while queued, 408 when the
+// caller went away while the job was queued.
+func jobStartFailed(c fiber.Ctx, err error) error {
+	if errors.Is(err, jobs.ErrShuttingDown) {
+		return apierror.Send(c, fiber.StatusServiceUnavailable, apierror.Response{
+			Message: "Server is shutting down",
+			Details: err.Error(),
+		})
+	}
+	if errors.Is(err, jobs.ErrCancelled) {
+		return apierror.Send(c, fiber.StatusConflict, apierror.Response{
+			Message: "Job was cancelled while waiting in the job queue",
+			Details: err.Error(),
+		})
+	}
+	return apierror.Send(c, fiber.StatusRequestTimeout, apierror.Response{
+		Message: "Request was cancelled while waiting in the job queue",
+		Details: err.Error(),
+	})
+}
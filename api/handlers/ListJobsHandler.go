@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+)
+
+const defaultJobHistoryLimit = 100
+
+// CreateListJobsHandler lists finished jobs from the history, newest first.
+func CreateListJobsHandler(history services.JobHistoryStore) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		params := new(requests.ListJobsRequest)
+		logger := logging.FromContext(logging.WithRequestID(c.Context(), requestid.FromContext(c)))
+
+		if err := c.Bind().Query(params); err != nil {
+			logger.Warn("Handler: Error parsing query params", "error", err)
+			return apierror.Send(c, fiber.StatusBadRequest, apierror.Response{
+				Message: "Invalid query params",
+				Details: err.Error(),
+			})
+		}
+		if errs := params.Validate(); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		since, _ := params.SinceTime()
+		limit := params.Limit
+		if limit == 0 {
+			limit = defaultJobHistoryLimit
+		}
+		records, err := history.List(services.JobHistoryFilter{Since: since, Job: params.Job, Sheet: params.Sheet, Limit: limit})
+		if err != nil {
+			logger.Error("Handler: Error reading job history", "error", err)
+			return apierror.Send(c, fiber.StatusInternalServerError, apierror.Response{
+				Message: "Failed to read job history",
+				Details: err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"message": "Job history listed",
+			"result":  records,
+		})
+	}
+}
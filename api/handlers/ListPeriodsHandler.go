@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+)
+
+// CreateListPeriodsHandler lists the periodos letivos with open process
+// notices so clients can offer them before starting a fetch.
+func CreateListPeriodsHandler(client *services.JacadClient, appConfig *config.Config) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		params := new(requests.ListPeriodsRequest)
+		requestCtx := logging.WithRequestID(c.Context(), requestid.FromContext(c))
+		logger := logging.FromContext(requestCtx)
+
+		if err := c.Bind().Query(params); err != nil {
+			logger.Warn("Handler: Error parsing query params", "error", err)
+			return apierror.Send(c, fiber.StatusBadRequest, apierror.Response{
+				Message: "Invalid query params",
+				Details: err.Error(),
+			})
+		}
+		if errs := params.Validate(appConfig); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		ctx, cancel := context.WithTimeout(services.WithTenant(requestCtx, params.Tenant), appConfig.FetchTimeout)
+		defer cancel()
+		if params.BypassCache {
+			ctx = services.WithCacheBypass(ctx)
+		}
+
+		periods, err := client.ListPeriods(ctx, params.IdOrg)
+		if err != nil {
+			logger.Error("Handler: Error listing periods", "error", err)
+			return apierror.Send(c, fiber.StatusInternalServerError, apierror.Response{
+				Message: "Failed to list periods",
+				Details: err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"message": "Periods listed",
+			"result":  periods,
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/gofiber/fiber/v3"
+)
+
+// CreateListPresetsHandler lists the presets fetch-enrollments accepts in its
+// preset parameter, keyed by name.
+func CreateListPresetsHandler(appConfig *config.Config) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		presets := appConfig.Presets
+		if presets == nil {
+			presets = map[string]config.Preset{}
+		}
+		return c.JSON(fiber.Map{
+			"message": "Presets listed",
+			"result":  presets,
+		})
+	}
+}
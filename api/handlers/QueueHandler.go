@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/gofiber/fiber/v3"
+)
+
+// CreateQueueHandler lists the running and queued jobs with their parameters,
+// elapsed time and queue position, and whether a job started now would run
+// straight away.
+func CreateQueueHandler(tracker *jobs.Tracker) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"message": "Job queue listed",
+			"result":  tracker.Queue(),
+		})
+	}
+}
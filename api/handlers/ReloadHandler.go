@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+)
+
+// CreateReloadHandler reloads the organizations from their configured source
+// without restarting the server.
+func CreateReloadHandler(client *services.JacadClient) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		ctx := logging.WithRequestID(c.Context(), requestid.FromContext(c))
+		logger := logging.FromContext(ctx)
+
+		logger.Info("Handler: Reloading organizations")
+		orgs, err := client.ReloadOrganizations(ctx)
+		if err != nil {
+			logger.Error("Handler: Error reloading organizations", "error", err)
+			return apierror.Send(c, fiber.StatusInternalServerError, apierror.Response{
+				Message: "Failed to reload organizations",
+				Details: err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Organizations reloaded successfully!",
+			"result":  orgs,
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orgs.json")
+	cfg := config.Defaults()
+	cfg.OrganizationsSource = config.OrgSourceFile
+	cfg.OrganizationsFile = path
+	client := services.NewJacadClient(&cfg, services.NewFakeSheetWriter(), nil, nil, nil)
+	app := fiber.New()
+	app.Post("/admin/reload", CreateReloadHandler(client))
+
+	reload := func() (int, []config.Organization) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body struct {
+			Result []config.Organization `json:"result"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body.Result
+	}
+
+	if status, _ := reload(); status != fiber.StatusInternalServerError {
+		t.Errorf("reload without the file = %d, want 500", status)
+	}
+	if _, ok := cfg.Organizations.ByID(20); !ok {
+		t.Error("failed reload dropped the current organizations")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"organizations": [{"id": 31, "name": "Escola Técnica"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	status, orgs := reload()
+	if status != fiber.StatusOK || len(orgs) != 1 || orgs[0].ID != 31 {
+		t.Errorf("reload = %d %+v, want organization 31", status, orgs)
+	}
+	if _, ok := cfg.Organizations.ByID(20); ok {
+		t.Error("reload kept an organization the file no longer lists")
+	}
+}
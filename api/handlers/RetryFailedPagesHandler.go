@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+)
+
+// CreateRetryFailedPagesHandler refetches the pages a finished fetch lost and
+// merges their rows into its sheets. It runs as a job of its own.
+func CreateRetryFailedPagesHandler(client *services.JacadClient, appConfig *config.Config, tracker *jobs.Tracker) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		jobID := c.Params("id")
+		requestCtx := logging.WithRequestID(c.Context(), requestid.FromContext(c))
+		logger := logging.FromContext(requestCtx).With("retryJobId", jobID)
+
+		jobCtx, jobDone, err := startJob(c, tracker, requestCtx, fiber.Map{"jobId": jobID})
+		if err != nil {
+			logger.Warn("Handler: Rejecting failed page retry", "error", err)
+			return jobStartFailed(c, err)
+		}
+		defer jobDone()
+
+		ctx, cancel := context.WithTimeout(jobCtx, appConfig.FetchTimeout)
+		defer cancel()
+
+		logger.Info("Handler: Retrying failed pages")
+		result, err := client.RetryFailedPages(ctx, jobID)
+		switch {
+		case errors.Is(err, services.ErrJobNotFound):
+			return apierror.Send(c, fiber.StatusNotFound, apierror.Response{
+				Message: "Job not found",
+				Details: err.Error(),
+			})
+		case errors.Is(err, services.ErrRetryNotSupported):
+			return apierror.Send(c, fiber.StatusBadRequest, apierror.Response{
+				Message: "Job pages cannot be retried",
+				Details: err.Error(),
+			})
+		case err != nil:
+			logger.Error("Handler: Error retrying failed pages", "error", err)
+			return apierror.Send(c, fiber.StatusInternalServerError, apierror.Response{
+				Message: "Failed to retry failed pages",
+				Details: err.Error(),
+				Result:  result,
+			})
+		}
+
+		message := "Failed pages fetched and merged into the sheets successfully!"
+		if result.Retried == 0 {
+			message = "Job has no failed pages to retry."
+		} else if len(result.FailedPages) > 0 {
+			message = "Some pages failed again; retry the job later."
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": message,
+			"result":  result,
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestRetryFailedPages(t *testing.T) {
+	cfg := config.Defaults()
+	client := services.NewJacadClient(&cfg, services.NewFakeSheetWriter(), nil, nil, nil)
+	history, err := services.NewSQLiteJobHistoryStore(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	client.History = history
+	for _, record := range []services.JobRecord{
+		{ID: "complete", Job: "fetch-enrollments", Status: "success"},
+		{ID: "courses", Job: "fetch-courses", FailedPages: []services.FailedPage{{Page: 1, PageSize: 50}}},
+	} {
+		if err := history.Record(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app := fiber.New()
+	app.Post("/jobs/:id/retry-failed-pages", CreateRetryFailedPagesHandler(client, &cfg, jobs.NewTracker(1)))
+
+	tests := []struct {
+		job         string
+		wantStatus  int
+		wantMessage string
+	}{
+		{job: "unknown", wantStatus: fiber.StatusNotFound, wantMessage: "Job not found"},
+		{job: "courses", wantStatus: fiber.StatusBadRequest, wantMessage: "Job pages cannot be retried"},
+		{job: "complete", wantStatus: fiber.StatusOK, wantMessage: "Job has no failed pages to retry."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.job, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/jobs/"+tt.job+"/retry-failed-pages", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var body struct {
+				Message string `json:"message"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || body.Message != tt.wantMessage {
+				t.Errorf("response = %d %q, want %d %q", resp.StatusCode, body.Message, tt.wantStatus, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+)
+
+// CreateSheetStatusHandler reports when each sheet was last refreshed and
+// which ones are stale, for data-freshness warnings on dashboards.
+func CreateSheetStatusHandler(client *services.JacadClient, appConfig *config.Config) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		params := new(requests.SheetStatusRequest)
+		logger := logging.FromContext(logging.WithRequestID(c.Context(), requestid.FromContext(c)))
+
+		if err := c.Bind().Query(params); err != nil {
+			logger.Warn("Handler: Error parsing query params", "error", err)
+			return apierror.Send(c, fiber.StatusBadRequest, apierror.Response{
+				Message: "Invalid query params",
+				Details: err.Error(),
+			})
+		}
+		if errs := params.Validate(); len(errs) > 0 {
+			return validationFailed(c, errs)
+		}
+
+		report, err := client.SheetStatuses(c.Context(), params.StaleAfter(appConfig.SheetStaleAfter), time.Now())
+		if err != nil {
+			logger.Error("Handler: Error reading sheet refreshes", "error", err)
+			return apierror.Send(c, fiber.StatusInternalServerError, apierror.Response{
+				Message: "Failed to read sheet refreshes",
+				Details: err.Error(),
+			})
+		}
+		if params.StaleOnly {
+			stale := report.Sheets[:0]
+			for _, sheet := range report.Sheets {
+				if sheet.Stale {
+					stale = append(stale, sheet)
+				}
+			}
+			report.Sheets = stale
+		}
+		return c.JSON(fiber.Map{
+			"message": "Sheet status listed",
+			"result":  report,
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+)
+
+// validationFailed responds 422 listing every invalid parameter.
+func validationFailed(c fiber.Ctx, errs requests.ValidationErrors) error {
+	return apierror.Send(c, fiber.StatusUnprocessableEntity, apierror.Response{
+		Message: "Invalid request",
+		Details: errs.Error(),
+		Errors:  errs,
+	})
+}
+
+// validateEnrollmentRequest applies the request's preset, then runs its own
+// validation plus the fields and sortBy checks, which need the enrollment
+// model.
+func validateEnrollmentRequest(params *requests.FetchEnrollmentsRequest, appConfig *config.Config) requests.ValidationErrors {
+	if err := params.ApplyPreset(appConfig); err != nil {
+		var errs requests.ValidationErrors
+		errs.Add("preset", err)
+		return errs
+	}
+	errs := params.Validate(appConfig)
+	if err := services.ValidateEnrollmentFields(params.ParseFields(), appConfig.Columns); err != nil {
+		errs.Add("fields", err)
+	}
+	if _, err := services.ParseEnrollmentSort(params.SortBy); err != nil {
+		errs.Add("sortBy", err)
+	}
+	return errs
+}
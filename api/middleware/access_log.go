@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+)
+
+// redactedValue replaces the value of a redacted query parameter.
+const redactedValue = "REDACTED"
+
+// sample decides whether a successful request is logged; tests replace it.
+var sample = func(ratio float64) bool { return ratio >= 1 || rand.Float64() < ratio }
+
+// AccessLog writes one "access" record to logger per request with its method,
+// path, redacted query, status, latency and body sizes. Requests answered
+// with a 4xx or 5xx are always logged; the rest with probability
+// sampleRatio. Query parameters named in redactParams, compared without
+// case, are logged as REDACTED.
+func AccessLog(logger *slog.Logger, sampleRatio float64, redactParams []string) fiber.Handler {
+	redact := make(map[string]bool, len(redactParams))
+	for _, name := range redactParams {
+		redact[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	return func(c fiber.Ctx) error {
+		started := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+		if status < fiber.StatusBadRequest && !sample(sampleRatio) {
+			return err
+		}
+
+		bytesOut := c.Response().Header.ContentLength()
+		if bytesOut < 0 {
+			bytesOut = len(c.Response().Body())
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.String("route", c.Route().Path),
+			slog.Int("status", status),
+			slog.Float64("latencyMs", float64(time.Since(started).Microseconds())/1000),
+			slog.Int("bytesIn", len(c.Body())),
+			slog.Int("bytesOut", bytesOut),
+			slog.String("ip", c.IP()),
+			slog.String("requestId", requestid.FromContext(c)),
+		}
+		if query := redactQuery(string(c.Request().URI().QueryString()), redact); query != "" {
+			attrs = append(attrs, slog.String("query", query))
+		}
+		if keyName, ok := c.Locals(APIKeyNameLocal).(string); ok {
+			attrs = append(attrs, slog.String("apiKey", keyName))
+		}
+		logger.LogAttrs(c.Context(), slog.LevelInfo, "access", attrs...)
+		return err
+	}
+}
+
+// redactQuery re-encodes a raw query string with the values of the redacted
+// parameters replaced. Unparseable queries are dropped rather than logged as
+// sent, since they may still hold a secret.
+func redactQuery(raw string, redact map[string]bool) string {
+	if raw == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return redactedValue
+	}
+	for name, vals := range values {
+		if redact[strings.ToLower(name)] {
+			for i := range vals {
+				vals[i] = redactedValue
+			}
+		}
+	}
+	return values.Encode()
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestAccessLog(t *testing.T) {
+	defer func(original func(float64) bool) { sample = original }(sample)
+	sample = func(float64) bool { return false }
+
+	var out bytes.Buffer
+	app := fiber.New()
+	app.Use(AccessLog(slog.New(slog.NewJSONHandler(&out, nil)), 0.1, []string{"Token"}))
+	app.Get("/ping", func(c fiber.Ctx) error { return c.SendString("pong") })
+	app.Get("/fail", func(c fiber.Ctx) error { return c.Status(fiber.StatusBadGateway).SendString("upstream down") })
+
+	tests := []struct {
+		name   string
+		target string
+		logged bool
+		status float64
+		query  string
+	}{
+		{name: "unsampled success", target: "/ping?dryRun=true"},
+		{name: "failure always logged", target: "/fail?token=s3cret&orgId=3", logged: true, status: 502, query: "orgId=3&token=REDACTED"},
+		{name: "unknown route", target: "/missing", logged: true, status: 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out.Reset()
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if !tt.logged {
+				if out.Len() != 0 {
+					t.Errorf("logged %s, want nothing", out.String())
+				}
+				return
+			}
+			var record map[string]interface{}
+			if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+				t.Fatalf("access log %q is not JSON: %v", out.String(), err)
+			}
+			if record["status"] != tt.status {
+				t.Errorf("status = %v, want %v", record["status"], tt.status)
+			}
+			if query, _ := record["query"].(string); query != tt.query {
+				t.Errorf("query = %q, want %q", query, tt.query)
+			}
+			if strings.Contains(out.String(), "s3cret") {
+				t.Errorf("access log leaks a redacted value: %s", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	HeaderAPIKey    = "X-API-Key"
+	HeaderSignature = "X-Signature"
+	HeaderTimestamp = "X-Timestamp"
+
+	// APIKeyNameLocal is the fiber.Ctx local holding the authenticated key name.
+	APIKeyNameLocal = "apiKeyName"
+	// APIKeyAdminLocal is the fiber.Ctx local set when the key has the admin
+	// scope.
+	APIKeyAdminLocal = "apiKeyAdmin"
+)
+
+// APIKeyAuth authenticates requests with an API key sent in X-API-Key or as a
+// Bearer token. When requireHMAC is set, requests must also carry
+// X-Timestamp and an X-Signature computed as
+// hex(HMAC-SHA256(key, timestamp + "\n" + method + "\n" + path + "\n" + query + "\n" + body)).
+// Rate limits are taken from keys, which the gRPC API shares.
+func APIKeyAuth(keys *services.APIKeyRegistry, requireHMAC bool, maxSkew time.Duration) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		provided := c.Get(HeaderAPIKey)
+		if provided == "" {
+			if auth := c.Get(fiber.HeaderAuthorization); strings.HasPrefix(auth, "Bearer ") {
+				provided = strings.TrimPrefix(auth, "Bearer ")
+			}
+		}
+		if provided == "" {
+			return unauthorized(c, "missing API key")
+		}
+
+		entry := keys.Find(provided)
+		if entry == nil {
+			return unauthorized(c, "invalid API key")
+		}
+
+		if requireHMAC {
+			if err := verifySignature(c, entry.Key, maxSkew); err != nil {
+				return unauthorized(c, err.Error())
+			}
+		}
+
+		if !entry.Allow() {
+			return apierror.Send(c, fiber.StatusTooManyRequests, apierror.Response{
+				Message: "Too many requests",
+				Details: fmt.Sprintf("rate limit of %d requests per minute exceeded for API key '%s'", entry.RequestsPerMinute, entry.Name),
+			})
+		}
+
+		c.Locals(APIKeyNameLocal, entry.Name)
+		c.Locals(APIKeyAdminLocal, entry.Admin)
+		return c.Next()
+	}
+}
+
+// RequireAdmin lets through only requests APIKeyAuth authenticated with a key
+// that has the admin scope. It must run after APIKeyAuth.
+func RequireAdmin() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if admin, _ := c.Locals(APIKeyAdminLocal).(bool); !admin {
+			return apierror.Send(c, fiber.StatusForbidden, apierror.Response{
+				Message: "Forbidden",
+				Details: fmt.Sprintf("API key '%v' does not have the %s scope", c.Locals(APIKeyNameLocal), config.APIKeyScopeAdmin),
+			})
+		}
+		return c.Next()
+	}
+}
+
+func verifySignature(c fiber.Ctx, key string, maxSkew time.Duration) error {
+	timestamp := c.Get(HeaderTimestamp)
+	signature := c.Get(HeaderSignature)
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("missing %s or %s header", HeaderTimestamp, HeaderSignature)
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s header", HeaderTimestamp)
+	}
+	if skew := time.Since(time.Unix(unix, 0)); skew > maxSkew || skew < -maxSkew {
+		return fmt.Errorf("request timestamp outside the allowed window of %s", maxSkew)
+	}
+
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n", timestamp, c.Method(), c.Path(), string(c.Request().URI().QueryString()))
+	mac.Write(c.Body())
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return fmt.Errorf("invalid request signature")
+	}
+	return nil
+}
+
+func unauthorized(c fiber.Ctx, details string) error {
+	return apierror.Send(c, fiber.StatusUnauthorized, apierror.Response{
+		Message: "Unauthorized",
+		Details: details,
+	})
+}
@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/gofiber/fiber/v3"
+)
+
+func newAuthApp(keys []config.APIKey, requireHMAC bool) *fiber.App {
+	app := fiber.New()
+	app.Use(APIKeyAuth(services.NewAPIKeyRegistry(keys), requireHMAC, time.Minute))
+	handler := func(c fiber.Ctx) error {
+		return c.SendString(fmt.Sprint(c.Locals(APIKeyNameLocal)))
+	}
+	app.Get("/api/v1/ping", handler)
+	app.Post("/api/v1/fetch-enrollments", handler)
+	return app
+}
+
+func sign(key, timestamp, method, path, query, body string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", timestamp, method, path, query, body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	keys := []config.APIKey{{Name: "ops", Key: "ops-key"}, {Name: "bi", Key: "bi-key"}}
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+		keyName string
+	}{
+		{name: "missing key", status: fiber.StatusUnauthorized},
+		{name: "invalid key", headers: map[string]string{HeaderAPIKey: "nope"}, status: fiber.StatusUnauthorized},
+		{name: "X-API-Key header", headers: map[string]string{HeaderAPIKey: "bi-key"}, status: fiber.StatusOK, keyName: "bi"},
+		{name: "bearer token", headers: map[string]string{fiber.HeaderAuthorization: "Bearer ops-key"}, status: fiber.StatusOK, keyName: "ops"},
+		{name: "other authorization scheme", headers: map[string]string{fiber.HeaderAuthorization: "Basic ops-key"}, status: fiber.StatusUnauthorized},
+	}
+
+	app := newAuthApp(keys, false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.keyName != "" {
+				body, _ := io.ReadAll(resp.Body)
+				if string(body) != tt.keyName {
+					t.Errorf("authenticated key = %q, want %q", body, tt.keyName)
+				}
+			}
+		})
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	app := fiber.New()
+	app.Use(APIKeyAuth(services.NewAPIKeyRegistry([]config.APIKey{{Name: "ops", Key: "ops-key", Admin: true}, {Name: "bi", Key: "bi-key"}}), false, time.Minute))
+	app.Use(RequireAdmin())
+	app.Post("/api/v1/admin/reload", func(c fiber.Ctx) error {
+		return c.SendString("reloaded")
+	})
+
+	tests := []struct {
+		key    string
+		status int
+	}{
+		{key: "ops-key", status: fiber.StatusOK},
+		{key: "bi-key", status: fiber.StatusForbidden},
+		{key: "", status: fiber.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/reload", nil)
+		req.Header.Set(HeaderAPIKey, tt.key)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("key %q: status = %d, want %d", tt.key, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+func TestAPIKeyAuthSignature(t *testing.T) {
+	const key, body, query = "ops-key", `{"idPeriodoLetivo":42}`, "dryRun=true"
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(2*time.Minute).Unix(), 10)
+	path := "/api/v1/fetch-enrollments"
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		status    int
+	}{
+		{name: "valid signature", timestamp: now, signature: sign(key, now, http.MethodPost, path, query, body), status: fiber.StatusOK},
+		{name: "upper-case hex is accepted", timestamp: now, signature: strings.ToUpper(sign(key, now, http.MethodPost, path, query, body)), status: fiber.StatusOK},
+		{name: "missing signature", timestamp: now, status: fiber.StatusUnauthorized},
+		{name: "non-numeric timestamp", timestamp: "yesterday", signature: sign(key, "yesterday", http.MethodPost, path, query, body), status: fiber.StatusUnauthorized},
+		{name: "timestamp too old", timestamp: stale, signature: sign(key, stale, http.MethodPost, path, query, body), status: fiber.StatusUnauthorized},
+		{name: "timestamp in the future", timestamp: future, signature: sign(key, future, http.MethodPost, path, query, body), status: fiber.StatusUnauthorized},
+		{name: "signed with another key", timestamp: now, signature: sign("other", now, http.MethodPost, path, query, body), status: fiber.StatusUnauthorized},
+		{name: "signature over another body", timestamp: now, signature: sign(key, now, http.MethodPost, path, query, "{}"), status: fiber.StatusUnauthorized},
+		{name: "signature over another query", timestamp: now, signature: sign(key, now, http.MethodPost, path, "", body), status: fiber.StatusUnauthorized},
+		{name: "signature over another method", timestamp: now, signature: sign(key, now, http.MethodGet, path, query, body), status: fiber.StatusUnauthorized},
+	}
+
+	app := newAuthApp([]config.APIKey{{Name: "ops", Key: key}}, true)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, path+"?"+query, strings.NewReader(body))
+			req.Header.Set(HeaderAPIKey, key)
+			req.Header.Set(HeaderTimestamp, tt.timestamp)
+			if tt.signature != "" {
+				req.Header.Set(HeaderSignature, tt.signature)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthRateLimit(t *testing.T) {
+	const perMinute = 30
+	app := newAuthApp([]config.APIKey{{Name: "limited", Key: "limited-key", RequestsPerMinute: perMinute}, {Name: "free", Key: "free-key"}}, false)
+
+	status := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil)
+		req.Header.Set(HeaderAPIKey, key)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	allowed := 0
+	for i := 0; i < perMinute; i++ {
+		switch code := status("limited-key"); code {
+		case fiber.StatusOK:
+			allowed++
+		case fiber.StatusTooManyRequests:
+		default:
+			t.Fatalf("status = %d, want 200 or 429", code)
+		}
+	}
+	// Only the capped burst is available up front, not the whole minute.
+	if allowed == 0 || allowed >= perMinute {
+		t.Errorf("allowed %d of %d immediate requests, want a small burst", allowed, perMinute)
+	}
+	if code := status("free-key"); code != fiber.StatusOK {
+		t.Errorf("key without a limit got status %d, want 200", code)
+	}
+}
+
+func TestAPIKeyAuthSharesRateLimit(t *testing.T) {
+	keys := services.NewAPIKeyRegistry([]config.APIKey{{Name: "shared", Key: "shared-key", RequestsPerMinute: 1}})
+	app := fiber.New()
+	app.Use(APIKeyAuth(keys, false, time.Minute))
+	app.Get("/api/v1/ping", func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	// The gRPC API spends the key's only token.
+	if !keys.Find("shared-key").Allow() {
+		t.Fatal("first call through the registry was not allowed")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil)
+	req.Header.Set(HeaderAPIKey, "shared-key")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429 once the shared budget is spent", resp.StatusCode)
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/SamuelLeutner/fetch-student-data/tracing"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// headerCarrier reads the trace context from request headers.
+type headerCarrier struct {
+	c fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string { return h.c.Get(key) }
+
+func (h headerCarrier) Set(key, value string) { h.c.Set(key, value) }
+
+func (h headerCarrier) Keys() []string {
+	headers := h.c.GetReqHeaders()
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Tracing starts a span for each request, continuing the trace of an incoming
+// traceparent header, and makes it the parent of the spans handlers start
+// from c.Context().
+func Tracing() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		ctx := tracing.Extract(c.Context(), headerCarrier{c})
+		ctx, span := tracing.Start(ctx, c.Method()+" "+c.Path(),
+			attribute.String("http.request.method", c.Method()),
+			attribute.String("url.path", c.Path()),
+			attribute.String("request.id", requestid.FromContext(c)),
+		)
+		c.SetContext(ctx)
+
+		err := c.Next()
+		status := c.Response().StatusCode()
+		span.SetName(c.Method() + " " + c.Route().Path)
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if err == nil && status >= fiber.StatusInternalServerError {
+			err = fmt.Errorf("HTTP %d", status)
+		}
+		tracing.End(span, err)
+		return err
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/tracing"
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	}()
+
+	app := fiber.New()
+	app.Use(Tracing())
+	app.Get("/api/v1/jobs/:id", func(c fiber.Ctx) error {
+		_, span := tracing.Start(c.Context(), "handler")
+		span.End()
+		if c.Params("id") == "broken" {
+			return c.SendStatus(fiber.StatusBadGateway)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/job-1", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/jobs/broken", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("recorded %d spans, want a request and a handler span per request", len(spans))
+	}
+	handler, request := spans[0], spans[1]
+	if request.Name() != "GET /api/v1/jobs/:id" {
+		t.Errorf("request span name = %q, want the route", request.Name())
+	}
+	if got := request.SpanContext().TraceID().String(); got != traceID || !request.Parent().IsRemote() {
+		t.Errorf("request span trace = %s, want the incoming trace %s", got, traceID)
+	}
+	if handler.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Error("handler span is not a child of the request span")
+	}
+	if !hasAttribute(request.Attributes(), attribute.Int("http.response.status_code", fiber.StatusOK)) || request.Status().Code == codes.Error {
+		t.Errorf("request span = %v %+v, want a successful 200", request.Attributes(), request.Status())
+	}
+
+	broken := spans[3]
+	if broken.Parent().IsValid() || broken.SpanContext().TraceID().String() == traceID {
+		t.Error("request without traceparent continued another trace")
+	}
+	if broken.Status().Code != codes.Error || !hasAttribute(broken.Attributes(), attribute.Int("http.response.status_code", fiber.StatusBadGateway)) {
+		t.Errorf("5xx request span = %v %+v, want an error status", broken.Attributes(), broken.Status())
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,352 @@
+// Package openapi builds an OpenAPI 3 document from the request and response
+// types of the HTTP API, so the spec cannot drift from the Go structs.
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operation describes one route. Query is a pointer to the request struct
+// bound from the query string and Body the one bound from a JSON request
+// body; Result is the value returned under "result" in JSON responses. Routes
+// with a non-JSON body set ContentType instead.
+type Operation struct {
+	// Method defaults to GET.
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tag         string
+	Query       interface{}
+	Body        interface{}
+	// PathParams maps path parameter names to their descriptions.
+	PathParams  map[string]string
+	Result      interface{}
+	ContentType string
+	// Raw marks routes whose JSON body is Result itself rather than the
+	// {message, result} envelope.
+	Raw bool
+	// Public routes skip API key authentication.
+	Public bool
+	// Admin routes need an API key with the admin scope.
+	Admin bool
+	// Queued, when set, is the job status returned under "job" with 202 by
+	// routes that answer at once when their job has to wait for a free slot.
+	Queued interface{}
+}
+
+// Build returns the OpenAPI document for ops. When secured is set, /api/v1
+// routes declare the API key security scheme.
+func Build(title, version string, ops []Operation, secured bool) map[string]interface{} {
+	schemas := map[string]interface{}{
+		"Error": object(map[string]interface{}{
+			"code":      map[string]interface{}{"type": "string"},
+			"message":   map[string]interface{}{"type": "string"},
+			"details":   map[string]interface{}{"type": "string"},
+			"requestId": map[string]interface{}{"type": "string"},
+			"jobId":     map[string]interface{}{"type": "string"},
+		}),
+		"ValidationError": object(map[string]interface{}{
+			"code":      map[string]interface{}{"type": "string"},
+			"message":   map[string]interface{}{"type": "string"},
+			"details":   map[string]interface{}{"type": "string"},
+			"requestId": map[string]interface{}{"type": "string"},
+			"errors": map[string]interface{}{
+				"type": "array",
+				"items": object(map[string]interface{}{
+					"field":   map[string]interface{}{"type": "string"},
+					"message": map[string]interface{}{"type": "string"},
+				}),
+			},
+		}),
+	}
+	g := &generator{schemas: schemas}
+
+	paths := make(map[string]interface{})
+	for _, op := range ops {
+		path := openAPIPath(op.Path)
+		methods, ok := paths[path].(map[string]interface{})
+		if !ok {
+			methods = make(map[string]interface{})
+			paths[path] = methods
+		}
+		method := strings.ToLower(op.Method)
+		if method == "" {
+			method = "get"
+		}
+		methods[method] = g.operation(op, secured)
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]interface{}{"title": title, "version": version},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}
+	if secured {
+		doc["components"].(map[string]interface{})["securitySchemes"] = map[string]interface{}{
+			"apiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+		}
+	}
+	return doc
+}
+
+// AddSchema registers the schema of v's type in doc's components, for types
+// that no operation references directly.
+func AddSchema(doc map[string]interface{}, v interface{}) {
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	g := &generator{schemas: schemas}
+	g.schemaFor(reflect.TypeOf(v))
+}
+
+type generator struct {
+	schemas map[string]interface{}
+}
+
+func (g *generator) operation(op Operation, secured bool) map[string]interface{} {
+	var params []interface{}
+	for name, description := range op.PathParams {
+		params = append(params, map[string]interface{}{
+			"name": name, "in": "path", "required": true, "description": description,
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	if op.Query != nil {
+		params = append(params, queryParameters(reflect.TypeOf(op.Query))...)
+	}
+
+	success := map[string]interface{}{"description": "OK"}
+	switch {
+	case op.ContentType != "":
+		success["content"] = map[string]interface{}{
+			op.ContentType: map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}},
+		}
+	case op.Raw:
+		success["content"] = jsonContent(g.schemaFor(reflect.TypeOf(op.Result)))
+	default:
+		envelope := map[string]interface{}{"message": map[string]interface{}{"type": "string"}}
+		if op.Result != nil {
+			envelope["result"] = g.schemaFor(reflect.TypeOf(op.Result))
+		}
+		success["content"] = jsonContent(object(envelope))
+	}
+
+	errorResponse := func(description, schema string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content":     jsonContent(map[string]interface{}{"$ref": "#/components/schemas/" + schema}),
+		}
+	}
+	responses := map[string]interface{}{"200": success}
+	if op.Query != nil {
+		responses["400"] = errorResponse("Query parameters could not be parsed", "Error")
+		responses["422"] = errorResponse("One or more parameters are invalid", "ValidationError")
+	}
+	if op.Body != nil {
+		responses["400"] = errorResponse("Request body could not be parsed", "Error")
+		responses["422"] = errorResponse("One or more parameters are invalid", "ValidationError")
+	}
+	if op.Queued != nil {
+		responses["202"] = map[string]interface{}{
+			"description": "Job queued; it runs in the background",
+			"content": jsonContent(object(map[string]interface{}{
+				"message": map[string]interface{}{"type": "string"},
+				"jobId":   map[string]interface{}{"type": "string"},
+				"job":     g.schemaFor(reflect.TypeOf(op.Queued)),
+			})),
+		}
+	}
+	if len(op.PathParams) > 0 {
+		responses["404"] = errorResponse("Not found", "Error")
+	}
+	if !op.Public && secured {
+		responses["401"] = errorResponse("Missing or invalid API key", "Error")
+	}
+	if op.Admin && secured {
+		responses["403"] = errorResponse("API key without the admin scope", "Error")
+	}
+
+	operation := map[string]interface{}{
+		"summary":   op.Summary,
+		"responses": responses,
+	}
+	if op.Description != "" {
+		operation["description"] = op.Description
+	}
+	if op.Tag != "" {
+		operation["tags"] = []string{op.Tag}
+	}
+	if len(params) > 0 {
+		operation["parameters"] = params
+	}
+	if op.Body != nil {
+		body := reflect.TypeOf(op.Body)
+		for body.Kind() == reflect.Pointer {
+			body = body.Elem()
+		}
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  jsonContent(g.schemaFor(body)),
+		}
+	}
+	if op.Public {
+		operation["security"] = []interface{}{}
+	} else if secured {
+		operation["security"] = []interface{}{map[string]interface{}{"apiKey": []string{}}}
+	}
+	return operation
+}
+
+// queryParameters lists the fields of a request struct tagged with query.
+// The doc, enum, min and required tags fill in the description, allowed
+// values, minimum and required flag.
+func queryParameters(t reflect.Type) []interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var params []interface{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("query")
+		if name == "" || name == "-" {
+			continue
+		}
+		schema := scalarSchema(field.Type)
+		if enum := field.Tag.Get("enum"); enum != "" {
+			schema["enum"] = strings.Split(enum, ",")
+		}
+		if min, err := strconv.Atoi(field.Tag.Get("min")); err == nil {
+			schema["minimum"] = min
+		}
+		param := map[string]interface{}{"name": name, "in": "query", "schema": schema}
+		if doc := field.Tag.Get("doc"); doc != "" {
+			param["description"] = doc
+		}
+		if field.Tag.Get("required") == "true" {
+			param["required"] = true
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor returns the schema of t, registering named structs as components.
+func (g *generator) schemaFor(t reflect.Type) map[string]interface{} {
+	switch {
+	case t == nil:
+		return map[string]interface{}{}
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := g.schemaFor(t.Elem())
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.Interface:
+		return map[string]interface{}{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := t.Name()
+		if _, ok := g.schemas[name]; !ok {
+			// Reserve the name first so recursive types terminate.
+			g.schemas[name] = map[string]interface{}{}
+			g.schemas[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return scalarSchema(t)
+}
+
+// structSchema follows encoding/json: embedded structs without a json name are
+// flattened, "-" fields are skipped and omitempty fields are optional.
+func (g *generator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	g.addFields(t, properties, &required)
+	schema := object(properties)
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (g *generator) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.addFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema := g.schemaFor(field.Type)
+		if _, isRef := schema["$ref"]; !isRef {
+			if doc := field.Tag.Get("doc"); doc != "" {
+				schema["description"] = doc
+			}
+		}
+		properties[name] = schema
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func scalarSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{"type": "string"}
+}
+
+func object(properties map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// openAPIPath turns Fiber's ":id" path parameters into "{id}".
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
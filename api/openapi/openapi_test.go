@@ -0,0 +1,129 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testQuery struct {
+	Mode     string `query:"mode" enum:"full,incremental" doc:"Sync mode."`
+	Page     int    `query:"page" min:"0"`
+	Period   int    `query:"period" required:"true"`
+	Internal string `query:"-"`
+	Body     string `json:"body"`
+}
+
+type testBase struct {
+	ID int `json:"id"`
+}
+
+type testNode struct {
+	testBase
+	Name     string     `json:"name" doc:"Display name."`
+	Note     string     `json:"note,omitempty"`
+	Parent   *testNode  `json:"parent"`
+	Children []testNode `json:"children,omitempty"`
+	At       time.Time  `json:"at"`
+	Skipped  string     `json:"-"`
+	hidden   string
+}
+
+func TestBuildQueryParameters(t *testing.T) {
+	doc := Build("test", "1.0.0", []Operation{{Path: "/items", Query: &testQuery{}}}, false)
+	get := doc["paths"].(map[string]interface{})["/items"].(map[string]interface{})["get"].(map[string]interface{})
+
+	want := []interface{}{
+		map[string]interface{}{"name": "mode", "in": "query", "description": "Sync mode.", "schema": map[string]interface{}{"type": "string", "enum": []string{"full", "incremental"}}},
+		map[string]interface{}{"name": "page", "in": "query", "schema": map[string]interface{}{"type": "integer", "minimum": 0}},
+		map[string]interface{}{"name": "period", "in": "query", "required": true, "schema": map[string]interface{}{"type": "integer"}},
+	}
+	if got := get["parameters"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("parameters = %v, want %v", got, want)
+	}
+	responses := get["responses"].(map[string]interface{})
+	for _, code := range []string{"200", "400", "422"} {
+		if _, ok := responses[code]; !ok {
+			t.Errorf("responses lack %s", code)
+		}
+	}
+	if _, ok := get["security"]; ok {
+		t.Error("unsecured document declares security")
+	}
+}
+
+func TestBuildSchemas(t *testing.T) {
+	doc := Build("test", "1.0.0", []Operation{{Path: "/nodes/:id", Raw: true, Result: testNode{}, PathParams: map[string]string{"id": "Node ID."}}}, true)
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	node, ok := schemas["testNode"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("testNode not registered; schemas = %v", schemas)
+	}
+
+	properties := node["properties"].(map[string]interface{})
+	var names []string
+	for name := range properties {
+		names = append(names, name)
+	}
+	for _, name := range []string{"id", "name", "note", "parent", "children", "at"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("property %q missing; got %v", name, names)
+		}
+	}
+	if len(properties) != 6 {
+		t.Errorf("properties = %v, want the 6 exported JSON fields", names)
+	}
+	if want := []string{"id", "name", "at"}; !reflect.DeepEqual(node["required"], want) {
+		t.Errorf("required = %v, want %v", node["required"], want)
+	}
+	if got := properties["name"].(map[string]interface{})["description"]; got != "Display name." {
+		t.Errorf("name description = %v, want the doc tag", got)
+	}
+	wantParent := map[string]interface{}{"allOf": []interface{}{map[string]interface{}{"$ref": "#/components/schemas/testNode"}}, "nullable": true}
+	if got := properties["parent"]; !reflect.DeepEqual(got, wantParent) {
+		t.Errorf("parent = %v, want %v", got, wantParent)
+	}
+	if got := properties["at"]; !reflect.DeepEqual(got, map[string]interface{}{"type": "string", "format": "date-time"}) {
+		t.Errorf("at = %v, want a date-time string", got)
+	}
+
+	paths := doc["paths"].(map[string]interface{})
+	get, ok := paths["/nodes/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("paths = %v, want /nodes/{id}", paths)
+	}
+	responses := get["responses"].(map[string]interface{})
+	for _, code := range []string{"200", "401", "404"} {
+		if _, ok := responses[code]; !ok {
+			t.Errorf("responses lack %s", code)
+		}
+	}
+	if want := []interface{}{map[string]interface{}{"apiKey": []string{}}}; !reflect.DeepEqual(get["security"], want) {
+		t.Errorf("security = %v, want %v", get["security"], want)
+	}
+}
+
+func TestBuildPublicAndBodyOperations(t *testing.T) {
+	doc := Build("test", "1.0.0", []Operation{
+		{Path: "/docs", Public: true, ContentType: "text/html"},
+		{Method: "POST", Path: "/items", Body: &testQuery{}, Result: testBase{}},
+	}, true)
+	paths := doc["paths"].(map[string]interface{})
+
+	docs := paths["/docs"].(map[string]interface{})["get"].(map[string]interface{})
+	if got := docs["security"]; !reflect.DeepEqual(got, []interface{}{}) {
+		t.Errorf("public route security = %v, want an empty list", got)
+	}
+	if _, ok := docs["responses"].(map[string]interface{})["401"]; ok {
+		t.Error("public route documents a 401")
+	}
+
+	post := paths["/items"].(map[string]interface{})["post"].(map[string]interface{})
+	body := post["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"]
+	if want := map[string]interface{}{"$ref": "#/components/schemas/testQuery"}; !reflect.DeepEqual(body, want) {
+		t.Errorf("request body schema = %v, want %v", body, want)
+	}
+	if _, ok := post["parameters"]; ok {
+		t.Error("body route lists query parameters")
+	}
+}
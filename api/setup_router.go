@@ -1,19 +1,86 @@
 package api
 
 import (
+	"log/slog"
+	"os"
+
+	"github.com/SamuelLeutner/fetch-student-data/api/apierror"
 	"github.com/SamuelLeutner/fetch-student-data/api/handlers"
+	"github.com/SamuelLeutner/fetch-student-data/api/middleware"
 	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
 	"github.com/SamuelLeutner/fetch-student-data/services"
 	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/pprof"
+	"github.com/gofiber/fiber/v3/middleware/recover"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
 )
 
-func SetupRouter(client *services.JacadClient, appConfig *config.Config) *fiber.App { 
+func SetupRouter(client *services.JacadClient, appConfig *config.Config, tracker *jobs.Tracker, probe *services.ReadinessProbe, keys *services.APIKeyRegistry) *fiber.App { 
 
-	r := fiber.New()
+	// Queued fetches run after their request returns, so values bound from
+	// the request must not alias fasthttp's reused buffers.
+	// Errors handlers return, unknown routes and panics all answer with the
+	// apierror envelope.
+	r := fiber.New(fiber.Config{Immutable: true, ErrorHandler: apierror.Handler})
+	r.Use(requestid.New())
+	if appConfig.AccessLog {
+		// Access logs are always JSON, whatever LOG_FORMAT is, so they can be
+		// shipped and parsed on their own; "log":"access" tells them apart.
+		accessLogger := slog.New(slog.NewJSONHandler(os.Stdout, nil)).With("log", "access")
+		r.Use(middleware.AccessLog(accessLogger, appConfig.AccessLogSampleRatio, appConfig.AccessLogRedactParams))
+	}
+	if appConfig.TracingEnabled {
+		r.Use(middleware.Tracing())
+	}
+	// Recovering after the access log and tracing lets both record the 500.
+	r.Use(recover.New(recover.Config{EnableStackTrace: true, StackTraceHandler: apierror.LogPanic}))
+	r.Get("/metrics", handlers.HandleMetrics)
+	r.Get("/healthz", handlers.HandleHealthz)
+	r.Get("/readyz", handlers.CreateReadyzHandler(probe, tracker))
+	// The docs are registered before the /api/v1 auth middleware so browsers
+	// can load them without an API key.
+	r.Get("/api/v1/openapi.json", handlers.CreateOpenAPIHandler(buildSpec(keys.Enabled())))
+	r.Get("/api/v1/docs", handlers.CreateDocsHandler("/api/v1/openapi.json"))
+	if appConfig.PprofEnabled {
+		// Validate refuses PPROF_ENABLED without an admin key in API_KEYS,
+		// so the profiles, which expose memory contents, are only served to
+		// admin keys.
+		r.Use("/debug/pprof", middleware.APIKeyAuth(keys, appConfig.APIRequireHMAC, appConfig.APIHMACMaxSkew), middleware.RequireAdmin(), pprof.New())
+	}
 	api := r.Group("/api/v1")
+	if keys.Enabled() {
+		api.Use(middleware.APIKeyAuth(keys, appConfig.APIRequireHMAC, appConfig.APIHMACMaxSkew))
+		// The admin routes change the server's state, so they are only
+		// registered behind authentication and for admin keys.
+		admin := api.Group("/admin", middleware.RequireAdmin())
+		admin.Post("/reload", handlers.CreateReloadHandler(client))
+		admin.Post("/auth/refresh", handlers.CreateAuthRefreshHandler(client, appConfig))
+	} else {
+		slog.Warn("API_KEYS is not set. The /api/v1 routes are not protected by authentication and the /api/v1/admin routes are not served.")
+	}
 
 	api.Get("/ping", handlers.HandlePing)
-	api.Get("/fetch-enrollments", handlers.CreateFetchEnrollmentsHandler(client, appConfig)) 
+	fetchEnrollments := handlers.CreateFetchEnrollmentsHandler(client, appConfig, tracker)
+	api.Get("/fetch-enrollments", fetchEnrollments)
+	api.Post("/fetch-enrollments", fetchEnrollments)
+	api.Get("/fetch-courses", handlers.CreateFetchCoursesHandler(services.NewCoursesService(client), appConfig, tracker))
+	api.Get("/fetch-classes", handlers.CreateFetchClassesHandler(services.NewTurmasService(client), appConfig, tracker))
+	api.Get("/periods", handlers.CreateListPeriodsHandler(client, appConfig))
+	api.Get("/presets", handlers.CreateListPresetsHandler(appConfig))
+	api.Get("/fetch-candidates", handlers.CreateFetchCandidatesHandler(services.NewCandidatesService(client), appConfig, tracker))
+	api.Get("/fetch-attendance", handlers.CreateFetchAttendanceHandler(services.NewAttendanceService(client), appConfig, tracker))
+	api.Get("/export/enrollments.xlsx", handlers.CreateExportEnrollmentsXLSXHandler(client, appConfig, tracker))
+	api.Get("/export/enrollments.csv", handlers.CreateExportEnrollmentsCSVHandler(client, appConfig, tracker))
+	api.Get("/compare-enrollments", handlers.CreateCompareEnrollmentsHandler(client, appConfig, tracker))
+	api.Get("/config", handlers.CreateConfigHandler(appConfig))
+	api.Get("/jobs", handlers.CreateListJobsHandler(client.History))
+	api.Get("/queue", handlers.CreateQueueHandler(tracker))
+	api.Get("/jobs/:id", handlers.CreateJobStatusHandler(tracker, client.Progress()))
+	api.Delete("/jobs/:id", handlers.CreateCancelJobHandler(tracker, client.History))
+	api.Get("/jobs/:id/events", handlers.CreateJobEventsHandler(client.Progress()))
+	api.Post("/jobs/:id/retry-failed-pages", handlers.CreateRetryFailedPagesHandler(client, appConfig, tracker))
+	api.Get("/sheets/status", handlers.CreateSheetStatusHandler(client, appConfig))
 
 	return r
 }
\ No newline at end of file
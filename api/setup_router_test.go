@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+)
+
+func TestAdminRoutes(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   []config.APIKey
+		key    string
+		status int
+	}{
+		{name: "not served without API keys", status: http.StatusNotFound},
+		{name: "missing key", keys: []config.APIKey{{Name: "ops", Key: "ops-key", Admin: true}}, status: http.StatusUnauthorized},
+		{name: "key without the admin scope", keys: []config.APIKey{{Name: "bi", Key: "bi-key"}}, key: "bi-key", status: http.StatusForbidden},
+		{name: "admin key", keys: []config.APIKey{{Name: "ops", Key: "ops-key", Admin: true}}, key: "ops-key", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Defaults()
+			client := services.NewJacadClient(&cfg, services.NewFakeSheetWriter(), nil, nil, nil)
+			app := SetupRouter(client, &cfg, jobs.NewTracker(1), services.NewReadinessProbe(client, time.Minute, time.Second), services.NewAPIKeyRegistry(tt.keys))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/reload", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("POST /api/v1/admin/reload = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestPprofNeedsAnAdminKey(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.PprofEnabled = true
+	client := services.NewJacadClient(&cfg, services.NewFakeSheetWriter(), nil, nil, nil)
+	keys := services.NewAPIKeyRegistry([]config.APIKey{{Name: "ops", Key: "ops-key", Admin: true}, {Name: "bi", Key: "bi-key"}})
+	app := SetupRouter(client, &cfg, jobs.NewTracker(1), services.NewReadinessProbe(client, time.Minute, time.Second), keys)
+
+	for key, want := range map[string]int{"bi-key": http.StatusForbidden, "ops-key": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+		req.Header.Set("X-API-Key", key)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET /debug/pprof/ with %s = %d, want %d", key, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/spf13/cobra"
+)
+
+type benchmarkWriteOptions struct {
+	rows          int
+	pageSize      int
+	pagesPerBatch int
+	target        string
+	sheet         string
+}
+
+func newBenchmarkCommand() *cobra.Command {
+	benchmark := &cobra.Command{
+		Use:   "benchmark",
+		Short: "Measure pipeline throughput with synthetic data",
+	}
+
+	opts := &benchmarkWriteOptions{}
+	write := &cobra.Command{
+		Use:   "write",
+		Short: "Map and write synthetic enrollments, reporting rows/sec and memory",
+		Long: "Generates synthetic enrollments and pushes them through the row mapper and a writer, " +
+			"batched like a streamed fetch, to tune MAX_PAGES_PER_BATCH and writer batching. " +
+			"--target=sheets overwrites --sheet in SPREADSHEET_ID.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBenchmarkWrite(cmd.Context(), opts)
+		},
+	}
+
+	flags := write.Flags()
+	flags.IntVar(&opts.rows, "rows", 100000, "synthetic enrollments to write")
+	flags.IntVar(&opts.pageSize, "page-size", 0, "rows per simulated Jacad page (default: PAGE_SIZE)")
+	flags.IntVar(&opts.pagesPerBatch, "pages-per-batch", 0, "pages handed to the writer at once (default: MAX_PAGES_PER_BATCH)")
+	flags.StringVar(&opts.target, "target", "mock", "writer: mock discards rows, sheets writes them to Google Sheets")
+	flags.StringVar(&opts.sheet, "sheet", "Benchmark", "tab written by --target=sheets")
+
+	benchmark.AddCommand(write)
+	return benchmark
+}
+
+func runBenchmarkWrite(ctx context.Context, opts *benchmarkWriteOptions) error {
+	benchOpts := services.BenchmarkOptions{
+		Rows:          opts.rows,
+		PageSize:      opts.pageSize,
+		PagesPerBatch: opts.pagesPerBatch,
+		Sheet:         opts.sheet,
+	}
+	if benchOpts.PageSize == 0 {
+		benchOpts.PageSize = config.AppConfig.PageSize
+	}
+	if benchOpts.PagesPerBatch == 0 {
+		benchOpts.PagesPerBatch = config.AppConfig.MaxPagesPerBatch
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var writer services.SheetWriter
+	switch opts.target {
+	case "mock":
+		writer = services.NewDiscardWriter()
+	case "sheets":
+		if err := loadSecrets(ctx); err != nil {
+			return err
+		}
+		sheetsWriter, err := newDestinationWriter(ctx, "sheets", "")
+		if err != nil {
+			return fmt.Errorf("failed to create sheets writer: %w", err)
+		}
+		writer = sheetsWriter
+	default:
+		return fmt.Errorf("unknown benchmark target '%s', want mock or sheets", opts.target)
+	}
+
+	fmt.Printf("Writing %d synthetic enrollments to %s (pageSize=%d, pagesPerBatch=%d)...\n", benchOpts.Rows, opts.target, benchOpts.PageSize, benchOpts.PagesPerBatch)
+	result, err := services.RunWriteBenchmark(ctx, writer, config.AppConfig.Columns, benchOpts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d rows in %d batches in %s\n", result.Rows, result.Batches, result.Duration.Round(time.Millisecond))
+	fmt.Printf("  %-16s %12.0f\n", "rows/sec", result.RowsPerSecond)
+	fmt.Printf("  %-16s %12s\n", "mapping", result.MapDuration.Round(time.Millisecond))
+	fmt.Printf("  %-16s %12s\n", "writing", result.WriteDuration.Round(time.Millisecond))
+	fmt.Printf("  %-16s %9.1f MiB\n", "allocated", float64(result.AllocBytes)/(1<<20))
+	fmt.Printf("  %-16s %9.1f MiB\n", "peak heap", float64(result.PeakHeapBytes)/(1<<20))
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+)
+
+// startDemo points the configuration at an in-process mock Jacad server
+// serving enrollments demo enrollments, and writes CSV instead of Sheets so
+// no credentials are needed. The returned function stops the server.
+func startDemo(enrollments int) func() {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(enrollments)})
+
+	config.AppConfig.APIBase = server.URL
+	config.AppConfig.UserToken = server.UserToken
+	config.AppConfig.JacadProfiles = nil
+	if config.AppConfig.OrganizationsSource == config.OrgSourceJacad {
+		config.AppConfig.OrganizationsSource = config.OrgSourceBuiltin
+	}
+	if config.AppConfig.Writer == "sheets" || config.AppConfig.Writer == "bigquery" || config.AppConfig.Writer == "excel" {
+		config.AppConfig.Writer = "csv"
+	}
+	config.AppConfig.FanoutWriters = nil
+	slog.Warn("Demo mode: serving Jacad from a local mock with synthetic data", "apiBase", server.URL, "enrollments", enrollments, "writer", config.AppConfig.Writer)
+	return server.Close
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/SamuelLeutner/fetch-student-data/tracing"
+	"github.com/spf13/cobra"
+)
+
+type fetchEnrollmentsOptions struct {
+	periodo        int
+	status         string
+	org            string
+	out            string
+	outDir         string
+	mode           string
+	writeMode      string
+	groupBy        string
+	splitByStatus  bool
+	dryRun         bool
+	previewRows    int
+	resume         bool
+	bypassCache    bool
+	pageSize       int
+	startPage      int
+	endPage        int
+	maxPages       int
+	anonymize      bool
+	deltaReport    bool
+	summaryTab     bool
+	newSpreadsheet bool
+	fields         string
+	sortBy         string
+	preset         string
+	tenant         string
+	from           string
+	to             string
+}
+
+func newFetchCommand() *cobra.Command {
+	fetch := &cobra.Command{
+		Use:   "fetch",
+		Short: "Run a one-shot fetch without starting the HTTP server",
+	}
+
+	opts := &fetchEnrollmentsOptions{}
+	enrollments := &cobra.Command{
+		Use:   "enrollments",
+		Short: "Fetch enrollments and write them to Sheets or CSV",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if demo.enabled && !cmd.Flags().Changed("out") {
+				opts.out = "csv"
+			}
+			return runFetchEnrollments(cmd.Context(), opts)
+		},
+	}
+
+	flags := enrollments.Flags()
+	flags.IntVar(&opts.periodo, "periodo", 0, "Jacad idPeriodoLetivo")
+	flags.StringVar(&opts.status, "status", "", "Jacad statusMatricula (e.g. ATIVA), or a comma-separated list fetched with one query each")
+	flags.StringVar(&opts.org, "org", "", "organization key or id, comma-separated list, or 'all'")
+	flags.StringVar(&opts.out, "out", "sheets", "output: sheets, bigquery, excel, csv or parquet")
+	flags.StringVar(&opts.outDir, "out-dir", "", "directory for --out=csv (default: current directory), or directory or gs:// or s3:// URL for --out=parquet (default: PARQUET_OUTPUT)")
+	// mode, write-mode and group-by default to empty so a --preset can set
+	// them; the fetch treats empty as full, atomic and none.
+	flags.StringVar(&opts.mode, "mode", "", "sync mode: full or incremental (default: full)")
+	flags.StringVar(&opts.writeMode, "write-mode", "", "write mode: atomic or stream (default: atomic)")
+	flags.StringVar(&opts.groupBy, "group-by", "", "split tabs by: "+strings.Join(requests.GroupByOptions, ", ")+" (default: none)")
+	flags.BoolVar(&opts.splitByStatus, "split-by-status", false, "with several statuses, write one tab per status instead of a combined sheet")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "preview rows without writing")
+	flags.IntVar(&opts.previewRows, "preview-rows", 0, "rows to preview per sheet on dry runs")
+	flags.BoolVar(&opts.resume, "resume", false, "resume from the last checkpoint for the same query")
+	flags.BoolVar(&opts.bypassCache, "bypass-cache", false, "ignore cached Jacad responses")
+	flags.IntVar(&opts.pageSize, "page-size", 0, "Jacad page size, between MIN_PAGE_SIZE and MAX_PAGE_SIZE (default: PAGE_SIZE)")
+	flags.IntVar(&opts.startPage, "start-page", 0, "first Jacad page to fetch, counted from 0")
+	flags.IntVar(&opts.endPage, "end-page", 0, "stop before this Jacad page (default: up to the last page)")
+	flags.IntVar(&opts.maxPages, "max-pages", 0, "fetch at most this many pages from --start-page (default: no limit)")
+	flags.StringVar(&opts.tenant, "tenant", "", "Jacad profile from JACAD_PROFILES (default: API_BASE)")
+	flags.StringVar(&opts.from, "from", "", "keep enrollments with dataMatricula on or after this date (YYYY-MM-DD)")
+	flags.StringVar(&opts.to, "to", "", "keep enrollments with dataMatricula on or before this date (YYYY-MM-DD)")
+	flags.StringVar(&opts.fields, "fields", "", "comma-separated enrollment fields to write (default: configured columns)")
+	flags.StringVar(&opts.preset, "preset", "", "preset from PRESETS_FILE supplying the options left unset")
+	flags.StringVar(&opts.sortBy, "sort-by", "", "comma-separated enrollment fields to sort rows by, '-' prefixed for descending (default: idMatricula)")
+	flags.BoolVar(&opts.deltaReport, "delta-report", false, "write a Changes tab with added, removed and status-changed enrollments")
+	flags.BoolVar(&opts.newSpreadsheet, "new-spreadsheet", false, "write to a new spreadsheet created in DRIVE_FOLDER_ID")
+	flags.BoolVar(&opts.summaryTab, "summary-tab", false, "write a Resumo tab with enrollment counts per course, status, unidade física and month")
+	flags.BoolVar(&opts.anonymize, "anonymize", false, "apply REDACTION_POLICY to PII columns")
+
+	fetch.AddCommand(enrollments)
+	return fetch
+}
+
+func runFetchEnrollments(ctx context.Context, opts *fetchEnrollmentsOptions) error {
+	orgIDs, err := resolveOrgFlag(opts.org)
+	if err != nil {
+		return err
+	}
+
+	params := &requests.FetchEnrollmentsRequest{
+		OrgIds:            orgIDs,
+		IdPeriodoLetivo:   opts.periodo,
+		StatusMatricula:   opts.status,
+		Mode:              opts.mode,
+		WriteMode:         opts.writeMode,
+		GroupBy:           opts.groupBy,
+		SplitByStatus:     opts.splitByStatus,
+		DryRun:            opts.dryRun,
+		PreviewRows:       opts.previewRows,
+		Resume:            opts.resume,
+		BypassCache:       opts.bypassCache,
+		PageSize:          opts.pageSize,
+		StartPage:         opts.startPage,
+		EndPage:           opts.endPage,
+		MaxPages:          opts.maxPages,
+		Anonymize:         opts.anonymize,
+		DeltaReport:       opts.deltaReport,
+		SummaryTab:        opts.summaryTab,
+		NewSpreadsheet:    opts.newSpreadsheet,
+		Fields:            opts.fields,
+		SortBy:            opts.sortBy,
+		Preset:            opts.preset,
+		Tenant:            opts.tenant,
+		DataMatriculaFrom: opts.from,
+		DataMatriculaTo:   opts.to,
+	}
+
+	if err := params.ApplyPreset(&config.AppConfig); err != nil {
+		return fmt.Errorf("invalid fetch options: preset: %w", err)
+	}
+	errs := params.Validate(&config.AppConfig)
+	if err := services.ValidateEnrollmentFields(params.ParseFields(), config.AppConfig.Columns); err != nil {
+		errs.Add("fields", err)
+	}
+	if _, err := services.ParseEnrollmentSort(params.SortBy); err != nil {
+		errs.Add("sortBy", err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid fetch options: %w", errs)
+	}
+
+	config.AppConfig.Writer = opts.out
+	if err := config.AppConfig.Validate(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing := initTracing(ctx)
+	defer shutdownTracing()
+
+	if err := loadSecrets(ctx); err != nil {
+		return err
+	}
+	go appSecrets.Run(ctx)
+
+	writer, err := newWriter(ctx, opts.out, opts.outDir)
+	if err != nil {
+		return fmt.Errorf("failed to create %s writer: %w", opts.out, err)
+	}
+	cache, closeCache := newCache()
+	defer closeCache()
+	history, closeHistory := newJobHistory()
+	defer closeHistory()
+
+	client, err := newJacadClient(ctx, writer, cache, history)
+	if err != nil {
+		return err
+	}
+	go client.RunTokenRefresher(ctx)
+	fmt.Printf("Fetching enrollments (periodo=%s, status=%s, org=%s, out=%s)...\n", params.PeriodLabel(), params.StatusLabel(), params.OrgIds, opts.out)
+
+	fetchCtx, span := tracing.Start(ctx, "fetch enrollments")
+	result, fetchErr := client.FetchEnrollmentsFiltered(fetchCtx, params)
+	tracing.End(span, fetchErr)
+	defer client.Notifications.Wait()
+	if flusher, ok := writer.(services.Flusher); ok {
+		if err := flusher.Flush(ctx); err != nil && fetchErr == nil {
+			fetchErr = fmt.Errorf("failed to flush pending sheet writes: %w", err)
+		}
+	}
+	if result != nil {
+		printFetchResult(result)
+	}
+	if fetchErr != nil {
+		return fetchErr
+	}
+	for _, org := range result.Organizations {
+		if org.Error != "" {
+			return fmt.Errorf("one or more sheets failed to be written")
+		}
+	}
+	return nil
+}
+
+// resolveOrgFlag turns --org values such as "EAD", "20", "EAD,POS_EAD" or
+// "all" into the orgIds query format.
+func resolveOrgFlag(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.EqualFold(value, "all") {
+		return value, nil
+	}
+
+	ids := make([]string, 0)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(part); err == nil {
+			ids = append(ids, part)
+			continue
+		}
+		org, ok := config.AppConfig.Organizations.ByKey(part)
+		if !ok {
+			return "", fmt.Errorf("unknown organization '%s'", part)
+		}
+		ids = append(ids, strconv.Itoa(org.ID))
+	}
+	return strings.Join(ids, ","), nil
+}
+
+func printFetchResult(result *services.FetchResult) {
+	fmt.Printf("Fetched %d enrollments (mode=%s, writeMode=%s, failedBatches=%d)\n", result.TotalFetched, result.Mode, result.WriteMode, result.FailedBatches)
+	for _, org := range result.Organizations {
+		status := "ok"
+		switch {
+		case org.Error != "":
+			status = "error: " + org.Error
+		case org.Resumed:
+			status = "resumed"
+		case result.DryRun:
+			status = "dry run"
+		}
+		fmt.Printf("  %-40s %8d rows  %s\n", org.Sheet, org.Rows, status)
+	}
+}
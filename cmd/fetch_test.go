@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+func TestResolveOrgFlag(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: ""},
+		{value: "ALL", want: "ALL"},
+		{value: "20", want: "20"},
+		{value: "EAD, POS_EAD", want: "20,17"},
+		{value: "EAD,9,", want: "20,9"},
+		{value: "NOPE", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := resolveOrgFlag(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveOrgFlag(%q) = %q, %v; want %q (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestFetchEnrollmentsCommand runs a one-shot fetch from the demo Jacad
+// server into CSV files.
+func TestFetchEnrollmentsCommand(t *testing.T) {
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+	t.Chdir(t.TempDir())
+	out := t.TempDir()
+
+	root := newRootCommand()
+	root.SetArgs([]string{"--demo", "--demo-enrollments", "720", "fetch", "enrollments", "--periodo", "87", "--status", "ATIVA", "--org", "EAD,POS_EAD", "--out-dir", out})
+	t.Cleanup(func() { demo.enabled, demo.stop = false, nil })
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(out, "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("wrote %v, want one CSV per organization", files)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 720 enrollments: a quarter are period 87 with status ATIVA, split
+		// evenly across the three organizations; plus the header.
+		if lines := strings.Count(string(data), "\n"); lines != 61 {
+			t.Errorf("%s has %d lines, want 61", filepath.Base(file), lines)
+		}
+	}
+}
+
+func TestFetchEnrollmentsCommandRejectsInvalidOptions(t *testing.T) {
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+	t.Chdir(t.TempDir())
+
+	root := newRootCommand()
+	root.SetArgs([]string{"fetch", "enrollments", "--mode", "sometimes"})
+	root.SetErr(new(strings.Builder))
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "invalid fetch options") {
+		t.Errorf("Execute() = %v, want invalid fetch options", err)
+	}
+}
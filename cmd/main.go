@@ -1,63 +1,68 @@
 package main
 
 import (
-	"context"
-	"log"
 	"os"
-	"path/filepath"
 
-	"github.com/SamuelLeutner/fetch-student-data/api"
 	"github.com/SamuelLeutner/fetch-student-data/config"
-	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/spf13/cobra"
 )
 
 func main() {
-	config.Init()
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-
-	credsPathForWriterFallback := config.AppConfig.CredentialsJSONBase64
-	if os.Getenv("GOOGLE_CREDENTIALS_JSON_BASE64") == "" {
-		log.Println("INFO: GOOGLE_CREDENTIALS_JSON_BASE64 not set. GoogleSheetsWriter will try the fallback file path if provided.")
-		if credsPathForWriterFallback == "" {
-			exePath, err := os.Executable()
-			if err != nil {
-				log.Printf("FATAL: Could not get executable path: %v", err)
-			}
-			exeDir := filepath.Dir(exePath)
-			credsPathForWriterFallback = filepath.Join(exeDir, "credentials.json")
-			log.Printf("INFO: CredentialsJSONBase64 from config is empty. Defaulting fallback path to be next to executable: '%s'", credsPathForWriterFallback)
-		}
-
-		if _, err := os.Stat(credsPathForWriterFallback); os.IsNotExist(err) {
-			log.Printf("WARN: Fallback credentials file not found at '%s'. GoogleSheetsWriter might attempt Application Default Credentials or fail if no credentials source is available.", credsPathForWriterFallback)
-		} else if err != nil {
-			log.Printf("ERROR: Error checking fallback credentials file at '%s': %v. GoogleSheetsWriter might still attempt ADC.", credsPathForWriterFallback, err)
-		}
-	} else {
-		log.Println("INFO: GOOGLE_CREDENTIALS_JSON_BASE64 is set. GoogleSheetsWriter will prioritize it.")
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
 	}
+}
 
-	ctx := context.Background()
-
-	sheetsWriter, err := services.NewGoogleSheetsWriter(
-		ctx,
-		config.AppConfig.SpreadsheetID,
-		credsPathForWriterFallback,
-		config.AppConfig.MaxRetries,
-		config.AppConfig.RetryDelay,
-	)
-	if err != nil {
-		log.Printf("FATAL: Error creating GoogleSheetsWriter: %v", err)
-	}
+// configFiles are set by --env-file and --config.
+var configFiles config.Options
 
-	client := services.NewJacadClient(&config.AppConfig, sheetsWriter)
-	app := api.SetupRouter(client, &config.AppConfig)
-	listenAddr := os.Getenv("LISTEN_ADDR")
+// demo is set by --demo; see startDemo.
+var demo struct {
+	enabled     bool
+	enrollments int
+	stop        func()
+}
 
-	log.Printf("INFO: Starting Fiber server on %s...", listenAddr)
-	if err := app.Listen(listenAddr); err != nil {
-		log.Printf("FATAL: Error starting Fiber server: %v", err)
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "fetch-student-data",
+		Short: "Fetch Jacad enrollments into Google Sheets",
+		Long:  "Runs the HTTP server when called without a subcommand.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.Init(configFiles); err != nil {
+				return err
+			}
+			logging.Init(config.AppConfig.LogFormat, config.AppConfig.LogLevel)
+			if demo.enabled {
+				demo.stop = startDemo(demo.enrollments)
+			}
+			return nil
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			if demo.stop != nil {
+				demo.stop()
+			}
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServer()
+		},
+		SilenceUsage: true,
 	}
 
-	log.Println("INFO: Main process completed (Fiber server stopped).")
+	root.PersistentFlags().StringVar(&configFiles.ConfigFile, "config", "", "YAML settings file, keyed by environment variable name (default: CONFIG_FILE)")
+	root.PersistentFlags().StringVar(&configFiles.EnvFile, "env-file", "", "dotenv file loaded into the environment (default: .env, skipped when missing)")
+	root.PersistentFlags().BoolVar(&demo.enabled, "demo", false, "serve Jacad from a local mock with synthetic data and write CSV instead of Sheets")
+	root.PersistentFlags().IntVar(&demo.enrollments, "demo-enrollments", 500, "synthetic enrollments served by --demo")
+
+	root.AddCommand(&cobra.Command{
+		Use:   "serve",
+		Short: "Run the HTTP server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServer()
+		},
+	})
+	root.AddCommand(newFetchCommand())
+	root.AddCommand(newBenchmarkCommand())
+	return root
 }
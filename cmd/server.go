@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/api"
+	"github.com/SamuelLeutner/fetch-student-data/api/grpcapi"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/metrics"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+)
+
+func runServer() error {
+	ctx := context.Background()
+
+	if err := config.AppConfig.Validate(); err != nil {
+		return err
+	}
+
+	shutdownTracing := initTracing(ctx)
+	defer shutdownTracing()
+
+	if err := loadSecrets(ctx); err != nil {
+		return err
+	}
+
+	writer, err := newWriter(ctx, config.AppConfig.Writer, "")
+	if err != nil {
+		return fmt.Errorf("failed to create %s writer: %w", config.AppConfig.Writer, err)
+	}
+
+	cache, closeCache := newCache()
+	defer closeCache()
+	history, closeHistory := newJobHistory()
+	defer closeHistory()
+
+	client, err := newJacadClient(ctx, writer, cache, history)
+	if err != nil {
+		return err
+	}
+	if config.AppConfig.StartupSelfCheck {
+		if err := runSelfCheck(ctx, client); err != nil {
+			return err
+		}
+	}
+
+	tracker := jobs.NewTracker(config.AppConfig.MaxConcurrentJobs)
+	probe := services.NewReadinessProbe(client, config.AppConfig.ReadinessCheckInterval, 30*time.Second)
+	probeCtx, stopProbe := context.WithCancel(ctx)
+	defer stopProbe()
+	go probe.Run(probeCtx)
+	go client.RunTokenRefresher(probeCtx)
+	go client.RunMetadataRefresher(probeCtx)
+	go appSecrets.Run(probeCtx)
+	go metrics.RunRuntimeStats(probeCtx, config.AppConfig.RuntimeStatsInterval)
+
+	keys := services.NewAPIKeyRegistry(config.AppConfig.APIKeys)
+	app := api.SetupRouter(client, &config.AppConfig, tracker, probe, keys)
+	listenAddr := config.AppConfig.ListenAddr
+
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listenErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting Fiber server...", "addr", listenAddr)
+		listenErr <- app.Listen(listenAddr)
+	}()
+
+	if grpcAddr := config.AppConfig.GRPCListenAddr; grpcAddr != "" {
+		grpcServer, err := grpcapi.NewServer(client, &config.AppConfig, tracker, keys)
+		if err != nil {
+			return fmt.Errorf("failed to create gRPC server: %w", err)
+		}
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen for gRPC on %s: %w", grpcAddr, err)
+		}
+		go func() {
+			slog.Info("Starting gRPC server...", "addr", grpcAddr)
+			if err := grpcServer.Serve(listener); err != nil {
+				slog.Error("Error running gRPC server", "error", err)
+			}
+		}()
+		defer grpcServer.GracefulStop()
+	}
+
+	select {
+	case <-sigCtx.Done():
+		slog.Info("Shutdown signal received. Starting graceful shutdown...", "drainTimeout", config.AppConfig.DrainTimeout.String())
+	case err := <-listenErr:
+		if err != nil {
+			slog.Error("Error starting Fiber server", "error", err)
+		}
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), config.AppConfig.DrainTimeout)
+	defer cancel()
+
+	if err := tracker.Shutdown(drainCtx); err != nil {
+		slog.Warn("In-flight jobs were cancelled after the drain timeout", "error", err)
+	}
+
+	if err := app.ShutdownWithTimeout(10 * time.Second); err != nil {
+		slog.Error("Error shutting down Fiber server", "error", err)
+	}
+
+	if flusher, ok := client.Writer.(services.Flusher); ok {
+		flushCtx, cancelFlush := context.WithTimeout(context.Background(), config.AppConfig.DrainTimeout)
+		if err := flusher.Flush(flushCtx); err != nil {
+			slog.Error("Error flushing pending sheet writes", "error", err)
+		}
+		cancelFlush()
+	}
+	client.Notifications.Wait()
+
+	slog.Info("Main process completed (Fiber server stopped).")
+	return nil
+}
+
+func runSelfCheck(ctx context.Context, client *services.JacadClient) error {
+	checkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	results := client.SelfCheck(checkCtx)
+	for _, result := range results {
+		if result.OK {
+			slog.Info("Startup self-check passed", "check", result.Name, "duration", result.Duration)
+		} else {
+			slog.Error("Startup self-check failed", "check", result.Name, "error", result.Error, "duration", result.Duration)
+		}
+	}
+	return services.SelfCheckError(results)
+}
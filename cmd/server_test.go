@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+func TestRunServerFailsWithoutWriter(t *testing.T) {
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+	config.AppConfig = config.Defaults()
+	config.AppConfig.APIBase = "http://jacad.invalid"
+	config.AppConfig.UserToken = "token"
+	config.AppConfig.Writer = "sheets"
+	config.AppConfig.SpreadsheetID = "book"
+	t.Setenv("GOOGLE_CREDENTIALS_JSON_BASE64", "not base64")
+
+	err := runServer()
+	if err == nil || !strings.Contains(err.Error(), "failed to create sheets writer") {
+		t.Fatalf("runServer() = %v, want the writer error", err)
+	}
+}
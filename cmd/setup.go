@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/notifications"
+	"github.com/SamuelLeutner/fetch-student-data/services"
+	"github.com/SamuelLeutner/fetch-student-data/tracing"
+)
+
+// appSecrets holds the secrets read by loadSecrets; nil without
+// SECRETS_PROVIDER.
+var appSecrets *services.Secrets
+
+// loadSecrets reads the secrets configured in place of USER_TOKEN and the
+// Google credentials. Run appSecrets.Run to keep them up to date.
+func loadSecrets(ctx context.Context) error {
+	provider, err := services.NewSecretProvider(ctx, &config.AppConfig, googleCredentials())
+	if err != nil {
+		return fmt.Errorf("failed to create %s secrets provider: %w", config.AppConfig.SecretsProvider, err)
+	}
+	if provider == nil {
+		return nil
+	}
+	secrets, err := services.LoadSecrets(ctx, provider, config.AppConfig.SecretUserToken, config.AppConfig.SecretGoogleCredentials, config.AppConfig.SecretsRefreshInterval)
+	if err != nil {
+		return err
+	}
+	appSecrets = secrets
+	slog.Info("Secrets loaded", "provider", config.AppConfig.SecretsProvider, "refreshInterval", config.AppConfig.SecretsRefreshInterval.String())
+	return nil
+}
+
+// googleCredentials returns how the Google writers and archiver authenticate.
+// A Workload Identity Federation configuration or a secret replaces the
+// credentials file.
+func googleCredentials() services.GoogleCredentials {
+	creds := services.GoogleCredentials{
+		WorkloadIdentityConfig:    config.AppConfig.GoogleWorkloadIdentityConfig,
+		ImpersonateServiceAccount: config.AppConfig.GoogleImpersonateServiceAccount,
+		ImpersonateDelegates:      config.AppConfig.GoogleImpersonateDelegates,
+	}
+	if appSecrets != nil && appSecrets.GoogleCredentials != nil {
+		creds.Secret = appSecrets.GoogleCredentials
+	} else if creds.WorkloadIdentityConfig == "" {
+		creds.Path = credentialsPath()
+	}
+	return creds
+}
+
+// credentialsPath returns the credentials file the Google writers fall back to
+// when GOOGLE_CREDENTIALS_JSON_BASE64 is not set.
+func credentialsPath() string {
+	credsPathForWriterFallback := config.AppConfig.CredentialsJSONBase64
+	if os.Getenv("GOOGLE_CREDENTIALS_JSON_BASE64") == "" {
+		slog.Info("GOOGLE_CREDENTIALS_JSON_BASE64 not set. GoogleSheetsWriter will try the fallback file path if provided.")
+		if credsPathForWriterFallback == "" {
+			exePath, err := os.Executable()
+			if err != nil {
+				slog.Error("Could not get executable path", "error", err)
+			}
+			exeDir := filepath.Dir(exePath)
+			credsPathForWriterFallback = filepath.Join(exeDir, "credentials.json")
+			slog.Info("CredentialsJSONBase64 from config is empty. Defaulting fallback path to be next to executable.", "path", credsPathForWriterFallback)
+		}
+
+		if _, err := os.Stat(credsPathForWriterFallback); os.IsNotExist(err) {
+			slog.Warn("Fallback credentials file not found. GoogleSheetsWriter might attempt Application Default Credentials or fail if no credentials source is available.", "path", credsPathForWriterFallback)
+		} else if err != nil {
+			slog.Error("Error checking fallback credentials file. GoogleSheetsWriter might still attempt ADC.", "path", credsPathForWriterFallback, "error", err)
+		}
+	} else {
+		slog.Info("GOOGLE_CREDENTIALS_JSON_BASE64 is set. GoogleSheetsWriter will prioritize it.")
+	}
+	return credsPathForWriterFallback
+}
+
+// initTracing starts exporting spans when TRACING_ENABLED is set. The returned
+// function flushes pending spans and must be called before exiting.
+func initTracing(ctx context.Context) func() {
+	if !config.AppConfig.TracingEnabled {
+		return func() {}
+	}
+	if err := tracing.Init(ctx, "fetch-student-data", config.AppConfig.TracingOTLPEndpoint, config.AppConfig.TracingSampleRatio); err != nil {
+		slog.Error("Error setting up tracing. Spans will not be exported.", "error", err)
+		return func() {}
+	}
+	slog.Info("Tracing enabled", "endpoint", config.AppConfig.TracingOTLPEndpoint, "sampleRatio", config.AppConfig.TracingSampleRatio)
+	return func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := tracing.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Error flushing pending spans", "error", err)
+		}
+	}
+}
+
+// newWriter builds the SheetWriter selected by writerName, teeing its writes
+// to the FANOUT_WRITERS ones when there are any.
+func newWriter(ctx context.Context, writerName, outDir string) (services.SheetWriter, error) {
+	primary, err := newDestinationWriter(ctx, writerName, outDir)
+	if err != nil || len(config.AppConfig.FanoutWriters) == 0 {
+		return primary, err
+	}
+	destinations := []services.WriterDestination{{Name: writerName, Writer: primary}}
+	for _, name := range config.AppConfig.FanoutWriters {
+		writer, err := newDestinationWriter(ctx, name, outDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s fan-out writer: %w", name, err)
+		}
+		destinations = append(destinations, services.WriterDestination{Name: name, Writer: writer})
+	}
+	multi, err := services.NewMultiWriter(destinations...)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Fanning writes out to several writers", "primary", writerName, "fanout", config.AppConfig.FanoutWriters)
+	return multi, nil
+}
+
+// newDestinationWriter builds the SheetWriter selected by writerName (sheets,
+// bigquery, excel, csv or parquet). outDir overrides PARQUET_OUTPUT for the
+// parquet writer.
+func newDestinationWriter(ctx context.Context, writerName, outDir string) (services.SheetWriter, error) {
+	switch writerName {
+	case "csv":
+		return services.NewCSVWriter(outDir), nil
+	case "parquet":
+		output := outDir
+		if output == "" {
+			output = config.AppConfig.ParquetOutput
+		}
+		return services.NewParquetWriter(
+			ctx,
+			output,
+			googleCredentials(),
+			config.AppConfig.ArchiveS3Region,
+			config.AppConfig.ArchiveS3Endpoint,
+			os.Getenv("AWS_ACCESS_KEY_ID"),
+			os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			os.Getenv("AWS_SESSION_TOKEN"),
+		)
+	case "excel":
+		return services.NewExcelOnlineWriter(
+			ctx,
+			config.AppConfig.ExcelGraphURL,
+			config.AppConfig.ExcelDriveID,
+			config.AppConfig.ExcelWorkbookID,
+			services.ExcelCredentials{
+				TenantID:     config.AppConfig.ExcelTenantID,
+				ClientID:     config.AppConfig.ExcelClientID,
+				ClientSecret: config.AppConfig.ExcelClientSecret,
+				AuthorityURL: config.AppConfig.ExcelAuthorityURL,
+			},
+			config.AppConfig.MaxRetries,
+			config.AppConfig.RetryDelay,
+		)
+	case "bigquery":
+		return services.NewBigQueryWriter(
+			ctx,
+			config.AppConfig.BigQueryProjectID,
+			config.AppConfig.BigQueryDataset,
+			config.AppConfig.BigQueryLocation,
+			googleCredentials(),
+			config.AppConfig.BigQueryLoadBatchRows,
+			config.AppConfig.MaxRetries,
+			config.AppConfig.RetryDelay,
+		)
+	default:
+		return services.NewGoogleSheetsWriter(ctx, services.GoogleSheetsWriterOptions{
+			SpreadsheetID:    config.AppConfig.SpreadsheetID,
+			Credentials:      googleCredentials(),
+			RetryMaxAttempts: config.AppConfig.MaxRetries,
+			RetryDelay:       config.AppConfig.RetryDelay,
+			WritesPerMinute:  config.AppConfig.SheetsWritesPerMinute,
+			CoalesceRows:     config.AppConfig.SheetsAppendCoalesceRows,
+			FormatSheets:     config.AppConfig.SheetsFormatting,
+			MaxRowsPerTab:    config.AppConfig.SheetsMaxRowsPerTab,
+			SafeOverwrite:    config.AppConfig.SheetsSafeOverwrite,
+			VerifyWrites:     config.AppConfig.SheetsVerifyWrites,
+			Template:         services.SheetTemplate{SpreadsheetID: config.AppConfig.SheetsTemplateSpreadsheetID, Sheet: config.AppConfig.SheetsTemplateSheet},
+			DriveFolderID:    config.AppConfig.DriveFolderID,
+		})
+	}
+}
+
+// newCache builds the configured Jacad response cache. The returned cleanup
+// function releases any connection it opened.
+func newCache() (services.ResponseCache, func()) {
+	switch config.AppConfig.CacheBackend {
+	case "memory":
+		return services.NewLRUCache(config.AppConfig.CacheMaxEntries), func() {}
+	case "redis":
+		redisCache, err := services.NewRedisCache(config.AppConfig.RedisURL, "jacad:")
+		if err != nil {
+			slog.Error("Error creating Redis cache. Jacad responses will not be cached.", "error", err)
+			return nil, func() {}
+		}
+		cache := services.NewTieredCache(services.NewLRUCache(config.AppConfig.CacheMaxEntries), redisCache, config.AppConfig.CacheTTL)
+		return cache, func() { redisCache.Close() }
+	}
+	return nil, func() {}
+}
+
+// newJobHistory opens the job history database, or returns nil when it
+// cannot be opened.
+func newJobHistory() (services.JobHistoryStore, func()) {
+	history, err := services.NewSQLiteJobHistoryStore(config.AppConfig.JobHistoryPath)
+	if err != nil {
+		slog.Error("Error opening job history. Finished jobs will not be recorded.", "error", err)
+		return nil, func() {}
+	}
+	return history, func() { history.Close() }
+}
+
+// newArchiver builds the configured raw page archiver, or nil when archival
+// is disabled or the backend cannot be set up.
+func newArchiver(ctx context.Context) services.PageArchiver {
+	switch config.AppConfig.ArchiveBackend {
+	case "gcs":
+		archiver, err := services.NewGCSArchiver(ctx, config.AppConfig.ArchiveBucket, googleCredentials())
+		if err != nil {
+			slog.Error("Error creating GCS archiver. Jacad pages will not be archived.", "error", err)
+			return nil
+		}
+		return archiver
+	case "s3":
+		archiver, err := services.NewS3Archiver(
+			config.AppConfig.ArchiveBucket,
+			config.AppConfig.ArchiveS3Region,
+			config.AppConfig.ArchiveS3Endpoint,
+			os.Getenv("AWS_ACCESS_KEY_ID"),
+			os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			os.Getenv("AWS_SESSION_TOKEN"),
+		)
+		if err != nil {
+			slog.Error("Error creating S3 archiver. Jacad pages will not be archived.", "error", err)
+			return nil
+		}
+		return archiver
+	}
+	return nil
+}
+
+// newNotifications builds the job summary dispatcher for the enabled
+// channels, or nil when none is enabled.
+func newNotifications() *notifications.Dispatcher {
+	cfg := &config.AppConfig
+	var notifiers []notifications.Notifier
+	if cfg.NotifyEmailEnabled {
+		notifiers = append(notifiers, notifications.NewEmailNotifier(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.NotifyEmailFrom, cfg.NotifyEmailTo))
+	}
+	if cfg.NotifySlackEnabled {
+		notifiers = append(notifiers, notifications.NewSlackNotifier(cfg.SlackWebhookURL))
+	}
+	if cfg.NotifyTelegramEnabled {
+		notifiers = append(notifiers, notifications.NewTelegramNotifier(cfg.TelegramBotToken, cfg.TelegramChatID))
+	}
+
+	subject, body := cfg.NotifySubjectTemplate, cfg.NotifyBodyTemplate
+	if subject == "" {
+		subject = notifications.DefaultSubjectTemplate
+	}
+	if body == "" {
+		body = notifications.DefaultBodyTemplate
+	}
+	dispatcher, err := notifications.NewDispatcher(notifiers, subject, body, cfg.NotifyOn)
+	if err != nil {
+		slog.Error("Error creating job notifications. No notifications will be sent.", "error", err)
+		return nil
+	}
+	return dispatcher
+}
+
+func newJacadClient(ctx context.Context, writer services.SheetWriter, cache services.ResponseCache, history services.JobHistoryStore) (*services.JacadClient, error) {
+	syncState := services.NewFileSyncStateStore(config.AppConfig.SyncStatePath)
+	checkpoints := services.NewCheckpointStore(config.AppConfig.CheckpointDir)
+	client := services.NewJacadClient(&config.AppConfig, writer, syncState, checkpoints, cache)
+	if path := config.AppConfig.JacadHTTPCABundle; path != "" {
+		rootCAs, err := services.LoadCABundle(path)
+		if err != nil {
+			return nil, fmt.Errorf("JACAD_HTTP_CA_BUNDLE: %w", err)
+		}
+		client.Client = services.NewJacadHTTPClient(&config.AppConfig, rootCAs)
+	}
+	if archiver := newArchiver(ctx); archiver != nil {
+		client.Archiver = archiver
+	}
+	client.Notifications = newNotifications()
+	client.History = history
+	if appSecrets != nil {
+		client.UserToken = appSecrets.UserToken
+	}
+	if config.AppConfig.OrganizationsSource == config.OrgSourceJacad {
+		if _, err := client.ReloadOrganizations(ctx); err != nil {
+			slog.Error("Error loading organizations from Jacad. The built-in organizations will be used.", "error", err)
+		}
+	}
+	return client, nil
+}
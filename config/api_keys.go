@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// APIKeyScopeAdmin is the API_KEYS scope that grants access to the admin
+// routes and pprof.
+const APIKeyScopeAdmin = "admin"
+
+type APIKey struct {
+	Name string
+	Key  string `secret:"true"`
+	// RequestsPerMinute limits calls made with this key. Zero means unlimited.
+	RequestsPerMinute int
+	// Admin lets the key call the /api/v1/admin routes and /debug/pprof.
+	Admin bool
+}
+
+// parseAPIKeys parses API_KEYS entries in the form
+// "name:key[:requestsPerMinute][:admin]".
+func parseAPIKeys(value string) ([]APIKey, error) {
+	var keys []APIKey
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 4 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid API key entry '%s': expected name:key[:requestsPerMinute][:admin]", parts[0])
+		}
+
+		key := APIKey{Name: parts[0], Key: parts[1]}
+		options := parts[2:]
+		if n := len(options); n > 0 && options[n-1] == APIKeyScopeAdmin {
+			key.Admin = true
+			options = options[:n-1]
+		}
+		switch len(options) {
+		case 0:
+		case 1:
+			limit, err := strconv.Atoi(options[0])
+			if err != nil || limit < 0 {
+				return nil, fmt.Errorf("invalid rate limit for API key '%s': %s", parts[0], options[0])
+			}
+			key.RequestsPerMinute = limit
+		default:
+			return nil, fmt.Errorf("invalid API key entry '%s': expected name:key[:requestsPerMinute][:admin]", parts[0])
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
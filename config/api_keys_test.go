@@ -0,0 +1,55 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAPIKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []APIKey
+		wantErr string
+	}{
+		{name: "empty", value: " , "},
+		{
+			name:  "limits and admin scope",
+			value: "ops:ops-key:60:admin, bi:bi-key:30,root:root-key:admin,cron:cron-key",
+			want: []APIKey{
+				{Name: "ops", Key: "ops-key", RequestsPerMinute: 60, Admin: true},
+				{Name: "bi", Key: "bi-key", RequestsPerMinute: 30},
+				{Name: "root", Key: "root-key", Admin: true},
+				{Name: "cron", Key: "cron-key"},
+			},
+		},
+		{name: "missing key", value: "ops", wantErr: "invalid API key entry 'ops'"},
+		{name: "empty key", value: "ops::admin", wantErr: "invalid API key entry 'ops'"},
+		{name: "negative limit", value: "ops:ops-key:-1", wantErr: "invalid rate limit for API key 'ops'"},
+		{name: "unknown scope", value: "ops:ops-key:root", wantErr: "invalid rate limit for API key 'ops'"},
+		{name: "unknown scope after limit", value: "ops:ops-key:60:root", wantErr: "invalid API key entry 'ops'"},
+		{name: "scope before limit", value: "ops:ops-key:admin:60", wantErr: "invalid API key entry 'ops'"},
+		{name: "too many fields", value: "ops:ops-key:60:admin:x", wantErr: "invalid API key entry 'ops'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAPIKeys(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseAPIKeys(%q) = %v, want an error containing %q", tt.value, err, tt.wantErr)
+				}
+				if strings.Contains(err.Error(), "ops-key") {
+					t.Errorf("parseAPIKeys(%q) error leaks the key: %v", tt.value, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAPIKeys(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
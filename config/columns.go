@@ -0,0 +1,117 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Cell types a column can be coerced to.
+const (
+	ColumnText   = "text"
+	ColumnNumber = "number"
+	ColumnDate   = "date"
+)
+
+// How DATE_CELLS writes date cells.
+const (
+	DateCellsNative = "native"
+	DateCellsSerial = "serial"
+	DateCellsText   = "text"
+)
+
+// Locales NUMBER_LOCALE selects: pt-BR writes 1.234,5 and en-US 1,234.5.
+const (
+	NumberLocalePtBR = "pt-BR"
+	NumberLocaleEnUS = "en-US"
+)
+
+type columnsFile struct {
+	Columns []Column `json:"columns" yaml:"columns"`
+}
+
+// loadColumns reads the column mapping from a YAML/JSON file or, failing that,
+// from a comma-separated "field:Header" list, where computed columns are
+// written "field=op(source):Header", e.g.
+// "diasDesdeMatricula=daysSince(dataMatricula):Dias". It returns nil when
+// neither is set.
+func loadColumns(path, inline string) ([]Column, error) {
+	var columns []Column
+
+	switch {
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read column mapping file '%s': %w", path, err)
+		}
+
+		var file columnsFile
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &file)
+		default:
+			err = json.Unmarshal(data, &file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse column mapping file '%s': %w", path, err)
+		}
+		columns = file.Columns
+	case inline != "":
+		for _, entry := range strings.Split(inline, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			field, header, _ := strings.Cut(entry, ":")
+			column, err := parseInlineColumn(field)
+			if err != nil {
+				return nil, err
+			}
+			column.Header = strings.TrimSpace(header)
+			columns = append(columns, column)
+		}
+	default:
+		return nil, nil
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("column mapping is empty")
+	}
+	for i := range columns {
+		if columns[i].Field == "" {
+			return nil, fmt.Errorf("column %d has no field", i+1)
+		}
+		if columns[i].Header == "" {
+			columns[i].Header = columns[i].Field
+		}
+		if (columns[i].Compute == "") != (columns[i].Source == "") {
+			return nil, fmt.Errorf("computed column '%s' needs both a compute op and a source field", columns[i].Field)
+		}
+		columns[i].Type = strings.ToLower(strings.TrimSpace(columns[i].Type))
+		switch columns[i].Type {
+		case "", ColumnText, ColumnNumber, ColumnDate:
+		default:
+			return nil, fmt.Errorf("column '%s' has type '%s': expected '%s', '%s' or '%s'", columns[i].Field, columns[i].Type, ColumnText, ColumnNumber, ColumnDate)
+		}
+	}
+	return columns, nil
+}
+
+// parseInlineColumn parses the field part of an inline column entry: a plain
+// field name or "field=op(source)".
+func parseInlineColumn(field string) (Column, error) {
+	name, expr, computed := strings.Cut(strings.TrimSpace(field), "=")
+	if !computed {
+		return Column{Field: name}, nil
+	}
+	expr = strings.TrimSpace(expr)
+	op, source, ok := strings.Cut(strings.TrimSuffix(expr, ")"), "(")
+	if !ok || !strings.HasSuffix(expr, ")") {
+		return Column{}, fmt.Errorf("invalid computed column '%s': expected field=op(source)", field)
+	}
+	return Column{Field: strings.TrimSpace(name), Compute: strings.TrimSpace(op), Source: strings.TrimSpace(source)}, nil
+}
@@ -1,87 +1,757 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"io/fs"
+	"log/slog"
+	"slices"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
-func Init() {
-	fmt.Println("Initializing configuration...")
-	err := godotenv.Load()
+// Options select the files Init reads settings from besides the environment.
+type Options struct {
+	// EnvFile is a dotenv file loaded into the environment; empty means
+	// ".env", which may be missing.
+	EnvFile string
+	// ConfigFile is the YAML settings file, replacing CONFIG_FILE.
+	ConfigFile string
+}
+
+// Init loads the env file into the environment and then the configuration
+// into AppConfig. It fails on settings that cannot be parsed rather than
+// falling back to their defaults.
+func Init(opts Options) error {
+	slog.Info("Initializing configuration...")
+	if err := loadEnvFile(opts.EnvFile); err != nil {
+		return err
+	}
+
+	cfg, err := LoadFile(opts.ConfigFile)
+	if err != nil {
+		return err
+	}
+	AppConfig = cfg
+	slog.Info("Configuration loaded", "profile", cfg.Profile, "configFile", cfg.ConfigFile)
+	return nil
+}
+
+// Writers returns WRITER followed by FANOUT_WRITERS. The first is the primary
+// writer jobs read back from and fail with; the others are written alongside
+// it and their failures are only reported.
+func (c *Config) Writers() []string {
+	return append([]string{c.Writer}, c.FanoutWriters...)
+}
+
+// UsesWriter reports whether name is WRITER or one of FANOUT_WRITERS.
+func (c *Config) UsesWriter(name string) bool {
+	return slices.Contains(c.Writers(), name)
+}
+
+// loadEnvFile loads path, or .env when path is empty, into the environment
+// without overriding the variables already set. A missing .env is fine, as
+// containers usually get every setting from the environment; a missing
+// explicit path is not.
+func loadEnvFile(path string) error {
+	explicit := path != ""
+	if !explicit {
+		path = ".env"
+	}
+	if err := godotenv.Load(path); err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			slog.Info("No .env file found. Reading settings from the environment.")
+			return nil
+		}
+		return fmt.Errorf("failed to load env file '%s': %w", path, err)
+	}
+	slog.Info("Loaded env file successfully", "path", path)
+	return nil
+}
+
+// Load builds the configuration from its layers, in increasing priority: the
+// built-in defaults, the defaults of the CONFIG_PROFILE profile, the YAML
+// file named by CONFIG_FILE, that file's section for the profile, and the
+// environment. Every setting that fails to parse is reported at once.
+func Load() (Config, error) {
+	return LoadFile("")
+}
+
+// LoadFile is Load with the settings file at path instead of CONFIG_FILE,
+// which is still used when path is empty.
+func LoadFile(path string) (Config, error) {
+	s, profile, path, err := newSource(path)
 	if err != nil {
-		log.Printf("Error loading .env file: %v", err)
+		return Config{}, err
+	}
+	c := Defaults()
+	c.Profile = profile
+	c.ConfigFile = path
+
+	s.str("LISTEN_ADDR", &c.ListenAddr)
+	s.str("GRPC_LISTEN_ADDR", &c.GRPCListenAddr)
+	s.str("USER_TOKEN", &c.UserToken)
+	s.str("API_BASE", &c.APIBase)
+	s.str("JACAD_API_VERSION", &c.JacadAPIVersion)
+	s.str("JACAD_V2_PATH_PREFIX", &c.JacadV2PathPrefix)
+	c.JacadProfiles = loadJacadProfiles(s.get("JACAD_PROFILES"), s.get)
+	s.duration("AUTH_TOKEN_EXPIRY", &c.AuthTokenExpiry, false)
+	s.duration("AUTH_REFRESH_MARGIN", &c.AuthRefreshMargin, true)
+	s.str("SPREADSHEET_ID", &c.SpreadsheetID)
+	s.str("GOOGLE_CREDENTIALS_JSON_BASE64", &c.CredentialsJSONBase64)
+	s.str("GOOGLE_WORKLOAD_IDENTITY_CONFIG", &c.GoogleWorkloadIdentityConfig)
+	s.str("GOOGLE_IMPERSONATE_SERVICE_ACCOUNT", &c.GoogleImpersonateServiceAccount)
+	s.list("GOOGLE_IMPERSONATE_DELEGATES", &c.GoogleImpersonateDelegates)
+	s.str("SECRETS_PROVIDER", &c.SecretsProvider)
+	s.duration("SECRETS_REFRESH_INTERVAL", &c.SecretsRefreshInterval, true)
+	s.str("SECRET_USER_TOKEN", &c.SecretUserToken)
+	s.str("SECRET_GOOGLE_CREDENTIALS", &c.SecretGoogleCredentials)
+	s.str("SECRETS_GCP_PROJECT", &c.SecretsGCPProject)
+	s.str("VAULT_ADDR", &c.VaultAddr)
+	s.str("VAULT_TOKEN", &c.VaultToken)
+	s.str("VAULT_KV_MOUNT", &c.VaultKVMount)
+	s.str("SYNC_STATE_PATH", &c.SyncStatePath)
+	s.str("CHECKPOINT_DIR", &c.CheckpointDir)
+	s.str("JOB_HISTORY_PATH", &c.JobHistoryPath)
+	s.str("LOG_FORMAT", &c.LogFormat)
+	s.str("LOG_LEVEL", &c.LogLevel)
+	s.boolean("TRACING_ENABLED", &c.TracingEnabled)
+	s.str("TRACING_OTLP_ENDPOINT", &c.TracingOTLPEndpoint)
+	s.float("TRACING_SAMPLE_RATIO", &c.TracingSampleRatio, 0)
+	s.boolean("ACCESS_LOG", &c.AccessLog)
+	s.float("ACCESS_LOG_SAMPLE_RATIO", &c.AccessLogSampleRatio, 0)
+	s.list("ACCESS_LOG_REDACT_PARAMS", &c.AccessLogRedactParams)
+	s.integer("PAGE_SIZE", &c.PageSize, 1)
+	s.integer("MIN_PAGE_SIZE", &c.MinPageSize, 1)
+	s.integer("MAX_PAGE_SIZE", &c.MaxPageSize, 1)
+	s.integer("MAX_PAGES_PER_BATCH", &c.MaxPagesPerBatch, 1)
+	s.integer("PIPELINE_MEMORY_MB", &c.PipelineMemoryMB, 0)
+	s.integer("DRY_RUN_PREVIEW_ROWS", &c.DryRunPreviewRows, 1)
+	s.integer("MAX_CONCURRENT_JOBS", &c.MaxConcurrentJobs, 0)
+	s.duration("DRAIN_TIMEOUT", &c.DrainTimeout, false)
+	s.integer("JOB_RETRY_MAX_ATTEMPTS", &c.JobRetryMaxAttempts, 1)
+	s.duration("JOB_RETRY_BACKOFF", &c.JobRetryBackoff, false)
+	s.duration("JOB_RETRY_MAX_BACKOFF", &c.JobRetryMaxBackoff, false)
+	s.duration("FETCH_TIMEOUT", &c.FetchTimeout, false)
+	s.duration("MAX_FETCH_TIMEOUT", &c.MaxFetchTimeout, false)
+	s.float("JACAD_RATE_LIMIT_RPS", &c.JacadRateLimitRPS, 0)
+	s.integer("JACAD_RATE_LIMIT_BURST", &c.JacadRateLimitBurst, 1)
+	s.integer("MAX_PARALLEL_REQUESTS", &c.MaxParallelRequests, 1)
+	s.duration("JACAD_HTTP_TIMEOUT", &c.JacadHTTPTimeout, false)
+	s.integer("JACAD_HTTP_MAX_IDLE_CONNS_PER_HOST", &c.JacadHTTPMaxIdleConnsPerHost, 0)
+	s.duration("JACAD_HTTP_IDLE_CONN_TIMEOUT", &c.JacadHTTPIdleConnTimeout, false)
+	s.duration("JACAD_HTTP_DIAL_TIMEOUT", &c.JacadHTTPDialTimeout, false)
+	s.duration("JACAD_HTTP_TLS_HANDSHAKE_TIMEOUT", &c.JacadHTTPTLSHandshakeTimeout, false)
+	s.str("JACAD_HTTP_PROXY", &c.JacadHTTPProxy)
+	s.str("JACAD_HTTP_CA_BUNDLE", &c.JacadHTTPCABundle)
+	s.integer("JACAD_MIN_CONCURRENCY", &c.JacadMinConcurrency, 1)
+	s.duration("JACAD_CONCURRENCY_COOLDOWN", &c.JacadConcurrencyCooldown, true)
+	s.integer("JACAD_BREAKER_THRESHOLD", &c.JacadBreakerThreshold, 0)
+	s.duration("JACAD_BREAKER_COOLDOWN", &c.JacadBreakerCooldown, false)
+	s.boolean("JACAD_COMPRESSION", &c.JacadCompression)
+	s.boolean("JACAD_CONDITIONAL_REQUESTS", &c.JacadConditionalRequests)
+	s.duration("JACAD_CONDITIONAL_TTL", &c.JacadConditionalTTL, false)
+	s.integer("SHEETS_WRITES_PER_MINUTE", &c.SheetsWritesPerMinute, 0)
+	s.integer("SHEETS_APPEND_COALESCE_ROWS", &c.SheetsAppendCoalesceRows, 0)
+	s.integer("SHEETS_MAX_ROWS_PER_TAB", &c.SheetsMaxRowsPerTab, 0)
+	s.boolean("SHEETS_SAFE_OVERWRITE", &c.SheetsSafeOverwrite)
+	s.boolean("SHEETS_VERIFY_WRITES", &c.SheetsVerifyWrites)
+	s.str("SHEETS_TEMPLATE_SPREADSHEET_ID", &c.SheetsTemplateSpreadsheetID)
+	s.str("SHEETS_TEMPLATE_SHEET", &c.SheetsTemplateSheet)
+	s.boolean("SHEETS_FORMATTING", &c.SheetsFormatting)
+	s.str("DRIVE_FOLDER_ID", &c.DriveFolderID)
+	s.list("SPREADSHEET_ALLOWLIST", &c.SpreadsheetAllowlist)
+	s.str("ORGANIZATIONS_SOURCE", &c.OrganizationsSource)
+	s.str("ORGANIZATIONS_FILE", &c.OrganizationsFile)
+	s.str("ORG_SPREADSHEETS", &c.OrgSpreadsheets)
+	if endpoint := s.get("ORGANIZATIONS_ENDPOINT"); endpoint != "" {
+		c.Endpoints["ORGANIZATIONS"] = endpoint
+	}
+	// Jacad organizations are loaded once the client is up.
+	if c.OrganizationsSource != OrgSourceJacad {
+		if orgs, err := c.LoadOrganizations(); err != nil {
+			s.fail("organizations: %s", err)
+		} else if err := c.Organizations.Replace(orgs); err != nil {
+			s.fail("organizations: %s", err)
+		}
+	}
+	if limits, err := parseEndpointConcurrency(s.get("JACAD_ENDPOINT_CONCURRENCY"), c.Endpoints); err != nil {
+		s.fail("JACAD_ENDPOINT_CONCURRENCY: %s", err)
+	} else if limits != nil {
+		c.JacadEndpointConcurrency = limits
+	}
+	if keys, err := parseAPIKeys(s.get("API_KEYS")); err != nil {
+		s.fail("API_KEYS: %s", err)
+	} else {
+		c.APIKeys = keys
+	}
+	s.boolean("API_REQUIRE_HMAC", &c.APIRequireHMAC)
+	s.boolean("PPROF_ENABLED", &c.PprofEnabled)
+	s.duration("RUNTIME_STATS_INTERVAL", &c.RuntimeStatsInterval, true)
+	s.str("COURSES_SHEET", &c.CoursesSheet)
+	s.str("CLASSES_SHEET", &c.ClassesSheet)
+	s.str("ATTENDANCE_SHEET", &c.AttendanceSheet)
+	s.str("CANDIDATES_SHEET", &c.CandidatesSheet)
+	s.boolean("AUDIT_TAB", &c.AuditTab)
+	s.str("AUDIT_SHEET", &c.AuditSheet)
+	s.duration("SHEET_STALE_AFTER", &c.SheetStaleAfter, true)
+	s.str("SHEET_NAME_TEMPLATE", &c.SheetNameTemplate)
+	s.str("GROUP_SHEET_NAME_TEMPLATE", &c.GroupSheetNameTemplate)
+	s.list("ENROLLMENT_STATUSES", &c.EnrollmentStatuses)
+	s.list("EDITAL_STATUS", &c.EditalStatus)
+	s.str("CACHE_BACKEND", &c.CacheBackend)
+	s.duration("CACHE_TTL", &c.CacheTTL, false)
+	s.integer("CACHE_MAX_ENTRIES", &c.CacheMaxEntries, 1)
+	s.str("REDIS_URL", &c.RedisURL)
+	s.str("ARCHIVE_BACKEND", &c.ArchiveBackend)
+	s.str("ARCHIVE_BUCKET", &c.ArchiveBucket)
+	s.str("ARCHIVE_PATH_TEMPLATE", &c.ArchivePathTemplate)
+	s.str("ARCHIVE_S3_REGION", &c.ArchiveS3Region)
+	s.str("ARCHIVE_S3_ENDPOINT", &c.ArchiveS3Endpoint)
+	s.str("NOTIFY_ON", &c.NotifyOn)
+	s.str("NOTIFY_SUBJECT_TEMPLATE", &c.NotifySubjectTemplate)
+	s.str("NOTIFY_BODY_TEMPLATE", &c.NotifyBodyTemplate)
+	s.boolean("NOTIFY_EMAIL_ENABLED", &c.NotifyEmailEnabled)
+	s.str("SMTP_HOST", &c.SMTPHost)
+	s.integer("SMTP_PORT", &c.SMTPPort, 1)
+	s.str("SMTP_USERNAME", &c.SMTPUsername)
+	s.str("SMTP_PASSWORD", &c.SMTPPassword)
+	s.str("NOTIFY_EMAIL_FROM", &c.NotifyEmailFrom)
+	s.list("NOTIFY_EMAIL_TO", &c.NotifyEmailTo)
+	s.boolean("NOTIFY_SLACK_ENABLED", &c.NotifySlackEnabled)
+	s.str("SLACK_WEBHOOK_URL", &c.SlackWebhookURL)
+	s.boolean("NOTIFY_TELEGRAM_ENABLED", &c.NotifyTelegramEnabled)
+	s.str("TELEGRAM_BOT_TOKEN", &c.TelegramBotToken)
+	s.str("TELEGRAM_CHAT_ID", &c.TelegramChatID)
+	s.boolean("STARTUP_SELF_CHECK", &c.StartupSelfCheck)
+	s.duration("READINESS_CHECK_INTERVAL", &c.ReadinessCheckInterval, false)
+	s.duration("METADATA_TTL", &c.MetadataTTL, false)
+	s.duration("METADATA_REFRESH_INTERVAL", &c.MetadataRefresh, true)
+	s.str("WRITER", &c.Writer)
+	s.list("FANOUT_WRITERS", &c.FanoutWriters)
+	s.str("PARQUET_OUTPUT", &c.ParquetOutput)
+	s.str("BIGQUERY_PROJECT_ID", &c.BigQueryProjectID)
+	s.str("BIGQUERY_DATASET", &c.BigQueryDataset)
+	s.str("BIGQUERY_LOCATION", &c.BigQueryLocation)
+	s.integer("BIGQUERY_LOAD_BATCH_ROWS", &c.BigQueryLoadBatchRows, 1)
+	s.str("EXCEL_DRIVE_ID", &c.ExcelDriveID)
+	s.str("EXCEL_WORKBOOK_ID", &c.ExcelWorkbookID)
+	s.str("EXCEL_TENANT_ID", &c.ExcelTenantID)
+	s.str("EXCEL_CLIENT_ID", &c.ExcelClientID)
+	s.str("EXCEL_CLIENT_SECRET", &c.ExcelClientSecret)
+	s.str("EXCEL_GRAPH_URL", &c.ExcelGraphURL)
+	s.str("EXCEL_AUTHORITY_URL", &c.ExcelAuthorityURL)
+	if policy, err := parseRedactionPolicy(s.get("REDACTION_POLICY")); err != nil {
+		s.fail("REDACTION_POLICY: %s", err)
+	} else if policy != nil {
+		c.RedactionPolicy = policy
+	}
+	s.str("REDACTION_SALT", &c.RedactionSalt)
+	if columns, err := loadColumns(s.get("COLUMNS_CONFIG_PATH"), s.get("COLUMNS")); err != nil {
+		s.fail("column mapping: %s", err)
+	} else if columns != nil {
+		c.Columns = columns
+	}
+	s.str("DATE_CELLS", &c.DateCells)
+	s.str("DATE_TEXT_LAYOUT", &c.DateTextLayout)
+	s.str("NUMBER_LOCALE", &c.NumberLocale)
+	s.list("DATA_QUALITY_RULES", &c.DataQualityRules)
+	s.integer("SNAPSHOT_RETENTION_DAYS", &c.SnapshotRetentionDays, 0)
+	if transforms, err := loadTransforms(s.get("TRANSFORMS_CONFIG_PATH"), s.get("TRANSFORMS")); err != nil {
+		s.fail("transforms: %s", err)
+	} else {
+		c.Transforms = transforms
+	}
+	s.str("PRESETS_FILE", &c.PresetsFile)
+	if presets, err := loadPresets(c.PresetsFile); err != nil {
+		s.fail("presets: %s", err)
+	} else {
+		c.Presets = presets
 	}
 
-	log.Println("Loaded .env file successfully")
-	AppConfig.UserToken = os.Getenv("USER_TOKEN")
-	AppConfig.APIBase = os.Getenv("API_BASE")
-	AppConfig.SpreadsheetID = os.Getenv("SPREADSHEET_ID")
-	AppConfig.CredentialsJSONBase64 = os.Getenv("GOOGLE_CREDENTIALS_JSON_BASE64")
+	if len(s.problems) > 0 {
+		return Config{}, &ValidationError{Problems: s.problems}
+	}
+	return c, nil
 }
 
 type Config struct {
-	UserToken           string
-	APIBase             string
-	Endpoints           map[string]string
-	Organizations       map[string]Organization
+	// Profile and ConfigFile record the CONFIG_PROFILE and CONFIG_FILE the
+	// configuration was loaded with.
+	Profile    string
+	ConfigFile string
+	ListenAddr string
+	// GRPCListenAddr is the address of the gRPC API; empty disables it.
+	GRPCListenAddr string
+	UserToken      string `secret:"true"`
+	APIBase        string
+	// JacadProfiles are additional Jacad instances selected by the tenant
+	// request parameter; the default instance is APIBase and UserToken.
+	JacadProfiles map[string]JacadProfile
+	Endpoints     map[string]string
+	// JacadAPIVersion is the Jacad API version to call: JacadAPIv1,
+	// JacadAPIv2, or JacadAPIAuto to probe each instance once and fall back
+	// to v1. v2 paths are the Endpoints under JacadV2PathPrefix.
+	JacadAPIVersion   string
+	JacadV2PathPrefix string
+	// Organizations come from OrganizationsSource: the built-in list, the
+	// OrganizationsFile or the Jacad ORGANIZATIONS endpoint. They can be
+	// reloaded at runtime.
+	Organizations       *OrgDirectory
+	OrganizationsSource string
+	OrganizationsFile   string
 	DefaultOrgSheet     string
-	PageSize            int
-	MaxPagesPerBatch    int
+	CoursesSheet        string
+	// ClassesSheet is suffixed with the period, e.g. "Turmas | Período ID 42".
+	ClassesSheet string
+	// AttendanceSheet is suffixed with the class, e.g. "Frequência | Turma 7".
+	AttendanceSheet string
+	// CandidatesSheet is suffixed with the process notice, e.g.
+	// "Inscrições | Edital 12".
+	CandidatesSheet string
+	// SheetNameTemplate names the enrollment sheet of each organization and
+	// GroupSheetNameTemplate the sheets of grouped fetches; see
+	// RenderSheetName for their syntax.
+	SheetNameTemplate      string
+	GroupSheetNameTemplate string
+	// AuditTab records every job that wrote to the spreadsheet in the
+	// AuditSheet tab, one row per job and spreadsheet written. Only the
+	// Google Sheets writer, or a fan-out with it as primary, keeps the tab;
+	// other writers skip it.
+	AuditTab   bool
+	AuditSheet string
+	// SheetStaleAfter is how long after its last refresh GET /sheets/status
+	// flags a sheet as stale; 0 flags none.
+	SheetStaleAfter time.Duration
+	// EnrollmentStatuses lists the statusMatricula values accepted by the API.
+	// Empty accepts any value.
+	EnrollmentStatuses []string
+	// PageSize is the default Jacad page size; requests may pick their own
+	// pageSize between MinPageSize and MaxPageSize.
+	PageSize         int
+	MinPageSize      int
+	MaxPageSize      int
+	MaxPagesPerBatch int
+	// PipelineMemoryMB caps, in MiB, the enrollments a streamed fetch holds
+	// between fetching and writing them; 0 removes the cap.
+	PipelineMemoryMB int
+	// MaxParallelRequests caps concurrent Jacad requests across all
+	// endpoints; the adaptive limit moves between JacadMinConcurrency and it.
 	MaxParallelRequests int
 	RetryDelay          time.Duration
 	MaxRetries          int
-	AuthTokenExpiry     time.Duration
-	SpreadsheetID       string
-	CredentialsJSONBase64 string
-	EditalStatus        []string
+	// JacadHTTPTimeout bounds each Jacad HTTP request. Up to
+	// JacadHTTPMaxIdleConnsPerHost idle connections per host (0 matches
+	// MaxParallelRequests) are kept for JacadHTTPIdleConnTimeout, so the
+	// worker pool reuses connections instead of reconnecting.
+	JacadHTTPTimeout             time.Duration
+	JacadHTTPMaxIdleConnsPerHost int
+	JacadHTTPIdleConnTimeout     time.Duration
+	JacadHTTPDialTimeout         time.Duration
+	JacadHTTPTLSHandshakeTimeout time.Duration
+	// JacadHTTPProxy is the proxy Jacad requests go through; empty uses
+	// HTTPS_PROXY, HTTP_PROXY and NO_PROXY. JacadHTTPCABundle is a PEM file
+	// of CA certificates trusted besides the system ones, e.g. for a TLS
+	// inspecting proxy.
+	JacadHTTPProxy    string
+	JacadHTTPCABundle string
+	// AuthTokenExpiry is the token lifetime assumed when the auth response
+	// does not state one; tokens are renewed AuthRefreshMargin before expiry.
+	AuthTokenExpiry   time.Duration
+	AuthRefreshMargin time.Duration
+	SpreadsheetID     string
+	// SheetsWritesPerMinute is the write-request budget per minute for the
+	// Sheets API (0 disables throttling); SheetsAppendCoalesceRows buffers
+	// appends per tab until that many rows are pending (0 disables).
+	SheetsWritesPerMinute    int
+	SheetsAppendCoalesceRows int
+	// SheetsFormatting formats overwritten tabs: bold frozen header, date
+	// columns, auto-sized columns and row banding.
+	SheetsFormatting bool
+	// SheetsMaxRowsPerTab splits overwritten sheets into numbered tabs of at
+	// most this many rows (0 disables).
+	SheetsMaxRowsPerTab int
+	// SheetsSafeOverwrite writes overwritten sheets to a temporary tab that
+	// replaces the target only once every row is written, so readers never
+	// see a blank or partial sheet. The replaced tab gets a new sheetId, which
+	// breaks links, formulas, charts and filters pointing at it, so it is off
+	// by default.
+	SheetsSafeOverwrite bool
+	// SheetsVerifyWrites reads back the row count of every overwritten sheet
+	// and fails the job when it differs from what was written.
+	SheetsVerifyWrites bool
+	// SheetsTemplateSpreadsheetID and SheetsTemplateSheet name a template tab
+	// copied, with its formatting, formulas and pivot tables, whenever a tab
+	// is created; an empty SheetsTemplateSheet copies the first tab. Empty
+	// SheetsTemplateSpreadsheetID creates blank tabs.
+	SheetsTemplateSpreadsheetID string
+	SheetsTemplateSheet         string
+	// DriveFolderID is the Drive folder new per-job spreadsheets are created
+	// in; empty disables the newSpreadsheet option.
+	DriveFolderID string
+	// SpreadsheetAllowlist lists the spreadsheets, besides SPREADSHEET_ID,
+	// that requests may write to with the spreadsheetId parameter; empty
+	// disables the parameter.
+	SpreadsheetAllowlist []string
+	// OrgSpreadsheets is ORG_SPREADSHEETS, "org:spreadsheetId" entries that
+	// override the spreadsheet of each loaded organization.
+	OrgSpreadsheets       string
+	CredentialsJSONBase64 string `secret:"true"`
+	EditalStatus          []string
+	SyncStatePath         string
+	LogFormat             string
+	LogLevel              string
+	// GoogleWorkloadIdentityConfig is the path of a Workload Identity
+	// Federation credential configuration (an "external_account" JSON), used
+	// instead of a service account key.
+	GoogleWorkloadIdentityConfig string
+	// GoogleImpersonateServiceAccount is the service account Google APIs are
+	// called as, through the IAM credentials API, with the resolved
+	// credentials only used to impersonate it; GoogleImpersonateDelegates is
+	// the optional delegation chain leading to it.
+	GoogleImpersonateServiceAccount string
+	GoogleImpersonateDelegates      []string
+	// SecretsProvider is where SecretUserToken and SecretGoogleCredentials,
+	// secret names that replace USER_TOKEN and the Google credentials when
+	// set, are read from at startup: none, gcp (Secret Manager in
+	// SecretsGCPProject) or vault (the KV v2 engine at VaultKVMount). They
+	// are read again every SecretsRefreshInterval (0 disables) so rotated
+	// secrets are picked up without a restart.
+	SecretsProvider         string
+	SecretsRefreshInterval  time.Duration
+	SecretUserToken         string
+	SecretGoogleCredentials string
+	SecretsGCPProject       string
+	VaultAddr               string
+	VaultToken              string `secret:"true"`
+	VaultKVMount            string
+	// TracingEnabled exports OpenTelemetry spans over OTLP/HTTP to
+	// TracingOTLPEndpoint, or to the OTEL_EXPORTER_OTLP_* endpoint when it is
+	// empty, sampling TracingSampleRatio of the traces started here.
+	TracingEnabled      bool
+	TracingOTLPEndpoint string
+	TracingSampleRatio  float64
+	DryRunPreviewRows   int
+	Columns             []Column
+	// DateCells is how date columns are written: as time.Time values left to
+	// each writer ("native"), as spreadsheet serial day numbers ("serial") or
+	// as text in DateTextLayout ("text"). NumberLocale is the decimal and
+	// thousands separators number columns are parsed from and text columns
+	// write numbers with.
+	DateCells      string
+	DateTextLayout string
+	NumberLocale   string
+	// AccessLog writes a JSON access-log line for API requests: every failed
+	// one and AccessLogSampleRatio of the rest. The query parameters named
+	// in AccessLogRedactParams are logged as "REDACTED".
+	AccessLog             bool
+	AccessLogSampleRatio  float64
+	AccessLogRedactParams []string
+	// Transforms rewrite enrollment fields, in order, before rows are mapped.
+	Transforms []Transform
+	// Presets are the named fetch-enrollments parameter sets read from
+	// PresetsFile.
+	PresetsFile string
+	Presets     map[string]Preset
+	// DataQualityRules are the checks every fetched enrollment must pass;
+	// failing ones go to the rejects tab instead of their sheet. Empty
+	// disables the checks.
+	DataQualityRules []string
+	// SnapshotRetentionDays is how long snapshot tabs are kept; older ones
+	// are deleted after each snapshot run. 0 keeps them all.
+	SnapshotRetentionDays int
+	// RedactionPolicy maps enrollment fields to the strategy used when a
+	// fetch is anonymized; RedactionSalt keys the hash strategy.
+	RedactionPolicy map[string]string
+	RedactionSalt   string `secret:"true"`
+	DrainTimeout    time.Duration
+	// MaxConcurrentJobs caps the fetch and export jobs the server runs at
+	// once; further requests wait in a queue (0 disables the limit).
+	MaxConcurrentJobs int
+	// JobRetryMaxAttempts is how many times a queued fetch that fails is run
+	// in all (1 disables retries). Retries are enqueued again after
+	// JobRetryBackoff, doubled for each further retry up to
+	// JobRetryMaxBackoff.
+	JobRetryMaxAttempts int
+	JobRetryBackoff     time.Duration
+	JobRetryMaxBackoff  time.Duration
+	// FetchTimeout bounds a fetch or export request unless the caller asks for
+	// timeoutMinutes, which is capped at MaxFetchTimeout.
+	FetchTimeout    time.Duration
+	MaxFetchTimeout time.Duration
+	// JacadRateLimitRPS and JacadRateLimitBurst size the token bucket of
+	// each Jacad instance, i.e. API_BASE and every distinct profile base.
+	JacadRateLimitRPS        float64
+	JacadRateLimitBurst      int
+	JacadMinConcurrency      int
+	JacadConcurrencyCooldown time.Duration
+	// JacadEndpointConcurrency caps concurrent requests per endpoint, keyed
+	// like Endpoints, below MaxParallelRequests so one endpoint cannot take
+	// every slot. Endpoints without an entry only share the global cap.
+	JacadEndpointConcurrency map[string]int
+	// JacadBreakerThreshold consecutive failures open the circuit breaker; 0 disables it.
+	JacadBreakerThreshold int
+	JacadBreakerCooldown  time.Duration
+	// JacadCompression asks Jacad for gzip responses and decodes them itself,
+	// so the bytes saved can be reported.
+	JacadCompression bool
+	// JacadConditionalRequests revalidates pages with If-None-Match and
+	// If-Modified-Since when Jacad sent an ETag or Last-Modified for them.
+	// Validated bodies are kept in the response cache for JacadConditionalTTL,
+	// so it needs CACHE_BACKEND set.
+	JacadConditionalRequests bool
+	JacadConditionalTTL      time.Duration
+	CheckpointDir            string
+	// JobHistoryPath is the SQLite database finished jobs are recorded in.
+	JobHistoryPath string
+	APIKeys        []APIKey
+	APIRequireHMAC bool
+	APIHMACMaxSkew time.Duration
+	Writer         string
+	// FanoutWriters receive a copy of every write to Writer; see Writers.
+	FanoutWriters []string
+	// PprofEnabled serves the net/http/pprof handlers under /debug/pprof
+	// behind the API key auth. RuntimeStatsInterval is how often the heap and
+	// goroutine gauges of /metrics are sampled; 0 disables them.
+	PprofEnabled         bool
+	RuntimeStatsInterval time.Duration
+	// ParquetOutput is the directory or gs:// or s3:// URL WRITER=parquet
+	// writes under; s3:// outputs use the ARCHIVE_S3_* settings.
+	ParquetOutput          string
+	StartupSelfCheck       bool
+	ReadinessCheckInterval time.Duration
+	MetadataTTL            time.Duration
+	MetadataRefresh        time.Duration
+	CacheBackend           string
+	CacheTTL               time.Duration
+	CacheMaxEntries        int
+	RedisURL               string `secret:"true"`
+	ArchiveBackend         string
+	ArchiveBucket          string
+	ArchivePathTemplate    string
+	ArchiveS3Region        string
+	ArchiveS3Endpoint      string
+	// NotifyOn is "always" or "failure". Empty templates use the defaults of
+	// the notifications package.
+	NotifyOn              string
+	NotifySubjectTemplate string
+	NotifyBodyTemplate    string
+	NotifyEmailEnabled    bool
+	SMTPHost              string
+	SMTPPort              int
+	SMTPUsername          string
+	SMTPPassword          string `secret:"true"`
+	NotifyEmailFrom       string
+	NotifyEmailTo         []string
+	NotifySlackEnabled    bool
+	SlackWebhookURL       string `secret:"true"`
+	NotifyTelegramEnabled bool
+	TelegramBotToken      string `secret:"true"`
+	TelegramChatID        string
+	BigQueryProjectID     string
+	BigQueryDataset       string
+	BigQueryLocation      string
+	BigQueryLoadBatchRows int
+	// ExcelDriveID and ExcelWorkbookID locate the workbook WRITER=excel
+	// writes to; ORG_SPREADSHEETS and organizations' spreadsheetId then name
+	// workbook item IDs in the same drive. The Excel* credentials are the
+	// Microsoft Entra app it signs in as, and the URLs point at Microsoft
+	// Graph and the Entra authority, to be changed for national clouds.
+	// The writer is chosen for the whole process: an organization or request
+	// cannot pick Excel while others write to Sheets.
+	ExcelDriveID      string
+	ExcelWorkbookID   string
+	ExcelTenantID     string
+	ExcelClientID     string
+	ExcelClientSecret string `secret:"true"`
+	ExcelGraphURL     string
+	ExcelAuthorityURL string
 }
 
-type Organization struct {
-	ID   int
-	Name string
+// Column maps an Enrollment field (by its JSON name) to a sheet header. A
+// computed column instead names a derived field, e.g. diasDesdeMatricula,
+// whose value the Compute op evaluates from the Source field of each row.
+// Type, when set, coerces the column's cells to text, number or date.
+type Column struct {
+	Field   string `json:"field" yaml:"field"`
+	Header  string `json:"header" yaml:"header"`
+	Compute string `json:"compute,omitempty" yaml:"compute,omitempty"`
+	Source  string `json:"source,omitempty" yaml:"source,omitempty"`
+	Type    string `json:"type,omitempty" yaml:"type,omitempty"`
+}
+
+// AppConfig is the loaded configuration; it holds the defaults until Init
+// runs.
+var AppConfig = Defaults()
+
+// Jacad API versions JACAD_API_VERSION selects.
+const (
+	JacadAPIv1   = "v1"
+	JacadAPIv2   = "v2"
+	JacadAPIAuto = "auto"
+)
+
+// Secret providers SECRETS_PROVIDER selects.
+const (
+	SecretsProviderNone  = "none"
+	SecretsProviderGCP   = "gcp"
+	SecretsProviderVault = "vault"
+)
+
+// Data quality rules DATA_QUALITY_RULES lists: a non-empty RA, dataMatricula
+// not after dataAtivacao, and a non-empty curso.
+const (
+	QualityRuleRA    = "ra"
+	QualityRuleDates = "dates"
+	QualityRuleCurso = "curso"
+)
+
+// QualityRules lists every data quality rule.
+var QualityRules = []string{QualityRuleRA, QualityRuleDates, QualityRuleCurso}
+
+// Defaults returns the built-in configuration every layer is applied over.
+func Defaults() Config {
+	return Config{
+		Endpoints: map[string]string{
+			"AUTH":            "/auth/token",
+			"ENROLLMENTS":     "/academico/matriculas",
+			"PROCESS_NOTICES": "/processo-seletivo/editais/",
+			"COURSES":         "/academico/cursos",
+			"CLASSES":         "/academico/turmas",
+			"ATTENDANCE":      "/academico/frequencias",
+			"CANDIDATES":      "/processo-seletivo/inscricoes",
+			"ORGANIZATIONS":   "/basico/organizacoes",
+		},
+		JacadAPIVersion:        JacadAPIv1,
+		JacadV2PathPrefix:      "/v2",
+		Organizations:          NewOrgDirectory(BuiltinOrganizations()),
+		OrganizationsSource:    OrgSourceBuiltin,
+		DefaultOrgSheet:        "Outras Matrículas",
+		CoursesSheet:           "Cursos",
+		ClassesSheet:           "Turmas",
+		AttendanceSheet:        "Frequência",
+		CandidatesSheet:        "Inscrições",
+		AuditTab:               true,
+		AuditSheet:             "Audit",
+		SheetStaleAfter:        26 * time.Hour,
+		SheetNameTemplate:      "Matrículas {{.Org}} STATUS: {{.Status}} | {{.PeriodName}}",
+		GroupSheetNameTemplate: "Matrículas {{.Group}} STATUS: {{.Status}} | {{.PeriodName}}",
+		EnrollmentStatuses:     []string{"ATIVA", "TRANCADA", "CANCELADA", "CONCLUIDA", "TRANSFERIDA", "DESISTENTE"},
+		PageSize:               500,
+		MinPageSize:            50,
+		MaxPageSize:            1000,
+		MaxPagesPerBatch:       50,
+		PipelineMemoryMB:       256,
+		MaxParallelRequests:    10,
+		RetryDelay:             2000 * time.Millisecond,
+		MaxRetries:             3,
+		AuthTokenExpiry:        60 * time.Minute,
+		AuthRefreshMargin:      5 * time.Minute,
+		EditalStatus: []string{
+			"ABERTO",
+			"AGUARDANDO",
+		},
+		SyncStatePath:            "sync_state.json",
+		LogFormat:                "json",
+		LogLevel:                 "info",
+		TracingSampleRatio:       1,
+		AccessLog:                true,
+		AccessLogSampleRatio:     1,
+		AccessLogRedactParams:    []string{"token", "apiKey", "api_key", "key", "password", "secret", "signature"},
+		DryRunPreviewRows:        20,
+		DrainTimeout:             30 * time.Second,
+		MaxConcurrentJobs:        2,
+		JobRetryMaxAttempts:      1,
+		JobRetryBackoff:          5 * time.Minute,
+		JobRetryMaxBackoff:       time.Hour,
+		FetchTimeout:             10 * time.Minute,
+		MaxFetchTimeout:          60 * time.Minute,
+		JacadRateLimitRPS:        8,
+		JacadRateLimitBurst:      10,
+		JacadMinConcurrency:      2,
+		JacadConcurrencyCooldown: 5 * time.Second,
+		JacadEndpointConcurrency: map[string]int{"ENROLLMENTS": 8},
+		JacadBreakerThreshold:    5,
+		JacadBreakerCooldown:     30 * time.Second,
+		JacadCompression:         true,
+		JacadConditionalTTL:      24 * time.Hour,
+		CheckpointDir:            "checkpoints",
+		JobHistoryPath:           "job_history.db",
+		APIHMACMaxSkew:           5 * time.Minute,
+		Writer:                   "sheets",
+		ParquetOutput:            "./parquet",
+		SheetsWritesPerMinute:    60,
+		SheetsAppendCoalesceRows: 2000,
+		SheetsMaxRowsPerTab:      500000,
+		SheetsVerifyWrites:       true,
+		DataQualityRules:         []string{QualityRuleRA, QualityRuleDates, QualityRuleCurso},
+		SnapshotRetentionDays:    30,
+		SecretsProvider:          SecretsProviderNone,
+		SecretsRefreshInterval:   time.Hour,
+		VaultKVMount:             "secret",
+		StartupSelfCheck:         true,
+		ReadinessCheckInterval:   30 * time.Second,
+		MetadataTTL:              time.Hour,
+		MetadataRefresh:          30 * time.Minute,
+		CacheBackend:             "none",
+		CacheTTL:                 5 * time.Minute,
+		CacheMaxEntries:          200,
+		ArchiveBackend:           "none",
+		ArchivePathTemplate:      "jacad/{date}/{job}/{endpoint}/page-{page}.json.gz",
+		ArchiveS3Region:          "us-east-1",
+		NotifyOn:                 "always",
+		SMTPPort:                 587,
+		BigQueryLocation:         "US",
+		BigQueryLoadBatchRows:    50000,
+		DateCells:                DateCellsNative,
+		DateTextLayout:           "02/01/2006",
+		NumberLocale:             NumberLocalePtBR,
+		RedactionPolicy: map[string]string{
+			"aluno": RedactMask,
+			"ra":    RedactHash,
+		},
+		Columns: []Column{
+			{Field: "idMatricula", Header: "idMatricula"},
+			{Field: "aluno", Header: "aluno"},
+			{Field: "ra", Header: "ra"},
+			{Field: "curso", Header: "curso"},
+			{Field: "turma", Header: "turma"},
+			{Field: "status", Header: "status"},
+			{Field: "periodoLetivo", Header: "periodoLetivo"},
+			{Field: "unidadeFisica", Header: "unidadeFisica"},
+			{Field: "organizacao", Header: "organizacao"},
+			{Field: "idOrg", Header: "idOrg"},
+			{Field: "dataMatricula", Header: "dataMatricula"},
+			{Field: "dataAtivacao", Header: "dataAtivacao"},
+			{Field: "dataCadastro", Header: "dataCadastro"},
+		},
+		// Jacad HTTP client timeouts; the transport ones match http.DefaultTransport.
+		JacadHTTPTimeout:             60 * time.Second,
+		JacadHTTPIdleConnTimeout:     90 * time.Second,
+		JacadHTTPDialTimeout:         30 * time.Second,
+		JacadHTTPTLSHandshakeTimeout: 10 * time.Second,
+		RuntimeStatsInterval:         15 * time.Second,
+		ExcelGraphURL:                "https://graph.microsoft.com/v1.0",
+		ExcelAuthorityURL:            "https://login.microsoftonline.com",
+	}
 }
 
-var AppConfig = Config{
-	UserToken:           "",
-	APIBase:             "",
-	SpreadsheetID:       "",
-	CredentialsJSONBase64: "",
-	Endpoints: map[string]string{
-		"AUTH":            "/auth/token",
-		"ENROLLMENTS":     "/academico/matriculas",
-		"PROCESS_NOTICES": "/processo-seletivo/editais/",
-	},
-	Organizations: map[string]Organization{
-		"EAD":            {ID: 20, Name: "EAD"},
-		"POS_EAD":        {ID: 17, Name: "PÓS EAD"},
-		"POS_PRESENCIAL": {ID: 9, Name: "PÓS Presencial"},
-		"PRESENCIAL":     {ID: 0, Name: "Presencial"},
-		"POLICLINICA":    {ID: 4, Name: "Policlínica Uniguairacá"},
-		"COLEGIO":        {ID: 15, Name: "Colégio Uniguairacá"},
-		"CLINICA":        {ID: 18, Name: "Clínica Integrada"},
-	},
-	DefaultOrgSheet:     "Outras Matrículas",
-	PageSize:            500,
-	MaxPagesPerBatch:    50,
-	MaxParallelRequests: 10,
-	RetryDelay:          2000 * time.Millisecond,
-	MaxRetries:          3,
-	AuthTokenExpiry:     60 * time.Minute,
-	EditalStatus: []string{
-		"ABERTO",
-		"AGUARDANDO",
-	},
+// BuiltinOrganizations returns the organizations used when no other source
+// is configured.
+func BuiltinOrganizations() []Organization {
+	return []Organization{
+		{Key: "EAD", ID: 20, Name: "EAD"},
+		{Key: "POS_EAD", ID: 17, Name: "PÓS EAD"},
+		{Key: "POS_PRESENCIAL", ID: 9, Name: "PÓS Presencial"},
+		{Key: "PRESENCIAL", ID: 0, Name: "Presencial"},
+		{Key: "POLICLINICA", ID: 4, Name: "Policlínica Uniguairacá"},
+		{Key: "COLEGIO", ID: 15, Name: "Colégio Uniguairacá"},
+		{Key: "CLINICA", ID: 18, Name: "Clínica Integrada"},
+	}
 }
 
 func GetOrganizationNameByID(orgID int) string {
-	for _, org := range AppConfig.Organizations {
-		if org.ID == orgID {
-			return org.Name
-		}
-	}
-	return ""
+	org, _ := AppConfig.Organizations.ByID(orgID)
+	return org.Name
 }
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestLoadEnvFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	clearEnv(t, "LOG_LEVEL")
+
+	if err := loadEnvFile(""); err != nil {
+		t.Fatalf("missing .env: %v", err)
+	}
+	if err := loadEnvFile("custom.env"); err == nil {
+		t.Fatal("missing explicit env file: expected an error")
+	}
+
+	os.Unsetenv("LOG_LEVEL")
+	if err := os.WriteFile("custom.env", []byte("LOG_LEVEL=debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadEnvFile("custom.env"); err != nil {
+		t.Fatalf("loadEnvFile: %v", err)
+	}
+	if got := os.Getenv("LOG_LEVEL"); got != "debug" {
+		t.Errorf("LOG_LEVEL = %q, want debug", got)
+	}
+}
+
+// TestEnvExampleDocumentsSettings keeps .env-example the reference for the
+// settings Load reads.
+func TestEnvExampleDocumentsSettings(t *testing.T) {
+	example, err := os.ReadFile(filepath.Join("..", ".env-example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	documented := make(map[string]bool)
+	for _, line := range strings.Split(string(example), "\n") {
+		if name, _, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(line, "#") {
+			documented[strings.TrimSpace(name)] = true
+		}
+	}
+
+	sources, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setting := regexp.MustCompile(`s\.(?:str|list|boolean|integer|float|duration|get|lookup)\("([A-Z0-9_]+)"`)
+	for _, path := range sources {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range setting.FindAllStringSubmatch(string(src), -1) {
+			if !documented[m[1]] {
+				t.Errorf("%s: setting %s is missing from .env-example", path, m[1])
+			}
+		}
+	}
+}
+
+// TestSafeOverwriteIsOptIn guards the default: swapping in a temporary tab
+// changes the sheetId links and formulas point at.
+func TestSafeOverwriteIsOptIn(t *testing.T) {
+	if Defaults().SheetsSafeOverwrite {
+		t.Error("SheetsSafeOverwrite is on by default")
+	}
+}
+
+func TestValidatePprofNeedsAnAdminKey(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []APIKey
+		want string
+	}{
+		{name: "no keys", want: "API_KEYS is empty"},
+		{name: "no admin key", keys: []APIKey{{Name: "bi", Key: "bi-key"}}, want: "no API key in API_KEYS has the admin scope"},
+		{name: "admin key", keys: []APIKey{{Name: "bi", Key: "bi-key"}, {Name: "ops", Key: "ops-key", Admin: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Defaults()
+			cfg.APIBase = "https://jacad.example.com"
+			cfg.UserToken = "token"
+			cfg.PprofEnabled = true
+			cfg.APIKeys = tt.keys
+			err := cfg.Validate()
+			if got := err != nil && strings.Contains(err.Error(), "PPROF_ENABLED"); got != (tt.want != "") {
+				t.Fatalf("Validate() = %v, want a PPROF_ENABLED problem: %v", err, tt.want != "")
+			}
+			if tt.want != "" && !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
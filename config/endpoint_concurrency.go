@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseEndpointConcurrency parses JACAD_ENDPOINT_CONCURRENCY entries in the
+// form "endpoint:limit", e.g. "ENROLLMENTS:8,CLASSES:2", where endpoint is a
+// key of endpoints. It returns nil when value is empty.
+func parseEndpointConcurrency(value string, endpoints map[string]string) (map[string]int, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	limits := make(map[string]int)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, limit, ok := strings.Cut(entry, ":")
+		name, limit = strings.ToUpper(strings.TrimSpace(name)), strings.TrimSpace(limit)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid endpoint concurrency entry '%s': expected endpoint:limit", entry)
+		}
+		if _, known := endpoints[name]; !known {
+			return nil, fmt.Errorf("unknown Jacad endpoint '%s'", name)
+		}
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid concurrency limit '%s' for endpoint '%s': expected a positive integer", limit, name)
+		}
+		limits[name] = n
+	}
+	return limits, nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Authentication strategies a Jacad profile can log in with: the token
+// header exchange of the auth endpoint, or OAuth2 client credentials.
+const (
+	AuthStrategyToken  = "token"
+	AuthStrategyOAuth2 = "oauth2"
+)
+
+// JacadProfile is one Jacad instance the client can talk to.
+type JacadProfile struct {
+	APIBase   string
+	UserToken string `secret:"true"`
+	// Auth is the authentication strategy; empty means AuthStrategyToken.
+	// The OAuth fields are only used by AuthStrategyOAuth2.
+	Auth              string
+	OAuthTokenURL     string
+	OAuthClientID     string
+	OAuthClientSecret string `secret:"true"`
+	OAuthScopes       []string
+}
+
+// AuthStrategy returns the profile's authentication strategy.
+func (p JacadProfile) AuthStrategy() string {
+	if p.Auth == "" {
+		return AuthStrategyToken
+	}
+	return p.Auth
+}
+
+// loadJacadProfiles reads the profiles named in JACAD_PROFILES (e.g.
+// "colegio,homolog"). Each profile takes its settings from
+// JACAD_PROFILE_<NAME>_API_BASE and JACAD_PROFILE_<NAME>_USER_TOKEN, read with
+// get. Profiles logging in with OAuth2 set JACAD_PROFILE_<NAME>_AUTH=oauth2
+// and the _OAUTH_TOKEN_URL, _OAUTH_CLIENT_ID, _OAUTH_CLIENT_SECRET and
+// optional _OAUTH_SCOPES settings instead of the user token.
+func loadJacadProfiles(names string, get func(string) string) map[string]JacadProfile {
+	profiles := make(map[string]JacadProfile)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "JACAD_PROFILE_" + strings.ToUpper(name) + "_"
+		scopes := strings.FieldsFunc(get(prefix+"OAUTH_SCOPES"), func(r rune) bool { return r == ',' || r == ' ' })
+		profiles[name] = JacadProfile{
+			APIBase:           get(prefix + "API_BASE"),
+			UserToken:         get(prefix + "USER_TOKEN"),
+			Auth:              strings.ToLower(strings.TrimSpace(get(prefix + "AUTH"))),
+			OAuthTokenURL:     get(prefix + "OAUTH_TOKEN_URL"),
+			OAuthClientID:     get(prefix + "OAUTH_CLIENT_ID"),
+			OAuthClientSecret: get(prefix + "OAUTH_CLIENT_SECRET"),
+			OAuthScopes:       scopes,
+		}
+	}
+	return profiles
+}
+
+// JacadProfile returns the profile for tenant. The empty tenant is the
+// default instance configured by API_BASE and USER_TOKEN.
+func (c *Config) JacadProfile(tenant string) (JacadProfile, error) {
+	if tenant == "" {
+		return JacadProfile{APIBase: c.APIBase, UserToken: c.UserToken}, nil
+	}
+	profile, ok := c.JacadProfiles[tenant]
+	if !ok {
+		return JacadProfile{}, fmt.Errorf("unknown tenant '%s'", tenant)
+	}
+	return profile, nil
+}
+
+// TenantNames returns the configured profile names, sorted.
+func (c *Config) TenantNames() []string {
+	names := make([]string, 0, len(c.JacadProfiles))
+	for name := range c.JacadProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadJacadProfiles(t *testing.T) {
+	env := map[string]string{
+		"JACAD_PROFILE_COLEGIO_API_BASE":            "https://colegio.jacad.example",
+		"JACAD_PROFILE_COLEGIO_USER_TOKEN":          "colegio-token",
+		"JACAD_PROFILE_HOMOLOG_API_BASE":            "https://homolog.jacad.example",
+		"JACAD_PROFILE_HOMOLOG_AUTH":                " OAuth2 ",
+		"JACAD_PROFILE_HOMOLOG_OAUTH_TOKEN_URL":     "https://sso.example/token",
+		"JACAD_PROFILE_HOMOLOG_OAUTH_CLIENT_ID":     "client",
+		"JACAD_PROFILE_HOMOLOG_OAUTH_CLIENT_SECRET": "secret",
+		"JACAD_PROFILE_HOMOLOG_OAUTH_SCOPES":        "academico.read, pessoas.read",
+	}
+	profiles := loadJacadProfiles(" colegio, homolog ,,", func(name string) string { return env[name] })
+
+	want := map[string]JacadProfile{
+		"colegio": {APIBase: "https://colegio.jacad.example", UserToken: "colegio-token", OAuthScopes: []string{}},
+		"homolog": {
+			APIBase:           "https://homolog.jacad.example",
+			Auth:              AuthStrategyOAuth2,
+			OAuthTokenURL:     "https://sso.example/token",
+			OAuthClientID:     "client",
+			OAuthClientSecret: "secret",
+			OAuthScopes:       []string{"academico.read", "pessoas.read"},
+		},
+	}
+	if !reflect.DeepEqual(profiles, want) {
+		t.Errorf("loadJacadProfiles() = %+v, want %+v", profiles, want)
+	}
+	if got := profiles["colegio"].AuthStrategy(); got != AuthStrategyToken {
+		t.Errorf("AuthStrategy() without AUTH = %q, want %q", got, AuthStrategyToken)
+	}
+}
+
+func TestJacadProfile(t *testing.T) {
+	c := Defaults()
+	c.APIBase = "https://jacad.example"
+	c.UserToken = "default-token"
+	c.JacadProfiles = map[string]JacadProfile{
+		"homolog": {APIBase: "https://homolog.jacad.example", UserToken: "homolog-token"},
+		"colegio": {APIBase: "https://colegio.jacad.example", UserToken: "colegio-token"},
+	}
+
+	if got, err := c.JacadProfile(""); err != nil || !reflect.DeepEqual(got, JacadProfile{APIBase: "https://jacad.example", UserToken: "default-token"}) {
+		t.Errorf("JacadProfile(\"\") = %+v, %v; want the default instance", got, err)
+	}
+	if got, err := c.JacadProfile("colegio"); err != nil || got.APIBase != "https://colegio.jacad.example" {
+		t.Errorf("JacadProfile(colegio) = %+v, %v; want its profile", got, err)
+	}
+	if _, err := c.JacadProfile("escola"); err == nil {
+		t.Error("JacadProfile(escola) succeeded, want an unknown tenant error")
+	}
+	if got, want := c.TenantNames(), []string{"colegio", "homolog"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TenantNames() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateJacadProfiles(t *testing.T) {
+	c := Defaults()
+	c.APIBase = "https://jacad.example"
+	c.UserToken = "default-token"
+	c.JacadProfiles = map[string]JacadProfile{
+		"colegio": {APIBase: "colegio.jacad.example"},
+		"homolog": {APIBase: "https://homolog.jacad.example", Auth: AuthStrategyOAuth2, OAuthTokenURL: "https://sso.example/token"},
+		"escola":  {APIBase: "https://escola.jacad.example", Auth: "saml", UserToken: "escola-token"},
+	}
+
+	err := c.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Validate() = %v, want *ValidationError", err)
+	}
+	var problems []string
+	for _, problem := range verr.Problems {
+		if strings.HasPrefix(problem, "JACAD_PROFILE_") {
+			problems = append(problems, problem)
+		}
+	}
+	want := []string{
+		"JACAD_PROFILE_COLEGIO_API_BASE must be an absolute URL, got 'colegio.jacad.example'",
+		"JACAD_PROFILE_COLEGIO_USER_TOKEN is required for tenant 'colegio'",
+		"JACAD_PROFILE_ESCOLA_AUTH must be 'token' or 'oauth2', got 'saml'",
+		"JACAD_PROFILE_HOMOLOG_OAUTH_CLIENT_ID and JACAD_PROFILE_HOMOLOG_OAUTH_CLIENT_SECRET are required for tenant 'homolog'",
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("profile problems = %q, want %q", problems, want)
+	}
+}
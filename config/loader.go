@@ -0,0 +1,224 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profiles are the environments a deployment can select with CONFIG_PROFILE.
+var Profiles = []string{"dev", "staging", "prod"}
+
+// profileDefaults are applied over the built-in defaults for each profile,
+// below the config file and the environment.
+var profileDefaults = map[string]map[string]string{
+	"dev": {
+		"LOG_FORMAT":          "text",
+		"LOG_LEVEL":           "debug",
+		"STARTUP_SELF_CHECK":  "false",
+		"MAX_CONCURRENT_JOBS": "1",
+	},
+	"staging": {
+		"LOG_LEVEL": "debug",
+	},
+	"prod": {
+		"LOG_FORMAT": "json",
+		"LOG_LEVEL":  "info",
+	},
+}
+
+// configFile is the YAML file named by CONFIG_FILE. Settings use the names of
+// their environment variables; the section of the selected profile overrides
+// the top-level settings:
+//
+//	PAGE_SIZE: 500
+//	ENROLLMENT_STATUSES: [ATIVA, TRANCADA]
+//	profiles:
+//	  prod:
+//	    LOG_LEVEL: warn
+type configFile struct {
+	Settings map[string]interface{}            `yaml:",inline"`
+	Profiles map[string]map[string]interface{} `yaml:"profiles"`
+}
+
+// source resolves settings by name from layers ordered from highest to lowest
+// priority and collects the values it fails to parse.
+type source struct {
+	layers   []map[string]string
+	problems []string
+}
+
+// newSource layers the environment over the profile section of the config
+// file at path, or CONFIG_FILE when path is empty, the file's top-level
+// settings and the profile defaults. It returns the selected profile and file.
+// Empty environment variables count as unset, so blank .env entries do not
+// hide the config file.
+func newSource(path string) (*source, string, string, error) {
+	env := make(map[string]string)
+	for _, entry := range os.Environ() {
+		if name, value, _ := strings.Cut(entry, "="); value != "" {
+			env[name] = value
+		}
+	}
+
+	if path == "" {
+		path = env["CONFIG_FILE"]
+	}
+	var file configFile
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to read config file '%s': %w", path, err)
+		}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, "", "", fmt.Errorf("failed to parse config file '%s': %w", path, err)
+		}
+	}
+	base, err := flattenSettings(file.Settings)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("invalid config file '%s': %w", path, err)
+	}
+
+	profile, ok := env["CONFIG_PROFILE"]
+	if !ok {
+		profile = base["CONFIG_PROFILE"]
+	}
+	profile = strings.ToLower(strings.TrimSpace(profile))
+	if profile != "" && profileDefaults[profile] == nil {
+		return nil, "", "", fmt.Errorf("CONFIG_PROFILE must be one of %s, got '%s'", strings.Join(Profiles, ", "), profile)
+	}
+	for name := range file.Profiles {
+		if profileDefaults[name] == nil {
+			return nil, "", "", fmt.Errorf("config file '%s' has unknown profile '%s': expected one of %s", path, name, strings.Join(Profiles, ", "))
+		}
+	}
+	overrides, err := flattenSettings(file.Profiles[profile])
+	if err != nil {
+		return nil, "", "", fmt.Errorf("invalid profile '%s' in config file '%s': %w", profile, path, err)
+	}
+
+	return &source{layers: []map[string]string{env, overrides, base, profileDefaults[profile]}}, profile, path, nil
+}
+
+// flattenSettings turns YAML scalars into strings and lists into
+// comma-separated values, as they would be written in the environment.
+func flattenSettings(settings map[string]interface{}) (map[string]string, error) {
+	flat := make(map[string]string, len(settings))
+	for name, value := range settings {
+		switch v := value.(type) {
+		case nil:
+			flat[name] = ""
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			flat[name] = strings.Join(items, ",")
+		case map[string]interface{}:
+			return nil, fmt.Errorf("setting '%s' must be a scalar or a list", name)
+		default:
+			flat[name] = fmt.Sprint(v)
+		}
+	}
+	return flat, nil
+}
+
+func (s *source) lookup(name string) (string, bool) {
+	for _, layer := range s.layers {
+		if value, ok := layer[name]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// get returns the value of name, or "" when it is not set in any layer.
+func (s *source) get(name string) string {
+	value, _ := s.lookup(name)
+	return value
+}
+
+func (s *source) fail(format string, args ...interface{}) {
+	s.problems = append(s.problems, fmt.Sprintf(format, args...))
+}
+
+// str sets dst when name has a non-empty value.
+func (s *source) str(name string, dst *string) {
+	if value := s.get(name); value != "" {
+		*dst = value
+	}
+}
+
+// list sets dst to the comma-separated items of name when it is set, so an
+// empty value clears the default.
+func (s *source) list(name string, dst *[]string) {
+	value, ok := s.lookup(name)
+	if !ok {
+		return
+	}
+	*dst = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*dst = append(*dst, item)
+		}
+	}
+}
+
+func (s *source) boolean(name string, dst *bool) {
+	value := s.get(name)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		s.fail("%s must be true or false, got '%s'", name, value)
+		return
+	}
+	*dst = parsed
+}
+
+// integer sets dst when name is an integer of at least min.
+func (s *source) integer(name string, dst *int, min int) {
+	value := s.get(name)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || parsed < min {
+		s.fail("%s must be an integer of at least %d, got '%s'", name, min, value)
+		return
+	}
+	*dst = parsed
+}
+
+func (s *source) float(name string, dst *float64, min float64) {
+	value := s.get(name)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || parsed < min {
+		s.fail("%s must be a number of at least %g, got '%s'", name, min, value)
+		return
+	}
+	*dst = parsed
+}
+
+// duration sets dst when name is a Go duration such as "90s". Zero is only
+// accepted with allowZero.
+func (s *source) duration(name string, dst *time.Duration, allowZero bool) {
+	value := s.get(name)
+	if value == "" {
+		return
+	}
+	parsed, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || parsed < 0 || (parsed == 0 && !allowZero) {
+		s.fail("%s must be a positive duration such as '30s' or '5m', got '%s'", name, value)
+		return
+	}
+	*dst = parsed
+}
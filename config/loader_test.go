@@ -0,0 +1,232 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// clearEnv unsets the settings the tests below read so values from the
+// developer's shell or .env do not leak in.
+func clearEnv(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		t.Setenv(name, "")
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	const file = `
+LOG_LEVEL: warn
+PAGE_SIZE: 200
+ENROLLMENT_STATUSES: [ATIVA, TRANCADA]
+profiles:
+  prod:
+    PAGE_SIZE: 300
+`
+	tests := []struct {
+		name      string
+		profile   string
+		file      bool
+		env       map[string]string
+		logFormat string
+		logLevel  string
+		pageSize  int
+		statuses  int
+		selfCheck bool
+		maxJobs   int
+	}{
+		{name: "built-in defaults", logFormat: "json", logLevel: "info", pageSize: 500, statuses: 6, selfCheck: true, maxJobs: 2},
+		{name: "profile over defaults", profile: "dev", logFormat: "text", logLevel: "debug", pageSize: 500, statuses: 6, selfCheck: false, maxJobs: 1},
+		{name: "file over profile", profile: "dev", file: true, logFormat: "text", logLevel: "warn", pageSize: 200, statuses: 2, selfCheck: false, maxJobs: 1},
+		{name: "file profile section over file", profile: "prod", file: true, logFormat: "json", logLevel: "warn", pageSize: 300, statuses: 2, selfCheck: true, maxJobs: 2},
+		{
+			name: "environment over everything", profile: "prod", file: true,
+			env:       map[string]string{"PAGE_SIZE": "400", "LOG_LEVEL": "error", "MAX_CONCURRENT_JOBS": "5"},
+			logFormat: "json", logLevel: "error", pageSize: 400, statuses: 2, selfCheck: true, maxJobs: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t, "CONFIG_FILE", "CONFIG_PROFILE", "LOG_FORMAT", "LOG_LEVEL", "PAGE_SIZE", "ENROLLMENT_STATUSES", "STARTUP_SELF_CHECK", "MAX_CONCURRENT_JOBS", "ORGANIZATIONS_SOURCE")
+			t.Setenv("CONFIG_PROFILE", tt.profile)
+			if tt.file {
+				path := filepath.Join(t.TempDir(), "config.yaml")
+				if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv("CONFIG_FILE", path)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			c, err := Load()
+			if err != nil {
+				t.Fatalf("Load() = %v", err)
+			}
+			if c.LogFormat != tt.logFormat || c.LogLevel != tt.logLevel {
+				t.Errorf("log = %s/%s, want %s/%s", c.LogFormat, c.LogLevel, tt.logFormat, tt.logLevel)
+			}
+			if c.PageSize != tt.pageSize {
+				t.Errorf("PageSize = %d, want %d", c.PageSize, tt.pageSize)
+			}
+			if len(c.EnrollmentStatuses) != tt.statuses {
+				t.Errorf("EnrollmentStatuses = %v, want %d statuses", c.EnrollmentStatuses, tt.statuses)
+			}
+			if c.StartupSelfCheck != tt.selfCheck {
+				t.Errorf("StartupSelfCheck = %v, want %v", c.StartupSelfCheck, tt.selfCheck)
+			}
+			if c.MaxConcurrentJobs != tt.maxJobs {
+				t.Errorf("MaxConcurrentJobs = %d, want %d", c.MaxConcurrentJobs, tt.maxJobs)
+			}
+		})
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	clearEnv(t, "CONFIG_FILE", "CONFIG_PROFILE", "ORGANIZATIONS_SOURCE")
+	t.Setenv("PAGE_SIZE", "zero")
+	t.Setenv("CACHE_TTL", "-1s")
+	t.Setenv("SHEETS_FORMATTING", "maybe")
+
+	_, err := Load()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Load() = %v, want *ValidationError", err)
+	}
+	if len(verr.Problems) != 3 {
+		t.Errorf("Problems = %q, want 3", verr.Problems)
+	}
+}
+
+func TestLoadRejectsUnknownProfile(t *testing.T) {
+	clearEnv(t, "CONFIG_FILE")
+	t.Setenv("CONFIG_PROFILE", "qa")
+	if _, err := Load(); err == nil {
+		t.Error("Load() with an unknown profile succeeded")
+	}
+}
+
+func TestParseEndpointConcurrency(t *testing.T) {
+	endpoints := map[string]string{"ENROLLMENTS": "/academico/matriculas", "CLASSES": "/academico/turmas"}
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]int
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: nil},
+		{name: "several endpoints", value: "enrollments:8, CLASSES:2", want: map[string]int{"ENROLLMENTS": 8, "CLASSES": 2}},
+		{name: "unknown endpoint", value: "STUDENTS:2", wantErr: true},
+		{name: "missing limit", value: "ENROLLMENTS", wantErr: true},
+		{name: "limit below one", value: "ENROLLMENTS:0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEndpointConcurrency(tt.value, endpoints)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEndpointConcurrency(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEndpointConcurrency(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadColumnsInline(t *testing.T) {
+	tests := []struct {
+		name    string
+		inline  string
+		want    []Column
+		wantErr bool
+	}{
+		{name: "plain", inline: "aluno:Aluno, ra", want: []Column{{Field: "aluno", Header: "Aluno"}, {Field: "ra", Header: "ra"}}},
+		{
+			name:   "computed",
+			inline: "diasDesdeMatricula = daysSince(dataMatricula):Dias",
+			want:   []Column{{Field: "diasDesdeMatricula", Header: "Dias", Compute: "daysSince", Source: "dataMatricula"}},
+		},
+		{name: "missing parenthesis", inline: "ano=year(periodoLetivo:Ano", wantErr: true},
+		{name: "missing source", inline: "ano=year():Ano", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadColumns("", tt.inline)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadColumns(%q) error = %v, wantErr %v", tt.inline, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadColumns(%q) = %+v, want %+v", tt.inline, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadColumnsTypes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "columns.yaml")
+	content := `
+columns:
+  - field: dataMatricula
+    header: Matrícula
+    type: Date
+  - field: ra
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadColumns(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Column{{Field: "dataMatricula", Header: "Matrícula", Type: ColumnDate}, {Field: "ra", Header: "ra"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadColumns() = %+v, want %+v", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte("columns:\n  - field: ra\n    type: money\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadColumns(path, ""); err == nil {
+		t.Error("loadColumns() accepted an unknown column type")
+	}
+}
+
+func TestLoadPresets(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	path := write("presets.yaml", `
+presets:
+  ead-ativas-2024-2:
+    description: EAD active enrollments of 2024/2
+    orgIds: 20
+    statusMatricula: ATIVA
+    idPeriodoLetivo: 87
+    sheetName: "EAD {status}"
+`)
+	got, err := loadPresets(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Preset{"ead-ativas-2024-2": {Description: "EAD active enrollments of 2024/2", OrgIds: "20", StatusMatricula: "ATIVA", IdPeriodoLetivo: 87, SheetName: "EAD {status}"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadPresets() = %+v, want %+v", got, want)
+	}
+
+	bad := write("bad.json", `{"presets": {"broken": {"sheetName": "EAD {{.Nope}}"}}}`)
+	if _, err := loadPresets(bad); err == nil {
+		t.Error("loadPresets accepted a preset with an invalid sheet name")
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// parseOrgSpreadsheets parses ORG_SPREADSHEETS entries in the form
+// "org:spreadsheetId", where org is an organization key (e.g. EAD) or id.
+func parseOrgSpreadsheets(value string, organizations []Organization) (map[int]string, error) {
+	spreadsheets := make(map[int]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		org, spreadsheetID, ok := strings.Cut(entry, ":")
+		org, spreadsheetID = strings.TrimSpace(org), strings.TrimSpace(spreadsheetID)
+		if !ok || org == "" || spreadsheetID == "" {
+			return nil, fmt.Errorf("invalid org spreadsheet entry '%s': expected org:spreadsheetId", entry)
+		}
+
+		id, err := strconv.Atoi(org)
+		if err != nil {
+			found := false
+			for _, known := range organizations {
+				if strings.EqualFold(known.Key, org) || strings.EqualFold(orgKey(known.Name), org) {
+					id, found = known.ID, true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unknown organization '%s' in org spreadsheet entry", org)
+			}
+		}
+		spreadsheets[id] = spreadsheetID
+	}
+	return spreadsheets, nil
+}
+
+// SpreadsheetAllowed reports whether requests may route their writes to
+// spreadsheetID.
+func (c *Config) SpreadsheetAllowed(spreadsheetID string) bool {
+	if spreadsheetID == "" {
+		return false
+	}
+	return spreadsheetID == c.SpreadsheetID || slices.Contains(c.SpreadsheetAllowlist, spreadsheetID)
+}
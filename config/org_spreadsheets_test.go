@@ -0,0 +1,56 @@
+package config
+
+import "testing"
+
+func TestApplyOrgSpreadsheets(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[int]string
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: map[int]string{}},
+		{name: "keys and ids", value: "EAD:ead-book, 17:pos-book", want: map[int]string{20: "ead-book", 17: "pos-book"}},
+		{name: "key derived from the name", value: "pós_presencial:pp-book", want: map[int]string{9: "pp-book"}},
+		{name: "later entry wins", value: "EAD:a,20:b", want: map[int]string{20: "b"}},
+		{name: "unknown organization", value: "NOPE:book", wantErr: true},
+		{name: "missing spreadsheet", value: "EAD:", wantErr: true},
+		{name: "missing separator", value: "EAD", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgs := BuiltinOrganizations()
+			cfg := Config{OrgSpreadsheets: tt.value}
+			err := cfg.ApplyOrgSpreadsheets(orgs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyOrgSpreadsheets() = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := make(map[int]string)
+			for _, org := range orgs {
+				if org.SpreadsheetID != "" {
+					got[org.ID] = org.SpreadsheetID
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("spreadsheets = %v, want %v", got, tt.want)
+			}
+			for id, spreadsheet := range tt.want {
+				if got[id] != spreadsheet {
+					t.Errorf("organization %d spreadsheet = %q, want %q", id, got[id], spreadsheet)
+				}
+			}
+		})
+	}
+}
+
+func TestSpreadsheetAllowed(t *testing.T) {
+	cfg := Config{SpreadsheetID: "main", SpreadsheetAllowlist: []string{"extra"}}
+	for spreadsheet, want := range map[string]bool{"main": true, "extra": true, "other": false, "": false} {
+		if got := cfg.SpreadsheetAllowed(spreadsheet); got != want {
+			t.Errorf("SpreadsheetAllowed(%q) = %v, want %v", spreadsheet, got, want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sources ORGANIZATIONS_SOURCE selects the organizations from.
+const (
+	OrgSourceBuiltin = "builtin"
+	OrgSourceFile    = "file"
+	OrgSourceJacad   = "jacad"
+)
+
+type Organization struct {
+	// Key names the organization in ORG_SPREADSHEETS and the CLI, e.g. EAD.
+	Key  string `json:"key" yaml:"key"`
+	ID   int    `json:"id" yaml:"id"`
+	Name string `json:"name" yaml:"name"`
+	// SheetNameTemplate overrides SHEET_NAME_TEMPLATE for this organization.
+	SheetNameTemplate string `json:"sheetNameTemplate,omitempty" yaml:"sheetNameTemplate,omitempty"`
+	// SpreadsheetID overrides SPREADSHEET_ID for this organization.
+	SpreadsheetID string `json:"spreadsheetId,omitempty" yaml:"spreadsheetId,omitempty"`
+}
+
+// OrgDirectory holds the organizations and can be replaced while requests
+// read it. A nil directory has no organizations.
+type OrgDirectory struct {
+	mu    sync.RWMutex
+	byKey map[string]Organization
+}
+
+// NewOrgDirectory panics on invalid organizations; it is meant for the
+// built-in list.
+func NewOrgDirectory(orgs []Organization) *OrgDirectory {
+	d := &OrgDirectory{}
+	if err := d.Replace(orgs); err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Replace swaps in orgs after checking that every organization has a name and
+// a unique key and id. Keys are upper-cased; empty keys are derived from the
+// name.
+func (d *OrgDirectory) Replace(orgs []Organization) error {
+	byKey := make(map[string]Organization, len(orgs))
+	ids := make(map[int]bool, len(orgs))
+	for i, org := range orgs {
+		org.Name = strings.TrimSpace(org.Name)
+		if org.Name == "" {
+			return fmt.Errorf("organization %d has no name", i+1)
+		}
+		if org.Key == "" {
+			org.Key = orgKey(org.Name)
+		}
+		org.Key = strings.ToUpper(strings.TrimSpace(org.Key))
+		if _, ok := byKey[org.Key]; ok {
+			return fmt.Errorf("duplicate organization key '%s'", org.Key)
+		}
+		if ids[org.ID] {
+			return fmt.Errorf("duplicate organization id %d", org.ID)
+		}
+		byKey[org.Key] = org
+		ids[org.ID] = true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.byKey = byKey
+	return nil
+}
+
+// All returns the organizations sorted by id.
+func (d *OrgDirectory) All() []Organization {
+	if d == nil {
+		return nil
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	orgs := make([]Organization, 0, len(d.byKey))
+	for _, org := range d.byKey {
+		orgs = append(orgs, org)
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].ID < orgs[j].ID })
+	return orgs
+}
+
+// ByKey looks an organization up by key, ignoring case.
+func (d *OrgDirectory) ByKey(key string) (Organization, bool) {
+	if d == nil {
+		return Organization{}, false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	org, ok := d.byKey[strings.ToUpper(key)]
+	return org, ok
+}
+
+func (d *OrgDirectory) ByID(id int) (Organization, bool) {
+	if d == nil {
+		return Organization{}, false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, org := range d.byKey {
+		if org.ID == id {
+			return org, true
+		}
+	}
+	return Organization{}, false
+}
+
+func (d *OrgDirectory) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.All())
+}
+
+// orgKey turns a name such as "PÓS Presencial" into "PÓS_PRESENCIAL".
+func orgKey(name string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(name), "_"))
+}
+
+type organizationsFile struct {
+	Organizations []Organization `json:"organizations" yaml:"organizations"`
+}
+
+// LoadOrganizationsFile reads organizations from a YAML or JSON file.
+func LoadOrganizationsFile(path string) ([]Organization, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read organizations file '%s': %w", path, err)
+	}
+
+	var file organizationsFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse organizations file '%s': %w", path, err)
+	}
+	if len(file.Organizations) == 0 {
+		return nil, fmt.Errorf("organizations file '%s' lists no organizations", path)
+	}
+	return file.Organizations, nil
+}
+
+// LoadOrganizations reads the built-in or file organizations, with
+// ORG_SPREADSHEETS applied. Jacad organizations are fetched by the client.
+func (c *Config) LoadOrganizations() ([]Organization, error) {
+	var orgs []Organization
+	switch c.OrganizationsSource {
+	case OrgSourceFile:
+		var err error
+		if orgs, err = LoadOrganizationsFile(c.OrganizationsFile); err != nil {
+			return nil, err
+		}
+	case OrgSourceBuiltin:
+		orgs = BuiltinOrganizations()
+	default:
+		return nil, fmt.Errorf("organizations source '%s' is not loaded from configuration", c.OrganizationsSource)
+	}
+	if err := c.ApplyOrgSpreadsheets(orgs); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// ApplyOrgSpreadsheets sets the spreadsheet of the organizations named in
+// ORG_SPREADSHEETS, which takes precedence over the organization source.
+func (c *Config) ApplyOrgSpreadsheets(orgs []Organization) error {
+	spreadsheets, err := parseOrgSpreadsheets(c.OrgSpreadsheets, orgs)
+	if err != nil {
+		return fmt.Errorf("invalid ORG_SPREADSHEETS: %w", err)
+	}
+	for i := range orgs {
+		if id, ok := spreadsheets[orgs[i].ID]; ok {
+			orgs[i].SpreadsheetID = id
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOrgDirectoryReplace(t *testing.T) {
+	d := NewOrgDirectory(BuiltinOrganizations())
+	err := d.Replace([]Organization{
+		{ID: 9, Name: " PÓS Presencial "},
+		{Key: "ead", ID: 20, Name: "EAD", SpreadsheetID: "ead-book"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Organization{
+		{Key: "PÓS_PRESENCIAL", ID: 9, Name: "PÓS Presencial"},
+		{Key: "EAD", ID: 20, Name: "EAD", SpreadsheetID: "ead-book"},
+	}
+	if got := d.All(); !reflect.DeepEqual(got, want) {
+		t.Errorf("All() = %+v, want %+v", got, want)
+	}
+	if org, ok := d.ByKey("Ead"); !ok || org.ID != 20 {
+		t.Errorf("ByKey(Ead) = %+v, %v; want organization 20", org, ok)
+	}
+	if org, ok := d.ByID(9); !ok || org.Name != "PÓS Presencial" {
+		t.Errorf("ByID(9) = %+v, %v; want PÓS Presencial", org, ok)
+	}
+	if _, ok := d.ByID(17); ok {
+		t.Error("ByID(17) found an organization dropped by Replace")
+	}
+
+	for _, invalid := range []struct {
+		orgs []Organization
+		want string
+	}{
+		{orgs: []Organization{{ID: 1, Name: " "}}, want: "organization 1 has no name"},
+		{orgs: []Organization{{Key: "ead", ID: 1, Name: "A"}, {Key: "EAD", ID: 2, Name: "B"}}, want: "duplicate organization key 'EAD'"},
+		{orgs: []Organization{{ID: 1, Name: "A"}, {ID: 1, Name: "B"}}, want: "duplicate organization id 1"},
+	} {
+		if err := d.Replace(invalid.orgs); err == nil || err.Error() != invalid.want {
+			t.Errorf("Replace(%+v) = %v, want %q", invalid.orgs, err, invalid.want)
+		}
+	}
+	if got := d.All(); !reflect.DeepEqual(got, want) {
+		t.Errorf("All() after failed replaces = %+v, want the previous organizations", got)
+	}
+
+	var nilDirectory *OrgDirectory
+	if _, ok := nilDirectory.ByKey("EAD"); ok || nilDirectory.All() != nil {
+		t.Error("nil directory has organizations")
+	}
+}
+
+func TestLoadOrganizations(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	yamlPath := write("orgs.yaml", `organizations:
+  - key: ead
+    id: 20
+    name: EAD
+    sheetNameTemplate: "{{.Org}} {{.PeriodName}}"
+  - id: 31
+    name: Escola Técnica
+`)
+	jsonPath := write("orgs.json", `{"organizations": [{"id": 20, "name": "EAD", "spreadsheetId": "file-book"}]}`)
+	emptyPath := write("empty.yaml", "organizations: []\n")
+	brokenPath := write("broken.json", "{")
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    []Organization
+		wantErr string
+	}{
+		{
+			name: "yaml file with ORG_SPREADSHEETS",
+			cfg:  Config{OrganizationsSource: OrgSourceFile, OrganizationsFile: yamlPath, OrgSpreadsheets: "escola_técnica:escola-book"},
+			want: []Organization{
+				{Key: "ead", ID: 20, Name: "EAD", SheetNameTemplate: "{{.Org}} {{.PeriodName}}"},
+				{ID: 31, Name: "Escola Técnica", SpreadsheetID: "escola-book"},
+			},
+		},
+		{
+			name: "ORG_SPREADSHEETS overrides the file",
+			cfg:  Config{OrganizationsSource: OrgSourceFile, OrganizationsFile: jsonPath, OrgSpreadsheets: "EAD:env-book"},
+			want: []Organization{{ID: 20, Name: "EAD", SpreadsheetID: "env-book"}},
+		},
+		{name: "builtin", cfg: Config{OrganizationsSource: OrgSourceBuiltin}, want: BuiltinOrganizations()},
+		{name: "empty file", cfg: Config{OrganizationsSource: OrgSourceFile, OrganizationsFile: emptyPath}, wantErr: "lists no organizations"},
+		{name: "broken file", cfg: Config{OrganizationsSource: OrgSourceFile, OrganizationsFile: brokenPath}, wantErr: "failed to parse organizations file"},
+		{name: "missing file", cfg: Config{OrganizationsSource: OrgSourceFile, OrganizationsFile: filepath.Join(dir, "missing.yaml")}, wantErr: "failed to read organizations file"},
+		{name: "jacad", cfg: Config{OrganizationsSource: OrgSourceJacad}, wantErr: "is not loaded from configuration"},
+		{name: "unknown organization in ORG_SPREADSHEETS", cfg: Config{OrganizationsSource: OrgSourceFile, OrganizationsFile: jsonPath, OrgSpreadsheets: "POS_EAD:book"}, wantErr: "invalid ORG_SPREADSHEETS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.LoadOrganizations()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadOrganizations() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadOrganizations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
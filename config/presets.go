@@ -0,0 +1,85 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Preset is a named set of fetch-enrollments parameters, selected with the
+// preset parameter so callers need not remember numeric period IDs.
+// Parameters set on the request itself take precedence.
+type Preset struct {
+	Description     string `json:"description,omitempty" yaml:"description,omitempty"`
+	OrgIds          string `json:"orgIds,omitempty" yaml:"orgIds,omitempty"`
+	IdPeriodoLetivo int    `json:"idPeriodoLetivo,omitempty" yaml:"idPeriodoLetivo,omitempty"`
+	StatusMatricula string `json:"statusMatricula,omitempty" yaml:"statusMatricula,omitempty"`
+	SheetName       string `json:"sheetName,omitempty" yaml:"sheetName,omitempty"`
+	GroupBy         string `json:"groupBy,omitempty" yaml:"groupBy,omitempty"`
+	Mode            string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	WriteMode       string `json:"writeMode,omitempty" yaml:"writeMode,omitempty"`
+}
+
+type presetsFile struct {
+	Presets map[string]Preset `json:"presets" yaml:"presets"`
+}
+
+// loadPresets reads the presets from a YAML/JSON file keyed by preset name:
+//
+//	presets:
+//	  ead-ativas-2024-2:
+//	    orgIds: "20"
+//	    statusMatricula: ATIVA
+//	    idPeriodoLetivo: 87
+//
+// It returns nil when path is empty.
+func loadPresets(path string) (map[string]Preset, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read presets file '%s': %w", path, err)
+	}
+
+	var file presetsFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse presets file '%s': %w", path, err)
+	}
+
+	for name, preset := range file.Presets {
+		if strings.TrimSpace(name) == "" || strings.Contains(name, ",") {
+			return nil, fmt.Errorf("invalid preset name '%s'", name)
+		}
+		if preset.IdPeriodoLetivo < 0 {
+			return nil, fmt.Errorf("preset '%s': idPeriodoLetivo must not be negative", name)
+		}
+		if preset.SheetName != "" {
+			if err := CheckSheetNameTemplate(preset.SheetName); err != nil {
+				return nil, fmt.Errorf("preset '%s': %w", name, err)
+			}
+		}
+	}
+	return file.Presets, nil
+}
+
+// PresetNames returns the configured preset names, sorted.
+func (c *Config) PresetNames() []string {
+	names := make([]string, 0, len(c.Presets))
+	for name := range c.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
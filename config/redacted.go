@@ -0,0 +1,63 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+const redactedValue = "[REDACTED]"
+
+// Redacted returns the configuration keyed by field name with every field
+// tagged secret:"true" that is set replaced by "[REDACTED]", for display.
+// Durations are rendered as strings such as "5m0s"; values that marshal
+// themselves, such as the organizations, are kept as they are.
+func (c *Config) Redacted() map[string]interface{} {
+	return redactedValueOf(reflect.ValueOf(*c)).(map[string]interface{})
+}
+
+func redactedValueOf(v reflect.Value) interface{} {
+	switch value := v.Interface().(type) {
+	case time.Duration:
+		return value.String()
+	case json.Marshaler:
+		return value
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		out := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Tag.Get("secret") == "true" && !v.Field(i).IsZero() {
+				out[field.Name] = redactedValue
+				continue
+			}
+			out[field.Name] = redactedValueOf(v.Field(i))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = redactedValueOf(v.Index(i))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = redactedValueOf(iter.Value())
+		}
+		return out
+	}
+	return v.Interface()
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRedacted(t *testing.T) {
+	c := Defaults()
+	c.UserToken = "jacad-token"
+	c.SMTPPassword = ""
+	c.CacheTTL = 90 * time.Second
+	c.APIKeys = []APIKey{{Name: "ops", Key: "secret-key", RequestsPerMinute: 60}}
+	c.JacadProfiles = map[string]JacadProfile{"colegio": {APIBase: "https://colegio.example", UserToken: "colegio-token"}}
+
+	got := c.Redacted()
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{name: "set secret", value: got["UserToken"], want: redactedValue},
+		{name: "empty secret is shown empty", value: got["SMTPPassword"], want: ""},
+		{name: "duration", value: got["CacheTTL"], want: "1m30s"},
+		{name: "plain setting", value: got["LogLevel"], want: "info"},
+		{name: "secret in a slice of structs", value: got["APIKeys"].([]interface{})[0].(map[string]interface{})["Key"], want: redactedValue},
+		{name: "plain field next to a secret", value: got["APIKeys"].([]interface{})[0].(map[string]interface{})["Name"], want: "ops"},
+		{name: "secret in a map of structs", value: got["JacadProfiles"].(map[string]interface{})["colegio"].(map[string]interface{})["UserToken"], want: redactedValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.value != tt.want {
+				t.Errorf("got %v, want %v", tt.value, tt.want)
+			}
+		})
+	}
+}
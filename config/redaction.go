@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Redaction strategies applied to a column when a fetch asks to anonymize.
+const (
+	RedactHash   = "hash"
+	RedactMask   = "mask"
+	RedactRemove = "remove"
+)
+
+// parseRedactionPolicy parses REDACTION_POLICY entries in the form
+// "field:strategy", e.g. "aluno:mask,ra:hash". It returns nil when value is empty.
+func parseRedactionPolicy(value string) (map[string]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	policy := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		field, strategy, ok := strings.Cut(entry, ":")
+		field, strategy = strings.TrimSpace(field), strings.ToLower(strings.TrimSpace(strategy))
+		if !ok || field == "" {
+			return nil, fmt.Errorf("invalid redaction entry '%s': expected field:strategy", entry)
+		}
+		switch strategy {
+		case RedactHash, RedactMask, RedactRemove:
+		default:
+			return nil, fmt.Errorf("invalid redaction strategy '%s' for field '%s': expected '%s', '%s' or '%s'", strategy, field, RedactHash, RedactMask, RedactRemove)
+		}
+		policy[field] = strategy
+	}
+	return policy, nil
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// SheetNameData holds the variables of a sheet name template.
+type SheetNameData struct {
+	Org    string
+	Status string
+	// PeriodID lists the period IDs of the job, e.g. "87" or "86,87";
+	// PeriodName lists their names, e.g. "2024/2", or reads "Período ID 87"
+	// when a name could not be resolved.
+	PeriodID   string
+	PeriodName string
+	// Date is the day the job ran, as YYYY-MM-DD.
+	Date string
+	// Group is the group of a grouped fetch, e.g. the course; empty otherwise.
+	Group string
+}
+
+// RenderSheetName renders a sheet name template. Templates containing "{{"
+// are Go templates over SheetNameData, e.g.
+// "{{.Org}} {{.PeriodName}} ({{.Date}})"; others use the older {org},
+// {status}, {periodo}, {date} and {group} placeholders.
+func RenderSheetName(tmpl string, data SheetNameData) (string, error) {
+	if !strings.Contains(tmpl, "{{") {
+		return strings.NewReplacer(
+			"{group}", data.Group,
+			"{org}", data.Org,
+			"{status}", data.Status,
+			"{periodo}", data.PeriodID,
+			"{date}", data.Date,
+		).Replace(tmpl), nil
+	}
+	t, err := template.New("sheetName").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid sheet name template: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid sheet name template: %w", err)
+	}
+	name := strings.TrimSpace(b.String())
+	if name == "" {
+		return "", fmt.Errorf("sheet name template '%s' renders an empty name", tmpl)
+	}
+	return name, nil
+}
+
+// CheckSheetNameTemplate renders tmpl with sample data to catch unknown
+// variables before a job runs.
+func CheckSheetNameTemplate(tmpl string) error {
+	_, err := RenderSheetName(tmpl, SheetNameData{Org: "EAD", Status: "ATIVA", PeriodID: "87", PeriodName: "2024/2", Date: "2025-01-31", Group: "Direito"})
+	return err
+}
@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestRenderSheetName(t *testing.T) {
+	data := SheetNameData{Org: "EAD", Status: "ATIVA", PeriodID: "87", PeriodName: "2024/2", Date: "2025-01-31", Group: "Direito"}
+	tests := []struct {
+		name    string
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", tmpl: Defaults().SheetNameTemplate, want: "Matrículas EAD STATUS: ATIVA | 2024/2"},
+		{name: "go template", tmpl: "{{.Org}} {{.PeriodName}} ({{.Date}})", want: "EAD 2024/2 (2025-01-31)"},
+		{name: "placeholders", tmpl: "{group} | {org} {periodo} {date}", want: "Direito | EAD 87 2025-01-31"},
+		{name: "unknown variable", tmpl: "{{.Curso}}", wantErr: true},
+		{name: "syntax error", tmpl: "{{.Org", wantErr: true},
+		{name: "empty name", tmpl: "{{if false}}x{{end}}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderSheetName(tt.tmpl, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderSheetName(%q) error = %v, wantErr %v", tt.tmpl, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RenderSheetName(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Transform is a rule that rewrites an enrollment field before it is mapped
+// to a row. Arg and Values parameterize ops such as pad and map.
+type Transform struct {
+	Field  string            `json:"field" yaml:"field"`
+	Op     string            `json:"op" yaml:"op"`
+	Arg    string            `json:"arg,omitempty" yaml:"arg,omitempty"`
+	Values map[string]string `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+type transformsFile struct {
+	Transforms []Transform `json:"transforms" yaml:"transforms"`
+}
+
+// loadTransforms reads the transformation rules from a YAML/JSON file or,
+// failing that, from a comma-separated "field:op[=arg]" list, e.g.
+// "ra:digits,aluno:upper,status:map=ATIVA>Ativa|CANCELADA>Cancelada". It
+// returns nil when neither is set.
+func loadTransforms(path, inline string) ([]Transform, error) {
+	var transforms []Transform
+
+	switch {
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transforms file '%s': %w", path, err)
+		}
+
+		var file transformsFile
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &file)
+		default:
+			err = json.Unmarshal(data, &file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse transforms file '%s': %w", path, err)
+		}
+		transforms = file.Transforms
+	case inline != "":
+		for _, entry := range strings.Split(inline, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			field, op, ok := strings.Cut(entry, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid transform entry '%s': expected field:op[=arg]", entry)
+			}
+			op, arg, _ := strings.Cut(op, "=")
+			transforms = append(transforms, Transform{Field: strings.TrimSpace(field), Op: strings.TrimSpace(op), Arg: strings.TrimSpace(arg)})
+		}
+	default:
+		return nil, nil
+	}
+
+	for i := range transforms {
+		transforms[i].Op = strings.ToLower(transforms[i].Op)
+		if transforms[i].Field == "" || transforms[i].Op == "" {
+			return nil, fmt.Errorf("transform %d needs a field and an op", i+1)
+		}
+	}
+	return transforms, nil
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadTransforms(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "transforms.yaml")
+	os.WriteFile(yamlPath, []byte(`transforms:
+  - field: status
+    op: MAP
+    values:
+      ATIVA: Ativa
+  - field: ra
+    op: pad
+    arg: "6"
+`), 0o644)
+	jsonPath := filepath.Join(dir, "transforms.json")
+	os.WriteFile(jsonPath, []byte(`{"transforms": [{"field": "aluno", "op": "upper"}]}`), 0o644)
+	invalidPath := filepath.Join(dir, "invalid.yaml")
+	os.WriteFile(invalidPath, []byte("transforms:\n  - op: upper\n"), 0o644)
+
+	tests := []struct {
+		name    string
+		path    string
+		inline  string
+		want    []Transform
+		wantErr string
+	}{
+		{name: "unset", want: nil},
+		{
+			name:   "inline",
+			inline: " ra:digits, aluno:Upper ,,status:map=ATIVA>Ativa|CANCELADA>Cancelada",
+			want: []Transform{
+				{Field: "ra", Op: "digits"},
+				{Field: "aluno", Op: "upper"},
+				{Field: "status", Op: "map", Arg: "ATIVA>Ativa|CANCELADA>Cancelada"},
+			},
+		},
+		{
+			name:   "yaml file wins over inline",
+			path:   yamlPath,
+			inline: "ra:digits",
+			want: []Transform{
+				{Field: "status", Op: "map", Values: map[string]string{"ATIVA": "Ativa"}},
+				{Field: "ra", Op: "pad", Arg: "6"},
+			},
+		},
+		{name: "json file", path: jsonPath, want: []Transform{{Field: "aluno", Op: "upper"}}},
+		{name: "inline entry without op", inline: "ra", wantErr: "invalid transform entry 'ra'"},
+		{name: "file rule without field", path: invalidPath, wantErr: "transform 1 needs a field and an op"},
+		{name: "missing file", path: filepath.Join(dir, "missing.yaml"), wantErr: "failed to read transforms file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadTransforms(tt.path, tt.inline)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadTransforms() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadTransforms() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ValidationError lists every configuration problem found by Validate.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration (%d problem(s)):\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// Validate checks that every setting required by the selected writer and
+// backends is present and well-formed. It reports all problems at once.
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.APIBase == "" {
+		add("API_BASE is required")
+	} else if u, err := url.Parse(c.APIBase); err != nil || u.Scheme == "" || u.Host == "" {
+		add("API_BASE must be an absolute URL, got '%s'", c.APIBase)
+	}
+	if c.UserToken == "" && (c.SecretsProvider == SecretsProviderNone || c.SecretUserToken == "") {
+		add("USER_TOKEN is required")
+	}
+	if c.JacadHTTPProxy != "" {
+		if u, err := url.Parse(c.JacadHTTPProxy); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			add("JACAD_HTTP_PROXY must be an http, https or socks5 URL, got '%s'", c.JacadHTTPProxy)
+		}
+	}
+	switch c.JacadAPIVersion {
+	case JacadAPIv1, JacadAPIv2, JacadAPIAuto:
+	default:
+		add("JACAD_API_VERSION must be 'v1', 'v2' or 'auto', got '%s'", c.JacadAPIVersion)
+	}
+	if c.JacadAPIVersion != JacadAPIv1 && !strings.HasPrefix(c.JacadV2PathPrefix, "/") {
+		add("JACAD_V2_PATH_PREFIX must start with '/', got '%s'", c.JacadV2PathPrefix)
+	}
+	for _, name := range c.TenantNames() {
+		profile := c.JacadProfiles[name]
+		prefix := "JACAD_PROFILE_" + strings.ToUpper(name) + "_"
+		if u, err := url.Parse(profile.APIBase); err != nil || u.Scheme == "" || u.Host == "" {
+			add("%sAPI_BASE must be an absolute URL, got '%s'", prefix, profile.APIBase)
+		}
+		switch profile.AuthStrategy() {
+		case AuthStrategyToken:
+			if profile.UserToken == "" {
+				add("%sUSER_TOKEN is required for tenant '%s'", prefix, name)
+			}
+		case AuthStrategyOAuth2:
+			if u, err := url.Parse(profile.OAuthTokenURL); err != nil || u.Scheme == "" || u.Host == "" {
+				add("%sOAUTH_TOKEN_URL must be an absolute URL, got '%s'", prefix, profile.OAuthTokenURL)
+			}
+			if profile.OAuthClientID == "" || profile.OAuthClientSecret == "" {
+				add("%sOAUTH_CLIENT_ID and %sOAUTH_CLIENT_SECRET are required for tenant '%s'", prefix, prefix, name)
+			}
+		default:
+			add("%sAUTH must be '%s' or '%s', got '%s'", prefix, AuthStrategyToken, AuthStrategyOAuth2, profile.Auth)
+		}
+	}
+
+	seenWriters := make(map[string]bool)
+	for _, writer := range c.Writers() {
+		if seenWriters[writer] {
+			add("FANOUT_WRITERS must not repeat a writer or WRITER, got '%s' twice", writer)
+			continue
+		}
+		seenWriters[writer] = true
+		switch writer {
+		case "sheets":
+			if c.SpreadsheetID == "" {
+				add("SPREADSHEET_ID is required by the sheets writer")
+			}
+			if c.SheetsTemplateSheet != "" && c.SheetsTemplateSpreadsheetID == "" {
+				add("SHEETS_TEMPLATE_SHEET requires SHEETS_TEMPLATE_SPREADSHEET_ID")
+			}
+		case "bigquery":
+			if c.BigQueryProjectID == "" {
+				add("BIGQUERY_PROJECT_ID is required by the bigquery writer")
+			}
+			if c.BigQueryDataset == "" {
+				add("BIGQUERY_DATASET is required by the bigquery writer")
+			}
+		case "excel":
+			required := []struct{ name, value string }{
+				{"EXCEL_DRIVE_ID", c.ExcelDriveID},
+				{"EXCEL_WORKBOOK_ID", c.ExcelWorkbookID},
+				{"EXCEL_TENANT_ID", c.ExcelTenantID},
+				{"EXCEL_CLIENT_ID", c.ExcelClientID},
+				{"EXCEL_CLIENT_SECRET", c.ExcelClientSecret},
+			}
+			for _, setting := range required {
+				if setting.value == "" {
+					add("%s is required by the excel writer", setting.name)
+				}
+			}
+			if u, err := url.Parse(c.ExcelGraphURL); err != nil || u.Scheme == "" || u.Host == "" {
+				add("EXCEL_GRAPH_URL must be an absolute URL, got '%s'", c.ExcelGraphURL)
+			}
+			if u, err := url.Parse(c.ExcelAuthorityURL); err != nil || u.Scheme == "" || u.Host == "" {
+				add("EXCEL_AUTHORITY_URL must be an absolute URL, got '%s'", c.ExcelAuthorityURL)
+			}
+		case "csv":
+		case "parquet":
+			if scheme, _, isURL := strings.Cut(c.ParquetOutput, "://"); isURL && scheme != "gs" && scheme != "s3" {
+				add("PARQUET_OUTPUT must be a directory or a gs:// or s3:// URL, got '%s'", c.ParquetOutput)
+			}
+		default:
+			add("WRITER and FANOUT_WRITERS must be 'sheets', 'bigquery', 'excel', 'csv' or 'parquet', got '%s'", writer)
+		}
+	}
+	if c.GoogleWorkloadIdentityConfig != "" && c.CredentialsJSONBase64 != "" {
+		add("GOOGLE_WORKLOAD_IDENTITY_CONFIG and GOOGLE_CREDENTIALS_JSON_BASE64 cannot both be set")
+	}
+	for _, account := range append([]string{c.GoogleImpersonateServiceAccount}, c.GoogleImpersonateDelegates...) {
+		if account != "" && !strings.Contains(account, "@") {
+			add("GOOGLE_IMPERSONATE_SERVICE_ACCOUNT and GOOGLE_IMPERSONATE_DELEGATES must be service account emails, got '%s'", account)
+		}
+	}
+	if len(c.GoogleImpersonateDelegates) > 0 && c.GoogleImpersonateServiceAccount == "" {
+		add("GOOGLE_IMPERSONATE_DELEGATES requires GOOGLE_IMPERSONATE_SERVICE_ACCOUNT")
+	}
+
+	switch c.SecretsProvider {
+	case SecretsProviderNone:
+	case SecretsProviderGCP:
+		if c.SecretsGCPProject == "" {
+			add("SECRETS_GCP_PROJECT is required when SECRETS_PROVIDER=gcp")
+		}
+	case SecretsProviderVault:
+		if u, err := url.Parse(c.VaultAddr); err != nil || u.Scheme == "" || u.Host == "" {
+			add("VAULT_ADDR must be an absolute URL when SECRETS_PROVIDER=vault, got '%s'", c.VaultAddr)
+		}
+		if c.VaultToken == "" {
+			add("VAULT_TOKEN is required when SECRETS_PROVIDER=vault")
+		}
+		if c.VaultKVMount == "" {
+			add("VAULT_KV_MOUNT is required when SECRETS_PROVIDER=vault")
+		}
+	default:
+		add("SECRETS_PROVIDER must be 'none', 'gcp' or 'vault', got '%s'", c.SecretsProvider)
+	}
+	if c.SecretsProvider != SecretsProviderNone && c.SecretUserToken == "" && c.SecretGoogleCredentials == "" {
+		add("SECRETS_PROVIDER=%s requires SECRET_USER_TOKEN or SECRET_GOOGLE_CREDENTIALS", c.SecretsProvider)
+	}
+	if c.SecretGoogleCredentials != "" && c.GoogleWorkloadIdentityConfig != "" {
+		add("SECRET_GOOGLE_CREDENTIALS and GOOGLE_WORKLOAD_IDENTITY_CONFIG cannot both be set")
+	}
+
+	switch c.OrganizationsSource {
+	case OrgSourceBuiltin, OrgSourceJacad:
+	case OrgSourceFile:
+		if c.OrganizationsFile == "" {
+			add("ORGANIZATIONS_FILE is required when ORGANIZATIONS_SOURCE=file")
+		}
+	default:
+		add("ORGANIZATIONS_SOURCE must be 'builtin', 'file' or 'jacad', got '%s'", c.OrganizationsSource)
+	}
+
+	switch c.CacheBackend {
+	case "none", "memory":
+	case "redis":
+		if c.RedisURL == "" {
+			add("REDIS_URL is required when CACHE_BACKEND=redis")
+		}
+	default:
+		add("CACHE_BACKEND must be 'none', 'memory' or 'redis', got '%s'", c.CacheBackend)
+	}
+
+	switch c.ArchiveBackend {
+	case "none":
+	case "gcs", "s3":
+		if c.ArchiveBucket == "" {
+			add("ARCHIVE_BUCKET is required when ARCHIVE_BACKEND=%s", c.ArchiveBackend)
+		}
+		if !strings.Contains(c.ArchivePathTemplate, "{page}") {
+			add("ARCHIVE_PATH_TEMPLATE must contain {page}, got '%s'", c.ArchivePathTemplate)
+		}
+	default:
+		add("ARCHIVE_BACKEND must be 'none', 'gcs' or 's3', got '%s'", c.ArchiveBackend)
+	}
+
+	if err := CheckSheetNameTemplate(c.SheetNameTemplate); err != nil {
+		add("SHEET_NAME_TEMPLATE: %v", err)
+	}
+	if err := CheckSheetNameTemplate(c.GroupSheetNameTemplate); err != nil {
+		add("GROUP_SHEET_NAME_TEMPLATE: %v", err)
+	}
+	for _, org := range c.Organizations.All() {
+		if org.SheetNameTemplate == "" {
+			continue
+		}
+		if err := CheckSheetNameTemplate(org.SheetNameTemplate); err != nil {
+			add("sheetNameTemplate of organization '%s': %v", org.Key, err)
+		}
+	}
+
+	for _, rule := range c.DataQualityRules {
+		if !slices.Contains(QualityRules, rule) {
+			add("DATA_QUALITY_RULES must list rules among %s, got '%s'", strings.Join(QualityRules, ", "), rule)
+		}
+	}
+
+	switch c.DateCells {
+	case DateCellsNative, DateCellsSerial:
+	case DateCellsText:
+		if c.DateTextLayout == "" {
+			add("DATE_TEXT_LAYOUT is required when DATE_CELLS=text")
+		}
+	default:
+		add("DATE_CELLS must be 'native', 'serial' or 'text', got '%s'", c.DateCells)
+	}
+	if c.NumberLocale != NumberLocalePtBR && c.NumberLocale != NumberLocaleEnUS {
+		add("NUMBER_LOCALE must be 'pt-BR' or 'en-US', got '%s'", c.NumberLocale)
+	}
+
+	if c.NotifyOn != "always" && c.NotifyOn != "failure" {
+		add("NOTIFY_ON must be 'always' or 'failure', got '%s'", c.NotifyOn)
+	}
+	if c.NotifyEmailEnabled {
+		if c.SMTPHost == "" {
+			add("SMTP_HOST is required when NOTIFY_EMAIL_ENABLED is set")
+		}
+		if c.NotifyEmailFrom == "" {
+			add("NOTIFY_EMAIL_FROM is required when NOTIFY_EMAIL_ENABLED is set")
+		}
+		if len(c.NotifyEmailTo) == 0 {
+			add("NOTIFY_EMAIL_TO is required when NOTIFY_EMAIL_ENABLED is set")
+		}
+	}
+	if c.NotifySlackEnabled && c.SlackWebhookURL == "" {
+		add("SLACK_WEBHOOK_URL is required when NOTIFY_SLACK_ENABLED is set")
+	}
+	if c.NotifyTelegramEnabled && (c.TelegramBotToken == "" || c.TelegramChatID == "") {
+		add("TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID are required when NOTIFY_TELEGRAM_ENABLED is set")
+	}
+
+	if !strings.EqualFold(c.LogFormat, "json") && !strings.EqualFold(c.LogFormat, "text") {
+		add("LOG_FORMAT must be 'json' or 'text', got '%s'", c.LogFormat)
+	}
+	if c.TracingSampleRatio > 1 {
+		add("TRACING_SAMPLE_RATIO must be between 0 and 1, got %g", c.TracingSampleRatio)
+	}
+	if c.AccessLogSampleRatio > 1 {
+		add("ACCESS_LOG_SAMPLE_RATIO must be between 0 and 1, got %g", c.AccessLogSampleRatio)
+	}
+	if c.PageSize <= 0 {
+		add("PageSize must be positive")
+	}
+	if c.PageSize < c.MinPageSize || c.PageSize > c.MaxPageSize {
+		add("PAGE_SIZE (%d) must be between MIN_PAGE_SIZE (%d) and MAX_PAGE_SIZE (%d)", c.PageSize, c.MinPageSize, c.MaxPageSize)
+	}
+	if c.MaxParallelRequests <= 0 {
+		add("MaxParallelRequests must be positive")
+	}
+	for name, limit := range c.JacadEndpointConcurrency {
+		if limit > c.MaxParallelRequests {
+			add("JACAD_ENDPOINT_CONCURRENCY limit %d for %s exceeds MAX_PARALLEL_REQUESTS (%d)", limit, name, c.MaxParallelRequests)
+		}
+	}
+	if c.JobRetryBackoff > c.JobRetryMaxBackoff {
+		add("JOB_RETRY_BACKOFF (%s) must not exceed JOB_RETRY_MAX_BACKOFF (%s)", c.JobRetryBackoff, c.JobRetryMaxBackoff)
+	}
+	if c.FetchTimeout > c.MaxFetchTimeout {
+		add("FETCH_TIMEOUT (%s) must not exceed MAX_FETCH_TIMEOUT (%s)", c.FetchTimeout, c.MaxFetchTimeout)
+	}
+	if c.AuthRefreshMargin >= c.AuthTokenExpiry {
+		add("AUTH_REFRESH_MARGIN (%s) must be shorter than AUTH_TOKEN_EXPIRY (%s)", c.AuthRefreshMargin, c.AuthTokenExpiry)
+	}
+	if c.APIRequireHMAC && len(c.APIKeys) == 0 {
+		add("API_REQUIRE_HMAC is set but API_KEYS is empty")
+	}
+	if c.PprofEnabled && len(c.APIKeys) == 0 {
+		add("PPROF_ENABLED is set but API_KEYS is empty; the profiles would be served without authentication")
+	} else if c.PprofEnabled && !slices.ContainsFunc(c.APIKeys, func(k APIKey) bool { return k.Admin }) {
+		add("PPROF_ENABLED is set but no API key in API_KEYS has the %s scope", APIKeyScopeAdmin)
+	}
+	if c.APIRequireHMAC && c.GRPCListenAddr != "" {
+		add("API_REQUIRE_HMAC is not supported by the gRPC API; unset GRPC_LISTEN_ADDR")
+	}
+	if len(c.Columns) == 0 {
+		add("column mapping has no columns")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
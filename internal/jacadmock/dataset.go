@@ -0,0 +1,156 @@
+package jacadmock
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+// Dataset is what a Server serves.
+type Dataset struct {
+	Enrollments   []models.Enrollment
+	Notices       []models.Period
+	Courses       []models.Course
+	Classes       []models.Turma
+	Organizations []models.Organization
+}
+
+// demoOrg is an organization of the demo data; the IDs match the built-in
+// organizations so their sheets are named as in production.
+type demoOrg struct {
+	id         int
+	name       string
+	modalidade string
+	courses    []string
+}
+
+var demoOrgs = []demoOrg{
+	{id: 20, name: "EAD", modalidade: "EAD", courses: []string{"Administração", "Pedagogia", "Gestão de RH"}},
+	{id: 17, name: "PÓS EAD", modalidade: "EAD", courses: []string{"MBA em Gestão", "Docência no Ensino Superior"}},
+	{id: 9, name: "PÓS Presencial", modalidade: "Presencial", courses: []string{"Direito Civil", "Enfermagem em UTI"}},
+}
+
+// demoPeriods are the periodos letivos of the demo data; the newest one has
+// an open process notice.
+var demoPeriods = []struct {
+	id     int
+	name   string
+	status string
+	start  time.Time
+}{
+	{id: 86, name: "2024/2", status: "ENCERRADO", start: time.Date(2024, time.August, 1, 0, 0, 0, 0, time.UTC)},
+	{id: 87, name: "2025/1", status: "ABERTO", start: time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)},
+}
+
+var demoStatuses = []string{"ATIVA", "ATIVA", "ATIVA", "TRANCADA", "CANCELADA", "CONCLUIDA"}
+
+var demoUnits = []string{"Polo Centro", "Polo Norte", "Polo Sul"}
+
+var demoAdmissions = []string{"Vestibular", "ENEM", "Transferência", "Segunda Graduação"}
+
+// DemoDataset returns n enrollments spread over the demo organizations,
+// periods and statuses, with their process notices, courses, classes and
+// organizations. The same n always yields the same data.
+func DemoDataset(n int) *Dataset {
+	str := func(s string) *string { return &s }
+	date := func(t time.Time) *utils.Date { d := utils.Date(t); return &d }
+	active := true
+
+	data := &Dataset{}
+	courseID := 0
+	courseIDs := make(map[string]int)
+	for _, org := range demoOrgs {
+		data.Organizations = append(data.Organizations, models.Organization{IdOrg: org.id, Nome: str(org.name)})
+		for _, course := range org.courses {
+			courseID++
+			courseIDs[course] = courseID
+			data.Courses = append(data.Courses, models.Course{
+				IdCurso:     courseID,
+				Codigo:      str(fmt.Sprintf("C%03d", courseID)),
+				Nome:        str(course),
+				Modalidade:  str(org.modalidade),
+				NivelEnsino: str("Graduação"),
+				Turno:       str("Noturno"),
+				OrgID:       org.id,
+				Organizacao: str(org.name),
+				Ativo:       &active,
+			})
+		}
+		for i, period := range demoPeriods {
+			data.Notices = append(data.Notices, models.Period{
+				OrgID:           org.id,
+				Organizacao:     org.name,
+				IDPeriodoLetivo: period.id,
+				PeriodoLetivo:   period.name,
+				IDEdital:        org.id*100 + i + 1,
+				Descricao:       "Processo Seletivo " + period.name,
+				StatusEdital:    period.status,
+				DataInicio:      date(period.start.AddDate(0, -2, 0)),
+				DataTermino:     date(period.start),
+			})
+		}
+	}
+
+	// Enrollment i is in class T<i%12+1>, so each organization has the
+	// four classes whose number matches its position in demoOrgs.
+	vagas := 40
+	for o, org := range demoOrgs {
+		for _, period := range demoPeriods {
+			for k := o + 1; k <= 12; k += len(demoOrgs) {
+				course := org.courses[(k-1)/len(demoOrgs)%len(org.courses)]
+				data.Classes = append(data.Classes, models.Turma{
+					IdTurma:         k,
+					Descricao:       str(fmt.Sprintf("T%02d", k)),
+					IdCurso:         courseIDs[course],
+					Curso:           str(course),
+					IdPeriodoLetivo: period.id,
+					PeriodoLetivo:   str(period.name),
+					Turno:           str("Noturno"),
+					Vagas:           &vagas,
+					OrgID:           org.id,
+					Organizacao:     str(org.name),
+				})
+			}
+		}
+	}
+
+	for i := range n {
+		// Organization, period and status vary independently, so every
+		// combination has enrollments.
+		org := demoOrgs[i%len(demoOrgs)]
+		period := demoPeriods[(i/len(demoOrgs))%len(demoPeriods)]
+		status := demoStatuses[(i/(len(demoOrgs)*len(demoPeriods)))%len(demoStatuses)]
+		matricula := period.start.AddDate(0, 0, -(i % 60))
+		course := org.courses[(i/len(demoOrgs))%len(org.courses)]
+		unit := demoUnits[i%len(demoUnits)]
+		data.Enrollments = append(data.Enrollments, models.Enrollment{
+			IdMatricula:     100000 + i,
+			Aluno:           str(fmt.Sprintf("Aluno Demo %04d", i+1)),
+			RA:              str(fmt.Sprintf("%08d", 20250000+i)),
+			Curso:           str(course),
+			Turma:           str(fmt.Sprintf("T%02d", i%12+1)),
+			Status:          str(status),
+			PeriodoLetivo:   str(period.name),
+			UnidadeFisica:   str(unit),
+			Organizacao:     str(org.name),
+			OrgID:           org.id,
+			DataMatricula:   date(matricula),
+			DataAtivacao:    date(matricula.AddDate(0, 0, 2)),
+			DataCadastro:    date(matricula.AddDate(0, 0, -5)),
+			IdAluno:         50000 + i,
+			IdCurso:         courseIDs[course],
+			IdTurma:         i%12 + 1,
+			IdPeriodoLetivo: period.id,
+			Modalidade:      str(org.modalidade),
+			Polo:            str(unit),
+			FormaIngresso:   str(demoAdmissions[i%len(demoAdmissions)]),
+			Turno:           str("Noturno"),
+			CPF:             str(fmt.Sprintf("000.%03d.%03d-%02d", i/1000%1000, i%1000, i%100)),
+			Email:           str(fmt.Sprintf("aluno.demo%04d@example.com", i+1)),
+			DataNascimento:  date(time.Date(1990+i%15, time.Month(i%12+1), i%28+1, 0, 0, 0, 0, time.UTC)),
+		})
+	}
+	return data
+}
@@ -0,0 +1,270 @@
+// Package jacadmock serves a small, deterministic imitation of the Jacad API
+// over httptest: token login, paginated enrollments, process notices,
+// courses, classes and organizations. Tests point a client's API_BASE at it, and the
+// --demo flag runs the whole pipeline against it without real credentials.
+package jacadmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// DefaultUserToken is the USER_TOKEN the server accepts unless
+// Options.UserToken says otherwise.
+const DefaultUserToken = "demo-user-token"
+
+// Options configures a Server. The zero value serves the DemoDataset with
+// the default endpoints.
+type Options struct {
+	// UserToken is the token POSTed to the auth endpoint.
+	UserToken string
+	// Data is served in place of DemoDataset(500).
+	Data *Dataset
+	// Endpoints overrides the paths of config.Defaults().Endpoints.
+	Endpoints map[string]string
+	// TokenLifetime is the expiresIn of issued tokens; default one hour.
+	TokenLifetime time.Duration
+	// Latency delays every response, to make demos look like real fetches.
+	Latency time.Duration
+	// APIVersion is the API served, models.APIV1 by default. With
+	// models.APIV2 every listing moves under config.Defaults()'s
+	// JacadV2PathPrefix and pages the v2 way; login stays where it is.
+	APIVersion string
+}
+
+// Server is a running mock Jacad API. URL is its API_BASE.
+type Server struct {
+	*httptest.Server
+	UserToken string
+
+	opts Options
+	data *Dataset
+
+	mu       sync.Mutex
+	tokens   map[string]bool
+	issued   int
+	requests map[string]int
+}
+
+// NewServer starts a mock Jacad server; Close stops it.
+func NewServer(opts Options) *Server {
+	s := newServer(opts)
+	s.Server = httptest.NewServer(s.handler())
+	return s
+}
+
+func newServer(opts Options) *Server {
+	if opts.UserToken == "" {
+		opts.UserToken = DefaultUserToken
+	}
+	if opts.TokenLifetime <= 0 {
+		opts.TokenLifetime = time.Hour
+	}
+	endpoints := config.Defaults().Endpoints
+	for name, path := range opts.Endpoints {
+		endpoints[name] = path
+	}
+	opts.Endpoints = endpoints
+	if opts.APIVersion == "" {
+		opts.APIVersion = models.APIV1
+	}
+	data := opts.Data
+	if data == nil {
+		data = DemoDataset(500)
+	}
+	return &Server{
+		UserToken: opts.UserToken,
+		opts:      opts,
+		data:      data,
+		tokens:    make(map[string]bool),
+		requests:  make(map[string]int),
+	}
+}
+
+// Requests returns how many requests the endpoint named name, e.g.
+// "ENROLLMENTS", has answered.
+func (s *Server) Requests(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[name]
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	routes := map[string]http.HandlerFunc{
+		"AUTH": s.handleAuth,
+		"ENROLLMENTS": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			serveFiltered(w, r, s.opts.APIVersion, s.data.Enrollments, func(e models.Enrollment) bool {
+				return matches(q, "idPeriodoLetivo", strconv.Itoa(e.IdPeriodoLetivo)) &&
+					matches(q, "statusMatricula", deref(e.Status)) &&
+					matches(q, "idOrg", strconv.Itoa(e.OrgID))
+			})
+		},
+		"PROCESS_NOTICES": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			serveFiltered(w, r, s.opts.APIVersion, s.data.Notices, func(p models.Period) bool {
+				return matches(q, "statusEdital", p.StatusEdital) && matches(q, "idOrg", strconv.Itoa(p.OrgID))
+			})
+		},
+		"COURSES": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			serveFiltered(w, r, s.opts.APIVersion, s.data.Courses, func(c models.Course) bool {
+				return matches(q, "idOrg", strconv.Itoa(c.OrgID))
+			})
+		},
+		"CLASSES": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			serveFiltered(w, r, s.opts.APIVersion, s.data.Classes, func(t models.Turma) bool {
+				return matches(q, "idPeriodoLetivo", strconv.Itoa(t.IdPeriodoLetivo)) && matches(q, "idOrg", strconv.Itoa(t.OrgID))
+			})
+		},
+		"ORGANIZATIONS": func(w http.ResponseWriter, r *http.Request) {
+			serveFiltered(w, r, s.opts.APIVersion, s.data.Organizations, func(models.Organization) bool { return true })
+		},
+	}
+	for name, handle := range routes {
+		path := s.opts.Endpoints[name]
+		if path == "" {
+			continue
+		}
+		if name != "AUTH" && s.opts.APIVersion == models.APIV2 {
+			path = config.Defaults().JacadV2PathPrefix + path
+		}
+		mux.HandleFunc(path, s.wrap(name, handle))
+		// Jacad answers with and without the trailing slash.
+		if trimmed := strings.TrimSuffix(path, "/"); trimmed != path {
+			mux.HandleFunc(trimmed, s.wrap(name, handle))
+		}
+	}
+	return mux
+}
+
+// wrap counts requests, applies the latency and, for everything but the
+// auth endpoint, checks the bearer token.
+func (s *Server) wrap(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[name]++
+		authorized := s.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		s.mu.Unlock()
+
+		if s.opts.Latency > 0 {
+			select {
+			case <-time.After(s.opts.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if name != "AUTH" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if !authorized {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "invalid or expired token"})
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleAuth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("token") != s.opts.UserToken {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "invalid user token"})
+		return
+	}
+	s.mu.Lock()
+	s.issued++
+	token := fmt.Sprintf("mock-token-%d", s.issued)
+	s.tokens[token] = true
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"token":     token,
+		"expiresIn": int(s.opts.TokenLifetime.Seconds()),
+	})
+}
+
+// serveFiltered answers one page of the items keep accepts, selected and
+// described the way version does: currentPage, from 0, and pageSize in v1;
+// page, from 1, and size in v2.
+func serveFiltered[T any](w http.ResponseWriter, r *http.Request, version string, items []T, keep func(T) bool) {
+	q := r.URL.Query()
+	pageParam, sizeParam, firstPage := "currentPage", "pageSize", 0
+	if version == models.APIV2 {
+		pageParam, sizeParam, firstPage = "page", "size", 1
+	}
+	page, err := strconv.Atoi(q.Get(pageParam))
+	if err != nil || page < firstPage {
+		page = firstPage
+	}
+	page -= firstPage
+	pageSize, err := strconv.Atoi(q.Get(sizeParam))
+	if err != nil || pageSize <= 0 {
+		pageSize = 20
+	}
+
+	kept := make([]T, 0, len(items))
+	for _, item := range items {
+		if keep(item) {
+			kept = append(kept, item)
+		}
+	}
+	first := min(page*pageSize, len(kept))
+	last := min(first+pageSize, len(kept))
+	totalPages := (len(kept) + pageSize - 1) / pageSize
+	if version == models.APIV2 {
+		writeJSON(w, http.StatusOK, models.APIResponseV2[T]{
+			Meta: &models.PageV2{
+				Page:       page + 1,
+				Size:       pageSize,
+				TotalItems: len(kept),
+				TotalPages: totalPages,
+			},
+			Data: kept[first:last],
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, models.APIResponse[T]{
+		Page: &models.Page{
+			CurrentPage:   page,
+			PageSize:      pageSize,
+			TotalElements: len(kept),
+			TotalPages:    totalPages,
+		},
+		Elements: kept[first:last],
+	})
+}
+
+// matches reports whether the query parameter name is unset or equals value.
+func matches(q map[string][]string, name, value string) bool {
+	want, ok := q[name]
+	return !ok || len(want) == 0 || want[0] == "" || want[0] == value
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,126 @@
+package jacadmock
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer(Options{Data: DemoDataset(120)})
+	defer server.Close()
+
+	login, err := http.NewRequest(http.MethodPost, server.URL+"/auth/token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	login.Header.Set("token", server.UserToken)
+	resp, err := http.DefaultClient.Do(login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var auth map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&auth)
+	resp.Body.Close()
+	token, _ := auth["token"].(string)
+	if resp.StatusCode != http.StatusOK || token == "" || auth["expiresIn"] != float64(3600) {
+		t.Fatalf("login = %d %v, want a token expiring in an hour", resp.StatusCode, auth)
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		token         string
+		status        int
+		totalElements int
+		totalPages    int
+		elements      int
+	}{
+		{name: "no token", path: "/academico/matriculas", status: http.StatusUnauthorized},
+		{name: "unknown token", path: "/academico/matriculas", token: "forged", status: http.StatusUnauthorized},
+		{name: "first page", path: "/academico/matriculas?currentPage=0&pageSize=50", token: token, status: http.StatusOK, totalElements: 120, totalPages: 3, elements: 50},
+		{name: "last page", path: "/academico/matriculas?currentPage=2&pageSize=50", token: token, status: http.StatusOK, totalElements: 120, totalPages: 3, elements: 20},
+		{name: "past the end", path: "/academico/matriculas?currentPage=5&pageSize=50", token: token, status: http.StatusOK, totalElements: 120, totalPages: 3},
+		{name: "filtered", path: "/academico/matriculas?idPeriodoLetivo=87&statusMatricula=ATIVA&idOrg=20&pageSize=100", token: token, status: http.StatusOK, totalElements: 11, totalPages: 1, elements: 11},
+		{name: "classes", path: "/academico/turmas?idPeriodoLetivo=87&idOrg=17", token: token, status: http.StatusOK, totalElements: 4, totalPages: 1, elements: 4},
+		{name: "notices without slash", path: "/processo-seletivo/editais?statusEdital=ABERTO", token: token, status: http.StatusOK, totalElements: 3, totalPages: 1, elements: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var page models.APIResponse[json.RawMessage]
+			if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+				t.Fatal(err)
+			}
+			if page.Page.TotalElements != tt.totalElements || page.Page.TotalPages != tt.totalPages || len(page.Elements) != tt.elements {
+				t.Errorf("page = %+v with %d elements, want %d elements over %d pages and %d on this one", *page.Page, len(page.Elements), tt.totalElements, tt.totalPages, tt.elements)
+			}
+		})
+	}
+	if got := server.Requests("ENROLLMENTS"); got != 6 {
+		t.Errorf("Requests(ENROLLMENTS) = %d, want 6", got)
+	}
+}
+
+func TestDemoDatasetCombinations(t *testing.T) {
+	seen := make(map[[3]string]bool)
+	for _, e := range DemoDataset(72).Enrollments {
+		seen[[3]string{*e.Organizacao, *e.PeriodoLetivo, *e.Status}] = true
+	}
+	// 3 organizations, 2 periods and 4 distinct statuses.
+	if len(seen) != 24 {
+		t.Errorf("got %d organization, period and status combinations, want 24", len(seen))
+	}
+}
+
+func TestServerV2(t *testing.T) {
+	server := NewServer(Options{Data: DemoDataset(120), APIVersion: models.APIV2})
+	defer server.Close()
+	server.tokens["test-token"] = true
+
+	get := func(path string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer test-token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := get("/academico/matriculas"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("v1 path status = %d, want 404", resp.StatusCode)
+	}
+	resp := get("/v2/academico/matriculas?page=3&size=50")
+	defer resp.Body.Close()
+	var page models.APIResponseV2[json.RawMessage]
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Meta == nil || *page.Meta != (models.PageV2{Page: 3, Size: 50, TotalItems: 120, TotalPages: 3}) || len(page.Data) != 20 {
+		t.Errorf("page = %+v with %d elements, want the last of 3 pages with 20 elements", page.Meta, len(page.Data))
+	}
+}
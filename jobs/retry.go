@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RetryPolicy re-enqueues failed background jobs: up to MaxAttempts attempts
+// in all, waiting Backoff before the first retry and twice as long before
+// each following one, at most MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Delay returns how long to wait after the given failed attempt, counted
+// from 1, before enqueueing the next one.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// Attempt numbers the runs of a job retried under a RetryPolicy. Each
+// attempt is a job of its own; FirstID is the ID of the first one.
+type Attempt struct {
+	Number  int
+	Max     int
+	FirstID string
+}
+
+type attemptKey struct{}
+
+// WithAttempt marks the job run with ctx as attempt a.
+func WithAttempt(ctx context.Context, a Attempt) context.Context {
+	return context.WithValue(ctx, attemptKey{}, a)
+}
+
+// AttemptFrom returns the attempt set by WithAttempt; ok is false for jobs
+// that are not retried.
+func AttemptFrom(ctx context.Context) (a Attempt, ok bool) {
+	a, ok = ctx.Value(attemptKey{}).(Attempt)
+	return a, ok
+}
+
+// WillRetry reports whether a job that ended with err, run with ctx, gets
+// another attempt: it failed, attempts are left, and it was neither
+// cancelled through Cancel nor stopped by a shutdown.
+func (a Attempt) WillRetry(ctx context.Context, err error) bool {
+	if err == nil || a.Number >= a.Max {
+		return false
+	}
+	cause := context.Cause(ctx)
+	return !errors.Is(cause, ErrCancelled) && !errors.Is(cause, ErrShuttingDown)
+}
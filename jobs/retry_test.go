@@ -0,0 +1,91 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, Backoff: time.Minute, MaxBackoff: 5 * time.Minute}
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, w := range want {
+		if got := policy.Delay(i + 1); got != w {
+			t.Errorf("Delay(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
+
+func TestAttemptWillRetry(t *testing.T) {
+	failure := errors.New("jacad unavailable")
+	cancelled, cancel := context.WithCancelCause(context.Background())
+	cancel(ErrCancelled)
+	stopped, stop := context.WithCancelCause(context.Background())
+	stop(ErrShuttingDown)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		attempt Attempt
+		err     error
+		want    bool
+	}{
+		{name: "failed with attempts left", ctx: context.Background(), attempt: Attempt{Number: 1, Max: 3}, err: failure, want: true},
+		{name: "succeeded", ctx: context.Background(), attempt: Attempt{Number: 1, Max: 3}},
+		{name: "last attempt", ctx: context.Background(), attempt: Attempt{Number: 3, Max: 3}, err: failure},
+		{name: "retries disabled", ctx: context.Background(), attempt: Attempt{Number: 1, Max: 1}, err: failure},
+		{name: "cancelled", ctx: cancelled, attempt: Attempt{Number: 1, Max: 3}, err: failure},
+		{name: "shutting down", ctx: stopped, attempt: Attempt{Number: 1, Max: 3}, err: failure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.attempt.WillRetry(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("WillRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttemptFrom(t *testing.T) {
+	if _, ok := AttemptFrom(context.Background()); ok {
+		t.Error("AttemptFrom(Background) ok, want no attempt")
+	}
+	want := Attempt{Number: 2, Max: 3, FirstID: "job-1"}
+	if got, ok := AttemptFrom(WithAttempt(context.Background(), want)); !ok || got != want {
+		t.Errorf("AttemptFrom() = %+v, %v, want %+v", got, ok, want)
+	}
+}
+
+func TestTrackerWaitRetry(t *testing.T) {
+	tracker := NewTracker(1)
+	if err := tracker.WaitRetry("done", time.Millisecond); err != nil {
+		t.Errorf("WaitRetry() = %v, want nil once the backoff elapsed", err)
+	}
+
+	waited := make(chan error, 1)
+	go func() { waited <- tracker.WaitRetry("failed", time.Hour) }()
+	var finished <-chan struct{}
+	for deadline := time.Now().Add(time.Second); finished == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("Cancel() never found the job backing off")
+		}
+		finished, _ = tracker.Cancel("failed")
+		time.Sleep(time.Millisecond)
+	}
+	if err := <-waited; !errors.Is(err, ErrCancelled) {
+		t.Errorf("WaitRetry() of a cancelled job = %v, want ErrCancelled", err)
+	}
+	<-finished
+
+	go func() { waited <- tracker.WaitRetry("failed", time.Hour) }()
+	if err := tracker.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-waited; !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("WaitRetry() during shutdown = %v, want ErrShuttingDown", err)
+	}
+	if err := tracker.WaitRetry("late", time.Millisecond); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("WaitRetry() after shutdown = %v, want ErrShuttingDown", err)
+	}
+}
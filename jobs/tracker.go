@@ -0,0 +1,502 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/metrics"
+)
+
+var ErrShuttingDown = errors.New("server is shutting down and not accepting new jobs")
+
+// ErrCancelled is the cancellation cause of jobs stopped through Cancel; see
+// context.Cause.
+var ErrCancelled = errors.New("job cancelled")
+
+// Job states reported by Status.
+const (
+	StateQueued  = "queued"
+	StateRunning = "running"
+)
+
+var (
+	jobsRunning = metrics.NewGaugeVec("jobs_running", "Jobs currently running.")
+	jobsQueued  = metrics.NewGaugeVec("jobs_queued", "Jobs waiting for a free slot.")
+)
+
+// JobStatus describes a queued or running job. Position is 1 for the next job
+// to start and 0 once the job is running.
+type JobStatus struct {
+	ID       string `json:"id"`
+	State    string `json:"state"`
+	Position int    `json:"position,omitempty"`
+	Queued   int    `json:"queued"`
+	Running  int    `json:"running"`
+}
+
+// JobInfo describes a job in the queue listing. ElapsedSeconds is the time
+// since the job started, or since it was enqueued while it is still queued.
+type JobInfo struct {
+	ID             string     `json:"id"`
+	Kind           string     `json:"kind,omitempty"`
+	Params         any        `json:"params,omitempty"`
+	Position       int        `json:"position,omitempty"`
+	EnqueuedAt     time.Time  `json:"enqueuedAt"`
+	StartedAt      *time.Time `json:"startedAt,omitempty"`
+	ElapsedSeconds float64    `json:"elapsedSeconds"`
+}
+
+// QueueStatus lists the running jobs, longest running first, and the queued
+// ones in the order they will start. StartsImmediately reports whether a job
+// enqueued now would get a slot straight away.
+type QueueStatus struct {
+	MaxConcurrentJobs int       `json:"maxConcurrentJobs"`
+	StartsImmediately bool      `json:"startsImmediately"`
+	Draining          bool      `json:"draining"`
+	Running           []JobInfo `json:"running"`
+	Queued            []JobInfo `json:"queued"`
+}
+
+// Description says what a job does for the queue listing; see
+// WithDescription.
+type Description struct {
+	Kind   string
+	Params any
+}
+
+type descriptionKey struct{}
+
+// WithDescription returns a context whose jobs Enqueue lists under desc.
+func WithDescription(ctx context.Context, desc Description) context.Context {
+	return context.WithValue(ctx, descriptionKey{}, desc)
+}
+
+func descriptionFrom(ctx context.Context) Description {
+	desc, _ := ctx.Value(descriptionKey{}).(Description)
+	return desc
+}
+
+type activeJob struct {
+	jobID      string
+	desc       Description
+	enqueuedAt time.Time
+	startedAt  time.Time
+	cancel     context.CancelCauseFunc
+	// finished is closed once the job's done func has been called.
+	finished chan struct{}
+}
+
+type waiter struct {
+	jobID      string
+	desc       Description
+	enqueuedAt time.Time
+	ready      chan struct{}
+	cancelled  chan struct{}
+}
+
+// Tracker keeps track of in-flight fetch jobs so the server can drain them
+// before exiting. At most maxConcurrent jobs run at once; the rest wait in a
+// FIFO queue.
+type Tracker struct {
+	mu            sync.Mutex
+	wg            sync.WaitGroup
+	draining      bool
+	drain         chan struct{}
+	nextID        uint64
+	active        map[uint64]activeJob
+	maxConcurrent int
+	// running counts active jobs plus queued jobs already granted a slot.
+	running int
+	queue   []*waiter
+	// backoffs holds, by job ID, the failed jobs waiting out their retry
+	// backoff; closing the channel drops the retry. See WaitRetry.
+	backoffs map[string]chan struct{}
+}
+
+// NewTracker returns a tracker running at most maxConcurrent jobs at once;
+// 0 or less means no limit.
+func NewTracker(maxConcurrent int) *Tracker {
+	return &Tracker{
+		active:        make(map[uint64]activeJob),
+		backoffs:      make(map[string]chan struct{}),
+		drain:         make(chan struct{}),
+		maxConcurrent: maxConcurrent,
+	}
+}
+
+// NewID returns a random job ID.
+func NewID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "job-" + hex.EncodeToString(b)
+}
+
+// Pending is a job registered by Enqueue that may still be waiting for a
+// slot. Wait must be called exactly once.
+type Pending struct {
+	// ID identifies the job in Status and the jobs API.
+	ID string
+	// Position is the job's place in the queue when it was enqueued, or 0
+	// when it got a slot straight away.
+	Position int
+
+	tracker    *Tracker
+	ctx        context.Context
+	desc       Description
+	enqueuedAt time.Time
+	waiter     *waiter
+}
+
+// Enqueue registers a job under a new ID without waiting for a slot: it takes
+// a free slot when there is one and joins the FIFO queue otherwise. ctx is the
+// parent of the job context returned by Wait, carries the job ID (see
+// logging.JobID) and cancels the wait if it ends while the job is queued. The
+// job is listed by Queue under ctx's description; see WithDescription. It
+// fails with ErrShuttingDown once shutdown starts.
+func (t *Tracker) Enqueue(ctx context.Context) (*Pending, error) {
+	jobID := NewID()
+	p := &Pending{
+		ID:         jobID,
+		tracker:    t,
+		ctx:        logging.WithJobID(ctx, jobID),
+		desc:       descriptionFrom(ctx),
+		enqueuedAt: time.Now(),
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return nil, ErrShuttingDown
+	}
+	if t.hasFreeSlot() && len(t.queue) == 0 {
+		t.running++
+		return p, nil
+	}
+
+	p.waiter = &waiter{jobID: jobID, desc: p.desc, enqueuedAt: p.enqueuedAt, ready: make(chan struct{}), cancelled: make(chan struct{})}
+	t.queue = append(t.queue, p.waiter)
+	p.Position = len(t.queue)
+	t.updateGauges()
+	return p, nil
+}
+
+// Wait blocks until the job holds a slot and starts it. It returns a context
+// that is cancelled when the job is cancelled or the tracker force-stops jobs
+// during shutdown, and fails with ErrShuttingDown once shutdown starts, with
+// ErrCancelled if the job is cancelled while queued or with the enqueue
+// context's error if it ends while the job is queued. The returned done func
+// must be called once the job finishes.
+func (p *Pending) Wait() (context.Context, func(), error) {
+	t, ctx, w := p.tracker, p.ctx, p.waiter
+	if w != nil {
+		logging.FromContext(ctx).Info("Job queued. Waiting for a free slot.", "position", p.Position, "maxConcurrentJobs", t.maxConcurrent)
+
+		select {
+		case <-w.ready:
+		case <-w.cancelled:
+			return nil, nil, ErrCancelled
+		case <-ctx.Done():
+			if t.leaveQueue(w) {
+				return nil, nil, ctx.Err()
+			}
+			// The slot was granted concurrently; hand it back.
+			t.release()
+			return nil, nil, ctx.Err()
+		case <-t.drain:
+			if t.leaveQueue(w) {
+				return nil, nil, ErrShuttingDown
+			}
+			<-w.ready
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		t.running--
+		t.promote()
+		return nil, nil, ErrShuttingDown
+	}
+	return t.register(ctx, p)
+}
+
+// Start enqueues a job and waits for its slot; see Enqueue and Wait.
+func (t *Tracker) Start(ctx context.Context) (context.Context, func(), error) {
+	p, err := t.Enqueue(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p.Wait()
+}
+
+// register adds a job that already holds a slot. t.mu must be held.
+func (t *Tracker) register(ctx context.Context, p *Pending) (context.Context, func(), error) {
+	t.nextID++
+	id := t.nextID
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	finished := make(chan struct{})
+	t.active[id] = activeJob{
+		jobID:      p.ID,
+		desc:       p.desc,
+		enqueuedAt: p.enqueuedAt,
+		startedAt:  time.Now(),
+		cancel:     cancel,
+		finished:   finished,
+	}
+	t.wg.Add(1)
+	t.updateGauges()
+
+	var once sync.Once
+	done := func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.active, id)
+			t.mu.Unlock()
+			t.release()
+			cancel(nil)
+			close(finished)
+			t.wg.Done()
+		})
+	}
+	return jobCtx, done, nil
+}
+
+func (t *Tracker) hasFreeSlot() bool {
+	return t.maxConcurrent <= 0 || t.running < t.maxConcurrent
+}
+
+// release frees a slot and hands it to the next queued job.
+func (t *Tracker) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running--
+	t.promote()
+}
+
+// promote grants free slots to queued jobs in order. t.mu must be held.
+func (t *Tracker) promote() {
+	for len(t.queue) > 0 && t.hasFreeSlot() {
+		next := t.queue[0]
+		t.queue = t.queue[1:]
+		t.running++
+		close(next.ready)
+	}
+	t.updateGauges()
+}
+
+// leaveQueue removes w from the queue and reports whether it was still
+// waiting, i.e. had not been granted a slot.
+func (t *Tracker) leaveQueue(w *waiter) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i := slices.Index(t.queue, w)
+	if i < 0 {
+		return false
+	}
+	t.queue = slices.Delete(t.queue, i, i+1)
+	t.updateGauges()
+	return true
+}
+
+// updateGauges publishes the queue metrics. t.mu must be held.
+func (t *Tracker) updateGauges() {
+	jobsRunning.Set(float64(len(t.active)))
+	jobsQueued.Set(float64(len(t.queue)))
+}
+
+// Status reports whether the job with the given ID is queued, and where, or
+// running.
+func (t *Tracker) Status(jobID string) (JobStatus, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := JobStatus{ID: jobID, Queued: len(t.queue), Running: len(t.active)}
+	for i, w := range t.queue {
+		if w.jobID == jobID {
+			status.State = StateQueued
+			status.Position = i + 1
+			return status, true
+		}
+	}
+	for _, job := range t.active {
+		if job.jobID == jobID {
+			status.State = StateRunning
+			return status, true
+		}
+	}
+	return status, false
+}
+
+// Queue lists the running and queued jobs.
+func (t *Tracker) Queue() QueueStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	status := QueueStatus{
+		MaxConcurrentJobs: t.maxConcurrent,
+		StartsImmediately: !t.draining && t.hasFreeSlot() && len(t.queue) == 0,
+		Draining:          t.draining,
+		Running:           make([]JobInfo, 0, len(t.active)),
+		Queued:            make([]JobInfo, 0, len(t.queue)),
+	}
+	for _, job := range t.active {
+		startedAt := job.startedAt
+		status.Running = append(status.Running, JobInfo{
+			ID:             job.jobID,
+			Kind:           job.desc.Kind,
+			Params:         job.desc.Params,
+			EnqueuedAt:     job.enqueuedAt,
+			StartedAt:      &startedAt,
+			ElapsedSeconds: now.Sub(startedAt).Seconds(),
+		})
+	}
+	slices.SortFunc(status.Running, func(a, b JobInfo) int {
+		return a.StartedAt.Compare(*b.StartedAt)
+	})
+	for i, w := range t.queue {
+		status.Queued = append(status.Queued, JobInfo{
+			ID:             w.jobID,
+			Kind:           w.desc.Kind,
+			Params:         w.desc.Params,
+			Position:       i + 1,
+			EnqueuedAt:     w.enqueuedAt,
+			ElapsedSeconds: now.Sub(w.enqueuedAt).Seconds(),
+		})
+	}
+	return status
+}
+
+// Cancel stops the job with the given ID. A queued job leaves the queue and
+// its Wait fails with ErrCancelled; a running job's context is cancelled with
+// ErrCancelled as its cause; a failed job waiting to be retried is not
+// retried. The returned channel is closed once the job has finished, i.e.
+// once it can no longer write anything. ok is false when no queued, running
+// or backing off job has that ID.
+func (t *Tracker) Cancel(jobID string) (finished <-chan struct{}, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if cancelled, ok := t.backoffs[jobID]; ok {
+		delete(t.backoffs, jobID)
+		close(cancelled)
+		return cancelled, true
+	}
+
+	for i, w := range t.queue {
+		if w.jobID == jobID {
+			t.queue = slices.Delete(t.queue, i, i+1)
+			close(w.cancelled)
+			t.updateGauges()
+			closed := make(chan struct{})
+			close(closed)
+			return closed, true
+		}
+	}
+	for _, job := range t.active {
+		if job.jobID == jobID {
+			job.cancel(ErrCancelled)
+			return job.finished, true
+		}
+	}
+	return nil, false
+}
+
+// WaitRetry waits delay before the failed job with the given ID is retried.
+// It fails with ErrCancelled if the job is cancelled meanwhile and with
+// ErrShuttingDown once shutdown starts, in which case the retry is dropped.
+func (t *Tracker) WaitRetry(jobID string, delay time.Duration) error {
+	cancelled := make(chan struct{})
+	t.mu.Lock()
+	if t.draining {
+		t.mu.Unlock()
+		return ErrShuttingDown
+	}
+	t.backoffs[jobID] = cancelled
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		if t.backoffs[jobID] == cancelled {
+			delete(t.backoffs, jobID)
+		}
+		t.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-cancelled:
+		return ErrCancelled
+	case <-t.drain:
+		return ErrShuttingDown
+	}
+}
+
+// Draining reports whether Shutdown has been called.
+func (t *Tracker) Draining() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.draining
+}
+
+func (t *Tracker) ActiveCount() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.active)
+}
+
+// QueuedCount returns how many jobs are waiting for a slot.
+func (t *Tracker) QueuedCount() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.queue)
+}
+
+// Shutdown stops accepting new jobs, rejects queued ones and waits for
+// in-flight ones until ctx expires. Jobs still running at that point are
+// cancelled and waited for.
+func (t *Tracker) Shutdown(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	t.mu.Lock()
+	if !t.draining {
+		t.draining = true
+		close(t.drain)
+	}
+	pending, queued := len(t.active), len(t.queue)
+	t.mu.Unlock()
+
+	logger.Info("Draining in-flight jobs", "jobs", pending, "rejectedQueued", queued)
+
+	finished := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		logger.Info("All in-flight jobs finished")
+		return nil
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	logger.Warn("Drain timeout reached. Cancelling remaining jobs", "jobs", len(t.active))
+	for _, job := range t.active {
+		job.cancel(ErrShuttingDown)
+	}
+	t.mu.Unlock()
+
+	<-finished
+	return ctx.Err()
+}
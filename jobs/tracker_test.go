@@ -0,0 +1,229 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+)
+
+// startQueued enqueues a job that has to wait for a slot and waits for it in
+// the background.
+func startQueued(t *testing.T, tracker *Tracker, ctx context.Context) (string, chan error) {
+	t.Helper()
+	pending, err := tracker.Enqueue(ctx)
+	if err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	if pending.Position == 0 {
+		t.Fatalf("job %s got a slot, want it queued", pending.ID)
+	}
+	started := make(chan error, 1)
+	go func() {
+		_, done, err := pending.Wait()
+		if err == nil {
+			defer done()
+		}
+		started <- err
+	}()
+	return pending.ID, started
+}
+
+// startRunning enqueues a job that gets a slot straight away and starts it.
+func startRunning(t *testing.T, tracker *Tracker) (string, context.Context, func()) {
+	t.Helper()
+	pending, err := tracker.Enqueue(context.Background())
+	if err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	if pending.Position != 0 {
+		t.Fatalf("job %s queued at %d, want a free slot", pending.ID, pending.Position)
+	}
+	jobCtx, done, err := pending.Wait()
+	if err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if got := logging.JobID(jobCtx); got != pending.ID {
+		t.Errorf("job context carries ID %q, want %q", got, pending.ID)
+	}
+	return pending.ID, jobCtx, done
+}
+
+func TestTrackerQueuePosition(t *testing.T) {
+	tracker := NewTracker(1)
+	running, _, done := startRunning(t, tracker)
+	first, _ := startQueued(t, tracker, context.Background())
+	second, _ := startQueued(t, tracker, context.Background())
+	if first == second || first == running {
+		t.Fatalf("job IDs are not unique: %s, %s, %s", running, first, second)
+	}
+
+	tests := []struct {
+		id       string
+		state    string
+		position int
+	}{
+		{id: running, state: StateRunning},
+		{id: first, state: StateQueued, position: 1},
+		{id: second, state: StateQueued, position: 2},
+	}
+	for _, tt := range tests {
+		status, ok := tracker.Status(tt.id)
+		if !ok {
+			t.Fatalf("Status(%q) not found", tt.id)
+		}
+		if status.State != tt.state || status.Position != tt.position || status.Queued != 2 || status.Running != 1 {
+			t.Errorf("Status(%q) = %+v, want state %s position %d with 2 queued and 1 running", tt.id, status, tt.state, tt.position)
+		}
+	}
+	if _, ok := tracker.Status("unknown"); ok {
+		t.Error("Status(unknown) found a job")
+	}
+
+	done()
+	if err := tracker.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+}
+
+func TestTrackerQueue(t *testing.T) {
+	tracker := NewTracker(1)
+	if status := tracker.Queue(); !status.StartsImmediately || len(status.Running) != 0 || len(status.Queued) != 0 {
+		t.Fatalf("Queue() of an idle tracker = %+v, want it empty and starting immediately", status)
+	}
+
+	running, _, done := startRunning(t, tracker)
+	nightly := WithDescription(context.Background(), Description{Kind: "GET /api/v1/fetch-enrollments", Params: map[string]string{"preset": "nightly"}})
+	first, _ := startQueued(t, tracker, nightly)
+	second, _ := startQueued(t, tracker, context.Background())
+
+	status := tracker.Queue()
+	if status.StartsImmediately || status.MaxConcurrentJobs != 1 {
+		t.Errorf("Queue() = %+v, want a full tracker of 1 slot", status)
+	}
+	if len(status.Running) != 1 || status.Running[0].ID != running || status.Running[0].StartedAt == nil {
+		t.Errorf("Queue().Running = %+v, want %s with its start time", status.Running, running)
+	}
+	if len(status.Queued) != 2 {
+		t.Fatalf("Queue().Queued = %+v, want 2 jobs", status.Queued)
+	}
+	if got := status.Queued[0]; got.ID != first || got.Position != 1 || got.Kind != "GET /api/v1/fetch-enrollments" || got.Params == nil || got.StartedAt != nil {
+		t.Errorf("Queue().Queued[0] = %+v, want %s at position 1 with its description", got, first)
+	}
+	if got := status.Queued[1]; got.ID != second || got.Position != 2 || got.Kind != "" {
+		t.Errorf("Queue().Queued[1] = %+v, want undescribed %s at position 2", got, second)
+	}
+
+	done()
+	if err := tracker.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+	if status := tracker.Queue(); status.StartsImmediately || !status.Draining {
+		t.Errorf("Queue() after Shutdown = %+v, want draining", status)
+	}
+}
+
+func TestTrackerPromotesInOrder(t *testing.T) {
+	tracker := NewTracker(1)
+	_, _, done := startRunning(t, tracker)
+
+	_, first := startQueued(t, tracker, context.Background())
+	startQueued(t, tracker, context.Background())
+	done()
+
+	if err := <-first; err != nil {
+		t.Fatalf("first queued job Wait() = %v", err)
+	}
+	if err := tracker.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+	if got := tracker.ActiveCount(); got != 0 {
+		t.Errorf("ActiveCount() after shutdown = %d, want 0", got)
+	}
+}
+
+func TestTrackerQueuedJobLeavesOnCancel(t *testing.T) {
+	tracker := NewTracker(1)
+	_, _, done := startRunning(t, tracker)
+	defer done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, started := startQueued(t, tracker, ctx)
+	cancel()
+	if err := <-started; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait() of cancelled queued job = %v, want Canceled", err)
+	}
+	if got := tracker.QueuedCount(); got != 0 {
+		t.Errorf("QueuedCount() = %d, want 0", got)
+	}
+}
+
+func TestTrackerCancel(t *testing.T) {
+	tracker := NewTracker(1)
+	running, jobCtx, done := startRunning(t, tracker)
+	queuedID, queued := startQueued(t, tracker, context.Background())
+
+	finished, ok := tracker.Cancel(queuedID)
+	if !ok {
+		t.Fatalf("Cancel(%q) of queued job not found", queuedID)
+	}
+	<-finished
+	if err := <-queued; !errors.Is(err, ErrCancelled) {
+		t.Errorf("Wait() of cancelled queued job = %v, want ErrCancelled", err)
+	}
+	if got := tracker.QueuedCount(); got != 0 {
+		t.Errorf("QueuedCount() = %d, want 0", got)
+	}
+
+	finished, ok = tracker.Cancel(running)
+	if !ok {
+		t.Fatalf("Cancel(%q) of running job not found", running)
+	}
+	<-jobCtx.Done()
+	if cause := context.Cause(jobCtx); !errors.Is(cause, ErrCancelled) {
+		t.Errorf("context.Cause() = %v, want ErrCancelled", cause)
+	}
+	select {
+	case <-finished:
+		t.Fatal("Cancel() reported the job finished before done was called")
+	default:
+	}
+	done()
+	<-finished
+
+	if _, ok := tracker.Cancel(running); ok {
+		t.Errorf("Cancel(%q) of a finished job reported ok", running)
+	}
+}
+
+func TestTrackerShutdown(t *testing.T) {
+	tracker := NewTracker(1)
+	_, jobCtx, done := startRunning(t, tracker)
+	_, queued := startQueued(t, tracker, context.Background())
+
+	finished := make(chan struct{})
+	go func() {
+		<-jobCtx.Done()
+		done()
+		close(finished)
+	}()
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tracker.Shutdown(drainCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() = %v, want DeadlineExceeded after cancelling the running job", err)
+	}
+	<-finished
+
+	if err := <-queued; !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("queued job Wait() = %v, want ErrShuttingDown", err)
+	}
+	if _, _, err := tracker.Start(context.Background()); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Start() after Shutdown = %v, want ErrShuttingDown", err)
+	}
+	if !tracker.Draining() {
+		t.Error("Draining() = false after Shutdown")
+	}
+}
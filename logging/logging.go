@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+	jobIDKey
+)
+
+func Init(format, level string) {
+	opts := &slog.HandlerOptions{Level: parseLevel(level)}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "text") {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+
+	slog.SetDefault(slog.New(handler))
+}
+
+// WithRequestID returns a context carrying the correlation ID and a logger
+// that tags every record with it.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey, requestID)
+	return context.WithValue(ctx, loggerKey, slog.Default().With("requestId", requestID))
+}
+
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if rid, ok := ctx.Value(requestIDKey).(string); ok {
+		return rid
+	}
+	return ""
+}
+
+// WithJobID returns a context carrying the ID of the job it runs and a logger
+// that tags every record with it.
+func WithJobID(ctx context.Context, jobID string) context.Context {
+	ctx = context.WithValue(ctx, jobIDKey, jobID)
+	return context.WithValue(ctx, loggerKey, FromContext(ctx).With("jobId", jobID))
+}
+
+// JobID returns the job ID set by WithJobID, or "" outside a job.
+func JobID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(jobIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx == nil {
+		return slog.Default()
+	}
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
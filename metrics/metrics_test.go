@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, c collector) string {
+	t.Helper()
+	var b strings.Builder
+	w := bufio.NewWriter(&b)
+	c.write(w)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestCounterExposition(t *testing.T) {
+	c := NewCounterVec("test_requests_total", "Requests by path.\nSplit on \\ and newlines.", "path", "status")
+	c.Inc("/b", "200")
+	c.Add(2.5, "/a", "500")
+	c.Inc("/a", "500")
+
+	want := `# HELP test_requests_total Requests by path.\nSplit on \\ and newlines.
+# TYPE test_requests_total counter
+test_requests_total{path="/a",status="500"} 3.5
+test_requests_total{path="/b",status="200"} 1
+`
+	if got := render(t, c); got != want {
+		t.Errorf("exposition =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLabelValueEscaping(t *testing.T) {
+	g := NewGaugeVec("test_sheet_rows", `Rows by "sheet".`, "sheet")
+	g.Set(7, "C:\\Matrículas \"2024\"\nEAD")
+
+	want := `# HELP test_sheet_rows Rows by "sheet".
+# TYPE test_sheet_rows gauge
+test_sheet_rows{sheet="C:\\Matrículas \"2024\"\nEAD"} 7
+`
+	if got := render(t, g); got != want {
+		t.Errorf("exposition =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestInfiniteValues(t *testing.T) {
+	g := NewGaugeVec("test_headroom", "Infinite values.")
+	g.Set(math.Inf(1))
+	if got, want := render(t, g), "test_headroom +Inf\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("exposition =\n%s\nwant a line %q", got, want)
+	}
+	g.Set(math.Inf(-1))
+	if got, want := render(t, g), "test_headroom -Inf\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("exposition =\n%s\nwant a line %q", got, want)
+	}
+}
+
+func TestHistogramExposition(t *testing.T) {
+	h := NewHistogramVec("test_call_duration_seconds", "Call latency.", []float64{0.1, 1, 5}, "target")
+	for _, v := range []float64{0.05, 0.1, 0.7, 3, 12} {
+		h.Observe(v, `sheet "EAD"`)
+	}
+	h.Observe(0.2, "jacad")
+
+	want := `# HELP test_call_duration_seconds Call latency.
+# TYPE test_call_duration_seconds histogram
+test_call_duration_seconds_bucket{target="jacad",le="0.1"} 0
+test_call_duration_seconds_bucket{target="jacad",le="1"} 1
+test_call_duration_seconds_bucket{target="jacad",le="5"} 1
+test_call_duration_seconds_bucket{target="jacad",le="+Inf"} 1
+test_call_duration_seconds_sum{target="jacad"} 0.2
+test_call_duration_seconds_count{target="jacad"} 1
+test_call_duration_seconds_bucket{target="sheet \"EAD\"",le="0.1"} 2
+test_call_duration_seconds_bucket{target="sheet \"EAD\"",le="1"} 3
+test_call_duration_seconds_bucket{target="sheet \"EAD\"",le="5"} 4
+test_call_duration_seconds_bucket{target="sheet \"EAD\"",le="+Inf"} 5
+test_call_duration_seconds_sum{target="sheet \"EAD\""} 15.85
+test_call_duration_seconds_count{target="sheet \"EAD\""} 5
+`
+	if got := render(t, h); got != want {
+		t.Errorf("exposition =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnlabelledHistogramExposition(t *testing.T) {
+	h := NewHistogramVec("test_job_duration_seconds", "Job duration.", []float64{1})
+	h.Observe(2)
+
+	want := `# HELP test_job_duration_seconds Job duration.
+# TYPE test_job_duration_seconds histogram
+test_job_duration_seconds_bucket{le="1"} 0
+test_job_duration_seconds_bucket{le="+Inf"} 1
+test_job_duration_seconds_sum 2
+test_job_duration_seconds_count 1
+`
+	if got := render(t, h); got != want {
+		t.Errorf("exposition =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRegistryWritesEveryCollector(t *testing.T) {
+	r := &Registry{}
+	c := &CounterVec{vec: newVec("test_a_total", "A.", nil)}
+	g := &GaugeVec{vec: newVec("test_b", "B.", nil)}
+	r.register(c)
+	r.register(g)
+	c.Inc()
+	g.Set(2)
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP test_a_total A.\n# TYPE test_a_total counter\ntest_a_total 1\n# HELP test_b B.\n# TYPE test_b gauge\ntest_b 2\n"
+	if b.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
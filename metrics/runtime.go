@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+var (
+	goroutines       = NewGaugeVec("go_goroutines", "Number of goroutines that currently exist.")
+	heapAllocBytes   = NewGaugeVec("go_memstats_heap_alloc_bytes", "Bytes of allocated heap objects, reachable or not yet freed.")
+	heapInuseBytes   = NewGaugeVec("go_memstats_heap_inuse_bytes", "Bytes in in-use heap spans.")
+	heapObjects      = NewGaugeVec("go_memstats_heap_objects", "Number of allocated heap objects.")
+	sysBytes         = NewGaugeVec("go_memstats_sys_bytes", "Bytes of memory obtained from the OS.")
+	nextGCBytes      = NewGaugeVec("go_memstats_next_gc_bytes", "Heap size the next garbage collection cycle targets.")
+	gcCycles         = NewGaugeVec("go_memstats_gc_cycles", "Garbage collection cycles completed since the process started.")
+	gcPauseSeconds   = NewGaugeVec("go_memstats_gc_pause_seconds", "Stop-the-world pause time of all garbage collections since the process started.")
+	runtimeSampledAt = NewGaugeVec("go_runtime_stats_sampled_timestamp_seconds", "Unix time the runtime stats were last sampled.")
+)
+
+// RecordRuntimeStats samples the heap and goroutine gauges.
+func RecordRuntimeStats() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	goroutines.Set(float64(runtime.NumGoroutine()))
+	heapAllocBytes.Set(float64(m.HeapAlloc))
+	heapInuseBytes.Set(float64(m.HeapInuse))
+	heapObjects.Set(float64(m.HeapObjects))
+	sysBytes.Set(float64(m.Sys))
+	nextGCBytes.Set(float64(m.NextGC))
+	gcCycles.Set(float64(m.NumGC))
+	gcPauseSeconds.Set(time.Duration(m.PauseTotalNs).Seconds())
+	runtimeSampledAt.Set(float64(time.Now().Unix()))
+}
+
+// RunRuntimeStats samples the runtime gauges every interval until ctx is
+// done. ReadMemStats briefly stops the world, so the gauges are sampled on a
+// timer rather than on every scrape.
+func RunRuntimeStats(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	RecordRuntimeStats()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			RecordRuntimeStats()
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// Jacad API versions. v1 listings take currentPage (from 0) and pageSize and
+// describe the page under "page"; v2 listings take page (from 1) and size
+// and describe it under "meta".
+const (
+	APIV1 = "v1"
+	APIV2 = "v2"
+)
+
+// PageV2 is the pagination block of a v2 listing.
+type PageV2 struct {
+	Page       int `json:"page"`
+	Size       int `json:"size"`
+	TotalItems int `json:"totalItems"`
+	TotalPages int `json:"totalPages"`
+}
+
+// APIResponseV2 is a v2 listing.
+type APIResponseV2[T any] struct {
+	Meta *PageV2 `json:"meta"`
+	Data []T     `json:"data"`
+}
+
+// ErrNotV2 is returned by DecodePage for a v2 body without a "meta" block.
+var ErrNotV2 = errors.New("response is not a v2 listing")
+
+// PageQuery returns the query parameters requesting page, counted from 0
+// whatever the version, of pageSize elements.
+func PageQuery(version string, page, pageSize int) map[string]string {
+	if version == APIV2 {
+		return map[string]string{"page": strconv.Itoa(page + 1), "size": strconv.Itoa(pageSize)}
+	}
+	return map[string]string{"currentPage": strconv.Itoa(page), "pageSize": strconv.Itoa(pageSize)}
+}
+
+// DecodePage parses a listing of version into its elements and a v1 Page,
+// counted from 0, so callers never see which version answered.
+func DecodePage[T any](version string, body []byte) ([]T, *Page, error) {
+	if version != APIV2 {
+		var resp APIResponse[T]
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, nil, err
+		}
+		return resp.Elements, resp.Page, nil
+	}
+
+	var resp APIResponseV2[T]
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, nil, err
+	}
+	if resp.Meta == nil {
+		return nil, nil, ErrNotV2
+	}
+	return resp.Data, &Page{
+		CurrentPage:   resp.Meta.Page - 1,
+		PageSize:      resp.Meta.Size,
+		TotalElements: resp.Meta.TotalItems,
+		TotalPages:    resp.Meta.TotalPages,
+	}, nil
+}
@@ -0,0 +1,19 @@
+package models
+
+import "github.com/SamuelLeutner/fetch-student-data/utils"
+
+// Candidate is one inscription in a selective process notice (edital).
+type Candidate struct {
+	IdInscricao   int         `json:"idInscricao"`
+	IdEdital      int         `json:"idEdital"`
+	IdCandidato   int         `json:"idCandidato"`
+	Nome          *string     `json:"nome"`
+	CPF           *string     `json:"cpf"`
+	Email         *string     `json:"email"`
+	Telefone      *string     `json:"telefone"`
+	Curso         *string     `json:"curso"`
+	Status        *string     `json:"status"`
+	Classificacao *int        `json:"classificacao"`
+	DataInscricao *utils.Date `json:"dataInscricao"`
+	OrgID         int         `json:"idOrg"`
+}
@@ -0,0 +1,13 @@
+package models
+
+type Course struct {
+	IdCurso     int     `json:"idCurso"`
+	Codigo      *string `json:"codigo"`
+	Nome        *string `json:"nome"`
+	Modalidade  *string `json:"modalidade"`
+	NivelEnsino *string `json:"nivelEnsino"`
+	Turno       *string `json:"turno"`
+	OrgID       int     `json:"idOrg"`
+	Organizacao *string `json:"organizacao"`
+	Ativo       *bool   `json:"ativo"`
+}
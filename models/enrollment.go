@@ -16,4 +16,21 @@ type Enrollment struct {
 	DataMatricula *utils.Date `json:"dataMatricula"`
 	DataAtivacao  *utils.Date `json:"dataAtivacao"`
 	DataCadastro  *utils.Date `json:"dataCadastro"`
+
+	// The rest of the payload is left out of the default column mapping;
+	// add the fields to COLUMNS or the fields parameter to write them. cpf,
+	// email and telefone identify students and are usually worth a
+	// redaction policy.
+	IdAluno         int         `json:"idAluno"`
+	IdCurso         int         `json:"idCurso"`
+	IdTurma         int         `json:"idTurma"`
+	IdPeriodoLetivo int         `json:"idPeriodoLetivo"`
+	Modalidade      *string     `json:"modalidade"`
+	Polo            *string     `json:"polo"`
+	FormaIngresso   *string     `json:"formaIngresso"`
+	Turno           *string     `json:"turno"`
+	CPF             *string     `json:"cpf"`
+	Email           *string     `json:"email"`
+	Telefone        *string     `json:"telefone"`
+	DataNascimento  *utils.Date `json:"dataNascimento"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import "github.com/SamuelLeutner/fetch-student-data/utils"
+
+// Frequencia is one attendance record: a student's presence in one class
+// meeting.
+type Frequencia struct {
+	IdFrequencia int         `json:"idFrequencia"`
+	IdTurma      int         `json:"idTurma"`
+	Turma        *string     `json:"turma"`
+	IdMatricula  int         `json:"idMatricula"`
+	IdAluno      int         `json:"idAluno"`
+	Aluno        *string     `json:"aluno"`
+	RA           *string     `json:"ra"`
+	Data         *utils.Date `json:"data"`
+	Presente     *bool       `json:"presente"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type Organization struct {
+	IdOrg int     `json:"idOrg"`
+	Nome  *string `json:"nome"`
+}
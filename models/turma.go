@@ -0,0 +1,14 @@
+package models
+
+type Turma struct {
+	IdTurma         int     `json:"idTurma"`
+	Descricao       *string `json:"descricao"`
+	IdCurso         int     `json:"idCurso"`
+	Curso           *string `json:"curso"`
+	IdPeriodoLetivo int     `json:"idPeriodoLetivo"`
+	PeriodoLetivo   *string `json:"periodoLetivo"`
+	Turno           *string `json:"turno"`
+	Vagas           *int    `json:"vagas"`
+	OrgID           int     `json:"idOrg"`
+	Organizacao     *string `json:"organizacao"`
+}
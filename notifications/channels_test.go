@@ -0,0 +1,144 @@
+package notifications
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifiers(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request = %s %s, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		got = nil
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		notifier Notifier
+		want     map[string]string
+	}{
+		{notifier: NewSlackNotifier(server.URL), want: map[string]string{"text": "*job done*\nrows: 3"}},
+		{notifier: &TelegramNotifier{apiURL: server.URL, chatID: "-100"}, want: map[string]string{"chat_id": "-100", "text": "job done\n\nrows: 3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.notifier.Name(), func(t *testing.T) {
+			if err := tt.notifier.Notify(context.Background(), "job done", "rows: 3"); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("payload = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostJSONHidesEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	secret := server.URL + "/services/T000/B000/secret"
+	err := NewSlackNotifier(secret).Notify(context.Background(), "subject", "body")
+	if err == nil || !strings.Contains(err.Error(), "status 403: invalid_token") {
+		t.Fatalf("Notify() = %v, want the 403", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("Notify() = %v, leaks the webhook URL", err)
+	}
+
+	server.Close()
+	if err := NewSlackNotifier(secret).Notify(context.Background(), "subject", "body"); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("Notify() to a closed server = %v, want an error without the webhook URL", err)
+	}
+}
+
+// fakeSMTPServer accepts one SMTP session without STARTTLS or AUTH and
+// returns the envelope and message it received.
+func fakeSMTPServer(t *testing.T) (port int, received <-chan []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	lines := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		var session []string
+		reply("220 localhost ESMTP")
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			session = append(session, line)
+			switch {
+			case inData && line == ".":
+				inData = false
+				reply("250 queued")
+			case inData:
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case line == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case line == "QUIT":
+				reply("221 bye")
+				lines <- session
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+		lines <- session
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, lines
+}
+
+func TestEmailNotifier(t *testing.T) {
+	port, received := fakeSMTPServer(t)
+	n := NewEmailNotifier("127.0.0.1", port, "", "", "jobs@example.com", []string{"ops@example.com", "dev@example.com"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := n.Notify(ctx, "Matrículas atualizadas", "rows: 3\nsheets: 1"); err != nil {
+		t.Fatal(err)
+	}
+	session := strings.Join(<-received, "\n")
+
+	for _, want := range []string{
+		"MAIL FROM:<jobs@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"RCPT TO:<dev@example.com>",
+		"To: ops@example.com, dev@example.com",
+		"Subject: =?utf-8?q?Matr=C3=ADculas_atualizadas?=",
+		"Content-Type: text/plain; charset=utf-8",
+		"rows: 3\nsheets: 1",
+	} {
+		if !strings.Contains(session, want) {
+			t.Errorf("SMTP session lacks %q:\n%s", want, session)
+		}
+	}
+}
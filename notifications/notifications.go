@@ -0,0 +1,171 @@
+// Package notifications sends a summary of each finished job to the
+// configured channels (email, Slack, Telegram).
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/metrics"
+)
+
+const (
+	StatusSuccess   = "success"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+
+	// NotifyAlways sends a summary after every job; NotifyFailure only after
+	// failed ones.
+	NotifyAlways  = "always"
+	NotifyFailure = "failure"
+)
+
+// DefaultSubjectTemplate and DefaultBodyTemplate render a Summary.
+const (
+	DefaultSubjectTemplate = `[fetch-student-data] {{.Job}} {{.Status}}`
+	DefaultBodyTemplate    = `{{.Job}} {{.Status}} in {{.Duration}}
+Rows written: {{.RowsWritten}} (fetched {{.RowsFetched}})
+{{- if .RowsRejected}}
+Rows rejected by data quality rules: {{.RowsRejected}}{{if .RejectsSheet}} (see tab {{.RejectsSheet}}){{end}}{{end}}
+{{- if .Tenant}}
+Tenant: {{.Tenant}}{{end}}
+{{- if .JobID}}
+Job ID: {{.JobID}}{{end}}
+{{- if .BytesReceived}}
+Jacad bytes received: {{.BytesReceived}} (saved {{.BytesSaved}}){{end}}
+{{- if .Error}}
+Error: {{.Error}}{{end}}
+{{- if .Failures}}
+Failures:{{range .Failures}}
+- {{.}}{{end}}{{end}}`
+)
+
+// sendTimeout bounds how long one channel may take to deliver a message.
+const sendTimeout = 15 * time.Second
+
+var notificationsSentTotal = metrics.NewCounterVec(
+	"notifications_sent_total",
+	"Job summary notifications by channel and result (ok, error).",
+	"channel", "result",
+)
+
+// Summary describes a finished job. It is the data passed to the templates.
+type Summary struct {
+	Job         string
+	JobID       string
+	Tenant      string
+	Status      string
+	RowsWritten int
+	RowsFetched int
+	// RowsRejected counts rows left out for failing a data quality rule;
+	// RejectsSheet is the tab they were written to.
+	RowsRejected int
+	RejectsSheet string
+	// Failures lists partial failures, e.g. sheets that could not be written.
+	Failures  []string
+	Error     string
+	Duration  time.Duration
+	StartedAt time.Time
+	// BytesReceived is the size of the Jacad responses the job downloaded;
+	// BytesSaved is what compression and conditional requests spared it.
+	BytesReceived int64
+	BytesSaved    int64
+}
+
+// Failed reports whether the job failed or had partial failures.
+func (s Summary) Failed() bool {
+	return s.Status == StatusFailed || len(s.Failures) > 0
+}
+
+// Notifier delivers a rendered message to one channel.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, subject, body string) error
+}
+
+// Dispatcher renders summaries and fans them out to its notifiers in the
+// background. A nil *Dispatcher sends nothing.
+type Dispatcher struct {
+	notifiers    []Notifier
+	subject      *template.Template
+	body         *template.Template
+	onlyFailures bool
+	wg           sync.WaitGroup
+}
+
+// NewDispatcher parses the templates and returns nil when notifiers is empty.
+func NewDispatcher(notifiers []Notifier, subjectTemplate, bodyTemplate, notifyOn string) (*Dispatcher, error) {
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+	subject, err := template.New("subject").Parse(subjectTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification subject template: %w", err)
+	}
+	body, err := template.New("body").Parse(bodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification body template: %w", err)
+	}
+	return &Dispatcher{
+		notifiers:    notifiers,
+		subject:      subject,
+		body:         body,
+		onlyFailures: notifyOn == NotifyFailure,
+	}, nil
+}
+
+// Send delivers summary to every channel without blocking the caller; ctx
+// only supplies the logger, so a cancelled job still notifies. Use Wait
+// before exiting to let pending messages go out.
+func (d *Dispatcher) Send(ctx context.Context, summary Summary) {
+	if d == nil || (d.onlyFailures && !summary.Failed()) {
+		return
+	}
+	logger := logging.FromContext(ctx).With("job", summary.Job)
+
+	subject, body, err := d.render(summary)
+	if err != nil {
+		logger.Error("Failed to render job notification", "error", err)
+		return
+	}
+
+	for _, notifier := range d.notifiers {
+		d.wg.Add(1)
+		go func(notifier Notifier) {
+			defer d.wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := notifier.Notify(ctx, subject, body); err != nil {
+				logger.Warn("Failed to send job notification", "channel", notifier.Name(), "error", err)
+				notificationsSentTotal.Inc(notifier.Name(), "error")
+				return
+			}
+			notificationsSentTotal.Inc(notifier.Name(), "ok")
+		}(notifier)
+	}
+}
+
+// Wait blocks until every message handed to Send has been delivered or has
+// failed.
+func (d *Dispatcher) Wait() {
+	if d == nil {
+		return
+	}
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) render(summary Summary) (string, string, error) {
+	var subject, body bytes.Buffer
+	if err := d.subject.Execute(&subject, summary); err != nil {
+		return "", "", fmt.Errorf("subject: %w", err)
+	}
+	if err := d.body.Execute(&body, summary); err != nil {
+		return "", "", fmt.Errorf("body: %w", err)
+	}
+	return subject.String(), body.String(), nil
+}
@@ -0,0 +1,126 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	mu       sync.Mutex
+	messages []string
+	err      error
+}
+
+func (n *recordingNotifier) Name() string { return "recording" }
+
+func (n *recordingNotifier) Notify(ctx context.Context, subject, body string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.messages = append(n.messages, subject+"\n"+body)
+	return n.err
+}
+
+func (n *recordingNotifier) Messages() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.messages...)
+}
+
+func TestDispatcherRendersDefaultTemplates(t *testing.T) {
+	notifier := &recordingNotifier{}
+	d, err := NewDispatcher([]Notifier{notifier}, DefaultSubjectTemplate, DefaultBodyTemplate, NotifyAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.Send(context.Background(), Summary{
+		Job:          "fetch-enrollments",
+		JobID:        "job-1",
+		Tenant:       "colegio",
+		Status:       StatusFailed,
+		RowsWritten:  40,
+		RowsFetched:  60,
+		RowsRejected: 2,
+		RejectsSheet: "Rejeitados",
+		Failures:     []string{"EAD: quota exceeded"},
+		Error:        "failed to write enrollments to 1 sheet(s)",
+		Duration:     90 * time.Second,
+	})
+	d.Wait()
+
+	want := `[fetch-student-data] fetch-enrollments failed
+fetch-enrollments failed in 1m30s
+Rows written: 40 (fetched 60)
+Rows rejected by data quality rules: 2 (see tab Rejeitados)
+Tenant: colegio
+Job ID: job-1
+Error: failed to write enrollments to 1 sheet(s)
+Failures:
+- EAD: quota exceeded`
+	if got := notifier.Messages(); len(got) != 1 || got[0] != want {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+}
+
+func TestDispatcherNotifyOn(t *testing.T) {
+	tests := []struct {
+		name     string
+		notifyOn string
+		summary  Summary
+		want     int
+	}{
+		{name: "always sends successes", notifyOn: NotifyAlways, summary: Summary{Status: StatusSuccess}, want: 1},
+		{name: "failure skips successes", notifyOn: NotifyFailure, summary: Summary{Status: StatusSuccess}, want: 0},
+		{name: "failure sends failed jobs", notifyOn: NotifyFailure, summary: Summary{Status: StatusFailed}, want: 1},
+		{name: "failure sends partial failures", notifyOn: NotifyFailure, summary: Summary{Status: StatusSuccess, Failures: []string{"EAD: quota"}}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &recordingNotifier{}
+			d, err := NewDispatcher([]Notifier{notifier}, "{{.Status}}", "{{.Job}}", tt.notifyOn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.Send(context.Background(), tt.summary)
+			d.Wait()
+			if got := len(notifier.Messages()); got != tt.want {
+				t.Errorf("sent %d messages, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatcherKeepsSendingAfterAChannelFails(t *testing.T) {
+	failing := &recordingNotifier{err: errors.New("webhook gone")}
+	working := &recordingNotifier{}
+	d, err := NewDispatcher([]Notifier{failing, working}, "{{.Status}}", "{{.Job}}", NotifyAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Send(context.Background(), Summary{Job: "fetch-courses", Status: StatusSuccess})
+	d.Wait()
+	if got := working.Messages(); len(got) != 1 || got[0] != "success\nfetch-courses" {
+		t.Errorf("working channel got %q, want the summary", got)
+	}
+}
+
+func TestNewDispatcher(t *testing.T) {
+	if d, err := NewDispatcher(nil, DefaultSubjectTemplate, DefaultBodyTemplate, NotifyAlways); d != nil || err != nil {
+		t.Errorf("NewDispatcher() without notifiers = %v, %v; want nil, nil", d, err)
+	}
+	var d *Dispatcher
+	d.Send(context.Background(), Summary{Status: StatusFailed})
+	d.Wait()
+
+	notifiers := []Notifier{&recordingNotifier{}}
+	if _, err := NewDispatcher(notifiers, "{{.Job", DefaultBodyTemplate, NotifyAlways); err == nil || !strings.Contains(err.Error(), "subject") {
+		t.Errorf("NewDispatcher() with a broken subject = %v, want a subject template error", err)
+	}
+	if _, err := NewDispatcher(notifiers, DefaultSubjectTemplate, "{{if}}", NotifyAlways); err == nil || !strings.Contains(err.Error(), "body") {
+		t.Errorf("NewDispatcher() with a broken body = %v, want a body template error", err)
+	}
+}
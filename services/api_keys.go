@@ -0,0 +1,54 @@
+package services
+
+import (
+	"crypto/subtle"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+// APIKeyRegistry holds the configured API keys and their per-key rate
+// limiters. The HTTP and gRPC APIs share one registry so a key's
+// RequestsPerMinute budget covers calls on both.
+type APIKeyRegistry struct {
+	keys []*RegisteredAPIKey
+}
+
+// RegisteredAPIKey is a configured API key with its rate limiter.
+type RegisteredAPIKey struct {
+	config.APIKey
+	limiter *RateLimiter
+}
+
+func NewAPIKeyRegistry(keys []config.APIKey) *APIKeyRegistry {
+	registry := &APIKeyRegistry{keys: make([]*RegisteredAPIKey, len(keys))}
+	for i, key := range keys {
+		registry.keys[i] = &RegisteredAPIKey{APIKey: key, limiter: NewPerMinuteLimiter(key.RequestsPerMinute)}
+	}
+	return registry
+}
+
+// Enabled reports whether any key is configured, i.e. whether callers must
+// authenticate.
+func (r *APIKeyRegistry) Enabled() bool {
+	return r != nil && len(r.keys) > 0
+}
+
+// Find returns the key matching provided, or nil. Every key is compared in
+// constant time so the lookup does not leak which prefix matched.
+func (r *APIKeyRegistry) Find(provided string) *RegisteredAPIKey {
+	if r == nil {
+		return nil
+	}
+	var match *RegisteredAPIKey
+	for _, key := range r.keys {
+		if subtle.ConstantTimeCompare([]byte(key.Key), []byte(provided)) == 1 {
+			match = key
+		}
+	}
+	return match
+}
+
+// Allow takes one request from the key's per-minute budget.
+func (k *RegisteredAPIKey) Allow() bool {
+	return k.limiter.Allow()
+}
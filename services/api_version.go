@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// apiVersionRetry is how long an instance whose v2 probe failed for a reason
+// other than a 404 is called with v1 before it is probed again.
+const apiVersionRetry = 5 * time.Minute
+
+// apiVersions remembers the API version detected for each tenant when
+// JACAD_API_VERSION=auto. The zero value is ready to use.
+type apiVersions struct {
+	mu      sync.Mutex
+	tenants map[string]*detectedVersion
+}
+
+// detectedVersion is the API version of one tenant; mu is held while it is
+// probed, so concurrent fetches wait for one probe instead of each sending
+// their own.
+type detectedVersion struct {
+	mu      sync.Mutex
+	version string
+	// retryAt is when to probe again; zero once the version is settled.
+	retryAt time.Time
+}
+
+func (v *apiVersions) tenant(name string) *detectedVersion {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.tenants == nil {
+		v.tenants = make(map[string]*detectedVersion)
+	}
+	d, ok := v.tenants[name]
+	if !ok {
+		d = &detectedVersion{}
+		v.tenants[name] = d
+	}
+	return d
+}
+
+// apiVersion returns the Jacad API version, models.APIV1 or models.APIV2,
+// to call the tenant in ctx with.
+func (c *JacadClient) apiVersion(ctx context.Context) string {
+	switch c.Config.JacadAPIVersion {
+	case config.JacadAPIv2:
+		return models.APIV2
+	case config.JacadAPIAuto:
+	default:
+		return models.APIV1
+	}
+
+	d := c.versions.tenant(tenantFrom(ctx))
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.version == "" || !d.retryAt.IsZero() && time.Now().After(d.retryAt) {
+		d.version, d.retryAt = c.detectAPIVersion(ctx)
+	}
+	return d.version
+}
+
+// detectAPIVersion asks the v2 enrollments endpoint for a single element. A
+// v2 listing means the instance speaks v2; a 404 or any other body means it
+// only speaks v1. Other failures fall back to v1 until apiVersionRetry has
+// passed.
+func (c *JacadClient) detectAPIVersion(ctx context.Context) (string, time.Time) {
+	logger := logging.FromContext(ctx).With("tenant", tenantFrom(ctx))
+	retry := func(err error) (string, time.Time) {
+		logger.Warn("Could not detect the Jacad API version. Using v1 for now.", "retryIn", apiVersionRetry.String(), "error", err)
+		return models.APIV1, time.Now().Add(apiVersionRetry)
+	}
+
+	profile, err := c.profile(ctx)
+	if err != nil {
+		return retry(err)
+	}
+	token, err := c.GetAuthToken(ctx)
+	if err != nil {
+		return retry(err)
+	}
+	q := url.Values{}
+	for k, v := range models.PageQuery(models.APIV2, 0, 1) {
+		q.Set(k, v)
+	}
+	probe := profile.APIBase + c.versionedPath(models.APIV2, c.Config.Endpoints["ENROLLMENTS"]) + "?" + q.Encode()
+	headers := map[string]string{
+		"Authorization": "Bearer " + token,
+		"Content-Type":  "application/json",
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodGet, probe, headers, nil)
+	if errors.Is(err, ErrNotFound) {
+		logger.Info("Jacad has no v2 API. Using v1.")
+		return models.APIV1, time.Time{}
+	}
+	if err != nil {
+		return retry(err)
+	}
+	if _, _, err := models.DecodePage[json.RawMessage](models.APIV2, resp.Body); err != nil {
+		logger.Info("Jacad answered the v2 probe with something else than a v2 listing. Using v1.", "error", err)
+		return models.APIV1, time.Time{}
+	}
+	logger.Info("Jacad speaks the v2 API. Using v2.")
+	return models.APIV2, time.Time{}
+}
+
+// versionedPath returns where endpoint, a v1 path from Config.Endpoints,
+// lives in version.
+func (c *JacadClient) versionedPath(version, endpoint string) string {
+	if version == models.APIV2 {
+		return c.Config.JacadV2PathPrefix + endpoint
+	}
+	return endpoint
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+func TestFetchEnrollmentsAcrossAPIVersions(t *testing.T) {
+	tests := []struct {
+		name       string
+		served     string
+		configured string
+		want       string
+	}{
+		{name: "v1", served: models.APIV1, configured: config.JacadAPIv1, want: models.APIV1},
+		{name: "v2", served: models.APIV2, configured: config.JacadAPIv2, want: models.APIV2},
+		{name: "auto on v1", served: models.APIV1, configured: config.JacadAPIAuto, want: models.APIV1},
+		{name: "auto on v2", served: models.APIV2, configured: config.JacadAPIAuto, want: models.APIV2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(72), APIVersion: tt.served})
+			defer server.Close()
+
+			dir := t.TempDir()
+			cfg := config.Defaults()
+			cfg.APIBase = server.URL
+			cfg.UserToken = server.UserToken
+			cfg.JacadAPIVersion = tt.configured
+			cfg.PageSize = 5
+			cfg.RetryDelay = 0
+			writer := NewDiscardWriter()
+			client := NewJacadClient(&cfg, writer, NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+
+			result, err := client.FetchEnrollmentsFiltered(context.Background(), &requests.FetchEnrollmentsRequest{
+				OrgIds:          "20,17",
+				IdPeriodoLetivo: 87,
+				StatusMatricula: "ATIVA",
+				Mode:            requests.SyncModeFull,
+				WriteMode:       requests.WriteModeAtomic,
+				GroupBy:         requests.GroupByNone,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 72 enrollments: a quarter are period 87 with status ATIVA,
+			// over several pages of 5.
+			if result.TotalFetched != 18 {
+				t.Errorf("fetched %d enrollments, want 18", result.TotalFetched)
+			}
+			if got := client.apiVersion(context.Background()); got != tt.want {
+				t.Errorf("apiVersion = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointLabelStripsV2Prefix(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.APIBase = "https://jacad.example"
+	client := &JacadClient{Config: &cfg}
+
+	tests := map[string]string{
+		"https://jacad.example/academico/matriculas?page=1":    "/academico/matriculas",
+		"https://jacad.example/v2/academico/matriculas?page=1": "/academico/matriculas",
+		"https://jacad.example/v2":                             "/v2",
+		"https://jacad.example":                                "/",
+	}
+	for url, want := range tests {
+		if got := client.endpointLabel(url); got != want {
+			t.Errorf("endpointLabel(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"google.golang.org/api/storage/v1"
+)
+
+// localJobID names archives written by calls without a job ID, such as
+// the CLI, so runs of the same process share a prefix.
+var localJobID = "local-" + time.Now().UTC().Format("20060102T150405")
+
+// PageArchiver stores raw Jacad page bodies, gzip-compressed, under key.
+type PageArchiver interface {
+	Archive(ctx context.Context, key string, gzipped []byte) error
+}
+
+// archivePath expands template for one page. Supported placeholders are
+// {job}, {endpoint}, {page} and {date}.
+func archivePath(template, jobID, endpoint string, page int, now time.Time) string {
+	endpointName := strings.ReplaceAll(strings.Trim(endpoint, "/"), "/", "-")
+	return strings.NewReplacer(
+		"{job}", jobID,
+		"{endpoint}", endpointName,
+		"{page}", strconv.Itoa(page),
+		"{date}", now.UTC().Format("2006-01-02"),
+	).Replace(template)
+}
+
+// archivePage stores body through c.Archiver. Archival is best effort: errors
+// are logged and counted but never fail the fetch.
+func (c *JacadClient) archivePage(ctx context.Context, endpoint string, page int, body []byte) {
+	if c.Archiver == nil {
+		return
+	}
+	jobID := logging.JobID(ctx)
+	if jobID == "" {
+		jobID = localJobID
+	}
+	key := archivePath(c.Config.ArchivePathTemplate, jobID, endpoint, page, time.Now())
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(body)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = c.Archiver.Archive(ctx, key, buf.Bytes())
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to archive Jacad page", "key", key, "page", page, "error", err)
+		jacadPagesArchivedTotal.Inc("error")
+		return
+	}
+	jacadPagesArchivedTotal.Inc("ok")
+}
+
+// GCSArchiver writes archives to a Google Cloud Storage bucket.
+type GCSArchiver struct {
+	service *storage.Service
+	bucket  string
+}
+
+func NewGCSArchiver(ctx context.Context, bucket string, creds GoogleCredentials) (*GCSArchiver, error) {
+	opts, credSourceDescription, err := googleClientOptions(ctx, creds, storage.DevstorageReadWriteScope)
+	if err != nil {
+		return nil, err
+	}
+	service, err := storage.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloud Storage client (source: %s): %w", credSourceDescription, err)
+	}
+	return &GCSArchiver{service: service, bucket: bucket}, nil
+}
+
+func (a *GCSArchiver) Archive(ctx context.Context, key string, gzipped []byte) error {
+	return a.upload(ctx, key, "application/json", "gzip", gzipped)
+}
+
+// Put uploads body as-is under key.
+func (a *GCSArchiver) Put(ctx context.Context, key, contentType string, body []byte) error {
+	return a.upload(ctx, key, contentType, "", body)
+}
+
+func (a *GCSArchiver) upload(ctx context.Context, key, contentType, contentEncoding string, body []byte) error {
+	object := &storage.Object{
+		Name:            key,
+		ContentType:     contentType,
+		ContentEncoding: contentEncoding,
+	}
+	_, err := a.service.Objects.Insert(a.bucket, object).
+		Media(bytes.NewReader(body)).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return fmt.Errorf("failed to upload gs://%s/%s: %w", a.bucket, key, err)
+	}
+	return nil
+}
+
+// S3Archiver writes archives to an S3 (or S3-compatible) bucket with SigV4
+// signed PUT requests. An empty endpoint means AWS itself.
+type S3Archiver struct {
+	client          *http.Client
+	bucket          string
+	region          string
+	endpoint        string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+func NewS3Archiver(bucket, region, endpoint, accessKeyID, secretAccessKey, sessionToken string) (*S3Archiver, error) {
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, fmt.Errorf("S3 archiver requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	return &S3Archiver{
+		client:          &http.Client{Timeout: 60 * time.Second},
+		bucket:          bucket,
+		region:          region,
+		endpoint:        strings.TrimRight(endpoint, "/"),
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		sessionToken:    sessionToken,
+	}, nil
+}
+
+func (a *S3Archiver) Archive(ctx context.Context, key string, gzipped []byte) error {
+	return a.upload(ctx, key, "application/json", "gzip", gzipped)
+}
+
+// Put uploads body as-is under key.
+func (a *S3Archiver) Put(ctx context.Context, key, contentType string, body []byte) error {
+	return a.upload(ctx, key, contentType, "", body)
+}
+
+func (a *S3Archiver) upload(ctx context.Context, key, contentType, contentEncoding string, body []byte) error {
+	objectURL := fmt.Sprintf("%s/%s/%s", a.endpoint, a.bucket, s3EscapePath(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build S3 request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	a.sign(req, body, time.Now().UTC())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload s3://%s/%s: %w", a.bucket, key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to upload s3://%s/%s: status %d: %s", a.bucket, key, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// sign adds AWS Signature Version 4 headers to req. Every header already set
+// on req is signed, along with host.
+func (a *S3Archiver) sign(req *http.Request, payload []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if a.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.sessionToken)
+	}
+
+	signedHeaders := []string{"host"}
+	for name := range req.Header {
+		if name = strings.ToLower(name); name != "authorization" && name != "host" {
+			signedHeaders = append(signedHeaders, name)
+		}
+	}
+	sort.Strings(signedHeaders)
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := strings.Join(req.Header.Values(name), ",")
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.Join(strings.Fields(value), " ") + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + a.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+a.secretAccessKey), day)
+	key = hmacSHA256(key, a.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		a.accessKeyID, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+// s3EscapePath encodes each segment of key the way SigV4 canonical URIs
+// require: every byte except the unreserved characters A-Z, a-z, 0-9, '-',
+// '.', '_' and '~' becomes %XX. Slashes are kept.
+func s3EscapePath(key string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0xF])
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
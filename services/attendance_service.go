@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+// attendanceLeadingHeaders and attendanceTrailingHeaders surround one column
+// per class meeting date in the attendance sheet.
+var (
+	attendanceLeadingHeaders  = []string{"idAluno", "aluno", "ra"}
+	attendanceTrailingHeaders = []string{"presencas", "faltas", "frequencia"}
+)
+
+// AttendanceResult reports an attendance sheet write.
+type AttendanceResult struct {
+	SheetResult
+	// Dates lists the class meetings written as columns, in order.
+	Dates []string `json:"dates"`
+}
+
+// AttendanceService writes the attendance of a class with one row per
+// student and one column per class meeting.
+type AttendanceService struct {
+	client *JacadClient
+}
+
+func NewAttendanceService(client *JacadClient) *AttendanceService {
+	return &AttendanceService{client: client}
+}
+
+// FetchAttendance fetches the class's attendance records and overwrites its
+// attendance sheet.
+func (s *AttendanceService) FetchAttendance(ctx context.Context, params *requests.FetchAttendanceRequest) (*AttendanceResult, error) {
+	startedAt := time.Now()
+	result, err := s.fetchAttendance(ctx, params)
+	var sheet *SheetResult
+	if result != nil {
+		sheet = &result.SheetResult
+	}
+	s.client.finishJob(ctx, sheetSummary("fetch-attendance", params.Tenant, sheet, err), params, sheet.writtenSheets(err), nil, params.DryRun, startedAt, err)
+	return result, err
+}
+
+func (s *AttendanceService) fetchAttendance(ctx context.Context, params *requests.FetchAttendanceRequest) (*AttendanceResult, error) {
+	c := s.client
+	logger := logging.FromContext(ctx).With("idTurma", params.IdTurma, "idPeriodoLetivo", params.IdPeriodoLetivo)
+	logger.Info("Starting attendance fetch")
+	startTime := time.Now()
+
+	if params.IdTurma == 0 {
+		return nil, fmt.Errorf("idTurma is required")
+	}
+	from, to, err := params.DateRange()
+	if err != nil {
+		return nil, err
+	}
+	if params.BypassCache {
+		ctx = WithCacheBypass(ctx)
+	}
+	ctx = WithTenant(ctx, params.Tenant)
+
+	query := map[string]string{"idTurma": strconv.Itoa(params.IdTurma)}
+	if params.IdPeriodoLetivo != 0 {
+		query["idPeriodoLetivo"] = strconv.Itoa(params.IdPeriodoLetivo)
+	}
+	if params.DataInicio != "" {
+		query["dataInicio"] = params.DataInicio
+	}
+	if params.DataFim != "" {
+		query["dataFim"] = params.DataFim
+	}
+	records, err := fetchAllPagesOf[models.Frequencia](ctx, c, c.Config.Endpoints["ATTENDANCE"], query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch attendance: %w", err)
+	}
+
+	headers, rows, dates := attendanceSheet(records, from, to)
+	result := &AttendanceResult{
+		SheetResult: SheetResult{
+			Sheet:  fmt.Sprintf("%s | Turma %d", c.Config.AttendanceSheet, params.IdTurma),
+			Rows:   len(rows),
+			DryRun: params.DryRun,
+		},
+		Dates: dates,
+	}
+
+	if params.DryRun {
+		limit := params.PreviewRows
+		if limit <= 0 {
+			limit = c.Config.DryRunPreviewRows
+		}
+		result.Preview = previewRows(headers, rows[:min(limit, len(rows))])
+		return result, nil
+	}
+
+	if err := c.Writer.OverwriteSheetData(ctx, result.Sheet, headers, rows); err != nil {
+		return result, fmt.Errorf("failed to write attendance to sheet: %w", err)
+	}
+
+	logger.Info("Attendance written", "sheet", result.Sheet, "students", len(rows), "dates", len(dates), "duration", time.Since(startTime).String())
+	return result, nil
+}
+
+// attendanceStudent accumulates one student's row.
+type attendanceStudent struct {
+	id      int
+	name    string
+	ra      string
+	present map[string]int
+	absent  map[string]int
+}
+
+// attendanceSheet pivots records into one row per student, ordered by name,
+// and one column per meeting date between from and to (to exclusive; zero
+// bounds are open). Records without a date or presence are skipped.
+func attendanceSheet(records []models.Frequencia, from, to time.Time) (headers []string, rows [][]interface{}, dates []string) {
+	students := make(map[int]*attendanceStudent)
+	seenDates := make(map[string]bool)
+	for _, record := range records {
+		if record.Data == nil || record.Presente == nil {
+			continue
+		}
+		day := time.Time(*record.Data)
+		if day.IsZero() || (!from.IsZero() && day.Before(from)) || (!to.IsZero() && !day.Before(to)) {
+			continue
+		}
+		date := day.Format(time.DateOnly)
+		seenDates[date] = true
+
+		student, ok := students[record.IdAluno]
+		if !ok {
+			student = &attendanceStudent{
+				id:      record.IdAluno,
+				name:    fmt.Sprint(utils.GetStringOrEmpty(record.Aluno)),
+				ra:      fmt.Sprint(utils.GetStringOrEmpty(record.RA)),
+				present: make(map[string]int),
+				absent:  make(map[string]int),
+			}
+			students[record.IdAluno] = student
+		}
+		if *record.Presente {
+			student.present[date]++
+		} else {
+			student.absent[date]++
+		}
+	}
+
+	dates = make([]string, 0, len(seenDates))
+	for date := range seenDates {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	ordered := make([]*attendanceStudent, 0, len(students))
+	for _, student := range students {
+		ordered = append(ordered, student)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		a, b := strings.ToLower(ordered[i].name), strings.ToLower(ordered[j].name)
+		if a != b {
+			return a < b
+		}
+		return ordered[i].id < ordered[j].id
+	})
+
+	headers = append(append(append([]string{}, attendanceLeadingHeaders...), dates...), attendanceTrailingHeaders...)
+	rows = make([][]interface{}, 0, len(ordered))
+	for _, student := range ordered {
+		row := make([]interface{}, 0, len(headers))
+		row = append(row, student.id, student.name, student.ra)
+		presences, absences := 0, 0
+		for _, date := range dates {
+			present, absent := student.present[date], student.absent[date]
+			presences += present
+			absences += absent
+			row = append(row, attendanceCell(present, absent))
+		}
+		var rate interface{} = ""
+		if total := presences + absences; total > 0 {
+			rate = float64(presences*10000/total) / 100
+		}
+		rows = append(rows, append(row, presences, absences, rate))
+	}
+	return headers, rows, dates
+}
+
+// attendanceCell shows "P" or "F" for a meeting, or the counts of each when
+// the class met more than once that day with mixed attendance. Dates the
+// student has no record for are left blank.
+func attendanceCell(present, absent int) string {
+	switch {
+	case present == 0 && absent == 0:
+		return ""
+	case absent == 0:
+		return "P"
+	case present == 0:
+		return "F"
+	}
+	return fmt.Sprintf("%dP %dF", present, absent)
+}
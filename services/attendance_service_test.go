@@ -0,0 +1,64 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+func TestAttendanceSheet(t *testing.T) {
+	day := func(d int) *utils.Date { return ptr(utils.Date(time.Date(2025, time.March, d, 0, 0, 0, 0, time.UTC))) }
+	record := func(aluno int, name string, d int, present bool) models.Frequencia {
+		return models.Frequencia{IdAluno: aluno, Aluno: ptr(name), RA: ptr("RA" + name), Data: day(d), Presente: ptr(present)}
+	}
+	records := []models.Frequencia{
+		record(2, "Bruno", 3, true),
+		record(1, "Ana", 3, true),
+		record(1, "Ana", 5, false),
+		record(2, "Bruno", 5, true),
+		record(2, "Bruno", 5, false),
+		record(1, "Ana", 10, true),
+		{IdAluno: 1, Aluno: ptr("Ana"), Data: day(4)},
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		headers  []string
+		rows     [][]interface{}
+	}{
+		{
+			name:    "students as rows and dates as columns",
+			headers: []string{"idAluno", "aluno", "ra", "2025-03-03", "2025-03-05", "2025-03-10", "presencas", "faltas", "frequencia"},
+			rows: [][]interface{}{
+				{1, "Ana", "RAAna", "P", "F", "P", 2, 1, 66.66},
+				{2, "Bruno", "RABruno", "P", "1P 1F", "", 2, 1, 66.66},
+			},
+		},
+		{
+			name:    "date range",
+			from:    time.Date(2025, time.March, 4, 0, 0, 0, 0, time.UTC),
+			to:      time.Date(2025, time.March, 6, 0, 0, 0, 0, time.UTC),
+			headers: []string{"idAluno", "aluno", "ra", "2025-03-05", "presencas", "faltas", "frequencia"},
+			rows: [][]interface{}{
+				{1, "Ana", "RAAna", "F", 0, 1, float64(0)},
+				{2, "Bruno", "RABruno", "1P 1F", 1, 1, float64(50)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers, rows, _ := attendanceSheet(records, tt.from, tt.to)
+			if !reflect.DeepEqual(headers, tt.headers) {
+				t.Errorf("headers = %v, want %v", headers, tt.headers)
+			}
+			if !reflect.DeepEqual(rows, tt.rows) {
+				t.Errorf("rows = %v, want %v", rows, tt.rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/notifications"
+)
+
+// Version is the service version written to the audit tab. Builds can set it
+// with -ldflags "-X github.com/SamuelLeutner/fetch-student-data/services.Version=1.2.0";
+// otherwise it falls back to the module version or VCS revision of the build.
+var Version string
+
+// auditHeaders heads the audit tab. Rows are keyed on the job ID, so a job
+// recorded twice updates its row.
+var auditHeaders = []string{"Job ID", "Atualizado em", "Job", "Status", "Abas", "Filtros", "Linhas", "Duração", "Versão"}
+
+// auditTimeout bounds the audit write, which runs even when the job's own
+// context was cancelled or timed out.
+const auditTimeout = 30 * time.Second
+
+// serviceVersion returns Version, or what the Go toolchain recorded about the
+// build.
+func serviceVersion() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+			return setting.Value[:12]
+		}
+	}
+	return "dev"
+}
+
+type sheetSpreadsheetsKey struct{}
+
+// withSheetSpreadsheets records, for writeAudit, the spreadsheet each sheet of
+// the job in ctx was written to. Sheets left out went to the default one.
+func withSheetSpreadsheets(ctx context.Context, spreadsheets map[string]string) context.Context {
+	if len(spreadsheets) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, sheetSpreadsheetsKey{}, spreadsheets)
+}
+
+// auditSpreadsheets groups sheets by the spreadsheet they were written to,
+// "" being the default one, in the order the spreadsheets first appear.
+func auditSpreadsheets(ctx context.Context, sheets []string) ([]string, map[string][]string) {
+	spreadsheets, _ := ctx.Value(sheetSpreadsheetsKey{}).(map[string]string)
+	var order []string
+	bySpreadsheet := make(map[string][]string)
+	for _, sheet := range sheets {
+		id := spreadsheets[sheet]
+		if _, ok := bySpreadsheet[id]; !ok {
+			order = append(order, id)
+		}
+		bySpreadsheet[id] = append(bySpreadsheet[id], sheet)
+	}
+	return order, bySpreadsheet
+}
+
+// writeAudit records a finished job in the audit tab of each spreadsheet it
+// wrote to, so sheet consumers can tell when each tab was last refreshed and
+// by what. Only Google Sheets jobs that wrote a sheet are recorded, and
+// failures are only logged since the job's own sheets are already written.
+func (c *JacadClient) writeAudit(ctx context.Context, summary notifications.Summary, params interface{}, sheets []string) {
+	if !c.Config.AuditTab || c.Config.AuditSheet == "" || len(sheets) == 0 {
+		return
+	}
+	writer, ok := primaryWriter(c.Writer).(*GoogleSheetsWriter)
+	if !ok {
+		logging.FromContext(ctx).Debug("Skipping the audit tab, which only the Google Sheets writer keeps", "writer", fmt.Sprintf("%T", primaryWriter(c.Writer)))
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditTimeout)
+	defer cancel()
+	now := time.Now()
+	order, bySpreadsheet := auditSpreadsheets(ctx, sheets)
+	for _, id := range order {
+		row := auditRow(summary, params, bySpreadsheet[id], now)
+		if err := writer.UpsertRows(WithSpreadsheetID(ctx, id), c.Config.AuditSheet, auditHeaders, auditHeaders[0], [][]interface{}{row}); err != nil {
+			logging.FromContext(ctx).Warn("Failed to write audit tab", "sheet", c.Config.AuditSheet, "spreadsheetId", id, "error", err)
+		}
+	}
+}
+
+func auditRow(summary notifications.Summary, params interface{}, sheets []string, now time.Time) []interface{} {
+	jobID := summary.JobID
+	if jobID == "" {
+		jobID = localJobID
+	}
+	return []interface{}{
+		jobID,
+		now.Format("2006-01-02 15:04:05"),
+		summary.Job,
+		jobStatus(summary),
+		strings.Join(sheets, "\n"),
+		auditFilters(jobParams(params)),
+		summary.RowsWritten,
+		summary.Duration.String(),
+		serviceVersion(),
+	}
+}
+
+// auditFilters renders a job's parameters as "name=value" pairs sorted by
+// name.
+func auditFilters(params map[string]interface{}) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := reflect.Indirect(reflect.ValueOf(params[name]))
+		pairs[i] = fmt.Sprintf("%s=%v", name, value)
+	}
+	return strings.Join(pairs, "; ")
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/notifications"
+)
+
+func TestAuditRow(t *testing.T) {
+	Version = "1.4.0"
+	defer func() { Version = "" }()
+
+	summary := notifications.Summary{
+		JobID:       "job-1",
+		Job:         "fetch-enrollments",
+		Status:      notifications.StatusSuccess,
+		RowsWritten: 120,
+		Duration:    95 * time.Second,
+		Failures:    []string{"EAD: quota"},
+	}
+	params := &requests.FetchEnrollmentsRequest{IdPeriodoLetivo: 42, StatusMatricula: "ATIVA", DryRun: false}
+	now := time.Date(2025, time.March, 3, 10, 30, 0, 0, time.UTC)
+
+	want := []interface{}{
+		"job-1", "2025-03-03 10:30:00", "fetch-enrollments", JobStatusPartial, "Graduação\nPós",
+		"idPeriodoLetivo=42; statusMatricula=ATIVA", 120, "1m35s", "1.4.0",
+	}
+	if got := auditRow(summary, params, []string{"Graduação", "Pós"}, now); !reflect.DeepEqual(got, want) {
+		t.Errorf("auditRow() = %v, want %v", got, want)
+	}
+}
+
+func TestAuditFilters(t *testing.T) {
+	statuses := []string{"ATIVA", "TRANCADA"}
+	got := auditFilters(map[string]interface{}{"statusesMatricula": statuses, "idPeriodoLetivo": 42, "dryRun": true})
+	if want := "dryRun=true; idPeriodoLetivo=42; statusesMatricula=[ATIVA TRANCADA]"; got != want {
+		t.Errorf("auditFilters() = %q, want %q", got, want)
+	}
+}
+
+func TestWriteAuditRoutesToJobSpreadsheets(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Organizations = config.NewOrgDirectory([]config.Organization{{Key: "EAD", ID: 20, Name: "EAD", SpreadsheetID: "ead-book"}, {Key: "POS", ID: 17, Name: "POS"}})
+	tests := []struct {
+		name   string
+		result *FetchResult
+		want   map[string]string
+	}{
+		{
+			name:   "organization spreadsheets",
+			result: &FetchResult{Organizations: []OrgSummary{{OrgID: 20, Sheet: "EAD"}, {OrgID: 17, Sheet: "POS"}}, SummarySheet: "Resumo"},
+			want:   map[string]string{"ead-book": "EAD", "book": "POS\nResumo"},
+		},
+		{
+			name:   "job spreadsheet",
+			result: &FetchResult{Organizations: []OrgSummary{{OrgID: 20, Sheet: "EAD"}, {OrgID: 17, Sheet: "POS"}}, Spreadsheet: &CreatedSpreadsheet{ID: "requested"}},
+			want:   map[string]string{"requested": "EAD\nPOS"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeSheetsAPI()
+			c := &JacadClient{Config: &cfg, Writer: newFakeSheetsWriter(t, api)}
+
+			ctx := withSheetSpreadsheets(context.Background(), c.sheetSpreadsheets(tt.result))
+			c.writeAudit(ctx, notifications.Summary{JobID: "job-1", Status: notifications.StatusSuccess}, nil, writtenSheets(tt.result))
+
+			for _, book := range []string{"book", "ead-book", "requested"} {
+				values, _ := api.Tab(book, cfg.AuditSheet)
+				want, ok := tt.want[book]
+				if !ok {
+					if len(values) > 0 {
+						t.Errorf("audit tab written to %q, want none", book)
+					}
+					continue
+				}
+				if len(values) != 2 || values[1][4] != want {
+					t.Errorf("audit tab of %q = %v, want one row listing %q", book, values, want)
+				}
+			}
+		})
+	}
+}
@@ -2,48 +2,293 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"log"
-	"net/http"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
 )
 
+// authRefreshCheckInterval is how often RunTokenRefresher looks for tokens
+// due for a refresh.
+const authRefreshCheckInterval = 30 * time.Second
+
+// authRetryBaseDelay and authRetryMaxDelay bound the backoff between failed
+// background refreshes.
+const (
+	authRetryBaseDelay = 10 * time.Second
+	authRetryMaxDelay  = 5 * time.Minute
+)
+
+// GetAuthToken returns the token of the tenant selected by ctx. Each tenant
+// has its own token. A missing or expired token is renewed before returning;
+// a token close to expiry is returned while a background login replaces it,
+// so fetches do not pause for auth.
 func (c *JacadClient) GetAuthToken(ctx context.Context) (string, error) {
-	c.muAuth.Lock()
-	defer c.muAuth.Unlock()
+	profile, err := c.profile(ctx)
+	if err != nil {
+		return "", err
+	}
+	tenant := tenantFrom(ctx)
+	state := c.authFor(tenant)
+	state.mu.Lock()
+	defer state.mu.Unlock()
 
-	if c.token != "" && time.Now().Before(c.tokenExpiry) {
-		return c.token, nil
+	now := time.Now()
+	if state.token != "" && now.Before(state.expiry) {
+		if !now.Before(state.refreshAt) {
+			c.refreshInBackground(tenant, state)
+		}
+		return state.token, nil
 	}
 
-	log.Println("Token expired or not available. Authenticating with Jacad...")
-	
-	authURL := c.Config.APIBase + c.Config.Endpoints["AUTH"]
-	authHeaders := map[string]string{
-		"token": c.Config.UserToken,
+	trigger := "missing"
+	if state.token != "" {
+		trigger = "expired"
+	}
+	logging.FromContext(ctx).Info("Token expired or not available. Authenticating with Jacad...", "tenant", tenant)
+	token, expiry, err := c.login(ctx, profile)
+	if err != nil {
+		jacadTokenRefreshesTotal.Inc(trigger, "error")
+		return "", err
 	}
+	jacadTokenRefreshesTotal.Inc(trigger, "ok")
+	c.storeToken(state, token, expiry)
+	return token, nil
+}
+
+// refreshInBackground starts a login for tenant unless one is already
+// running. The current token keeps being served until it succeeds; on failure
+// the next attempt is delayed by retryRefresh. state.mu must be held.
+func (c *JacadClient) refreshInBackground(tenant string, state *authState) {
+	if state.refreshing {
+		return
+	}
+	state.refreshing = true
+
+	go func() {
+		ctx := WithTenant(context.Background(), tenant)
+		logger := logging.FromContext(ctx).With("tenant", tenant)
+
+		var token string
+		var expiry time.Time
+		profile, err := c.profile(ctx)
+		if err == nil {
+			logger.Info("Token close to expiry. Refreshing it in the background...")
+			token, expiry, err = c.login(ctx, profile)
+		}
+
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		state.refreshing = false
+		if err != nil {
+			state.retryRefresh(time.Now())
+			logger.Warn("Background token refresh failed. The current token is kept until it expires.", "expiresAt", state.expiry, "retryAt", state.refreshAt, "error", err)
+			jacadTokenRefreshesTotal.Inc("proactive", "error")
+			return
+		}
+		jacadTokenRefreshesTotal.Inc("proactive", "ok")
+		c.storeToken(state, token, expiry)
+	}()
+}
+
+// storeToken caches token and schedules its refresh AuthRefreshMargin before
+// expiry, or halfway through its lifetime when that is shorter than the
+// margin. state.mu must be held.
+func (c *JacadClient) storeToken(state *authState, token string, expiry time.Time) {
+	margin := min(c.Config.AuthRefreshMargin, time.Until(expiry)/2)
+	state.token, state.expiry, state.refreshAt = token, expiry, expiry.Add(-margin)
+	state.failures = 0
+}
+
+// retryRefresh schedules the next background refresh after a failed one,
+// doubling the delay from authRetryBaseDelay up to authRetryMaxDelay so a
+// Jacad outage is not hit with a login on every request. state.mu must be
+// held.
+func (state *authState) retryRefresh(now time.Time) {
+	state.failures++
+	delay := authRetryMaxDelay
+	if shift := state.failures - 1; shift < 16 {
+		delay = min(authRetryBaseDelay<<shift, authRetryMaxDelay)
+	}
+	state.refreshAt = now.Add(delay)
+}
+
+// RunTokenRefresher renews tokens shortly before they expire until ctx is
+// done, so long fetches and idle servers always hold a valid token. Tenants
+// are only refreshed after their first login.
+func (c *JacadClient) RunTokenRefresher(ctx context.Context) {
+	ticker := time.NewTicker(authRefreshCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		c.muAuth.Lock()
+		states := make(map[string]*authState, len(c.auth))
+		for tenant, state := range c.auth {
+			states[tenant] = state
+		}
+		c.muAuth.Unlock()
+
+		now := time.Now()
+		for tenant, state := range states {
+			state.mu.Lock()
+			if state.token != "" && !now.Before(state.refreshAt) {
+				c.refreshInBackground(tenant, state)
+			}
+			state.mu.Unlock()
+		}
+	}
+}
+
+// login authenticates against profile with its strategy and returns the
+// token with its expiry.
+func (c *JacadClient) login(ctx context.Context, profile config.JacadProfile) (string, time.Time, error) {
+	logger := logging.FromContext(ctx)
 
-	authBody, err := c.MakeRequest(ctx, http.MethodPost, authURL, authHeaders, nil)
+	authenticator, err := c.authenticator(profile)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	token, authResp, err := authenticator.Authenticate(ctx, profile)
 	if err != nil {
 		if ctx.Err() != nil {
-			return "", fmt.Errorf("failed to get new auth token due to context cancellation: %w", ctx.Err())
+			return "", time.Time{}, fmt.Errorf("failed to get new auth token due to context cancellation: %w", ctx.Err())
 		}
-		return "", fmt.Errorf("failed to get new auth token: %w", err)
+		return "", time.Time{}, fmt.Errorf("failed to get new auth token: %w", err)
 	}
 
-	var authResp struct {
-		Token string `json:"token"`
+	now := time.Now()
+	expiry, source := tokenExpiry(authResp, token, now)
+	if !expiry.IsZero() && !expiry.After(now) {
+		logger.Warn("Auth response reported an expiry in the past. Using the configured token lifetime instead.", "reported", expiry, "source", source)
+		expiry = time.Time{}
 	}
-	if err := json.Unmarshal(authBody, &authResp); err != nil {
-		return "", fmt.Errorf("failed to parse auth token response: %w", err)
+	if expiry.IsZero() {
+		expiry, source = now.Add(c.Config.AuthTokenExpiry), "config"
 	}
-	if authResp.Token == "" {
-		return "", fmt.Errorf("auth token response was empty")
+	logger.Info("New token obtained successfully.", "expiresAt", expiry, "expirySource", source)
+	return token, expiry, nil
+}
+
+// tokenExpiry reads the token's expiry from the auth response: a lifetime in
+// seconds (expiresIn, expires_in), an absolute time (expiresAt, expiration,
+// expires_at) or the exp claim of a JWT token. It returns the zero time when
+// none is present.
+func tokenExpiry(authResp map[string]interface{}, token string, now time.Time) (time.Time, string) {
+	for _, key := range []string{"expiresIn", "expires_in"} {
+		if seconds, ok := numberValue(authResp[key]); ok && seconds > 0 {
+			return now.Add(time.Duration(seconds * float64(time.Second))), key
+		}
 	}
+	for _, key := range []string{"expiresAt", "expiration", "expires_at"} {
+		switch value := authResp[key].(type) {
+		case string:
+			if t, err := time.Parse(time.RFC3339, value); err == nil {
+				return t, key
+			}
+			if epoch, ok := numberValue(value); ok {
+				return epochTime(epoch), key
+			}
+		case float64:
+			return epochTime(value), key
+		}
+	}
+	if exp, ok := jwtExpiry(token); ok {
+		return exp, "jwt"
+	}
+	return time.Time{}, ""
+}
 
-	c.token = authResp.Token
-	c.tokenExpiry = time.Now().Add(1 * time.Hour)
-	log.Println("New token obtained successfully.")
-	return c.token, nil
+func numberValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// epochTime accepts Unix timestamps in seconds or milliseconds.
+func epochTime(epoch float64) time.Time {
+	if epoch > 1e12 {
+		return time.UnixMilli(int64(epoch))
+	}
+	return time.Unix(int64(epoch), 0)
+}
+
+func jwtExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(claims.Exp), 0), true
+}
+
+// AuthRefresh is the token RefreshAuthToken obtained.
+type AuthRefresh struct {
+	Tenant    string    `json:"tenant,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	RefreshAt time.Time `json:"refreshAt" doc:"When the token will be renewed in the background."`
+}
+
+// RefreshAuthToken discards the cached token of the tenant in ctx and logs
+// in again, for when USER_TOKEN was rotated on the Jacad side and the cached
+// session keeps being rejected. The old token is dropped even if the login
+// fails, so the next request logs in again.
+func (c *JacadClient) RefreshAuthToken(ctx context.Context) (*AuthRefresh, error) {
+	profile, err := c.profile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tenant := tenantFrom(ctx)
+	state := c.authFor(tenant)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.token, state.expiry, state.refreshAt = "", time.Time{}, time.Time{}
+	logging.FromContext(ctx).Info("Discarding the cached token and authenticating with Jacad...", "tenant", tenant)
+	token, expiry, err := c.login(ctx, profile)
+	if err != nil {
+		jacadTokenRefreshesTotal.Inc("manual", "error")
+		return nil, err
+	}
+	jacadTokenRefreshesTotal.Inc("manual", "ok")
+	c.storeToken(state, token, expiry)
+	return &AuthRefresh{Tenant: tenant, ExpiresAt: state.expiry, RefreshAt: state.refreshAt}, nil
+}
+
+// InvalidateAuthToken drops the tenant's cached token so the next
+// GetAuthToken call authenticates again. It is a no-op when another caller has already replaced
+// the rejected token.
+func (c *JacadClient) InvalidateAuthToken(ctx context.Context, rejected string) {
+	state := c.authFor(tenantFrom(ctx))
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.token == rejected {
+		state.token = ""
+		state.expiry = time.Time{}
+	}
 }
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+)
+
+func TestStoreToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		lifetime time.Duration
+		margin   time.Duration
+		wantLead time.Duration
+	}{
+		{name: "refreshes the margin before expiry", lifetime: time.Hour, margin: 5 * time.Minute, wantLead: 5 * time.Minute},
+		{name: "refreshes halfway through a short lifetime", lifetime: 4 * time.Minute, margin: 5 * time.Minute, wantLead: 2 * time.Minute},
+		{name: "zero margin refreshes at expiry", lifetime: time.Hour, margin: 0, wantLead: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &JacadClient{Config: &config.Config{AuthRefreshMargin: tt.margin}}
+			state := &authState{failures: 3}
+			expiry := time.Now().Add(tt.lifetime)
+
+			c.storeToken(state, "token", expiry)
+
+			if state.token != "token" || !state.expiry.Equal(expiry) {
+				t.Fatalf("stored token %q expiring %v, want %q expiring %v", state.token, state.expiry, "token", expiry)
+			}
+			if lead := expiry.Sub(state.refreshAt); lead < tt.wantLead-time.Second || lead > tt.wantLead+time.Second {
+				t.Errorf("refresh %v before expiry, want about %v", lead, tt.wantLead)
+			}
+			if state.failures != 0 {
+				t.Errorf("failures = %d after storing a token, want 0", state.failures)
+			}
+		})
+	}
+}
+
+func TestRetryRefresh(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	state := &authState{}
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second, 160 * time.Second, 5 * time.Minute, 5 * time.Minute}
+	for i, delay := range want {
+		state.retryRefresh(now)
+		if got := state.refreshAt.Sub(now); got != delay {
+			t.Errorf("failure %d: retry after %v, want %v", i+1, got, delay)
+		}
+	}
+
+	state.failures = 1000
+	state.retryRefresh(now)
+	if got := state.refreshAt.Sub(now); got != authRetryMaxDelay {
+		t.Errorf("after many failures: retry after %v, want %v", got, authRetryMaxDelay)
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	jwt := "eyJhbGciOiJIUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(`{"exp":1740834000}`)) + ".sig"
+
+	tests := []struct {
+		name       string
+		resp       map[string]interface{}
+		token      string
+		want       time.Time
+		wantSource string
+	}{
+		{name: "expiresIn seconds", resp: map[string]interface{}{"expiresIn": float64(3600)}, want: now.Add(time.Hour), wantSource: "expiresIn"},
+		{name: "expires_in as string", resp: map[string]interface{}{"expires_in": "90"}, want: now.Add(90 * time.Second), wantSource: "expires_in"},
+		{name: "non-positive lifetime is ignored", resp: map[string]interface{}{"expiresIn": float64(0)}, token: "opaque"},
+		{name: "expiresAt RFC 3339", resp: map[string]interface{}{"expiresAt": "2025-03-01T13:00:00Z"}, want: now.Add(time.Hour), wantSource: "expiresAt"},
+		{name: "expiration epoch seconds", resp: map[string]interface{}{"expiration": float64(1740834000)}, want: time.Unix(1740834000, 0), wantSource: "expiration"},
+		{name: "expires_at epoch milliseconds string", resp: map[string]interface{}{"expires_at": "1740834000000"}, want: time.UnixMilli(1740834000000), wantSource: "expires_at"},
+		{name: "lifetime wins over absolute time", resp: map[string]interface{}{"expiresIn": float64(60), "expiresAt": "2030-01-01T00:00:00Z"}, want: now.Add(time.Minute), wantSource: "expiresIn"},
+		{name: "jwt exp claim", resp: map[string]interface{}{}, token: jwt, want: time.Unix(1740834000, 0), wantSource: "jwt"},
+		{name: "malformed jwt", resp: map[string]interface{}{}, token: "a.!!!.c"},
+		{name: "nothing reported", resp: map[string]interface{}{"token": "opaque"}, token: "opaque"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, source := tokenExpiry(tt.resp, tt.token, now)
+			if !got.Equal(tt.want) || source != tt.wantSource {
+				t.Errorf("tokenExpiry() = %v (%q), want %v (%q)", got, source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestRefreshAuthToken(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{})
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.RetryDelay = 0
+	client := NewJacadClient(&cfg, NewDiscardWriter(), nil, nil, nil)
+	ctx := context.Background()
+
+	first, err := client.GetAuthToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh, err := client.RefreshAuthToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !refresh.ExpiresAt.After(time.Now()) || !refresh.RefreshAt.Before(refresh.ExpiresAt) {
+		t.Errorf("refresh = %+v, want a future expiry after the refresh time", refresh)
+	}
+	second, err := client.GetAuthToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first {
+		t.Error("GetAuthToken returned the discarded token")
+	}
+	if logins := server.Requests("AUTH"); logins != 2 {
+		t.Errorf("logged in %d times, want 2", logins)
+	}
+
+	// A failed login still discards the cached token.
+	cfg.UserToken = "rotated"
+	if _, err := client.RefreshAuthToken(ctx); err == nil {
+		t.Fatal("RefreshAuthToken with a rejected USER_TOKEN did not fail")
+	}
+	if state := client.authFor(""); state.token != "" {
+		t.Errorf("cached token %q kept after a failed refresh", state.token)
+	}
+}
+
+func TestOAuth2Login(t *testing.T) {
+	logins := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logins++
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("scope") != "read enrollments" {
+			t.Errorf("token request form = %v, want the client_credentials grant with both scopes", r.PostForm)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "oauth-token", "token_type": "Bearer", "expires_in": 600})
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.RetryDelay = 0
+	cfg.JacadProfiles = map[string]config.JacadProfile{"colegio": {
+		APIBase:           server.URL,
+		Auth:              config.AuthStrategyOAuth2,
+		OAuthTokenURL:     server.URL + "/oauth/token",
+		OAuthClientID:     "client",
+		OAuthClientSecret: "s3cret",
+		OAuthScopes:       []string{"read", "enrollments"},
+	}}
+	client := NewJacadClient(&cfg, NewDiscardWriter(), nil, nil, nil)
+	ctx := WithTenant(context.Background(), "colegio")
+
+	token, err := client.GetAuthToken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "oauth-token" {
+		t.Errorf("GetAuthToken = %q, want oauth-token", token)
+	}
+	if lifetime := time.Until(client.authFor("colegio").expiry); lifetime < 9*time.Minute || lifetime > 10*time.Minute {
+		t.Errorf("token expires in %v, want the 10m of expires_in", lifetime)
+	}
+	if _, err := client.GetAuthToken(ctx); err != nil || logins != 1 {
+		t.Errorf("second GetAuthToken logged in again (%d logins, error %v), want the cached token", logins, err)
+	}
+}
+
+// TestFetchPageReauthenticates answers a rejected token with one fresh login
+// and a retry of the page.
+func TestFetchPageReauthenticates(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(20)})
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.RetryDelay = 0
+	client := NewJacadClient(&cfg, NewDiscardWriter(), nil, nil, nil)
+	state := client.authFor("")
+	state.token = "revoked"
+	state.expiry = time.Now().Add(time.Hour)
+	state.refreshAt = state.expiry
+
+	elements, _, err := client.FetchPage(context.Background(), cfg.Endpoints["ENROLLMENTS"], 0, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elements) != 10 {
+		t.Errorf("fetched %d enrollments, want 10", len(elements))
+	}
+	if logins := server.Requests("AUTH"); logins != 1 {
+		t.Errorf("logged in %d times, want once", logins)
+	}
+	if state.token == "revoked" {
+		t.Error("rejected token still cached")
+	}
+}
+
+func TestFetchPageReauthenticatesOnce(t *testing.T) {
+	logins := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			logins++
+			json.NewEncoder(w).Encode(map[string]interface{}{"token": fmt.Sprintf("token-%d", logins), "expiresIn": 3600})
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = "user"
+	cfg.RetryDelay = 0
+	client := NewJacadClient(&cfg, NewDiscardWriter(), nil, nil, nil)
+
+	_, _, err := client.FetchPage(context.Background(), cfg.Endpoints["ENROLLMENTS"], 0, 10, nil)
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("FetchPage() = %v, want ErrUnauthorized", err)
+	}
+	if logins != 2 {
+		t.Errorf("logged in %d times, want the first login and one retry", logins)
+	}
+}
+
+func TestInvalidateAuthTokenKeepsNewerToken(t *testing.T) {
+	cfg := config.Defaults()
+	client := NewJacadClient(&cfg, NewDiscardWriter(), nil, nil, nil)
+	state := client.authFor("")
+	state.token = "fresh"
+
+	client.InvalidateAuthToken(context.Background(), "rejected")
+	if state.token != "fresh" {
+		t.Errorf("token = %q, want the newer token kept", state.token)
+	}
+	client.InvalidateAuthToken(context.Background(), "fresh")
+	if state.token != "" {
+		t.Errorf("token = %q, want it dropped", state.token)
+	}
+}
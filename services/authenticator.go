@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+// Authenticator logs in to a Jacad instance. It returns the bearer token
+// sent with every request and the decoded login response, from which
+// tokenExpiry reads the token's lifetime.
+type Authenticator interface {
+	Authenticate(ctx context.Context, profile config.JacadProfile) (token string, response map[string]interface{}, err error)
+}
+
+// authenticator returns the Authenticator of the profile's strategy.
+func (c *JacadClient) authenticator(profile config.JacadProfile) (Authenticator, error) {
+	switch profile.AuthStrategy() {
+	case config.AuthStrategyToken:
+		return tokenAuthenticator{client: c}, nil
+	case config.AuthStrategyOAuth2:
+		return oauth2Authenticator{client: c}, nil
+	}
+	return nil, fmt.Errorf("unknown auth strategy '%s'", profile.Auth)
+}
+
+// tokenAuthenticator exchanges the profile's user token, sent in the token
+// header, for a session token at the AUTH endpoint.
+type tokenAuthenticator struct {
+	client *JacadClient
+}
+
+func (a tokenAuthenticator) Authenticate(ctx context.Context, profile config.JacadProfile) (string, map[string]interface{}, error) {
+	authURL := profile.APIBase + a.client.Config.Endpoints["AUTH"]
+	body, err := a.client.MakeRequest(ctx, http.MethodPost, authURL, map[string]string{"token": profile.UserToken}, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return decodeAuthResponse(body, "token")
+}
+
+// oauth2Authenticator runs the OAuth2 client credentials grant against the
+// profile's token URL, authenticating the client with HTTP Basic auth.
+type oauth2Authenticator struct {
+	client *JacadClient
+}
+
+func (a oauth2Authenticator) Authenticate(ctx context.Context, profile config.JacadProfile) (string, map[string]interface{}, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(profile.OAuthScopes) > 0 {
+		form.Set("scope", strings.Join(profile.OAuthScopes, " "))
+	}
+	credentials := url.QueryEscape(profile.OAuthClientID) + ":" + url.QueryEscape(profile.OAuthClientSecret)
+	headers := map[string]string{
+		"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)),
+		"Content-Type":  "application/x-www-form-urlencoded",
+		"Accept":        "application/json",
+	}
+	body, err := a.client.MakeRequest(ctx, http.MethodPost, profile.OAuthTokenURL, headers, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", nil, err
+	}
+	return decodeAuthResponse(body, "access_token")
+}
+
+// decodeAuthResponse parses a login response and reads the token from its
+// tokenField.
+func decodeAuthResponse(body []byte, tokenField string) (string, map[string]interface{}, error) {
+	var authResp map[string]interface{}
+	if err := json.Unmarshal(body, &authResp); err != nil {
+		return "", nil, fmt.Errorf("failed to parse auth token response: %w", err)
+	}
+	token, _ := authResp[tokenField].(string)
+	if token == "" {
+		return "", nil, fmt.Errorf("auth token response was empty")
+	}
+	return token, authResp, nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+func TestRunWriteBenchmark(t *testing.T) {
+	columns := []config.Column{{Field: "idMatricula", Header: "ID"}, {Field: "aluno", Header: "Aluno"}, {Field: "dataMatricula", Header: "Data"}}
+
+	tests := []struct {
+		name    string
+		opts    BenchmarkOptions
+		batches int
+		wantErr bool
+	}{
+		{name: "even batches", opts: BenchmarkOptions{Rows: 1000, PageSize: 100, PagesPerBatch: 5, Sheet: "Bench"}, batches: 2},
+		{name: "partial last batch", opts: BenchmarkOptions{Rows: 1050, PageSize: 100, PagesPerBatch: 5, Sheet: "Bench"}, batches: 3},
+		{name: "no rows", opts: BenchmarkOptions{PageSize: 100, PagesPerBatch: 5}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := NewDiscardWriter()
+			result, err := RunWriteBenchmark(context.Background(), writer, columns, tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Rows != tt.opts.Rows || result.Batches != tt.batches {
+				t.Errorf("rows, batches = %d, %d, want %d, %d", result.Rows, result.Batches, tt.opts.Rows, tt.batches)
+			}
+			if written, _ := writer.CountRows(context.Background(), "Bench"); written != tt.opts.Rows {
+				t.Errorf("writer got %d rows, want %d", written, tt.opts.Rows)
+			}
+			if result.RowsPerSecond <= 0 || result.AllocBytes == 0 {
+				t.Errorf("result = %+v, want throughput and allocations", result)
+			}
+		})
+	}
+}
+
+func TestSyntheticEnrollmentsDeterministic(t *testing.T) {
+	first, second := syntheticEnrollments(10, 3), syntheticEnrollments(10, 3)
+	for i := range first {
+		if first[i].IdMatricula != 11+i || *first[i].Aluno != *second[i].Aluno || *first[i].Curso != *second[i].Curso {
+			t.Errorf("enrollment %d = %+v, want ID %d and the same fields on every call", i, first[i], 11+i)
+		}
+	}
+}
@@ -0,0 +1,587 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+)
+
+const bigQueryJobPollInterval = time.Second
+
+// BigQueryWriter implements SheetWriter on top of BigQuery: every sheet maps
+// to a table in a single dataset, and rows are sent as NDJSON load jobs of at
+// most loadBatchRows rows each.
+type BigQueryWriter struct {
+	service          *bigquery.Service
+	projectID        string
+	datasetID        string
+	location         string
+	loadBatchRows    int
+	retryMaxAttempts int
+	retryDelay       time.Duration
+
+	mu      sync.Mutex
+	headers map[string][]string
+}
+
+func NewBigQueryWriter(ctx context.Context, projectID, datasetID, location string, creds GoogleCredentials, loadBatchRows, retryMaxAttempts int, retryDelay time.Duration) (*BigQueryWriter, error) {
+	if projectID == "" || datasetID == "" {
+		return nil, fmt.Errorf("BigQuery writer requires both a project ID and a dataset")
+	}
+	logger := logging.FromContext(ctx)
+
+	opts, credSourceDescription, err := googleClientOptions(ctx, creds, bigquery.BigqueryScope)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Configurando cliente BigQuery.", "source", credSourceDescription, "project", projectID, "dataset", datasetID)
+	service, err := bigquery.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao criar cliente da API BigQuery (fonte: %s): %w", credSourceDescription, err)
+	}
+
+	if loadBatchRows <= 0 {
+		loadBatchRows = 50000
+	}
+
+	logger.Info("Cliente do BigQuery inicializado com sucesso.")
+	return &BigQueryWriter{
+		service:          service,
+		projectID:        projectID,
+		datasetID:        datasetID,
+		location:         location,
+		loadBatchRows:    loadBatchRows,
+		retryMaxAttempts: retryMaxAttempts,
+		retryDelay:       retryDelay,
+		headers:          make(map[string][]string),
+	}, nil
+}
+
+// EnsureSheetExists creates the dataset when missing. Tables are created by
+// the first load job, once the schema can be inferred from the rows.
+func (w *BigQueryWriter) EnsureSheetExists(ctx context.Context, sheetName string) error {
+	getCallFunc := func() error {
+		_, err := w.service.Datasets.Get(w.projectID, w.datasetID).Context(ctx).Do()
+		return err
+	}
+	err := w.executeBigQueryCall(ctx, "ensure_dataset", getCallFunc, fmt.Sprintf("verificar dataset '%s'", w.datasetID))
+	if err == nil {
+		return nil
+	}
+	if !isNotFoundError(err) {
+		return fmt.Errorf("falha ao verificar dataset '%s': %w", w.datasetID, err)
+	}
+
+	logging.FromContext(ctx).Info("API BigQuery: Dataset não encontrado. Criando...", "dataset", w.datasetID, "location", w.location)
+	dataset := &bigquery.Dataset{
+		DatasetReference: &bigquery.DatasetReference{ProjectId: w.projectID, DatasetId: w.datasetID},
+		Location:         w.location,
+	}
+	insertCallFunc := func() error {
+		_, err := w.service.Datasets.Insert(w.projectID, dataset).Context(ctx).Do()
+		return err
+	}
+	if err := w.executeBigQueryCall(ctx, "ensure_dataset", insertCallFunc, fmt.Sprintf("criar dataset '%s'", w.datasetID)); err != nil && !isConflictError(err) {
+		return fmt.Errorf("falha ao criar dataset '%s': %w", w.datasetID, err)
+	}
+	return nil
+}
+
+// CheckHealth verifies access to the dataset. A missing dataset is fine since
+// EnsureSheetExists creates it.
+func (w *BigQueryWriter) CheckHealth(ctx context.Context) error {
+	getCallFunc := func() error {
+		_, err := w.service.Datasets.Get(w.projectID, w.datasetID).Context(ctx).Do()
+		return err
+	}
+	if err := w.executeBigQueryCall(ctx, "health", getCallFunc, fmt.Sprintf("verificar dataset '%s'", w.datasetID)); err != nil && !isNotFoundError(err) {
+		return fmt.Errorf("dataset '%s' inacessível: %w", w.datasetID, err)
+	}
+	return nil
+}
+
+// SetHeaders remembers the column names used by later AppendRows calls.
+func (w *BigQueryWriter) SetHeaders(ctx context.Context, sheetName string, headers []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.headers[sheetName] = append([]string(nil), headers...)
+	return nil
+}
+
+func (w *BigQueryWriter) Clear(ctx context.Context, sheetName string) error {
+	table := bigQueryTableName(sheetName)
+	if _, err := w.tableSchema(ctx, table); err != nil {
+		if isNotFoundError(err) {
+			return nil
+		}
+		return fmt.Errorf("falha ao verificar tabela '%s': %w", table, err)
+	}
+
+	query := fmt.Sprintf("TRUNCATE TABLE %s", w.qualifiedTable(table))
+	if err := w.runQuery(ctx, "clear", query); err != nil {
+		return fmt.Errorf("falha ao limpar tabela '%s': %w", table, err)
+	}
+	return nil
+}
+
+func (w *BigQueryWriter) CountRows(ctx context.Context, sheetName string) (int, error) {
+	table := bigQueryTableName(sheetName)
+	var count int
+	getCallFunc := func() error {
+		t, err := w.service.Tables.Get(w.projectID, w.datasetID, table).Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		count = int(t.NumRows)
+		return nil
+	}
+	if err := w.executeBigQueryCall(ctx, "get_table", getCallFunc, fmt.Sprintf("contar linhas da tabela '%s'", table)); err != nil {
+		if isNotFoundError(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("falha ao contar linhas da tabela '%s': %w", table, err)
+	}
+	return count, nil
+}
+
+func (w *BigQueryWriter) AppendRows(ctx context.Context, sheetName string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	table := bigQueryTableName(sheetName)
+
+	schema, err := w.tableSchema(ctx, table)
+	if err != nil && !isNotFoundError(err) {
+		return fmt.Errorf("falha ao verificar tabela '%s': %w", table, err)
+	}
+	w.mu.Lock()
+	headers := w.headers[sheetName]
+	w.mu.Unlock()
+	if len(headers) > 0 {
+		schema = alignBigQuerySchema(schema, headers, rows)
+	} else if schema == nil {
+		return fmt.Errorf("tabela '%s' não existe e nenhum cabeçalho foi definido para a aba '%s'", table, sheetName)
+	}
+
+	if err := w.load(ctx, "append", table, schema, rows, "WRITE_APPEND"); err != nil {
+		return fmt.Errorf("falha ao anexar %d linhas na tabela '%s': %w", len(rows), table, err)
+	}
+	return nil
+}
+
+func (w *BigQueryWriter) OverwriteSheetData(ctx context.Context, sheetName string, headers []string, rows [][]interface{}) error {
+	table := bigQueryTableName(sheetName)
+	logger := logging.FromContext(ctx).With("table", table)
+
+	if err := w.EnsureSheetExists(ctx, sheetName); err != nil {
+		return err
+	}
+	if err := w.SetHeaders(ctx, sheetName, headers); err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		logger.Info("Nenhuma linha para escrever. Limpando tabela.")
+		return w.Clear(ctx, sheetName)
+	}
+
+	schema := inferBigQuerySchema(headers, rows)
+	if err := w.load(ctx, "overwrite", table, schema, rows, "WRITE_TRUNCATE"); err != nil {
+		return fmt.Errorf("falha ao escrever dados na tabela '%s': %w", table, err)
+	}
+
+	logger.Info("API BigQuery: Tabela sobrescrita com sucesso.", "rows", len(rows))
+	return nil
+}
+
+// UpsertRows loads rows into a staging table and MERGEs them into the target
+// on keyColumn, so reruns update existing rows instead of duplicating them.
+func (w *BigQueryWriter) UpsertRows(ctx context.Context, sheetName string, headers []string, keyColumn string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	table := bigQueryTableName(sheetName)
+	logger := logging.FromContext(ctx).With("table", table)
+
+	keyIndex := -1
+	for i, h := range headers {
+		if h == keyColumn {
+			keyIndex = i
+			break
+		}
+	}
+	if keyIndex < 0 {
+		return fmt.Errorf("coluna chave '%s' não encontrada nos cabeçalhos da tabela '%s'", keyColumn, table)
+	}
+
+	if err := w.EnsureSheetExists(ctx, sheetName); err != nil {
+		return err
+	}
+	if err := w.SetHeaders(ctx, sheetName, headers); err != nil {
+		return err
+	}
+
+	existing, err := w.tableSchema(ctx, table)
+	if err != nil {
+		if !isNotFoundError(err) {
+			return fmt.Errorf("falha ao verificar tabela '%s': %w", table, err)
+		}
+		logger.Info("API BigQuery: Tabela ainda não existe. Carregando linhas diretamente.")
+		if err := w.load(ctx, "upsert", table, inferBigQuerySchema(headers, rows), rows, "WRITE_APPEND"); err != nil {
+			return fmt.Errorf("falha ao carregar linhas na tabela '%s': %w", table, err)
+		}
+		return nil
+	}
+
+	schema := alignBigQuerySchema(existing, headers, rows)
+	staging := fmt.Sprintf("%s__staging_%d", table, time.Now().UnixNano())
+	defer func() {
+		if err := w.service.Tables.Delete(w.projectID, w.datasetID, staging).Context(context.WithoutCancel(ctx)).Do(); err != nil && !isNotFoundError(err) {
+			logger.Warn("Falha ao remover tabela temporária de upsert.", "staging", staging, "error", err)
+		}
+	}()
+
+	if err := w.load(ctx, "upsert", staging, schema, rows, "WRITE_TRUNCATE"); err != nil {
+		return fmt.Errorf("falha ao carregar linhas na tabela temporária '%s': %w", staging, err)
+	}
+
+	columns := make([]string, len(schema.Fields))
+	sets := make([]string, 0, len(schema.Fields))
+	sourceColumns := make([]string, len(schema.Fields))
+	key := schema.Fields[keyIndex].Name
+	for i, field := range schema.Fields {
+		columns[i] = fmt.Sprintf("`%s`", field.Name)
+		sourceColumns[i] = fmt.Sprintf("S.`%s`", field.Name)
+		if field.Name != key {
+			sets = append(sets, fmt.Sprintf("`%s` = S.`%s`", field.Name, field.Name))
+		}
+	}
+
+	var query strings.Builder
+	fmt.Fprintf(&query, "MERGE %s T USING %s S ON T.`%s` = S.`%s`", w.qualifiedTable(table), w.qualifiedTable(staging), key, key)
+	if len(sets) > 0 {
+		fmt.Fprintf(&query, " WHEN MATCHED THEN UPDATE SET %s", strings.Join(sets, ", "))
+	}
+	fmt.Fprintf(&query, " WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)", strings.Join(columns, ", "), strings.Join(sourceColumns, ", "))
+
+	if err := w.runQuery(ctx, "upsert", query.String()); err != nil {
+		return fmt.Errorf("falha ao mesclar linhas na tabela '%s': %w", table, err)
+	}
+
+	logger.Info("API BigQuery: Upsert concluído.", "rows", len(rows))
+	return nil
+}
+
+// load sends rows in chunks of loadBatchRows. Only the first chunk uses
+// writeDisposition; the rest are appended.
+func (w *BigQueryWriter) load(ctx context.Context, operation, table string, schema *bigquery.TableSchema, rows [][]interface{}, writeDisposition string) error {
+	logger := logging.FromContext(ctx).With("table", table)
+
+	for start := 0; start < len(rows); start += w.loadBatchRows {
+		end := min(start+w.loadBatchRows, len(rows))
+		payload, err := encodeNDJSON(schema, rows[start:end])
+		if err != nil {
+			return err
+		}
+
+		disposition := "WRITE_APPEND"
+		if start == 0 {
+			disposition = writeDisposition
+		}
+		job := &bigquery.Job{
+			JobReference: &bigquery.JobReference{ProjectId: w.projectID, Location: w.location},
+			Configuration: &bigquery.JobConfiguration{
+				Load: &bigquery.JobConfigurationLoad{
+					DestinationTable:  &bigquery.TableReference{ProjectId: w.projectID, DatasetId: w.datasetID, TableId: table},
+					Schema:            schema,
+					SourceFormat:      "NEWLINE_DELIMITED_JSON",
+					CreateDisposition: "CREATE_IF_NEEDED",
+					WriteDisposition:  disposition,
+				},
+			},
+		}
+
+		logger.Info("API BigQuery: Enviando job de carga...", "rows", end-start, "offset", start, "writeDisposition", disposition)
+		var inserted *bigquery.Job
+		insertCallFunc := func() error {
+			var err error
+			inserted, err = w.service.Jobs.Insert(w.projectID, job).Media(bytes.NewReader(payload)).Context(ctx).Do()
+			return err
+		}
+		if err := w.executeBigQueryCall(ctx, operation, insertCallFunc, fmt.Sprintf("carregar linhas na tabela '%s'", table)); err != nil {
+			return err
+		}
+		if err := w.waitForJob(ctx, operation, inserted); err != nil {
+			return err
+		}
+		bigQueryRowsLoadedTotal.Add(float64(end-start), operation)
+	}
+	return nil
+}
+
+func (w *BigQueryWriter) runQuery(ctx context.Context, operation, query string) error {
+	job := &bigquery.Job{
+		JobReference: &bigquery.JobReference{ProjectId: w.projectID, Location: w.location},
+		Configuration: &bigquery.JobConfiguration{
+			Query: &bigquery.JobConfigurationQuery{
+				Query:        query,
+				UseLegacySql: googleapi.Bool(false),
+			},
+		},
+	}
+
+	logging.FromContext(ctx).Debug("API BigQuery: Executando consulta...", "query", query)
+	var inserted *bigquery.Job
+	insertCallFunc := func() error {
+		var err error
+		inserted, err = w.service.Jobs.Insert(w.projectID, job).Context(ctx).Do()
+		return err
+	}
+	if err := w.executeBigQueryCall(ctx, operation, insertCallFunc, "executar consulta"); err != nil {
+		return err
+	}
+	return w.waitForJob(ctx, operation, inserted)
+}
+
+func (w *BigQueryWriter) waitForJob(ctx context.Context, operation string, job *bigquery.Job) error {
+	ref := job.JobReference
+	for {
+		if job.Status != nil && job.Status.State == "DONE" {
+			if job.Status.ErrorResult != nil {
+				return fmt.Errorf("job '%s' falhou: %s", ref.JobId, job.Status.ErrorResult.Message)
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("espera pelo job '%s' cancelada via contexto: %w", ref.JobId, ctx.Err())
+		case <-time.After(bigQueryJobPollInterval):
+		}
+
+		getCallFunc := func() error {
+			var err error
+			job, err = w.service.Jobs.Get(ref.ProjectId, ref.JobId).Location(ref.Location).Context(ctx).Do()
+			return err
+		}
+		if err := w.executeBigQueryCall(ctx, operation, getCallFunc, fmt.Sprintf("consultar job '%s'", ref.JobId)); err != nil {
+			return err
+		}
+	}
+}
+
+func (w *BigQueryWriter) tableSchema(ctx context.Context, table string) (*bigquery.TableSchema, error) {
+	var schema *bigquery.TableSchema
+	getCallFunc := func() error {
+		t, err := w.service.Tables.Get(w.projectID, w.datasetID, table).Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		schema = t.Schema
+		return nil
+	}
+	if err := w.executeBigQueryCall(ctx, "get_table", getCallFunc, fmt.Sprintf("obter tabela '%s'", table)); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (w *BigQueryWriter) qualifiedTable(table string) string {
+	return fmt.Sprintf("`%s.%s.%s`", w.projectID, w.datasetID, table)
+}
+
+func (w *BigQueryWriter) executeBigQueryCall(ctx context.Context, operation string, callFunc func() error, operationDesc string) error {
+	logger := logging.FromContext(ctx).With("operation", operationDesc)
+
+	for attempt := 0; attempt <= w.retryMaxAttempts; attempt++ {
+		err := callFunc()
+		if err == nil {
+			return nil
+		}
+		bigQueryAPIErrorsTotal.Inc(operation, sheetsErrorCode(err))
+
+		if !isRetryableSheetsError(err) || attempt == w.retryMaxAttempts {
+			return fmt.Errorf("falha na operação da API BigQuery '%s' após %d tentativas: %w", operationDesc, attempt+1, err)
+		}
+
+		delay := w.retryDelay * time.Duration(1<<attempt)
+		wait, asked := googleRetryAfter(err, time.Now())
+		if asked {
+			delay = wait
+		}
+		logger.Warn("Operação da API BigQuery falhou. Aguardando antes de tentar novamente...", "attempt", attempt+1, "maxAttempts", w.retryMaxAttempts+1, "error", err, "delay", delay.String(), "retryAfter", asked)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("operação '%s' cancelada via contexto durante a espera da nova tentativa: %w", operationDesc, ctx.Err())
+		}
+	}
+	return fmt.Errorf("executeBigQueryCall atingiu um estado inesperado para a operação: %s", operationDesc)
+}
+
+// alignBigQuerySchema orders fields to match headers, keeping the types of
+// columns that already exist in the table and inferring the rest.
+func alignBigQuerySchema(existing *bigquery.TableSchema, headers []string, rows [][]interface{}) *bigquery.TableSchema {
+	inferred := inferBigQuerySchema(headers, rows)
+	if existing == nil {
+		return inferred
+	}
+	byName := make(map[string]*bigquery.TableFieldSchema, len(existing.Fields))
+	for _, field := range existing.Fields {
+		byName[field.Name] = field
+	}
+	for i, field := range inferred.Fields {
+		if current, ok := byName[field.Name]; ok {
+			inferred.Fields[i] = current
+		}
+	}
+	return inferred
+}
+
+// inferBigQuerySchema types each column from its first non-empty value.
+// Columns with mixed or no values fall back to STRING.
+func inferBigQuerySchema(headers []string, rows [][]interface{}) *bigquery.TableSchema {
+	fields := make([]*bigquery.TableFieldSchema, len(headers))
+	for i, header := range headers {
+		fieldType := ""
+		for _, row := range rows {
+			if i >= len(row) || isEmptyCell(row[i]) {
+				continue
+			}
+			valueType := bigQueryType(row[i])
+			if fieldType == "" {
+				fieldType = valueType
+			} else if fieldType != valueType {
+				fieldType = "STRING"
+				break
+			}
+		}
+		if fieldType == "" {
+			fieldType = "STRING"
+		}
+		fields[i] = &bigquery.TableFieldSchema{Name: bigQueryColumnName(header), Type: fieldType, Mode: "NULLABLE"}
+	}
+	return &bigquery.TableSchema{Fields: fields}
+}
+
+func bigQueryType(value interface{}) string {
+	switch value.(type) {
+	case int, int32, int64:
+		return "INTEGER"
+	case float32, float64, SerialDate:
+		return "FLOAT"
+	case bool:
+		return "BOOLEAN"
+	case time.Time:
+		return "DATE"
+	default:
+		return "STRING"
+	}
+}
+
+func isEmptyCell(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	s, ok := value.(string)
+	return ok && s == ""
+}
+
+func encodeNDJSON(schema *bigquery.TableSchema, rows [][]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, row := range rows {
+		record := make(map[string]interface{}, len(schema.Fields))
+		for i, field := range schema.Fields {
+			if i >= len(row) {
+				break
+			}
+			value := row[i]
+			if isEmptyCell(value) && field.Type != "STRING" {
+				continue
+			}
+			switch v := value.(type) {
+			case time.Time:
+				if field.Type == "STRING" {
+					value = v.Format(time.RFC3339)
+				} else {
+					value = v.Format("2006-01-02")
+				}
+			default:
+				if field.Type == "STRING" && value != nil {
+					value = fmt.Sprint(value)
+				}
+			}
+			record[field.Name] = value
+		}
+		if err := enc.Encode(record); err != nil {
+			return nil, fmt.Errorf("falha ao codificar linha para o BigQuery: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+var accentReplacer = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ç", "C", "Ñ", "N",
+)
+
+// bigQueryTableName turns a sheet name such as "PÓS EAD" into a valid table ID.
+func bigQueryTableName(sheetName string) string {
+	return sanitizeBigQueryIdentifier(sheetName, 1024)
+}
+
+func bigQueryColumnName(header string) string {
+	return sanitizeBigQueryIdentifier(header, 300)
+}
+
+func sanitizeBigQueryIdentifier(name string, maxLen int) string {
+	var b strings.Builder
+	for _, r := range accentReplacer.Replace(strings.TrimSpace(name)) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	id := b.String()
+	if id == "" || unicode.IsDigit(rune(id[0])) {
+		id = "_" + id
+	}
+	if len(id) > maxLen {
+		id = id[:maxLen]
+	}
+	return id
+}
+
+func isNotFoundError(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+func isConflictError(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/option"
+)
+
+func TestInferBigQuerySchema(t *testing.T) {
+	headers := []string{"ID Matrícula", "Nota", "Data", "Ativo", "Misto", "Vazio"}
+	rows := [][]interface{}{
+		{int64(1), "", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), true, 1, nil},
+		{int64(2), 7.5, nil, false, "x", ""},
+	}
+
+	var got []string
+	for _, field := range inferBigQuerySchema(headers, rows).Fields {
+		got = append(got, field.Name+":"+field.Type)
+	}
+	want := []string{"ID_Matricula:INTEGER", "Nota:FLOAT", "Data:DATE", "Ativo:BOOLEAN", "Misto:STRING", "Vazio:STRING"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("inferBigQuerySchema() = %v, want %v", got, want)
+	}
+}
+
+func TestAlignBigQuerySchemaKeepsExistingTypes(t *testing.T) {
+	existing := &bigquery.TableSchema{Fields: []*bigquery.TableFieldSchema{
+		{Name: "Status", Type: "STRING"},
+		{Name: "ID", Type: "STRING"},
+	}}
+	schema := alignBigQuerySchema(existing, []string{"ID", "Status", "Nota"}, [][]interface{}{{1, "Ativo", 9.0}})
+
+	var got []string
+	for _, field := range schema.Fields {
+		got = append(got, field.Name+":"+field.Type)
+	}
+	want := []string{"ID:STRING", "Status:STRING", "Nota:FLOAT"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("alignBigQuerySchema() = %v, want %v", got, want)
+	}
+}
+
+func TestEncodeNDJSON(t *testing.T) {
+	schema := &bigquery.TableSchema{Fields: []*bigquery.TableFieldSchema{
+		{Name: "ID", Type: "INTEGER"},
+		{Name: "Data", Type: "DATE"},
+		{Name: "Texto", Type: "STRING"},
+	}}
+	day := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	payload, err := encodeNDJSON(schema, [][]interface{}{
+		{int64(1), day, 42},
+		{"", nil, day},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Data":"2025-03-01","ID":1,"Texto":"42"}` + "\n" + `{"Texto":"2025-03-01T12:00:00Z"}` + "\n"
+	if string(payload) != want {
+		t.Errorf("encodeNDJSON() = %s, want %s", payload, want)
+	}
+}
+
+func TestBigQueryTableName(t *testing.T) {
+	tests := map[string]string{
+		"PÓS EAD":                       "POS_EAD",
+		"Matrículas | 2025/1":           "Matriculas___2025_1",
+		"2025 Alunos":                   "_2025_Alunos",
+		"  Ação  ":                      "Acao",
+		strings.Repeat("a", 1100) + "!": strings.Repeat("a", 1024),
+	}
+	for sheet, want := range tests {
+		if got := bigQueryTableName(sheet); got != want {
+			t.Errorf("bigQueryTableName(%.20q) = %.20q, want %.20q", sheet, got, want)
+		}
+	}
+}
+
+// fakeBigQueryAPI answers the table and job calls BigQueryWriter makes,
+// finishing every job at once.
+type fakeBigQueryAPI struct {
+	mu     sync.Mutex
+	schema *bigquery.TableSchema
+	// jobs lists the load dispositions and queries inserted, in order.
+	jobs    []string
+	deleted []string
+}
+
+func (a *fakeBigQueryAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/jobs") && r.Method == http.MethodPost:
+		var job bigquery.Job
+		if mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); strings.HasPrefix(mediaType, "multipart/") {
+			part, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			json.NewDecoder(part).Decode(&job)
+		} else {
+			json.NewDecoder(r.Body).Decode(&job)
+		}
+		if load := job.Configuration.Load; load != nil {
+			a.jobs = append(a.jobs, load.WriteDisposition+" "+load.DestinationTable.TableId)
+		} else {
+			a.jobs = append(a.jobs, job.Configuration.Query.Query)
+		}
+		job.JobReference.JobId = "job"
+		job.Status = &bigquery.JobStatus{State: "DONE"}
+		json.NewEncoder(w).Encode(job)
+	case strings.Contains(r.URL.Path, "/tables/") && r.Method == http.MethodDelete:
+		a.deleted = append(a.deleted, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+	case strings.Contains(r.URL.Path, "/tables/"):
+		if a.schema == nil {
+			http.Error(w, `{"error":{"code":404,"message":"Not found"}}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(bigquery.Table{Schema: a.schema, NumRows: 2})
+	default:
+		json.NewEncoder(w).Encode(bigquery.Dataset{})
+	}
+}
+
+func newFakeBigQueryWriter(t *testing.T, api *fakeBigQueryAPI) *BigQueryWriter {
+	t.Helper()
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	service, err := bigquery.NewService(context.Background(), option.WithEndpoint(server.URL+"/"), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &BigQueryWriter{
+		service:       service,
+		projectID:     "proj",
+		datasetID:     "ds",
+		loadBatchRows: 2,
+		headers:       make(map[string][]string),
+	}
+}
+
+func TestBigQueryWriterOverwriteLoadsInBatches(t *testing.T) {
+	api := &fakeBigQueryAPI{}
+	writer := newFakeBigQueryWriter(t, api)
+
+	rows := [][]interface{}{{1}, {2}, {3}}
+	if err := writer.OverwriteSheetData(context.Background(), "PÓS EAD", []string{"ID"}, rows); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"WRITE_TRUNCATE POS_EAD", "WRITE_APPEND POS_EAD"}
+	if !reflect.DeepEqual(api.jobs, want) {
+		t.Errorf("jobs = %v, want %v", api.jobs, want)
+	}
+}
+
+func TestBigQueryWriterUpsertMerges(t *testing.T) {
+	api := &fakeBigQueryAPI{schema: &bigquery.TableSchema{Fields: []*bigquery.TableFieldSchema{
+		{Name: "ID", Type: "INTEGER"},
+		{Name: "Status", Type: "STRING"},
+	}}}
+	writer := newFakeBigQueryWriter(t, api)
+
+	err := writer.UpsertRows(context.Background(), "Alunos", []string{"ID", "Status"}, "ID", [][]interface{}{{1, "Trancado"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(api.jobs) != 2 || !strings.HasPrefix(api.jobs[0], "WRITE_TRUNCATE Alunos__staging_") {
+		t.Fatalf("jobs = %v, want a staging load then a MERGE", api.jobs)
+	}
+	merge := api.jobs[1]
+	for _, part := range []string{"MERGE `proj.ds.Alunos` T", "ON T.`ID` = S.`ID`", "UPDATE SET `Status` = S.`Status`", "INSERT (`ID`, `Status`)"} {
+		if !strings.Contains(merge, part) {
+			t.Errorf("MERGE query %q lacks %q", merge, part)
+		}
+	}
+	if len(api.deleted) != 1 || !strings.HasPrefix(api.deleted[0], "Alunos__staging_") {
+		t.Errorf("deleted tables = %v, want the staging table", api.deleted)
+	}
+}
+
+func TestBigQueryWriterUpsertRequiresKeyColumn(t *testing.T) {
+	writer := newFakeBigQueryWriter(t, &fakeBigQueryAPI{})
+	err := writer.UpsertRows(context.Background(), "Alunos", []string{"Status"}, "ID", [][]interface{}{{"Ativo"}})
+	if err == nil {
+		t.Fatal("UpsertRows() without the key column succeeded")
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ResponseCache stores raw Jacad response bodies keyed on endpoint and query.
+type ResponseCache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+type cacheBypassKey struct{}
+
+// WithCacheBypass marks ctx so Jacad responses are fetched fresh. Fresh
+// responses are still stored for later callers.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// LRUCache is an in-memory ResponseCache that evicts the least recently used
+// entry once maxEntries is reached.
+type LRUCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+func NewLRUCache(maxEntries int) *LRUCache {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	return &LRUCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return nil, false, nil
+	}
+	c.ll.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+func (c *LRUCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(elem)
+		return nil
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// RedisCache is a ResponseCache shared between instances through Redis.
+type RedisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisCache(redisURL, prefix string) (*RedisCache, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	return &RedisCache{client: redis.NewClient(opts), prefix: prefix}, nil
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("redis get failed: %w", err)
+	}
+	return value, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.client.Set(ctx, c.prefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	return nil
+}
+
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
+
+// TieredCache checks a local cache before a shared one and fills the local
+// cache on remote hits.
+type TieredCache struct {
+	local  ResponseCache
+	remote ResponseCache
+	ttl    time.Duration
+}
+
+func NewTieredCache(local, remote ResponseCache, ttl time.Duration) *TieredCache {
+	return &TieredCache{local: local, remote: remote, ttl: ttl}
+}
+
+func (c *TieredCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if value, ok, err := c.local.Get(ctx, key); err == nil && ok {
+		return value, true, nil
+	}
+	value, ok, err := c.remote.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	_ = c.local.Set(ctx, key, value, c.ttl)
+	return value, true, nil
+}
+
+func (c *TieredCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.local.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	return c.remote.Set(ctx, key, value, ttl)
+}
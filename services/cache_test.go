@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(2)
+	cache.Set(ctx, "a", []byte("1"), time.Minute)
+	cache.Set(ctx, "b", []byte("2"), time.Minute)
+	cache.Get(ctx, "a")
+	cache.Set(ctx, "c", []byte("3"), time.Minute)
+
+	if _, ok, _ := cache.Get(ctx, "b"); ok {
+		t.Error("least recently used entry b was kept")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := cache.Get(ctx, key); !ok {
+			t.Errorf("entry %s was evicted", key)
+		}
+	}
+}
+
+func TestLRUCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(10)
+	cache.Set(ctx, "gone", []byte("1"), -time.Second)
+	cache.Set(ctx, "kept", []byte("2"), time.Minute)
+
+	if _, ok, _ := cache.Get(ctx, "gone"); ok {
+		t.Error("expired entry returned")
+	}
+	if value, ok, _ := cache.Get(ctx, "kept"); !ok || string(value) != "2" {
+		t.Errorf("Get(kept) = %q, %v", value, ok)
+	}
+}
+
+func TestTieredCacheFillsLocalFromRemote(t *testing.T) {
+	ctx := context.Background()
+	local, remote := NewLRUCache(10), NewLRUCache(10)
+	cache := NewTieredCache(local, remote, time.Minute)
+	remote.Set(ctx, "k", []byte("v"), time.Minute)
+
+	if value, ok, err := cache.Get(ctx, "k"); err != nil || !ok || string(value) != "v" {
+		t.Fatalf("Get() = %q, %v, %v", value, ok, err)
+	}
+	if _, ok, _ := local.Get(ctx, "k"); !ok {
+		t.Error("remote hit not stored locally")
+	}
+
+	cache.Set(ctx, "new", []byte("x"), time.Minute)
+	if _, ok, _ := remote.Get(ctx, "new"); !ok {
+		t.Error("Set did not reach the remote cache")
+	}
+}
+
+func TestFetchPageUsesCache(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(20)})
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.RetryDelay = 0
+	cfg.CacheTTL = time.Minute
+	client := NewJacadClient(&cfg, NewDiscardWriter(), nil, nil, NewLRUCache(10))
+	ctx := context.Background()
+	endpoint := cfg.Endpoints["ENROLLMENTS"]
+	params := map[string]string{"idPeriodoLetivo": "87"}
+
+	first, _, err := client.FetchPage(ctx, endpoint, 0, 10, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := client.FetchPage(ctx, endpoint, 0, 10, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests := server.Requests("ENROLLMENTS"); requests != 1 {
+		t.Errorf("sent %d requests for the same page, want 1", requests)
+	}
+	if len(first) != len(second) {
+		t.Errorf("cached page has %d enrollments, fetched page %d", len(second), len(first))
+	}
+
+	if _, _, err := client.FetchPage(ctx, endpoint, 1, 10, params); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.FetchPage(WithCacheBypass(ctx), endpoint, 0, 10, params); err != nil {
+		t.Fatal(err)
+	}
+	if requests := server.Requests("ENROLLMENTS"); requests != 3 {
+		t.Errorf("sent %d requests, want another page and the bypassed one to be fetched", requests)
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/notifications"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+var candidateHeaders = []string{"idInscricao", "idEdital", "idCandidato", "nome", "cpf", "email", "telefone", "curso", "status", "classificacao", "dataInscricao", "idOrg"}
+
+// CandidatesResult reports a fetch-candidates job, one sheet per process
+// notice.
+type CandidatesResult struct {
+	TotalFetched int                 `json:"totalFetched"`
+	DryRun       bool                `json:"dryRun"`
+	Editais      []EditalSheetResult `json:"editais"`
+}
+
+// EditalSheetResult reports the inscriptions sheet of one process notice.
+type EditalSheetResult struct {
+	SheetResult
+	IdEdital  int    `json:"idEdital"`
+	Descricao string `json:"descricao,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// CandidatesService writes the inscriptions of each selective process notice
+// (edital) to its own sheet, next to the enrollment sheets.
+type CandidatesService struct {
+	client *JacadClient
+}
+
+func NewCandidatesService(client *JacadClient) *CandidatesService {
+	return &CandidatesService{client: client}
+}
+
+// FetchCandidates fetches the inscriptions of params.IdEdital, or of every
+// notice with a status in Config.EditalStatus, and overwrites one sheet per
+// notice. A notice that fails is reported in its result and does not stop
+// the others.
+func (s *CandidatesService) FetchCandidates(ctx context.Context, params *requests.FetchCandidatesRequest) (*CandidatesResult, error) {
+	startedAt := time.Now()
+	result, err := s.fetchCandidates(ctx, params)
+	s.client.finishJob(ctx, candidatesSummary(result, params.Tenant), params, result.writtenSheets(), nil, params.DryRun, startedAt, err)
+	return result, err
+}
+
+func (s *CandidatesService) fetchCandidates(ctx context.Context, params *requests.FetchCandidatesRequest) (*CandidatesResult, error) {
+	c := s.client
+	logger := logging.FromContext(ctx).With("idEdital", params.IdEdital, "orgId", params.OrgId)
+	logger.Info("Starting candidate fetch")
+	startTime := time.Now()
+
+	if params.BypassCache {
+		ctx = WithCacheBypass(ctx)
+	}
+	ctx = WithTenant(ctx, params.Tenant)
+
+	editais := []models.Period{{IDEdital: params.IdEdital}}
+	if params.IdEdital == 0 {
+		var err error
+		if editais, err = c.orgNotices(ctx, params.OrgId); err != nil {
+			return nil, err
+		}
+		logger.Info("Process notices found", "editais", len(editais))
+	}
+
+	result := &CandidatesResult{DryRun: params.DryRun, Editais: make([]EditalSheetResult, 0, len(editais))}
+	for _, edital := range editais {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		sheet := s.fetchEdital(ctx, params, edital)
+		result.TotalFetched += sheet.Rows
+		result.Editais = append(result.Editais, sheet)
+	}
+
+	logger.Info("Candidates fetched", "editais", len(result.Editais), "candidates", result.TotalFetched, "duration", time.Since(startTime).String())
+	return result, nil
+}
+
+func (s *CandidatesService) fetchEdital(ctx context.Context, params *requests.FetchCandidatesRequest, edital models.Period) EditalSheetResult {
+	c := s.client
+	logger := logging.FromContext(ctx).With("idEdital", edital.IDEdital)
+	result := EditalSheetResult{
+		SheetResult: SheetResult{Sheet: fmt.Sprintf("%s | Edital %d", c.Config.CandidatesSheet, edital.IDEdital), DryRun: params.DryRun},
+		IdEdital:    edital.IDEdital,
+		Descricao:   edital.Descricao,
+	}
+
+	candidates, err := fetchAllPagesOf[models.Candidate](ctx, c, c.Config.Endpoints["CANDIDATES"], map[string]string{"idEdital": strconv.Itoa(edital.IDEdital)})
+	if err != nil {
+		logger.Error("Failed to fetch candidates", "error", err)
+		result.Error = fmt.Sprintf("failed to fetch candidates: %s", err)
+		return result
+	}
+
+	rows := make([][]interface{}, 0, len(candidates))
+	for _, candidate := range candidates {
+		if params.OrgId != 0 && candidate.OrgID != 0 && candidate.OrgID != params.OrgId {
+			continue
+		}
+		rows = append(rows, candidateRow(candidate))
+	}
+	result.Rows = len(rows)
+
+	if params.DryRun {
+		limit := params.PreviewRows
+		if limit <= 0 {
+			limit = c.Config.DryRunPreviewRows
+		}
+		result.Preview = previewRows(candidateHeaders, rows[:min(limit, len(rows))])
+		return result
+	}
+
+	if err := c.Writer.OverwriteSheetData(ctx, result.Sheet, candidateHeaders, rows); err != nil {
+		logger.Error("Failed to write candidates to sheet", "sheet", result.Sheet, "error", err)
+		result.Error = fmt.Sprintf("failed to write candidates to sheet: %s", err)
+		return result
+	}
+	logger.Info("Candidates written", "sheet", result.Sheet, "rows", len(rows))
+	return result
+}
+
+func candidateRow(candidate models.Candidate) []interface{} {
+	var classificacao interface{} = ""
+	if candidate.Classificacao != nil {
+		classificacao = *candidate.Classificacao
+	}
+	return []interface{}{
+		candidate.IdInscricao,
+		candidate.IdEdital,
+		candidate.IdCandidato,
+		utils.GetStringOrEmpty(candidate.Nome),
+		utils.GetStringOrEmpty(candidate.CPF),
+		utils.GetStringOrEmpty(candidate.Email),
+		utils.GetStringOrEmpty(candidate.Telefone),
+		utils.GetStringOrEmpty(candidate.Curso),
+		utils.GetStringOrEmpty(candidate.Status),
+		classificacao,
+		utils.GetTimeOrNilDate(candidate.DataInscricao),
+		candidate.OrgID,
+	}
+}
+
+// writtenSheets lists the notice sheets a fetch-candidates job wrote.
+func (r *CandidatesResult) writtenSheets() []string {
+	if r == nil || r.DryRun {
+		return nil
+	}
+	var sheets []string
+	for _, edital := range r.Editais {
+		if edital.Error == "" {
+			sheets = append(sheets, edital.Sheet)
+		}
+	}
+	return sheets
+}
+
+// candidatesSummary describes a fetch-candidates job for notifications.
+func candidatesSummary(result *CandidatesResult, tenant string) notifications.Summary {
+	summary := notifications.Summary{Job: "fetch-candidates", Tenant: tenant}
+	if result == nil {
+		return summary
+	}
+	summary.RowsFetched = result.TotalFetched
+	for _, edital := range result.Editais {
+		if edital.Error != "" {
+			summary.Failures = append(summary.Failures, fmt.Sprintf("%s: %s", edital.Sheet, edital.Error))
+			continue
+		}
+		summary.RowsWritten += edital.Rows
+	}
+	return summary
+}
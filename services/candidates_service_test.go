@@ -0,0 +1,32 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCandidatesResultSummary(t *testing.T) {
+	result := &CandidatesResult{
+		TotalFetched: 5,
+		Editais: []EditalSheetResult{
+			{SheetResult: SheetResult{Sheet: "Inscrições | Edital 1", Rows: 3}, IdEdital: 1},
+			{SheetResult: SheetResult{Sheet: "Inscrições | Edital 2", Rows: 2}, IdEdital: 2, Error: "failed to write candidates to sheet: quota"},
+		},
+	}
+
+	summary := candidatesSummary(result, "")
+	if summary.RowsFetched != 5 || summary.RowsWritten != 3 {
+		t.Errorf("rows fetched/written = %d/%d, want 5/3", summary.RowsFetched, summary.RowsWritten)
+	}
+	if want := []string{"Inscrições | Edital 2: failed to write candidates to sheet: quota"}; !reflect.DeepEqual(summary.Failures, want) {
+		t.Errorf("Failures = %v, want %v", summary.Failures, want)
+	}
+	if got, want := result.writtenSheets(), []string{"Inscrições | Edital 1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("writtenSheets() = %v, want %v", got, want)
+	}
+
+	result.DryRun = true
+	if got := result.writtenSheets(); got != nil {
+		t.Errorf("writtenSheets() on a dry run = %v, want nil", got)
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+// SerialDate is a date written as a spreadsheet serial number: the days since
+// 1899-12-30, with the time of day as the fraction. The Sheets and XLSX
+// writers format its columns as dates; other writers get a plain number.
+type SerialDate float64
+
+type coerceFunc func(value interface{}) interface{}
+
+// numberLocale holds the separators numbers are written with.
+type numberLocale struct {
+	decimal   string
+	thousands string
+}
+
+var numberLocales = map[string]numberLocale{
+	config.NumberLocalePtBR: {decimal: ",", thousands: "."},
+	config.NumberLocaleEnUS: {decimal: ".", thousands: ","},
+}
+
+// Coerced returns a copy of m that coerces the cells of columns with a Type
+// and, unless dateCells is native, the cells of date fields, which USER_ENTERED
+// writes would otherwise leave to the spreadsheet's locale to interpret. Dates
+// are written per dateCells and text dates in dateLayout. Number columns parse
+// text written with the separators of locale into int64 or float64 cells, and
+// text columns write fractional numbers with its decimal separator. Coercion
+// runs after redaction; cells that cannot be coerced are kept as they are.
+func (m *EnrollmentRowMapper) Coerced(dateCells, dateLayout, locale string) (*EnrollmentRowMapper, error) {
+	separators, ok := numberLocales[locale]
+	if !ok {
+		return nil, fmt.Errorf("unknown number locale '%s'", locale)
+	}
+
+	coerced := *m
+	coerced.coercers = make([]coerceFunc, len(m.columns))
+	typed := false
+	for i, col := range m.columns {
+		typ := col.Type
+		if typ == "" && col.Compute == "" && enrollmentType.Field(m.fieldIndex[i]).Type == datePtrType && dateCells != config.DateCellsNative {
+			typ = config.ColumnDate
+		}
+		switch typ {
+		case "":
+			continue
+		case config.ColumnDate:
+			coerced.coercers[i] = dateCoercion(dateCells, dateLayout)
+		case config.ColumnNumber:
+			coerced.coercers[i] = numberCoercion(separators)
+		case config.ColumnText:
+			coerced.coercers[i] = textCoercion(dateLayout, separators)
+		default:
+			return nil, fmt.Errorf("unknown type '%s' for column '%s'", col.Type, col.Field)
+		}
+		typed = true
+	}
+	if !typed {
+		return m, nil
+	}
+	return &coerced, nil
+}
+
+// dateCoercion writes time.Time cells, and text cells holding a date, per
+// dateCells.
+func dateCoercion(dateCells, layout string) coerceFunc {
+	return func(value interface{}) interface{} {
+		t, ok := cellTime(value, layout)
+		if !ok {
+			return value
+		}
+		switch dateCells {
+		case config.DateCellsSerial:
+			return serialDate(t)
+		case config.DateCellsText:
+			return t.Format(layout)
+		}
+		return t
+	}
+}
+
+// cellTime returns the date a cell holds, parsing text in ISO 8601 or layout.
+func cellTime(value interface{}, layout string) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case string:
+		s := strings.TrimSpace(v)
+		for _, l := range []string{time.RFC3339, "2006-01-02T15:04:05", time.DateTime, time.DateOnly, layout} {
+			if t, err := time.Parse(l, s); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// serialDate converts the wall clock time of t, ignoring its zone, to a
+// spreadsheet serial date.
+func serialDate(t time.Time) SerialDate {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return SerialDate(wall.Sub(sheetsEpoch).Hours() / 24)
+}
+
+// numberCoercion parses text cells written with the separators of locale,
+// e.g. "1.234,5" in pt-BR, into int64 or float64 numbers. Text whose thousands
+// separators do not split the integer part in groups of three, such as "1.5"
+// in pt-BR, is left as it is rather than guessed at.
+func numberCoercion(locale numberLocale) coerceFunc {
+	return func(value interface{}) interface{} {
+		s, ok := value.(string)
+		if !ok {
+			return value
+		}
+		if n, ok := locale.parse(s); ok {
+			return n
+		}
+		return value
+	}
+}
+
+func (l numberLocale) parse(s string) (interface{}, bool) {
+	s = strings.TrimSpace(s)
+	integer, fraction, _ := strings.Cut(s, l.decimal)
+	if strings.Contains(fraction, l.thousands) || strings.Contains(fraction, l.decimal) {
+		return nil, false
+	}
+	if strings.Contains(integer, l.thousands) {
+		groups := strings.Split(strings.TrimLeft(integer, "+-"), l.thousands)
+		if len(groups[0]) == 0 || len(groups[0]) > 3 {
+			return nil, false
+		}
+		for _, group := range groups[1:] {
+			if len(group) != 3 {
+				return nil, false
+			}
+		}
+		s = strings.ReplaceAll(s, l.thousands, "")
+	}
+	if s == "" {
+		return nil, false
+	}
+	if !strings.Contains(s, l.decimal) {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, true
+		}
+	}
+	f, err := strconv.ParseFloat(strings.Replace(s, l.decimal, ".", 1), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	return f, true
+}
+
+// textCoercion writes every cell as text: dates in layout and fractional
+// numbers with the decimal separator of locale.
+func textCoercion(layout string, locale numberLocale) coerceFunc {
+	return func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case nil:
+			return ""
+		case string:
+			return v
+		case time.Time:
+			return v.Format(layout)
+		case float32:
+			return strings.Replace(strconv.FormatFloat(float64(v), 'f', -1, 32), ".", locale.decimal, 1)
+		case float64:
+			return strings.Replace(strconv.FormatFloat(v, 'f', -1, 64), ".", locale.decimal, 1)
+		}
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+func TestEnrollmentRowMapperCoerced(t *testing.T) {
+	matricula := time.Date(2025, time.February, 10, 12, 0, 0, 0, time.UTC)
+	enrollment := models.Enrollment{
+		IdMatricula:   7,
+		RA:            ptr("1.234,5"),
+		Turma:         ptr("2025-03-01"),
+		Curso:         ptr("Direito"),
+		DataMatricula: ptr(utils.Date(matricula)),
+	}
+	columns := []config.Column{
+		{Field: "dataMatricula", Header: "m"},
+		{Field: "ra", Header: "ra", Type: config.ColumnNumber},
+		{Field: "turma", Header: "turma", Type: config.ColumnDate},
+		{Field: "curso", Header: "curso", Type: config.ColumnNumber},
+		{Field: "idMatricula", Header: "id", Type: config.ColumnText},
+		{Field: "dataAtivacao", Header: "a"},
+	}
+
+	tests := []struct {
+		name      string
+		dateCells string
+		locale    string
+		row       []interface{}
+	}{
+		{
+			name:      "native dates keep times and parse typed text",
+			dateCells: config.DateCellsNative,
+			locale:    config.NumberLocalePtBR,
+			row:       []interface{}{matricula, 1234.5, time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), "Direito", "7", nil},
+		},
+		{
+			name:      "serial dates count days from 1899-12-30",
+			dateCells: config.DateCellsSerial,
+			locale:    config.NumberLocalePtBR,
+			row:       []interface{}{SerialDate(45698.5), 1234.5, SerialDate(45717), "Direito", "7", nil},
+		},
+		{
+			name:      "text dates use the layout",
+			dateCells: config.DateCellsText,
+			locale:    config.NumberLocalePtBR,
+			row:       []interface{}{"10/02/2025", 1234.5, "01/03/2025", "Direito", "7", nil},
+		},
+		{
+			name:      "en-US keeps pt-BR separators as text",
+			dateCells: config.DateCellsNative,
+			locale:    config.NumberLocaleEnUS,
+			row:       []interface{}{matricula, "1.234,5", time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), "Direito", "7", nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper, err := NewEnrollmentRowMapper(columns)
+			if err != nil {
+				t.Fatalf("NewEnrollmentRowMapper() = %v", err)
+			}
+			if mapper, err = mapper.Coerced(tt.dateCells, "02/01/2006", tt.locale); err != nil {
+				t.Fatalf("Coerced() = %v", err)
+			}
+			if got := mapper.Row(enrollment); !reflect.DeepEqual(got, tt.row) {
+				t.Errorf("Row() = %#v, want %#v", got, tt.row)
+			}
+		})
+	}
+}
+
+func TestEnrollmentRowMapperCoercedUntyped(t *testing.T) {
+	mapper, err := NewEnrollmentRowMapper([]config.Column{{Field: "dataMatricula", Header: "m"}})
+	if err != nil {
+		t.Fatalf("NewEnrollmentRowMapper() = %v", err)
+	}
+	coerced, err := mapper.Coerced(config.DateCellsNative, "02/01/2006", config.NumberLocalePtBR)
+	if err != nil || coerced != mapper {
+		t.Errorf("Coerced() without types = %p, %v; want the mapper itself", coerced, err)
+	}
+	if _, err := mapper.Coerced(config.DateCellsNative, "02/01/2006", "fr-FR"); err == nil {
+		t.Error("Coerced() with an unknown locale succeeded")
+	}
+}
+
+func TestTextCoercion(t *testing.T) {
+	coerce := textCoercion("02/01/2006", numberLocales[config.NumberLocalePtBR])
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{nil, ""},
+		{1234.5, "1234,5"},
+		{int64(42), "42"},
+		{time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), "01/03/2025"},
+	}
+	for _, tt := range tests {
+		if got := coerce(tt.in); got != tt.want {
+			t.Errorf("textCoercion(%v) = %v, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSheetDateCellsSerialDates(t *testing.T) {
+	rows := [][]interface{}{{"Ana", SerialDate(45698)}}
+	converted, columns := sheetDateCells(rows)
+	if converted[0][1] != 45698.0 || !reflect.DeepEqual(columns, []int{1}) {
+		t.Errorf("sheetDateCells() = %v, %v; want the serial as a number in date column 1", converted, columns)
+	}
+	if _, ok := rows[0][1].(SerialDate); !ok {
+		t.Error("sheetDateCells() changed the caller's rows")
+	}
+}
+
+func TestNumberLocaleParse(t *testing.T) {
+	tests := []struct {
+		locale string
+		in     string
+		want   interface{}
+	}{
+		{config.NumberLocalePtBR, "1.234,5", 1234.5},
+		{config.NumberLocalePtBR, "1.234.567", int64(1234567)},
+		{config.NumberLocalePtBR, "-12.345,67", -12345.67},
+		{config.NumberLocalePtBR, "2,50", 2.5},
+		{config.NumberLocalePtBR, " 42 ", int64(42)},
+		{config.NumberLocalePtBR, "1.5", nil},
+		{config.NumberLocalePtBR, "2.50", nil},
+		{config.NumberLocalePtBR, "1234.567", nil},
+		{config.NumberLocalePtBR, ".123", nil},
+		{config.NumberLocalePtBR, "1,2.3", nil},
+		{config.NumberLocalePtBR, "1,2,3", nil},
+		{config.NumberLocaleEnUS, "1,234.5", 1234.5},
+		{config.NumberLocaleEnUS, "1.5", 1.5},
+		{config.NumberLocaleEnUS, "1,5", nil},
+		{config.NumberLocaleEnUS, "12,34", nil},
+		{config.NumberLocaleEnUS, "", nil},
+	}
+	for _, tt := range tests {
+		got, ok := numberLocales[tt.locale].parse(tt.in)
+		if tt.want == nil {
+			if ok {
+				t.Errorf("%s parse(%q) = %v, want the cell left as text", tt.locale, tt.in, got)
+			}
+			continue
+		}
+		if !ok || got != tt.want {
+			t.Errorf("%s parse(%q) = %#v, %v; want %#v", tt.locale, tt.in, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// CheckpointStore persists fetched pages and written sheets on disk so that a
+// failed job can be resumed without re-fetching everything.
+type CheckpointStore struct {
+	dir string
+}
+
+func NewCheckpointStore(dir string) *CheckpointStore {
+	return &CheckpointStore{dir: dir}
+}
+
+type checkpointManifest struct {
+	Key            string    `json:"key"`
+	TotalPages     int       `json:"totalPages"`
+	PageSize       int       `json:"pageSize"`
+	CompletedPages []int     `json:"completedPages"`
+	WrittenSheets  []string  `json:"writtenSheets"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+type Checkpoint struct {
+	dir       string
+	mu        sync.Mutex
+	manifest  checkpointManifest
+	completed map[int]bool
+	written   map[string]bool
+}
+
+// CheckpointKey identifies a job by its Jacad query, so retries of the same
+// fetch share a checkpoint. scope separates identical queries against
+// different Jacad instances.
+func CheckpointKey(scope string, params map[string]string, pageSize int) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(scope)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s&", k, params[k])
+	}
+	fmt.Fprintf(&b, "pageSize=%d", pageSize)
+
+	sum := sha1.Sum([]byte(b.String()))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// Load returns the checkpoint for key, or nil when none exists.
+func (s *CheckpointStore) Load(key string) (*Checkpoint, error) {
+	dir := filepath.Join(s.dir, key)
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoint manifest for '%s': %w", key, err)
+	}
+
+	var manifest checkpointManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint manifest for '%s': %w", key, err)
+	}
+
+	cp := &Checkpoint{
+		dir:       dir,
+		manifest:  manifest,
+		completed: make(map[int]bool, len(manifest.CompletedPages)),
+		written:   make(map[string]bool, len(manifest.WrittenSheets)),
+	}
+	for _, page := range manifest.CompletedPages {
+		cp.completed[page] = true
+	}
+	for _, sheet := range manifest.WrittenSheets {
+		cp.written[sheet] = true
+	}
+	return cp, nil
+}
+
+// Create starts a fresh checkpoint for key, discarding any previous one.
+func (s *CheckpointStore) Create(key string, totalPages, pageSize int) (*Checkpoint, error) {
+	if err := s.Delete(key); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(s.dir, key)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory '%s': %w", dir, err)
+	}
+
+	cp := &Checkpoint{
+		dir: dir,
+		manifest: checkpointManifest{
+			Key:        key,
+			TotalPages: totalPages,
+			PageSize:   pageSize,
+		},
+		completed: make(map[int]bool),
+		written:   make(map[string]bool),
+	}
+	if err := cp.saveManifest(); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+func (s *CheckpointStore) Delete(key string) error {
+	if err := os.RemoveAll(filepath.Join(s.dir, key)); err != nil {
+		return fmt.Errorf("failed to delete checkpoint '%s': %w", key, err)
+	}
+	return nil
+}
+
+func (cp *Checkpoint) Key() string {
+	return cp.manifest.Key
+}
+
+func (cp *Checkpoint) TotalPages() int {
+	return cp.manifest.TotalPages
+}
+
+func (cp *Checkpoint) HasPage(page int) bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.completed[page]
+}
+
+func (cp *Checkpoint) CompletedPages() int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return len(cp.completed)
+}
+
+func (cp *Checkpoint) SavePage(page int, data []models.Enrollment) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode page %d for checkpoint: %w", page, err)
+	}
+	if err := os.WriteFile(cp.pagePath(page), encoded, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint page %d: %w", page, err)
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.completed[page] = true
+	return cp.saveManifestLocked()
+}
+
+// LoadPages returns the enrollments of every completed page, in page order.
+func (cp *Checkpoint) LoadPages() ([]models.Enrollment, error) {
+	cp.mu.Lock()
+	pages := make([]int, 0, len(cp.completed))
+	for page := range cp.completed {
+		pages = append(pages, page)
+	}
+	cp.mu.Unlock()
+	sort.Ints(pages)
+
+	var all []models.Enrollment
+	for _, page := range pages {
+		data, err := os.ReadFile(cp.pagePath(page))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read checkpoint page %d: %w", page, err)
+		}
+		var elements []models.Enrollment
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return nil, fmt.Errorf("failed to parse checkpoint page %d: %w", page, err)
+		}
+		all = append(all, elements...)
+	}
+	return all, nil
+}
+
+func (cp *Checkpoint) SheetWritten(sheet string) bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.written[sheet]
+}
+
+func (cp *Checkpoint) MarkSheetWritten(sheet string) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.written[sheet] = true
+	return cp.saveManifestLocked()
+}
+
+func (cp *Checkpoint) pagePath(page int) string {
+	return filepath.Join(cp.dir, fmt.Sprintf("page-%05d.json", page))
+}
+
+func (cp *Checkpoint) saveManifest() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.saveManifestLocked()
+}
+
+func (cp *Checkpoint) saveManifestLocked() error {
+	cp.manifest.CompletedPages = cp.manifest.CompletedPages[:0]
+	for page := range cp.completed {
+		cp.manifest.CompletedPages = append(cp.manifest.CompletedPages, page)
+	}
+	sort.Ints(cp.manifest.CompletedPages)
+
+	cp.manifest.WrittenSheets = cp.manifest.WrittenSheets[:0]
+	for sheet := range cp.written {
+		cp.manifest.WrittenSheets = append(cp.manifest.WrittenSheets, sheet)
+	}
+	sort.Strings(cp.manifest.WrittenSheets)
+	cp.manifest.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(cp.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint manifest: %w", err)
+	}
+
+	path := filepath.Join(cp.dir, "manifest.json")
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint manifest: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+func TestCheckpointStore(t *testing.T) {
+	store := NewCheckpointStore(t.TempDir())
+	key := CheckpointKey("", map[string]string{"idPeriodoLetivo": "87"}, 50)
+
+	if cp, err := store.Load(key); err != nil || cp != nil {
+		t.Fatalf("Load() before Create = %v, %v; want nil", cp, err)
+	}
+	cp, err := store.Create(key, 3, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := func(n int) []models.Enrollment { return []models.Enrollment{{IdMatricula: n}} }
+	if err := cp.SavePage(2, id(3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.SavePage(0, id(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.MarkSheetWritten("EAD"); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := store.Load(key)
+	if err != nil || loaded == nil {
+		t.Fatalf("Load() = %v, %v", loaded, err)
+	}
+	if loaded.TotalPages() != 3 || loaded.CompletedPages() != 2 || !loaded.HasPage(2) || loaded.HasPage(1) {
+		t.Errorf("loaded pages: total %d, completed %d", loaded.TotalPages(), loaded.CompletedPages())
+	}
+	if !loaded.SheetWritten("EAD") || loaded.SheetWritten("PÓS") {
+		t.Error("written sheets not restored")
+	}
+	pages, err := loaded.LoadPages()
+	if err != nil || len(pages) != 2 || pages[0].IdMatricula != 1 || pages[1].IdMatricula != 3 {
+		t.Errorf("LoadPages() = %+v, %v; want pages 0 and 2 in order", pages, err)
+	}
+
+	if err := store.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	if cp, err := store.Load(key); err != nil || cp != nil {
+		t.Errorf("Load() after Delete = %v, %v; want nil", cp, err)
+	}
+}
+
+func TestCheckpointKeySeparatesScopes(t *testing.T) {
+	params := map[string]string{"idPeriodoLetivo": "87", "statusMatricula": "ATIVA"}
+	if CheckpointKey("", params, 50) == CheckpointKey("tenant:b", params, 50) {
+		t.Error("same key for different scopes")
+	}
+	if CheckpointKey("", params, 50) == CheckpointKey("", params, 100) {
+		t.Error("same key for different page sizes")
+	}
+}
+
+// TestFetchEnrollmentsResume fails one sheet, then resumes: the pages come
+// from the checkpoint and only the failed sheet is written again.
+func TestFetchEnrollmentsResume(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.PageSize = 50
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	writer := NewFakeSheetWriter()
+	checkpoints := NewCheckpointStore(filepath.Join(dir, "checkpoints"))
+	client := NewJacadClient(&cfg, writer, NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), checkpoints, nil)
+	params := func(resume bool) *requests.FetchEnrollmentsRequest {
+		return &requests.FetchEnrollmentsRequest{
+			IdPeriodoLetivo: 87,
+			StatusMatricula: "ATIVA",
+			Mode:            requests.SyncModeFull,
+			WriteMode:       requests.WriteModeAtomic,
+			GroupBy:         requests.GroupByOrganization,
+			Resume:          resume,
+		}
+	}
+
+	overwrites := 0
+	writer.FailOn = func(method, sheet string) error {
+		if method == "OverwriteSheetData" {
+			if overwrites++; overwrites == 2 {
+				return errors.New("quota exceeded")
+			}
+		}
+		return nil
+	}
+	first, err := client.FetchEnrollmentsFiltered(context.Background(), params(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failedSheet string
+	for _, org := range first.Organizations {
+		if org.Error != "" {
+			failedSheet = org.Sheet
+		}
+	}
+	if failedSheet == "" || len(first.Organizations) < 3 {
+		t.Fatalf("want one of several sheets to fail: %+v", first.Organizations)
+	}
+
+	writer.FailOn = nil
+	before := server.Requests("ENROLLMENTS")
+	callsBefore := len(writer.Calls())
+	second, err := client.FetchEnrollmentsFiltered(context.Background(), params(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages := server.Requests("ENROLLMENTS") - before; pages > 1 {
+		t.Errorf("resume fetched %d enrollment pages, want only the one that counts the pages", pages)
+	}
+	if second.TotalFetched != first.TotalFetched {
+		t.Errorf("resume fetched %d enrollments, first run %d", second.TotalFetched, first.TotalFetched)
+	}
+	var written []string
+	for _, call := range writer.Calls()[callsBefore:] {
+		if call.Method == "OverwriteSheetData" {
+			written = append(written, call.Sheet)
+		}
+	}
+	if !slices.Equal(written, []string{failedSheet}) {
+		t.Errorf("resume wrote %v, want only %q", written, failedSheet)
+	}
+	for _, org := range second.Organizations {
+		if org.Error != "" || org.Resumed == (org.Sheet == failedSheet) {
+			t.Errorf("sheet %q: resumed %v, error %q", org.Sheet, org.Resumed, org.Error)
+		}
+	}
+
+	entries, _ := filepath.Glob(filepath.Join(dir, "checkpoints", "*"))
+	if len(entries) != 0 {
+		t.Errorf("checkpoint kept after a successful resume: %v", entries)
+	}
+}
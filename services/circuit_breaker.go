@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned for Jacad requests rejected while the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("jacad circuit breaker is open")
+
+const (
+	CircuitClosed   = "closed"
+	CircuitHalfOpen = "half_open"
+	CircuitOpen     = "open"
+)
+
+// circuitStateValues maps states to the jacad_circuit_breaker_state gauge.
+var circuitStateValues = map[string]float64{
+	CircuitClosed:   0,
+	CircuitHalfOpen: 1,
+	CircuitOpen:     2,
+}
+
+// CircuitBreaker stops calling Jacad after threshold consecutive failures
+// (network errors, 429 and 5xx). While open it fails fast; after cooldown a
+// single probe request is let through, closing the breaker on success and
+// reopening it on failure. A nil *CircuitBreaker always allows requests.
+type CircuitBreaker struct {
+	mu sync.Mutex
+	// apiBase labels the breaker's metrics with the Jacad instance it
+	// guards.
+	apiBase   string
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	probeAt   time.Time
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return newInstanceCircuitBreaker("", threshold, cooldown)
+}
+
+// newCircuitBreakers keeps a breaker per Jacad instance, so one failing
+// deployment does not make calls to the others fail fast.
+func newCircuitBreakers(threshold int, cooldown time.Duration) *perInstance[*CircuitBreaker] {
+	return newPerInstance(func(apiBase string) *CircuitBreaker {
+		return newInstanceCircuitBreaker(apiBase, threshold, cooldown)
+	})
+}
+
+func newInstanceCircuitBreaker(apiBase string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	b := &CircuitBreaker{apiBase: apiBase, threshold: threshold, cooldown: cooldown, state: CircuitClosed}
+	jacadCircuitBreakerState.Set(circuitStateValues[CircuitClosed], apiBase)
+	return b
+}
+
+// Allow reports whether a request may be sent now.
+func (b *CircuitBreaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if wait := b.cooldown - time.Since(b.openedAt); wait > 0 {
+			jacadCircuitBreakerRejectionsTotal.Inc(b.apiBase)
+			return fmt.Errorf("%w after %d consecutive failures; retrying in %s", ErrCircuitOpen, b.failures, wait.Round(time.Second))
+		}
+		b.setStateLocked(CircuitHalfOpen)
+		b.probeAt = time.Now()
+		return nil
+	case CircuitHalfOpen:
+		// A probe that never reported back (e.g. cancelled) must not keep the
+		// breaker half-open forever.
+		if time.Since(b.probeAt) < b.cooldown {
+			jacadCircuitBreakerRejectionsTotal.Inc(b.apiBase)
+			return fmt.Errorf("%w: waiting for probe request", ErrCircuitOpen)
+		}
+		b.probeAt = time.Now()
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) RecordSuccess() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	if b.state != CircuitClosed {
+		b.setStateLocked(CircuitClosed)
+	}
+}
+
+func (b *CircuitBreaker) RecordFailure() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == CircuitHalfOpen || (b.state == CircuitClosed && b.failures >= b.threshold) {
+		b.openedAt = time.Now()
+		b.setStateLocked(CircuitOpen)
+	}
+}
+
+func (b *CircuitBreaker) State() string {
+	if b == nil {
+		return CircuitClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *CircuitBreaker) setStateLocked(state string) {
+	b.state = state
+	jacadCircuitBreakerState.Set(circuitStateValues[state], b.apiBase)
+	jacadCircuitBreakerTransitionsTotal.Inc(b.apiBase, state)
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps func(b *CircuitBreaker)
+		want  string
+		allow bool
+	}{
+		{
+			name:  "stays closed below the threshold",
+			steps: func(b *CircuitBreaker) { b.RecordFailure(); b.RecordFailure() },
+			want:  CircuitClosed,
+			allow: true,
+		},
+		{
+			name:  "opens at the threshold",
+			steps: func(b *CircuitBreaker) { b.RecordFailure(); b.RecordFailure(); b.RecordFailure() },
+			want:  CircuitOpen,
+			allow: false,
+		},
+		{
+			name: "success resets the failure count",
+			steps: func(b *CircuitBreaker) {
+				b.RecordFailure()
+				b.RecordFailure()
+				b.RecordSuccess()
+				b.RecordFailure()
+				b.RecordFailure()
+			},
+			want:  CircuitClosed,
+			allow: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewCircuitBreaker(3, time.Hour)
+			tt.steps(b)
+			if got := b.State(); got != tt.want {
+				t.Errorf("State() = %q, want %q", got, tt.want)
+			}
+			err := b.Allow()
+			if (err == nil) != tt.allow {
+				t.Errorf("Allow() = %v, want allowed %v", err, tt.allow)
+			}
+			if err != nil && !errors.Is(err, ErrCircuitOpen) {
+				t.Errorf("Allow() = %v, want ErrCircuitOpen", err)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	tests := []struct {
+		name   string
+		result func(b *CircuitBreaker)
+		want   string
+	}{
+		{name: "probe success closes", result: (*CircuitBreaker).RecordSuccess, want: CircuitClosed},
+		{name: "probe failure reopens", result: (*CircuitBreaker).RecordFailure, want: CircuitOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewCircuitBreaker(1, 20*time.Millisecond)
+			b.RecordFailure()
+			time.Sleep(30 * time.Millisecond)
+
+			if err := b.Allow(); err != nil {
+				t.Fatalf("Allow() after cooldown = %v, want probe allowed", err)
+			}
+			if got := b.State(); got != CircuitHalfOpen {
+				t.Fatalf("State() = %q, want %q", got, CircuitHalfOpen)
+			}
+			if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("second Allow() while probing = %v, want ErrCircuitOpen", err)
+			}
+
+			tt.result(b)
+			if got := b.State(); got != tt.want {
+				t.Errorf("State() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilCircuitBreaker(t *testing.T) {
+	b := NewCircuitBreaker(0, time.Second)
+	b.RecordFailure()
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow() = %v, want nil", err)
+	}
+	if got := b.State(); got != CircuitClosed {
+		t.Errorf("State() = %q, want %q", got, CircuitClosed)
+	}
+}
+
+// TestCircuitBreakerPerInstance checks that a failing Jacad deployment only
+// trips the breaker of the tenants it serves.
+func TestCircuitBreakerPerInstance(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer up.Close()
+
+	cfg := config.Defaults()
+	cfg.APIBase = up.URL
+	cfg.JacadProfiles = map[string]config.JacadProfile{"down": {APIBase: down.URL}, "shared": {APIBase: down.URL}}
+	cfg.JacadBreakerThreshold = 1
+	cfg.JacadBreakerCooldown = time.Hour
+	cfg.MaxRetries = 0
+	cfg.JacadRateLimitRPS = 0
+	c := NewJacadClient(&cfg, NewFakeSheetWriter(), nil, nil, nil)
+
+	downCtx := WithTenant(context.Background(), "down")
+	if _, err := c.MakeRequest(downCtx, http.MethodGet, down.URL+"/academico/matriculas", nil, nil); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("first request to the failing instance = %v, want its 503", err)
+	}
+	if _, err := c.MakeRequest(downCtx, http.MethodGet, down.URL+"/academico/matriculas", nil, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("second request to the failing instance = %v, want ErrCircuitOpen", err)
+	}
+	if got := c.CircuitState(WithTenant(context.Background(), "shared")); got != CircuitOpen {
+		t.Errorf("breaker of a tenant on the same instance = %q, want %q", got, CircuitOpen)
+	}
+
+	if got := c.CircuitState(context.Background()); got != CircuitClosed {
+		t.Errorf("breaker of the default instance = %q, want %q", got, CircuitClosed)
+	}
+	if _, err := c.MakeRequest(context.Background(), http.MethodGet, up.URL+"/academico/matriculas", nil, nil); err != nil {
+		t.Errorf("request to the healthy instance = %v, want it sent", err)
+	}
+}
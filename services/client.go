@@ -2,18 +2,24 @@ package services
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
 	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/notifications"
+	"github.com/SamuelLeutner/fetch-student-data/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SheetWriter interface {
@@ -21,37 +27,189 @@ type SheetWriter interface {
 	Clear(ctx context.Context, sheetName string) error
 	SetHeaders(ctx context.Context, sheetName string, headers []string) error
 	AppendRows(ctx context.Context, sheetName string, rows [][]interface{}) error
-	OverwriteSheetData(ctx context.Context, sheetName string, headers []string, rows [][]interface{}) error 
+	OverwriteSheetData(ctx context.Context, sheetName string, headers []string, rows [][]interface{}) error
+	UpsertRows(ctx context.Context, sheetName string, headers []string, keyColumn string, rows [][]interface{}) error
+}
+
+// Flusher is implemented by writers that buffer rows and must push them to
+// their destination before the process exits.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// BufferDiscarder is implemented by writers that buffer rows, so a cancelled
+// job's pending rows are dropped instead of being flushed later.
+type BufferDiscarder interface {
+	DiscardBuffered(ctx context.Context) int
+}
+
+// ErrUnauthorized is returned by MakeRequest when Jacad answers 401.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ErrNotFound is returned by MakeRequest when Jacad answers 404.
+var ErrNotFound = errors.New("not found")
+
+// RowCounter is implemented by writers that can report how many data rows a
+// sheet holds, used to verify streamed writes.
+type RowCounter interface {
+	CountRows(ctx context.Context, sheetName string) (int, error)
+}
+
+// TabSplitter is implemented by writers that split overwrites into numbered
+// tabs past a row limit, so streamed writes can roll over the same way.
+type TabSplitter interface {
+	// MaxRowsPerTab returns the row limit per tab, or 0 when tabs are not
+	// split.
+	MaxRowsPerTab() int
+	// SplitTab renames sheetName to its first numbered tab.
+	SplitTab(ctx context.Context, sheetName string) error
+}
+
+// SheetLeaser is implemented by writers that can hold a tab's lock across
+// several calls, so a streamed job's header, appends and row count are not
+// interleaved with another job's writes to the same tab.
+type SheetLeaser interface {
+	// LeaseSheet waits up to wait, or for as long as ctx allows when wait is
+	// 0, for sheetName's lock and returns a context whose calls on the tab
+	// reuse it. release must be called once the job is done with the tab.
+	LeaseSheet(ctx context.Context, sheetName string, wait time.Duration) (leased context.Context, release func(), err error)
+}
+
+// EnrollmentWriter is implemented by writers that lay out enrollments
+// themselves, such as partitioned files, instead of overwriting a sheet with
+// the mapped rows.
+type EnrollmentWriter interface {
+	WriteEnrollments(ctx context.Context, sheetName string, mapper *EnrollmentRowMapper, data []models.Enrollment) error
 }
 
 type JacadClient struct {
 	Config      *config.Config
 	Client      *http.Client
 	Writer      SheetWriter
-	token       string
-	tokenExpiry time.Time
-	muAuth      sync.Mutex
+	State       SyncStateStore
+	Checkpoints *CheckpointStore
+	Cache       ResponseCache
+	// Archiver, when set, receives a gzip copy of every page body fetched.
+	Archiver PageArchiver
+	// Notifications, when set, receives a summary of every finished job
+	// except dry runs.
+	Notifications *notifications.Dispatcher
+	// History, when set, records every finished fetch job.
+	History JobHistoryStore
+	// UserToken, when set, replaces USER_TOKEN for the default tenant and is
+	// read at every login, so a rotated token is picked up.
+	UserToken   *Secret
+	limiters    *perInstance[*RateLimiter]
+	concurrency *ConcurrencyController
+	endpoints   *EndpointLimiter
+	progress    *ProgressHub
+	breakers    *perInstance[*CircuitBreaker]
+	transfers   transferTracker
+	meta        metadataCache
+	versions    apiVersions
+	// auth holds one token cache per tenant; muAuth guards the map.
+	auth   map[string]*authState
+	muAuth sync.Mutex
 }
 
-func NewJacadClient(config *config.Config, writer SheetWriter) *JacadClient {
+func NewJacadClient(config *config.Config, writer SheetWriter, state SyncStateStore, checkpoints *CheckpointStore, cache ResponseCache) *JacadClient {
 	return &JacadClient{
-		Config: config,
-		Client: &http.Client{Timeout: 60 * time.Second},
-		Writer: writer,
+		Config:      config,
+		Client:      NewJacadHTTPClient(config, nil),
+		Writer:      writer,
+		State:       state,
+		Checkpoints: checkpoints,
+		Cache:       cache,
+		limiters:    newPerInstance(func(string) *RateLimiter { return NewRateLimiter(config.JacadRateLimitRPS, config.JacadRateLimitBurst) }),
+		concurrency: NewConcurrencyController(config.JacadMinConcurrency, config.MaxParallelRequests, config.JacadConcurrencyCooldown),
+		endpoints:   NewEndpointLimiter(config.Endpoints, config.JacadEndpointConcurrency),
+		progress:    NewProgressHub(),
+		breakers:    newCircuitBreakers(config.JacadBreakerThreshold, config.JacadBreakerCooldown),
+		auth:        make(map[string]*authState),
 	}
 }
 
+// CircuitState reports the state of the circuit breaker of the Jacad
+// instance ctx's calls go to.
+func (c *JacadClient) CircuitState(ctx context.Context) string {
+	return c.breakers.get(c.apiBase(ctx)).State()
+}
+
+// MakeRequest sends a Jacad request, retrying throttled and failed attempts.
+// The call holds a slot of its endpoint's limit and of the global limit until
+// it returns. The whole call, slot, rate limiter and retry waits included, is
+// traced as one span with an event per attempt.
 func (c *JacadClient) MakeRequest(ctx context.Context, method, url string, headers map[string]string, body io.Reader) ([]byte, error) {
+	resp, err := c.doRequest(ctx, method, url, headers, body)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// jacadResponse is a successful Jacad response; Status may be 304 for
+// conditional requests, with an empty Body.
+type jacadResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// doRequest is MakeRequest returning the response status and headers too.
+// With JacadCompression set it asks for gzip and decodes the body itself,
+// counting the bytes in the job's TransferStats.
+func (c *JacadClient) doRequest(ctx context.Context, method, url string, headers map[string]string, body io.Reader) (_ *jacadResponse, err error) {
 	var lastErr error
+	logger := logging.FromContext(ctx).With("method", method, "url", strings.Split(url, "?")[0])
+	endpoint := c.endpointLabel(url)
+	apiBase := c.apiBase(ctx)
+	breaker, limiter := c.breakers.get(apiBase), c.limiters.get(apiBase)
+
+	ctx, span := tracing.Start(ctx, "jacad "+method+" "+endpoint,
+		attribute.String("http.request.method", method),
+		attribute.String("jacad.endpoint", endpoint),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	release, err := c.acquireRequestSlot(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("request '%s %s' cancelled while waiting for a concurrency slot: %w", method, strings.Split(url, "?")[0], err)
+	}
+	defer release()
 
 	for attempt := 0; attempt <= c.Config.MaxRetries; attempt++ {
+		// askedWait is the Retry-After of a throttled attempt.
+		var askedWait time.Duration
+		var asked bool
 		select {
 		case <-ctx.Done():
-			log.Printf("Request '%s %s' cancelled via context before attempt %d: %v", method, strings.Split(url, "?")[0], attempt+1, ctx.Err())
+			logger.Warn("Request cancelled via context before attempt", "attempt", attempt+1, "error", ctx.Err())
 			return nil, fmt.Errorf("request '%s %s' cancelled via context: %w", method, strings.Split(url, "?")[0], ctx.Err())
 		default:
 		}
 
+		if err := breaker.Allow(); err != nil {
+			logger.Warn("Request rejected by circuit breaker", "error", err)
+			return nil, fmt.Errorf("request '%s %s' not sent: %w", method, strings.Split(url, "?")[0], err)
+		}
+
+		waitStarted := time.Now()
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("request '%s %s' cancelled while waiting for rate limiter: %w", method, strings.Split(url, "?")[0], err)
+		}
+		rateLimitWait := time.Since(waitStarted)
+		jacadRateLimitWait.Observe(rateLimitWait.Seconds(), endpoint)
+		span.AddEvent("attempt", trace.WithAttributes(
+			attribute.Int("attempt", attempt+1),
+			attribute.Float64("rate_limit_wait_seconds", rateLimitWait.Seconds()),
+		))
+
+		// Retries resend the body from the start when it can be rewound.
+		if seeker, ok := body.(io.Seeker); ok && attempt > 0 {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, fmt.Errorf("error rewinding request body on attempt %d: %w", attempt+1, err)
+			}
+		}
 		req, err := http.NewRequestWithContext(ctx, method, url, body)
 		if err != nil {
 			return nil, fmt.Errorf("error creating request on attempt %d: %w", attempt+1, err)
@@ -62,15 +220,41 @@ func (c *JacadClient) MakeRequest(ctx context.Context, method, url string, heade
 				req.Header.Set(key, value)
 			}
 		}
+		if c.Config.JacadCompression && req.Header.Get("Accept-Encoding") == "" {
+			req.Header.Set("Accept-Encoding", "gzip")
+		}
+		tracing.Inject(ctx, propagation.HeaderCarrier(req.Header))
 
-		log.Printf("Request (%s): %s (Attempt %d/%d)...", method, strings.Split(url, "?")[0], attempt+1, c.Config.MaxRetries+1)
+		logger.Debug("Sending request", "attempt", attempt+1, "maxAttempts", c.Config.MaxRetries+1)
 
+		started := time.Now()
 		resp, err := c.Client.Do(req)
+		jacadRequestDuration.Observe(time.Since(started).Seconds(), endpoint)
+		if err != nil {
+			jacadRequestsTotal.Inc(endpoint, method, "error")
+			if ctx.Err() == nil {
+				c.concurrency.RecordThrottled()
+				breaker.RecordFailure()
+			}
+		} else {
+			jacadRequestsTotal.Inc(endpoint, method, strconv.Itoa(resp.StatusCode))
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+			if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+				c.concurrency.RecordThrottled()
+				breaker.RecordFailure()
+			} else {
+				if resp.StatusCode < 400 {
+					c.concurrency.RecordSuccess()
+				}
+				breaker.RecordSuccess()
+			}
+		}
 
 		if err != nil {
 			lastErr = fmt.Errorf("http client error on attempt %d: %w", attempt+1, err)
 		} else if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
-			bodyBytes, readErr := io.ReadAll(resp.Body)
+			askedWait, asked = retryAfter(resp.StatusCode, resp.Header, time.Now())
+			bodyBytes, _, readErr := readResponseBody(resp)
 			resp.Body.Close()
 			if readErr == nil {
 				lastErr = fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
@@ -78,36 +262,53 @@ func (c *JacadClient) MakeRequest(ctx context.Context, method, url string, heade
 				lastErr = fmt.Errorf("HTTP %d: Error reading body: %w", resp.StatusCode, readErr)
 			}
 		} else if resp.StatusCode == http.StatusUnauthorized {
-			bodyBytes, readErr := io.ReadAll(resp.Body)
+			bodyBytes, _, readErr := readResponseBody(resp)
 			resp.Body.Close()
 			if readErr != nil {
-				return nil, fmt.Errorf("HTTP %d: error reading error response body: %w", resp.StatusCode, readErr)
+				return nil, fmt.Errorf("%w: HTTP %d: error reading error response body: %v", ErrUnauthorized, resp.StatusCode, readErr)
 			}
-			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
+			return nil, fmt.Errorf("%w: HTTP %d: %s", ErrUnauthorized, resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
+		} else if resp.StatusCode == http.StatusNotFound {
+			bodyBytes, _, readErr := readResponseBody(resp)
+			resp.Body.Close()
+			if readErr != nil {
+				return nil, fmt.Errorf("%w: HTTP %d: error reading error response body: %v", ErrNotFound, resp.StatusCode, readErr)
+			}
+			return nil, fmt.Errorf("%w: HTTP %d: %s", ErrNotFound, resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
 		} else if resp.StatusCode >= 400 {
-			bodyBytes, readErr := io.ReadAll(resp.Body)
+			bodyBytes, _, readErr := readResponseBody(resp)
 			resp.Body.Close()
 			if readErr != nil {
 				return nil, fmt.Errorf("HTTP %d: error reading error response body: %w", resp.StatusCode, readErr)
 			}
-			log.Printf("HTTP %d error: %s", resp.StatusCode, string(bodyBytes))
+			logger.Error("HTTP error response", "status", resp.StatusCode, "body", string(bodyBytes))
 			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
 		} else {
 			defer resp.Body.Close()
-			bodyBytes, err := io.ReadAll(resp.Body)
+			bodyBytes, wire, err := readResponseBody(resp)
 			if err != nil {
 				return nil, fmt.Errorf("error reading response body on success: %w", err)
 			}
-			return bodyBytes, nil
+			c.transfers.add(ctx, func(s *TransferStats) {
+				s.Requests++
+				s.BytesReceived += wire
+				s.BytesDecoded += int64(len(bodyBytes))
+				s.BytesSaved += max(int64(len(bodyBytes))-wire, 0)
+			})
+			return &jacadResponse{Status: resp.StatusCode, Header: resp.Header, Body: bodyBytes}, nil
 		}
 
 		if attempt < c.Config.MaxRetries {
 			delay := c.Config.RetryDelay * time.Duration(1<<attempt)
-			log.Printf("Request failed (attempt %d/%d): %v. Waiting %s before retrying...", attempt+1, c.Config.MaxRetries+1, lastErr, delay)
+			if asked {
+				delay = askedWait
+			}
+			logger.Warn("Request failed, waiting before retrying", "attempt", attempt+1, "maxAttempts", c.Config.MaxRetries+1, "error", lastErr, "delay", delay.String(), "retryAfter", asked)
+			jacadRetriesTotal.Inc(endpoint)
 			select {
 			case <-time.After(delay):
 			case <-ctx.Done():
-				log.Printf("Context cancelled during retry wait for %s: %v", url, ctx.Err())
+				logger.Warn("Context cancelled during retry wait", "error", ctx.Err())
 				return nil, fmt.Errorf("request cancelled during retry wait after %d attempts for %s: %w", attempt+1, url, ctx.Err())
 			}
 		} else {
@@ -118,40 +319,162 @@ func (c *JacadClient) MakeRequest(ctx context.Context, method, url string, heade
 }
 
 func (c *JacadClient) FetchPage(ctx context.Context, endpoint string, page, pageSize int, params map[string]string) ([]models.Enrollment, *models.Page, error) {
+	return fetchPageOf[models.Enrollment](ctx, c, endpoint, page, pageSize, params)
+}
+
+// fetchPageOf fetches one page of a paginated Jacad listing and decodes its
+// elements as T.
+func fetchPageOf[T any](ctx context.Context, c *JacadClient, endpoint string, page, pageSize int, params map[string]string) ([]T, *models.Page, error) {
+	body, version, err := c.fetchPageBody(ctx, endpoint, page, pageSize, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	elements, pageInfo, err := models.DecodePage[T](version, body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing API %s response from page %d: %w", version, page, err)
+	}
+
+	return elements, pageInfo, nil
+}
+
+type pageSizeKey struct{}
+
+// WithPageSize makes Jacad listings fetched with ctx use size instead of the
+// configured page size.
+func WithPageSize(ctx context.Context, size int) context.Context {
+	return context.WithValue(ctx, pageSizeKey{}, size)
+}
+
+func (c *JacadClient) pageSize(ctx context.Context) int {
+	if size, ok := ctx.Value(pageSizeKey{}).(int); ok && size > 0 {
+		return size
+	}
+	return c.Config.PageSize
+}
+
+type pageRangeKey struct{}
+
+// WithPageRange makes enrollment fetches with ctx fetch only the pages from
+// start up to end exclusive of each query; end 0 means up to the last page.
+func WithPageRange(ctx context.Context, start, end int) context.Context {
+	return context.WithValue(ctx, pageRangeKey{}, [2]int{start, end})
+}
+
+func pageRange(ctx context.Context) (start, end int) {
+	r, _ := ctx.Value(pageRangeKey{}).([2]int)
+	return r[0], r[1]
+}
+
+// fetchAllPagesOf fetches every page of a small Jacad listing sequentially.
+func fetchAllPagesOf[T any](ctx context.Context, c *JacadClient, endpoint string, params map[string]string) ([]T, error) {
+	var all []T
+	for page, totalPages := 0, 1; page < totalPages; page++ {
+		elements, pageInfo, err := fetchPageOf[T](ctx, c, endpoint, page, c.pageSize(ctx), params)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, elements...)
+		if pageInfo != nil {
+			totalPages = pageInfo.TotalPages
+		}
+	}
+	return all, nil
+}
+
+// fetchPageBody returns the raw body of one page, from the cache when possible,
+// and the API version it is in. Only bodies fetched from Jacad are archived.
+func (c *JacadClient) fetchPageBody(ctx context.Context, endpoint string, page, pageSize int, params map[string]string) ([]byte, string, error) {
+	profile, err := c.profile(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	version := c.apiVersion(ctx)
+
 	q := url.Values{}
-	q.Set("currentPage", fmt.Sprintf("%d", page))
-	q.Set("pageSize", fmt.Sprintf("%d", pageSize))
+	for k, v := range models.PageQuery(version, page, pageSize) {
+		q.Set(k, v)
+	}
 	for k, v := range params {
 		q.Set(k, v)
 	}
+	url := fmt.Sprintf("%s%s?%s", profile.APIBase, c.versionedPath(version, endpoint), q.Encode())
+
+	cacheKey := tenantScoped(ctx, endpoint+"?"+q.Encode())
+	body, cached := c.cachedResponse(ctx, cacheKey)
+	for reauthenticated := false; !cached; reauthenticated = true {
+		token, err := c.GetAuthToken(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, "", fmt.Errorf("failed to get token for page %d due to context cancellation: %w", page, ctx.Err())
+			}
+			return nil, "", fmt.Errorf("failed to get token for page %d: %w", page, err)
+		}
 
-	url := fmt.Sprintf("%s%s?%s", c.Config.APIBase, endpoint, q.Encode())
+		headers := map[string]string{
+			"Authorization": "Bearer " + token,
+			"Content-Type":  "application/json",
+		}
+		kept := c.conditionalEntry(ctx, cacheKey)
+		kept.headers(headers)
 
-	token, err := c.GetAuthToken(ctx)
-	if err != nil {
+		var resp *jacadResponse
+		resp, err = c.doRequest(ctx, http.MethodGet, url, headers, nil)
+		if err == nil && resp.Status == http.StatusNotModified && kept != nil {
+			body = kept.Body
+			c.transfers.add(ctx, func(s *TransferStats) {
+				s.NotModified++
+				s.BytesSaved += int64(len(body))
+			})
+			c.storeResponse(ctx, cacheKey, body)
+			break
+		}
+		if err == nil {
+			body = resp.Body
+			c.storeResponse(ctx, cacheKey, body)
+			c.storeConditional(ctx, cacheKey, resp.Header, body)
+			c.archivePage(ctx, endpoint, page, body)
+			break
+		}
 		if ctx.Err() != nil {
-			return nil, nil, fmt.Errorf("failed to get token for page %d due to context cancellation: %w", page, ctx.Err())
+			return nil, "", fmt.Errorf("fetching page %d cancelled via context: %w", page, ctx.Err())
 		}
-		return nil, nil, fmt.Errorf("failed to get token for page %d: %w", page, err)
+		if errors.Is(err, ErrUnauthorized) && !reauthenticated {
+			logging.FromContext(ctx).Warn("Jacad rejected the auth token. Re-authenticating before retrying the page.", "page", page)
+			c.InvalidateAuthToken(ctx, token)
+			jacadReauthTotal.Inc()
+			continue
+		}
+		return nil, "", fmt.Errorf("error fetching page %d from %s: %w", page, endpoint, err)
 	}
+	return body, version, nil
+}
 
-	headers := map[string]string{
-		"Authorization": "Bearer " + token,
-		"Content-Type":  "application/json",
+// cachedResponse returns the cached body for key unless caching is disabled or
+// bypassed for ctx. Cache errors are logged and treated as misses.
+func (c *JacadClient) cachedResponse(ctx context.Context, key string) ([]byte, bool) {
+	if c.Cache == nil || cacheBypassed(ctx) {
+		return nil, false
 	}
-
-	body, err := c.MakeRequest(ctx, http.MethodGet, url, headers, nil)
+	body, ok, err := c.Cache.Get(ctx, key)
 	if err != nil {
-		if ctx.Err() != nil {
-			return nil, nil, fmt.Errorf("fetching page %d cancelled via context: %w", page, ctx.Err())
-		}
-		return nil, nil, fmt.Errorf("error fetching page %d from %s: %w", page, endpoint, err)
+		logging.FromContext(ctx).Warn("Failed to read Jacad response from cache", "key", key, "error", err)
+		jacadCacheLookupsTotal.Inc("error")
+		return nil, false
 	}
-
-	var apiResp models.APIResponse[models.Enrollment]
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, nil, fmt.Errorf("error parsing API response from page %d: %w", page, err)
+	if !ok {
+		jacadCacheLookupsTotal.Inc("miss")
+		return nil, false
 	}
+	jacadCacheLookupsTotal.Inc("hit")
+	return body, true
+}
 
-	return apiResp.Elements, apiResp.Page, nil
+func (c *JacadClient) storeResponse(ctx context.Context, key string, body []byte) {
+	if c.Cache == nil {
+		return
+	}
+	if err := c.Cache.Set(ctx, key, body, c.Config.CacheTTL); err != nil {
+		logging.FromContext(ctx).Warn("Failed to store Jacad response in cache", "key", key, "error", err)
+	}
 }
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/notifications"
+)
+
+// Student situations in the comparison students tab.
+const (
+	comparisonRetained = "Retido"
+	comparisonNew      = "Novo"
+	comparisonDropout  = "Evasão"
+)
+
+var comparisonCourseHeaders = []string{"Curso", "Período A", "Período B", "Variação", "Retidos", "Novos", "Evasões"}
+
+// EnrollmentComparison compares the students enrolled in two periods.
+// Students are matched by idAluno, or by RA when Jacad leaves idAluno out.
+type EnrollmentComparison struct {
+	PeriodA ComparedPeriod `json:"periodA"`
+	PeriodB ComparedPeriod `json:"periodB"`
+	// Retained are enrolled in both periods, New only in PeriodB and
+	// Dropouts only in PeriodA.
+	Retained []ComparedStudent `json:"retained"`
+	New      []ComparedStudent `json:"new"`
+	Dropouts []ComparedStudent `json:"dropouts"`
+	Courses  []CourseDelta     `json:"courses"`
+	// Sheet and StudentsSheet are the tabs the comparison was written to
+	// when it was requested with write.
+	Sheet         string `json:"sheet,omitempty"`
+	StudentsSheet string `json:"studentsSheet,omitempty"`
+}
+
+// ComparedPeriod counts the distinct students of one compared period.
+type ComparedPeriod struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Students int    `json:"students"`
+}
+
+// ComparedStudent is one student of the comparison, with the course of their
+// enrollment in each period they are enrolled in.
+type ComparedStudent struct {
+	IdAluno int    `json:"idAluno,omitempty"`
+	RA      string `json:"ra,omitempty"`
+	Aluno   string `json:"aluno"`
+	CursoA  string `json:"cursoA,omitempty"`
+	CursoB  string `json:"cursoB,omitempty"`
+}
+
+// CourseDelta compares the students of one course. Retained counts the
+// course's PeriodA students still enrolled in PeriodB, in any course.
+type CourseDelta struct {
+	Curso    string `json:"curso"`
+	PeriodA  int    `json:"periodA"`
+	PeriodB  int    `json:"periodB"`
+	Delta    int    `json:"delta"`
+	Retained int    `json:"retained"`
+	New      int    `json:"new"`
+	Dropouts int    `json:"dropouts"`
+}
+
+// CompareEnrollments fetches both periods and compares their students,
+// writing the comparison when params asks to.
+func (c *JacadClient) CompareEnrollments(ctx context.Context, params *requests.CompareEnrollmentsRequest) (*EnrollmentComparison, error) {
+	startedAt := time.Now()
+	comparison, fetched, err := c.compareEnrollments(ctx, params)
+
+	summary := notifications.Summary{Job: "compare-enrollments", Tenant: params.Tenant, RowsFetched: fetched}
+	var sheets []string
+	if comparison != nil && err == nil && params.Write {
+		summary.RowsWritten = len(comparison.Courses) + len(comparison.Retained) + len(comparison.New) + len(comparison.Dropouts)
+		sheets = []string{comparison.Sheet, comparison.StudentsSheet}
+	}
+	c.finishJob(ctx, summary, params, sheets, nil, !params.Write, startedAt, err)
+	return comparison, err
+}
+
+func (c *JacadClient) compareEnrollments(ctx context.Context, params *requests.CompareEnrollmentsRequest) (*EnrollmentComparison, int, error) {
+	logger := logging.FromContext(ctx).With("periodA", params.PeriodA, "periodB", params.PeriodB, "statusMatricula", params.StatusMatricula, "orgId", params.OrgId)
+	logger.Info("Starting enrollment comparison")
+	startTime := time.Now()
+
+	if params.BypassCache {
+		ctx = WithCacheBypass(ctx)
+	}
+	ctx = WithTenant(ctx, params.Tenant)
+
+	var periods [2]ComparedPeriod
+	var enrollments [2][]models.Enrollment
+	fetched := 0
+	for i, id := range []int{params.PeriodA, params.PeriodB} {
+		single := params.PeriodRequest(id)
+		var data []models.Enrollment
+		collect := func(batch []models.Enrollment) error {
+			data = append(data, batch...)
+			return nil
+		}
+		dedup := newEnrollmentDeduper()
+		n, failed, _, err := c.fetchEnrollmentQueries(ctx, logger, enrollmentFetchParams(single), startTime, false, false, dedup.wrap(collect))
+		fetched += n
+		if err != nil {
+			return nil, fetched, fmt.Errorf("failed to fetch period %d: %w", id, err)
+		}
+		logDuplicates(logger, dedup)
+		if failed.batches > 0 {
+			return nil, fetched, fmt.Errorf("failed to fetch %d batch(es) of pages of period %d; the comparison would be wrong", failed.batches, id)
+		}
+		if params.OrgId != 0 {
+			data = filterEnrollmentsByOrg(data, params.OrgId)
+		}
+		periods[i] = ComparedPeriod{ID: id, Name: c.periodName(ctx, single)}
+		enrollments[i] = data
+	}
+
+	comparison := compareStudents(enrollments[0], enrollments[1])
+	periods[0].Students = len(comparison.Retained) + len(comparison.Dropouts)
+	periods[1].Students = len(comparison.Retained) + len(comparison.New)
+	comparison.PeriodA, comparison.PeriodB = periods[0], periods[1]
+	logger.Info("Enrollments compared", "retained", len(comparison.Retained), "new", len(comparison.New), "dropouts", len(comparison.Dropouts), "courses", len(comparison.Courses))
+
+	if params.Write {
+		if err := c.writeComparison(ctx, comparison); err != nil {
+			return comparison, fetched, err
+		}
+	}
+
+	logger.Info("Enrollment comparison completed", "fetched", fetched, "duration", time.Since(startTime).String())
+	return comparison, fetched, nil
+}
+
+// writeComparison overwrites the comparison's course and students tabs.
+func (c *JacadClient) writeComparison(ctx context.Context, comparison *EnrollmentComparison) error {
+	name := truncateRunes(fmt.Sprintf("Comparação %s x %s", comparison.PeriodA.Name, comparison.PeriodB.Name), maxSheetNameLength-len(" - Alunos"))
+	courses := make([][]interface{}, len(comparison.Courses))
+	for i, course := range comparison.Courses {
+		courses[i] = []interface{}{course.Curso, course.PeriodA, course.PeriodB, course.Delta, course.Retained, course.New, course.Dropouts}
+	}
+	if err := c.Writer.OverwriteSheetData(ctx, name, comparisonCourseHeaders, courses); err != nil {
+		return fmt.Errorf("failed to write comparison sheet: %w", err)
+	}
+	comparison.Sheet = name
+
+	studentsName := name + " - Alunos"
+	headers := []string{"Situação", "idAluno", "RA", "Aluno", "Curso " + comparison.PeriodA.Name, "Curso " + comparison.PeriodB.Name}
+	var students [][]interface{}
+	for _, group := range []struct {
+		situation string
+		students  []ComparedStudent
+	}{
+		{comparisonRetained, comparison.Retained},
+		{comparisonNew, comparison.New},
+		{comparisonDropout, comparison.Dropouts},
+	} {
+		for _, s := range group.students {
+			students = append(students, []interface{}{group.situation, s.IdAluno, s.RA, s.Aluno, s.CursoA, s.CursoB})
+		}
+	}
+	if err := c.Writer.OverwriteSheetData(ctx, studentsName, headers, students); err != nil {
+		return fmt.Errorf("failed to write comparison students sheet: %w", err)
+	}
+	comparison.StudentsSheet = studentsName
+	return nil
+}
+
+// compareStudents matches the students of periods a and b. A student with
+// several enrollments in a period is counted once, under the course of the
+// first one. Enrollments identifying no student are left out.
+func compareStudents(a, b []models.Enrollment) *EnrollmentComparison {
+	studentsA, orderA := studentsByKey(a)
+	studentsB, orderB := studentsByKey(b)
+
+	comparison := &EnrollmentComparison{
+		Retained: []ComparedStudent{},
+		New:      []ComparedStudent{},
+		Dropouts: []ComparedStudent{},
+	}
+	courses := make(map[string]*CourseDelta)
+	course := func(name string) *CourseDelta {
+		delta, ok := courses[name]
+		if !ok {
+			delta = &CourseDelta{Curso: name}
+			courses[name] = delta
+		}
+		return delta
+	}
+
+	for _, key := range orderA {
+		student := studentsA[key]
+		delta := course(student.CursoA)
+		delta.PeriodA++
+		if inB, ok := studentsB[key]; ok {
+			student.CursoB = inB.CursoA
+			delta.Retained++
+			comparison.Retained = append(comparison.Retained, student)
+		} else {
+			delta.Dropouts++
+			comparison.Dropouts = append(comparison.Dropouts, student)
+		}
+	}
+	for _, key := range orderB {
+		student := studentsB[key]
+		delta := course(student.CursoA)
+		delta.PeriodB++
+		student.CursoA, student.CursoB = "", student.CursoA
+		if _, ok := studentsA[key]; !ok {
+			delta.New++
+			comparison.New = append(comparison.New, student)
+		}
+	}
+
+	comparison.Courses = make([]CourseDelta, 0, len(courses))
+	for _, delta := range courses {
+		delta.Delta = delta.PeriodB - delta.PeriodA
+		comparison.Courses = append(comparison.Courses, *delta)
+	}
+	sort.Slice(comparison.Courses, func(i, j int) bool { return comparison.Courses[i].Curso < comparison.Courses[j].Curso })
+	for _, list := range [][]ComparedStudent{comparison.Retained, comparison.New, comparison.Dropouts} {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Aluno < list[j].Aluno })
+	}
+	return comparison
+}
+
+// studentsByKey indexes the students of one period, with their course in
+// CursoA; order keeps the order they were first seen in.
+func studentsByKey(data []models.Enrollment) (students map[string]ComparedStudent, order []string) {
+	students = make(map[string]ComparedStudent, len(data))
+	for _, item := range data {
+		key := studentKey(item)
+		if key == "" {
+			continue
+		}
+		if _, seen := students[key]; seen {
+			continue
+		}
+		students[key] = ComparedStudent{IdAluno: item.IdAluno, RA: strings.TrimSpace(deref(item.RA)), Aluno: deref(item.Aluno), CursoA: summaryLabel(item.Curso, "Sem curso")}
+		order = append(order, key)
+	}
+	return students, order
+}
+
+// studentKey identifies the student of an enrollment across periods, or
+// returns "" when the enrollment has neither idAluno nor RA.
+func studentKey(item models.Enrollment) string {
+	if item.IdAluno != 0 {
+		return "id:" + strconv.Itoa(item.IdAluno)
+	}
+	if item.RA != nil && strings.TrimSpace(*item.RA) != "" {
+		return "ra:" + strings.TrimSpace(*item.RA)
+	}
+	return ""
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+func TestCompareStudents(t *testing.T) {
+	enrollment := func(idAluno int, ra, aluno, curso string) models.Enrollment {
+		return models.Enrollment{IdAluno: idAluno, RA: ptr(ra), Aluno: ptr(aluno), Curso: ptr(curso)}
+	}
+	a := []models.Enrollment{
+		enrollment(1, "100", "Ana", "Direito"),
+		enrollment(1, "100", "Ana", "Letras"),
+		enrollment(2, "200", "Bruno", "Direito"),
+		enrollment(0, "300", "Carla", "Letras"),
+		enrollment(0, "", "Sem RA", "Letras"),
+	}
+	b := []models.Enrollment{
+		enrollment(1, "100", "Ana", "Letras"),
+		enrollment(0, "300", "Carla", "Letras"),
+		enrollment(4, "400", "Davi", "Direito"),
+	}
+
+	got := compareStudents(a, b)
+
+	wantRetained := []ComparedStudent{
+		{IdAluno: 1, RA: "100", Aluno: "Ana", CursoA: "Direito", CursoB: "Letras"},
+		{RA: "300", Aluno: "Carla", CursoA: "Letras", CursoB: "Letras"},
+	}
+	wantNew := []ComparedStudent{{IdAluno: 4, RA: "400", Aluno: "Davi", CursoB: "Direito"}}
+	wantDropouts := []ComparedStudent{{IdAluno: 2, RA: "200", Aluno: "Bruno", CursoA: "Direito"}}
+	wantCourses := []CourseDelta{
+		{Curso: "Direito", PeriodA: 2, PeriodB: 1, Delta: -1, Retained: 1, New: 1, Dropouts: 1},
+		{Curso: "Letras", PeriodA: 1, PeriodB: 2, Delta: 1, Retained: 1},
+	}
+	if !reflect.DeepEqual(got.Retained, wantRetained) {
+		t.Errorf("Retained = %+v, want %+v", got.Retained, wantRetained)
+	}
+	if !reflect.DeepEqual(got.New, wantNew) {
+		t.Errorf("New = %+v, want %+v", got.New, wantNew)
+	}
+	if !reflect.DeepEqual(got.Dropouts, wantDropouts) {
+		t.Errorf("Dropouts = %+v, want %+v", got.Dropouts, wantDropouts)
+	}
+	if !reflect.DeepEqual(got.Courses, wantCourses) {
+		t.Errorf("Courses = %+v, want %+v", got.Courses, wantCourses)
+	}
+}
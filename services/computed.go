@@ -0,0 +1,149 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+// ComputeFunc derives a computed column's cell from the value of its source
+// field, e.g. a *utils.Date or *string. It returns nil to leave the cell
+// empty.
+type ComputeFunc func(value interface{}) interface{}
+
+// ComputeFactory builds the ComputeFunc of a computed column, checking that
+// its Source field has a type the op understands.
+type ComputeFactory func(column config.Column) (ComputeFunc, error)
+
+var (
+	computeMu  sync.RWMutex
+	computeOps = map[string]ComputeFactory{
+		"dayssince":  dateCompute(daysSince),
+		"yearssince": dateCompute(yearsSince),
+		"year":       yearCompute,
+	}
+	// computeNow is the reference time of daysSince and yearsSince.
+	computeNow = time.Now
+)
+
+var datePtrType = reflect.TypeOf((*utils.Date)(nil))
+
+// RegisterCompute makes op available to computed columns, replacing a
+// built-in op of the same name. Register ops before the first fetch, e.g.
+// from an init function.
+func RegisterCompute(op string, factory ComputeFactory) {
+	computeMu.Lock()
+	defer computeMu.Unlock()
+	computeOps[strings.ToLower(op)] = factory
+}
+
+// newComputeFunc returns the ComputeFunc of a computed column.
+func newComputeFunc(column config.Column) (ComputeFunc, error) {
+	if _, ok := enrollmentFields[column.Field]; ok {
+		return nil, fmt.Errorf("computed column '%s' shadows the enrollment field of the same name", column.Field)
+	}
+	if _, ok := enrollmentFields[column.Source]; !ok {
+		return nil, fmt.Errorf("unknown source field '%s' for computed column '%s'", column.Source, column.Field)
+	}
+
+	computeMu.RLock()
+	factory, ok := computeOps[strings.ToLower(column.Compute)]
+	computeMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown compute op '%s' for column '%s'", column.Compute, column.Field)
+	}
+	compute, err := factory(column)
+	if err != nil {
+		return nil, fmt.Errorf("invalid '%s' computed column '%s': %w", column.Compute, column.Field, err)
+	}
+	return compute, nil
+}
+
+// sourceType returns the type of the column's source field.
+func sourceType(column config.Column) reflect.Type {
+	return enrollmentType.Field(enrollmentFields[column.Source]).Type
+}
+
+// dateCompute builds ops that only apply to date fields.
+func dateCompute(fn func(day time.Time) interface{}) ComputeFactory {
+	return func(column config.Column) (ComputeFunc, error) {
+		if sourceType(column) != datePtrType {
+			return nil, fmt.Errorf("source field '%s' is not a date", column.Source)
+		}
+		return func(value interface{}) interface{} {
+			date, _ := value.(*utils.Date)
+			if date == nil || time.Time(*date).IsZero() {
+				return nil
+			}
+			return fn(time.Time(*date))
+		}, nil
+	}
+}
+
+// daysSince counts the calendar days from day to today, e.g. the days since
+// an enrollment.
+func daysSince(day time.Time) interface{} {
+	return int(calendarDay(computeNow()).Sub(calendarDay(day)).Hours() / 24)
+}
+
+// yearsSince counts the full years from day to today.
+func yearsSince(day time.Time) interface{} {
+	now := computeNow()
+	years := now.Year() - day.Year()
+	if now.Month() < day.Month() || (now.Month() == day.Month() && now.Day() < day.Day()) {
+		years--
+	}
+	return years
+}
+
+func calendarDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// yearCompute takes the year of a date field, or the first four-digit number
+// of a text field, e.g. 2025 from periodoLetivo "2025/1".
+func yearCompute(column config.Column) (ComputeFunc, error) {
+	switch sourceType(column) {
+	case datePtrType:
+		return dateCompute(func(day time.Time) interface{} { return day.Year() })(column)
+	case stringPtrType:
+		return func(value interface{}) interface{} {
+			text, _ := value.(*string)
+			if text == nil {
+				return nil
+			}
+			return firstYear(*text)
+		}, nil
+	}
+	return nil, fmt.Errorf("source field '%s' is neither a date nor text", column.Source)
+}
+
+// firstYear returns the first run of exactly four digits in s as a number,
+// or nil.
+func firstYear(s string) interface{} {
+	runes := []rune(s)
+	for start := 0; start < len(runes); {
+		if !unicode.IsDigit(runes[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && unicode.IsDigit(runes[end]) {
+			end++
+		}
+		if end-start == 4 {
+			year, _ := strconv.Atoi(string(runes[start:end]))
+			return year
+		}
+		start = end
+	}
+	return nil
+}
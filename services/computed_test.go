@@ -0,0 +1,20 @@
+package services
+
+import "testing"
+
+func TestFirstYear(t *testing.T) {
+	tests := []struct {
+		value string
+		want  interface{}
+	}{
+		{value: "2025/1", want: 2025},
+		{value: "1º semestre de 2024", want: 2024},
+		{value: "Turma 123 - 20251", want: nil},
+		{value: "", want: nil},
+	}
+	for _, tt := range tests {
+		if got := firstYear(tt.value); got != tt.want {
+			t.Errorf("firstYear(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
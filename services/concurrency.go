@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ConcurrencyController caps how many Jacad requests run at once and
+// adapts the cap with AIMD: it halves on 429/5xx responses and grows by one
+// after a full window of healthy responses. A nil *ConcurrencyController
+// never blocks.
+type ConcurrencyController struct {
+	mu        sync.Mutex
+	min       int
+	max       int
+	limit     int
+	inFlight  int
+	successes int
+	cooldown  time.Duration
+	lastDrop  time.Time
+	changed   chan struct{}
+}
+
+func NewConcurrencyController(minLimit, maxLimit int, cooldown time.Duration) *ConcurrencyController {
+	if maxLimit <= 0 {
+		return nil
+	}
+	if minLimit < 1 {
+		minLimit = 1
+	}
+	if minLimit > maxLimit {
+		minLimit = maxLimit
+	}
+	c := &ConcurrencyController{
+		min:      minLimit,
+		max:      maxLimit,
+		limit:    maxLimit,
+		cooldown: cooldown,
+		changed:  make(chan struct{}),
+	}
+	jacadConcurrencyLimit.Set(float64(c.limit))
+	return c
+}
+
+// Acquire blocks until a slot under the current limit is free or ctx is done.
+func (c *ConcurrencyController) Acquire(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+
+	for {
+		c.mu.Lock()
+		if c.inFlight < c.limit {
+			c.inFlight++
+			jacadConcurrencyInFlight.Set(float64(c.inFlight))
+			c.mu.Unlock()
+			return nil
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *ConcurrencyController) Release() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	jacadConcurrencyInFlight.Set(float64(c.inFlight))
+	c.notifyLocked()
+}
+
+// RecordSuccess counts a healthy response towards the next increase.
+func (c *ConcurrencyController) RecordSuccess() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.successes++
+	if c.successes >= c.limit && c.limit < c.max {
+		c.limit++
+		c.successes = 0
+		jacadConcurrencyLimit.Set(float64(c.limit))
+		c.notifyLocked()
+	}
+}
+
+// RecordThrottled halves the limit, at most once per cooldown so a burst of
+// failures from requests already in flight only counts once.
+func (c *ConcurrencyController) RecordThrottled() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.successes = 0
+	if time.Since(c.lastDrop) < c.cooldown {
+		return
+	}
+	c.lastDrop = time.Now()
+	c.limit = max(c.limit/2, c.min)
+	jacadConcurrencyLimit.Set(float64(c.limit))
+}
+
+func (c *ConcurrencyController) Limit() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.limit
+}
+
+func (c *ConcurrencyController) notifyLocked() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
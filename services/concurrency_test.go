@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyControllerAIMD(t *testing.T) {
+	tests := []struct {
+		name      string
+		min, max  int
+		cooldown  time.Duration
+		throttles int
+		successes int
+		want      int
+	}{
+		{name: "starts at max", min: 2, max: 8, want: 8},
+		{name: "halves on throttle", min: 2, max: 8, throttles: 1, want: 4},
+		{name: "never drops below min", min: 2, max: 8, throttles: 5, want: 2},
+		{name: "cooldown counts a burst once", min: 1, max: 8, cooldown: time.Hour, throttles: 3, want: 4},
+		{name: "grows by one after a window of successes", min: 1, max: 8, throttles: 2, successes: 2, want: 3},
+		{name: "needs a full window to grow", min: 1, max: 8, throttles: 2, successes: 1, want: 2},
+		{name: "does not grow past max", min: 1, max: 4, successes: 20, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConcurrencyController(tt.min, tt.max, tt.cooldown)
+			for i := 0; i < tt.throttles; i++ {
+				c.RecordThrottled()
+			}
+			for i := 0; i < tt.successes; i++ {
+				c.RecordSuccess()
+			}
+			if got := c.Limit(); got != tt.want {
+				t.Errorf("Limit() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConcurrencyControllerAcquire(t *testing.T) {
+	c := NewConcurrencyController(1, 2, 0)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := c.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire() #%d = %v", i+1, err)
+		}
+	}
+
+	blocked, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := c.Acquire(blocked); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire() over the limit = %v, want DeadlineExceeded", err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- c.Acquire(ctx) }()
+	c.Release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("Acquire() after Release = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Acquire() did not wake up after Release")
+	}
+}
+
+func TestNilConcurrencyController(t *testing.T) {
+	c := NewConcurrencyController(1, 0, 0)
+	if err := c.Acquire(context.Background()); err != nil {
+		t.Errorf("Acquire() = %v, want nil", err)
+	}
+	c.RecordThrottled()
+	c.Release()
+	if got := c.Limit(); got != 0 {
+		t.Errorf("Limit() = %d, want 0", got)
+	}
+}
+
+func TestEndpointLimiterAcquire(t *testing.T) {
+	l := NewEndpointLimiter(
+		map[string]string{"ENROLLMENTS": "/academico/matriculas", "CLASSES": "/academico/turmas"},
+		map[string]int{"ENROLLMENTS": 1},
+	)
+	ctx := context.Background()
+
+	release, err := l.Acquire(ctx, "/academico/matriculas")
+	if err != nil {
+		t.Fatalf("Acquire() = %v", err)
+	}
+
+	blocked, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(blocked, "/academico/matriculas"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire() over the endpoint limit = %v, want DeadlineExceeded", err)
+	}
+
+	// Endpoints without a limit are not held up by a busy one.
+	other, err := l.Acquire(ctx, "/academico/turmas")
+	if err != nil {
+		t.Fatalf("Acquire() for an unlimited endpoint = %v", err)
+	}
+	other()
+
+	release()
+	again, err := l.Acquire(ctx, "/academico/matriculas")
+	if err != nil {
+		t.Fatalf("Acquire() after release = %v", err)
+	}
+	again()
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+var courseHeaders = []string{"idCurso", "codigo", "nome", "modalidade", "nivelEnsino", "turno", "idOrg", "organizacao", "ativo"}
+
+// CoursesService writes the Jacad course catalog to its own sheet so other
+// sheets can resolve course IDs to names and modalities.
+type CoursesService struct {
+	client *JacadClient
+}
+
+func NewCoursesService(client *JacadClient) *CoursesService {
+	return &CoursesService{client: client}
+}
+
+// FetchCourses fetches every course and overwrites Config.CoursesSheet.
+func (s *CoursesService) FetchCourses(ctx context.Context, params *requests.FetchCoursesRequest) (*SheetResult, error) {
+	startedAt := time.Now()
+	result, err := s.fetchCourses(ctx, params)
+	s.client.finishJob(ctx, sheetSummary("fetch-courses", params.Tenant, result, err), params, result.writtenSheets(err), nil, params.DryRun, startedAt, err)
+	return result, err
+}
+
+func (s *CoursesService) fetchCourses(ctx context.Context, params *requests.FetchCoursesRequest) (*SheetResult, error) {
+	c := s.client
+	logger := logging.FromContext(ctx).With("orgId", params.OrgId)
+	logger.Info("Starting course catalog fetch")
+	startTime := time.Now()
+
+	if params.BypassCache {
+		ctx = WithCacheBypass(ctx)
+	}
+	ctx = WithTenant(ctx, params.Tenant)
+
+	courses, err := c.cachedCourses(ctx, params.BypassCache)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch course catalog: %w", err)
+	}
+
+	rows := make([][]interface{}, 0, len(courses))
+	for _, course := range courses {
+		if params.OrgId != 0 && course.OrgID != params.OrgId {
+			continue
+		}
+		rows = append(rows, courseRow(course))
+	}
+
+	result := &SheetResult{Sheet: c.Config.CoursesSheet, Rows: len(rows), DryRun: params.DryRun}
+	if params.DryRun {
+		limit := params.PreviewRows
+		if limit <= 0 {
+			limit = c.Config.DryRunPreviewRows
+		}
+		result.Preview = previewRows(courseHeaders, rows[:min(limit, len(rows))])
+		return result, nil
+	}
+
+	if err := c.Writer.OverwriteSheetData(ctx, result.Sheet, courseHeaders, rows); err != nil {
+		return result, fmt.Errorf("failed to write course catalog to sheet: %w", err)
+	}
+
+	logger.Info("Course catalog written", "sheet", result.Sheet, "rows", len(rows), "duration", time.Since(startTime).String())
+	return result, nil
+}
+
+func courseRow(course models.Course) []interface{} {
+	var ativo interface{} = ""
+	if course.Ativo != nil {
+		ativo = *course.Ativo
+	}
+	return []interface{}{
+		course.IdCurso,
+		utils.GetStringOrEmpty(course.Codigo),
+		utils.GetStringOrEmpty(course.Nome),
+		utils.GetStringOrEmpty(course.Modalidade),
+		utils.GetStringOrEmpty(course.NivelEnsino),
+		utils.GetStringOrEmpty(course.Turno),
+		course.OrgID,
+		utils.GetStringOrEmpty(course.Organizacao),
+		ativo,
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+)
+
+func newCoursesTestService(t *testing.T, server *jacadmock.Server, writer SheetWriter) *CoursesService {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	client := NewJacadClient(&cfg, writer, NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+	return NewCoursesService(client)
+}
+
+func TestFetchCourses(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(30)})
+	defer server.Close()
+	writer := NewFakeSheetWriter()
+	courses := newCoursesTestService(t, server, writer)
+	sheet := courses.client.Config.CoursesSheet
+
+	result, err := courses.FetchCourses(context.Background(), &requests.FetchCoursesRequest{OrgId: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sheet != sheet || result.Rows != 3 {
+		t.Errorf("result = %+v, want 3 rows in %q", result, sheet)
+	}
+	written, ok := writer.Sheet(sheet)
+	if !ok {
+		t.Fatalf("sheet %q not written", sheet)
+	}
+	if !reflect.DeepEqual(written.Headers, courseHeaders) {
+		t.Errorf("headers = %v, want %v", written.Headers, courseHeaders)
+	}
+	want := []interface{}{1, "C001", "Administração", "EAD", "Graduação", "Noturno", 20, "EAD", true}
+	if len(written.Rows) != 3 || !reflect.DeepEqual(written.Rows[0], want) {
+		t.Errorf("rows = %v, want 3 starting with %v", written.Rows, want)
+	}
+
+	// The catalog is cached, so the unfiltered fetch does not hit Jacad again.
+	result, err = courses.FetchCourses(context.Background(), &requests.FetchCoursesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Rows != 7 {
+		t.Errorf("rows = %d, want all 7 courses", result.Rows)
+	}
+	if fetches := server.Requests("COURSES"); fetches != 1 {
+		t.Errorf("fetched the catalog %d times, want once", fetches)
+	}
+	if _, err := courses.FetchCourses(context.Background(), &requests.FetchCoursesRequest{BypassCache: true}); err != nil {
+		t.Fatal(err)
+	}
+	if fetches := server.Requests("COURSES"); fetches != 2 {
+		t.Errorf("fetched the catalog %d times after bypassing the cache, want twice", fetches)
+	}
+}
+
+func TestFetchCoursesDryRun(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(30)})
+	defer server.Close()
+	writer := NewFakeSheetWriter()
+	courses := newCoursesTestService(t, server, writer)
+
+	result, err := courses.FetchCourses(context.Background(), &requests.FetchCoursesRequest{DryRun: true, PreviewRows: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Rows != 7 || len(result.Preview) != 2 || !result.DryRun {
+		t.Errorf("result = %d rows, %d previewed, dry run %v; want 7, 2, true", result.Rows, len(result.Preview), result.DryRun)
+	}
+	if got := result.Preview[1]["nome"]; got != "Pedagogia" {
+		t.Errorf("second preview row nome = %v, want Pedagogia", got)
+	}
+	if calls := writer.Calls(); len(calls) != 0 {
+		t.Errorf("dry run made %d writer calls, want none", len(calls))
+	}
+}
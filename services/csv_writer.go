@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CSVWriter implements SheetWriter by writing one CSV file per sheet into dir.
+type CSVWriter struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewCSVWriter writes into dir, or the current directory when dir is empty.
+func NewCSVWriter(dir string) *CSVWriter {
+	if dir == "" {
+		dir = "."
+	}
+	return &CSVWriter{dir: dir}
+}
+
+func (w *CSVWriter) EnsureSheetExists(ctx context.Context, sheetName string) error {
+	if err := os.MkdirAll(w.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory '%s': %w", w.dir, err)
+	}
+	return nil
+}
+
+// CheckHealth verifies the output directory is writable.
+func (w *CSVWriter) CheckHealth(ctx context.Context) error {
+	if err := w.EnsureSheetExists(ctx, ""); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(w.dir, ".healthcheck-*")
+	if err != nil {
+		return fmt.Errorf("output directory '%s' is not writable: %w", w.dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func (w *CSVWriter) Clear(ctx context.Context, sheetName string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := os.Remove(w.path(sheetName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear CSV for sheet '%s': %w", sheetName, err)
+	}
+	return nil
+}
+
+func (w *CSVWriter) SetHeaders(ctx context.Context, sheetName string, headers []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	records, err := w.readLocked(sheetName)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		records = [][]string{headers}
+	} else {
+		records[0] = headers
+	}
+	return w.writeLocked(sheetName, records)
+}
+
+func (w *CSVWriter) AppendRows(ctx context.Context, sheetName string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := w.EnsureSheetExists(ctx, sheetName); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	f, err := os.OpenFile(w.path(sheetName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open CSV for sheet '%s': %w", sheetName, err)
+	}
+	defer f.Close()
+
+	cw := csv.NewWriter(f)
+	for _, row := range rows {
+		if err := cw.Write(csvRecord(row)); err != nil {
+			return fmt.Errorf("failed to append to CSV for sheet '%s': %w", sheetName, err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to append to CSV for sheet '%s': %w", sheetName, err)
+	}
+	return nil
+}
+
+func (w *CSVWriter) OverwriteSheetData(ctx context.Context, sheetName string, headers []string, rows [][]interface{}) error {
+	if err := w.EnsureSheetExists(ctx, sheetName); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	records := make([][]string, 0, len(rows)+1)
+	if len(headers) > 0 {
+		records = append(records, headers)
+	}
+	for _, row := range rows {
+		records = append(records, csvRecord(row))
+	}
+	return w.writeLocked(sheetName, records)
+}
+
+func (w *CSVWriter) UpsertRows(ctx context.Context, sheetName string, headers []string, keyColumn string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	keyIndex := -1
+	for i, h := range headers {
+		if h == keyColumn {
+			keyIndex = i
+			break
+		}
+	}
+	if keyIndex < 0 {
+		return fmt.Errorf("key column '%s' not found in headers for sheet '%s'", keyColumn, sheetName)
+	}
+	if err := w.EnsureSheetExists(ctx, sheetName); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	records, err := w.readLocked(sheetName)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		records = [][]string{headers}
+	}
+
+	positions := make(map[string]int, len(records))
+	for i, record := range records[1:] {
+		if keyIndex < len(record) {
+			positions[record[keyIndex]] = i + 1
+		}
+	}
+	for _, row := range rows {
+		record := csvRecord(row)
+		if pos, ok := positions[record[keyIndex]]; ok {
+			records[pos] = record
+			continue
+		}
+		positions[record[keyIndex]] = len(records)
+		records = append(records, record)
+	}
+
+	return w.writeLocked(sheetName, records)
+}
+
+func (w *CSVWriter) CountRows(ctx context.Context, sheetName string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	records, err := w.readLocked(sheetName)
+	if err != nil {
+		return 0, err
+	}
+	return max(len(records)-1, 0), nil
+}
+
+func (w *CSVWriter) ReadRows(ctx context.Context, sheetName string) ([][]interface{}, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	records, err := w.readLocked(sheetName)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]interface{}, len(records))
+	for i, record := range records {
+		rows[i] = make([]interface{}, len(record))
+		for j, value := range record {
+			rows[i][j] = value
+		}
+	}
+	return rows, nil
+}
+
+func (w *CSVWriter) path(sheetName string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, sheetName)
+	return filepath.Join(w.dir, name+".csv")
+}
+
+func (w *CSVWriter) readLocked(sheetName string) ([][]string, error) {
+	f, err := os.Open(w.path(sheetName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open CSV for sheet '%s': %w", sheetName, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read CSV for sheet '%s': %w", sheetName, err)
+	}
+	return records, nil
+}
+
+// writeLocked replaces the sheet's file atomically.
+func (w *CSVWriter) writeLocked(sheetName string, records [][]string) error {
+	path := w.path(sheetName)
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV for sheet '%s': %w", sheetName, err)
+	}
+
+	cw := csv.NewWriter(f)
+	if err := cw.WriteAll(records); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write CSV for sheet '%s': %w", sheetName, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write CSV for sheet '%s': %w", sheetName, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace CSV for sheet '%s': %w", sheetName, err)
+	}
+	return nil
+}
+
+func csvRecord(row []interface{}) []string {
+	record := make([]string, len(row))
+	for i, value := range row {
+		switch v := value.(type) {
+		case nil:
+			record[i] = ""
+		case time.Time:
+			record[i] = v.Format("2006-01-02")
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return record
+}
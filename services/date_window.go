@@ -0,0 +1,78 @@
+package services
+
+import (
+	"log/slog"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+// dataMatriculaWindow drops enrollments outside the request's dataMatricula
+// range. Jacad cannot filter on it, so every page is fetched and the window
+// is applied here. Calendar dates are compared in each timestamp's own zone,
+// and enrollments without a date are dropped when a range is set.
+type dataMatriculaWindow struct {
+	from, to time.Time
+	// fromLabel and toLabel are the requested bounds, for logs.
+	fromLabel, toLabel string
+	dropped            int
+}
+
+// newDataMatriculaWindow returns nil when params sets no range.
+func newDataMatriculaWindow(params *requests.FetchEnrollmentsRequest) (*dataMatriculaWindow, error) {
+	from, to, err := params.DataMatriculaRange()
+	if err != nil {
+		return nil, err
+	}
+	if from.IsZero() && to.IsZero() {
+		return nil, nil
+	}
+	return &dataMatriculaWindow{from: from, to: to, fromLabel: params.DataMatriculaFrom, toLabel: params.DataMatriculaTo}, nil
+}
+
+// wrap returns a sink that forwards only enrollments inside the window to
+// sink. A nil window forwards everything.
+func (w *dataMatriculaWindow) wrap(sink func([]models.Enrollment) error) func([]models.Enrollment) error {
+	if w == nil {
+		return sink
+	}
+	return func(data []models.Enrollment) error {
+		kept := make([]models.Enrollment, 0, len(data))
+		for _, item := range data {
+			if !w.contains(item.DataMatricula) {
+				w.dropped++
+				continue
+			}
+			kept = append(kept, item)
+		}
+		if len(kept) == 0 {
+			return nil
+		}
+		return sink(kept)
+	}
+}
+
+func (w *dataMatriculaWindow) contains(d *utils.Date) bool {
+	if d == nil || time.Time(*d).IsZero() {
+		return false
+	}
+	y, m, day := time.Time(*d).Date()
+	date := time.Date(y, m, day, 0, 0, 0, 0, time.UTC)
+	return (w.from.IsZero() || !date.Before(w.from)) && (w.to.IsZero() || date.Before(w.to))
+}
+
+// Dropped returns how many enrollments fell outside the window.
+func (w *dataMatriculaWindow) Dropped() int {
+	if w == nil {
+		return 0
+	}
+	return w.dropped
+}
+
+func logOutsideWindow(logger *slog.Logger, window *dataMatriculaWindow) {
+	if window.Dropped() > 0 {
+		logger.Info("Dropped enrollments outside the dataMatricula range", "enrollments", window.Dropped(), "from", window.fromLabel, "to", window.toLabel)
+	}
+}
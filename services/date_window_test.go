@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+func TestDataMatriculaWindowContains(t *testing.T) {
+	saoPaulo := time.FixedZone("BRT", -3*60*60)
+	date := func(t time.Time) *utils.Date { d := utils.Date(t); return &d }
+
+	tests := []struct {
+		name     string
+		from, to string
+		date     *utils.Date
+		want     bool
+	}{
+		{name: "inside", from: "2025-02-01", to: "2025-02-28", date: date(time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC)), want: true},
+		{name: "first day", from: "2025-02-01", to: "2025-02-28", date: date(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)), want: true},
+		{name: "end of the last day", from: "2025-02-01", to: "2025-02-28", date: date(time.Date(2025, 2, 28, 23, 59, 0, 0, time.UTC)), want: true},
+		{name: "day before", from: "2025-02-01", to: "2025-02-28", date: date(time.Date(2025, 1, 31, 23, 59, 0, 0, time.UTC)), want: false},
+		{name: "day after", from: "2025-02-01", to: "2025-02-28", date: date(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)), want: false},
+		{name: "calendar date in its own zone", to: "2025-02-28", date: date(time.Date(2025, 2, 28, 22, 0, 0, 0, saoPaulo)), want: true},
+		{name: "open start", to: "2025-02-28", date: date(time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)), want: true},
+		{name: "open end", from: "2025-02-01", date: date(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)), want: true},
+		{name: "missing date", from: "2025-02-01", date: nil, want: false},
+		{name: "zero date", from: "2025-02-01", date: date(time.Time{}), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, err := newDataMatriculaWindow(&requests.FetchEnrollmentsRequest{DataMatriculaFrom: tt.from, DataMatriculaTo: tt.to})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := window.contains(tt.date); got != tt.want {
+				t.Errorf("contains(%v) = %v, want %v", tt.date, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataMatriculaWindowWrap(t *testing.T) {
+	window, err := newDataMatriculaWindow(&requests.FetchEnrollmentsRequest{})
+	if err != nil || window != nil {
+		t.Fatalf("newDataMatriculaWindow() without a range = %v, %v; want nil", window, err)
+	}
+	if _, err := newDataMatriculaWindow(&requests.FetchEnrollmentsRequest{DataMatriculaFrom: "01/02/2025"}); err == nil {
+		t.Error("newDataMatriculaWindow() accepted a malformed date")
+	}
+
+	window, _ = newDataMatriculaWindow(&requests.FetchEnrollmentsRequest{DataMatriculaFrom: "2025-02-01"})
+	inside := utils.Date(time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC))
+	outside := utils.Date(time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC))
+	var batches [][]models.Enrollment
+	sink := window.wrap(func(data []models.Enrollment) error {
+		batches = append(batches, data)
+		return nil
+	})
+	sink([]models.Enrollment{{IdMatricula: 1, DataMatricula: &inside}, {IdMatricula: 2, DataMatricula: &outside}, {IdMatricula: 3}})
+	sink([]models.Enrollment{{IdMatricula: 4, DataMatricula: &outside}})
+
+	if len(batches) != 1 || len(batches[0]) != 1 || batches[0][0].IdMatricula != 1 {
+		t.Errorf("sink got %v, want only enrollment 1 and no empty batch", batches)
+	}
+	if window.Dropped() != 3 {
+		t.Errorf("Dropped() = %d, want 3", window.Dropped())
+	}
+}
+
+// TestFetchEnrollmentsDataMatriculaRange checks the range is applied to the
+// fetched pages, by the sheet job and the CSV export alike.
+func TestFetchEnrollmentsDataMatriculaRange(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer server.Close()
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.PageSize = 50
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	writer := NewFakeSheetWriter()
+	client := NewJacadClient(&cfg, writer, NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+
+	// Jacad returns period 87's enrollments of every organization, made over
+	// the 60 days before the period starts; keep those of days 5 to 9 before
+	// it. Only organization 20's are written.
+	var start time.Time
+	var fetched []models.Enrollment
+	for _, item := range jacadmock.DemoDataset(720).Enrollments {
+		if item.IdPeriodoLetivo == 87 && *item.Status == "ATIVA" {
+			fetched = append(fetched, item)
+			if d := time.Time(*item.DataMatricula); d.After(start) {
+				start = d
+			}
+		}
+	}
+	from, to := start.AddDate(0, 0, -9), start.AddDate(0, 0, -5)
+	inside, want := 0, 0
+	for _, item := range fetched {
+		if d := time.Time(*item.DataMatricula); !d.Before(from) && !d.After(to) {
+			inside++
+			if item.OrgID == 20 {
+				want++
+			}
+		}
+	}
+	if want == 0 || inside == len(fetched) {
+		t.Fatalf("range keeps %d of %d enrollments, want a strict subset", inside, len(fetched))
+	}
+
+	params := func() *requests.FetchEnrollmentsRequest {
+		return &requests.FetchEnrollmentsRequest{
+			OrgIds:            "20",
+			IdPeriodoLetivo:   87,
+			StatusMatricula:   "ATIVA",
+			DataMatriculaFrom: from.Format(time.DateOnly),
+			DataMatriculaTo:   to.Format(time.DateOnly),
+			Mode:              requests.SyncModeFull,
+			WriteMode:         requests.WriteModeAtomic,
+			GroupBy:           requests.GroupByNone,
+		}
+	}
+
+	result, err := client.FetchEnrollmentsFiltered(context.Background(), params())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalFetched != len(fetched) || result.OutsideDateRange != len(fetched)-inside {
+		t.Errorf("fetched %d and dropped %d, want %d fetched and %d dropped", result.TotalFetched, result.OutsideDateRange, len(fetched), len(fetched)-inside)
+	}
+	var written int
+	for _, call := range writer.Calls() {
+		written += len(call.Rows)
+	}
+	if written != want {
+		t.Errorf("wrote %d rows, want the %d inside the range", written, want)
+	}
+
+	rows, err := client.WriteEnrollmentsCSV(context.Background(), params(), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != want {
+		t.Errorf("CSV export wrote %d rows, want %d", rows, want)
+	}
+}
@@ -0,0 +1,36 @@
+package services
+
+import "github.com/SamuelLeutner/fetch-student-data/models"
+
+// enrollmentDeduper drops enrollments whose idMatricula was already seen in
+// the same fetch. Concurrent page fetches and retries can return a row twice,
+// typically when the dataset shifts between pages. The first occurrence wins
+// so streamed writes never have to take a row back.
+type enrollmentDeduper struct {
+	seen    map[int]struct{}
+	dropped int
+}
+
+func newEnrollmentDeduper() *enrollmentDeduper {
+	return &enrollmentDeduper{seen: make(map[int]struct{})}
+}
+
+// wrap returns a sink that forwards only unseen enrollments to sink.
+func (d *enrollmentDeduper) wrap(sink func([]models.Enrollment) error) func([]models.Enrollment) error {
+	return func(data []models.Enrollment) error {
+		unique := make([]models.Enrollment, 0, len(data))
+		for _, item := range data {
+			if _, ok := d.seen[item.IdMatricula]; ok {
+				d.dropped++
+				enrollmentDuplicatesDroppedTotal.Inc()
+				continue
+			}
+			d.seen[item.IdMatricula] = struct{}{}
+			unique = append(unique, item)
+		}
+		if len(unique) == 0 {
+			return nil
+		}
+		return sink(unique)
+	}
+}
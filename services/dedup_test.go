@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+func TestEnrollmentDeduper(t *testing.T) {
+	dedup := newEnrollmentDeduper()
+	var batches [][]int
+	sink := dedup.wrap(func(data []models.Enrollment) error {
+		var ids []int
+		for _, item := range data {
+			ids = append(ids, item.IdMatricula)
+		}
+		batches = append(batches, ids)
+		return nil
+	})
+	batch := func(ids ...int) []models.Enrollment {
+		data := make([]models.Enrollment, len(ids))
+		for i, id := range ids {
+			data[i] = models.Enrollment{IdMatricula: id}
+		}
+		return data
+	}
+
+	for _, data := range [][]models.Enrollment{batch(1, 2, 2), batch(3, 1), batch(2, 3), batch(4)} {
+		if err := sink(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := [][]int{{1, 2}, {3}, {4}}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Errorf("forwarded %v, want %v", batches, want)
+	}
+	if dedup.dropped != 4 {
+		t.Errorf("dropped %d duplicates, want 4", dedup.dropped)
+	}
+}
+
+// TestFetchEnrollmentsDropsDuplicates serves some enrollments twice, on
+// different pages, and expects each to be written once.
+func TestFetchEnrollmentsDropsDuplicates(t *testing.T) {
+	data := jacadmock.DemoDataset(720)
+	repeated := 0
+	for _, item := range data.Enrollments[:60] {
+		if item.IdPeriodoLetivo == 87 && item.Status != nil && *item.Status == "ATIVA" {
+			repeated++
+		}
+	}
+	data.Enrollments = append(data.Enrollments, data.Enrollments[:60]...)
+	server := jacadmock.NewServer(jacadmock.Options{Data: data})
+	defer server.Close()
+
+	for _, writeMode := range []string{requests.WriteModeAtomic, requests.WriteModeStream} {
+		t.Run(writeMode, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := config.Defaults()
+			cfg.APIBase = server.URL
+			cfg.UserToken = server.UserToken
+			cfg.PageSize = 50
+			cfg.RetryDelay = 0
+			cfg.JacadRateLimitRPS = 0
+			writer := NewFakeSheetWriter()
+			client := NewJacadClient(&cfg, writer, NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+
+			result, err := client.FetchEnrollmentsFiltered(context.Background(), &requests.FetchEnrollmentsRequest{
+				IdPeriodoLetivo: 87,
+				StatusMatricula: "ATIVA",
+				Mode:            requests.SyncModeFull,
+				WriteMode:       writeMode,
+				GroupBy:         requests.GroupByNone,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if repeated == 0 || result.DuplicatesDropped != repeated {
+				t.Errorf("dropped %d duplicates, want %d", result.DuplicatesDropped, repeated)
+			}
+			if len(result.Organizations) != 1 || result.Organizations[0].Rows != 180 {
+				t.Fatalf("organizations = %+v, want one sheet of 180 rows", result.Organizations)
+			}
+			if written, _ := writer.CountRows(context.Background(), result.Organizations[0].Sheet); written != 180 {
+				t.Errorf("sheet holds %d rows, want 180", written)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SheetReader is implemented by writers that can read a sheet back, header
+// row included. It is required for delta reports.
+type SheetReader interface {
+	ReadRows(ctx context.Context, sheetName string) ([][]interface{}, error)
+}
+
+const (
+	DeltaAdded         = "added"
+	DeltaRemoved       = "removed"
+	DeltaStatusChanged = "status_changed"
+)
+
+// DeltaCounts summarizes how a sheet changed compared to its previous contents.
+type DeltaCounts struct {
+	Added         int `json:"added"`
+	Removed       int `json:"removed"`
+	StatusChanged int `json:"statusChanged"`
+}
+
+var deltaHeaders = []string{"change", "sheet", "idMatricula", "aluno", "previousStatus", "status"}
+
+// deltaSheetName is the tab the delta report for a run started at t goes to.
+func deltaSheetName(t time.Time) string {
+	return "Changes " + t.Format("2006-01-02 15:04")
+}
+
+// diffSheet compares the current contents of sheetName with rows, keyed on
+// idMatricula, and returns one report row per added, removed or
+// status-changed enrollment.
+func (c *JacadClient) diffSheet(ctx context.Context, sheetName string, mapper *EnrollmentRowMapper, rows [][]interface{}) ([][]interface{}, DeltaCounts, error) {
+	var counts DeltaCounts
+	reader, ok := c.Writer.(SheetReader)
+	if !ok {
+		return nil, counts, fmt.Errorf("writer %T cannot read sheets back for a delta report", c.Writer)
+	}
+
+	keyHeader, ok := mapper.HeaderFor("idMatricula")
+	if !ok {
+		return nil, counts, fmt.Errorf("delta report requires the idMatricula column in the column mapping")
+	}
+	statusHeader, _ := mapper.HeaderFor("status")
+	nameHeader, _ := mapper.HeaderFor("aluno")
+
+	existing, err := reader.ReadRows(ctx, sheetName)
+	if err != nil {
+		return nil, counts, fmt.Errorf("failed to read sheet '%s' for delta report: %w", sheetName, err)
+	}
+
+	newIdx := headerIndexes(mapper.Headers(), keyHeader, statusHeader, nameHeader)
+	previous := make(map[string][]interface{})
+	var previousOrder []string
+	var oldIdx [3]int
+	if len(existing) > 0 {
+		oldHeaders := make([]string, len(existing[0]))
+		for i, h := range existing[0] {
+			oldHeaders[i] = fmt.Sprint(h)
+		}
+		oldIdx = headerIndexes(oldHeaders, keyHeader, statusHeader, nameHeader)
+		if oldIdx[0] < 0 {
+			return nil, counts, fmt.Errorf("existing sheet '%s' has no '%s' column to diff on", sheetName, keyHeader)
+		}
+		for _, row := range existing[1:] {
+			key := deltaValue(cellAt(row, oldIdx[0]))
+			if key == "" {
+				continue
+			}
+			if _, seen := previous[key]; !seen {
+				previousOrder = append(previousOrder, key)
+			}
+			previous[key] = row
+		}
+	}
+
+	var report [][]interface{}
+	current := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		key := deltaValue(cellAt(row, newIdx[0]))
+		current[key] = true
+		status := deltaValue(cellAt(row, newIdx[1]))
+		old, found := previous[key]
+		switch {
+		case !found:
+			counts.Added++
+			report = append(report, []interface{}{DeltaAdded, sheetName, key, deltaValue(cellAt(row, newIdx[2])), "", status})
+		case statusHeader != "" && oldIdx[1] >= 0 && deltaValue(cellAt(old, oldIdx[1])) != status:
+			counts.StatusChanged++
+			report = append(report, []interface{}{DeltaStatusChanged, sheetName, key, deltaValue(cellAt(row, newIdx[2])), deltaValue(cellAt(old, oldIdx[1])), status})
+		}
+	}
+	for _, key := range previousOrder {
+		if current[key] {
+			continue
+		}
+		old := previous[key]
+		counts.Removed++
+		report = append(report, []interface{}{DeltaRemoved, sheetName, key, deltaValue(cellAt(old, oldIdx[2])), deltaValue(cellAt(old, oldIdx[1])), ""})
+	}
+	return report, counts, nil
+}
+
+// headerIndexes returns the positions of the key, status and name headers,
+// or -1 for those missing.
+func headerIndexes(headers []string, key, status, name string) [3]int {
+	idx := [3]int{-1, -1, -1}
+	for n, name := range []string{key, status, name} {
+		if name == "" {
+			continue
+		}
+		for i, h := range headers {
+			if h == name {
+				idx[n] = i
+				break
+			}
+		}
+	}
+	return idx
+}
+
+func cellAt(row []interface{}, i int) interface{} {
+	if i < 0 || i >= len(row) {
+		return nil
+	}
+	return row[i]
+}
+
+// deltaValue normalizes a cell so values read back from a sheet compare equal
+// to freshly mapped ones (e.g. 123 and 123.0).
+func deltaValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case time.Time:
+		return val.Format("2006-01-02")
+	}
+	return strings.TrimSpace(fmt.Sprint(v))
+}
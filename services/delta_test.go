@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+)
+
+func TestDiffSheet(t *testing.T) {
+	tests := []struct {
+		name       string
+		sheet      [][]interface{}
+		wantReport [][]interface{}
+		wantCounts DeltaCounts
+	}{
+		{
+			name: "empty sheet",
+			wantReport: [][]interface{}{
+				{DeltaAdded, "Alunos", "1", "Ana", "", "Ativo"},
+				{DeltaAdded, "Alunos", "2", "Bruno", "", "Trancado"},
+				{DeltaAdded, "Alunos", "3", "Carla", "", "Ativo"},
+			},
+			wantCounts: DeltaCounts{Added: 3},
+		},
+		{
+			name:  "added, removed and status-changed rows",
+			sheet: [][]interface{}{{"ID", "Nome", "Status"}, {1.0, "Ana", "Ativo"}, {2.0, "Bruno", "Ativo"}, {4.0, "Davi", "Ativo"}},
+			wantReport: [][]interface{}{
+				{DeltaStatusChanged, "Alunos", "2", "Bruno", "Ativo", "Trancado"},
+				{DeltaAdded, "Alunos", "3", "Carla", "", "Ativo"},
+				{DeltaRemoved, "Alunos", "4", "Davi", "Ativo", ""},
+			},
+			wantCounts: DeltaCounts{Added: 1, Removed: 1, StatusChanged: 1},
+		},
+		{
+			name:       "columns in another order",
+			sheet:      [][]interface{}{{"Status", "Nome", "ID"}, {"Ativo", "Ana", "1"}, {"Trancado", "Bruno", "2"}, {"Ativo", "Carla", "3"}},
+			wantCounts: DeltaCounts{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeSheetsAPI()
+			api.SetTab("book", "Alunos", tt.sheet)
+			c := &JacadClient{Config: &config.Config{SpreadsheetID: "book"}, Writer: newFakeSheetsWriter(t, api)}
+			mapper := incrementalTestMapper(t)
+
+			report, counts, err := c.diffSheet(context.Background(), "Alunos", mapper, mapper.Rows(incrementalTestData()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(report, tt.wantReport) || counts != tt.wantCounts {
+				t.Errorf("diffSheet() = %v, %+v; want %v, %+v", report, counts, tt.wantReport, tt.wantCounts)
+			}
+		})
+	}
+}
+
+func TestDiffSheetErrors(t *testing.T) {
+	api := newFakeSheetsAPI()
+	api.SetTab("book", "Alunos", [][]interface{}{{"Nome", "Status"}, {"Ana", "Ativo"}})
+	mapper := incrementalTestMapper(t)
+	noKey, err := NewEnrollmentRowMapper([]config.Column{{Field: "aluno", Header: "Nome"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		writer SheetWriter
+		mapper *EnrollmentRowMapper
+	}{
+		{name: "writer cannot read back", writer: NewFakeSheetWriter(), mapper: mapper},
+		{name: "mapping without idMatricula", writer: newFakeSheetsWriter(t, api), mapper: noKey},
+		{name: "sheet without the key column", writer: newFakeSheetsWriter(t, api), mapper: mapper},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &JacadClient{Config: &config.Config{SpreadsheetID: "book"}, Writer: tt.writer}
+			if _, _, err := c.diffSheet(context.Background(), "Alunos", tt.mapper, tt.mapper.Rows(incrementalTestData())); err == nil {
+				t.Error("diffSheet() succeeded, want an error")
+			}
+		})
+	}
+}
+
+// TestFetchEnrollmentsDeltaReport runs a delta report twice, editing the
+// sheet in between, and checks the counts and the Changes tab of each run.
+func TestFetchEnrollmentsDeltaReport(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.PageSize = 50
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	writer := NewCSVWriter(filepath.Join(dir, "out"))
+	client := NewJacadClient(&cfg, writer, NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+	ctx := context.Background()
+	fetch := func(t *testing.T) *FetchResult {
+		t.Helper()
+		result, err := client.FetchEnrollmentsFiltered(ctx, &requests.FetchEnrollmentsRequest{
+			OrgIds:          "20",
+			IdPeriodoLetivo: 87,
+			StatusMatricula: "ATIVA",
+			Mode:            requests.SyncModeFull,
+			WriteMode:       requests.WriteModeAtomic,
+			GroupBy:         requests.GroupByNone,
+			DeltaReport:     true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Organizations) != 1 || result.Organizations[0].Delta == nil {
+			t.Fatalf("organizations = %+v, want one with delta counts", result.Organizations)
+		}
+		return result
+	}
+
+	first := fetch(t)
+	if got, want := *first.Organizations[0].Delta, (DeltaCounts{Added: 60}); got != want {
+		t.Errorf("first run delta = %+v, want %+v", got, want)
+	}
+	if changes, _ := writer.CountRows(ctx, first.DeltaSheet); changes != 60 {
+		t.Errorf("first run wrote %d changes, want 60", changes)
+	}
+
+	// Drop the last enrollment, change the status of the first and add one
+	// the query does not return.
+	sheet := first.Organizations[0].Sheet
+	rows, err := writer.ReadRows(ctx, sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := make([]string, len(rows[0]))
+	for i, h := range rows[0] {
+		headers[i] = h.(string)
+	}
+	edited := rows[1 : len(rows)-1]
+	edited[0][5] = "TRANCADA"
+	extra := make([]interface{}, len(headers))
+	extra[0] = "999999"
+	edited = append(edited, extra)
+	if err := writer.OverwriteSheetData(ctx, sheet, headers, edited); err != nil {
+		t.Fatal(err)
+	}
+
+	second := fetch(t)
+	if got, want := *second.Organizations[0].Delta, (DeltaCounts{Added: 1, Removed: 1, StatusChanged: 1}); got != want {
+		t.Errorf("second run delta = %+v, want %+v", got, want)
+	}
+	report, err := writer.ReadRows(ctx, second.DeltaSheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes []interface{}
+	for _, row := range report[1:] {
+		changes = append(changes, row[0])
+	}
+	if want := []interface{}{DeltaStatusChanged, DeltaAdded, DeltaRemoved}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("second run changes = %v, want %v", changes, want)
+	}
+}
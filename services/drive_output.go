@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"google.golang.org/api/drive/v3"
+)
+
+const spreadsheetMimeType = "application/vnd.google-apps.spreadsheet"
+
+// CreatedSpreadsheet is the spreadsheet a single job writes to: one created
+// for it, or the one it asked for with spreadsheetId.
+type CreatedSpreadsheet struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// SpreadsheetCreator is implemented by writers that can create a new
+// spreadsheet for a job instead of writing to a shared one.
+type SpreadsheetCreator interface {
+	CreateSpreadsheet(ctx context.Context, title string) (*CreatedSpreadsheet, error)
+}
+
+// CreateSpreadsheet creates an empty spreadsheet named title in the writer's
+// Drive folder.
+func (w *GoogleSheetsWriter) CreateSpreadsheet(ctx context.Context, title string) (*CreatedSpreadsheet, error) {
+	if w.driveService == nil {
+		return nil, fmt.Errorf("nenhuma pasta do Drive configurada (DRIVE_FOLDER_ID) para criar a planilha '%s'", title)
+	}
+	logger := logging.FromContext(ctx).With("title", title, "folderId", w.driveFolderID)
+
+	var file *drive.File
+	createCallFunc := func() error {
+		logger.Info("API Drive: Criando planilha na pasta...")
+		var err error
+		file, err = w.driveService.Files.Create(&drive.File{
+			Name:     title,
+			MimeType: spreadsheetMimeType,
+			Parents:  []string{w.driveFolderID},
+		}).SupportsAllDrives(true).Fields("id", "webViewLink").Context(ctx).Do()
+		return err
+	}
+	if err := w.executeSheetsCall(ctx, "create_spreadsheet", createCallFunc, fmt.Sprintf("criar planilha '%s'", title)); err != nil {
+		return nil, fmt.Errorf("falha ao criar a planilha '%s' na pasta '%s': %w", title, w.driveFolderID, err)
+	}
+
+	url := file.WebViewLink
+	if url == "" {
+		url = spreadsheetURL(file.Id)
+	}
+	logger.Info("API Drive: Planilha criada com sucesso.", "spreadsheetId", file.Id)
+	return &CreatedSpreadsheet{ID: file.Id, URL: url}, nil
+}
+
+func spreadsheetURL(id string) string {
+	return "https://docs.google.com/spreadsheets/d/" + id
+}
+
+type jobSpreadsheetKey struct{}
+
+// withJobSpreadsheet routes every write made with ctx to spreadsheet,
+// overriding the per-organization spreadsheets.
+func withJobSpreadsheet(ctx context.Context, spreadsheet *CreatedSpreadsheet) context.Context {
+	ctx = context.WithValue(ctx, jobSpreadsheetKey{}, spreadsheet)
+	return WithSpreadsheetID(ctx, spreadsheet.ID)
+}
+
+// jobSpreadsheet returns the spreadsheet created for the job in ctx, if any.
+func jobSpreadsheet(ctx context.Context) *CreatedSpreadsheet {
+	spreadsheet, _ := ctx.Value(jobSpreadsheetKey{}).(*CreatedSpreadsheet)
+	return spreadsheet
+}
+
+// jobSpreadsheetTitle names a job's spreadsheet after its period, status and
+// start time.
+func jobSpreadsheetTitle(params *requests.FetchEnrollmentsRequest, startTime time.Time) string {
+	title := "Matrículas | Período ID " + params.PeriodLabel()
+	if status := params.StatusLabel(); status != "" {
+		title += " | STATUS: " + status
+	}
+	return title + " | " + startTime.Format("2006-01-02 15:04")
+}
+
+// createJobSpreadsheet creates the spreadsheet a newSpreadsheet job writes to
+// and returns ctx routed to it.
+func (c *JacadClient) createJobSpreadsheet(ctx context.Context, logger *slog.Logger, params *requests.FetchEnrollmentsRequest, startTime time.Time) (context.Context, error) {
+	creator, ok := c.Writer.(SpreadsheetCreator)
+	if !ok {
+		return nil, fmt.Errorf("writer %T cannot create spreadsheets", c.Writer)
+	}
+	spreadsheet, err := creator.CreateSpreadsheet(ctx, jobSpreadsheetTitle(params, startTime))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the job spreadsheet: %w", err)
+	}
+	logger.Info("Writing to a new spreadsheet", "spreadsheetId", spreadsheet.ID, "url", spreadsheet.URL)
+	return withJobSpreadsheet(ctx, spreadsheet), nil
+}
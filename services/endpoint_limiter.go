@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// EndpointLimiter caps concurrent Jacad requests per endpoint, so a heavy
+// enrollments job cannot take every slot of the global ConcurrencyController
+// and starve lightweight lookups running next to it. Endpoints without a
+// limit only share the global cap. A nil *EndpointLimiter never blocks.
+type EndpointLimiter struct {
+	// endpoints is sorted by descending path length so the most specific
+	// path matches first.
+	endpoints []endpointSlots
+}
+
+type endpointSlots struct {
+	name  string
+	path  string
+	slots chan struct{}
+}
+
+// NewEndpointLimiter limits the Jacad endpoints named in limits, keyed like
+// config.Endpoints (e.g. ENROLLMENTS). It returns nil when no limit is set.
+func NewEndpointLimiter(endpoints map[string]string, limits map[string]int) *EndpointLimiter {
+	l := &EndpointLimiter{}
+	for name, limit := range limits {
+		path := endpoints[name]
+		if path == "" || limit <= 0 {
+			continue
+		}
+		l.endpoints = append(l.endpoints, endpointSlots{name: name, path: path, slots: make(chan struct{}, limit)})
+	}
+	if len(l.endpoints) == 0 {
+		return nil
+	}
+	sort.Slice(l.endpoints, func(i, j int) bool {
+		return len(l.endpoints[i].path) > len(l.endpoints[j].path)
+	})
+	return l
+}
+
+// Acquire blocks until the endpoint serving path has a free slot or ctx is
+// done. The returned release must be called once the request is finished.
+func (l *EndpointLimiter) Acquire(ctx context.Context, path string) (func(), error) {
+	slots := l.slotsFor(path)
+	if slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case slots.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	jacadEndpointInFlight.Add(1, slots.name)
+	return func() {
+		<-slots.slots
+		jacadEndpointInFlight.Add(-1, slots.name)
+	}, nil
+}
+
+func (l *EndpointLimiter) slotsFor(path string) *endpointSlots {
+	if l == nil {
+		return nil
+	}
+	for i := range l.endpoints {
+		if strings.HasPrefix(path, l.endpoints[i].path) {
+			return &l.endpoints[i]
+		}
+	}
+	return nil
+}
+
+// acquireRequestSlot takes a slot for endpoint and then one under the global
+// adaptive limit. The endpoint slot comes first so a request queued behind
+// its own endpoint does not hold a global slot other endpoints could use.
+func (c *JacadClient) acquireRequestSlot(ctx context.Context, endpoint string) (func(), error) {
+	releaseEndpoint, err := c.endpoints.Acquire(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.concurrency.Acquire(ctx); err != nil {
+		releaseEndpoint()
+		return nil, err
+	}
+	return func() {
+		c.concurrency.Release()
+		releaseEndpoint()
+	}, nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+// DefaultEnrollmentSort is the order rows are written in when the request
+// sets no sortBy.
+const DefaultEnrollmentSort = "idMatricula"
+
+// EnrollmentSort orders enrollments by one or more fields. Batches of pages
+// are collected concurrently, so without it rows would come out in a
+// different order on every run.
+type EnrollmentSort []enrollmentSortKey
+
+type enrollmentSortKey struct {
+	field int
+	desc  bool
+}
+
+// ParseEnrollmentSort parses a comma-separated list of enrollment fields,
+// each prefixed with '-' to sort it descending. Empty means
+// DefaultEnrollmentSort. Ties are broken by idMatricula so the order does
+// not depend on the order enrollments were fetched in.
+func ParseEnrollmentSort(spec string) (EnrollmentSort, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = DefaultEnrollmentSort
+	}
+	var keys EnrollmentSort
+	seen := make(map[string]bool)
+	for _, term := range strings.Split(spec, ",") {
+		term = strings.TrimSpace(term)
+		name := strings.TrimPrefix(term, "-")
+		if name == "" {
+			continue
+		}
+		idx, ok := enrollmentFields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown enrollment field '%s'", name)
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		keys = append(keys, enrollmentSortKey{field: idx, desc: term != name})
+	}
+	if !seen["idMatricula"] {
+		keys = append(keys, enrollmentSortKey{field: enrollmentFields["idMatricula"]})
+	}
+	return keys, nil
+}
+
+// Sort sorts data in place.
+func (s EnrollmentSort) Sort(data []models.Enrollment) {
+	slices.SortStableFunc(data, func(a, b models.Enrollment) int {
+		va, vb := reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem()
+		for _, key := range s {
+			if c := compareFields(va.Field(key.field), vb.Field(key.field)); c != 0 {
+				if key.desc {
+					return -c
+				}
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// compareFields compares two values of the same enrollment field. Missing
+// strings sort as empty and missing dates before every date.
+func compareFields(a, b reflect.Value) int {
+	switch va := a.Interface().(type) {
+	case int:
+		return cmp.Compare(va, b.Interface().(int))
+	case *string:
+		return strings.Compare(deref(va), deref(b.Interface().(*string)))
+	case *utils.Date:
+		vb := b.Interface().(*utils.Date)
+		switch {
+		case va == nil && vb == nil:
+			return 0
+		case va == nil:
+			return -1
+		case vb == nil:
+			return 1
+		}
+		return time.Time(*va).Compare(time.Time(*vb))
+	}
+	return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+}
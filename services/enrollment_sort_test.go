@@ -0,0 +1,59 @@
+package services
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+func TestEnrollmentSort(t *testing.T) {
+	str := func(s string) *string { return &s }
+	date := func(s string) *utils.Date {
+		d, _ := time.Parse(time.DateOnly, s)
+		u := utils.Date(d)
+		return &u
+	}
+	data := []models.Enrollment{
+		{IdMatricula: 4, Organizacao: str("EAD"), Curso: str("Direito"), Aluno: str("Bruno"), DataMatricula: date("2025-02-01")},
+		{IdMatricula: 2, Organizacao: str("Presencial"), Curso: str("Direito"), Aluno: str("Ana")},
+		{IdMatricula: 3, Organizacao: str("EAD"), Curso: str("Direito"), Aluno: str("Ana"), DataMatricula: date("2025-03-01")},
+		{IdMatricula: 1, Organizacao: str("EAD"), Curso: str("Administração"), Aluno: str("Carla"), DataMatricula: date("2025-01-01")},
+		{IdMatricula: 5, Organizacao: str("EAD"), Curso: str("Direito"), Aluno: str("Ana"), DataMatricula: date("2025-01-15")},
+	}
+
+	tests := []struct {
+		spec string
+		want []int
+	}{
+		{spec: "", want: []int{1, 2, 3, 4, 5}},
+		{spec: "-idMatricula", want: []int{5, 4, 3, 2, 1}},
+		{spec: "organizacao,curso,aluno", want: []int{1, 3, 5, 4, 2}},
+		{spec: "organizacao, -curso", want: []int{3, 4, 5, 1, 2}},
+		{spec: "dataMatricula", want: []int{2, 1, 5, 4, 3}},
+		{spec: "-dataMatricula", want: []int{3, 4, 5, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			order, err := ParseEnrollmentSort(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sorted := slices.Clone(data)
+			order.Sort(sorted)
+			var got []int
+			for _, item := range sorted {
+				got = append(got, item.IdMatricula)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseEnrollmentSort("curso,nota"); err == nil {
+		t.Error("ParseEnrollmentSort accepted an unknown field")
+	}
+}
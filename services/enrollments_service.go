@@ -2,156 +2,993 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
 	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
 	"github.com/SamuelLeutner/fetch-student-data/models"
 	"github.com/SamuelLeutner/fetch-student-data/utils"
 )
 
-func (c *JacadClient) FetchEnrollmentsFiltered(ctx context.Context, params *requests.FetchEnrollmentsRequest) error {
-	log.Printf("Starting filtered enrollment fetch for PeriodoLetivo='%d', StatusMatricula='%s' (with context)...", params.IdPeriodoLetivo, params.StatusMatricula)
+// FetchEnrollmentsFiltered fetches and writes enrollments for params,
+// publishing progress under the job ID in ctx and notifying the
+// configured channels when done.
+func (c *JacadClient) FetchEnrollmentsFiltered(ctx context.Context, params *requests.FetchEnrollmentsRequest) (*FetchResult, error) {
+	startedAt := time.Now()
+	deadline, hasDeadline := ctx.Deadline()
+	c.progress.start(logging.JobID(ctx), deadline, hasDeadline)
+	result, err := c.fetchEnrollmentsFiltered(ctx, params)
+	var failedPages []FailedPage
+	if result != nil {
+		failedPages = result.FailedPages
+		if transfer := c.transfers.peek(ctx); transfer.Requests > 0 {
+			result.Transfer = &transfer
+		}
+		if multi, ok := c.Writer.(*MultiWriter); ok {
+			result.Destinations = multi.PeekOutcomes(ctx)
+		}
+	}
+	c.finishJob(withSheetSpreadsheets(ctx, c.sheetSpreadsheets(result)), enrollmentSummary(result, params.Tenant), params, writtenSheets(result), failedPages, params.DryRun, startedAt, err)
+	if result != nil {
+		if state := c.CircuitState(WithTenant(ctx, params.Tenant)); state != CircuitClosed {
+			result.CircuitBreaker = state
+		}
+	}
+	c.reportProgress(ctx, func(e *ProgressEvent) {
+		e.Stage = ProgressStageDone
+		e.ETASeconds = 0
+		if err != nil {
+			e.Stage = ProgressStageFailed
+			e.Error = err.Error()
+		}
+	})
+	return result, err
+}
+
+func (c *JacadClient) fetchEnrollmentsFiltered(ctx context.Context, params *requests.FetchEnrollmentsRequest) (*FetchResult, error) {
+	logger := logging.FromContext(ctx).With("idPeriodoLetivo", params.PeriodLabel(), "statusMatricula", params.StatusLabel(), "orgId", params.OrgId, "orgIds", params.OrgIds)
+	logger.Info("Starting filtered enrollment fetch")
 	startTime := time.Now()
 
-	headers := []string{
-		"idMatricula", "aluno", "ra", "curso",
-		"turma", "status", "periodoLetivo",
-		"unidadeFisica", "organizacao",
-		"idOrg", "dataMatricula",
-		"dataAtivacao", "dataCadastro",
+	if params.BypassCache {
+		ctx = WithCacheBypass(ctx)
+	}
+	if params.PageSize > 0 {
+		ctx = WithPageSize(ctx, params.PageSize)
+	}
+	if params.PartialPages() {
+		start, end := params.PageRange()
+		ctx = WithPageRange(ctx, start, end)
+	}
+	ctx = WithTenant(ctx, params.Tenant)
+
+	mapper, err := c.rowMapper(logger, params)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchParams := enrollmentFetchParams(params)
+
+	mode := params.Mode
+	if mode == "" {
+		mode = requests.SyncModeFull
+	}
+	if mode != requests.SyncModeFull && mode != requests.SyncModeIncremental {
+		return nil, fmt.Errorf("invalid mode '%s': expected '%s' or '%s'", mode, requests.SyncModeFull, requests.SyncModeIncremental)
+	}
+
+	writeMode := params.WriteMode
+	if writeMode == "" || params.DryRun {
+		writeMode = requests.WriteModeAtomic
+	}
+	if writeMode != requests.WriteModeAtomic && writeMode != requests.WriteModeStream {
+		return nil, fmt.Errorf("invalid writeMode '%s': expected '%s' or '%s'", writeMode, requests.WriteModeAtomic, requests.WriteModeStream)
+	}
+	if writeMode == requests.WriteModeStream && mode != requests.SyncModeFull {
+		return nil, fmt.Errorf("writeMode '%s' is only supported with mode '%s'", requests.WriteModeStream, requests.SyncModeFull)
+	}
+	if params.DeltaReport && (mode != requests.SyncModeFull || writeMode != requests.WriteModeAtomic) {
+		return nil, fmt.Errorf("deltaReport is only supported with mode '%s' and writeMode '%s'", requests.SyncModeFull, requests.WriteModeAtomic)
+	}
+
+	groupBy := params.GroupBy
+	if groupBy == "" {
+		groupBy = requests.GroupByNone
+	}
+	if !requests.IsValidGroupBy(groupBy) {
+		return nil, fmt.Errorf("invalid groupBy '%s': expected one of %s", groupBy, strings.Join(requests.GroupByOptions, ", "))
+	}
+	if params.SplitByStatus && groupBy != requests.GroupByNone {
+		return nil, fmt.Errorf("splitByStatus cannot be combined with groupBy '%s'", groupBy)
+	}
+	if params.SpreadsheetId != "" && !c.Config.SpreadsheetAllowed(params.SpreadsheetId) {
+		return nil, fmt.Errorf("spreadsheet '%s' is not SPREADSHEET_ID nor listed in SPREADSHEET_ALLOWLIST", params.SpreadsheetId)
+	}
+	order, err := ParseEnrollmentSort(params.SortBy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sortBy: %w", err)
+	}
+
+	targets, err := c.resolveSheetTargets(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.With("mode", mode, "writeMode", writeMode, "groupBy", groupBy)
+	logger.Info("Sheet targets determined", "targets", len(targets))
+
+	if params.NewSpreadsheet && !params.DryRun {
+		if conflict := params.NewSpreadsheetConflict(); conflict != "" {
+			return nil, fmt.Errorf("newSpreadsheet is not supported with %s", conflict)
+		}
+		if ctx, err = c.createJobSpreadsheet(ctx, logger, params, startTime); err != nil {
+			return nil, err
+		}
+	} else if params.SpreadsheetId != "" {
+		logger.Info("Writing to the requested spreadsheet", "spreadsheetId", params.SpreadsheetId)
+		ctx = withJobSpreadsheet(ctx, &CreatedSpreadsheet{ID: params.SpreadsheetId, URL: spreadsheetURL(params.SpreadsheetId)})
+	}
+
+	if writeMode == requests.WriteModeStream {
+		return c.streamEnrollmentsToTargets(ctx, logger, targets, mapper, fetchParams, startTime, params, groupBy)
+	}
+
+	var allEnrollments []models.Enrollment
+	collect := func(data []models.Enrollment) error {
+		allEnrollments = append(allEnrollments, data...)
+		return nil
+	}
+	window, err := newDataMatriculaWindow(params)
+	if err != nil {
+		return nil, err
+	}
+	dedup := newEnrollmentDeduper()
+	quality := newQualityCheck(c.Config.DataQualityRules)
+	stats := newEnrollmentSummary(params, targets)
+	fetched, failed, cp, err := c.fetchEnrollmentQueries(ctx, logger, fetchParams, startTime, !params.DryRun, params.Resume, dedup.wrap(window.wrap(quality.wrap(stats.wrap(collect)))))
+	if err != nil {
+		return nil, err
+	}
+	logDuplicates(logger, dedup)
+	logOutsideWindow(logger, window)
+	order.Sort(allEnrollments)
+
+	result := &FetchResult{
+		Mode:              mode,
+		WriteMode:         writeMode,
+		DryRun:            params.DryRun,
+		Anonymized:        params.Anonymize,
+		Tenant:            params.Tenant,
+		TotalFetched:      fetched,
+		FailedBatches:     failed.batches,
+		FailedPages:       failed.pages,
+		DuplicatesDropped: dedup.dropped,
+		OutsideDateRange:  window.Dropped(),
+		Spreadsheet:       jobSpreadsheet(ctx),
+	}
+
+	c.reportProgress(ctx, func(e *ProgressEvent) {
+		e.Stage = ProgressStageWriting
+		e.ETASeconds = 0
+	})
+
+	hold := syncStateHold(failed, params)
+	var lastErr error
+	var deltaRows [][]interface{}
+	var snapshots snapshotSets
+	failures := 0
+	sheets := 0
+	for _, target := range targets {
+		data := allEnrollments
+		if target.PartitionByOrg {
+			data = filterEnrollmentsByOrg(allEnrollments, target.OrgID)
+		}
+
+		for _, group := range c.groupEnrollments(target, data, groupBy, params) {
+			sheets++
+			summary := OrgSummary{OrgID: target.OrgID, OrgName: target.OrgName, Sheet: group.Sheet, Group: group.Group}
+			if cp != nil && cp.SheetWritten(group.Sheet) {
+				logger.Info("Sheet already written by a previous attempt. Skipping.", "sheet", group.Sheet)
+				summary.Rows = len(group.Data)
+				summary.Resumed = true
+				result.Organizations = append(result.Organizations, summary)
+				if params.Snapshot {
+					snapshots.add(c.spreadsheetContext(ctx, group.OrgID), group.Sheet)
+				}
+				continue
+			}
+
+			if params.DeltaReport {
+				report, counts, err := c.diffSheet(c.spreadsheetContext(ctx, group.OrgID), group.Sheet, mapper, mapper.Rows(group.Data))
+				if err != nil {
+					logger.Warn("Failed to compute delta report for sheet", "sheet", group.Sheet, "error", err)
+					summary.DeltaError = err.Error()
+				} else {
+					summary.Delta = &counts
+					deltaRows = append(deltaRows, report...)
+				}
+			}
+
+			if params.DryRun {
+				previewLimit := params.PreviewRows
+				if previewLimit <= 0 {
+					previewLimit = c.Config.DryRunPreviewRows
+				}
+				summary.Rows, summary.Preview, err = c.previewEnrollmentsForTarget(c.spreadsheetContext(ctx, group.OrgID), mode, group.Sheet, mapper, group.Data, previewLimit)
+			} else {
+				summary.Rows, err = c.writeEnrollmentsToTarget(c.spreadsheetContext(ctx, group.OrgID), mode, group.Sheet, mapper, group.Data, hold)
+			}
+			if err != nil {
+				logger.Error("Failed to write enrollments for organization", "orgId", target.OrgID, "sheet", group.Sheet, "error", err)
+				summary.Error = err.Error()
+				lastErr = err
+				failures++
+			} else {
+				if !params.DryRun {
+					c.reportProgress(ctx, func(e *ProgressEvent) { e.RowsWritten += summary.Rows })
+					if params.Snapshot {
+						snapshots.add(c.spreadsheetContext(ctx, group.OrgID), group.Sheet)
+					}
+				}
+				if cp != nil {
+					if err := cp.MarkSheetWritten(group.Sheet); err != nil {
+						logger.Warn("Failed to checkpoint written sheet", "sheet", group.Sheet, "error", err)
+					}
+				}
+			}
+			result.Organizations = append(result.Organizations, summary)
+		}
+	}
+
+	if params.DeltaReport && !params.DryRun {
+		name := deltaSheetName(startTime)
+		logger.Info("Writing delta report sheet", "sheet", name, "changes", len(deltaRows))
+		if err := c.Writer.OverwriteSheetData(ctx, name, deltaHeaders, deltaRows); err != nil {
+			logger.Error("Failed to write delta report sheet", "sheet", name, "error", err)
+			result.DeltaError = err.Error()
+		} else {
+			result.DeltaSheet = name
+		}
+	}
+	c.writeRejects(ctx, logger, quality, mapper, params, result)
+	c.writeSummary(ctx, logger, stats, params, startTime, result)
+	c.pruneSnapshots(logger, &snapshots, startTime, result)
+
+	if cp != nil && failures == 0 && failed.batches == 0 && len(failed.pages) == 0 {
+		if err := c.Checkpoints.Delete(cp.Key()); err != nil {
+			logger.Warn("Failed to delete checkpoint after a successful run", "error", err)
+		}
 	}
 
-	fetchParams := make(map[string]string)
-	if params.IdPeriodoLetivo != 0 {
-		fetchParams["idPeriodoLetivo"] = strconv.Itoa(params.IdPeriodoLetivo)
+	if sheets > 0 && failures == sheets {
+		return result, fmt.Errorf("failed to write enrollments to %d sheet(s): %w", failures, lastErr)
+	}
+
+	logger.Info("Process completed!", "fetched", fetched, "sheets", sheets, "failedSheets", failures, "duration", time.Since(startTime).String())
+	return result, nil
+}
+
+// rowMapper builds the mapper for the configured columns, narrowed to the
+// requested fields, with the configured transforms and column types, and
+// redacted when params asks to anonymize.
+func (c *JacadClient) rowMapper(logger *slog.Logger, params *requests.FetchEnrollmentsRequest) (*EnrollmentRowMapper, error) {
+	mapper, err := NewEnrollmentRowMapper(c.Config.Columns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid column mapping: %w", err)
+	}
+	if fields := params.ParseFields(); len(fields) > 0 {
+		if mapper, err = mapper.Select(fields); err != nil {
+			return nil, fmt.Errorf("invalid fields: %w", err)
+		}
+	}
+	if mapper, err = mapper.Transformed(c.Config.Transforms); err != nil {
+		return nil, fmt.Errorf("invalid transforms: %w", err)
+	}
+	if mapper, err = mapper.Coerced(c.Config.DateCells, c.Config.DateTextLayout, c.Config.NumberLocale); err != nil {
+		return nil, fmt.Errorf("invalid column types: %w", err)
 	}
-	if params.StatusMatricula != "" {
-		fetchParams["statusMatricula"] = params.StatusMatricula
+	if !params.Anonymize {
+		return mapper, nil
 	}
 
-	sheetName := c.determineSheetName(params)
-	log.Printf("Sheet name determined: '%s'", sheetName)
+	if c.Config.RedactionSalt == "" {
+		logger.Warn("Anonymizing without REDACTION_SALT. Hashed values can be reversed by brute force.")
+	}
+	mapper, err = mapper.Redacted(c.Config.RedactionPolicy, c.Config.RedactionSalt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redaction policy: %w", err)
+	}
+	return mapper, nil
+}
+
+func logDuplicates(logger *slog.Logger, dedup *enrollmentDeduper) {
+	if dedup.dropped > 0 {
+		logger.Warn("Dropped duplicate enrollments fetched more than once", "duplicates", dedup.dropped)
+	}
+}
+
+// enrollmentFetchParams builds the Jacad query filters for params, one query
+// per combination of requested period and status.
+func enrollmentFetchParams(params *requests.FetchEnrollmentsRequest) []map[string]string {
+	periods := params.PeriodIDs()
+	if len(periods) == 0 {
+		periods = []int{0}
+	}
+	statuses := params.Statuses()
+	if len(statuses) == 0 {
+		statuses = []string{""}
+	}
+
+	queries := make([]map[string]string, 0, len(periods)*len(statuses))
+	for _, period := range periods {
+		for _, status := range statuses {
+			fetchParams := make(map[string]string)
+			if period != 0 {
+				fetchParams["idPeriodoLetivo"] = strconv.Itoa(period)
+			}
+			if status != "" {
+				fetchParams["statusMatricula"] = status
+			}
+			queries = append(queries, fetchParams)
+		}
+	}
+	return queries
+}
+
+// fetchEnrollmentQueries runs fetchAllEnrollments for each query in turn into
+// the same sink, so the results merge into one dataset; callers dedup as
+// usual. Only single-query fetches are checkpointed, so the returned
+// checkpoint is nil when there are several queries.
+func (c *JacadClient) fetchEnrollmentQueries(ctx context.Context, logger *slog.Logger, queries []map[string]string, startTime time.Time, checkpointing, resume bool, sink func([]models.Enrollment) error) (int, pageFailures, *Checkpoint, error) {
+	if len(queries) == 1 {
+		return c.fetchAllEnrollments(ctx, logger, queries[0], startTime, checkpointing, resume, sink)
+	}
+
+	logger.Info("Fetching enrollments with several Jacad queries", "queries", len(queries))
+	fetched := 0
+	var failed pageFailures
+	for i, query := range queries {
+		n, queryFailed, _, err := c.fetchAllEnrollments(ctx, logger.With("query", i+1, "queries", len(queries)), query, startTime, false, false, sink)
+		fetched += n
+		failed.batches += queryFailed.batches
+		failed.pages = append(failed.pages, queryFailed.pages...)
+		if err != nil {
+			return fetched, failed, nil, fmt.Errorf("query %d of %d: %w", i+1, len(queries), err)
+		}
+	}
+	return fetched, failed, nil, nil
+}
 
-	log.Println("Fetching initial page (0) to get total pages...")
-	firstPageElements, Page, err := c.FetchPage(ctx, c.Config.Endpoints["ENROLLMENTS"], 0, c.Config.PageSize, fetchParams)
+// fetchAllEnrollments fetches every page for fetchParams and hands each chunk of
+// enrollments to sink as soon as it is available, returning how many were
+// fetched and which pages were lost. When checkpointing is enabled each fetched page is persisted, and
+// with resume the pages already stored by a previous failed attempt are reused
+// instead of re-fetched. A page range set with WithPageRange limits the fetch
+// to those pages and disables checkpointing.
+func (c *JacadClient) fetchAllEnrollments(ctx context.Context, logger *slog.Logger, fetchParams map[string]string, startTime time.Time, checkpointing, resume bool, sink func([]models.Enrollment) error) (int, pageFailures, *Checkpoint, error) {
+	pageSize := c.pageSize(ctx)
+	first, last := pageRange(ctx)
+	partial := first > 0 || last > 0
+	logger.Info(fmt.Sprintf("Fetching initial page (%d) to get total pages...", first), "pageSize", pageSize)
+	firstPageElements, Page, err := c.FetchPage(ctx, c.Config.Endpoints["ENROLLMENTS"], first, pageSize, fetchParams)
 	if err != nil {
 		if ctx.Err() != nil {
-			return fmt.Errorf("fetching initial page cancelled: %w", ctx.Err())
+			return 0, pageFailures{}, nil, fmt.Errorf("fetching initial page cancelled: %w", ctx.Err())
 		}
-		return fmt.Errorf("failed to fetch initial page to get total: %w", err)
+		return 0, pageFailures{}, nil, fmt.Errorf("failed to fetch initial page to get total: %w", err)
 	}
 
 	if Page == nil {
-		return fmt.Errorf("API response for page 0 did not contain pagination info")
+		return 0, pageFailures{}, nil, fmt.Errorf("API response for page %d did not contain pagination info", first)
 	}
 
 	totalPages := Page.TotalPages
 	totalElements := Page.TotalElements
-	log.Printf("Initial page fetched. Total pages: %d (Total elements: %d)", totalPages, totalElements)
+	logger.Info("Initial page fetched", "totalPages", totalPages, "totalElements", totalElements)
 
 	if totalPages == 0 || totalElements == 0 {
-		log.Println("Total pages or elements is zero. No enrollments to process.")
-		return c.Writer.OverwriteSheetData(ctx, sheetName, headers, [][]interface{}{})
+		logger.Info("Total pages or elements is zero. No enrollments to process.")
+		return 0, pageFailures{}, nil, nil
+	}
+	if first >= totalPages {
+		return 0, pageFailures{}, nil, fmt.Errorf("startPage %d is past the last page %d", first, totalPages-1)
+	}
+	if last == 0 || last > totalPages {
+		last = totalPages
+	}
+	if partial {
+		logger.Info("Fetching a slice of the pages", "fromPage", first, "toPage", last-1)
+	}
+	c.reportProgress(ctx, func(e *ProgressEvent) { e.TotalPages = last - first })
+
+	fetched := 0
+
+	var cp *Checkpoint
+	if checkpointing && !partial {
+		cp = c.openCheckpoint(logger, CheckpointKey(tenantScoped(ctx, ""), fetchParams, pageSize), totalPages, pageSize, resume)
+	}
+	if cp != nil && cp.CompletedPages() > 0 {
+		stored, err := cp.LoadPages()
+		if err != nil {
+			return 0, pageFailures{}, nil, fmt.Errorf("failed to load pages from checkpoint: %w", err)
+		}
+		if err := sink(stored); err != nil {
+			return 0, pageFailures{}, cp, err
+		}
+		fetched += len(stored)
+		logger.Info("Resuming from checkpoint", "completedPages", cp.CompletedPages(), "enrollments", len(stored))
 	}
 
-	allEnrollments := make([]models.Enrollment, 0, totalElements)
-	allEnrollments = append(allEnrollments, firstPageElements...)
+	if cp == nil || !cp.HasPage(first) {
+		if err := sink(firstPageElements); err != nil {
+			return fetched, pageFailures{}, cp, err
+		}
+		fetched += len(firstPageElements)
+		if cp != nil {
+			if err := cp.SavePage(first, firstPageElements); err != nil {
+				logger.Warn("Failed to checkpoint page", "page", first, "error", err)
+			}
+		}
+	}
 
-	if totalPages > 1 {
-		remainingPages := totalPages - 1
+	var failed pageFailures
+	c.logProgress(ctx, logger, startTime, 1, last-first, fetched)
+
+	if last-first > 1 {
+		remainingPages := last - first - 1
 		batchSize := c.Config.MaxPagesPerBatch
 		if remainingPages < batchSize {
 			batchSize = remainingPages
 		}
 
-		currentPage := 1
-		for currentPage < totalPages {
+		currentPage := first + 1
+		for currentPage < last {
 			select {
 			case <-ctx.Done():
-				log.Printf("Process cancelled via context before starting batch from page %d: %v", currentPage, ctx.Err())
-				return fmt.Errorf("filtered enrollment fetch cancelled: %w", ctx.Err())
+				logger.Warn("Process cancelled via context before starting batch", "page", currentPage, "error", ctx.Err())
+				return fetched, failed, cp, fmt.Errorf("filtered enrollment fetch cancelled: %w", ctx.Err())
 			default:
 			}
-			
-			batchData, err := c.processBatchEnrollmentsFiltered(ctx, currentPage, batchSize, fetchParams)
-			if err != nil {
-				log.Printf("Failed to process batch of pages %d-%d: %v. Moving to next batch.", currentPage, currentPage+batchSize-1, err)
-			} else {
-				allEnrollments = append(allEnrollments, batchData...)
+
+			batchEnd := min(currentPage+batchSize, last)
+			pages := make([]int, 0, batchEnd-currentPage)
+			for page := currentPage; page < batchEnd; page++ {
+				if cp == nil || !cp.HasPage(page) {
+					pages = append(pages, page)
+				}
 			}
-			currentPage += batchSize
-			c.logProgress(startTime, currentPage, totalPages, len(allEnrollments))
+
+			if len(pages) > 0 {
+				batchData, failedPages, err := c.processBatchEnrollmentsFiltered(ctx, pages, fetchParams, cp)
+				failed.add(fetchParams, pageSize, failedPages)
+				if err != nil {
+					logger.Error("Failed to process batch of pages. Moving to next batch.", "fromPage", currentPage, "toPage", batchEnd-1, "error", err)
+					failed.batches++
+				} else {
+					if err := sink(batchData); err != nil {
+						return fetched, failed, cp, err
+					}
+					fetched += len(batchData)
+				}
+			}
+			currentPage = batchEnd
+			c.logProgress(ctx, logger, startTime, currentPage-first, last-first, fetched)
 		}
 	}
 
-	log.Printf("All %d enrollments fetched. Writing to sheet '%s'...", len(allEnrollments), sheetName)
-	if err := c.writeAllEnrollmentsToSheet(ctx, allEnrollments, sheetName, headers); err != nil {
-		return fmt.Errorf("failed to write all enrollments to sheet: %w", err)
+	return fetched, failed, cp, nil
+}
+
+// streamBatch is the rows of one fetched chunk bound for one tab.
+type streamBatch struct {
+	target sheetTarget
+	group  sheetGroup
+	rows   [][]interface{}
+}
+
+// sheetStream tracks a single sheet while batches are streamed into it.
+type sheetStream struct {
+	target sheetTarget
+	ctx    context.Context
+	group  string
+	sheet  string
+	rows   int
+	// tabs lists the tabs written so far with their row counts. It holds
+	// just sheet until the stream outgrows the writer's rows-per-tab limit,
+	// and the numbered tabs after that.
+	tabs  []streamTab
+	state SyncState
+	err   error
+	// release gives up the lease on sheet taken when the stream opened, if
+	// the writer supports leases.
+	release func()
+}
+
+// streamLeaseWait bounds how long a streamed job that already leases other
+// tabs waits for the next one, so two jobs streaming the same tabs in a
+// different order cannot wait on each other forever. The job that gives up
+// fails that sheet.
+const streamLeaseWait = 2 * time.Minute
+
+type streamTab struct {
+	name string
+	rows int
+}
+
+// appendStreamed appends rows to the stream's current tab. When the writer
+// splits tabs past a row limit, full tabs roll over to numbered ones: the
+// first tab is renamed to "sheet (1)" and later ones are created as needed,
+// matching the layout of a split overwrite.
+func (c *JacadClient) appendStreamed(stream *sheetStream, headers []string, rows [][]interface{}) error {
+	maxRows := 0
+	splitter, canSplit := c.Writer.(TabSplitter)
+	if canSplit {
+		maxRows = splitter.MaxRowsPerTab()
 	}
 
-	log.Printf("Process completed! Total: %d enrollments written to sheet '%s'.", len(allEnrollments), sheetName)
+	for len(rows) > 0 {
+		current := len(stream.tabs) - 1
+		if maxRows > 0 && stream.tabs[current].rows >= maxRows {
+			if current == 0 {
+				if err := splitter.SplitTab(stream.ctx, stream.sheet); err != nil {
+					return err
+				}
+				stream.tabs[0].name = splitTabName(stream.sheet, 1)
+			}
+			next := splitTabName(stream.sheet, len(stream.tabs)+1)
+			if err := c.Writer.EnsureSheetExists(stream.ctx, next); err != nil {
+				return err
+			}
+			if err := c.Writer.Clear(stream.ctx, next); err != nil {
+				return err
+			}
+			if err := c.Writer.SetHeaders(stream.ctx, next, headers); err != nil {
+				return err
+			}
+			stream.tabs = append(stream.tabs, streamTab{name: next})
+			continue
+		}
+
+		n := len(rows)
+		if maxRows > 0 {
+			n = min(n, maxRows-stream.tabs[current].rows)
+		}
+		if err := c.Writer.AppendRows(stream.ctx, stream.tabs[current].name, rows[:n]); err != nil {
+			return err
+		}
+		stream.tabs[current].rows += n
+		rows = rows[n:]
+	}
 	return nil
 }
 
-func (c *JacadClient) writeAllEnrollmentsToSheet(ctx context.Context, data []models.Enrollment, sheetName string, headers []string) error {
-	rows := make([][]interface{}, len(data))
-	for i, item := range data {
-		rows[i] = make([]interface{}, len(headers))
-		for j, field := range headers {
-			switch field {
-			case "idMatricula":
-				rows[i][j] = item.IdMatricula
-			case "aluno":
-				rows[i][j] = utils.GetStringOrEmpty(item.Aluno)
-			case "ra":
-				rows[i][j] = utils.GetStringOrEmpty(item.RA)
-			case "curso":
-				rows[i][j] = utils.GetStringOrEmpty(item.Curso)
-			case "turma":
-				rows[i][j] = utils.GetStringOrEmpty(item.Turma)
-			case "status":
-				rows[i][j] = utils.GetStringOrEmpty(item.Status)
-			case "periodoLetivo":
-				rows[i][j] = utils.GetStringOrEmpty(item.PeriodoLetivo)
-			case "unidadeFisica":
-				rows[i][j] = utils.GetStringOrEmpty(item.UnidadeFisica)
-			case "organizacao":
-				rows[i][j] = utils.GetStringOrEmpty(item.Organizacao)
-			case "idOrg":
-				rows[i][j] = item.OrgID
-			case "dataMatricula":
-				rows[i][j] = utils.GetTimeOrNilDate(item.DataMatricula)
-			case "dataAtivacao":
-				rows[i][j] = utils.GetTimeOrNilDate(item.DataAtivacao)
-			case "dataCadastro":
-				rows[i][j] = utils.GetTimeOrNilDate(item.DataCadastro)
+// streamEnrollmentsToTargets writes headers to each tab before its first batch
+// and then appends batches as they arrive through an enrollmentPipeline, so
+// memory use is bounded by PipelineMemoryMB instead of the whole dataset.
+// Without grouping every target's tab is prepared up front; grouped tabs are
+// opened as their first row shows up.
+// Resume reuses checkpointed pages but always rewrites the sheets from scratch.
+func (c *JacadClient) streamEnrollmentsToTargets(ctx context.Context, logger *slog.Logger, targets []sheetTarget, mapper *EnrollmentRowMapper, fetchParams []map[string]string, startTime time.Time, params *requests.FetchEnrollmentsRequest, groupBy string) (*FetchResult, error) {
+	var streams []*sheetStream
+	bySheet := make(map[string]*sheetStream)
+	openStream := func(target sheetTarget, orgID int, group, sheet string) *sheetStream {
+		if stream, ok := bySheet[sheet]; ok {
+			return stream
+		}
+		stream := &sheetStream{target: target, ctx: c.spreadsheetContext(ctx, orgID), group: group, sheet: sheet, tabs: []streamTab{{name: sheet}}}
+		bySheet[sheet] = stream
+		if leaser, ok := c.Writer.(SheetLeaser); ok {
+			// The lease keeps other jobs off the tab from the header to the
+			// row count check, which per-call locks would let them interleave.
+			wait := time.Duration(0)
+			if len(streams) > 0 {
+				wait = streamLeaseWait
+			}
+			leased, release, err := leaser.LeaseSheet(stream.ctx, sheet, wait)
+			if err != nil {
+				logger.Error("Failed to lease sheet for streaming", "sheet", sheet, "error", err)
+				stream.err = fmt.Errorf("failed to lease sheet: %w", err)
+				streams = append(streams, stream)
+				return stream
+			}
+			stream.ctx, stream.release = leased, release
+		}
+		logger.Info("Preparing sheet for streamed write...", "sheet", sheet)
+		if err := c.Writer.OverwriteSheetData(stream.ctx, sheet, mapper.Headers(), nil); err != nil {
+			logger.Error("Failed to prepare sheet for streaming", "sheet", sheet, "error", err)
+			stream.err = fmt.Errorf("failed to write headers: %w", err)
+		}
+		streams = append(streams, stream)
+		return stream
+	}
+
+	defer func() {
+		for _, stream := range streams {
+			if stream.release != nil {
+				stream.release()
+			}
+		}
+	}()
+
+	if groupBy == requests.GroupByNone {
+		for _, target := range targets {
+			for _, group := range c.groupEnrollments(target, nil, groupBy, params) {
+				openStream(target, group.OrgID, group.Group, group.Sheet)
+			}
+		}
+	}
+
+	// Chunks are grouped by tab and mapped to rows on one goroutine and
+	// written on another, while the next pages are fetched.
+	mapChunk := func(data []models.Enrollment) []streamBatch {
+		var batches []streamBatch
+		for _, target := range targets {
+			batch := data
+			if target.PartitionByOrg {
+				batch = filterEnrollmentsByOrg(data, target.OrgID)
+			}
+			if len(batch) == 0 {
+				continue
+			}
+			for _, group := range c.groupEnrollments(target, batch, groupBy, params) {
+				batches = append(batches, streamBatch{target: target, group: group, rows: mapper.Rows(group.Data)})
+			}
+		}
+		return batches
+	}
+	writeChunk := func(batches []streamBatch) {
+		for _, batch := range batches {
+			group := batch.group
+			stream := openStream(batch.target, group.OrgID, group.Group, group.Sheet)
+			if stream.err != nil {
+				continue
+			}
+			if err := c.appendStreamed(stream, mapper.Headers(), batch.rows); err != nil {
+				logger.Error("Failed to append streamed batch", "sheet", stream.sheet, "rows", len(group.Data), "error", err)
+				stream.err = fmt.Errorf("failed to append batch: %w", err)
+				continue
+			}
+			stream.rows += len(group.Data)
+			c.reportProgress(ctx, func(e *ProgressEvent) { e.RowsWritten += len(group.Data) })
+			stream.state = buildSyncState(group.Data, &stream.state)
+		}
+	}
+	pipeline := newEnrollmentPipeline(ctx, int64(c.Config.PipelineMemoryMB)<<20, mapChunk, writeChunk)
+
+	window, err := newDataMatriculaWindow(params)
+	if err != nil {
+		return nil, err
+	}
+	dedup := newEnrollmentDeduper()
+	quality := newQualityCheck(c.Config.DataQualityRules)
+	stats := newEnrollmentSummary(params, targets)
+	fetched, failed, cp, err := c.fetchEnrollmentQueries(ctx, logger, fetchParams, startTime, true, params.Resume, dedup.wrap(window.wrap(quality.wrap(stats.wrap(pipeline.Sink)))))
+	pipeline.Close()
+	if pipeline.waits > 0 {
+		logger.Info("Fetching paused until earlier batches were written to stay within the pipeline memory limit", "pauses", pipeline.waits, "limitMB", c.Config.PipelineMemoryMB)
+	}
+	if err != nil {
+		return nil, err
+	}
+	logDuplicates(logger, dedup)
+	logOutsideWindow(logger, window)
+
+	result := &FetchResult{
+		Mode:              requests.SyncModeFull,
+		WriteMode:         requests.WriteModeStream,
+		Anonymized:        params.Anonymize,
+		Tenant:            params.Tenant,
+		TotalFetched:      fetched,
+		FailedBatches:     failed.batches,
+		FailedPages:       failed.pages,
+		DuplicatesDropped: dedup.dropped,
+		OutsideDateRange:  window.Dropped(),
+		Spreadsheet:       jobSpreadsheet(ctx),
+	}
+
+	hold := syncStateHold(failed, params)
+	var lastErr error
+	var snapshots snapshotSets
+	failures := 0
+	counter, canVerify := c.Writer.(RowCounter)
+	for _, stream := range streams {
+		summary := OrgSummary{OrgID: stream.target.OrgID, OrgName: stream.target.OrgName, Sheet: stream.sheet, Group: stream.group, Rows: stream.rows}
+
+		if stream.err == nil && canVerify {
+			for _, tab := range stream.tabs {
+				written, err := counter.CountRows(stream.ctx, tab.name)
+				if errors.Is(err, errors.ErrUnsupported) {
+					break
+				}
+				if err != nil {
+					stream.err = fmt.Errorf("failed to verify row count of '%s': %w", tab.name, err)
+					break
+				}
+				if written != tab.rows {
+					stream.err = fmt.Errorf("row count mismatch after streaming: sent %d rows to '%s' but it has %d", tab.rows, tab.name, written)
+					break
+				}
+			}
+		}
+		if stream.err == nil {
+			if err := c.saveSyncState(stream.ctx, logger, stream.sheet, stream.state, hold); err != nil {
+				stream.err = fmt.Errorf("enrollments written but failed to save sync state: %w", err)
+			}
+		}
+
+		if stream.err != nil {
+			logger.Error("Failed to stream enrollments for organization", "orgId", stream.target.OrgID, "sheet", stream.sheet, "error", stream.err)
+			summary.Error = stream.err.Error()
+			lastErr = stream.err
+			failures++
+		} else {
+			if cp != nil {
+				if err := cp.MarkSheetWritten(stream.sheet); err != nil {
+					logger.Warn("Failed to checkpoint written sheet", "sheet", stream.sheet, "error", err)
+				}
+			}
+			if params.Snapshot {
+				snapshots.add(stream.ctx, stream.sheet)
+			}
+		}
+		result.Organizations = append(result.Organizations, summary)
+	}
+	c.writeRejects(ctx, logger, quality, mapper, params, result)
+	c.writeSummary(ctx, logger, stats, params, startTime, result)
+	c.pruneSnapshots(logger, &snapshots, startTime, result)
+
+	if cp != nil && failures == 0 && failed.batches == 0 && len(failed.pages) == 0 {
+		if err := c.Checkpoints.Delete(cp.Key()); err != nil {
+			logger.Warn("Failed to delete checkpoint after a successful run", "error", err)
+		}
+	}
+
+	if len(streams) > 0 && failures == len(streams) {
+		return result, fmt.Errorf("failed to stream enrollments to %d sheet(s): %w", failures, lastErr)
+	}
+
+	logger.Info("Process completed!", "fetched", fetched, "sheets", len(streams), "failedSheets", failures, "duration", time.Since(startTime).String())
+	return result, nil
+}
+
+func (c *JacadClient) openCheckpoint(logger *slog.Logger, key string, totalPages, pageSize int, resume bool) *Checkpoint {
+	if resume {
+		cp, err := c.Checkpoints.Load(key)
+		switch {
+		case err != nil:
+			logger.Warn("Failed to load checkpoint. Starting from scratch.", "checkpoint", key, "error", err)
+		case cp == nil:
+			logger.Info("No checkpoint found to resume. Starting from scratch.", "checkpoint", key)
+		case cp.TotalPages() != totalPages:
+			logger.Warn("Checkpoint total pages differ from the current dataset. Starting from scratch.", "checkpoint", key, "checkpointPages", cp.TotalPages(), "totalPages", totalPages)
+		default:
+			return cp
+		}
+	}
+
+	cp, err := c.Checkpoints.Create(key, totalPages, pageSize)
+	if err != nil {
+		logger.Warn("Failed to create checkpoint. Continuing without checkpointing.", "checkpoint", key, "error", err)
+		return nil
+	}
+	return cp
+}
+
+// writeEnrollmentsToTarget writes data to a single sheet according to mode and
+// returns how many rows were sent to the writer. The sync state is saved
+// afterwards unless hold gives a reason to keep the previous one; see
+// syncStateHold.
+func (c *JacadClient) writeEnrollmentsToTarget(ctx context.Context, mode, sheetName string, mapper *EnrollmentRowMapper, data []models.Enrollment, hold string) (int, error) {
+	logger := logging.FromContext(ctx).With("sheet", sheetName, "mode", mode)
+
+	if mode == requests.SyncModeIncremental {
+		state, err := c.State.Load(c.syncStateKey(ctx, sheetName))
+		if err != nil {
+			return 0, fmt.Errorf("failed to load sync state for sheet '%s': %w", sheetName, err)
+		}
+		keyHeader, ok := mapper.HeaderFor("idMatricula")
+		if !ok {
+			return 0, fmt.Errorf("incremental mode requires the idMatricula column in the column mapping")
+		}
+		if state == nil {
+			logger.Info("No sync state found for sheet. Falling back to a full overwrite.")
+		} else {
+			logger.Info("Incremental sync from last watermark", "lastDataCadastro", state.LastDataCadastro.Format("2006-01-02"), "lastDataMatricula", state.LastDataMatricula.Format("2006-01-02"))
+			changes, err := c.incrementalChanges(ctx, logger, sheetName, mapper, data, state)
+			if err != nil {
+				return 0, err
+			}
+			switch {
+			case changes.Relayout:
+				logger.Info("Sheet headers differ from the column mapping. Falling back to a full overwrite.")
+			case changes.Removed > 0 && hold == "":
+				logger.Info("Enrollments left the sheet's query since the last sync. Falling back to a full overwrite.", "removed", changes.Removed)
 			default:
-				rows[i][j] = ""
+				if changes.Removed > 0 {
+					logger.Warn("Keeping sheet rows missing from an incomplete fetch", "rows", changes.Removed, "reason", hold)
+				}
+				logger.Info("Upserting new or changed enrollments into sheet...", "fetched", len(data), "changed", len(changes.Changed))
+				if err := c.Writer.UpsertRows(ctx, sheetName, mapper.Headers(), keyHeader, mapper.Rows(changes.Changed)); err != nil {
+					return 0, fmt.Errorf("failed to upsert enrollments into sheet: %w", err)
+				}
+				if err := c.saveSyncState(ctx, logger, sheetName, buildSyncState(data, state), hold); err != nil {
+					return len(changes.Changed), fmt.Errorf("enrollments upserted but failed to save sync state: %w", err)
+				}
+				return len(changes.Changed), nil
 			}
 		}
 	}
 
-	return c.Writer.OverwriteSheetData(ctx, sheetName, headers, rows)
+	logger.Info("Writing enrollments to sheet...", "rows", len(data))
+	if err := c.writeAllEnrollmentsToSheet(ctx, data, sheetName, mapper); err != nil {
+		return 0, fmt.Errorf("failed to write all enrollments to sheet: %w", err)
+	}
+	if err := c.saveSyncState(ctx, logger, sheetName, buildSyncState(data, nil), hold); err != nil {
+		return len(data), fmt.Errorf("enrollments written but failed to save sync state: %w", err)
+	}
+	return len(data), nil
+}
+
+// previewEnrollmentsForTarget computes what writeEnrollmentsToTarget would send
+// to the sheet without calling the writer or touching the sync state.
+func (c *JacadClient) previewEnrollmentsForTarget(ctx context.Context, mode, sheetName string, mapper *EnrollmentRowMapper, data []models.Enrollment, limit int) (int, []map[string]interface{}, error) {
+	if mode == requests.SyncModeIncremental {
+		state, err := c.State.Load(c.syncStateKey(ctx, sheetName))
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to load sync state for sheet '%s': %w", sheetName, err)
+		}
+		if state != nil {
+			changes, err := c.incrementalChanges(ctx, logging.FromContext(ctx), sheetName, mapper, data, state)
+			if err != nil {
+				return 0, nil, err
+			}
+			if !changes.Relayout && changes.Removed == 0 {
+				data = changes.Changed
+			}
+		}
+	}
+
+	previewData := data
+	if len(previewData) > limit {
+		previewData = previewData[:limit]
+	}
+
+	return len(data), previewRows(mapper.Headers(), mapper.Rows(previewData)), nil
+}
+
+// previewRows turns rows into header-keyed maps for dry-run responses.
+func previewRows(headers []string, rows [][]interface{}) []map[string]interface{} {
+	preview := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		preview[i] = make(map[string]interface{}, len(headers))
+		for j, header := range headers {
+			preview[i][header] = row[j]
+		}
+	}
+	return preview
+}
+
+func (c *JacadClient) resolveSheetTargets(ctx context.Context, params *requests.FetchEnrollmentsRequest) ([]sheetTarget, error) {
+	orgIDs, all, err := params.ParseOrgIDs()
+	if err != nil {
+		return nil, err
+	}
+	periodName := c.periodName(ctx, params)
+
+	if !all && len(orgIDs) == 0 {
+		return []sheetTarget{{
+			OrgID:      params.OrgId,
+			OrgName:    config.GetOrganizationNameByID(params.OrgId),
+			PeriodName: periodName,
+			Sheet:      c.determineSheetName(params.OrgId, periodName, params),
+		}}, nil
+	}
+
+	if all {
+		for _, org := range c.Config.Organizations.All() {
+			orgIDs = append(orgIDs, org.ID)
+		}
+	}
+
+	targets := make([]sheetTarget, 0, len(orgIDs))
+	for _, id := range orgIDs {
+		name := config.GetOrganizationNameByID(id)
+		if name == "" {
+			return nil, fmt.Errorf("unknown organization id %d in orgIds", id)
+		}
+		targets = append(targets, sheetTarget{
+			OrgID:          id,
+			OrgName:        name,
+			PeriodName:     periodName,
+			Sheet:          c.determineSheetName(id, periodName, params),
+			PartitionByOrg: true,
+		})
+	}
+	return targets, nil
+}
+
+func filterEnrollmentsByOrg(data []models.Enrollment, orgID int) []models.Enrollment {
+	filtered := make([]models.Enrollment, 0)
+	for _, item := range data {
+		if item.OrgID == orgID {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// filterEnrollmentsSince keeps enrollments whose dataCadastro or dataMatricula
+// is on or after the recorded watermark. Jacad dates have day precision, so the
+// comparison is inclusive and relies on the upsert being idempotent.
+func filterEnrollmentsSince(data []models.Enrollment, state *SyncState) []models.Enrollment {
+	if state == nil {
+		return data
+	}
+	filtered := make([]models.Enrollment, 0)
+	for _, item := range data {
+		if dateOnOrAfter(item.DataCadastro, state.LastDataCadastro) || dateOnOrAfter(item.DataMatricula, state.LastDataMatricula) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+func buildSyncState(data []models.Enrollment, previous *SyncState) SyncState {
+	state := SyncState{UpdatedAt: time.Now()}
+	if previous != nil {
+		state.LastDataCadastro = previous.LastDataCadastro
+		state.LastDataMatricula = previous.LastDataMatricula
+	}
+	for _, item := range data {
+		if item.DataCadastro != nil && time.Time(*item.DataCadastro).After(state.LastDataCadastro) {
+			state.LastDataCadastro = time.Time(*item.DataCadastro)
+		}
+		if item.DataMatricula != nil && time.Time(*item.DataMatricula).After(state.LastDataMatricula) {
+			state.LastDataMatricula = time.Time(*item.DataMatricula)
+		}
+	}
+	return state
 }
 
-func (c *JacadClient) processBatchEnrollmentsFiltered(ctx context.Context, startPage, count int, params map[string]string) ([]models.Enrollment, error) {
+func dateOnOrAfter(d *utils.Date, watermark time.Time) bool {
+	if d == nil || time.Time(*d).IsZero() {
+		return false
+	}
+	return !time.Time(*d).Before(watermark)
+}
+
+func (c *JacadClient) writeAllEnrollmentsToSheet(ctx context.Context, data []models.Enrollment, sheetName string, mapper *EnrollmentRowMapper) error {
+	if writer, ok := c.Writer.(EnrollmentWriter); ok {
+		return writer.WriteEnrollments(ctx, sheetName, mapper, data)
+	}
+	return c.Writer.OverwriteSheetData(ctx, sheetName, mapper.Headers(), mapper.Rows(data))
+}
+
+// processBatchEnrollmentsFiltered fetches pages concurrently and returns their
+// enrollments along with the pages that failed after all retries.
+func (c *JacadClient) processBatchEnrollmentsFiltered(ctx context.Context, pages []int, params map[string]string, cp *Checkpoint) ([]models.Enrollment, []int, error) {
 	var mu sync.Mutex
 	wg := sync.WaitGroup{}
 	var allData []models.Enrollment
 
+	count := len(pages)
+	startPage, endPage := pages[0], pages[count-1]
 	dataChan := make(chan []models.Enrollment, count)
-	errorCount := 0
+	var failedPages []int
 
-	log.Printf("Starting concurrent fetch of %d pages (batch %d-%d) (Max Concurrency: %d)...", count, startPage, startPage+count-1, c.Config.MaxParallelRequests)
+	logger := logging.FromContext(ctx).With("batchStart", startPage, "batchEnd", endPage)
+	logger.Info("Starting concurrent fetch of batch pages", "pages", count, "maxConcurrency", c.Config.MaxParallelRequests, "currentConcurrency", c.concurrency.Limit())
 
 	pagesToFetch := make(chan int, count)
-	for i := 0; i < count; i++ {
-		pagesToFetch <- startPage + i
+	for _, page := range pages {
+		pagesToFetch <- page
 	}
 	close(pagesToFetch)
 
@@ -168,36 +1005,38 @@ func (c *JacadClient) processBatchEnrollmentsFiltered(ctx context.Context, start
 			for pageNum := range pagesToFetch {
 				select {
 				case <-ctx.Done():
-					log.Printf("Worker stopping due to context cancellation for page %d: %v", pageNum, ctx.Err())
+					logger.Warn("Worker stopping due to context cancellation", "page", pageNum, "error", ctx.Err())
 					return
 				default:
 				}
 
-				log.Printf("-> Fetching page %d (batch %d-%d) (with context and filters)...", pageNum, startPage, startPage+count-1)
+				logger.Debug("Fetching page", "page", pageNum)
 
-				pageElements, _, err := c.FetchPage(ctx, c.Config.Endpoints["ENROLLMENTS"], pageNum, c.Config.PageSize, params)
+				pageElements, _, err := c.FetchPage(ctx, c.Config.Endpoints["ENROLLMENTS"], pageNum, c.pageSize(ctx), params)
 
 				if err != nil {
 					if ctx.Err() != nil {
-						log.Printf("Failed to fetch page %d due to context cancellation: %v", pageNum, err)
+						logger.Warn("Failed to fetch page due to context cancellation", "page", pageNum, "error", err)
 					} else {
-						log.Printf("Failed to fetch page %d after retries: %v", pageNum, err)
+						logger.Error("Failed to fetch page after retries", "page", pageNum, "error", err)
 						mu.Lock()
-						errorCount++
+						failedPages = append(failedPages, pageNum)
 						mu.Unlock()
 					}
 					continue
 				}
 
+				if cp != nil {
+					if err := cp.SavePage(pageNum, pageElements); err != nil {
+						logger.Warn("Failed to checkpoint page", "page", pageNum, "error", err)
+					}
+				}
+
 				select {
 				case dataChan <- pageElements:
-					if len(pageElements) > 0 {
-						log.Printf("<- Page %d (batch %d-%d): %d enrollments found.", pageNum, startPage, startPage+count-1, len(pageElements))
-					} else {
-						log.Printf("<- Page %d (batch %d-%d): 0 enrollments found.", pageNum, startPage, startPage+count-1)
-					}
+					logger.Debug("Page fetched", "page", pageNum, "enrollments", len(pageElements))
 				case <-ctx.Done():
-					log.Printf("Context cancelled while trying to send data for page %d to channel: %v", pageNum, ctx.Err())
+					logger.Warn("Context cancelled while trying to send page data to channel", "page", pageNum, "error", ctx.Err())
 					return
 				}
 			}
@@ -216,35 +1055,66 @@ func (c *JacadClient) processBatchEnrollmentsFiltered(ctx context.Context, start
 	}
 
 	if ctx.Err() != nil {
-		log.Printf("Batch processing cancelled via context after waiting for goroutines: %v", ctx.Err())
-		return nil, fmt.Errorf("batch processing cancelled: %w", ctx.Err())
+		logger.Warn("Batch processing cancelled via context after waiting for goroutines", "error", ctx.Err())
+		return nil, nil, fmt.Errorf("batch processing cancelled: %w", ctx.Err())
 	}
 
-	if errorCount > 0 {
-		if errorCount == count && count > 0 {
-			log.Printf("Batch completed. ALL %d requests in batch failed (not cancelled).", count)
-			return nil, fmt.Errorf("all %d requests in batch failed in batch %d-%d", count, startPage, startPage+count-1)
+	sort.Ints(failedPages)
+	if len(failedPages) > 0 {
+		if len(failedPages) == count && count > 0 {
+			logger.Error("Batch completed. ALL requests in batch failed (not cancelled).", "pages", count)
+			return nil, failedPages, fmt.Errorf("all %d requests in batch failed in batch %d-%d", count, startPage, endPage)
 		}
-		log.Printf("Batch completed. Total %d enrollments collected from successful requests (%d failures) in batch %d-%d", len(allData), errorCount, startPage, startPage+count-1)
+		logger.Warn("Batch completed with failures", "enrollments", len(allData), "failures", len(failedPages), "failedPages", failedPages)
 
 	} else {
-		log.Printf("Batch completed. Total %d enrollments collected from successful requests (0 failures) in batch %d-%d", len(allData), startPage, startPage+count-1)
+		logger.Info("Batch completed", "enrollments", len(allData), "failures", 0)
 	}
 
-	return allData, nil
+	return allData, failedPages, nil
 }
 
 
-func (c *JacadClient) determineSheetName(params *requests.FetchEnrollmentsRequest) string {
-	orgName := config.GetOrganizationNameByID(params.OrgId)
+// spreadsheetContext selects the spreadsheet configured for orgID, if any,
+// for writes made with the returned context. Jobs writing to a spreadsheet of
+// their own keep it.
+func (c *JacadClient) spreadsheetContext(ctx context.Context, orgID int) context.Context {
+	if jobSpreadsheet(ctx) != nil {
+		return ctx
+	}
+	org, _ := c.Config.Organizations.ByID(orgID)
+	return WithSpreadsheetID(ctx, org.SpreadsheetID)
+}
+
+// determineSheetName renders the organization's sheet name template, or
+// SHEET_NAME_TEMPLATE when it has none, naming the fetched periods
+// periodName.
+func (c *JacadClient) determineSheetName(orgID int, periodName string, params *requests.FetchEnrollmentsRequest) string {
+	org, _ := c.Config.Organizations.ByID(orgID)
+	orgName := org.Name
 	if orgName == "" {
-		orgName = config.AppConfig.DefaultOrgSheet
+		orgName = c.Config.DefaultOrgSheet
+	}
+	tmpl := params.SheetName
+	if tmpl == "" {
+		tmpl = org.SheetNameTemplate
 	}
-	return fmt.Sprintf("Matrículas %s STATUS: %s | Período ID %d", orgName, params.StatusMatricula, params.IdPeriodoLetivo)
+	if tmpl == "" {
+		tmpl = c.Config.SheetNameTemplate
+	}
+	return snapshotName(renderSheetName(tmpl, sheetNameData(orgName, "", periodName, params)), params)
 }
 
-func (c *JacadClient) logProgress(startTime time.Time, currentPage, totalPages, totalProcessed int) {
+func (c *JacadClient) logProgress(ctx context.Context, logger *slog.Logger, startTime time.Time, currentPage, totalPages, totalProcessed int) {
 	elapsed := time.Since(startTime).Seconds()
+	c.reportProgress(ctx, func(e *ProgressEvent) {
+		e.PagesFetched = currentPage
+		e.TotalPages = totalPages
+		e.RowsFetched = totalProcessed
+		if currentPage > 0 {
+			e.ETASeconds = elapsed / float64(currentPage) * float64(totalPages-currentPage)
+		}
+	})
 	progress := 0.0
 
 	if totalPages > 0 {
@@ -252,6 +1122,11 @@ func (c *JacadClient) logProgress(startTime time.Time, currentPage, totalPages,
 		progress = float64(currentPage) / float64(totalPages) * 100
 	}
 
-	log.Printf("Pages (batches started): %d/%d (%.1f%%) | Enrollments Processed: %d | Time: %.1fs",
-		currentPage, totalPages, progress, totalProcessed, elapsed)
+	logger.Info("Progress",
+		"pagesStarted", currentPage,
+		"totalPages", totalPages,
+		"progressPercent", fmt.Sprintf("%.1f", progress),
+		"enrollmentsProcessed", totalProcessed,
+		"elapsedSeconds", fmt.Sprintf("%.1f", elapsed),
+	)
 }
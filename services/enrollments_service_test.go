@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// splitRecorder is an in-memory SheetWriter and TabSplitter that keeps the
+// data rows of each tab.
+type splitRecorder struct {
+	maxRows int
+	tabs    map[string]int
+}
+
+func (w *splitRecorder) EnsureSheetExists(ctx context.Context, sheetName string) error {
+	if _, ok := w.tabs[sheetName]; !ok {
+		w.tabs[sheetName] = 0
+	}
+	return nil
+}
+func (w *splitRecorder) Clear(ctx context.Context, sheetName string) error {
+	w.tabs[sheetName] = 0
+	return nil
+}
+func (w *splitRecorder) SetHeaders(ctx context.Context, sheetName string, headers []string) error {
+	return nil
+}
+func (w *splitRecorder) AppendRows(ctx context.Context, sheetName string, rows [][]interface{}) error {
+	w.tabs[sheetName] += len(rows)
+	return nil
+}
+func (w *splitRecorder) OverwriteSheetData(ctx context.Context, sheetName string, headers []string, rows [][]interface{}) error {
+	w.tabs[sheetName] = len(rows)
+	return nil
+}
+func (w *splitRecorder) UpsertRows(ctx context.Context, sheetName string, headers []string, keyColumn string, rows [][]interface{}) error {
+	return nil
+}
+func (w *splitRecorder) MaxRowsPerTab() int { return w.maxRows }
+func (w *splitRecorder) SplitTab(ctx context.Context, sheetName string) error {
+	w.tabs[splitTabName(sheetName, 1)] = w.tabs[sheetName]
+	delete(w.tabs, sheetName)
+	return nil
+}
+
+func TestAppendStreamedSplitsTabs(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxRows  int
+		batches  []int
+		wantTabs []streamTab
+	}{
+		{name: "splitting disabled", maxRows: 0, batches: []int{7, 7}, wantTabs: []streamTab{{"Alunos", 14}}},
+		{name: "fits in one tab", maxRows: 10, batches: []int{4, 6}, wantTabs: []streamTab{{"Alunos", 10}}},
+		{name: "batch crosses the limit", maxRows: 10, batches: []int{8, 5}, wantTabs: []streamTab{{"Alunos (1)", 10}, {"Alunos (2)", 3}}},
+		{name: "single batch spans several tabs", maxRows: 4, batches: []int{11}, wantTabs: []streamTab{{"Alunos (1)", 4}, {"Alunos (2)", 4}, {"Alunos (3)", 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &splitRecorder{maxRows: tt.maxRows, tabs: map[string]int{"Alunos": 0}}
+			c := &JacadClient{Writer: writer}
+			stream := &sheetStream{ctx: context.Background(), sheet: "Alunos", tabs: []streamTab{{name: "Alunos"}}}
+
+			for _, n := range tt.batches {
+				if err := c.appendStreamed(stream, []string{"id"}, make([][]interface{}, n)); err != nil {
+					t.Fatalf("appendStreamed() error = %v", err)
+				}
+			}
+
+			if !slices.Equal(stream.tabs, tt.wantTabs) {
+				t.Errorf("tabs = %v, want %v", stream.tabs, tt.wantTabs)
+			}
+			for _, tab := range tt.wantTabs {
+				if got := writer.tabs[tab.name]; got != tab.rows {
+					t.Errorf("writer tab %q has %d rows, want %d", tab.name, got, tab.rows)
+				}
+			}
+			if len(writer.tabs) != len(tt.wantTabs) {
+				t.Errorf("writer has tabs %v, want %d", writer.tabs, len(tt.wantTabs))
+			}
+		})
+	}
+}
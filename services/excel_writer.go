@@ -0,0 +1,618 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	// excelRowsPerRequest bounds the rows of one range update, keeping the
+	// request body well under the Microsoft Graph payload limit.
+	excelRowsPerRequest = 2000
+	// excelMaxWorksheetName is Excel's limit for worksheet names.
+	excelMaxWorksheetName = 31
+	excelRequestTimeout   = 2 * time.Minute
+)
+
+// ExcelCredentials is the Microsoft Entra app ExcelOnlineWriter signs in as
+// with the OAuth 2.0 client credentials flow. The app needs the
+// Files.ReadWrite.All application permission.
+type ExcelCredentials struct {
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+	// AuthorityURL is the Entra login endpoint, e.g.
+	// https://login.microsoftonline.com.
+	AuthorityURL string
+}
+
+// ExcelOnlineWriter implements SheetWriter on an Excel workbook stored in
+// OneDrive or SharePoint, through the Microsoft Graph workbook API: every
+// sheet maps to a worksheet of the workbook. WithSpreadsheetID routes calls to
+// another workbook item of the same drive.
+type ExcelOnlineWriter struct {
+	client           *http.Client
+	graphURL         string
+	driveID          string
+	workbookID       string
+	retryMaxAttempts int
+	retryDelay       time.Duration
+	// locker serializes writes to the same worksheet, so an append reads
+	// where the used range ends and writes after it without interleaving.
+	locker SheetLocker
+
+	mu sync.Mutex
+	// worksheets caches the worksheets known to exist, by workbook and name.
+	worksheets map[string]bool
+}
+
+func NewExcelOnlineWriter(ctx context.Context, graphURL, driveID, workbookID string, creds ExcelCredentials, retryMaxAttempts int, retryDelay time.Duration) (*ExcelOnlineWriter, error) {
+	if driveID == "" || workbookID == "" {
+		return nil, fmt.Errorf("Excel Online writer requires both a drive ID and a workbook ID")
+	}
+	if creds.TenantID == "" || creds.ClientID == "" || creds.ClientSecret == "" {
+		return nil, fmt.Errorf("Excel Online writer requires a tenant ID, client ID and client secret")
+	}
+	graph, err := url.Parse(graphURL)
+	if err != nil || graph.Scheme == "" || graph.Host == "" {
+		return nil, fmt.Errorf("invalid Microsoft Graph URL '%s'", graphURL)
+	}
+
+	oauth := clientcredentials.Config{
+		ClientID:     creds.ClientID,
+		ClientSecret: creds.ClientSecret,
+		TokenURL:     strings.TrimRight(creds.AuthorityURL, "/") + "/" + url.PathEscape(creds.TenantID) + "/oauth2/v2.0/token",
+		Scopes:       []string{graph.Scheme + "://" + graph.Host + "/.default"},
+	}
+	// Tokens are refreshed for as long as the writer lives, not just while
+	// ctx does.
+	client := oauth.Client(context.WithoutCancel(ctx))
+	client.Timeout = excelRequestTimeout
+
+	logging.FromContext(ctx).Info("Cliente do Excel Online inicializado com sucesso.", "drive", driveID, "workbook", workbookID, "tenant", creds.TenantID)
+	return newExcelOnlineWriter(client, graphURL, driveID, workbookID, retryMaxAttempts, retryDelay), nil
+}
+
+// newExcelOnlineWriter returns a writer sending its Graph calls through
+// client, which must authenticate them.
+func newExcelOnlineWriter(client *http.Client, graphURL, driveID, workbookID string, retryMaxAttempts int, retryDelay time.Duration) *ExcelOnlineWriter {
+	return &ExcelOnlineWriter{
+		client:           client,
+		graphURL:         strings.TrimRight(graphURL, "/"),
+		driveID:          driveID,
+		workbookID:       workbookID,
+		retryMaxAttempts: retryMaxAttempts,
+		retryDelay:       retryDelay,
+		locker:           NewLocalSheetLocker(),
+		worksheets:       make(map[string]bool),
+	}
+}
+
+func (w *ExcelOnlineWriter) workbookFor(ctx context.Context) string {
+	if id, ok := ctx.Value(spreadsheetIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	return w.workbookID
+}
+
+// CheckHealth verifies access to the workbook.
+func (w *ExcelOnlineWriter) CheckHealth(ctx context.Context) error {
+	getCallFunc := func() error {
+		return w.call(ctx, http.MethodGet, "?$select=id", nil, nil)
+	}
+	if err := w.executeGraphCall(ctx, "health", getCallFunc, "verificar pasta de trabalho"); err != nil {
+		return fmt.Errorf("pasta de trabalho '%s' inacessível: %w", w.workbookFor(ctx), err)
+	}
+	return nil
+}
+
+func (w *ExcelOnlineWriter) EnsureSheetExists(ctx context.Context, sheetName string) error {
+	name := excelWorksheetName(sheetName)
+	key := w.workbookFor(ctx) + "/" + name
+	w.mu.Lock()
+	known := w.worksheets[key]
+	w.mu.Unlock()
+	if known {
+		return nil
+	}
+
+	getCallFunc := func() error {
+		return w.call(ctx, http.MethodGet, w.worksheetPath(sheetName)+"?$select=name", nil, nil)
+	}
+	err := w.executeGraphCall(ctx, "ensure_sheet", getCallFunc, fmt.Sprintf("verificar aba '%s'", name))
+	if err != nil {
+		if !isGraphStatus(err, http.StatusNotFound) {
+			return fmt.Errorf("falha ao verificar aba '%s': %w", name, err)
+		}
+		logging.FromContext(ctx).Info("API Graph: Aba não encontrada. Criando...", "sheet", name)
+		addCallFunc := func() error {
+			return w.call(ctx, http.MethodPost, "/workbook/worksheets/add", map[string]string{"name": name}, nil)
+		}
+		if err := w.executeGraphCall(ctx, "ensure_sheet", addCallFunc, fmt.Sprintf("criar aba '%s'", name)); err != nil && !isGraphStatus(err, http.StatusConflict) {
+			return fmt.Errorf("falha ao criar aba '%s': %w", name, err)
+		}
+	}
+
+	w.mu.Lock()
+	w.worksheets[key] = true
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *ExcelOnlineWriter) Clear(ctx context.Context, sheetName string) error {
+	unlock, err := w.lockSheet(ctx, sheetName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return w.clearLocked(ctx, sheetName)
+}
+
+// clearLocked clears the values of the used range, keeping the worksheet's
+// formatting.
+func (w *ExcelOnlineWriter) clearLocked(ctx context.Context, sheetName string) error {
+	used, ok, err := w.usedRange(ctx, "clear", sheetName, false)
+	if err != nil {
+		return fmt.Errorf("falha ao limpar aba '%s': %w", sheetName, err)
+	}
+	if !ok {
+		return nil
+	}
+	clearCallFunc := func() error {
+		return w.call(ctx, http.MethodPost, w.rangePath(sheetName, excelLocalAddress(used.Address))+"/clear", map[string]string{"applyTo": "Contents"}, nil)
+	}
+	if err := w.executeGraphCall(ctx, "clear", clearCallFunc, fmt.Sprintf("limpar aba '%s'", sheetName)); err != nil {
+		return fmt.Errorf("falha ao limpar aba '%s': %w", sheetName, err)
+	}
+	return nil
+}
+
+func (w *ExcelOnlineWriter) SetHeaders(ctx context.Context, sheetName string, headers []string) error {
+	if err := w.EnsureSheetExists(ctx, sheetName); err != nil {
+		return err
+	}
+	unlock, err := w.lockSheet(ctx, sheetName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return w.writeRows(ctx, "set_headers", sheetName, 1, [][]interface{}{excelHeaderRow(headers)})
+}
+
+// AppendRows writes rows below the used range of the worksheet.
+func (w *ExcelOnlineWriter) AppendRows(ctx context.Context, sheetName string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := w.EnsureSheetExists(ctx, sheetName); err != nil {
+		return err
+	}
+	unlock, err := w.lockSheet(ctx, sheetName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	used, ok, err := w.usedRange(ctx, "append", sheetName, false)
+	if err != nil {
+		return fmt.Errorf("falha ao adicionar linhas na aba '%s': %w", sheetName, err)
+	}
+	next := 1
+	if ok {
+		next = used.RowIndex + used.RowCount + 1
+	}
+	if err := w.writeRows(ctx, "append", sheetName, next, rows); err != nil {
+		return err
+	}
+	excelRowsWrittenTotal.Add(float64(len(rows)), "append")
+	return nil
+}
+
+func (w *ExcelOnlineWriter) OverwriteSheetData(ctx context.Context, sheetName string, headers []string, rows [][]interface{}) error {
+	if err := w.EnsureSheetExists(ctx, sheetName); err != nil {
+		return err
+	}
+	unlock, err := w.lockSheet(ctx, sheetName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := w.clearLocked(ctx, sheetName); err != nil {
+		return err
+	}
+	data := make([][]interface{}, 0, len(rows)+1)
+	if len(headers) > 0 {
+		data = append(data, excelHeaderRow(headers))
+	}
+	data = append(data, rows...)
+	if err := w.writeRows(ctx, "overwrite", sheetName, 1, data); err != nil {
+		return err
+	}
+	excelRowsWrittenTotal.Add(float64(len(rows)), "overwrite")
+	logging.FromContext(ctx).Info("API Graph: Aba sobrescrita com sucesso.", "sheet", sheetName, "rows", len(rows))
+	return nil
+}
+
+// UpsertRows replaces the rows whose keyColumn value matches one of rows and
+// appends the others. Only the rows from the first one replaced on are
+// written back.
+func (w *ExcelOnlineWriter) UpsertRows(ctx context.Context, sheetName string, headers []string, keyColumn string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	keyIndex := -1
+	for i, h := range headers {
+		if h == keyColumn {
+			keyIndex = i
+			break
+		}
+	}
+	if keyIndex < 0 {
+		return fmt.Errorf("key column '%s' not found in headers for sheet '%s'", keyColumn, sheetName)
+	}
+	if err := w.EnsureSheetExists(ctx, sheetName); err != nil {
+		return err
+	}
+	unlock, err := w.lockSheet(ctx, sheetName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	records, err := w.readLocked(ctx, "upsert", sheetName)
+	if err != nil {
+		return fmt.Errorf("falha ao ler aba '%s' para upsert: %w", sheetName, err)
+	}
+	first := 0
+	if len(records) == 0 {
+		records = [][]interface{}{excelHeaderRow(headers)}
+	} else {
+		first = len(records)
+	}
+
+	positions := make(map[string]int, len(records))
+	for i, record := range records[1:] {
+		if keyIndex < len(record) {
+			positions[fmt.Sprint(record[keyIndex])] = i + 1
+		}
+	}
+	for _, row := range rows {
+		key := ""
+		if keyIndex < len(row) {
+			key = fmt.Sprint(row[keyIndex])
+		}
+		if pos, ok := positions[key]; ok {
+			records[pos] = row
+			first = min(first, pos)
+			continue
+		}
+		positions[key] = len(records)
+		records = append(records, row)
+	}
+
+	if err := w.writeRows(ctx, "upsert", sheetName, first+1, records[first:]); err != nil {
+		return err
+	}
+	excelRowsWrittenTotal.Add(float64(len(rows)), "upsert")
+	return nil
+}
+
+// CountRows returns the data rows of the worksheet, i.e. its used rows but
+// the header.
+func (w *ExcelOnlineWriter) CountRows(ctx context.Context, sheetName string) (int, error) {
+	used, ok, err := w.usedRange(ctx, "count_rows", sheetName, false)
+	if err != nil {
+		return 0, fmt.Errorf("falha ao contar linhas da aba '%s': %w", sheetName, err)
+	}
+	if !ok {
+		return 0, nil
+	}
+	return max(used.RowIndex+used.RowCount-1, 0), nil
+}
+
+func (w *ExcelOnlineWriter) ReadRows(ctx context.Context, sheetName string) ([][]interface{}, error) {
+	rows, err := w.readLocked(ctx, "read", sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler dados da aba '%s': %w", sheetName, err)
+	}
+	return rows, nil
+}
+
+// readLocked returns the worksheet's values from row 1, or nil when it is
+// blank or missing.
+func (w *ExcelOnlineWriter) readLocked(ctx context.Context, operation, sheetName string) ([][]interface{}, error) {
+	used, ok, err := w.usedRange(ctx, operation, sheetName, true)
+	if err != nil || !ok {
+		return nil, err
+	}
+	rows := make([][]interface{}, used.RowIndex, used.RowIndex+len(used.Values))
+	return append(rows, used.Values...), nil
+}
+
+// excelUsedRange is the part of a worksheet holding values.
+type excelUsedRange struct {
+	Address     string          `json:"address"`
+	RowIndex    int             `json:"rowIndex"`
+	RowCount    int             `json:"rowCount"`
+	ColumnCount int             `json:"columnCount"`
+	Values      [][]interface{} `json:"values"`
+}
+
+// usedRange returns the range of the worksheet holding values, with them when
+// withValues is set. ok is false when the worksheet is blank or missing.
+func (w *ExcelOnlineWriter) usedRange(ctx context.Context, operation, sheetName string, withValues bool) (excelUsedRange, bool, error) {
+	fields := "address,rowIndex,rowCount,columnCount"
+	if withValues {
+		fields += ",values"
+	}
+	var used excelUsedRange
+	getCallFunc := func() error {
+		return w.call(ctx, http.MethodGet, w.worksheetPath(sheetName)+"/usedRange(valuesOnly=true)?$select="+fields, nil, &used)
+	}
+	if err := w.executeGraphCall(ctx, operation, getCallFunc, fmt.Sprintf("ler intervalo usado da aba '%s'", sheetName)); err != nil {
+		if isGraphStatus(err, http.StatusNotFound) {
+			return used, false, nil
+		}
+		return used, false, err
+	}
+	if used.RowIndex > 0 || used.RowCount > 1 || used.ColumnCount > 1 {
+		return used, true, nil
+	}
+
+	// A blank worksheet reports A1 as its used range.
+	cell := used
+	if !withValues {
+		cellCallFunc := func() error {
+			return w.call(ctx, http.MethodGet, w.rangePath(sheetName, "A1")+"?$select=values", nil, &cell)
+		}
+		if err := w.executeGraphCall(ctx, operation, cellCallFunc, fmt.Sprintf("ler célula A1 da aba '%s'", sheetName)); err != nil {
+			return used, false, err
+		}
+	}
+	if len(cell.Values) == 0 || len(cell.Values[0]) == 0 || cell.Values[0][0] == nil || cell.Values[0][0] == "" {
+		return used, false, nil
+	}
+	return used, true, nil
+}
+
+// writeRows writes rows from firstRow on, in requests of at most
+// excelRowsPerRequest rows. Short rows are padded so every request covers a
+// rectangle.
+func (w *ExcelOnlineWriter) writeRows(ctx context.Context, operation, sheetName string, firstRow int, rows [][]interface{}) error {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	if width == 0 {
+		return nil
+	}
+
+	for start := 0; start < len(rows); start += excelRowsPerRequest {
+		chunk := rows[start:min(start+excelRowsPerRequest, len(rows))]
+		top := firstRow + start
+		address := fmt.Sprintf("A%d:%s%d", top, excelColumn(width), top+len(chunk)-1)
+		body := map[string]interface{}{"values": excelValues(chunk, width)}
+		patchCallFunc := func() error {
+			return w.call(ctx, http.MethodPatch, w.rangePath(sheetName, address), body, nil)
+		}
+		if err := w.executeGraphCall(ctx, operation, patchCallFunc, fmt.Sprintf("escrever intervalo '%s' da aba '%s'", address, sheetName)); err != nil {
+			return fmt.Errorf("falha ao escrever intervalo '%s' da aba '%s': %w", address, sheetName, err)
+		}
+	}
+	return nil
+}
+
+func (w *ExcelOnlineWriter) lockSheet(ctx context.Context, sheetName string) (func(), error) {
+	unlock, err := w.locker.Lock(ctx, w.workbookFor(ctx)+"/"+excelWorksheetName(sheetName))
+	if err != nil {
+		return nil, fmt.Errorf("espera pelo bloqueio da aba '%s' cancelada: %w", sheetName, err)
+	}
+	return unlock, nil
+}
+
+func (w *ExcelOnlineWriter) worksheetPath(sheetName string) string {
+	return "/workbook/worksheets/" + url.PathEscape(excelWorksheetName(sheetName))
+}
+
+func (w *ExcelOnlineWriter) rangePath(sheetName, address string) string {
+	return w.worksheetPath(sheetName) + "/range(address='" + address + "')"
+}
+
+// call sends one Graph request for the drive item of the workbook selected by
+// ctx and decodes the JSON response into out, when set. path is relative to
+// the drive item.
+func (w *ExcelOnlineWriter) call(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("falha ao serializar requisição: %w", err)
+		}
+		payload = bytes.NewReader(data)
+	}
+	endpoint := fmt.Sprintf("%s/drives/%s/items/%s%s", w.graphURL, url.PathEscape(w.driveID), url.PathEscape(w.workbookFor(ctx)), path)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, payload)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return newGraphError(resp.StatusCode, resp.Header, data)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("falha ao decodificar resposta da API Graph: %w", err)
+		}
+	}
+	return nil
+}
+
+func (w *ExcelOnlineWriter) executeGraphCall(ctx context.Context, operation string, callFunc func() error, operationDesc string) error {
+	logger := logging.FromContext(ctx).With("operation", operationDesc)
+
+	for attempt := 0; attempt <= w.retryMaxAttempts; attempt++ {
+		err := callFunc()
+		if err == nil {
+			return nil
+		}
+		excelAPIErrorsTotal.Inc(operation, graphErrorCode(err))
+
+		if !isRetryableGraphError(err) || attempt == w.retryMaxAttempts {
+			return fmt.Errorf("falha na operação da API Graph '%s' após %d tentativas: %w", operationDesc, attempt+1, err)
+		}
+
+		delay := w.retryDelay * time.Duration(1<<attempt)
+		var graphErr *graphError
+		asked := false
+		if errors.As(err, &graphErr) {
+			var wait time.Duration
+			if wait, asked = retryAfter(graphErr.Status, graphErr.header, time.Now()); asked {
+				delay = wait
+			}
+		}
+		logger.Warn("Operação da API Graph falhou. Aguardando antes de tentar novamente...", "attempt", attempt+1, "maxAttempts", w.retryMaxAttempts+1, "error", err, "delay", delay.String(), "retryAfter", asked)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("operação '%s' cancelada via contexto durante a espera da nova tentativa: %w", operationDesc, ctx.Err())
+		}
+	}
+	return fmt.Errorf("executeGraphCall atingiu um estado inesperado para a operação: %s", operationDesc)
+}
+
+// graphError is a Microsoft Graph error response.
+type graphError struct {
+	Status  int
+	Code    string
+	Message string
+	header  http.Header
+}
+
+func newGraphError(status int, header http.Header, body []byte) *graphError {
+	var parsed struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(body, &parsed)
+	err := &graphError{Status: status, Code: parsed.Error.Code, Message: parsed.Error.Message, header: header}
+	if err.Message == "" {
+		err.Message = strings.TrimSpace(string(body))
+	}
+	return err
+}
+
+func (e *graphError) Error() string {
+	return fmt.Sprintf("Microsoft Graph %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+func isGraphStatus(err error, status int) bool {
+	var graphErr *graphError
+	return errors.As(err, &graphErr) && graphErr.Status == status
+}
+
+func isRetryableGraphError(err error) bool {
+	var graphErr *graphError
+	if !errors.As(err, &graphErr) {
+		return false
+	}
+	return graphErr.Status == http.StatusTooManyRequests || graphErr.Status >= 500
+}
+
+func graphErrorCode(err error) string {
+	var graphErr *graphError
+	if errors.As(err, &graphErr) {
+		return strconv.Itoa(graphErr.Status)
+	}
+	return "unknown"
+}
+
+// excelWorksheetName fits sheetName to Excel's worksheet name rules: at most
+// 31 characters, none of : \ / ? * [ ], and no leading or trailing
+// apostrophe. Names longer than that are cut, so sheet names should differ
+// early on.
+func excelWorksheetName(sheetName string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`:\/?*[]`, r) {
+			return '-'
+		}
+		return r
+	}, sheetName)
+	if runes := []rune(name); len(runes) > excelMaxWorksheetName {
+		name = string(runes[:excelMaxWorksheetName])
+	}
+	name = strings.Trim(name, "'")
+	if strings.TrimSpace(name) == "" {
+		return "Sheet"
+	}
+	return name
+}
+
+// excelLocalAddress drops the worksheet from an address such as
+// 'EAD 2024'!A1:M20.
+func excelLocalAddress(address string) string {
+	if i := strings.LastIndex(address, "!"); i >= 0 {
+		return address[i+1:]
+	}
+	return address
+}
+
+// excelColumn returns the letters of the 1-based column n, e.g. 28 is AB.
+func excelColumn(n int) string {
+	var letters []byte
+	for ; n > 0; n = (n - 1) / 26 {
+		letters = append([]byte{byte('A' + (n-1)%26)}, letters...)
+	}
+	return string(letters)
+}
+
+func excelHeaderRow(headers []string) []interface{} {
+	row := make([]interface{}, len(headers))
+	for i, h := range headers {
+		row[i] = h
+	}
+	return row
+}
+
+// excelValues pads rows to width. Missing and nil cells are sent as empty
+// strings, since Graph leaves null cells unchanged.
+func excelValues(rows [][]interface{}, width int) [][]interface{} {
+	values := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		values[i] = make([]interface{}, width)
+		for j := range values[i] {
+			values[i][j] = ""
+			if j < len(row) && row[j] != nil {
+				values[i][j] = row[j]
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGraph serves the workbook calls ExcelOnlineWriter makes against
+// in-memory worksheets of drive d, item book.
+type fakeGraph struct {
+	mu         sync.Mutex
+	worksheets map[string][][]interface{}
+	// throttle answers that many calls with 429 before serving them.
+	throttle int
+}
+
+var (
+	fakeGraphWorksheet = regexp.MustCompile(`^/drives/d/items/book/workbook/worksheets/([^/]+)(/.*)?$`)
+	fakeGraphRange     = regexp.MustCompile(`^/range\(address='([A-Z]+)(\d+)(?::([A-Z]+)(\d+))?'\)(/clear)?$`)
+)
+
+func (g *fakeGraph) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.throttle > 0 {
+		g.throttle--
+		rw.Header().Set("Retry-After", "0")
+		rw.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	if r.URL.Path == "/drives/d/items/book/workbook/worksheets/add" {
+		var body struct{ Name string }
+		json.NewDecoder(r.Body).Decode(&body)
+		g.worksheets[body.Name] = nil
+		json.NewEncoder(rw).Encode(map[string]string{"name": body.Name})
+		return
+	}
+	m := fakeGraphWorksheet.FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		http.NotFound(rw, r)
+		return
+	}
+	name, rest := m[1], m[2]
+	grid, ok := g.worksheets[name]
+	if !ok {
+		rw.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(rw, `{"error":{"code":"ItemNotFound","message":"worksheet not found"}}`)
+		return
+	}
+
+	switch {
+	case rest == "":
+		json.NewEncoder(rw).Encode(map[string]string{"name": name})
+	case rest == "/usedRange(valuesOnly=true)":
+		rows, cols := 0, 0
+		for i, row := range grid {
+			for j, v := range row {
+				if v != "" && v != nil {
+					rows, cols = max(rows, i+1), max(cols, j+1)
+				}
+			}
+		}
+		values := [][]interface{}{{""}}
+		if rows > 0 {
+			values = fakeGraphCells(grid, 0, 0, rows, cols)
+		}
+		rows, cols = max(rows, 1), max(cols, 1)
+		json.NewEncoder(rw).Encode(map[string]interface{}{
+			"address":     fmt.Sprintf("'%s'!A1:%s%d", name, excelColumn(cols), rows),
+			"rowIndex":    0,
+			"rowCount":    rows,
+			"columnCount": cols,
+			"values":      values,
+		})
+	default:
+		rm := fakeGraphRange.FindStringSubmatch(rest)
+		if rm == nil {
+			http.NotFound(rw, r)
+			return
+		}
+		top, _ := strconv.Atoi(rm[2])
+		bottom, right := top, fakeGraphColumn(rm[1])
+		if rm[3] != "" {
+			right = fakeGraphColumn(rm[3])
+			bottom, _ = strconv.Atoi(rm[4])
+		}
+		left := fakeGraphColumn(rm[1])
+		switch {
+		case rm[5] != "":
+			for i := top - 1; i < bottom && i < len(grid); i++ {
+				for j := left - 1; j < right && j < len(grid[i]); j++ {
+					grid[i][j] = ""
+				}
+			}
+		case r.Method == http.MethodPatch:
+			var body struct{ Values [][]interface{} }
+			json.NewDecoder(r.Body).Decode(&body)
+			if len(body.Values) != bottom-top+1 {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			for i, row := range body.Values {
+				for len(grid) < top+i {
+					grid = append(grid, nil)
+				}
+				for j, v := range row {
+					for len(grid[top+i-1]) < left+j {
+						grid[top+i-1] = append(grid[top+i-1], "")
+					}
+					grid[top+i-1][left+j-1] = v
+				}
+			}
+			g.worksheets[name] = grid
+		default:
+			json.NewEncoder(rw).Encode(map[string]interface{}{"values": fakeGraphCells(grid, top-1, left-1, bottom, right)})
+		}
+	}
+}
+
+func fakeGraphCells(grid [][]interface{}, top, left, bottom, right int) [][]interface{} {
+	values := make([][]interface{}, 0, bottom-top)
+	for i := top; i < bottom; i++ {
+		row := make([]interface{}, right-left)
+		for j := range row {
+			row[j] = ""
+			if i < len(grid) && left+j < len(grid[i]) {
+				row[j] = grid[i][left+j]
+			}
+		}
+		values = append(values, row)
+	}
+	return values
+}
+
+func fakeGraphColumn(letters string) int {
+	n := 0
+	for _, c := range letters {
+		n = n*26 + int(c-'A'+1)
+	}
+	return n
+}
+
+func newFakeGraphWriter(t *testing.T, graph *fakeGraph) *ExcelOnlineWriter {
+	t.Helper()
+	server := httptest.NewServer(graph)
+	t.Cleanup(server.Close)
+	return newExcelOnlineWriter(server.Client(), server.URL, "d", "book", 2, 0)
+}
+
+func TestExcelOnlineWriter(t *testing.T) {
+	graph := &fakeGraph{worksheets: map[string][][]interface{}{}}
+	writer := newFakeGraphWriter(t, graph)
+	ctx := t.Context()
+	headers := []string{"idMatricula", "aluno", "status"}
+
+	if rows, err := writer.ReadRows(ctx, "EAD"); err != nil || rows != nil {
+		t.Fatalf("ReadRows() of a missing worksheet = %v, %v; want nil", rows, err)
+	}
+	if err := writer.OverwriteSheetData(ctx, "EAD", headers, [][]interface{}{{1, "Ana", "ATIVA"}, {2, "Bruno", nil}}); err != nil {
+		t.Fatalf("OverwriteSheetData() = %v", err)
+	}
+	if err := writer.AppendRows(ctx, "EAD", [][]interface{}{{3, "Carla"}}); err != nil {
+		t.Fatalf("AppendRows() = %v", err)
+	}
+	if err := writer.UpsertRows(ctx, "EAD", headers, "idMatricula", [][]interface{}{{2, "Bruno", "TRANCADA"}, {4, "Davi", "ATIVA"}}); err != nil {
+		t.Fatalf("UpsertRows() = %v", err)
+	}
+
+	rows, err := writer.ReadRows(ctx, "EAD")
+	if err != nil {
+		t.Fatalf("ReadRows() = %v", err)
+	}
+	want := [][]interface{}{
+		{"idMatricula", "aluno", "status"},
+		{1.0, "Ana", "ATIVA"},
+		{2.0, "Bruno", "TRANCADA"},
+		{3.0, "Carla", ""},
+		{4.0, "Davi", "ATIVA"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("ReadRows() = %v, want %v", rows, want)
+	}
+	if count, err := writer.CountRows(ctx, "EAD"); err != nil || count != 4 {
+		t.Errorf("CountRows() = %d, %v; want 4", count, err)
+	}
+
+	if err := writer.OverwriteSheetData(ctx, "EAD", headers, [][]interface{}{{5, "Eva", "ATIVA"}}); err != nil {
+		t.Fatalf("second OverwriteSheetData() = %v", err)
+	}
+	if count, err := writer.CountRows(ctx, "EAD"); err != nil || count != 1 {
+		t.Errorf("CountRows() after overwrite = %d, %v; want 1", count, err)
+	}
+	if err := writer.Clear(ctx, "EAD"); err != nil {
+		t.Fatalf("Clear() = %v", err)
+	}
+	if count, err := writer.CountRows(ctx, "EAD"); err != nil || count != 0 {
+		t.Errorf("CountRows() after Clear = %d, %v; want 0", count, err)
+	}
+}
+
+func TestExcelOnlineWriterRetriesThrottledCalls(t *testing.T) {
+	graph := &fakeGraph{worksheets: map[string][][]interface{}{"EAD": nil}, throttle: 2}
+	writer := newFakeGraphWriter(t, graph)
+
+	if err := writer.AppendRows(t.Context(), "EAD", [][]interface{}{{1, "Ana"}}); err != nil {
+		t.Fatalf("AppendRows() = %v", err)
+	}
+	if got := graph.worksheets["EAD"]; len(got) != 1 || got[0][1] != "Ana" {
+		t.Errorf("worksheet = %v, want the appended row", got)
+	}
+
+	graph.throttle = 10
+	if err := writer.AppendRows(t.Context(), "EAD", [][]interface{}{{2, "Bruno"}}); !isGraphStatus(err, http.StatusTooManyRequests) {
+		t.Errorf("AppendRows() past the retries = %v, want the 429", err)
+	}
+}
+
+func TestExcelWorksheetName(t *testing.T) {
+	tests := map[string]string{
+		"EAD 2024/2":                   "EAD 2024-2",
+		"'Pós'":                        "Pós",
+		"":                             "Sheet",
+		strings.Repeat("Matrícula", 5): "MatrículaMatrículaMatrículaMatr",
+	}
+	for in, want := range tests {
+		if got := excelWorksheetName(in); got != want {
+			t.Errorf("excelWorksheetName(%q) = %q, want %q", in, got, want)
+		}
+	}
+	for n, want := range map[int]string{1: "A", 26: "Z", 27: "AA", 52: "AZ", 703: "AAA"} {
+		if got := excelColumn(n); got != want {
+			t.Errorf("excelColumn(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// ExportSheet is one tab of an ad-hoc export.
+type ExportSheet struct {
+	Name    string
+	Headers []string
+	Rows    [][]interface{}
+}
+
+// ExportEnrollments runs the filtered fetch and returns one sheet per
+// organization without calling the writer, touching the sync state or
+// checkpointing. Without orgIds every organization present in the data gets
+// its own sheet.
+func (c *JacadClient) ExportEnrollments(ctx context.Context, params *requests.FetchEnrollmentsRequest) ([]ExportSheet, error) {
+	logger := logging.FromContext(ctx).With("idPeriodoLetivo", params.PeriodLabel(), "statusMatricula", params.StatusLabel(), "orgId", params.OrgId, "orgIds", params.OrgIds)
+	logger.Info("Starting enrollment export")
+	startTime := time.Now()
+
+	if params.BypassCache {
+		ctx = WithCacheBypass(ctx)
+	}
+	if params.PageSize > 0 {
+		ctx = WithPageSize(ctx, params.PageSize)
+	}
+	if params.PartialPages() {
+		start, end := params.PageRange()
+		ctx = WithPageRange(ctx, start, end)
+	}
+	ctx = WithTenant(ctx, params.Tenant)
+
+	mapper, err := c.rowMapper(logger, params)
+	if err != nil {
+		return nil, err
+	}
+	order, err := ParseEnrollmentSort(params.SortBy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sortBy: %w", err)
+	}
+
+	targets, err := c.resolveSheetTargets(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var allEnrollments []models.Enrollment
+	collect := func(data []models.Enrollment) error {
+		allEnrollments = append(allEnrollments, data...)
+		return nil
+	}
+	window, err := newDataMatriculaWindow(params)
+	if err != nil {
+		return nil, err
+	}
+	dedup := newEnrollmentDeduper()
+	fetched, failed, _, err := c.fetchEnrollmentQueries(ctx, logger, enrollmentFetchParams(params), startTime, false, false, dedup.wrap(window.wrap(collect)))
+	if err != nil {
+		return nil, err
+	}
+	logDuplicates(logger, dedup)
+	logOutsideWindow(logger, window)
+	if failed.batches > 0 {
+		return nil, fmt.Errorf("failed to fetch %d batch(es) of pages; export would be incomplete", failed.batches)
+	}
+	order.Sort(allEnrollments)
+
+	var sheets []ExportSheet
+	for _, target := range targets {
+		if target.PartitionByOrg {
+			data := filterEnrollmentsByOrg(allEnrollments, target.OrgID)
+			sheets = append(sheets, ExportSheet{Name: target.OrgName, Headers: mapper.Headers(), Rows: mapper.Rows(data)})
+			continue
+		}
+
+		byOrg := make(map[int][]models.Enrollment)
+		for _, item := range allEnrollments {
+			byOrg[item.OrgID] = append(byOrg[item.OrgID], item)
+		}
+		orgIDs := make([]int, 0, len(byOrg))
+		for id := range byOrg {
+			orgIDs = append(orgIDs, id)
+		}
+		sort.Ints(orgIDs)
+		for _, id := range orgIDs {
+			name := config.GetOrganizationNameByID(id)
+			if name == "" {
+				name = fmt.Sprintf("Org %d", id)
+			}
+			sheets = append(sheets, ExportSheet{Name: name, Headers: mapper.Headers(), Rows: mapper.Rows(byOrg[id])})
+		}
+	}
+	if len(sheets) == 0 {
+		sheets = append(sheets, ExportSheet{Name: c.Config.DefaultOrgSheet, Headers: mapper.Headers()})
+	}
+
+	logger.Info("Export completed", "fetched", fetched, "sheets", len(sheets), "duration", time.Since(startTime).String())
+	return sheets, nil
+}
+
+// WriteEnrollmentsCSV runs the filtered fetch and writes the header row and
+// then every batch of enrollments to w as soon as it arrives, flushing w after
+// each batch when it supports it. With orgIds only those organizations are
+// written. It returns the number of rows written.
+func (c *JacadClient) WriteEnrollmentsCSV(ctx context.Context, params *requests.FetchEnrollmentsRequest, w io.Writer) (int, error) {
+	logger := logging.FromContext(ctx).With("idPeriodoLetivo", params.PeriodLabel(), "statusMatricula", params.StatusLabel(), "orgId", params.OrgId, "orgIds", params.OrgIds)
+	logger.Info("Starting streamed CSV export")
+	startTime := time.Now()
+
+	if params.BypassCache {
+		ctx = WithCacheBypass(ctx)
+	}
+	if params.PageSize > 0 {
+		ctx = WithPageSize(ctx, params.PageSize)
+	}
+	if params.PartialPages() {
+		start, end := params.PageRange()
+		ctx = WithPageRange(ctx, start, end)
+	}
+	ctx = WithTenant(ctx, params.Tenant)
+
+	mapper, err := c.rowMapper(logger, params)
+	if err != nil {
+		return 0, err
+	}
+
+	targets, err := c.resolveSheetTargets(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+	var orgs map[int]bool
+	for _, target := range targets {
+		if target.PartitionByOrg {
+			if orgs == nil {
+				orgs = make(map[int]bool, len(targets))
+			}
+			orgs[target.OrgID] = true
+		}
+	}
+
+	flusher, canFlush := w.(interface{ Flush() error })
+	cw := csv.NewWriter(w)
+	flush := func() error {
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+		if canFlush {
+			return flusher.Flush()
+		}
+		return nil
+	}
+
+	if err := cw.Write(mapper.Headers()); err != nil {
+		return 0, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	if err := flush(); err != nil {
+		return 0, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	written := 0
+	sink := func(data []models.Enrollment) error {
+		for _, item := range data {
+			if orgs != nil && !orgs[item.OrgID] {
+				continue
+			}
+			if err := cw.Write(csvRecord(mapper.Row(item))); err != nil {
+				return fmt.Errorf("failed to write CSV row: %w", err)
+			}
+			written++
+		}
+		if err := flush(); err != nil {
+			return fmt.Errorf("failed to send CSV rows: %w", err)
+		}
+		return nil
+	}
+
+	window, err := newDataMatriculaWindow(params)
+	if err != nil {
+		return 0, err
+	}
+	dedup := newEnrollmentDeduper()
+	fetched, failed, _, err := c.fetchEnrollmentQueries(ctx, logger, enrollmentFetchParams(params), startTime, false, false, dedup.wrap(window.wrap(sink)))
+	if err != nil {
+		return written, err
+	}
+	logDuplicates(logger, dedup)
+	logOutsideWindow(logger, window)
+	if failed.batches > 0 {
+		return written, fmt.Errorf("failed to fetch %d batch(es) of pages; export is incomplete", failed.batches)
+	}
+
+	logger.Info("Streamed CSV export completed", "fetched", fetched, "rows", written, "duration", time.Since(startTime).String())
+	return written, nil
+}
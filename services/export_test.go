@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+)
+
+// flushCounter counts the Flush calls WriteEnrollmentsCSV makes, noting how
+// many bytes had been written at each.
+type flushCounter struct {
+	strings.Builder
+	flushedAt []int
+}
+
+func (f *flushCounter) Flush() error {
+	f.flushedAt = append(f.flushedAt, f.Len())
+	return nil
+}
+
+func newExportClient(t *testing.T) (*JacadClient, *jacadmock.Server) {
+	t.Helper()
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	t.Cleanup(server.Close)
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.PageSize = 50
+	cfg.MaxPagesPerBatch = 1
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	return NewJacadClient(&cfg, NewFakeSheetWriter(), nil, nil, nil), server
+}
+
+func TestWriteEnrollmentsCSV(t *testing.T) {
+	client, _ := newExportClient(t)
+	var out flushCounter
+
+	rows, err := client.WriteEnrollmentsCSV(context.Background(), &requests.FetchEnrollmentsRequest{
+		OrgIds:          "20",
+		IdPeriodoLetivo: 87,
+		StatusMatricula: "ATIVA",
+	}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(out.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != 60 || len(records) != 61 {
+		t.Fatalf("wrote %d rows and %d records, want the 60 of organization 20 after the header", rows, len(records))
+	}
+	// The header goes out before any page is fetched, then each of the four
+	// pages of 180 enrollments is flushed as it arrives.
+	if len(out.flushedAt) != 5 || out.flushedAt[0] != strings.Index(out.String(), "\n")+1 {
+		t.Errorf("flushed at %v, want the header alone and then every page", out.flushedAt)
+	}
+	if client.Writer.(*FakeSheetWriter).Calls() != nil {
+		t.Error("export called the sheet writer")
+	}
+}
+
+func TestExportEnrollments(t *testing.T) {
+	client, _ := newExportClient(t)
+
+	sheets, err := client.ExportEnrollments(context.Background(), &requests.FetchEnrollmentsRequest{
+		IdPeriodoLetivo: 87,
+		StatusMatricula: "ATIVA",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, sheet := range sheets {
+		names = append(names, sheet.Name)
+		if len(sheet.Rows) != 60 {
+			t.Errorf("sheet %q has %d rows, want 60", sheet.Name, len(sheet.Rows))
+		}
+	}
+	if got := strings.Join(names, ","); got != "PÓS Presencial,PÓS EAD,EAD" {
+		t.Errorf("sheets = %s, want one per organization in id order", got)
+	}
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found in the history")
+	// ErrRetryNotSupported is returned for jobs whose pages cannot be retried,
+	// such as dry runs or jobs other than fetch-enrollments.
+	ErrRetryNotSupported = errors.New("job does not support retrying failed pages")
+)
+
+// FailedPage is a Jacad page a fetch lost after all retries. Query and
+// PageSize identify the listing the page belongs to.
+type FailedPage struct {
+	Query    map[string]string `json:"query,omitempty"`
+	PageSize int               `json:"pageSize"`
+	Page     int               `json:"page"`
+}
+
+// pageFailures tracks what a fetch lost: batches in which every page failed,
+// and every failed page.
+type pageFailures struct {
+	batches int
+	pages   []FailedPage
+}
+
+func (f *pageFailures) add(query map[string]string, pageSize int, pages []int) {
+	for _, page := range pages {
+		f.pages = append(f.pages, FailedPage{Query: query, PageSize: pageSize, Page: page})
+	}
+}
+
+// RetryResult reports a retry of a job's failed pages.
+type RetryResult struct {
+	JobID   string `json:"jobId"`
+	Retried int    `json:"retried"`
+	// Recovered counts the retried pages that were fetched this time.
+	Recovered    int `json:"recovered"`
+	TotalFetched int `json:"totalFetched"`
+	// FailedPages lists the pages that failed again.
+	FailedPages   []FailedPage `json:"failedPages,omitempty"`
+	Organizations []OrgSummary `json:"organizations"`
+}
+
+// RetryFailedPages refetches the pages a fetch-enrollments job lost and
+// upserts their rows into the job's sheets by idMatricula, so rows already in
+// the sheets are kept. Summary and delta tabs are not rewritten. The job's
+// history record is updated to the pages that still fail.
+func (c *JacadClient) RetryFailedPages(ctx context.Context, jobID string) (*RetryResult, error) {
+	logger := logging.FromContext(ctx).With("retryJobId", jobID)
+	startTime := time.Now()
+
+	if c.History == nil {
+		return nil, fmt.Errorf("%w: no job history is configured", ErrJobNotFound)
+	}
+	record, err := c.History.Find(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrJobNotFound, jobID)
+	}
+	params, err := retryableRequest(record)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RetryResult{JobID: jobID, Retried: len(record.FailedPages), Organizations: []OrgSummary{}}
+	if len(record.FailedPages) == 0 {
+		logger.Info("Job has no failed pages to retry")
+		return result, nil
+	}
+
+	mapper, err := c.rowMapper(logger, params)
+	if err != nil {
+		return nil, err
+	}
+	keyHeader, ok := mapper.HeaderFor("idMatricula")
+	if !ok {
+		return nil, fmt.Errorf("retrying failed pages requires the idMatricula column in the column mapping")
+	}
+	targets, err := c.resolveSheetTargets(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	window, err := newDataMatriculaWindow(params)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = WithTenant(WithCacheBypass(ctx), params.Tenant)
+	var data []models.Enrollment
+	collect := newEnrollmentDeduper().wrap(window.wrap(func(batch []models.Enrollment) error {
+		data = append(data, batch...)
+		return nil
+	}))
+
+	var failed pageFailures
+	for _, group := range groupFailedPages(record.FailedPages) {
+		first := group[0]
+		pages := make([]int, len(group))
+		for i, page := range group {
+			pages[i] = page.Page
+		}
+		logger.Info("Retrying failed pages", "query", first.Query, "pageSize", first.PageSize, "pages", pages)
+		batch, failedPages, err := c.processBatchEnrollmentsFiltered(WithPageSize(ctx, first.PageSize), pages, first.Query, nil)
+		failed.add(first.Query, first.PageSize, failedPages)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			logger.Error("Failed to retry pages", "query", first.Query, "error", err)
+			continue
+		}
+		if err := collect(batch); err != nil {
+			return nil, err
+		}
+		result.TotalFetched += len(batch)
+	}
+	result.FailedPages = failed.pages
+	result.Recovered = result.Retried - len(failed.pages)
+
+	var lastErr error
+	failures, sheets := 0, 0
+	for _, target := range targets {
+		targetData := data
+		if target.PartitionByOrg {
+			targetData = filterEnrollmentsByOrg(data, target.OrgID)
+		}
+		for _, group := range c.groupEnrollments(target, targetData, params.GroupBy, params) {
+			if len(group.Data) == 0 {
+				continue
+			}
+			sheets++
+			summary := OrgSummary{OrgID: target.OrgID, OrgName: target.OrgName, Sheet: group.Sheet, Group: group.Group, Rows: len(group.Data)}
+			logger.Info("Merging recovered enrollments into sheet...", "sheet", group.Sheet, "rows", len(group.Data))
+			if err := c.Writer.UpsertRows(c.spreadsheetContext(ctx, group.OrgID), group.Sheet, mapper.Headers(), keyHeader, mapper.Rows(group.Data)); err != nil {
+				logger.Error("Failed to merge recovered enrollments", "sheet", group.Sheet, "error", err)
+				summary.Rows = 0
+				summary.Error = err.Error()
+				lastErr = err
+				failures++
+			}
+			result.Organizations = append(result.Organizations, summary)
+		}
+	}
+
+	if failures == 0 {
+		record.FailedPages = failed.pages
+		record.RowsFetched += result.TotalFetched
+		for _, org := range result.Organizations {
+			record.RowsWritten += org.Rows
+		}
+		if err := c.History.Record(*record); err != nil {
+			logger.Warn("Failed to update job history after retry", "error", err)
+		}
+	}
+	if sheets > 0 && failures == sheets {
+		return result, fmt.Errorf("failed to merge enrollments into %d sheet(s): %w", failures, lastErr)
+	}
+
+	logger.Info("Failed page retry completed", "retried", result.Retried, "recovered", result.Recovered, "fetched", result.TotalFetched, "failedSheets", failures, "duration", time.Since(startTime).String())
+	return result, nil
+}
+
+// retryableRequest rebuilds the request of a job from its recorded parameters,
+// which are keyed by the same names as the request's JSON fields.
+func retryableRequest(record *JobRecord) (*requests.FetchEnrollmentsRequest, error) {
+	if record.Job != "fetch-enrollments" {
+		return nil, fmt.Errorf("%w: job '%s' is a %s job", ErrRetryNotSupported, record.ID, record.Job)
+	}
+	raw, err := json.Marshal(record.Params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the parameters of job '%s': %w", record.ID, err)
+	}
+	params := new(requests.FetchEnrollmentsRequest)
+	if err := json.Unmarshal(raw, params); err != nil {
+		return nil, fmt.Errorf("failed to decode the parameters of job '%s': %w", record.ID, err)
+	}
+	switch {
+	case params.DryRun:
+		return nil, fmt.Errorf("%w: job '%s' was a dry run", ErrRetryNotSupported, record.ID)
+	case params.NewSpreadsheet:
+		return nil, fmt.Errorf("%w: job '%s' wrote to a spreadsheet of its own", ErrRetryNotSupported, record.ID)
+	}
+	return params, nil
+}
+
+// groupFailedPages groups pages by listing, in a stable order.
+func groupFailedPages(pages []FailedPage) [][]FailedPage {
+	byListing := make(map[string][]FailedPage)
+	var keys []string
+	for _, page := range pages {
+		key := CheckpointKey("", page.Query, page.PageSize)
+		if _, ok := byListing[key]; !ok {
+			keys = append(keys, key)
+		}
+		byListing[key] = append(byListing[key], page)
+	}
+	sort.Strings(keys)
+	groups := make([][]FailedPage, len(keys))
+	for i, key := range keys {
+		groups[i] = byListing[key]
+		sort.Slice(groups[i], func(a, b int) bool { return groups[i][a].Page < groups[i][b].Page })
+	}
+	return groups
+}
+
+// failedPagesSummary describes lost pages for notifications, e.g.
+// "3 page(s) failed to fetch: 4, 9, 12".
+func failedPagesSummary(pages []FailedPage) string {
+	numbers := make([]string, len(pages))
+	for i, page := range pages {
+		numbers[i] = fmt.Sprint(page.Page)
+	}
+	return fmt.Sprintf("%d page(s) failed to fetch: %s", len(pages), strings.Join(numbers, ", "))
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+)
+
+func TestGroupFailedPages(t *testing.T) {
+	ativa := map[string]string{"statusMatricula": "ATIVA"}
+	trancada := map[string]string{"statusMatricula": "TRANCADA"}
+	groups := groupFailedPages([]FailedPage{
+		{Query: trancada, PageSize: 50, Page: 7},
+		{Query: ativa, PageSize: 50, Page: 4},
+		{Query: trancada, PageSize: 50, Page: 2},
+		{Query: ativa, PageSize: 100, Page: 1},
+	})
+
+	got := make(map[string][]int)
+	for _, group := range groups {
+		listing := fmt.Sprintf("%s/%d", group[0].Query["statusMatricula"], group[0].PageSize)
+		for _, page := range group {
+			if !reflect.DeepEqual(page.Query, group[0].Query) || page.PageSize != group[0].PageSize {
+				t.Errorf("group %v mixes listings", group)
+			}
+			got[listing] = append(got[listing], page.Page)
+		}
+	}
+	want := map[string][]int{"ATIVA/50": {4}, "ATIVA/100": {1}, "TRANCADA/50": {2, 7}}
+	if len(groups) != 3 || !reflect.DeepEqual(got, want) {
+		t.Errorf("groupFailedPages() pages = %v, want %v", got, want)
+	}
+	if again := groupFailedPages([]FailedPage{{Query: ativa, PageSize: 100, Page: 1}, {Query: trancada, PageSize: 50, Page: 2}, {Query: trancada, PageSize: 50, Page: 7}, {Query: ativa, PageSize: 50, Page: 4}}); !reflect.DeepEqual(again, groups) {
+		t.Errorf("groupFailedPages() order depends on the input: %v vs %v", again, groups)
+	}
+
+	if got := failedPagesSummary([]FailedPage{{Page: 4}, {Page: 9}}); got != "2 page(s) failed to fetch: 4, 9" {
+		t.Errorf("failedPagesSummary() = %q", got)
+	}
+}
+
+// TestRetryFailedPages loses a page of a fetch, then retries it once Jacad
+// serves it again and checks its rows are merged into the sheet.
+func TestRetryFailedPages(t *testing.T) {
+	jacad := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer jacad.Close()
+	target, _ := url.Parse(jacad.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() && r.URL.Query().Get("currentPage") == "2" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = jacad.UserToken
+	cfg.PageSize = 50
+	cfg.MaxPagesPerBatch = 1
+	cfg.MaxRetries = 1
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	writer := NewFakeSheetWriter()
+	client := NewJacadClient(&cfg, writer, NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+	client.History = newTestJobHistory(t)
+
+	ctx := logging.WithJobID(context.Background(), "job-1")
+	result, err := client.FetchEnrollmentsFiltered(ctx, &requests.FetchEnrollmentsRequest{
+		OrgIds:          "20",
+		IdPeriodoLetivo: 87,
+		StatusMatricula: "ATIVA",
+		Mode:            requests.SyncModeFull,
+		WriteMode:       requests.WriteModeAtomic,
+		GroupBy:         requests.GroupByNone,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.FailedPages) != 1 || result.FailedPages[0].Page != 2 || result.FailedPages[0].PageSize != 50 {
+		t.Fatalf("FailedPages = %+v, want page 2", result.FailedPages)
+	}
+	sheet := result.Organizations[0].Sheet
+	written, _ := writer.Sheet(sheet)
+	lost := 60 - len(written.Rows)
+	if lost <= 0 {
+		t.Fatalf("sheet holds %d rows, want organization 20's rows of page 2 missing", len(written.Rows))
+	}
+
+	failing.Store(false)
+	retry, err := client.RetryFailedPages(context.Background(), "job-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry.Retried != 1 || retry.Recovered != 1 || retry.TotalFetched != 50 || len(retry.FailedPages) != 0 {
+		t.Errorf("retry = %+v, want page 2's 50 enrollments recovered", retry)
+	}
+	if len(retry.Organizations) != 1 || retry.Organizations[0].Sheet != sheet || retry.Organizations[0].Rows != lost {
+		t.Errorf("retry organizations = %+v, want %d rows merged into %q", retry.Organizations, lost, sheet)
+	}
+	if written, _ = writer.Sheet(sheet); len(written.Rows) != 60 {
+		t.Errorf("sheet holds %d rows after the retry, want 60", len(written.Rows))
+	}
+
+	record, err := client.History.Find("job-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(record.FailedPages) != 0 || record.RowsWritten != 60 {
+		t.Errorf("history = %+v, want no failed pages and 60 rows written", record)
+	}
+	again, err := client.RetryFailedPages(context.Background(), "job-1")
+	if err != nil || again.Retried != 0 {
+		t.Errorf("second retry = %+v, %v; want nothing to retry", again, err)
+	}
+}
+
+func TestRetryFailedPagesErrors(t *testing.T) {
+	cfg := config.Defaults()
+	client := NewJacadClient(&cfg, NewFakeSheetWriter(), nil, nil, nil)
+	if _, err := client.RetryFailedPages(context.Background(), "job-1"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("RetryFailedPages() without history = %v, want ErrJobNotFound", err)
+	}
+
+	client.History = newTestJobHistory(t)
+	pages := []FailedPage{{Page: 1, PageSize: 50}}
+	for _, record := range []JobRecord{
+		{ID: "courses", Job: "fetch-courses", FailedPages: pages},
+		{ID: "dry-run", Job: "fetch-enrollments", Params: map[string]interface{}{"dryRun": true}, FailedPages: pages},
+		{ID: "new-spreadsheet", Job: "fetch-enrollments", Params: map[string]interface{}{"newSpreadsheet": true}, FailedPages: pages},
+	} {
+		if err := client.History.Record(record); err != nil {
+			t.Fatal(err)
+		}
+		_, err := client.RetryFailedPages(context.Background(), record.ID)
+		if !errors.Is(err, ErrRetryNotSupported) || !strings.Contains(err.Error(), record.ID) {
+			t.Errorf("RetryFailedPages(%s) = %v, want ErrRetryNotSupported", record.ID, err)
+		}
+	}
+	if _, err := client.RetryFailedPages(context.Background(), "unknown"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("RetryFailedPages(unknown) = %v, want ErrJobNotFound", err)
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeCall is one SheetWriter call a FakeSheetWriter received.
+type FakeCall struct {
+	Method string
+	// Spreadsheet is the spreadsheet selected by WithSpreadsheetID, if any.
+	Spreadsheet string
+	Sheet       string
+	Headers     []string
+	KeyColumn   string
+	Rows        [][]interface{}
+}
+
+// FakeSheet is the content of one emulated tab.
+type FakeSheet struct {
+	Headers []string
+	Rows    [][]interface{}
+}
+
+// FakeSheetWriter is an in-memory SheetWriter for tests. It records every
+// call and applies it to emulated tabs the way Google Sheets would, so tests
+// can check both what was sent and what the sheet ends up holding. Dump
+// renders both as text for golden files.
+type FakeSheetWriter struct {
+	// FailOn, when set, is asked before each call; a non-nil error fails
+	// the call without recording it.
+	FailOn func(method, sheet string) error
+
+	mu     sync.Mutex
+	calls  []FakeCall
+	sheets map[string]*FakeSheet
+}
+
+func NewFakeSheetWriter() *FakeSheetWriter {
+	return &FakeSheetWriter{sheets: make(map[string]*FakeSheet)}
+}
+
+// Calls returns the recorded calls in the order they were made.
+func (w *FakeSheetWriter) Calls() []FakeCall {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.calls)
+}
+
+// Sheet returns a copy of the tab named sheetName, or false if no call
+// created it.
+func (w *FakeSheetWriter) Sheet(sheetName string) (FakeSheet, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sheet, ok := w.sheets[sheetName]
+	if !ok {
+		return FakeSheet{}, false
+	}
+	return FakeSheet{Headers: slices.Clone(sheet.Headers), Rows: slices.Clone(sheet.Rows)}, true
+}
+
+// record checks FailOn, logs call and returns the tab it targets, creating
+// it. w.mu must be held.
+func (w *FakeSheetWriter) record(ctx context.Context, call FakeCall) (*FakeSheet, error) {
+	if w.FailOn != nil {
+		if err := w.FailOn(call.Method, call.Sheet); err != nil {
+			return nil, err
+		}
+	}
+	call.Spreadsheet, _ = ctx.Value(spreadsheetIDKey{}).(string)
+	call.Headers = slices.Clone(call.Headers)
+	call.Rows = slices.Clone(call.Rows)
+
+	w.calls = append(w.calls, call)
+	sheet, ok := w.sheets[call.Sheet]
+	if !ok {
+		sheet = &FakeSheet{}
+		w.sheets[call.Sheet] = sheet
+	}
+	return sheet, nil
+}
+
+func (w *FakeSheetWriter) EnsureSheetExists(ctx context.Context, sheetName string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.record(ctx, FakeCall{Method: "EnsureSheetExists", Sheet: sheetName})
+	return err
+}
+
+func (w *FakeSheetWriter) Clear(ctx context.Context, sheetName string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sheet, err := w.record(ctx, FakeCall{Method: "Clear", Sheet: sheetName})
+	if err != nil {
+		return err
+	}
+	sheet.Headers, sheet.Rows = nil, nil
+	return nil
+}
+
+func (w *FakeSheetWriter) SetHeaders(ctx context.Context, sheetName string, headers []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sheet, err := w.record(ctx, FakeCall{Method: "SetHeaders", Sheet: sheetName, Headers: headers})
+	if err != nil {
+		return err
+	}
+	sheet.Headers = slices.Clone(headers)
+	return nil
+}
+
+func (w *FakeSheetWriter) AppendRows(ctx context.Context, sheetName string, rows [][]interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sheet, err := w.record(ctx, FakeCall{Method: "AppendRows", Sheet: sheetName, Rows: rows})
+	if err != nil {
+		return err
+	}
+	sheet.Rows = append(sheet.Rows, rows...)
+	return nil
+}
+
+func (w *FakeSheetWriter) OverwriteSheetData(ctx context.Context, sheetName string, headers []string, rows [][]interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sheet, err := w.record(ctx, FakeCall{Method: "OverwriteSheetData", Sheet: sheetName, Headers: headers, Rows: rows})
+	if err != nil {
+		return err
+	}
+	sheet.Headers, sheet.Rows = slices.Clone(headers), slices.Clone(rows)
+	return nil
+}
+
+// UpsertRows replaces the rows whose keyColumn value matches one of rows and
+// appends the rest, like GoogleSheetsWriter.UpsertRows.
+func (w *FakeSheetWriter) UpsertRows(ctx context.Context, sheetName string, headers []string, keyColumn string, rows [][]interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sheet, err := w.record(ctx, FakeCall{Method: "UpsertRows", Sheet: sheetName, Headers: headers, KeyColumn: keyColumn, Rows: rows})
+	if err != nil {
+		return err
+	}
+	key := slices.Index(headers, keyColumn)
+	if key < 0 {
+		return fmt.Errorf("key column '%s' not in headers", keyColumn)
+	}
+	sheet.Headers = slices.Clone(headers)
+	existing := make(map[string]int, len(sheet.Rows))
+	for i, row := range sheet.Rows {
+		if key < len(row) {
+			existing[fmt.Sprint(row[key])] = i
+		}
+	}
+	for _, row := range rows {
+		if i, ok := existing[fmt.Sprint(row[key])]; ok {
+			sheet.Rows[i] = row
+			continue
+		}
+		existing[fmt.Sprint(row[key])] = len(sheet.Rows)
+		sheet.Rows = append(sheet.Rows, row)
+	}
+	return nil
+}
+
+// CountRows reports the data rows of sheetName, implementing RowCounter.
+func (w *FakeSheetWriter) CountRows(ctx context.Context, sheetName string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if sheet, ok := w.sheets[sheetName]; ok {
+		return len(sheet.Rows), nil
+	}
+	return 0, nil
+}
+
+// Dump renders the recorded calls, then every tab sorted by name with its
+// headers and rows, one line each with cells separated by " | ". The output
+// only depends on what was written, so it can be compared to a golden file.
+func (w *FakeSheetWriter) Dump() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# calls\n")
+	for _, call := range w.calls {
+		fmt.Fprintf(&b, "%s %q", call.Method, call.Sheet)
+		if call.Spreadsheet != "" {
+			fmt.Fprintf(&b, " spreadsheet=%s", call.Spreadsheet)
+		}
+		if call.Headers != nil {
+			fmt.Fprintf(&b, " headers=%d", len(call.Headers))
+		}
+		if call.KeyColumn != "" {
+			fmt.Fprintf(&b, " key=%q", call.KeyColumn)
+		}
+		if call.Rows != nil || call.Method == "AppendRows" || call.Method == "OverwriteSheetData" {
+			fmt.Fprintf(&b, " rows=%d", len(call.Rows))
+		}
+		b.WriteString("\n")
+	}
+
+	names := make([]string, 0, len(w.sheets))
+	for name := range w.sheets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		sheet := w.sheets[name]
+		fmt.Fprintf(&b, "\n# sheet %q\n", name)
+		b.WriteString(strings.Join(sheet.Headers, " | "))
+		b.WriteString("\n")
+		for _, row := range sheet.Rows {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = dumpCell(cell)
+			}
+			b.WriteString(strings.Join(cells, " | "))
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// dumpCell renders a cell; dates without a time of day as YYYY-MM-DD.
+func dumpCell(cell interface{}) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		if v.Equal(v.Truncate(24 * time.Hour)) {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(cell)
+}
@@ -0,0 +1,415 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// fakeSheetsAPI serves the Sheets API calls GoogleSheetsWriter makes against
+// in-memory spreadsheets. Reads render cells as text unless they ask for
+// UNFORMATTED_VALUE, as the API does.
+type fakeSheetsAPI struct {
+	mu     sync.Mutex
+	nextID int64
+	books  map[string][]*fakeSheetsTab
+	// calls lists every request as "METHOD path", in order.
+	calls []string
+	// failOn, when set, is asked before each request; a non-nil error
+	// answers it with a 400.
+	failOn func(method, path string) error
+}
+
+type fakeSheetsTab struct {
+	id     int64
+	title  string
+	values [][]interface{}
+	// banded holds the ids of the tab's banded ranges.
+	banded []int64
+	// formats lists the formatting requests batchUpdate received for the
+	// tab, in order.
+	formats []*sheets.Request
+}
+
+var fakeSheetsCell = regexp.MustCompile(`^([A-Z]*)(\d*)$`)
+
+func newFakeSheetsAPI() *fakeSheetsAPI {
+	return &fakeSheetsAPI{books: make(map[string][]*fakeSheetsTab)}
+}
+
+// newFakeSheetsWriter returns a writer for spreadsheet "book" served by api.
+func newFakeSheetsWriter(t *testing.T, api *fakeSheetsAPI) *GoogleSheetsWriter {
+	t.Helper()
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	service, err := sheets.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &GoogleSheetsWriter{
+		sheetsService:    service,
+		spreadsheetID:    "book",
+		retryMaxAttempts: 1,
+		writeLimiter:     NewPerMinuteLimiter(0),
+		appendBuffers:    make(map[pendingAppendKey][][]interface{}),
+		writtenRows:      make(map[appendBufferKey]int),
+		locker:           NewLocalSheetLocker(),
+	}
+}
+
+// Tab returns the values of a tab of book, or false if it does not exist.
+func (a *fakeSheetsAPI) Tab(book, title string) ([][]interface{}, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	tab := a.tab(book, title)
+	if tab == nil {
+		return nil, false
+	}
+	return fakeSheetsTrim(tab.values), true
+}
+
+// SetTab creates or replaces a tab of book.
+func (a *fakeSheetsAPI) SetTab(book, title string, values [][]interface{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	tab := a.tab(book, title)
+	if tab == nil {
+		tab = a.addTab(book, title)
+	}
+	tab.values = values
+}
+
+// Formats returns the formatting requests sent for a tab of book and the ids
+// of its banded ranges.
+func (a *fakeSheetsAPI) Formats(book, title string) ([]*sheets.Request, []int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	tab := a.tab(book, title)
+	if tab == nil {
+		return nil, nil
+	}
+	return append([]*sheets.Request(nil), tab.formats...), append([]int64(nil), tab.banded...)
+}
+
+// Calls returns the requests served so far.
+func (a *fakeSheetsAPI) Calls() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.calls...)
+}
+
+func (a *fakeSheetsAPI) tab(book, title string) *fakeSheetsTab {
+	for _, tab := range a.books[book] {
+		if tab.title == title {
+			return tab
+		}
+	}
+	return nil
+}
+
+func (a *fakeSheetsAPI) tabByID(book string, id int64) *fakeSheetsTab {
+	for _, tab := range a.books[book] {
+		if tab.id == id {
+			return tab
+		}
+	}
+	return &fakeSheetsTab{}
+}
+
+func (a *fakeSheetsAPI) addTab(book, title string) *fakeSheetsTab {
+	a.nextID++
+	tab := &fakeSheetsTab{id: a.nextID, title: title}
+	a.books[book] = append(a.books[book], tab)
+	return tab
+}
+
+func (a *fakeSheetsAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls = append(a.calls, r.Method+" "+r.URL.Path)
+	if a.failOn != nil {
+		if err := a.failOn(r.Method, r.URL.Path); err != nil {
+			fakeSheetsError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	path, ok := strings.CutPrefix(r.URL.Path, "/v4/spreadsheets/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	end := strings.IndexAny(path, "/:")
+	if end < 0 {
+		end = len(path)
+	}
+	book, rest := path[:end], path[end:]
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		a.getSpreadsheet(w, book)
+	case rest == ":batchUpdate":
+		a.batchUpdate(w, r, book)
+	case rest == "/values:batchUpdate":
+		var req sheets.BatchUpdateValuesRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		total := 0
+		for _, data := range req.Data {
+			n, err := a.write(book, data.Range, data.Values)
+			if err != nil {
+				fakeSheetsError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			total += n
+		}
+		json.NewEncoder(w).Encode(sheets.BatchUpdateValuesResponse{TotalUpdatedRows: int64(total)})
+	case strings.HasPrefix(rest, "/values/"):
+		a.values(w, r, book, strings.TrimPrefix(rest, "/values/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (a *fakeSheetsAPI) getSpreadsheet(w http.ResponseWriter, book string) {
+	spreadsheet := sheets.Spreadsheet{SpreadsheetId: book}
+	for i, tab := range a.books[book] {
+		sheet := &sheets.Sheet{Properties: &sheets.SheetProperties{
+			SheetId: tab.id,
+			Title:   tab.title,
+			Index:   int64(i),
+		}}
+		for _, id := range tab.banded {
+			sheet.BandedRanges = append(sheet.BandedRanges, &sheets.BandedRange{BandedRangeId: id})
+		}
+		spreadsheet.Sheets = append(spreadsheet.Sheets, sheet)
+	}
+	json.NewEncoder(w).Encode(spreadsheet)
+}
+
+func (a *fakeSheetsAPI) batchUpdate(w http.ResponseWriter, r *http.Request, book string) {
+	var req sheets.BatchUpdateSpreadsheetRequest
+	json.NewDecoder(r.Body).Decode(&req)
+	var resp sheets.BatchUpdateSpreadsheetResponse
+	for _, request := range req.Requests {
+		reply := &sheets.Response{}
+		switch {
+		case request.AddSheet != nil:
+			title := request.AddSheet.Properties.Title
+			if a.tab(book, title) != nil {
+				fakeSheetsError(w, http.StatusBadRequest, fmt.Sprintf("A sheet with the name %q already exists.", title))
+				return
+			}
+			tab := a.addTab(book, title)
+			reply.AddSheet = &sheets.AddSheetResponse{Properties: &sheets.SheetProperties{SheetId: tab.id, Title: title}}
+		case request.DeleteSheet != nil:
+			tabs := a.books[book]
+			for i, tab := range tabs {
+				if tab.id == request.DeleteSheet.SheetId {
+					a.books[book] = append(tabs[:i:i], tabs[i+1:]...)
+					break
+				}
+			}
+		case request.UpdateSheetProperties != nil && strings.Contains(request.UpdateSheetProperties.Fields, "title"):
+			for _, tab := range a.books[book] {
+				if tab.id == request.UpdateSheetProperties.Properties.SheetId {
+					tab.title = request.UpdateSheetProperties.Properties.Title
+				}
+			}
+		case request.AddBanding != nil:
+			tab := a.tabByID(book, request.AddBanding.BandedRange.Range.SheetId)
+			a.nextID++
+			tab.banded = append(tab.banded, a.nextID)
+			tab.formats = append(tab.formats, request)
+		case request.DeleteBanding != nil:
+			for _, tab := range a.books[book] {
+				for i, id := range tab.banded {
+					if id == request.DeleteBanding.BandedRangeId {
+						tab.banded = append(tab.banded[:i:i], tab.banded[i+1:]...)
+						tab.formats = append(tab.formats, request)
+					}
+				}
+			}
+		case request.UpdateSheetProperties != nil:
+			tab := a.tabByID(book, request.UpdateSheetProperties.Properties.SheetId)
+			tab.formats = append(tab.formats, request)
+		case request.RepeatCell != nil:
+			tab := a.tabByID(book, request.RepeatCell.Range.SheetId)
+			tab.formats = append(tab.formats, request)
+		case request.AutoResizeDimensions != nil:
+			tab := a.tabByID(book, request.AutoResizeDimensions.Dimensions.SheetId)
+			tab.formats = append(tab.formats, request)
+		}
+		resp.Replies = append(resp.Replies, reply)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (a *fakeSheetsAPI) values(w http.ResponseWriter, r *http.Request, book, valueRange string) {
+	switch {
+	case strings.HasSuffix(valueRange, ":append"):
+		var body sheets.ValueRange
+		json.NewDecoder(r.Body).Decode(&body)
+		title, _ := fakeSheetsRange(strings.TrimSuffix(valueRange, ":append"))
+		tab := a.tab(book, title)
+		if tab == nil {
+			fakeSheetsError(w, http.StatusBadRequest, "Unable to parse range: "+title)
+			return
+		}
+		start := len(fakeSheetsTrim(tab.values))
+		tab.values = fakeSheetsPut(tab.values, start, 0, body.Values)
+		json.NewEncoder(w).Encode(sheets.AppendValuesResponse{Updates: &sheets.UpdateValuesResponse{UpdatedRows: int64(len(body.Values))}})
+	case strings.HasSuffix(valueRange, ":clear"):
+		title, anchor := fakeSheetsRange(strings.TrimSuffix(valueRange, ":clear"))
+		tab := a.tab(book, title)
+		if tab == nil {
+			fakeSheetsError(w, http.StatusBadRequest, "Unable to parse range: "+title)
+			return
+		}
+		if anchor == "" {
+			tab.values = nil
+		} else {
+			from, to, _ := strings.Cut(anchor, ":")
+			first, last := fakeSheetsColumn(from), fakeSheetsColumn(to)
+			for _, row := range tab.values {
+				for j := first; j <= last && j < len(row); j++ {
+					row[j] = ""
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(sheets.ClearValuesResponse{})
+	case r.Method == http.MethodPut:
+		var body sheets.ValueRange
+		json.NewDecoder(r.Body).Decode(&body)
+		n, err := a.write(book, valueRange, body.Values)
+		if err != nil {
+			fakeSheetsError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		json.NewEncoder(w).Encode(sheets.UpdateValuesResponse{UpdatedRows: int64(n)})
+	default:
+		title, anchor := fakeSheetsRange(valueRange)
+		tab := a.tab(book, title)
+		if tab == nil {
+			fakeSheetsError(w, http.StatusBadRequest, "Unable to parse range: "+title)
+			return
+		}
+		values := fakeSheetsTrim(tab.values)
+		if from, _, ok := strings.Cut(anchor, ":"); ok {
+			column := fakeSheetsColumn(from)
+			var cells [][]interface{}
+			for _, row := range values {
+				var cell interface{} = ""
+				if column < len(row) {
+					cell = row[column]
+				}
+				cells = append(cells, []interface{}{cell})
+			}
+			values = fakeSheetsTrim(cells)
+		}
+		if r.URL.Query().Get("valueRenderOption") != "UNFORMATTED_VALUE" {
+			values = fakeSheetsFormatted(values)
+		}
+		json.NewEncoder(w).Encode(sheets.ValueRange{Range: valueRange, Values: values})
+	}
+}
+
+// write puts values at the top-left cell of valueRange and returns the
+// number of rows written.
+func (a *fakeSheetsAPI) write(book, valueRange string, values [][]interface{}) (int, error) {
+	title, anchor := fakeSheetsRange(valueRange)
+	tab := a.tab(book, title)
+	if tab == nil {
+		return 0, fmt.Errorf("Unable to parse range: %s", valueRange)
+	}
+	m := fakeSheetsCell.FindStringSubmatch(strings.Split(anchor, ":")[0])
+	row, column := 0, 0
+	if m != nil && m[2] != "" {
+		row, _ = strconv.Atoi(m[2])
+		row--
+	}
+	if m != nil && m[1] != "" {
+		column = fakeSheetsColumn(m[1])
+	}
+	tab.values = fakeSheetsPut(tab.values, row, column, values)
+	return len(values), nil
+}
+
+// fakeSheetsRange splits "'Tab'!A1:B2" into the tab title and the cells.
+func fakeSheetsRange(valueRange string) (string, string) {
+	title, anchor, _ := strings.Cut(valueRange, "!")
+	return strings.ReplaceAll(strings.Trim(title, "'"), "''", "'"), anchor
+}
+
+func fakeSheetsColumn(letters string) int {
+	n := 0
+	for _, c := range strings.TrimRight(letters, "0123456789") {
+		n = n*26 + int(c-'A'+1)
+	}
+	return n - 1
+}
+
+func fakeSheetsPut(grid [][]interface{}, row, column int, values [][]interface{}) [][]interface{} {
+	for i, cells := range values {
+		for len(grid) <= row+i {
+			grid = append(grid, nil)
+		}
+		for j, cell := range cells {
+			for len(grid[row+i]) <= column+j {
+				grid[row+i] = append(grid[row+i], "")
+			}
+			grid[row+i][column+j] = cell
+		}
+	}
+	return grid
+}
+
+// fakeSheetsTrim drops trailing empty cells and rows, which the API leaves
+// out of what it returns.
+func fakeSheetsTrim(grid [][]interface{}) [][]interface{} {
+	trimmed := make([][]interface{}, 0, len(grid))
+	last := 0
+	for _, row := range grid {
+		n := len(row)
+		for n > 0 && (row[n-1] == nil || row[n-1] == "") {
+			n--
+		}
+		trimmed = append(trimmed, append([]interface{}(nil), row[:n]...))
+		if n > 0 {
+			last = len(trimmed)
+		}
+	}
+	return trimmed[:last]
+}
+
+func fakeSheetsFormatted(grid [][]interface{}) [][]interface{} {
+	formatted := make([][]interface{}, len(grid))
+	for i, row := range grid {
+		formatted[i] = make([]interface{}, len(row))
+		for j, cell := range row {
+			switch v := cell.(type) {
+			case nil:
+				formatted[i][j] = ""
+			case float64:
+				formatted[i][j] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				formatted[i][j] = fmt.Sprint(v)
+			}
+		}
+	}
+	return formatted
+}
+
+func fakeSheetsError(w http.ResponseWriter, code int, message string) {
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"code": code, "message": message}})
+}
@@ -0,0 +1,87 @@
+package services
+
+type OrgSummary struct {
+	OrgID   int    `json:"orgId"`
+	OrgName string `json:"orgName"`
+	Sheet   string `json:"sheet"`
+	// Group is the groupBy value the sheet holds, empty without grouping.
+	Group string `json:"group,omitempty"`
+	Rows  int    `json:"rows"`
+	Error string `json:"error,omitempty"`
+	// Resumed is set when the sheet was already written by a previous attempt.
+	Resumed bool `json:"resumed,omitempty"`
+	// Delta counts changes against the sheet's previous contents when a delta
+	// report was requested; DeltaError explains why it could not be computed.
+	Delta      *DeltaCounts `json:"delta,omitempty"`
+	DeltaError string       `json:"deltaError,omitempty"`
+	// Preview holds the first rows that would have been written, only on dry runs.
+	Preview []map[string]interface{} `json:"preview,omitempty"`
+}
+
+type FetchResult struct {
+	Mode       string `json:"mode"`
+	WriteMode  string `json:"writeMode"`
+	DryRun     bool   `json:"dryRun"`
+	Anonymized bool   `json:"anonymized,omitempty"`
+	// Tenant is the Jacad profile the enrollments came from, empty for the
+	// default instance.
+	Tenant        string `json:"tenant,omitempty"`
+	TotalFetched  int    `json:"totalFetched"`
+	FailedBatches int    `json:"failedBatches"`
+	// FailedPages lists the pages lost after all retries; they can be
+	// refetched with POST /api/v1/jobs/:id/retry-failed-pages.
+	FailedPages []FailedPage `json:"failedPages,omitempty"`
+	// DuplicatesDropped counts enrollments skipped for repeating an idMatricula.
+	DuplicatesDropped int `json:"duplicatesDropped"`
+	// OutsideDateRange counts enrollments dropped by the dataMatricula range.
+	OutsideDateRange int `json:"outsideDateRange,omitempty"`
+	// Rejected counts enrollments that failed a data quality rule; they were
+	// written to RejectsSheet instead of their sheet.
+	Rejected     int    `json:"rejected,omitempty"`
+	RejectsSheet string `json:"rejectsSheet,omitempty"`
+	RejectsError string `json:"rejectsError,omitempty"`
+	// TimeoutSeconds is the effective timeout the job ran with, when known.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// CircuitBreaker is the Jacad circuit breaker state when the job ended
+	// with it not closed.
+	CircuitBreaker string `json:"circuitBreaker,omitempty"`
+	// DeltaSheet is the tab the delta report was written to.
+	DeltaSheet string `json:"deltaSheet,omitempty"`
+	DeltaError string `json:"deltaError,omitempty"`
+	// PrunedSnapshots lists the snapshot tabs deleted for being older than
+	// SNAPSHOT_RETENTION_DAYS.
+	PrunedSnapshots []string `json:"prunedSnapshots,omitempty"`
+	PruneError      string   `json:"pruneError,omitempty"`
+	// Spreadsheet is the spreadsheet a newSpreadsheet or spreadsheetId job
+	// wrote to.
+	Spreadsheet *CreatedSpreadsheet `json:"spreadsheet,omitempty"`
+	// Summary holds the aggregate counts when a summary tab was requested;
+	// SummarySheet is the tab they were written to.
+	Summary       *EnrollmentSummary `json:"summary,omitempty"`
+	SummarySheet  string             `json:"summarySheet,omitempty"`
+	SummaryError  string             `json:"summaryError,omitempty"`
+	Organizations []OrgSummary       `json:"organizations"`
+	// Transfer counts the Jacad bytes the job received and saved.
+	Transfer *TransferStats `json:"transfer,omitempty"`
+	// Destinations reports each writer's outcome when FANOUT_WRITERS is set.
+	Destinations []DestinationOutcome `json:"destinations,omitempty"`
+}
+
+// SheetResult reports a single-sheet write such as the course catalog.
+type SheetResult struct {
+	Sheet  string `json:"sheet"`
+	Rows   int    `json:"rows"`
+	DryRun bool   `json:"dryRun"`
+	// Preview holds the first rows that would have been written, only on dry runs.
+	Preview []map[string]interface{} `json:"preview,omitempty"`
+}
+
+type sheetTarget struct {
+	OrgID   int
+	OrgName string
+	// PeriodName names the fetched periods in sheet names, e.g. "2024/2".
+	PeriodName string
+	Sheet      string
+	// PartitionByOrg keeps only the enrollments whose idOrg matches OrgID.
+	PartitionByOrg bool
+}
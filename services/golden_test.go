@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// update rewrites the golden files under testdata: go test ./services -update
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// assertGolden compares got with testdata/name, or writes it there with
+// -update.
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run go test ./services -update to create it", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s; run go test ./services -update and review the diff\ngot:\n%s", path, got)
+	}
+}
+
+func TestEnrollmentRowMapperGolden(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "enrollments_page.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var page models.APIResponse[models.Enrollment]
+	if err := json.Unmarshal(raw, &page); err != nil {
+		t.Fatal(err)
+	}
+	mapper, err := NewEnrollmentRowMapper(config.Defaults().Columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := NewFakeSheetWriter()
+	if err := writer.OverwriteSheetData(context.Background(), "Matrículas", mapper.Headers(), mapper.Rows(page.Elements)); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "enrollment_rows.golden", writer.Dump())
+}
+
+// TestFetchEnrollmentsGolden records the writer calls of whole fetches
+// against the mock Jacad server. The data fits one page, so rows arrive in a
+// fixed order.
+func TestFetchEnrollmentsGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		params requests.FetchEnrollmentsRequest
+	}{
+		{
+			name:   "fetch_atomic.golden",
+			params: requests.FetchEnrollmentsRequest{OrgIds: "20,17", IdPeriodoLetivo: 87, StatusMatricula: "ATIVA", WriteMode: requests.WriteModeAtomic},
+		},
+		{
+			name:   "fetch_stream.golden",
+			params: requests.FetchEnrollmentsRequest{OrgIds: "20,17", IdPeriodoLetivo: 87, StatusMatricula: "ATIVA", WriteMode: requests.WriteModeStream},
+		},
+		{
+			name:   "fetch_combined_statuses.golden",
+			params: requests.FetchEnrollmentsRequest{OrgIds: "9", IdPeriodoLetivo: 87, StatusMatricula: "ATIVA,TRANCADA", WriteMode: requests.WriteModeAtomic},
+		},
+		{
+			name:   "fetch_split_by_status.golden",
+			params: requests.FetchEnrollmentsRequest{OrgIds: "9", IdPeriodoLetivo: 87, StatusMatricula: "ATIVA,TRANCADA,CANCELADA", SplitByStatus: true, WriteMode: requests.WriteModeStream},
+		},
+		{
+			name:   "fetch_grouped.golden",
+			params: requests.FetchEnrollmentsRequest{OrgIds: "9", IdPeriodoLetivo: 86, StatusesMatricula: []string{"ATIVA", "TRANCADA"}, WriteMode: requests.WriteModeAtomic, GroupBy: requests.GroupByCourse},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(72)})
+			defer server.Close()
+
+			dir := t.TempDir()
+			cfg := config.Defaults()
+			cfg.APIBase = server.URL
+			cfg.UserToken = server.UserToken
+			cfg.PageSize = 100
+			writer := NewFakeSheetWriter()
+			client := NewJacadClient(&cfg, writer, NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+
+			params := tt.params
+			params.Mode = requests.SyncModeFull
+			if params.GroupBy == "" {
+				params.GroupBy = requests.GroupByNone
+			}
+			if _, err := client.FetchEnrollmentsFiltered(context.Background(), &params); err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, tt.name, writer.Dump())
+		})
+	}
+}
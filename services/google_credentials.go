@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+)
+
+// GoogleCredentials says how Google API clients authenticate.
+type GoogleCredentials struct {
+	// Secret, when set, holds the credentials JSON and takes precedence over
+	// every other source; rotated credentials are used from the next token
+	// on.
+	Secret *Secret
+	// Path is a credentials file, used when GOOGLE_CREDENTIALS_JSON_BASE64
+	// and WorkloadIdentityConfig are unset; a missing file falls back to
+	// Application Default Credentials.
+	Path string
+	// WorkloadIdentityConfig is a Workload Identity Federation credential
+	// configuration file, an "external_account" JSON.
+	WorkloadIdentityConfig string
+	// ImpersonateServiceAccount, when set, is the service account the
+	// clients act as; the resolved credentials are only used to mint its
+	// tokens through the IAM credentials API, via ImpersonateDelegates.
+	ImpersonateServiceAccount string
+	ImpersonateDelegates      []string
+}
+
+// googleClientOptions resolves Google credentials in the order secret,
+// GOOGLE_CREDENTIALS_JSON_BASE64, Workload Identity Federation
+// configuration, credentials file, Application Default Credentials, and
+// returns client options for the given scopes along with a description of
+// the source used. With ImpersonateServiceAccount those credentials
+// impersonate that service account, which gets the scopes instead.
+func googleClientOptions(ctx context.Context, creds GoogleCredentials, scopes ...string) ([]option.ClientOption, string, error) {
+	var err error
+	var credentialsJSON []byte
+	var credSourceDescription string
+	logger := logging.FromContext(ctx)
+
+	envCredsBase64 := os.Getenv("GOOGLE_CREDENTIALS_JSON_BASE64")
+	if creds.Secret != nil {
+		logger.Info("Usando as credenciais do Google lidas do provedor de segredos.", "secret", creds.Secret.name)
+		credSourceDescription = fmt.Sprintf("segredo '%s'", creds.Secret.name)
+	} else if envCredsBase64 != "" {
+		logger.Info("Variável de ambiente GOOGLE_CREDENTIALS_JSON_BASE64 encontrada. Usando-a.")
+		credentialsJSON, err = base64.StdEncoding.DecodeString(envCredsBase64)
+		if err != nil {
+			return nil, "", fmt.Errorf("falha ao decodificar GOOGLE_CREDENTIALS_JSON_BASE64: %w", err)
+		}
+		credSourceDescription = "variável de ambiente GOOGLE_CREDENTIALS_JSON_BASE64"
+	} else if creds.WorkloadIdentityConfig != "" {
+		logger.Info("Usando a configuração de Workload Identity Federation.", "path", creds.WorkloadIdentityConfig)
+		credentialsJSON, err = readWorkloadIdentityConfig(creds.WorkloadIdentityConfig)
+		if err != nil {
+			return nil, "", err
+		}
+		credSourceDescription = fmt.Sprintf("Workload Identity Federation ('%s')", creds.WorkloadIdentityConfig)
+	} else if creds.Path != "" {
+		logger.Info("GOOGLE_CREDENTIALS_JSON_BASE64 não definida. Tentando arquivo de credenciais.", "path", creds.Path)
+		credentialsJSON, err = os.ReadFile(creds.Path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				logger.Warn("Arquivo de credenciais não encontrado. Tentará Application Default Credentials.", "path", creds.Path)
+				credentialsJSON = nil
+			} else {
+				return nil, "", fmt.Errorf("falha ao ler arquivo de credenciais '%s': %w", creds.Path, err)
+			}
+		} else {
+			credSourceDescription = fmt.Sprintf("arquivo ('%s')", creds.Path)
+		}
+	} else {
+		logger.Info("Nem GOOGLE_CREDENTIALS_JSON_BASE64 nem CredentialsJSONBase64 fornecidos. Tentando Application Default Credentials.")
+	}
+
+	var baseOpts []option.ClientOption
+	switch {
+	case creds.Secret != nil:
+		if creds.ImpersonateServiceAccount == "" {
+			return []option.ClientOption{secretTokenSource(ctx, creds.Secret, scopes...)}, credSourceDescription, nil
+		}
+		baseOpts = append(baseOpts, secretTokenSource(ctx, creds.Secret, "https://www.googleapis.com/auth/cloud-platform"))
+	case credentialsJSON == nil:
+		credSourceDescription = "Application Default Credentials"
+	default:
+		baseOpts = append(baseOpts, option.WithCredentialsJSON(credentialsJSON))
+	}
+
+	if creds.ImpersonateServiceAccount == "" {
+		return append(baseOpts, option.WithScopes(scopes...)), credSourceDescription, nil
+	}
+
+	logger.Info("Personificando conta de serviço do Google.", "serviceAccount", creds.ImpersonateServiceAccount, "source", credSourceDescription)
+	tokenSource, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: creds.ImpersonateServiceAccount,
+		Scopes:          scopes,
+		Delegates:       creds.ImpersonateDelegates,
+	}, baseOpts...)
+	if err != nil {
+		return nil, "", fmt.Errorf("falha ao personificar a conta de serviço '%s' (fonte: %s): %w", creds.ImpersonateServiceAccount, credSourceDescription, err)
+	}
+	credSourceDescription = fmt.Sprintf("%s, personificando '%s'", credSourceDescription, creds.ImpersonateServiceAccount)
+	return []option.ClientOption{option.WithTokenSource(tokenSource)}, credSourceDescription, nil
+}
+
+// readWorkloadIdentityConfig reads a Workload Identity Federation credential
+// configuration, rejecting files of any other credential type so a service
+// account key is not mistaken for one.
+func readWorkloadIdentityConfig(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler a configuração de Workload Identity Federation '%s': %w", path, err)
+	}
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("configuração de Workload Identity Federation '%s' inválida: %w", path, err)
+	}
+	if header.Type != "external_account" {
+		return nil, fmt.Errorf("a configuração de Workload Identity Federation '%s' deve ter o tipo 'external_account', tem '%s'", path, header.Type)
+	}
+	return data, nil
+}
+
+// secretCredentials serves tokens from the credentials JSON in a Secret,
+// switching to rotated credentials from the next token on.
+type secretCredentials struct {
+	ctx    context.Context
+	secret *Secret
+	scopes []string
+
+	mu      sync.Mutex
+	version int
+	source  oauth2.TokenSource
+}
+
+// secretTokenSource returns a client option authenticating with the
+// credentials JSON in secret, for scopes.
+func secretTokenSource(ctx context.Context, secret *Secret, scopes ...string) option.ClientOption {
+	return option.WithTokenSource(&secretCredentials{ctx: context.WithoutCancel(ctx), secret: secret, scopes: scopes})
+}
+
+func (c *secretCredentials) Token() (*oauth2.Token, error) {
+	value, version := c.secret.Value()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.source == nil || version != c.version {
+		creds, err := google.CredentialsFromJSON(c.ctx, value, c.scopes...)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao configurar as credenciais do segredo '%s': %w", c.secret.name, err)
+		}
+		c.source, c.version = creds.TokenSource, version
+	}
+	return c.source.Token()
+}
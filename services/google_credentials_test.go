@@ -0,0 +1,37 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadWorkloadIdentityConfig(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]struct {
+		content string
+		wantErr bool
+	}{
+		"external account":    {content: `{"type":"external_account","audience":"//iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/p/providers/gke"}`},
+		"service account key": {content: `{"type":"service_account","client_email":"sa@p.iam.gserviceaccount.com"}`, wantErr: true},
+		"not json":            {content: "type: external_account", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			data, err := readWorkloadIdentityConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readWorkloadIdentityConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && string(data) != tt.content {
+				t.Errorf("readWorkloadIdentityConfig() = %s, want the file contents", data)
+			}
+		})
+	}
+	if _, err := readWorkloadIdentityConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("readWorkloadIdentityConfig() of a missing file did not fail")
+	}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// maxSheetNameLength is the Google Sheets limit for tab names.
+const maxSheetNameLength = 100
+
+// sheetGroup is the slice of a target's enrollments that goes to one tab.
+type sheetGroup struct {
+	// OrgID selects the destination spreadsheet; with GroupByOrganization it is
+	// the group's organization instead of the target's.
+	OrgID int
+	Group string
+	Sheet string
+	Data  []models.Enrollment
+}
+
+// groupEnrollments splits data into one tab per groupBy value. With
+// GroupByNone everything goes to the target's own sheet, or to one sheet per
+// status with splitByStatus.
+func (c *JacadClient) groupEnrollments(target sheetTarget, data []models.Enrollment, groupBy string, params *requests.FetchEnrollmentsRequest) []sheetGroup {
+	if groupBy == "" || groupBy == requests.GroupByNone {
+		if params.SplitByStatus {
+			return c.splitByStatus(target, data, params)
+		}
+		return []sheetGroup{{OrgID: target.OrgID, Sheet: target.Sheet, Data: data}}
+	}
+
+	byGroup := make(map[string][]models.Enrollment)
+	for _, item := range data {
+		key := c.groupKey(item, groupBy)
+		byGroup[key] = append(byGroup[key], item)
+	}
+
+	groups := make([]sheetGroup, 0, len(byGroup))
+	for key, items := range byGroup {
+		orgID := target.OrgID
+		if groupBy == requests.GroupByOrganization {
+			orgID = items[0].OrgID
+		}
+		groups = append(groups, sheetGroup{
+			OrgID: orgID,
+			Group: key,
+			Sheet: c.groupSheetName(target, key, params),
+			Data:  items,
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Group < groups[j].Group })
+	return groups
+}
+
+// splitByStatus gives every requested status, and any other status found in
+// data, its own sheet, named as the target's sheet would be had that status
+// been fetched alone. Requested statuses without enrollments still get their
+// sheet, so it is emptied like the combined one would be.
+func (c *JacadClient) splitByStatus(target sheetTarget, data []models.Enrollment, params *requests.FetchEnrollmentsRequest) []sheetGroup {
+	byStatus := make(map[string][]models.Enrollment)
+	for _, status := range params.Statuses() {
+		byStatus[status] = nil
+	}
+	for _, item := range data {
+		status := c.groupKey(item, requests.GroupByStatus)
+		byStatus[status] = append(byStatus[status], item)
+	}
+
+	groups := make([]sheetGroup, 0, len(byStatus))
+	for status, items := range byStatus {
+		single := *params
+		single.StatusMatricula, single.StatusesMatricula = status, nil
+		groups = append(groups, sheetGroup{
+			OrgID: target.OrgID,
+			Group: status,
+			Sheet: c.determineSheetName(target.OrgID, target.PeriodName, &single),
+			Data:  items,
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Group < groups[j].Group })
+	return groups
+}
+
+func (c *JacadClient) groupKey(item models.Enrollment, groupBy string) string {
+	switch groupBy {
+	case requests.GroupByOrganization:
+		if name := config.GetOrganizationNameByID(item.OrgID); name != "" {
+			return name
+		}
+		return c.Config.DefaultOrgSheet
+	case requests.GroupByCourse:
+		if item.Curso != nil && strings.TrimSpace(*item.Curso) != "" {
+			return strings.TrimSpace(*item.Curso)
+		}
+		return "Sem curso"
+	case requests.GroupByStatus:
+		if item.Status != nil && strings.TrimSpace(*item.Status) != "" {
+			return strings.TrimSpace(*item.Status)
+		}
+		return "Sem status"
+	}
+	return ""
+}
+
+// groupSheetName renders Config.GroupSheetNameTemplate.
+func (c *JacadClient) groupSheetName(target sheetTarget, group string, params *requests.FetchEnrollmentsRequest) string {
+	orgName := target.OrgName
+	if orgName == "" {
+		orgName = c.Config.DefaultOrgSheet
+	}
+	tmpl := params.SheetName
+	if tmpl == "" {
+		tmpl = c.Config.GroupSheetNameTemplate
+	}
+	return snapshotName(renderSheetName(tmpl, sheetNameData(orgName, group, target.PeriodName, params)), params)
+}
+
+// sheetNameData fills the sheet name template variables of an enrollment
+// fetch.
+func sheetNameData(orgName, group, periodName string, params *requests.FetchEnrollmentsRequest) config.SheetNameData {
+	return config.SheetNameData{
+		Org:        orgName,
+		Status:     params.StatusLabel(),
+		PeriodID:   params.PeriodLabel(),
+		PeriodName: periodName,
+		Date:       time.Now().Format("2006-01-02"),
+		Group:      group,
+	}
+}
+
+// renderSheetName renders tmpl, falling back to the default template when it
+// fails, since the configured templates were checked at startup and only an
+// organization list reloaded since can be invalid.
+func renderSheetName(tmpl string, data config.SheetNameData) string {
+	name, err := config.RenderSheetName(tmpl, data)
+	if err != nil {
+		slog.Warn("Invalid sheet name template, using the default", "template", tmpl, "error", err)
+		name, _ = config.RenderSheetName(config.Defaults().SheetNameTemplate, data)
+	}
+	return truncateRunes(name, maxSheetNameLength)
+}
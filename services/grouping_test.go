@@ -0,0 +1,87 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+func TestGroupEnrollments(t *testing.T) {
+	cfg := config.Defaults()
+	client := NewJacadClient(&cfg, NewDiscardWriter(), nil, nil, nil)
+	data := []models.Enrollment{
+		{IdMatricula: 1, OrgID: 20, Curso: ptr("Direito"), Status: ptr("ATIVA")},
+		{IdMatricula: 2, OrgID: 17, Curso: ptr(" Direito "), Status: ptr("TRANCADA")},
+		{IdMatricula: 3, OrgID: 99, Status: ptr("ATIVA")},
+		{IdMatricula: 4, OrgID: 20, Curso: ptr("")},
+	}
+	target := sheetTarget{OrgID: 0, OrgName: "Presencial", PeriodName: "2025/1"}
+	params := &requests.FetchEnrollmentsRequest{StatusMatricula: "ATIVA"}
+
+	type group struct {
+		orgID int
+		sheet string
+		ids   []int
+	}
+	tests := []struct {
+		groupBy string
+		want    []group
+	}{
+		{groupBy: requests.GroupByNone, want: []group{
+			{0, "", []int{1, 2, 3, 4}},
+		}},
+		{groupBy: requests.GroupByOrganization, want: []group{
+			{20, "Matrículas EAD STATUS: ATIVA | 2025/1", []int{1, 4}},
+			{99, "Matrículas Outras Matrículas STATUS: ATIVA | 2025/1", []int{3}},
+			{17, "Matrículas PÓS EAD STATUS: ATIVA | 2025/1", []int{2}},
+		}},
+		{groupBy: requests.GroupByCourse, want: []group{
+			{0, "Matrículas Direito STATUS: ATIVA | 2025/1", []int{1, 2}},
+			{0, "Matrículas Sem curso STATUS: ATIVA | 2025/1", []int{3, 4}},
+		}},
+		{groupBy: requests.GroupByStatus, want: []group{
+			{0, "Matrículas ATIVA STATUS: ATIVA | 2025/1", []int{1, 3}},
+			{0, "Matrículas Sem status STATUS: ATIVA | 2025/1", []int{4}},
+			{0, "Matrículas TRANCADA STATUS: ATIVA | 2025/1", []int{2}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.groupBy, func(t *testing.T) {
+			groups := client.groupEnrollments(target, data, tt.groupBy, params)
+			if len(groups) != len(tt.want) {
+				t.Fatalf("got %d groups, want %d: %+v", len(groups), len(tt.want), groups)
+			}
+			for i, g := range groups {
+				var ids []int
+				for _, item := range g.Data {
+					ids = append(ids, item.IdMatricula)
+				}
+				want := tt.want[i]
+				if g.OrgID != want.orgID || g.Sheet != want.sheet || !slices.Equal(ids, want.ids) {
+					t.Errorf("group %d = org %d, sheet %q, ids %v; want org %d, sheet %q, ids %v", i, g.OrgID, g.Sheet, ids, want.orgID, want.sheet, want.ids)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupSheetNameTemplate(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.GroupSheetNameTemplate = "{{.Org}} - {{.Group}}"
+	client := NewJacadClient(&cfg, NewDiscardWriter(), nil, nil, nil)
+	target := sheetTarget{OrgID: 20, OrgName: "EAD"}
+	data := []models.Enrollment{{IdMatricula: 1, OrgID: 20, Curso: ptr("Direito")}}
+
+	groups := client.groupEnrollments(target, data, requests.GroupByCourse, &requests.FetchEnrollmentsRequest{})
+	if len(groups) != 1 || groups[0].Sheet != "EAD - Direito" {
+		t.Errorf("groups = %+v, want the configured template", groups)
+	}
+
+	groups = client.groupEnrollments(target, data, requests.GroupByCourse, &requests.FetchEnrollmentsRequest{SheetName: "{{.Group}}"})
+	if len(groups) != 1 || groups[0].Sheet != "Direito" {
+		t.Errorf("groups = %+v, want the request's sheetName", groups)
+	}
+}
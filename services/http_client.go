@@ -0,0 +1,58 @@
+package services
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+// NewJacadHTTPClient builds the HTTP client for Jacad requests from the
+// JACAD_HTTP_* settings. rootCAs, when set, replaces the system CA pool; see
+// LoadCABundle.
+func NewJacadHTTPClient(cfg *config.Config, rootCAs *x509.CertPool) *http.Client {
+	perHost := cfg.JacadHTTPMaxIdleConnsPerHost
+	if perHost <= 0 {
+		perHost = max(cfg.MaxParallelRequests, 1)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: cfg.JacadHTTPDialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = cfg.JacadHTTPTLSHandshakeTimeout
+	transport.IdleConnTimeout = cfg.JacadHTTPIdleConnTimeout
+	transport.MaxIdleConnsPerHost = perHost
+	transport.MaxIdleConns = max(transport.MaxIdleConns, perHost)
+	if cfg.JacadHTTPProxy != "" {
+		// The URL was checked by config validation.
+		if proxy, err := url.Parse(cfg.JacadHTTPProxy); err == nil {
+			transport.Proxy = http.ProxyURL(proxy)
+		}
+	}
+	if rootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+	}
+	return &http.Client{Timeout: cfg.JacadHTTPTimeout, Transport: transport}
+}
+
+// LoadCABundle returns the system CA pool with the PEM certificates of path
+// added.
+func LoadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("CA bundle '%s' holds no PEM certificate", path)
+	}
+	return pool, nil
+}
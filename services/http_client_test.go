@@ -0,0 +1,48 @@
+package services
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+func TestNewJacadHTTPClient(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.MaxParallelRequests = 16
+	cfg.JacadHTTPProxy = "http://proxy.internal:3128"
+
+	client := NewJacadHTTPClient(&cfg, nil)
+	transport := client.Transport.(*http.Transport)
+	if client.Timeout != time.Minute {
+		t.Errorf("Timeout = %v, want 1m", client.Timeout)
+	}
+	if transport.MaxIdleConnsPerHost != 16 {
+		t.Errorf("MaxIdleConnsPerHost = %d, want MAX_PARALLEL_REQUESTS (16)", transport.MaxIdleConnsPerHost)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://jacad.example/api", nil)
+	if proxy, err := transport.Proxy(req); err != nil || proxy.String() != cfg.JacadHTTPProxy {
+		t.Errorf("Proxy = %v (%v), want %s", proxy, err, cfg.JacadHTTPProxy)
+	}
+
+	cfg.JacadHTTPMaxIdleConnsPerHost = 4
+	if got := NewJacadHTTPClient(&cfg, nil).Transport.(*http.Transport).MaxIdleConnsPerHost; got != 4 {
+		t.Errorf("MaxIdleConnsPerHost = %d, want 4", got)
+	}
+}
+
+func TestLoadCABundleRejectsNonPEM(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCABundle(path); err == nil {
+		t.Error("LoadCABundle accepted a file without certificates")
+	}
+	if _, err := LoadCABundle(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("LoadCABundle accepted a missing file")
+	}
+}
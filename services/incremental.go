@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// sheetChanges is what an incremental run has to write to bring a sheet in
+// line with the fetched enrollments.
+type sheetChanges struct {
+	// Changed holds the enrollments that are new or whose mapped row
+	// differs from the sheet's.
+	Changed []models.Enrollment
+	// Removed counts the sheet rows whose idMatricula was not fetched,
+	// e.g. enrollments that left a status-filtered query.
+	Removed int
+	// Relayout is set when the sheet's header row differs from the column
+	// mapping, so upserted rows would land under the wrong headers.
+	Relayout bool
+}
+
+// incrementalChanges works out what an incremental run writes to sheetName.
+// When the writer can read the sheet back, data is diffed against it on
+// idMatricula, which catches status and other field changes. Otherwise the
+// enrollments registered since the state's watermark are taken as changed;
+// that misses changes to older enrollments and never reports removals.
+func (c *JacadClient) incrementalChanges(ctx context.Context, logger *slog.Logger, sheetName string, mapper *EnrollmentRowMapper, data []models.Enrollment, state *SyncState) (sheetChanges, error) {
+	changes, err := c.diffEnrollments(ctx, sheetName, mapper, data)
+	if errors.Is(err, errors.ErrUnsupported) {
+		logger.Info("Writer cannot read the sheet back. Upserting the enrollments registered since the last watermark.")
+		return sheetChanges{Changed: filterEnrollmentsSince(data, state)}, nil
+	}
+	return changes, err
+}
+
+// diffEnrollments compares the rows data maps to with the rows sheetName
+// holds, keyed on idMatricula. It fails with errors.ErrUnsupported when the
+// writer cannot read sheets back.
+func (c *JacadClient) diffEnrollments(ctx context.Context, sheetName string, mapper *EnrollmentRowMapper, data []models.Enrollment) (sheetChanges, error) {
+	reader, ok := c.Writer.(SheetReader)
+	if !ok {
+		return sheetChanges{}, fmt.Errorf("writer %T cannot read sheets back: %w", c.Writer, errors.ErrUnsupported)
+	}
+	keyHeader, ok := mapper.HeaderFor("idMatricula")
+	if !ok {
+		return sheetChanges{}, fmt.Errorf("incremental mode requires the idMatricula column in the column mapping")
+	}
+	existing, err := reader.ReadRows(ctx, sheetName)
+	if err != nil {
+		return sheetChanges{}, fmt.Errorf("failed to read sheet '%s' to find changed enrollments: %w", sheetName, err)
+	}
+	if len(existing) == 0 {
+		return sheetChanges{Changed: data}, nil
+	}
+
+	headers := mapper.Headers()
+	columns := make([]int, len(headers))
+	sheetHeaders := make(map[string]int, len(existing[0]))
+	for i, h := range existing[0] {
+		sheetHeaders[fmt.Sprint(h)] = i
+	}
+	for i, h := range headers {
+		column, ok := sheetHeaders[h]
+		if !ok || len(existing[0]) != len(headers) {
+			return sheetChanges{Changed: data, Relayout: true}, nil
+		}
+		columns[i] = column
+	}
+	keyColumn := sheetHeaders[keyHeader]
+
+	previous := make(map[string][]interface{}, len(existing)-1)
+	for _, row := range existing[1:] {
+		if key := deltaValue(cellAt(row, keyColumn)); key != "" {
+			previous[key] = row
+		}
+	}
+
+	var changes sheetChanges
+	fetched := make(map[string]bool, len(data))
+	keyIndex := columnIndex(headers, keyHeader)
+	for _, item := range data {
+		row := mapper.Row(item)
+		key := deltaValue(row[keyIndex])
+		fetched[key] = true
+		old, found := previous[key]
+		if !found || !sameRow(row, old, columns) {
+			changes.Changed = append(changes.Changed, item)
+		}
+	}
+	for key := range previous {
+		if !fetched[key] {
+			changes.Removed++
+		}
+	}
+	return changes, nil
+}
+
+func columnIndex(headers []string, header string) int {
+	for i, h := range headers {
+		if h == header {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameRow reports whether the freshly mapped row matches the sheet row,
+// whose cells are at columns.
+func sameRow(row, sheetRow []interface{}, columns []int) bool {
+	for i, cell := range row {
+		if !sameCell(cell, cellAt(sheetRow, columns[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameCell compares a mapped cell with one read back unformatted, where
+// dates written as text come back as serial numbers.
+func sameCell(cell, sheetCell interface{}) bool {
+	t, ok := cell.(time.Time)
+	if !ok {
+		return deltaValue(cell) == deltaValue(sheetCell)
+	}
+	switch v := sheetCell.(type) {
+	case float64:
+		return math.Abs(float64(serialDate(t))-v) < 1e-6
+	case string:
+		read, ok := cellTime(v, time.DateOnly)
+		return ok && read.Equal(t)
+	}
+	return false
+}
+
+// syncStateHold returns why this run must keep the previous sync state, or
+// "" when the whole dataset was fetched and its watermark can be saved.
+// Saving a watermark past enrollments on pages that failed to fetch would
+// make later incremental runs skip them for good, and a page range would mark
+// the sheet synced with only a slice of the data.
+func syncStateHold(failed pageFailures, params *requests.FetchEnrollmentsRequest) string {
+	if params.PartialPages() {
+		start, end := params.PageRange()
+		if end == 0 {
+			return fmt.Sprintf("only pages from %d on were requested", start)
+		}
+		return fmt.Sprintf("only pages %d to %d were requested", start, end-1)
+	}
+	if failed.batches > 0 || len(failed.pages) > 0 {
+		return fmt.Sprintf("%d page(s) in %d batch(es) failed to fetch", len(failed.pages), failed.batches)
+	}
+	return ""
+}
+
+// saveSyncState records state for sheetName in the spreadsheet of ctx unless
+// hold gives a reason to keep the previous one.
+func (c *JacadClient) saveSyncState(ctx context.Context, logger *slog.Logger, sheetName string, state SyncState, hold string) error {
+	if hold != "" {
+		logger.Warn("Keeping the previous sync state of the sheet", "sheet", sheetName, "reason", hold)
+		return nil
+	}
+	return c.State.Save(c.syncStateKey(ctx, sheetName), state)
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+func incrementalTestMapper(t *testing.T) *EnrollmentRowMapper {
+	t.Helper()
+	mapper, err := NewEnrollmentRowMapper([]config.Column{{Field: "idMatricula", Header: "ID"}, {Field: "aluno", Header: "Nome"}, {Field: "status", Header: "Status"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mapper
+}
+
+func incrementalTestData() []models.Enrollment {
+	return []models.Enrollment{
+		{IdMatricula: 1, Aluno: ptr("Ana"), Status: ptr("Ativo")},
+		{IdMatricula: 2, Aluno: ptr("Bruno"), Status: ptr("Trancado")},
+		{IdMatricula: 3, Aluno: ptr("Carla"), Status: ptr("Ativo")},
+	}
+}
+
+func TestDiffEnrollments(t *testing.T) {
+	tests := []struct {
+		name         string
+		sheet        [][]interface{}
+		wantChanged  []int
+		wantRemoved  int
+		wantRelayout bool
+	}{
+		{name: "empty sheet", wantChanged: []int{1, 2, 3}},
+		{
+			name:        "changed, new and removed rows",
+			sheet:       [][]interface{}{{"ID", "Nome", "Status"}, {1.0, "Ana", "Ativo"}, {2.0, "Bruno", "Ativo"}, {4.0, "Davi", "Ativo"}},
+			wantChanged: []int{2, 3},
+			wantRemoved: 1,
+		},
+		{
+			name:        "columns in another order",
+			sheet:       [][]interface{}{{"Status", "ID", "Nome"}, {"Ativo", 1.0, "Ana"}, {"Trancado", 2.0, "Bruno"}, {"Ativo", 3.0, "Carla"}},
+			wantChanged: nil,
+		},
+		{
+			name:         "headers differ from the mapping",
+			sheet:        [][]interface{}{{"ID", "Nome"}, {1.0, "Ana"}},
+			wantChanged:  []int{1, 2, 3},
+			wantRelayout: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeSheetsAPI()
+			api.SetTab("book", "Alunos", tt.sheet)
+			c := &JacadClient{Config: &config.Config{SpreadsheetID: "book"}, Writer: newFakeSheetsWriter(t, api)}
+
+			changes, err := c.diffEnrollments(context.Background(), "Alunos", incrementalTestMapper(t), incrementalTestData())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var changed []int
+			for _, item := range changes.Changed {
+				changed = append(changed, item.IdMatricula)
+			}
+			if !reflect.DeepEqual(changed, tt.wantChanged) || changes.Removed != tt.wantRemoved || changes.Relayout != tt.wantRelayout {
+				t.Errorf("diffEnrollments() = changed %v, removed %d, relayout %v; want %v, %d, %v", changed, changes.Removed, changes.Relayout, tt.wantChanged, tt.wantRemoved, tt.wantRelayout)
+			}
+		})
+	}
+}
+
+func TestWriteEnrollmentsToTargetIncremental(t *testing.T) {
+	previous := SyncState{LastDataCadastro: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	sheet := [][]interface{}{{"ID", "Nome", "Status"}, {1.0, "Ana", "Ativo"}, {2.0, "Bruno", "Ativo"}, {4.0, "Davi", "Ativo"}}
+	tests := []struct {
+		name      string
+		hold      string
+		wantRows  int
+		wantSheet [][]interface{}
+		wantSaved bool
+	}{
+		{
+			name:      "complete fetch rewrites removed rows away",
+			wantRows:  3,
+			wantSheet: [][]interface{}{{"ID", "Nome", "Status"}, {1.0, "Ana", "Ativo"}, {2.0, "Bruno", "Trancado"}, {3.0, "Carla", "Ativo"}},
+			wantSaved: true,
+		},
+		{
+			name:      "failed pages only upsert and keep the state",
+			hold:      "1 page(s) in 1 batch(es) failed to fetch",
+			wantRows:  2,
+			wantSheet: [][]interface{}{{"ID", "Nome", "Status"}, {1.0, "Ana", "Ativo"}, {2.0, "Bruno", "Trancado"}, {4.0, "Davi", "Ativo"}, {3.0, "Carla", "Ativo"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeSheetsAPI()
+			api.SetTab("book", "Alunos", sheet)
+			state := NewFileSyncStateStore(filepath.Join(t.TempDir(), "sync_state.json"))
+			if err := state.Save("book!Alunos", previous); err != nil {
+				t.Fatal(err)
+			}
+			c := &JacadClient{Config: &config.Config{SpreadsheetID: "book"}, Writer: newFakeSheetsWriter(t, api), State: state}
+
+			rows, err := c.writeEnrollmentsToTarget(context.Background(), requests.SyncModeIncremental, "Alunos", incrementalTestMapper(t), incrementalTestData(), tt.hold)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rows != tt.wantRows {
+				t.Errorf("rows = %d, want %d", rows, tt.wantRows)
+			}
+			if got, _ := api.Tab("book", "Alunos"); !reflect.DeepEqual(got, tt.wantSheet) {
+				t.Errorf("sheet = %v, want %v", got, tt.wantSheet)
+			}
+			saved, err := state.Load("book!Alunos")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := !saved.UpdatedAt.IsZero(); got != tt.wantSaved {
+				t.Errorf("sync state saved = %v, want %v", got, tt.wantSaved)
+			}
+		})
+	}
+}
+
+func TestSyncStateHold(t *testing.T) {
+	params := &requests.FetchEnrollmentsRequest{}
+	if hold := syncStateHold(pageFailures{}, params); hold != "" {
+		t.Errorf("syncStateHold() with no failures = %q, want \"\"", hold)
+	}
+	if hold := syncStateHold(pageFailures{batches: 1, pages: []FailedPage{{Page: 3}}}, params); hold == "" {
+		t.Error("syncStateHold() with a failed page = \"\", want a reason")
+	}
+}
+
+func TestSyncStateHoldPartialPages(t *testing.T) {
+	for _, params := range []*requests.FetchEnrollmentsRequest{{StartPage: 2}, {EndPage: 5}, {MaxPages: 3}} {
+		if hold := syncStateHold(pageFailures{}, params); hold == "" {
+			t.Errorf("syncStateHold(%+v) = \"\", want a reason", *params)
+		}
+	}
+}
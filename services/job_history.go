@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/notifications"
+)
+
+// JobStatusPartial marks jobs that finished with some sheets or batches failed.
+const JobStatusPartial = "partial"
+
+// JobRecord is one finished job in the history.
+type JobRecord struct {
+	ID  string `json:"id"`
+	Job string `json:"job"`
+	// Params holds the request parameters that were set, keyed by their query
+	// name.
+	Params      map[string]interface{} `json:"params,omitempty"`
+	Tenant      string                 `json:"tenant,omitempty"`
+	Status      string                 `json:"status" doc:"success, partial, failed or cancelled."`
+	StartedAt   time.Time              `json:"startedAt"`
+	FinishedAt  time.Time              `json:"finishedAt"`
+	RowsFetched int                    `json:"rowsFetched"`
+	RowsWritten int                    `json:"rowsWritten"`
+	// Sheets lists the sheets the job wrote.
+	Sheets   []string `json:"sheets,omitempty"`
+	Failures []string `json:"failures,omitempty"`
+	// FailedPages lists the pages still missing from the job's sheets.
+	FailedPages []FailedPage `json:"failedPages,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	// Attempt numbers the runs of a job retried after failing; RetryOf is
+	// the ID of its first attempt, set from the second one on.
+	Attempt int    `json:"attempt,omitempty"`
+	RetryOf string `json:"retryOf,omitempty"`
+}
+
+// JobHistoryFilter selects records for List. Zero fields match everything.
+type JobHistoryFilter struct {
+	Since time.Time
+	Job   string
+	Sheet string
+	Limit int
+}
+
+// JobHistoryStore persists finished jobs. Recording an ID again replaces the
+// earlier record. List returns the newest first; Find returns nil for unknown
+// IDs.
+type JobHistoryStore interface {
+	Record(record JobRecord) error
+	List(filter JobHistoryFilter) ([]JobRecord, error)
+	Find(id string) (*JobRecord, error)
+}
+
+// recordJob adds a finished job to c.History. Failures are only logged.
+func (c *JacadClient) recordJob(ctx context.Context, summary notifications.Summary, params interface{}, sheets []string, failedPages []FailedPage) {
+	if c.History == nil {
+		return
+	}
+	record := JobRecord{
+		ID:          summary.JobID,
+		Job:         summary.Job,
+		Params:      jobParams(params),
+		Tenant:      summary.Tenant,
+		Status:      jobStatus(summary),
+		StartedAt:   summary.StartedAt,
+		FinishedAt:  summary.StartedAt.Add(summary.Duration),
+		RowsFetched: summary.RowsFetched,
+		RowsWritten: summary.RowsWritten,
+		Sheets:      sheets,
+		Failures:    summary.Failures,
+		FailedPages: failedPages,
+		Error:       summary.Error,
+	}
+	if record.ID == "" {
+		record.ID = localJobID
+	}
+	if attempt, ok := jobs.AttemptFrom(ctx); ok {
+		record.Attempt = attempt.Number
+		if attempt.FirstID != record.ID {
+			record.RetryOf = attempt.FirstID
+		}
+	}
+	if err := c.History.Record(record); err != nil {
+		logging.FromContext(ctx).Warn("Failed to record job in history", "job", summary.Job, "error", err)
+	}
+}
+
+// jobStatus is the summary's status, or JobStatusPartial for a successful
+// job that reported failures.
+func jobStatus(summary notifications.Summary) string {
+	if summary.Status == notifications.StatusSuccess && len(summary.Failures) > 0 {
+		return JobStatusPartial
+	}
+	return summary.Status
+}
+
+// jobParams lists the non-zero fields of a request struct under their query
+// name, or JSON name for body-only fields.
+func jobParams(params interface{}) map[string]interface{} {
+	v := reflect.Indirect(reflect.ValueOf(params))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	out := make(map[string]interface{})
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := field.Tag.Get("query")
+		if name == "" {
+			name, _, _ = strings.Cut(field.Tag.Get("json"), ",")
+		}
+		if name == "" || name == "-" || v.Field(i).IsZero() {
+			continue
+		}
+		out[name] = v.Field(i).Interface()
+	}
+	return out
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const jobHistorySchema = `
+CREATE TABLE IF NOT EXISTS job_history (
+	id           TEXT PRIMARY KEY,
+	job          TEXT NOT NULL,
+	tenant       TEXT NOT NULL DEFAULT '',
+	status       TEXT NOT NULL,
+	started_at   INTEGER NOT NULL,
+	finished_at  INTEGER NOT NULL,
+	rows_fetched INTEGER NOT NULL DEFAULT 0,
+	rows_written INTEGER NOT NULL DEFAULT 0,
+	params       TEXT,
+	failures     TEXT,
+	failed_pages TEXT,
+	error        TEXT NOT NULL DEFAULT '',
+	attempt      INTEGER NOT NULL DEFAULT 0,
+	retry_of     TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS job_history_started_at ON job_history (started_at);
+CREATE INDEX IF NOT EXISTS job_history_job ON job_history (job, started_at);
+CREATE TABLE IF NOT EXISTS job_history_sheets (
+	job_id   TEXT NOT NULL REFERENCES job_history (id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	sheet    TEXT NOT NULL,
+	PRIMARY KEY (job_id, position)
+);
+CREATE INDEX IF NOT EXISTS job_history_sheets_sheet ON job_history_sheets (sheet);
+`
+
+const jobHistoryColumns = `id, job, tenant, status, started_at, finished_at, rows_fetched, rows_written, params, failures, failed_pages, error, attempt, retry_of`
+
+// jobHistoryAddedColumns are the job_history columns added after its first
+// release, created in existing databases when missing.
+var jobHistoryAddedColumns = []struct{ name, definition string }{
+	{"attempt", "INTEGER NOT NULL DEFAULT 0"},
+	{"retry_of", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// SQLiteJobHistoryStore keeps finished jobs in a SQLite database. Records are
+// indexed by start time, job and sheet, so List and Find do not read the whole
+// history, and SQLite serializes writers from this and other processes.
+type SQLiteJobHistoryStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteJobHistoryStore opens or creates the database at path.
+func NewSQLiteJobHistoryStore(path string) (*SQLiteJobHistoryStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create job history directory '%s': %w", dir, err)
+		}
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job history database '%s': %w", path, err)
+	}
+	if _, err := db.Exec(jobHistorySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create job history tables in '%s': %w", path, err)
+	}
+	if err := addJobHistoryColumns(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to upgrade job history tables in '%s': %w", path, err)
+	}
+	return &SQLiteJobHistoryStore{db: db}, nil
+}
+
+// addJobHistoryColumns adds the jobHistoryAddedColumns missing from
+// job_history.
+func addJobHistoryColumns(db *sql.DB) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('job_history')`)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, column := range jobHistoryAddedColumns {
+		if existing[column.name] {
+			continue
+		}
+		if _, err := db.Exec(`ALTER TABLE job_history ADD COLUMN ` + column.name + ` ` + column.definition); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", column.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteJobHistoryStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteJobHistoryStore) Record(record JobRecord) error {
+	params, err := jsonColumn(record.Params)
+	if err != nil {
+		return fmt.Errorf("failed to encode job params: %w", err)
+	}
+	failures, err := jsonColumn(record.Failures)
+	if err != nil {
+		return fmt.Errorf("failed to encode job failures: %w", err)
+	}
+	failedPages, err := jsonColumn(record.FailedPages)
+	if err != nil {
+		return fmt.Errorf("failed to encode job failed pages: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start job history transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO job_history (`+jobHistoryColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			job = excluded.job, tenant = excluded.tenant, status = excluded.status,
+			started_at = excluded.started_at, finished_at = excluded.finished_at,
+			rows_fetched = excluded.rows_fetched, rows_written = excluded.rows_written,
+			params = excluded.params, failures = excluded.failures,
+			failed_pages = excluded.failed_pages, error = excluded.error,
+			attempt = excluded.attempt, retry_of = excluded.retry_of`,
+		record.ID, record.Job, record.Tenant, record.Status,
+		record.StartedAt.UnixNano(), record.FinishedAt.UnixNano(),
+		record.RowsFetched, record.RowsWritten, params, failures, failedPages, record.Error,
+		record.Attempt, record.RetryOf)
+	if err != nil {
+		return fmt.Errorf("failed to record job '%s': %w", record.ID, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM job_history_sheets WHERE job_id = ?`, record.ID); err != nil {
+		return fmt.Errorf("failed to replace sheets of job '%s': %w", record.ID, err)
+	}
+	for i, sheet := range record.Sheets {
+		if _, err := tx.Exec(`INSERT INTO job_history_sheets (job_id, position, sheet) VALUES (?, ?, ?)`, record.ID, i, sheet); err != nil {
+			return fmt.Errorf("failed to record sheets of job '%s': %w", record.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit job '%s': %w", record.ID, err)
+	}
+	return nil
+}
+
+func (s *SQLiteJobHistoryStore) List(filter JobHistoryFilter) ([]JobRecord, error) {
+	var where []string
+	var args []interface{}
+	if !filter.Since.IsZero() {
+		where = append(where, "started_at >= ?")
+		args = append(args, filter.Since.UnixNano())
+	}
+	if filter.Job != "" {
+		where = append(where, "job = ?")
+		args = append(args, filter.Job)
+	}
+	if filter.Sheet != "" {
+		where = append(where, "id IN (SELECT job_id FROM job_history_sheets WHERE sheet = ?)")
+		args = append(args, filter.Sheet)
+	}
+
+	query := `SELECT ` + jobHistoryColumns + ` FROM job_history`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY started_at DESC, id"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job history: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]JobRecord, 0)
+	for rows.Next() {
+		record, err := scanJobRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list job history: %w", err)
+	}
+	for i := range records {
+		if records[i].Sheets, err = s.sheets(records[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+func (s *SQLiteJobHistoryStore) Find(id string) (*JobRecord, error) {
+	row := s.db.QueryRow(`SELECT `+jobHistoryColumns+` FROM job_history WHERE id = ?`, id)
+	record, err := scanJobRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.Sheets, err = s.sheets(id); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *SQLiteJobHistoryStore) sheets(id string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT sheet FROM job_history_sheets WHERE job_id = ? ORDER BY position`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheets of job '%s': %w", id, err)
+	}
+	defer rows.Close()
+
+	var sheets []string
+	for rows.Next() {
+		var sheet string
+		if err := rows.Scan(&sheet); err != nil {
+			return nil, fmt.Errorf("failed to read sheets of job '%s': %w", id, err)
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets, rows.Err()
+}
+
+func scanJobRecord(row interface{ Scan(...interface{}) error }) (JobRecord, error) {
+	var record JobRecord
+	var startedAt, finishedAt int64
+	var params, failures, failedPages sql.NullString
+	err := row.Scan(&record.ID, &record.Job, &record.Tenant, &record.Status, &startedAt, &finishedAt,
+		&record.RowsFetched, &record.RowsWritten, &params, &failures, &failedPages, &record.Error,
+		&record.Attempt, &record.RetryOf)
+	if errors.Is(err, sql.ErrNoRows) {
+		return record, err
+	}
+	if err != nil {
+		return record, fmt.Errorf("failed to read job record: %w", err)
+	}
+	record.StartedAt, record.FinishedAt = time.Unix(0, startedAt), time.Unix(0, finishedAt)
+	for _, column := range []struct {
+		raw  sql.NullString
+		dest interface{}
+	}{{params, &record.Params}, {failures, &record.Failures}, {failedPages, &record.FailedPages}} {
+		if !column.raw.Valid {
+			continue
+		}
+		if err := json.Unmarshal([]byte(column.raw.String), column.dest); err != nil {
+			return record, fmt.Errorf("failed to decode job record '%s': %w", record.ID, err)
+		}
+	}
+	return record, nil
+}
+
+// jsonColumn encodes value for a TEXT column, storing NULL for empty values.
+func jsonColumn(value interface{}) (sql.NullString, error) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || v.Len() == 0 {
+		return sql.NullString{}, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(raw), Valid: true}, nil
+}
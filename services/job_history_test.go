@@ -0,0 +1,174 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestJobHistory(t *testing.T) *SQLiteJobHistoryStore {
+	t.Helper()
+	store, err := NewSQLiteJobHistoryStore(filepath.Join(t.TempDir(), "history", "jobs.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteJobHistoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLiteJobHistoryStoreList(t *testing.T) {
+	store := newTestJobHistory(t)
+	base := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	records := []JobRecord{
+		{ID: "a", Job: "enrollments", Status: "success", StartedAt: base, Sheets: []string{"EAD", "POS"}},
+		{ID: "b", Job: "enrollments", Status: "failed", StartedAt: base.Add(time.Hour), Sheets: []string{"POS"}},
+		{ID: "c", Job: "students", Status: "success", StartedAt: base.Add(2 * time.Hour), Sheets: []string{"EAD"}},
+	}
+	for _, record := range records {
+		if err := store.Record(record); err != nil {
+			t.Fatalf("Record(%s): %v", record.ID, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter JobHistoryFilter
+		want   []string
+	}{
+		{name: "newest first", want: []string{"c", "b", "a"}},
+		{name: "since", filter: JobHistoryFilter{Since: base.Add(time.Hour)}, want: []string{"c", "b"}},
+		{name: "job", filter: JobHistoryFilter{Job: "enrollments"}, want: []string{"b", "a"}},
+		{name: "sheet", filter: JobHistoryFilter{Sheet: "EAD"}, want: []string{"c", "a"}},
+		{name: "job and sheet", filter: JobHistoryFilter{Job: "enrollments", Sheet: "POS"}, want: []string{"b", "a"}},
+		{name: "limit", filter: JobHistoryFilter{Limit: 1}, want: []string{"c"}},
+		{name: "no match", filter: JobHistoryFilter{Sheet: "Outro"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			ids := make([]string, 0, len(got))
+			for _, record := range got {
+				ids = append(ids, record.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("List ids = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLiteJobHistoryStoreFindReplaces(t *testing.T) {
+	store := newTestJobHistory(t)
+	started := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	first := JobRecord{ID: "job-1", Job: "enrollments", Status: "failed", StartedAt: started, Sheets: []string{"EAD", "POS"}, Error: "boom"}
+	if err := store.Record(first); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	second := JobRecord{
+		ID:          "job-1",
+		Job:         "enrollments",
+		Params:      map[string]interface{}{"idPeriodoLetivo": float64(20)},
+		Status:      JobStatusPartial,
+		StartedAt:   started,
+		FinishedAt:  started.Add(time.Minute),
+		RowsFetched: 10,
+		RowsWritten: 8,
+		Sheets:      []string{"POS"},
+		Failures:    []string{"EAD: quota"},
+		FailedPages: []FailedPage{{Query: map[string]string{"orgIds": "20"}, PageSize: 100, Page: 3}},
+		Attempt:     2,
+		RetryOf:     "job-0",
+	}
+	if err := store.Record(second); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	got, err := store.Find("job-1")
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if got == nil {
+		t.Fatal("Find returned nil for a recorded job")
+	}
+	got.StartedAt, got.FinishedAt = got.StartedAt.UTC(), got.FinishedAt.UTC()
+	if !reflect.DeepEqual(*got, second) {
+		t.Errorf("Find = %+v, want %+v", *got, second)
+	}
+
+	if missing, err := store.Find("unknown"); err != nil || missing != nil {
+		t.Errorf("Find(unknown) = %v, %v, want nil, nil", missing, err)
+	}
+}
+
+func TestSQLiteJobHistoryStoreConcurrentRecords(t *testing.T) {
+	store := newTestJobHistory(t)
+	const writers = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- store.Record(JobRecord{ID: fmt.Sprintf("job-%d", i), Job: "enrollments", Status: "success", StartedAt: time.Now(), Sheets: []string{"EAD"}})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	got, err := store.List(JobHistoryFilter{Sheet: "EAD"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(got) != writers {
+		t.Errorf("List returned %d records, want %d", len(got), writers)
+	}
+}
+
+func TestSQLiteJobHistoryStoreUpgradesSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	db, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The job_history table as first released, before retries were recorded.
+	_, err = db.Exec(`CREATE TABLE job_history (
+		id TEXT PRIMARY KEY, job TEXT NOT NULL, tenant TEXT NOT NULL DEFAULT '', status TEXT NOT NULL,
+		started_at INTEGER NOT NULL, finished_at INTEGER NOT NULL,
+		rows_fetched INTEGER NOT NULL DEFAULT 0, rows_written INTEGER NOT NULL DEFAULT 0,
+		params TEXT, failures TEXT, failed_pages TEXT, error TEXT NOT NULL DEFAULT '');
+		INSERT INTO job_history (id, job, status, started_at, finished_at) VALUES ('old', 'enrollments', 'success', 0, 0)`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewSQLiteJobHistoryStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteJobHistoryStore: %v", err)
+	}
+	defer store.Close()
+	if old, err := store.Find("old"); err != nil || old == nil || old.Attempt != 0 {
+		t.Fatalf("Find(old) = %+v, %v, want the record without attempt", old, err)
+	}
+	if err := store.Record(JobRecord{ID: "new", Job: "enrollments", Status: "failed", Attempt: 3, RetryOf: "first"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if got, err := store.Find("new"); err != nil || got.Attempt != 3 || got.RetryOf != "first" {
+		t.Errorf("Find(new) = %+v, %v, want attempt 3 of first", got, err)
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// metadataRetry spaces out fetches of a list whose last fetch failed, while
+// the stale copy keeps being served.
+const metadataRetry = 5 * time.Minute
+
+// metadataList is one Jacad list kept in memory, such as the process notices.
+type metadataList[T any] struct {
+	mu        sync.Mutex
+	items     []T
+	fetchedAt time.Time
+	triedAt   time.Time
+}
+
+// get returns the list, fetching it when it is older than ttl or force is
+// set. A failed fetch serves the stale list, if there is one, and is not
+// retried for metadataRetry, forced or not.
+func (l *metadataList[T]) get(ctx context.Context, ttl time.Duration, force bool, fetch func(context.Context) ([]T, error)) ([]T, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	fresh := !l.fetchedAt.IsZero() && now.Sub(l.fetchedAt) < ttl
+	if fresh && !force {
+		return l.items, nil
+	}
+	if l.items != nil && l.triedAt.After(l.fetchedAt) && now.Sub(l.triedAt) < metadataRetry {
+		return l.items, nil
+	}
+
+	l.triedAt = now
+	items, err := fetch(ctx)
+	if err != nil {
+		if l.items != nil {
+			logging.FromContext(ctx).Warn("Failed to refresh Jacad metadata, keeping the cached copy", "age", now.Sub(l.fetchedAt).Round(time.Second).String(), "error", err)
+			return l.items, nil
+		}
+		return nil, err
+	}
+	if items == nil {
+		items = []T{}
+	}
+	l.items, l.fetchedAt = items, now
+	return items, nil
+}
+
+// age returns how long ago the list was fetched, or false if it never was.
+func (l *metadataList[T]) age(now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return now.Sub(l.fetchedAt), !l.fetchedAt.IsZero()
+}
+
+// tenantMetadata holds the lookups of one Jacad profile.
+type tenantMetadata struct {
+	notices metadataList[models.Period]
+	courses metadataList[models.Course]
+}
+
+// metadataCache keeps the periods and courses of each tenant, so jobs reuse
+// them instead of listing them again. The zero value is ready to use.
+type metadataCache struct {
+	mu      sync.Mutex
+	tenants map[string]*tenantMetadata
+}
+
+// metadata returns the lookups of the tenant in ctx, creating them on first
+// use.
+func (c *JacadClient) metadata(ctx context.Context) *tenantMetadata {
+	tenant := tenantFrom(ctx)
+	c.meta.mu.Lock()
+	defer c.meta.mu.Unlock()
+	if c.meta.tenants == nil {
+		c.meta.tenants = make(map[string]*tenantMetadata)
+	}
+	m, ok := c.meta.tenants[tenant]
+	if !ok {
+		m = &tenantMetadata{}
+		c.meta.tenants[tenant] = m
+	}
+	return m
+}
+
+// cachedNotices returns the process notices of every organization, as
+// FetchPeriods lists them, from the metadata cache.
+func (c *JacadClient) cachedNotices(ctx context.Context, force bool) ([]models.Period, error) {
+	return c.metadata(ctx).notices.get(ctx, c.Config.MetadataTTL, force, func(ctx context.Context) ([]models.Period, error) {
+		return c.FetchPeriods(ctx, 0)
+	})
+}
+
+// cachedCourses returns the course catalog from the metadata cache.
+func (c *JacadClient) cachedCourses(ctx context.Context, force bool) ([]models.Course, error) {
+	return c.metadata(ctx).courses.get(ctx, c.Config.MetadataTTL, force, func(ctx context.Context) ([]models.Course, error) {
+		return fetchAllPagesOf[models.Course](ctx, c, c.Config.Endpoints["COURSES"], nil)
+	})
+}
+
+// RefreshMetadata fetches the periods and courses of every tenant again, and
+// the organizations when they come from Jacad. Failures are logged and the
+// cached copies kept.
+func (c *JacadClient) RefreshMetadata(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	for _, tenant := range append([]string{""}, c.Config.TenantNames()...) {
+		tenantCtx := WithCacheBypass(WithTenant(ctx, tenant))
+		if _, err := c.cachedNotices(tenantCtx, true); err != nil {
+			logger.Warn("Failed to refresh periods", "tenant", tenant, "error", err)
+		}
+		if _, err := c.cachedCourses(tenantCtx, true); err != nil {
+			logger.Warn("Failed to refresh courses", "tenant", tenant, "error", err)
+		}
+	}
+	if c.Config.OrganizationsSource == config.OrgSourceJacad {
+		if _, err := c.ReloadOrganizations(ctx); err != nil {
+			logger.Warn("Failed to refresh organizations", "error", err)
+		}
+	}
+}
+
+// RunMetadataRefresher refreshes the metadata every Config.MetadataRefresh
+// until ctx is done, so jobs find it already cached. A zero interval leaves
+// the metadata to be fetched when jobs need it.
+func (c *JacadClient) RunMetadataRefresher(ctx context.Context) {
+	if c.Config.MetadataRefresh <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.Config.MetadataRefresh)
+	defer ticker.Stop()
+
+	for {
+		c.RefreshMetadata(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMetadataListGet(t *testing.T) {
+	var list metadataList[int]
+	calls := 0
+	var fetchErr error
+	fetch := func(context.Context) ([]int, error) {
+		calls++
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		return []int{calls}, nil
+	}
+	get := func(ttl time.Duration, force bool) ([]int, error) {
+		return list.get(context.Background(), ttl, force, fetch)
+	}
+
+	fetchErr = errors.New("jacad down")
+	if _, err := get(time.Hour, false); err == nil {
+		t.Fatal("get() with nothing cached and a failing fetch = nil error")
+	}
+
+	fetchErr = nil
+	if items, _ := get(time.Hour, false); len(items) != 1 || items[0] != 2 {
+		t.Fatalf("get() = %v, want the fetched list [2]", items)
+	}
+	if items, _ := get(time.Hour, false); items[0] != 2 || calls != 2 {
+		t.Errorf("get() within the TTL = %v after %d fetches, want the cached [2]", items, calls)
+	}
+	if items, _ := get(time.Hour, true); items[0] != 3 {
+		t.Errorf("forced get() = %v, want a fresh [3]", items)
+	}
+
+	fetchErr = errors.New("jacad down")
+	if items, err := get(0, false); err != nil || items[0] != 3 {
+		t.Errorf("get() with a failing fetch = %v, %v, want the stale [3]", items, err)
+	}
+	get(0, true)
+	if calls != 4 {
+		t.Errorf("get() right after a failed fetch made %d fetches, want it to wait for metadataRetry", calls)
+	}
+}
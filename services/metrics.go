@@ -0,0 +1,164 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/SamuelLeutner/fetch-student-data/metrics"
+)
+
+var (
+	jacadRequestsTotal = metrics.NewCounterVec(
+		"jacad_requests_total",
+		"Jacad API request attempts by endpoint, method and resulting status.",
+		"endpoint", "method", "status",
+	)
+	jacadRetriesTotal = metrics.NewCounterVec(
+		"jacad_request_retries_total",
+		"Jacad API request retries by endpoint.",
+		"endpoint",
+	)
+	jacadReauthTotal = metrics.NewCounterVec(
+		"jacad_reauth_total",
+		"Jacad re-authentications triggered by a 401 response mid-fetch.",
+	)
+	jacadTokenRefreshesTotal = metrics.NewCounterVec(
+		"jacad_token_refreshes_total",
+		"Jacad logins by trigger (missing, expired, proactive, manual) and result (ok, error).",
+		"trigger", "result",
+	)
+	secretRefreshesTotal = metrics.NewCounterVec(
+		"secret_refreshes_total",
+		"Reads of secrets from SECRETS_PROVIDER by secret name and result (ok, error).",
+		"secret", "result",
+	)
+	jacadRequestDuration = metrics.NewHistogramVec(
+		"jacad_request_duration_seconds",
+		"Latency of Jacad API request attempts by endpoint.",
+		nil,
+		"endpoint",
+	)
+	jacadRateLimitWait = metrics.NewHistogramVec(
+		"jacad_rate_limit_wait_seconds",
+		"Time spent waiting for the Jacad rate limiter before each request attempt.",
+		[]float64{0.001, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		"endpoint",
+	)
+	jacadCacheLookupsTotal = metrics.NewCounterVec(
+		"jacad_cache_lookups_total",
+		"Jacad response cache lookups by result (hit, miss, error).",
+		"result",
+	)
+	jacadConcurrencyLimit = metrics.NewGaugeVec(
+		"jacad_concurrency_limit",
+		"Current adaptive limit on concurrent Jacad requests across endpoints.",
+	)
+	jacadConcurrencyInFlight = metrics.NewGaugeVec(
+		"jacad_concurrency_in_flight",
+		"Jacad requests currently holding a global concurrency slot.",
+	)
+	jacadEndpointInFlight = metrics.NewGaugeVec(
+		"jacad_endpoint_in_flight",
+		"Jacad requests currently holding a slot of their endpoint's concurrency limit, by endpoint.",
+		"endpoint",
+	)
+	pipelineBytesHeld = metrics.NewGaugeVec(
+		"pipeline_bytes_held",
+		"Estimated bytes of enrollments streamed fetches hold between fetching and writing them.",
+	)
+	jacadCircuitBreakerState = metrics.NewGaugeVec(
+		"jacad_circuit_breaker_state",
+		"Jacad circuit breaker state by Jacad API base: 0 closed, 1 half-open, 2 open.",
+		"api_base",
+	)
+	jacadCircuitBreakerTransitionsTotal = metrics.NewCounterVec(
+		"jacad_circuit_breaker_transitions_total",
+		"Jacad circuit breaker state changes by Jacad API base and new state.",
+		"api_base", "state",
+	)
+	jacadCircuitBreakerRejectionsTotal = metrics.NewCounterVec(
+		"jacad_circuit_breaker_rejections_total",
+		"Jacad requests failed fast because the circuit breaker was open, by Jacad API base.",
+		"api_base",
+	)
+	enrollmentDuplicatesDroppedTotal = metrics.NewCounterVec(
+		"enrollment_duplicates_dropped_total",
+		"Enrollments dropped because their idMatricula was already fetched in the same run.",
+	)
+	enrollmentsRejectedTotal = metrics.NewCounterVec(
+		"enrollments_rejected_total",
+		"Enrollments sent to the rejects tab by the data quality rule they failed.",
+		"rule",
+	)
+	sheetsRowsWrittenTotal = metrics.NewCounterVec(
+		"sheets_rows_written_total",
+		"Rows sent to the Google Sheets API by operation (append, overwrite, upsert).",
+		"operation",
+	)
+	sheetsAPIErrorsTotal = metrics.NewCounterVec(
+		"sheets_api_errors_total",
+		"Failed Google Sheets API calls by operation and HTTP code.",
+		"operation", "code",
+	)
+	sheetsQuotaRetriesTotal = metrics.NewCounterVec(
+		"sheets_quota_retries_total",
+		"Google Sheets API calls retried after a retryable error, by HTTP code.",
+		"code",
+	)
+	sheetsQuotaWaitsTotal = metrics.NewCounterVec(
+		"sheets_quota_waits_total",
+		"Google Sheets write calls delayed to stay within the per-minute write budget, by operation.",
+		"operation",
+	)
+	sheetsLockWait = metrics.NewHistogramVec(
+		"sheets_lock_wait_seconds",
+		"Time Google Sheets writes waited for another write to the same tab, by operation.",
+		[]float64{0.001, 0.01, 0.1, 0.5, 1, 5, 15, 30, 60},
+		"operation",
+	)
+	jacadPagesArchivedTotal = metrics.NewCounterVec(
+		"jacad_pages_archived_total",
+		"Raw Jacad pages written to object storage, by result (ok, error).",
+		"result",
+	)
+	bigQueryRowsLoadedTotal = metrics.NewCounterVec(
+		"bigquery_rows_loaded_total",
+		"Rows loaded into BigQuery by operation (append, overwrite, upsert).",
+		"operation",
+	)
+	bigQueryAPIErrorsTotal = metrics.NewCounterVec(
+		"bigquery_api_errors_total",
+		"Failed BigQuery API calls by operation and HTTP code.",
+		"operation", "code",
+	)
+	excelRowsWrittenTotal = metrics.NewCounterVec(
+		"excel_rows_written_total",
+		"Rows sent to Excel Online worksheets by operation (append, overwrite, upsert).",
+		"operation",
+	)
+	excelAPIErrorsTotal = metrics.NewCounterVec(
+		"excel_api_errors_total",
+		"Failed Microsoft Graph workbook calls by operation and HTTP code.",
+		"operation", "code",
+	)
+)
+
+func (c *JacadClient) endpointLabel(url string) string {
+	path := strings.Split(url, "?")[0]
+	base := c.Config.APIBase
+	for _, profile := range c.Config.JacadProfiles {
+		if profile.APIBase != "" && strings.HasPrefix(path, profile.APIBase) {
+			base = profile.APIBase
+			break
+		}
+	}
+	path = strings.TrimPrefix(path, base)
+	// v2 paths are labelled like their v1 counterparts, so endpoint limits
+	// and dashboards keep working whichever version is called.
+	if prefix := c.Config.JacadV2PathPrefix; prefix != "" && strings.HasPrefix(path, prefix+"/") {
+		path = strings.TrimPrefix(path, prefix)
+	}
+	if path == "" {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,429 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// WriterDestination is one writer of a MultiWriter, named after its WRITER
+// value.
+type WriterDestination struct {
+	Name   string
+	Writer SheetWriter
+}
+
+// DestinationOutcome reports how a job's writes to one MultiWriter
+// destination went. Skipped counts the writes not attempted because the
+// destination had already failed.
+type DestinationOutcome struct {
+	Name     string `json:"name"`
+	Primary  bool   `json:"primary,omitempty"`
+	Writes   int    `json:"writes"`
+	Rows     int    `json:"rows"`
+	Failures int    `json:"failures"`
+	Skipped  int    `json:"skipped,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// MultiWriter tees every write to several writers, e.g. Sheets for people and
+// BigQuery for BI. The first destination is the primary: it is written in the
+// caller's goroutine, its errors fail the write as they would without
+// fan-out, and reads, row counts and tab splits come from it. The others are
+// secondaries: each job queues its writes to each of them, in order, and a
+// worker of their own sends them, so a slow secondary only holds a job back
+// once maxQueuedWrites of its writes are waiting. Their failures are only
+// recorded against the job, and a secondary that failed is skipped for the
+// rest of the job so it is not left further out of step. TakeOutcomes and
+// Flush wait for the job's queued writes.
+//
+// Secondaries mirror the primary's default spreadsheet only: writes routed to
+// another spreadsheet with WithSpreadsheetID, such as ORG_SPREADSHEETS or a
+// per-request spreadsheetId, are skipped for them, since their tabs would
+// otherwise collide with the default spreadsheet's.
+type MultiWriter struct {
+	destinations []WriterDestination
+
+	mu sync.Mutex
+	// jobs holds the outcomes of each job ID, in destination order.
+	jobs map[string][]DestinationOutcome
+	// queues holds each job's write queue per secondary, in destination
+	// order; the primary's entry is nil.
+	queues map[string][]*writeQueue
+}
+
+// maxQueuedWrites bounds the writes a job queues for a secondary before its
+// writes wait for the secondary to catch up.
+const maxQueuedWrites = 64
+
+func NewMultiWriter(destinations ...WriterDestination) (*MultiWriter, error) {
+	if len(destinations) == 0 {
+		return nil, fmt.Errorf("multi writer requires at least one destination")
+	}
+	return &MultiWriter{destinations: destinations, jobs: make(map[string][]DestinationOutcome), queues: make(map[string][]*writeQueue)}, nil
+}
+
+// primaryWriter returns the writer jobs read back from: the primary of a
+// MultiWriter, or w itself.
+func primaryWriter(w SheetWriter) SheetWriter {
+	if multi, ok := w.(*MultiWriter); ok {
+		return multi.destinations[0].Writer
+	}
+	return w
+}
+
+func (m *MultiWriter) EnsureSheetExists(ctx context.Context, sheetName string) error {
+	return m.fanOut(ctx, "ensure_sheet", 0, func(ctx context.Context, w SheetWriter) error {
+		return w.EnsureSheetExists(ctx, sheetName)
+	})
+}
+
+func (m *MultiWriter) Clear(ctx context.Context, sheetName string) error {
+	return m.fanOut(ctx, "clear", 0, func(ctx context.Context, w SheetWriter) error {
+		return w.Clear(ctx, sheetName)
+	})
+}
+
+func (m *MultiWriter) SetHeaders(ctx context.Context, sheetName string, headers []string) error {
+	return m.fanOut(ctx, "set_headers", 0, func(ctx context.Context, w SheetWriter) error {
+		return w.SetHeaders(ctx, sheetName, headers)
+	})
+}
+
+func (m *MultiWriter) AppendRows(ctx context.Context, sheetName string, rows [][]interface{}) error {
+	return m.fanOut(ctx, "append", len(rows), func(ctx context.Context, w SheetWriter) error {
+		return w.AppendRows(ctx, sheetName, rows)
+	})
+}
+
+func (m *MultiWriter) OverwriteSheetData(ctx context.Context, sheetName string, headers []string, rows [][]interface{}) error {
+	return m.fanOut(ctx, "overwrite", len(rows), func(ctx context.Context, w SheetWriter) error {
+		return w.OverwriteSheetData(ctx, sheetName, headers, rows)
+	})
+}
+
+func (m *MultiWriter) UpsertRows(ctx context.Context, sheetName string, headers []string, keyColumn string, rows [][]interface{}) error {
+	return m.fanOut(ctx, "upsert", len(rows), func(ctx context.Context, w SheetWriter) error {
+		return w.UpsertRows(ctx, sheetName, headers, keyColumn, rows)
+	})
+}
+
+// WriteEnrollments hands data to the destinations that lay out enrollments
+// themselves and overwrites the sheet of the others with the mapped rows.
+func (m *MultiWriter) WriteEnrollments(ctx context.Context, sheetName string, mapper *EnrollmentRowMapper, data []models.Enrollment) error {
+	var rows [][]interface{}
+	var once sync.Once
+	return m.fanOut(ctx, "overwrite", len(data), func(ctx context.Context, w SheetWriter) error {
+		if writer, ok := w.(EnrollmentWriter); ok {
+			return writer.WriteEnrollments(ctx, sheetName, mapper, data)
+		}
+		once.Do(func() { rows = mapper.Rows(data) })
+		return w.OverwriteSheetData(ctx, sheetName, mapper.Headers(), rows)
+	})
+}
+
+// CountRows counts the rows of the primary. It fails with
+// errors.ErrUnsupported when the primary cannot count rows.
+func (m *MultiWriter) CountRows(ctx context.Context, sheetName string) (int, error) {
+	counter, ok := primaryWriter(m).(RowCounter)
+	if !ok {
+		return 0, fmt.Errorf("writer %s cannot count rows: %w", m.destinations[0].Name, errors.ErrUnsupported)
+	}
+	return counter.CountRows(ctx, sheetName)
+}
+
+// ReadRows reads the sheet back from the primary.
+func (m *MultiWriter) ReadRows(ctx context.Context, sheetName string) ([][]interface{}, error) {
+	reader, ok := primaryWriter(m).(SheetReader)
+	if !ok {
+		return nil, fmt.Errorf("writer %s cannot read sheets back: %w", m.destinations[0].Name, errors.ErrUnsupported)
+	}
+	return reader.ReadRows(ctx, sheetName)
+}
+
+// LeaseSheet leases the tab on the primary, when it supports leases. The
+// secondaries keep locking per call.
+func (m *MultiWriter) LeaseSheet(ctx context.Context, sheetName string, wait time.Duration) (context.Context, func(), error) {
+	leaser, ok := primaryWriter(m).(SheetLeaser)
+	if !ok {
+		return ctx, func() {}, nil
+	}
+	return leaser.LeaseSheet(ctx, sheetName, wait)
+}
+
+// CreateSpreadsheet creates a spreadsheet with the primary, which is the only
+// destination it is routed to.
+func (m *MultiWriter) CreateSpreadsheet(ctx context.Context, title string) (*CreatedSpreadsheet, error) {
+	creator, ok := primaryWriter(m).(SpreadsheetCreator)
+	if !ok {
+		return nil, fmt.Errorf("writer %s cannot create spreadsheets: %w", m.destinations[0].Name, errors.ErrUnsupported)
+	}
+	return creator.CreateSpreadsheet(ctx, title)
+}
+
+// MaxRowsPerTab returns the primary's tab row limit, 0 when it does not split
+// tabs.
+func (m *MultiWriter) MaxRowsPerTab() int {
+	if splitter, ok := primaryWriter(m).(TabSplitter); ok {
+		return splitter.MaxRowsPerTab()
+	}
+	return 0
+}
+
+// SplitTab splits the tab in the destinations that split tabs; the others
+// just get the numbered tabs written after it.
+func (m *MultiWriter) SplitTab(ctx context.Context, sheetName string) error {
+	return m.fanOut(ctx, "split_tab", 0, func(ctx context.Context, w SheetWriter) error {
+		if splitter, ok := w.(TabSplitter); ok {
+			return splitter.SplitTab(ctx, sheetName)
+		}
+		return nil
+	})
+}
+
+// Flush flushes every destination that buffers rows, once the job's queued
+// writes to the secondaries are done.
+func (m *MultiWriter) Flush(ctx context.Context) error {
+	err := m.fanOut(ctx, "flush", 0, func(ctx context.Context, w SheetWriter) error {
+		if flusher, ok := w.(Flusher); ok {
+			return flusher.Flush(ctx)
+		}
+		return nil
+	})
+	m.waitQueued(logging.JobID(ctx))
+	return err
+}
+
+// DiscardBuffered drops the rows every destination buffered for the job in
+// ctx and returns how many the primary held.
+func (m *MultiWriter) DiscardBuffered(ctx context.Context) int {
+	dropped := 0
+	for i, dest := range m.destinations {
+		if discarder, ok := dest.Writer.(BufferDiscarder); ok {
+			if n := discarder.DiscardBuffered(ctx); i == 0 {
+				dropped = n
+			}
+		}
+	}
+	return dropped
+}
+
+// CheckHealth checks every destination, so a misconfigured one is caught at
+// startup rather than by the first job.
+func (m *MultiWriter) CheckHealth(ctx context.Context) error {
+	var errs []error
+	for i, dest := range m.destinations {
+		checker, ok := dest.Writer.(HealthChecker)
+		if !ok {
+			continue
+		}
+		destCtx := ctx
+		if i > 0 {
+			destCtx = withoutSpreadsheetID(ctx)
+		}
+		if err := checker.CheckHealth(destCtx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", dest.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// TakeOutcomes waits for the job in ctx's queued writes, then returns and
+// forgets the outcome of every destination for it, or nil when the job wrote
+// nothing.
+func (m *MultiWriter) TakeOutcomes(ctx context.Context) []DestinationOutcome {
+	jobID := logging.JobID(ctx)
+	m.waitQueued(jobID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	outcomes := m.jobs[jobID]
+	delete(m.jobs, jobID)
+	delete(m.queues, jobID)
+	return outcomes
+}
+
+// PeekOutcomes is TakeOutcomes without forgetting them.
+func (m *MultiWriter) PeekOutcomes(ctx context.Context) []DestinationOutcome {
+	jobID := logging.JobID(ctx)
+	m.waitQueued(jobID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DestinationOutcome(nil), m.jobs[jobID]...)
+}
+
+// fanOut runs write on the primary and queues it for every secondary. rows is
+// how many rows the write sends, for the outcomes.
+func (m *MultiWriter) fanOut(ctx context.Context, operation string, rows int, write func(ctx context.Context, w SheetWriter) error) error {
+	jobID := logging.JobID(ctx)
+	routed := m.routedAway(ctx)
+	queues := m.jobQueues(jobID)
+	for i, dest := range m.destinations[1:] {
+		i := i + 1
+		if routed {
+			m.record(ctx, i, operation, rows, true, nil)
+			continue
+		}
+		destCtx := withoutSpreadsheetID(ctx)
+		queues[i].push(func() {
+			if m.failed(jobID, i) {
+				m.record(ctx, i, operation, rows, true, nil)
+				return
+			}
+			m.record(ctx, i, operation, rows, false, write(destCtx, dest.Writer))
+		})
+	}
+
+	err := write(ctx, m.destinations[0].Writer)
+	m.record(ctx, 0, operation, rows, false, err)
+	return err
+}
+
+// routedAway reports whether ctx routes writes to a spreadsheet other than
+// the primary's default one, which secondaries do not mirror.
+func (m *MultiWriter) routedAway(ctx context.Context) bool {
+	id := spreadsheetIDFrom(ctx)
+	if id == "" {
+		return false
+	}
+	if sheets, ok := primaryWriter(m).(*GoogleSheetsWriter); ok {
+		return id != sheets.spreadsheetID
+	}
+	return true
+}
+
+// jobQueues returns the secondaries' write queues of the job, creating them
+// on its first write.
+func (m *MultiWriter) jobQueues(jobID string) []*writeQueue {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	queues, ok := m.queues[jobID]
+	if !ok {
+		queues = make([]*writeQueue, len(m.destinations))
+		for i := 1; i < len(queues); i++ {
+			queues[i] = newWriteQueue(maxQueuedWrites)
+		}
+		m.queues[jobID] = queues
+	}
+	return queues
+}
+
+// waitQueued waits until the job's queued writes to the secondaries are done.
+func (m *MultiWriter) waitQueued(jobID string) {
+	m.mu.Lock()
+	queues := m.queues[jobID]
+	m.mu.Unlock()
+	for _, queue := range queues {
+		if queue != nil {
+			queue.wait()
+		}
+	}
+}
+
+// failed reports whether secondary i already failed for the job.
+func (m *MultiWriter) failed(jobID string, i int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	outcomes := m.jobs[jobID]
+	return i < len(outcomes) && outcomes[i].Failures > 0
+}
+
+// record counts a write to destination i against the job in ctx.
+func (m *MultiWriter) record(ctx context.Context, i int, operation string, rows int, skipped bool, err error) {
+	jobID := logging.JobID(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	outcomes, ok := m.jobs[jobID]
+	if !ok {
+		outcomes = make([]DestinationOutcome, len(m.destinations))
+		for i, dest := range m.destinations {
+			outcomes[i] = DestinationOutcome{Name: dest.Name, Primary: i == 0}
+		}
+		m.jobs[jobID] = outcomes
+	}
+	outcome := &outcomes[i]
+	switch {
+	case skipped:
+		outcome.Skipped++
+	case err != nil:
+		outcome.Failures++
+		outcome.Error = err.Error()
+		if i > 0 {
+			logging.FromContext(ctx).Warn("Fan-out destination failed. Skipping it for the rest of the job.", "destination", outcome.Name, "operation", operation, "error", err)
+		}
+	default:
+		outcome.Writes++
+		outcome.Rows += rows
+	}
+}
+
+// writeQueue runs queued writes one at a time, in order, on a worker
+// goroutine that exits whenever the queue runs empty.
+type writeQueue struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	pending []func()
+	limit   int
+	running bool
+}
+
+func newWriteQueue(limit int) *writeQueue {
+	q := &writeQueue{limit: limit}
+	q.changed = sync.NewCond(&q.mu)
+	return q
+}
+
+// push queues write, waiting while limit writes are already queued.
+func (q *writeQueue) push(write func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.pending) >= q.limit {
+		q.changed.Wait()
+	}
+	q.pending = append(q.pending, write)
+	if !q.running {
+		q.running = true
+		go q.run()
+	}
+}
+
+func (q *writeQueue) run() {
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.running = false
+			q.changed.Broadcast()
+			q.mu.Unlock()
+			return
+		}
+		write := q.pending[0]
+		q.pending = q.pending[1:]
+		q.changed.Broadcast()
+		q.mu.Unlock()
+
+		write()
+	}
+}
+
+// wait blocks until every queued write is done.
+func (q *writeQueue) wait() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.running {
+		q.changed.Wait()
+	}
+}
+
+// withoutSpreadsheetID undoes WithSpreadsheetID, whose spreadsheet IDs name
+// spreadsheets of the primary writer only.
+func withoutSpreadsheetID(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(spreadsheetIDKey{}).(string); !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, spreadsheetIDKey{}, "")
+}
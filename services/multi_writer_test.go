@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+)
+
+func TestMultiWriterFansOut(t *testing.T) {
+	primary, secondary := NewFakeSheetWriter(), NewFakeSheetWriter()
+	writer, err := NewMultiWriter(
+		WriterDestination{Name: "sheets", Writer: primary},
+		WriterDestination{Name: "bigquery", Writer: secondary},
+	)
+	if err != nil {
+		t.Fatalf("NewMultiWriter() = %v", err)
+	}
+	ctx := logging.WithJobID(context.Background(), "job-a")
+	headers := []string{"idMatricula", "aluno"}
+
+	if err := writer.OverwriteSheetData(ctx, "EAD", headers, [][]interface{}{{1, "Ana"}, {2, "Bruno"}}); err != nil {
+		t.Fatalf("OverwriteSheetData() = %v", err)
+	}
+	// Writes routed to another spreadsheet only go to the primary.
+	if err := writer.OverwriteSheetData(WithSpreadsheetID(ctx, "org-book"), "POS", headers, [][]interface{}{{3, "Carla"}}); err != nil {
+		t.Fatalf("OverwriteSheetData() to another spreadsheet = %v", err)
+	}
+	if count, err := writer.CountRows(ctx, "EAD"); err != nil || count != 2 {
+		t.Errorf("CountRows() = %d, %v; want 2", count, err)
+	}
+
+	outcomes := writer.TakeOutcomes(ctx)
+	for name, w := range map[string]*FakeSheetWriter{"primary": primary, "secondary": secondary} {
+		if sheet, ok := w.Sheet("EAD"); !ok || len(sheet.Rows) != 2 {
+			t.Errorf("%s sheet = %v, want the 2 rows", name, sheet)
+		}
+	}
+	if got := primary.Calls()[1].Spreadsheet; got != "org-book" {
+		t.Errorf("primary spreadsheet = %q, want org-book", got)
+	}
+	if _, ok := secondary.Sheet("POS"); ok {
+		t.Error("secondary got the write routed to another spreadsheet")
+	}
+	want := []DestinationOutcome{
+		{Name: "sheets", Primary: true, Writes: 2, Rows: 3},
+		{Name: "bigquery", Writes: 1, Rows: 2, Skipped: 1},
+	}
+	if len(outcomes) != len(want) || outcomes[0] != want[0] || outcomes[1] != want[1] {
+		t.Errorf("TakeOutcomes() = %+v, want %+v", outcomes, want)
+	}
+	if outcomes := writer.TakeOutcomes(ctx); outcomes != nil {
+		t.Errorf("second TakeOutcomes() = %+v, want nil", outcomes)
+	}
+}
+
+func TestMultiWriterSecondaryFailure(t *testing.T) {
+	primary, secondary := NewFakeSheetWriter(), NewFakeSheetWriter()
+	secondary.FailOn = func(method, sheet string) error {
+		if method == "AppendRows" {
+			return errors.New("quota exceeded")
+		}
+		return nil
+	}
+	writer, _ := NewMultiWriter(
+		WriterDestination{Name: "sheets", Writer: primary},
+		WriterDestination{Name: "excel", Writer: secondary},
+	)
+	jobA := logging.WithJobID(context.Background(), "job-a")
+
+	if err := writer.AppendRows(jobA, "EAD", [][]interface{}{{1, "Ana"}}); err != nil {
+		t.Fatalf("AppendRows() with a failing secondary = %v, want nil", err)
+	}
+	if err := writer.Clear(jobA, "EAD"); err != nil {
+		t.Fatalf("Clear() = %v", err)
+	}
+	outcomes := writer.PeekOutcomes(jobA)
+	if calls := secondary.Calls(); len(calls) != 0 {
+		t.Errorf("secondary calls after its failure = %+v, want none", calls)
+	}
+	if got := outcomes[1]; got.Failures != 1 || got.Skipped != 1 || got.Error != "quota exceeded" {
+		t.Errorf("secondary outcome = %+v, want 1 failure then 1 skipped write", got)
+	}
+	if got := outcomes[0]; got.Writes != 2 || got.Rows != 1 {
+		t.Errorf("primary outcome = %+v, want 2 writes of 1 row", got)
+	}
+
+	// Another job starts over with every destination.
+	jobB := logging.WithJobID(context.Background(), "job-b")
+	if err := writer.Clear(jobB, "EAD"); err != nil {
+		t.Fatalf("Clear() for another job = %v", err)
+	}
+	writer.TakeOutcomes(jobB)
+	if calls := secondary.Calls(); len(calls) != 1 || calls[0].Method != "Clear" {
+		t.Errorf("secondary calls for another job = %+v, want the Clear", calls)
+	}
+}
+
+func TestMultiWriterPrimaryFailure(t *testing.T) {
+	primary, secondary := NewFakeSheetWriter(), NewFakeSheetWriter()
+	failure := errors.New("sheet is protected")
+	primary.FailOn = func(method, sheet string) error { return failure }
+	writer, _ := NewMultiWriter(
+		WriterDestination{Name: "sheets", Writer: primary},
+		WriterDestination{Name: "csv", Writer: secondary},
+	)
+
+	if err := writer.SetHeaders(context.Background(), "EAD", []string{"aluno"}); !errors.Is(err, failure) {
+		t.Errorf("SetHeaders() = %v, want the primary's error", err)
+	}
+	writer.TakeOutcomes(context.Background())
+	if _, ok := secondary.Sheet("EAD"); !ok {
+		t.Error("secondary was not written alongside the failing primary")
+	}
+	if _, err := writer.ReadRows(context.Background(), "EAD"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("ReadRows() from a primary that cannot read = %v, want errors.ErrUnsupported", err)
+	}
+}
+
+func TestMultiWriterSecondariesAreAsync(t *testing.T) {
+	primary, secondary := NewFakeSheetWriter(), NewFakeSheetWriter()
+	unblock := make(chan struct{})
+	secondary.FailOn = func(method, sheet string) error {
+		<-unblock
+		return nil
+	}
+	writer, _ := NewMultiWriter(
+		WriterDestination{Name: "sheets", Writer: primary},
+		WriterDestination{Name: "bigquery", Writer: secondary},
+	)
+	ctx := logging.WithJobID(context.Background(), "job-a")
+
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 3; i++ {
+			if err := writer.AppendRows(ctx, "EAD", [][]interface{}{{i}}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("AppendRows() = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("writes waited for a blocked secondary")
+	}
+
+	taken := make(chan []DestinationOutcome, 1)
+	go func() { taken <- writer.TakeOutcomes(ctx) }()
+	select {
+	case <-taken:
+		t.Fatal("TakeOutcomes() returned before the secondary's queued writes")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(unblock)
+	if outcomes := <-taken; outcomes[1].Writes != 3 || outcomes[1].Rows != 3 {
+		t.Errorf("secondary outcome = %+v, want the 3 queued writes", outcomes[1])
+	}
+	if sheet, _ := secondary.Sheet("EAD"); len(sheet.Rows) != 3 || sheet.Rows[2][0] != 2 {
+		t.Errorf("secondary rows = %v, want the 3 rows in order", sheet.Rows)
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/jobs"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/notifications"
+)
+
+// finishJob records a finished job in the history and, unless it was a dry
+// run, in the audit tab, and sends its summary through c.Notifications.
+// sheets lists the sheets the job wrote. A job stopped through jobs.Tracker.Cancel is recorded as
+// cancelled with the progress it reached, and the rows it still had buffered
+// in the writer are dropped.
+func (c *JacadClient) finishJob(ctx context.Context, summary notifications.Summary, params interface{}, sheets []string, failedPages []FailedPage, dryRun bool, startedAt time.Time, err error) {
+	summary.JobID = logging.JobID(ctx)
+	summary.StartedAt = startedAt
+	summary.Duration = time.Since(startedAt).Round(time.Millisecond)
+	summary.Status = notifications.StatusSuccess
+	if err != nil {
+		summary.Status = notifications.StatusFailed
+		summary.Error = err.Error()
+	}
+	transfer := c.transfers.take(ctx)
+	summary.BytesReceived, summary.BytesSaved = transfer.BytesReceived, transfer.BytesSaved
+	if multi, ok := c.Writer.(*MultiWriter); ok {
+		for _, outcome := range multi.TakeOutcomes(ctx) {
+			if !outcome.Primary && outcome.Failures > 0 {
+				logging.FromContext(ctx).Warn("Job finished with a failed fan-out destination", "destination", outcome.Name, "writes", outcome.Writes, "skipped", outcome.Skipped, "error", outcome.Error)
+			}
+		}
+	}
+	if errors.Is(context.Cause(ctx), jobs.ErrCancelled) {
+		summary.Status = notifications.StatusCancelled
+		c.cancelledProgress(ctx, &summary)
+	}
+	c.recordJob(ctx, summary, params, sheets, failedPages)
+	if !dryRun {
+		c.writeAudit(ctx, summary, params, sheets)
+		if attempt, ok := jobs.AttemptFrom(ctx); ok && attempt.WillRetry(ctx, err) {
+			logging.FromContext(ctx).Info("Job failed and will be retried. Notifications wait for the last attempt.", "attempt", attempt.Number, "maxAttempts", attempt.Max)
+			return
+		}
+		c.Notifications.Send(ctx, summary)
+	}
+}
+
+// cancelledProgress drops the rows a cancelled job left buffered in the
+// writer and fills in the rows it fetched and wrote from its last progress
+// report when its result did not count them.
+func (c *JacadClient) cancelledProgress(ctx context.Context, summary *notifications.Summary) {
+	if discarder, ok := c.Writer.(BufferDiscarder); ok {
+		if dropped := discarder.DiscardBuffered(ctx); dropped > 0 {
+			logging.FromContext(ctx).Info("Dropped buffered rows of cancelled job", "rows", dropped)
+		}
+	}
+	if progress, ok := c.progress.Latest(summary.JobID); ok {
+		summary.RowsFetched = max(summary.RowsFetched, progress.RowsFetched)
+		summary.RowsWritten = max(summary.RowsWritten, progress.RowsWritten)
+	}
+}
+
+// enrollmentSummary describes a fetch-enrollments job for notifications.
+func enrollmentSummary(result *FetchResult, tenant string) notifications.Summary {
+	summary := notifications.Summary{Job: "fetch-enrollments", Tenant: tenant}
+	if result == nil {
+		return summary
+	}
+	summary.RowsFetched = result.TotalFetched
+	summary.RowsRejected, summary.RejectsSheet = result.Rejected, result.RejectsSheet
+	for _, org := range result.Organizations {
+		if org.Error != "" {
+			summary.Failures = append(summary.Failures, fmt.Sprintf("%s: %s", org.Sheet, org.Error))
+			continue
+		}
+		summary.RowsWritten += org.Rows
+	}
+	if result.FailedBatches > 0 {
+		summary.Failures = append(summary.Failures, fmt.Sprintf("%d batch(es) failed to fetch", result.FailedBatches))
+	}
+	if len(result.FailedPages) > 0 {
+		summary.Failures = append(summary.Failures, failedPagesSummary(result.FailedPages))
+	}
+	if result.DeltaError != "" {
+		summary.Failures = append(summary.Failures, "delta report: "+result.DeltaError)
+	}
+	if result.SummaryError != "" {
+		summary.Failures = append(summary.Failures, "summary tab: "+result.SummaryError)
+	}
+	if result.RejectsError != "" {
+		summary.Failures = append(summary.Failures, "rejects tab: "+result.RejectsError)
+	}
+	if result.PruneError != "" {
+		summary.Failures = append(summary.Failures, "snapshot pruning: "+result.PruneError)
+	}
+	return summary
+}
+
+// sheetSpreadsheets maps the sheets a fetch-enrollments job wrote outside the
+// default spreadsheet to the spreadsheet they went to: the job's own
+// spreadsheet, or otherwise the organization's ORG_SPREADSHEETS entry.
+func (c *JacadClient) sheetSpreadsheets(result *FetchResult) map[string]string {
+	if result == nil {
+		return nil
+	}
+	spreadsheets := make(map[string]string)
+	if result.Spreadsheet != nil {
+		for _, sheet := range writtenSheets(result) {
+			spreadsheets[sheet] = result.Spreadsheet.ID
+		}
+		return spreadsheets
+	}
+	for _, org := range result.Organizations {
+		if configured, _ := c.Config.Organizations.ByID(org.OrgID); configured.SpreadsheetID != "" {
+			spreadsheets[org.Sheet] = configured.SpreadsheetID
+		}
+	}
+	return spreadsheets
+}
+
+// writtenSheets lists the sheets a fetch-enrollments job wrote.
+func writtenSheets(result *FetchResult) []string {
+	if result == nil || result.DryRun {
+		return nil
+	}
+	var sheets []string
+	for _, org := range result.Organizations {
+		if org.Error == "" {
+			sheets = append(sheets, org.Sheet)
+		}
+	}
+	for _, sheet := range []string{result.DeltaSheet, result.SummarySheet, result.RejectsSheet} {
+		if sheet != "" {
+			sheets = append(sheets, sheet)
+		}
+	}
+	return sheets
+}
+
+// writtenSheets lists the sheet of a single-sheet job when it was written.
+// A nil result wrote nothing.
+func (r *SheetResult) writtenSheets(err error) []string {
+	if r == nil || r.DryRun || err != nil || r.Sheet == "" {
+		return nil
+	}
+	return []string{r.Sheet}
+}
+
+// sheetSummary describes a single-sheet job such as the course catalog. The
+// sheet counts as unwritten when the job failed.
+func sheetSummary(job, tenant string, result *SheetResult, err error) notifications.Summary {
+	summary := notifications.Summary{Job: job, Tenant: tenant}
+	if result != nil {
+		summary.RowsFetched = result.Rows
+		if err == nil {
+			summary.RowsWritten = result.Rows
+		}
+	}
+	return summary
+}
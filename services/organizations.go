@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// ReloadOrganizations reloads the organizations from the configured source
+// and replaces Config.Organizations with them. On failure the current
+// organizations are kept.
+func (c *JacadClient) ReloadOrganizations(ctx context.Context) ([]config.Organization, error) {
+	logger := logging.FromContext(ctx).With("source", c.Config.OrganizationsSource)
+
+	var orgs []config.Organization
+	if c.Config.OrganizationsSource == config.OrgSourceJacad {
+		fetched, err := fetchAllPagesOf[models.Organization](WithCacheBypass(ctx), c, c.Config.Endpoints["ORGANIZATIONS"], nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch organizations from Jacad: %w", err)
+		}
+		if len(fetched) == 0 {
+			return nil, fmt.Errorf("Jacad returned no organizations")
+		}
+		orgs = jacadOrganizations(fetched)
+		if err := c.Config.ApplyOrgSpreadsheets(orgs); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if orgs, err = c.Config.LoadOrganizations(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Config.Organizations.Replace(orgs); err != nil {
+		return nil, fmt.Errorf("invalid organizations: %w", err)
+	}
+	logger.Info("Organizations loaded", "organizations", len(orgs))
+	return c.Config.Organizations.All(), nil
+}
+
+// jacadOrganizations keeps the built-in keys of known organization ids, so
+// names such as EAD keep working in ORG_SPREADSHEETS and the CLI.
+func jacadOrganizations(fetched []models.Organization) []config.Organization {
+	keys := make(map[int]string)
+	for _, org := range config.BuiltinOrganizations() {
+		keys[org.ID] = org.Key
+	}
+
+	orgs := make([]config.Organization, 0, len(fetched))
+	for _, org := range fetched {
+		name := ""
+		if org.Nome != nil {
+			name = strings.TrimSpace(*org.Nome)
+		}
+		if name == "" {
+			name = fmt.Sprintf("Organização %d", org.IdOrg)
+		}
+		orgs = append(orgs, config.Organization{Key: keys[org.IdOrg], ID: org.IdOrg, Name: name})
+	}
+	return orgs
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+)
+
+func TestReloadOrganizationsFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orgs.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.Defaults()
+	cfg.OrganizationsSource = config.OrgSourceFile
+	cfg.OrganizationsFile = path
+	client := NewJacadClient(&cfg, NewFakeSheetWriter(), nil, nil, nil)
+
+	write("organizations:\n  - {key: EAD, id: 20, name: EAD}\n  - {id: 31, name: Escola Técnica}\n")
+	orgs, err := client.ReloadOrganizations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []config.Organization{{Key: "EAD", ID: 20, Name: "EAD"}, {Key: "ESCOLA_TÉCNICA", ID: 31, Name: "Escola Técnica"}}
+	if !reflect.DeepEqual(orgs, want) || !reflect.DeepEqual(cfg.Organizations.All(), want) {
+		t.Errorf("ReloadOrganizations() = %+v, want %+v", orgs, want)
+	}
+
+	write("organizations:\n  - {id: 20, name: EAD}\n  - {id: 20, name: EAD Novo}\n")
+	if _, err := client.ReloadOrganizations(context.Background()); err == nil {
+		t.Error("ReloadOrganizations() accepted duplicate ids")
+	}
+	if got := cfg.Organizations.All(); !reflect.DeepEqual(got, want) {
+		t.Errorf("organizations after a failed reload = %+v, want the previous ones", got)
+	}
+}
+
+func TestReloadOrganizationsFromJacad(t *testing.T) {
+	data := jacadmock.DemoDataset(30)
+	data.Organizations = append(data.Organizations, data.Organizations[0])
+	data.Organizations[len(data.Organizations)-1].IdOrg = 44
+	data.Organizations[len(data.Organizations)-1].Nome = nil
+	server := jacadmock.NewServer(jacadmock.Options{Data: data})
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	cfg.OrganizationsSource = config.OrgSourceJacad
+	cfg.OrgSpreadsheets = "POS_EAD:pos-book"
+	client := NewJacadClient(&cfg, NewFakeSheetWriter(), nil, nil, nil)
+
+	orgs, err := client.ReloadOrganizations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []config.Organization{
+		{Key: "POS_PRESENCIAL", ID: 9, Name: "PÓS Presencial"},
+		{Key: "POS_EAD", ID: 17, Name: "PÓS EAD", SpreadsheetID: "pos-book"},
+		{Key: "EAD", ID: 20, Name: "EAD"},
+		{Key: "ORGANIZAÇÃO_44", ID: 44, Name: "Organização 44"},
+	}
+	if !reflect.DeepEqual(orgs, want) {
+		t.Errorf("ReloadOrganizations() = %+v, want %+v", orgs, want)
+	}
+}
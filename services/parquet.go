@@ -0,0 +1,397 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Parquet is written with one row group per file and GZIP-compressed,
+// PLAIN-encoded data pages of up to parquetPageRows rows per column. Every
+// column is optional; empty cells of non-string columns are stored as nulls.
+
+var parquetMagic = []byte("PAR1")
+
+// parquetPageRows caps the rows of one data page, keeping pages well below
+// the int32 sizes their headers can describe.
+const parquetPageRows = 20000
+
+// Parquet physical types, converted types and enums from parquet.thrift.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMillis = 9
+
+	parquetOptional = 1
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetCodecGzip    = 2
+	parquetPageTypeData = 0
+)
+
+// parquetColumn is the storage type inferred for a column.
+type parquetColumn struct {
+	name          string
+	physicalType  int32
+	convertedType int32 // -1 when none
+}
+
+// inferParquetColumns types each column from its values: ints become INT64,
+// floats DOUBLE, bools BOOLEAN, time.Time INT64 TIMESTAMP_MILLIS and anything
+// else, or a mix, UTF8 strings.
+func inferParquetColumns(names []string, rows [][]interface{}) []parquetColumn {
+	columns := make([]parquetColumn, len(names))
+	for i, name := range names {
+		columns[i] = inferParquetColumn(name, i, rows)
+	}
+	return columns
+}
+
+// writeParquet encodes rows as a Parquet file with the given schema, splitting
+// each column into pages of at most pageRows rows.
+func writeParquet(w io.Writer, columns []parquetColumn, rows [][]interface{}, pageRows int) error {
+	var file bytes.Buffer
+	file.Write(parquetMagic)
+
+	chunks := make([]parquetChunk, len(columns))
+	var totalUncompressed int64
+	for i, col := range columns {
+		chunks[i].offset = int64(file.Len())
+		for start := 0; start == 0 || start < len(rows); start += pageRows {
+			page := rows[start:min(start+pageRows, len(rows))]
+			header, body, err := encodeParquetDataPage(col, i, page)
+			if err != nil {
+				return fmt.Errorf("failed to encode parquet column '%s': %w", col.name, err)
+			}
+			chunks[i].uncompressed += int64(len(header)) + int64(len(body.raw))
+			chunks[i].compressed += int64(len(header)) + int64(len(body.gzipped))
+			file.Write(header)
+			file.Write(body.gzipped)
+		}
+		totalUncompressed += chunks[i].uncompressed
+	}
+
+	footer := &thriftWriter{}
+	footer.begin()
+	footer.i32(1, 1)
+	footer.listBegin(2, thriftStruct, len(columns)+1)
+	footer.elemBegin()
+	footer.binary(4, "schema")
+	footer.i32(5, int32(len(columns)))
+	footer.elemEnd()
+	for _, col := range columns {
+		footer.elemBegin()
+		footer.i32(1, col.physicalType)
+		footer.i32(3, parquetOptional)
+		footer.binary(4, col.name)
+		if col.convertedType >= 0 {
+			footer.i32(6, col.convertedType)
+		}
+		footer.elemEnd()
+	}
+	footer.i64(3, int64(len(rows)))
+	footer.listBegin(4, thriftStruct, 1)
+	footer.elemBegin()
+	footer.listBegin(1, thriftStruct, len(columns))
+	for i, col := range columns {
+		footer.elemBegin()
+		footer.i64(2, chunks[i].offset)
+		footer.structBegin(3)
+		footer.i32(1, col.physicalType)
+		footer.listBegin(2, thriftI32, 2)
+		footer.listI32(parquetEncodingPlain)
+		footer.listI32(parquetEncodingRLE)
+		footer.listBegin(3, thriftBinary, 1)
+		footer.listBinary(col.name)
+		footer.i32(4, parquetCodecGzip)
+		footer.i64(5, int64(len(rows)))
+		footer.i64(6, chunks[i].uncompressed)
+		footer.i64(7, chunks[i].compressed)
+		footer.i64(9, chunks[i].offset)
+		footer.structEnd()
+		footer.elemEnd()
+	}
+	footer.i64(2, totalUncompressed)
+	footer.i64(3, int64(len(rows)))
+	footer.elemEnd()
+	footer.binary(6, "fetch-student-data")
+	footer.end()
+
+	file.Write(footer.buf.Bytes())
+	binary.Write(&file, binary.LittleEndian, uint32(footer.buf.Len()))
+	file.Write(parquetMagic)
+
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+type parquetChunk struct {
+	offset, uncompressed, compressed int64
+}
+
+type parquetPageBody struct {
+	raw, gzipped []byte
+}
+
+// encodeParquetDataPage returns the Thrift page header and body of one data
+// page holding rows.
+func encodeParquetDataPage(col parquetColumn, index int, rows [][]interface{}) ([]byte, parquetPageBody, error) {
+	raw, err := encodeParquetPage(col, index, rows)
+	if err != nil {
+		return nil, parquetPageBody{}, err
+	}
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(raw); err != nil {
+		return nil, parquetPageBody{}, fmt.Errorf("failed to compress page: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, parquetPageBody{}, fmt.Errorf("failed to compress page: %w", err)
+	}
+
+	header := &thriftWriter{}
+	header.begin()
+	header.i32(1, parquetPageTypeData)
+	header.i32(2, int32(len(raw)))
+	header.i32(3, int32(compressed.Len()))
+	header.structBegin(5)
+	header.i32(1, int32(len(rows)))
+	header.i32(2, parquetEncodingPlain)
+	header.i32(3, parquetEncodingRLE)
+	header.i32(4, parquetEncodingRLE)
+	header.structEnd()
+	header.end()
+	return header.buf.Bytes(), parquetPageBody{raw: raw, gzipped: compressed.Bytes()}, nil
+}
+
+func inferParquetColumn(name string, index int, rows [][]interface{}) parquetColumn {
+	col := parquetStringColumn(name)
+	kind := -1
+	for _, row := range rows {
+		value := parquetCell(row, index)
+		if value == nil || value == "" {
+			continue
+		}
+		k := parquetKind(value)
+		if kind == -1 {
+			kind = k
+		} else if k != kind {
+			return col
+		}
+	}
+	switch kind {
+	case parquetInt64:
+		col.physicalType, col.convertedType = parquetInt64, -1
+	case parquetDouble:
+		col.physicalType, col.convertedType = parquetDouble, -1
+	case parquetBoolean:
+		col.physicalType, col.convertedType = parquetBoolean, -1
+	case parquetConvertedTimestampMillis:
+		col.physicalType, col.convertedType = parquetInt64, parquetConvertedTimestampMillis
+	}
+	return col
+}
+
+func parquetStringColumn(name string) parquetColumn {
+	return parquetColumn{name: name, physicalType: parquetByteArray, convertedType: parquetConvertedUTF8}
+}
+
+// parquetKind classifies a cell; timestamps get their converted type so they
+// stay apart from plain INT64 columns.
+func parquetKind(value interface{}) int {
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32:
+		return parquetInt64
+	case float32, float64, SerialDate:
+		return parquetDouble
+	case bool:
+		return parquetBoolean
+	case time.Time:
+		return parquetConvertedTimestampMillis
+	}
+	return parquetByteArray
+}
+
+func parquetString(value interface{}) string {
+	if t, ok := value.(time.Time); ok {
+		return t.Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprint(value)
+}
+
+func parquetCell(row []interface{}, index int) interface{} {
+	if index >= len(row) {
+		return nil
+	}
+	return row[index]
+}
+
+// encodeParquetPage returns the uncompressed page body: the length-prefixed
+// definition levels followed by the PLAIN-encoded non-null values.
+func encodeParquetPage(col parquetColumn, index int, rows [][]interface{}) ([]byte, error) {
+	defined := make([]bool, len(rows))
+	var values bytes.Buffer
+	var bits []bool
+	for i, row := range rows {
+		value := parquetCell(row, index)
+		if value == nil || (value == "" && col.physicalType != parquetByteArray) {
+			continue
+		}
+		defined[i] = true
+		switch col.physicalType {
+		case parquetByteArray:
+			s := parquetString(value)
+			binary.Write(&values, binary.LittleEndian, uint32(len(s)))
+			values.WriteString(s)
+		case parquetInt64:
+			var n int64
+			if t, ok := value.(time.Time); ok {
+				n = t.UnixMilli()
+			} else {
+				parsed, err := strconv.ParseInt(fmt.Sprint(value), 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				n = parsed
+			}
+			binary.Write(&values, binary.LittleEndian, n)
+		case parquetDouble:
+			f, err := strconv.ParseFloat(fmt.Sprint(value), 64)
+			if err != nil {
+				return nil, err
+			}
+			binary.Write(&values, binary.LittleEndian, math.Float64bits(f))
+		case parquetBoolean:
+			bits = append(bits, value.(bool))
+		}
+	}
+	if col.physicalType == parquetBoolean {
+		packed := make([]byte, (len(bits)+7)/8)
+		for i, bit := range bits {
+			if bit {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		values.Write(packed)
+	}
+
+	levels := encodeDefinitionLevels(defined)
+	var page bytes.Buffer
+	binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+	page.Write(levels)
+	page.Write(values.Bytes())
+	return page.Bytes(), nil
+}
+
+// encodeDefinitionLevels encodes 1-bit definition levels as RLE runs of the
+// RLE/bit-packing hybrid encoding.
+func encodeDefinitionLevels(defined []bool) []byte {
+	var out []byte
+	for i := 0; i < len(defined); {
+		j := i
+		for j < len(defined) && defined[j] == defined[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		if defined[i] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		i = j
+	}
+	return out
+}
+
+// Thrift compact protocol type ids.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the Parquet metadata structs with the Thrift compact
+// protocol. Fields must be written in increasing id order within a struct.
+type thriftWriter struct {
+	buf bytes.Buffer
+	// last holds the previous field id of each open struct.
+	last []int16
+}
+
+func (t *thriftWriter) begin() { t.last = append(t.last, 0) }
+
+func (t *thriftWriter) end() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	top := len(t.last) - 1
+	if delta := id - t.last[top]; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.last[top] = id
+}
+
+func (t *thriftWriter) varint(v int64) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64((v<<1)^(v>>63))))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.listBinary(s)
+}
+
+func (t *thriftWriter) structBegin(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+func (t *thriftWriter) structEnd() { t.end() }
+
+func (t *thriftWriter) listBegin(id int16, elemType byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	t.buf.WriteByte(0xF0 | elemType)
+	t.buf.Write(binary.AppendUvarint(nil, uint64(size)))
+}
+
+func (t *thriftWriter) elemBegin() { t.begin() }
+
+func (t *thriftWriter) elemEnd() { t.end() }
+
+func (t *thriftWriter) listI32(v int32) { t.varint(int64(v)) }
+
+func (t *thriftWriter) listBinary(s string) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	t.buf.WriteString(s)
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestWriteParquetRoundTrip(t *testing.T) {
+	enrolled := time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+	names := []string{"nome", "idade", "media", "ativo", "dataMatricula", "misto"}
+	rows := [][]interface{}{
+		{"Ana", 20, 7.5, true, enrolled, "x"},
+		{"", nil, "", nil, nil, 3},
+		{"Bruno", int64(31), float32(8.25), false, enrolled.Add(time.Hour), ""},
+		{nil, 40},
+		{"Carla", uint16(18), 10.0, true, enrolled.AddDate(0, 1, 0), nil},
+	}
+	columns := inferParquetColumns(names, rows)
+
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, columns, rows, 2); err != nil {
+		t.Fatalf("writeParquet() error = %v", err)
+	}
+	file, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if got := file.NumRows(); got != int64(len(rows)) {
+		t.Fatalf("NumRows() = %d, want %d", got, len(rows))
+	}
+
+	wantTypes := map[string]parquet.Kind{
+		"nome":          parquet.ByteArray,
+		"idade":         parquet.Int64,
+		"media":         parquet.Double,
+		"ativo":         parquet.Boolean,
+		"dataMatricula": parquet.Int64,
+		"misto":         parquet.ByteArray,
+	}
+	index := make(map[string]int)
+	for _, name := range names {
+		leaf, ok := file.Schema().Lookup(name)
+		if !ok {
+			t.Fatalf("column %q missing from the schema", name)
+		}
+		if !leaf.Node.Optional() {
+			t.Errorf("column %q is not optional", name)
+		}
+		if got := leaf.Node.Type().Kind(); got != wantTypes[name] {
+			t.Errorf("column %q has type %v, want %v", name, got, wantTypes[name])
+		}
+		index[name] = leaf.ColumnIndex
+	}
+
+	rowGroup := file.RowGroups()[0]
+	for _, chunk := range rowGroup.ColumnChunks() {
+		pages := chunk.Pages()
+		count := 0
+		for {
+			_, err := pages.ReadPage()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("ReadPage() column %d error = %v", chunk.Column(), err)
+			}
+			count++
+		}
+		pages.Close()
+		if count != 3 {
+			t.Errorf("column %d has %d pages, want 3", chunk.Column(), count)
+		}
+	}
+
+	got := make([]parquet.Row, len(rows))
+	reader := rowGroup.Rows()
+	defer reader.Close()
+	if n, err := reader.ReadRows(got); n != len(rows) || (err != nil && !errors.Is(err, io.EOF)) {
+		t.Fatalf("ReadRows() = %d, %v; want %d rows", n, err, len(rows))
+	}
+
+	want := []map[string]interface{}{
+		{"nome": "Ana", "idade": int64(20), "media": 7.5, "ativo": true, "dataMatricula": enrolled.UnixMilli(), "misto": "x"},
+		{"nome": "", "idade": nil, "media": nil, "ativo": nil, "dataMatricula": nil, "misto": "3"},
+		{"nome": "Bruno", "idade": int64(31), "media": 8.25, "ativo": false, "dataMatricula": enrolled.Add(time.Hour).UnixMilli(), "misto": ""},
+		{"nome": nil, "idade": int64(40), "media": nil, "ativo": nil, "dataMatricula": nil, "misto": nil},
+		{"nome": "Carla", "idade": int64(18), "media": 10.0, "ativo": true, "dataMatricula": enrolled.AddDate(0, 1, 0).UnixMilli(), "misto": nil},
+	}
+	for r, row := range got {
+		for _, name := range names {
+			value := row[index[name]]
+			var cell interface{}
+			switch {
+			case value.IsNull():
+			case wantTypes[name] == parquet.ByteArray:
+				cell = string(value.ByteArray())
+			case wantTypes[name] == parquet.Int64:
+				cell = value.Int64()
+			case wantTypes[name] == parquet.Double:
+				cell = value.Double()
+			case wantTypes[name] == parquet.Boolean:
+				cell = value.Boolean()
+			}
+			if cell != want[r][name] {
+				t.Errorf("row %d column %q = %#v, want %#v", r, name, cell, want[r][name])
+			}
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+const (
+	parquetContentType = "application/vnd.apache.parquet"
+	// hiveDefaultPartition is the Hive name for a partition with no value.
+	hiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+)
+
+var errParquetNotSupported = errors.New("not supported by the parquet writer, which only overwrites whole files")
+
+// objectStore stores whole files under slash-separated keys.
+type objectStore interface {
+	Put(ctx context.Context, key, contentType string, body []byte) error
+}
+
+// ParquetWriter implements SheetWriter with Parquet files for data lake
+// ingestion. Enrollments are partitioned Hive-style under
+// enrollments/idOrg=<id>/periodoLetivo=<period>/, one file per sheet, so
+// rerunning a fetch replaces its files; other sheets become a single file.
+// Columns are named after the enrollment fields rather than the sheet headers.
+type ParquetWriter struct {
+	store  objectStore
+	prefix string
+}
+
+// NewParquetWriter writes under output, which is a local directory or a
+// gs://bucket/prefix or s3://bucket/prefix URL. The S3 settings are only used
+// for s3:// outputs.
+func NewParquetWriter(ctx context.Context, output string, creds GoogleCredentials, s3Region, s3Endpoint, accessKeyID, secretAccessKey, sessionToken string) (*ParquetWriter, error) {
+	scheme, rest, isURL := strings.Cut(output, "://")
+	if !isURL {
+		if output == "" {
+			output = "."
+		}
+		return &ParquetWriter{store: localObjectStore{dir: output}}, nil
+	}
+
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("parquet output '%s' has no bucket", output)
+	}
+	w := &ParquetWriter{prefix: strings.Trim(prefix, "/")}
+	var err error
+	switch scheme {
+	case "gs":
+		w.store, err = NewGCSArchiver(ctx, bucket, creds)
+	case "s3":
+		w.store, err = NewS3Archiver(bucket, s3Region, s3Endpoint, accessKeyID, secretAccessKey, sessionToken)
+	default:
+		return nil, fmt.Errorf("unsupported parquet output '%s': expected a directory, gs:// or s3:// URL", output)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// WriteEnrollments writes data partitioned by idOrg and periodoLetivo.
+func (w *ParquetWriter) WriteEnrollments(ctx context.Context, sheetName string, mapper *EnrollmentRowMapper, data []models.Enrollment) error {
+	partitions := make(map[string][]models.Enrollment)
+	for _, item := range data {
+		period := hiveDefaultPartition
+		if item.PeriodoLetivo != nil && strings.TrimSpace(*item.PeriodoLetivo) != "" {
+			period = hivePartitionValue(strings.TrimSpace(*item.PeriodoLetivo))
+		}
+		dir := path.Join("enrollments", "idOrg="+strconv.Itoa(item.OrgID), "periodoLetivo="+period)
+		partitions[dir] = append(partitions[dir], item)
+	}
+	dirs := make([]string, 0, len(partitions))
+	for dir := range partitions {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	columns := enrollmentParquetColumns(mapper)
+	for _, dir := range dirs {
+		key := path.Join(dir, parquetFileName(sheetName))
+		if err := w.put(ctx, key, columns, mapper.Rows(partitions[dir])); err != nil {
+			return err
+		}
+	}
+	logging.FromContext(ctx).Info("Parquet partitions written", "sheet", sheetName, "partitions", len(dirs), "rows", len(data))
+	return nil
+}
+
+// enrollmentParquetColumns types each column after its enrollment field, so
+// every partition gets the same schema. Redacted, computed and coerced
+// columns are strings.
+func enrollmentParquetColumns(mapper *EnrollmentRowMapper) []parquetColumn {
+	fields := mapper.Fields()
+	columns := make([]parquetColumn, len(fields))
+	for i, field := range fields {
+		columns[i] = parquetStringColumn(field)
+		if mapper.redactors != nil && mapper.redactors[i] != nil {
+			continue
+		}
+		if mapper.computes != nil && mapper.computes[i] != nil {
+			continue
+		}
+		if mapper.coercers != nil && mapper.coercers[i] != nil {
+			continue
+		}
+		switch enrollmentType.Field(enrollmentFields[field]).Type {
+		case reflect.TypeOf(0):
+			columns[i].physicalType, columns[i].convertedType = parquetInt64, -1
+		case reflect.TypeOf((*utils.Date)(nil)):
+			columns[i].physicalType, columns[i].convertedType = parquetInt64, parquetConvertedTimestampMillis
+		}
+	}
+	return columns
+}
+
+var enrollmentType = reflect.TypeOf(models.Enrollment{})
+
+// OverwriteSheetData writes rows to a single file named after sheetName.
+func (w *ParquetWriter) OverwriteSheetData(ctx context.Context, sheetName string, headers []string, rows [][]interface{}) error {
+	return w.put(ctx, parquetFileName(sheetName), inferParquetColumns(headers, rows), rows)
+}
+
+func (w *ParquetWriter) put(ctx context.Context, key string, columns []parquetColumn, rows [][]interface{}) error {
+	if w.prefix != "" {
+		key = w.prefix + "/" + key
+	}
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, columns, rows, parquetPageRows); err != nil {
+		return fmt.Errorf("failed to encode parquet file '%s': %w", key, err)
+	}
+	if err := w.store.Put(ctx, key, parquetContentType, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write parquet file '%s': %w", key, err)
+	}
+	return nil
+}
+
+func (w *ParquetWriter) EnsureSheetExists(ctx context.Context, sheetName string) error {
+	return nil
+}
+
+func (w *ParquetWriter) Clear(ctx context.Context, sheetName string) error {
+	return nil
+}
+
+func (w *ParquetWriter) SetHeaders(ctx context.Context, sheetName string, headers []string) error {
+	return fmt.Errorf("setting headers on '%s' is %w", sheetName, errParquetNotSupported)
+}
+
+func (w *ParquetWriter) AppendRows(ctx context.Context, sheetName string, rows [][]interface{}) error {
+	return fmt.Errorf("appending to '%s' is %w", sheetName, errParquetNotSupported)
+}
+
+func (w *ParquetWriter) UpsertRows(ctx context.Context, sheetName string, headers []string, keyColumn string, rows [][]interface{}) error {
+	return fmt.Errorf("upserting into '%s' is %w", sheetName, errParquetNotSupported)
+}
+
+// parquetFileName turns a sheet name such as "Matrículas EAD STATUS: ATIVA"
+// into "matriculas-ead-status-ativa.parquet".
+func parquetFileName(sheetName string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(sheetName) {
+		if folded, ok := accentFolds[r]; ok {
+			r = folded
+		}
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "-")
+	if name == "" {
+		name = "sheet"
+	}
+	return name + ".parquet"
+}
+
+var accentFolds = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ç': 'c', 'ñ': 'n',
+}
+
+// hivePartitionValue escapes the characters Hive does not allow in partition
+// directory names, such as the slash in "2024/1".
+func hivePartitionValue(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 0x20 || c >= 0x7f || strings.IndexByte("\"#%'*/:=?\\{[]^", c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// localObjectStore writes keys as files under dir, replacing them atomically.
+type localObjectStore struct {
+	dir string
+}
+
+func (s localObjectStore) Put(ctx context.Context, key, contentType string, body []byte) error {
+	target := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for '%s': %w", target, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".parquet-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for '%s': %w", target, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write '%s': %w", target, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write '%s': %w", target, err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to replace '%s': %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// periodNamesRetry spaces out listings of the process notices made because a
+// period was missing from the cached ones.
+const periodNamesRetry = 5 * time.Minute
+
+// periodName names the periods a fetch asks for, e.g. "2024/2", from the
+// cached process notices. It falls back to "Período ID 87" when a period has
+// no notice or the notices cannot be listed, so sheet names never depend on
+// the lookup succeeding.
+func (c *JacadClient) periodName(ctx context.Context, params *requests.FetchEnrollmentsRequest) string {
+	fallback := "Período ID " + params.PeriodLabel()
+	ids := params.PeriodIDs()
+	if len(ids) == 0 {
+		return fallback
+	}
+
+	names, ok := c.lookupPeriodNames(ctx, ids, false)
+	if !ok {
+		if age, fetched := c.metadata(ctx).notices.age(time.Now()); fetched && age >= periodNamesRetry {
+			names, ok = c.lookupPeriodNames(ctx, ids, true)
+		}
+	}
+	if !ok {
+		return fallback
+	}
+	return strings.Join(names, ",")
+}
+
+// lookupPeriodNames returns the periodoLetivo of each of ids, in order, and
+// whether all of them were found.
+func (c *JacadClient) lookupPeriodNames(ctx context.Context, ids []int, force bool) ([]string, bool) {
+	notices, err := c.cachedNotices(ctx, force)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to resolve period names, naming sheets by period ID", "error", err)
+		return nil, false
+	}
+	return periodNames(notices, ids)
+}
+
+func periodNames(notices []models.Period, ids []int) ([]string, bool) {
+	byID := make(map[int]string, len(notices))
+	for _, notice := range notices {
+		if name := strings.TrimSpace(notice.PeriodoLetivo); name != "" {
+			byID[notice.IDPeriodoLetivo] = name
+		}
+	}
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		name, ok := byID[id]
+		if !ok {
+			return nil, false
+		}
+		names[i] = name
+	}
+	return names, true
+}
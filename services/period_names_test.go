@@ -0,0 +1,36 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+func TestPeriodNames(t *testing.T) {
+	notices := []models.Period{
+		{IDPeriodoLetivo: 86, PeriodoLetivo: "2024/1"},
+		{IDPeriodoLetivo: 87, PeriodoLetivo: " 2024/2 "},
+		{IDPeriodoLetivo: 87, PeriodoLetivo: "2024/2"},
+		{IDPeriodoLetivo: 88},
+	}
+	tests := []struct {
+		name   string
+		ids    []int
+		want   []string
+		wantOK bool
+	}{
+		{name: "one period", ids: []int{87}, want: []string{"2024/2"}, wantOK: true},
+		{name: "several in order", ids: []int{87, 86}, want: []string{"2024/2", "2024/1"}, wantOK: true},
+		{name: "unnamed period", ids: []int{86, 88}},
+		{name: "unknown period", ids: []int{99}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := periodNames(notices, tt.ids)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("periodNames(%v) = %v, %v, want %v, %v", tt.ids, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// FetchPeriods lists the process notices (editais) with a status in
+// Config.EditalStatus, each tied to a periodo letivo. orgID keeps the notices
+// of one organization; 0 keeps all. Notices are ordered by ID.
+func (c *JacadClient) FetchPeriods(ctx context.Context, orgID int) ([]models.Period, error) {
+	seen := make(map[int]bool)
+	var periods []models.Period
+	for _, status := range c.Config.EditalStatus {
+		query := map[string]string{"statusEdital": status}
+		if orgID != 0 {
+			query["idOrg"] = strconv.Itoa(orgID)
+		}
+		fetched, err := fetchAllPagesOf[models.Period](ctx, c, c.Config.Endpoints["PROCESS_NOTICES"], query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch process notices with status '%s': %w", status, err)
+		}
+		for _, period := range fetched {
+			if (orgID != 0 && period.OrgID != orgID) || seen[period.IDEdital] {
+				continue
+			}
+			seen[period.IDEdital] = true
+			periods = append(periods, period)
+		}
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].IDEdital < periods[j].IDEdital })
+	return periods, nil
+}
+
+// PeriodSummary is one periodo letivo with open process notices.
+type PeriodSummary struct {
+	IdPeriodoLetivo int    `json:"idPeriodoLetivo"`
+	PeriodoLetivo   string `json:"periodoLetivo"`
+	// Status is the statusEdital of the period's notices that comes first in
+	// EDITAL_STATUS.
+	Status      string `json:"status"`
+	IdOrg       int    `json:"idOrg"`
+	Organizacao string `json:"organizacao"`
+	// Editais lists the IDs of the period's process notices.
+	Editais []int `json:"editais"`
+}
+
+// ListPeriods groups the cached notices found by FetchPeriods by
+// organization and periodo letivo, newest period first, for callers choosing
+// which period to fetch. orgID keeps the periods of one organization; 0 keeps
+// all.
+func (c *JacadClient) ListPeriods(ctx context.Context, orgID int) ([]PeriodSummary, error) {
+	notices, err := c.orgNotices(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return summarizePeriods(notices, c.Config.EditalStatus), nil
+}
+
+// orgNotices returns the cached process notices of orgID, or of every
+// organization for 0. Callers bypassing the response cache get a fresh list.
+func (c *JacadClient) orgNotices(ctx context.Context, orgID int) ([]models.Period, error) {
+	notices, err := c.cachedNotices(ctx, cacheBypassed(ctx))
+	if err != nil || orgID == 0 {
+		return notices, err
+	}
+	kept := make([]models.Period, 0, len(notices))
+	for _, notice := range notices {
+		if notice.OrgID == orgID {
+			kept = append(kept, notice)
+		}
+	}
+	return kept, nil
+}
+
+func summarizePeriods(notices []models.Period, statuses []string) []PeriodSummary {
+	rank := func(status string) int {
+		for i, s := range statuses {
+			if strings.EqualFold(s, status) {
+				return i
+			}
+		}
+		return len(statuses)
+	}
+
+	type periodKey struct{ org, period int }
+	index := make(map[periodKey]int)
+	periods := make([]PeriodSummary, 0)
+	for _, notice := range notices {
+		key := periodKey{notice.OrgID, notice.IDPeriodoLetivo}
+		i, ok := index[key]
+		if !ok {
+			i = len(periods)
+			index[key] = i
+			periods = append(periods, PeriodSummary{
+				IdPeriodoLetivo: notice.IDPeriodoLetivo,
+				PeriodoLetivo:   notice.PeriodoLetivo,
+				Status:          notice.StatusEdital,
+				IdOrg:           notice.OrgID,
+				Organizacao:     notice.Organizacao,
+			})
+		}
+		period := &periods[i]
+		period.Editais = append(period.Editais, notice.IDEdital)
+		if rank(notice.StatusEdital) < rank(period.Status) {
+			period.Status = notice.StatusEdital
+		}
+	}
+	sort.SliceStable(periods, func(i, j int) bool {
+		if periods[i].IdPeriodoLetivo != periods[j].IdPeriodoLetivo {
+			return periods[i].IdPeriodoLetivo > periods[j].IdPeriodoLetivo
+		}
+		return periods[i].IdOrg < periods[j].IdOrg
+	})
+	return periods
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+func TestSummarizePeriods(t *testing.T) {
+	notices := []models.Period{
+		{IDEdital: 1, OrgID: 20, IDPeriodoLetivo: 41, PeriodoLetivo: "2024/2", StatusEdital: "AGUARDANDO"},
+		{IDEdital: 2, OrgID: 20, IDPeriodoLetivo: 42, PeriodoLetivo: "2025/1", StatusEdital: "AGUARDANDO"},
+		{IDEdital: 3, OrgID: 20, IDPeriodoLetivo: 42, PeriodoLetivo: "2025/1", StatusEdital: "ABERTO"},
+		{IDEdital: 4, OrgID: 10, IDPeriodoLetivo: 42, PeriodoLetivo: "2025/1", StatusEdital: "AGUARDANDO"},
+	}
+	want := []PeriodSummary{
+		{IdPeriodoLetivo: 42, PeriodoLetivo: "2025/1", Status: "AGUARDANDO", IdOrg: 10, Editais: []int{4}},
+		{IdPeriodoLetivo: 42, PeriodoLetivo: "2025/1", Status: "ABERTO", IdOrg: 20, Editais: []int{2, 3}},
+		{IdPeriodoLetivo: 41, PeriodoLetivo: "2024/2", Status: "AGUARDANDO", IdOrg: 20, Editais: []int{1}},
+	}
+
+	got := summarizePeriods(notices, []string{"ABERTO", "AGUARDANDO"})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summarizePeriods() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// pipelineQueueLength is how many chunks may wait between two stages before
+// the earlier one blocks, whatever the memory budget.
+const pipelineQueueLength = 4
+
+// memoryBudget is a semaphore over estimated bytes. A request larger than
+// the whole budget is let through once nothing else is held, so oversized
+// chunks slow the pipeline down instead of stalling it. A zero limit never
+// blocks.
+type memoryBudget struct {
+	mu    sync.Mutex
+	limit int64
+	used  int64
+	// freed is closed and replaced whenever memory is released.
+	freed chan struct{}
+}
+
+func newMemoryBudget(limit int64) *memoryBudget {
+	return &memoryBudget{limit: limit, freed: make(chan struct{})}
+}
+
+// acquire blocks until n bytes fit in the budget or ctx is done, and
+// reports whether it had to wait.
+func (b *memoryBudget) acquire(ctx context.Context, n int64) (bool, error) {
+	waited := false
+	for {
+		b.mu.Lock()
+		if b.limit <= 0 || b.used == 0 || b.used+n <= b.limit {
+			b.used += n
+			b.mu.Unlock()
+			pipelineBytesHeld.Add(float64(n))
+			return waited, nil
+		}
+		freed := b.freed
+		b.mu.Unlock()
+
+		waited = true
+		select {
+		case <-ctx.Done():
+			return waited, ctx.Err()
+		case <-freed:
+		}
+	}
+}
+
+func (b *memoryBudget) release(n int64) {
+	b.mu.Lock()
+	b.used -= n
+	close(b.freed)
+	b.freed = make(chan struct{})
+	b.mu.Unlock()
+	pipelineBytesHeld.Add(float64(-n))
+}
+
+// enrollmentPipeline runs the map and write stages of a streamed fetch on
+// their own goroutines, so the next batch of pages is fetched while the
+// previous one is written. Chunks hold their estimated size of the memory
+// budget from the moment the fetch hands them over until they are written;
+// when the budget is used up the fetch waits, which keeps memory bounded
+// however large the dataset.
+type enrollmentPipeline[M any] struct {
+	ctx    context.Context
+	budget *memoryBudget
+	mapped chan pipelineChunk[M]
+	input  chan pipelineChunk[[]models.Enrollment]
+	done   chan struct{}
+	// waits counts the chunks the fetch had to hold back for memory.
+	waits int
+}
+
+type pipelineChunk[T any] struct {
+	data  T
+	bytes int64
+}
+
+// newEnrollmentPipeline starts the stages: mapStage turns fetched
+// enrollments into what writeStage writes. Each stage sees chunks in fetch
+// order on a single goroutine, so neither needs locking. Close must be
+// called once the fetch is over.
+func newEnrollmentPipeline[M any](ctx context.Context, limitBytes int64, mapStage func([]models.Enrollment) M, writeStage func(M)) *enrollmentPipeline[M] {
+	p := &enrollmentPipeline[M]{
+		ctx:    ctx,
+		budget: newMemoryBudget(limitBytes),
+		input:  make(chan pipelineChunk[[]models.Enrollment], pipelineQueueLength),
+		mapped: make(chan pipelineChunk[M], pipelineQueueLength),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(p.mapped)
+		for chunk := range p.input {
+			p.mapped <- pipelineChunk[M]{data: mapStage(chunk.data), bytes: chunk.bytes}
+		}
+	}()
+	go func() {
+		defer close(p.done)
+		for chunk := range p.mapped {
+			writeStage(chunk.data)
+			p.budget.release(chunk.bytes)
+		}
+	}()
+	return p
+}
+
+// Sink hands data to the map stage, first waiting for room in the memory
+// budget. It fails only when the context is done.
+func (p *enrollmentPipeline[M]) Sink(data []models.Enrollment) error {
+	if len(data) == 0 {
+		return nil
+	}
+	bytes := estimateEnrollmentBytes(data)
+	waited, err := p.budget.acquire(p.ctx, bytes)
+	if err != nil {
+		return err
+	}
+	if waited {
+		p.waits++
+	}
+	select {
+	case p.input <- pipelineChunk[[]models.Enrollment]{data: data, bytes: bytes}:
+		return nil
+	case <-p.ctx.Done():
+		p.budget.release(bytes)
+		return p.ctx.Err()
+	}
+}
+
+// Close lets the stages drain what was handed to them and waits for the
+// write stage to finish.
+func (p *enrollmentPipeline[M]) Close() {
+	close(p.input)
+	<-p.done
+}
+
+// enrollmentOverhead approximates what an enrollment costs besides its
+// string contents: the struct, the string headers and the four dates.
+const enrollmentOverhead = 600
+
+// estimateEnrollmentBytes approximates the memory data takes until written,
+// counting the mapped rows, which hold about as much again.
+func estimateEnrollmentBytes(data []models.Enrollment) int64 {
+	var total int64
+	for i := range data {
+		e := &data[i]
+		total += enrollmentOverhead
+		for _, s := range []*string{e.Aluno, e.RA, e.Curso, e.Turma, e.Status, e.PeriodoLetivo, e.UnidadeFisica, e.Organizacao, e.Modalidade, e.Polo, e.FormaIngresso, e.Turno, e.CPF, e.Email, e.Telefone} {
+			if s != nil {
+				total += int64(len(*s))
+			}
+		}
+	}
+	return 2 * total
+}
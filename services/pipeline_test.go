@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// TestFetchEnrollmentsFromMockJacad runs a whole fetch, login to written
+// rows, against the mock Jacad server.
+func TestFetchEnrollmentsFromMockJacad(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.PageSize = 50
+	cfg.MaxPagesPerBatch = 2
+	cfg.RetryDelay = 0
+	writer := NewDiscardWriter()
+	client := NewJacadClient(&cfg, writer, NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+
+	result, err := client.FetchEnrollmentsFiltered(context.Background(), &requests.FetchEnrollmentsRequest{
+		OrgIds:          "20,17",
+		IdPeriodoLetivo: 87,
+		StatusMatricula: "ATIVA",
+		Mode:            requests.SyncModeFull,
+		WriteMode:       requests.WriteModeAtomic,
+		GroupBy:         requests.GroupByNone,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 720 enrollments: a quarter are period 87 with status ATIVA, split
+	// evenly across the three organizations.
+	if result.TotalFetched != 180 {
+		t.Errorf("fetched %d enrollments, want 180", result.TotalFetched)
+	}
+	want := map[string]int{
+		"Matrículas EAD STATUS: ATIVA | 2025/1":     60,
+		"Matrículas PÓS EAD STATUS: ATIVA | 2025/1": 60,
+	}
+	if len(result.Organizations) != len(want) {
+		t.Fatalf("wrote %d sheets, want %d", len(result.Organizations), len(want))
+	}
+	for _, org := range result.Organizations {
+		written, _ := writer.CountRows(context.Background(), org.Sheet)
+		if org.Error != "" || want[org.Sheet] != org.Rows || written != org.Rows {
+			t.Errorf("sheet %q: %d rows reported, %d written, error %q; want %d", org.Sheet, org.Rows, written, org.Error, want[org.Sheet])
+		}
+	}
+	if logins := server.Requests("AUTH"); logins != 1 {
+		t.Errorf("logged in %d times, want once", logins)
+	}
+}
+
+// TestFetchEnrollmentsPageRange fetches a slice of the pages of a query.
+func TestFetchEnrollmentsPageRange(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.PageSize = 50
+	cfg.MaxPagesPerBatch = 2
+	cfg.RetryDelay = 0
+	client := NewJacadClient(&cfg, NewDiscardWriter(), NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+
+	// 180 enrollments of period 87 with status ATIVA make four pages, the
+	// last one holding 30.
+	tests := []struct {
+		name        string
+		start, end  int
+		maxPages    int
+		wantFetched int
+		wantPages   int
+	}{
+		{name: "middle pages", start: 1, maxPages: 2, wantFetched: 100, wantPages: 2},
+		{name: "up to the last page", start: 2, wantFetched: 80, wantPages: 2},
+		{name: "end past the last page", start: 3, end: 10, wantFetched: 30, wantPages: 1},
+		{name: "first page only", end: 1, wantFetched: 50, wantPages: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := server.Requests("ENROLLMENTS")
+			result, err := client.FetchEnrollmentsFiltered(context.Background(), &requests.FetchEnrollmentsRequest{
+				IdPeriodoLetivo: 87,
+				StatusMatricula: "ATIVA",
+				DryRun:          true,
+				StartPage:       tt.start,
+				EndPage:         tt.end,
+				MaxPages:        tt.maxPages,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.TotalFetched != tt.wantFetched {
+				t.Errorf("fetched %d enrollments, want %d", result.TotalFetched, tt.wantFetched)
+			}
+			if pages := server.Requests("ENROLLMENTS") - before; pages != tt.wantPages {
+				t.Errorf("requested %d pages, want %d", pages, tt.wantPages)
+			}
+		})
+	}
+
+	_, err := client.FetchEnrollmentsFiltered(context.Background(), &requests.FetchEnrollmentsRequest{
+		IdPeriodoLetivo: 87,
+		StatusMatricula: "ATIVA",
+		DryRun:          true,
+		StartPage:       4,
+	})
+	if err == nil {
+		t.Error("startPage past the last page succeeded")
+	}
+}
+
+// TestFetchEnrollmentsPageRangeKeepsSyncState checks a page range does not
+// mark the sheet synced, in either write mode.
+func TestFetchEnrollmentsPageRangeKeepsSyncState(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer server.Close()
+
+	for _, writeMode := range []string{requests.WriteModeAtomic, requests.WriteModeStream} {
+		t.Run(writeMode, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := config.Defaults()
+			cfg.APIBase = server.URL
+			cfg.UserToken = server.UserToken
+			cfg.PageSize = 50
+			cfg.RetryDelay = 0
+			state := NewFileSyncStateStore(filepath.Join(dir, "sync_state.json"))
+			client := NewJacadClient(&cfg, NewFakeSheetWriter(), state, NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+
+			result, err := client.FetchEnrollmentsFiltered(context.Background(), &requests.FetchEnrollmentsRequest{
+				IdPeriodoLetivo: 87,
+				StatusMatricula: "ATIVA",
+				Mode:            requests.SyncModeFull,
+				WriteMode:       writeMode,
+				GroupBy:         requests.GroupByNone,
+				StartPage:       1,
+				MaxPages:        1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Organizations) == 0 {
+				t.Fatal("no sheet written")
+			}
+			for _, org := range result.Organizations {
+				if saved, err := state.Load(client.syncStateKey(context.Background(), org.Sheet)); err != nil || saved != nil {
+					t.Errorf("sync state of %q = %+v, %v; want none saved", org.Sheet, saved, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryBudget(t *testing.T) {
+	budget := newMemoryBudget(100)
+	ctx := context.Background()
+
+	if waited, err := budget.acquire(ctx, 60); waited || err != nil {
+		t.Fatalf("acquire(60) = %v, %v, want no wait", waited, err)
+	}
+	if waited, err := budget.acquire(ctx, 40); waited || err != nil {
+		t.Fatalf("acquire(40) = %v, %v, want no wait", waited, err)
+	}
+
+	acquired := make(chan bool)
+	go func() {
+		waited, err := budget.acquire(ctx, 50)
+		acquired <- waited && err == nil
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquire(50) returned while the budget was used up")
+	case <-time.After(20 * time.Millisecond):
+	}
+	budget.release(60)
+	if ok := <-acquired; !ok {
+		t.Error("acquire(50) after release did not report waiting")
+	}
+	budget.release(90)
+
+	// Chunks larger than the whole budget pass once nothing else is held.
+	if _, err := budget.acquire(ctx, 500); err != nil {
+		t.Fatalf("acquire(500) on an empty budget = %v", err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := budget.acquire(cancelled, 1); err == nil {
+		t.Error("acquire on a cancelled context while the budget is used up did not fail")
+	}
+}
+
+func TestEnrollmentPipelineBackpressure(t *testing.T) {
+	chunk := func(first, n int) []models.Enrollment {
+		data := make([]models.Enrollment, n)
+		for i := range data {
+			data[i].IdMatricula = first + i
+		}
+		return data
+	}
+	limit := estimateEnrollmentBytes(chunk(0, 10)) * 2
+
+	unblock := make(chan struct{})
+	var written []int
+	pipeline := newEnrollmentPipeline(context.Background(), limit,
+		func(data []models.Enrollment) []int {
+			ids := make([]int, len(data))
+			for i, e := range data {
+				ids[i] = e.IdMatricula
+			}
+			return ids
+		},
+		func(ids []int) {
+			<-unblock
+			written = append(written, ids...)
+		},
+	)
+
+	// Two chunks fill the budget; the third waits for the writer.
+	for i := range 2 {
+		if err := pipeline.Sink(chunk(i*10, 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	third := make(chan error)
+	go func() { third <- pipeline.Sink(chunk(20, 10)) }()
+	select {
+	case <-third:
+		t.Fatal("Sink returned while the memory budget was used up")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(unblock)
+	if err := <-third; err != nil {
+		t.Fatal(err)
+	}
+	pipeline.Close()
+
+	if pipeline.waits != 1 {
+		t.Errorf("waits = %d, want 1", pipeline.waits)
+	}
+	if len(written) != 30 || !slices.IsSorted(written) {
+		t.Errorf("written = %v, want 30 IDs in fetch order", written)
+	}
+}
+
+// TestFetchEnrollmentsRoutesOrgSpreadsheets writes each organization's sheet
+// to the spreadsheet ORG_SPREADSHEETS gives it, or to the default one.
+func TestFetchEnrollmentsRoutesOrgSpreadsheets(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.PageSize = 50
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	cfg.SpreadsheetID = "book"
+	cfg.OrgSpreadsheets = "EAD:ead-book"
+	orgs := config.BuiltinOrganizations()
+	if err := cfg.ApplyOrgSpreadsheets(orgs); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Organizations = config.NewOrgDirectory(orgs)
+	writer := NewFakeSheetWriter()
+	client := NewJacadClient(&cfg, writer, NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+
+	for _, writeMode := range []string{requests.WriteModeAtomic, requests.WriteModeStream} {
+		t.Run(writeMode, func(t *testing.T) {
+			before := len(writer.Calls())
+			_, err := client.FetchEnrollmentsFiltered(context.Background(), &requests.FetchEnrollmentsRequest{
+				OrgIds:          "20,17",
+				IdPeriodoLetivo: 87,
+				StatusMatricula: "ATIVA",
+				Mode:            requests.SyncModeFull,
+				WriteMode:       writeMode,
+				GroupBy:         requests.GroupByNone,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]string{
+				"Matrículas EAD STATUS: ATIVA | 2025/1":     "ead-book",
+				"Matrículas PÓS EAD STATUS: ATIVA | 2025/1": "",
+			}
+			written := make(map[string]bool)
+			for _, call := range writer.Calls()[before:] {
+				spreadsheet, ok := want[call.Sheet]
+				if ok && call.Spreadsheet != spreadsheet {
+					t.Errorf("%s of %q went to spreadsheet %q, want %q", call.Method, call.Sheet, call.Spreadsheet, spreadsheet)
+				}
+				written[call.Sheet] = ok
+			}
+			for sheet := range want {
+				if !written[sheet] {
+					t.Errorf("sheet %q not written", sheet)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+)
+
+// Progress stages reported for a fetch job.
+const (
+	ProgressStageFetching = "fetching"
+	ProgressStageWriting  = "writing"
+	ProgressStageDone     = "done"
+	ProgressStageFailed   = "failed"
+)
+
+// finishedJobRetention is how long a finished job's last event stays available
+// to late subscribers.
+const finishedJobRetention = 5 * time.Minute
+
+// ProgressEvent is a snapshot of a fetch job's progress.
+type ProgressEvent struct {
+	JobID        string  `json:"jobId"`
+	Stage        string  `json:"stage"`
+	PagesFetched int     `json:"pagesFetched"`
+	TotalPages   int     `json:"totalPages"`
+	RowsFetched  int     `json:"rowsFetched"`
+	RowsWritten  int     `json:"rowsWritten"`
+	ETASeconds   float64 `json:"etaSeconds,omitempty"`
+	// Deadline is when the job times out, if it has a timeout.
+	Deadline  *time.Time `json:"deadline,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	StartedAt time.Time  `json:"startedAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// Finished reports whether the job has reached a final stage.
+func (e ProgressEvent) Finished() bool {
+	return e.Stage == ProgressStageDone || e.Stage == ProgressStageFailed
+}
+
+type progressJob struct {
+	latest      ProgressEvent
+	subscribers map[chan ProgressEvent]struct{}
+}
+
+// ProgressHub keeps the latest progress of each running job, keyed on the
+// job ID, and fans updates out to subscribers.
+type ProgressHub struct {
+	mu   sync.Mutex
+	jobs map[string]*progressJob
+}
+
+func NewProgressHub() *ProgressHub {
+	return &ProgressHub{jobs: make(map[string]*progressJob)}
+}
+
+// Latest returns the job's most recent snapshot; ok is false when jobID is
+// unknown.
+func (h *ProgressHub) Latest(jobID string) (event ProgressEvent, ok bool) {
+	if h == nil {
+		return ProgressEvent{}, false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	job, ok := h.jobs[jobID]
+	if !ok {
+		return ProgressEvent{}, false
+	}
+	return job.latest, true
+}
+
+// Subscribe returns a channel that first receives the job's current snapshot
+// and then every update. Slow subscribers only miss intermediate snapshots,
+// never the newest one. The channel is closed when the job finishes. ok is
+// false when jobID is unknown.
+func (h *ProgressHub) Subscribe(jobID string) (events <-chan ProgressEvent, unsubscribe func(), ok bool) {
+	if h == nil {
+		return nil, nil, false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	job, ok := h.jobs[jobID]
+	if !ok {
+		return nil, nil, false
+	}
+
+	ch := make(chan ProgressEvent, 1)
+	ch <- job.latest
+	if job.latest.Finished() {
+		close(ch)
+		return ch, func() {}, true
+	}
+
+	job.subscribers[ch] = struct{}{}
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := job.subscribers[ch]; ok {
+			delete(job.subscribers, ch)
+			close(ch)
+		}
+	}, true
+}
+
+// start registers jobID, replacing a finished job that reused the same ID.
+func (h *ProgressHub) start(jobID string, deadline time.Time, hasDeadline bool) {
+	if h == nil || jobID == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if job, ok := h.jobs[jobID]; ok && !job.latest.Finished() {
+		return
+	}
+	now := time.Now()
+	job := &progressJob{
+		latest:      ProgressEvent{JobID: jobID, Stage: ProgressStageFetching, StartedAt: now, UpdatedAt: now},
+		subscribers: make(map[chan ProgressEvent]struct{}),
+	}
+	if hasDeadline {
+		job.latest.Deadline = &deadline
+	}
+	h.jobs[jobID] = job
+}
+
+// update applies fn to the job's snapshot and publishes the result. Finished
+// jobs are forgotten after finishedJobRetention.
+func (h *ProgressHub) update(jobID string, fn func(*ProgressEvent)) {
+	if h == nil || jobID == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	job, ok := h.jobs[jobID]
+	if !ok || job.latest.Finished() {
+		return
+	}
+
+	fn(&job.latest)
+	job.latest.UpdatedAt = time.Now()
+	for ch := range job.subscribers {
+		select {
+		case ch <- job.latest:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- job.latest
+		}
+	}
+
+	if job.latest.Finished() {
+		for ch := range job.subscribers {
+			close(ch)
+		}
+		job.subscribers = nil
+		time.AfterFunc(finishedJobRetention, func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if h.jobs[jobID] == job {
+				delete(h.jobs, jobID)
+			}
+		})
+	}
+}
+
+// Progress returns the hub publishing progress for fetches run by c.
+func (c *JacadClient) Progress() *ProgressHub {
+	return c.progress
+}
+
+// reportProgress updates the progress of the job identified by the job ID
+// in ctx. It is a no-op for calls without a job ID, such as the CLI.
+func (c *JacadClient) reportProgress(ctx context.Context, fn func(*ProgressEvent)) {
+	c.progress.update(logging.JobID(ctx), fn)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+)
+
+func TestProgressHub(t *testing.T) {
+	hub := NewProgressHub()
+	if _, _, ok := hub.Subscribe("job"); ok {
+		t.Fatal("Subscribe to an unknown job succeeded")
+	}
+
+	hub.start("job", time.Time{}, false)
+	events, unsubscribe, ok := hub.Subscribe("job")
+	if !ok {
+		t.Fatal("Subscribe to a started job failed")
+	}
+	defer unsubscribe()
+	if first := <-events; first.Stage != ProgressStageFetching || first.JobID != "job" {
+		t.Errorf("first event = %+v, want the fetching snapshot", first)
+	}
+
+	// A subscriber that does not keep up only sees the newest snapshot.
+	for page := 1; page <= 3; page++ {
+		hub.update("job", func(e *ProgressEvent) { e.PagesFetched = page })
+	}
+	if event := <-events; event.PagesFetched != 3 {
+		t.Errorf("pending event has %d pages fetched, want the newest, 3", event.PagesFetched)
+	}
+
+	// Restarting a running job keeps its progress.
+	hub.start("job", time.Time{}, false)
+	if latest, _ := hub.Latest("job"); latest.PagesFetched != 3 {
+		t.Errorf("restart reset the running job: %+v", latest)
+	}
+
+	hub.update("job", func(e *ProgressEvent) { e.Stage = ProgressStageDone })
+	if event := <-events; event.Stage != ProgressStageDone {
+		t.Errorf("last event = %+v, want done", event)
+	}
+	if _, open := <-events; open {
+		t.Error("channel still open after the job finished")
+	}
+
+	hub.update("job", func(e *ProgressEvent) { e.RowsWritten = 99 })
+	late, _, ok := hub.Subscribe("job")
+	if !ok {
+		t.Fatal("finished job forgotten at once")
+	}
+	if event, open := <-late; !open || event.Stage != ProgressStageDone || event.RowsWritten != 0 {
+		t.Errorf("late subscriber got %+v (open %v), want the final snapshot", event, open)
+	}
+	if _, open := <-late; open {
+		t.Error("late subscriber's channel not closed")
+	}
+}
+
+func TestProgressHubUnsubscribe(t *testing.T) {
+	hub := NewProgressHub()
+	hub.start("job", time.Time{}, false)
+	events, unsubscribe, _ := hub.Subscribe("job")
+	<-events
+
+	unsubscribe()
+	if _, open := <-events; open {
+		t.Error("channel open after unsubscribe")
+	}
+	hub.update("job", func(e *ProgressEvent) { e.PagesFetched = 1 })
+	unsubscribe()
+}
+
+// TestFetchEnrollmentsReportsProgress follows a fetch job from start to its
+// done event.
+func TestFetchEnrollmentsReportsProgress(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.PageSize = 50
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	client := NewJacadClient(&cfg, NewFakeSheetWriter(), NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+
+	ctx := logging.WithJobID(context.Background(), "job-1")
+	if _, err := client.FetchEnrollmentsFiltered(ctx, &requests.FetchEnrollmentsRequest{
+		IdPeriodoLetivo: 87,
+		StatusMatricula: "ATIVA",
+		Mode:            requests.SyncModeFull,
+		WriteMode:       requests.WriteModeStream,
+		GroupBy:         requests.GroupByNone,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	event, ok := client.Progress().Latest("job-1")
+	if !ok {
+		t.Fatal("no progress recorded for the job")
+	}
+	if event.Stage != ProgressStageDone || event.PagesFetched != 4 || event.TotalPages != 4 || event.RowsFetched != 180 || event.RowsWritten != 180 {
+		t.Errorf("final progress = %+v, want done with 4 of 4 pages and 180 rows fetched and written", event)
+	}
+}
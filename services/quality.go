@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// rejectsSheetName is the tab enrollments failing a data quality rule are
+// written to.
+const rejectsSheetName = "Rejeitados"
+
+// qualityCheck holds back enrollments that fail a data quality rule, so they
+// are reported in the rejects tab instead of silently written with the rest.
+type qualityCheck struct {
+	rules    []string
+	rejected []rejectedEnrollment
+}
+
+type rejectedEnrollment struct {
+	item   models.Enrollment
+	reason string
+}
+
+// newQualityCheck returns nil when no rule is enabled.
+func newQualityCheck(rules []string) *qualityCheck {
+	if len(rules) == 0 {
+		return nil
+	}
+	return &qualityCheck{rules: rules}
+}
+
+// wrap returns a sink that forwards only enrollments passing every rule to
+// sink. A nil check forwards everything.
+func (q *qualityCheck) wrap(sink func([]models.Enrollment) error) func([]models.Enrollment) error {
+	if q == nil {
+		return sink
+	}
+	return func(data []models.Enrollment) error {
+		kept := make([]models.Enrollment, 0, len(data))
+		for _, item := range data {
+			if reasons := q.check(item); len(reasons) > 0 {
+				q.rejected = append(q.rejected, rejectedEnrollment{item: item, reason: strings.Join(reasons, "; ")})
+				continue
+			}
+			kept = append(kept, item)
+		}
+		if len(kept) == 0 {
+			return nil
+		}
+		return sink(kept)
+	}
+}
+
+// check returns why item fails the enabled rules, in rule order.
+func (q *qualityCheck) check(item models.Enrollment) []string {
+	var reasons []string
+	for _, rule := range q.rules {
+		var reason string
+		switch rule {
+		case config.QualityRuleRA:
+			if item.RA == nil || strings.TrimSpace(*item.RA) == "" {
+				reason = "RA ausente"
+			}
+		case config.QualityRuleDates:
+			if item.DataMatricula != nil && item.DataAtivacao != nil &&
+				!time.Time(*item.DataAtivacao).IsZero() && time.Time(*item.DataMatricula).After(time.Time(*item.DataAtivacao)) {
+				reason = "dataMatricula posterior à dataAtivacao"
+			}
+		case config.QualityRuleCurso:
+			if item.Curso == nil || strings.TrimSpace(*item.Curso) == "" {
+				reason = "curso vazio"
+			}
+		}
+		if reason != "" {
+			enrollmentsRejectedTotal.Inc(rule)
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// Rejected returns how many enrollments failed a rule.
+func (q *qualityCheck) Rejected() int {
+	if q == nil {
+		return 0
+	}
+	return len(q.rejected)
+}
+
+// rows renders the rejected enrollments with mapper, each preceded by why it
+// was rejected.
+func (q *qualityCheck) rows(mapper *EnrollmentRowMapper) [][]interface{} {
+	rows := make([][]interface{}, len(q.rejected))
+	for i, rejected := range q.rejected {
+		rows[i] = append([]interface{}{rejected.reason}, mapper.Row(rejected.item)...)
+	}
+	return rows
+}
+
+// writeRejects records the rejected enrollments in result and, unless this is
+// a dry run or none was rejected, writes them to the rejects tab. Failures
+// are reported in result rather than failing the fetch, since the data tabs
+// were already written.
+func (c *JacadClient) writeRejects(ctx context.Context, logger *slog.Logger, quality *qualityCheck, mapper *EnrollmentRowMapper, params *requests.FetchEnrollmentsRequest, result *FetchResult) {
+	result.Rejected = quality.Rejected()
+	if result.Rejected == 0 {
+		return
+	}
+	logger.Warn("Enrollments failed data quality rules and were left out of their sheets", "rejected", result.Rejected)
+	if params.DryRun {
+		return
+	}
+
+	headers := append([]string{"Motivo"}, mapper.Headers()...)
+	logger.Info("Writing rejects sheet", "sheet", rejectsSheetName, "rejected", result.Rejected)
+	if err := c.Writer.OverwriteSheetData(ctx, rejectsSheetName, headers, quality.rows(mapper)); err != nil {
+		logger.Error("Failed to write rejects sheet", "sheet", rejectsSheetName, "error", err)
+		result.RejectsError = err.Error()
+		return
+	}
+	result.RejectsSheet = rejectsSheetName
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+func TestQualityCheck(t *testing.T) {
+	march := ptr(utils.Date(time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)))
+	april := ptr(utils.Date(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)))
+	valid := models.Enrollment{IdMatricula: 1, RA: ptr("123"), Curso: ptr("Direito"), DataMatricula: march, DataAtivacao: april}
+
+	tests := []struct {
+		name  string
+		rules []string
+		item  models.Enrollment
+		want  string
+	}{
+		{name: "valid", rules: config.QualityRules, item: valid},
+		{name: "missing RA", rules: config.QualityRules, item: models.Enrollment{IdMatricula: 2, RA: ptr(" "), Curso: ptr("Direito")}, want: "RA ausente"},
+		{name: "activated before enrollment", rules: config.QualityRules, item: models.Enrollment{IdMatricula: 3, RA: ptr("1"), Curso: ptr("Direito"), DataMatricula: april, DataAtivacao: march}, want: "dataMatricula posterior à dataAtivacao"},
+		{name: "not activated", rules: config.QualityRules, item: models.Enrollment{IdMatricula: 4, RA: ptr("1"), Curso: ptr("Direito"), DataMatricula: april, DataAtivacao: ptr(utils.Date(time.Time{}))}},
+		{name: "several failures", rules: config.QualityRules, item: models.Enrollment{IdMatricula: 5}, want: "RA ausente; curso vazio"},
+		{name: "rule disabled", rules: []string{config.QualityRuleCurso}, item: models.Enrollment{IdMatricula: 6, Curso: ptr("Direito")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newQualityCheck(tt.rules)
+			var kept []models.Enrollment
+			sink := q.wrap(func(data []models.Enrollment) error {
+				kept = append(kept, data...)
+				return nil
+			})
+			if err := sink([]models.Enrollment{tt.item}); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.want == "" {
+				if q.Rejected() != 0 || !reflect.DeepEqual(kept, []models.Enrollment{tt.item}) {
+					t.Errorf("enrollment rejected (%v), want it kept", q.rejected)
+				}
+				return
+			}
+			if len(kept) != 0 || q.Rejected() != 1 || q.rejected[0].reason != tt.want {
+				t.Errorf("rejected = %v, kept = %v; want rejected for %q", q.rejected, kept, tt.want)
+			}
+		})
+	}
+}
+
+func TestQualityCheckDisabled(t *testing.T) {
+	if q := newQualityCheck(nil); q != nil || q.Rejected() != 0 {
+		t.Errorf("newQualityCheck(nil) = %v, want nil", q)
+	}
+}
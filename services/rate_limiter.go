@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket shared by every goroutine that calls Jacad.
+// A nil *RateLimiter never blocks.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	// now is the clock, replaced in tests.
+	now func() time.Time
+}
+
+// perMinuteBurst caps the burst of NewPerMinuteLimiter so a fresh limiter
+// cannot spend most of a minute's budget at once on top of the tokens it
+// refills during that minute.
+const perMinuteBurst = 5
+
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// NewPerMinuteLimiter allows perMinute calls per minute with a burst of at
+// most perMinuteBurst, so no 60-second window sees much more than perMinute
+// calls. It returns nil, which never blocks, when perMinute is not positive.
+func NewPerMinuteLimiter(perMinute int) *RateLimiter {
+	return NewRateLimiter(float64(perMinute)/60, min(perMinute, perMinuteBurst))
+}
+
+// Allow takes a token if one is available without blocking.
+func (l *RateLimiter) Allow() bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refillLocked()
+	if l.tokens >= 1 {
+		l.tokens--
+		return true
+	}
+	return false
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+		l.refillLocked()
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *RateLimiter) refillLocked() {
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name  string
+		rps   float64
+		burst int
+		calls int
+		want  int
+	}{
+		{name: "allows the burst", rps: 1, burst: 3, calls: 5, want: 3},
+		{name: "burst below one is one", rps: 1, burst: 0, calls: 3, want: 1},
+		{name: "disabled limiter allows everything", rps: 0, burst: 1, calls: 5, want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewRateLimiter(tt.rps, tt.burst)
+			allowed := 0
+			for i := 0; i < tt.calls; i++ {
+				if l.Allow() {
+					allowed++
+				}
+			}
+			if allowed != tt.want {
+				t.Errorf("allowed %d of %d calls, want %d", allowed, tt.calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(50, 1)
+	ctx := context.Background()
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("first Wait() = %v", err)
+	}
+
+	started := time.Now()
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("second Wait() = %v", err)
+	}
+	if waited := time.Since(started); waited < 10*time.Millisecond {
+		t.Errorf("second Wait() returned after %s, want about 20ms", waited)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := l.Wait(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() with cancelled context = %v, want Canceled", err)
+	}
+}
+
+func TestPerMinuteLimiterBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		perMinute int
+	}{
+		{name: "Sheets default quota", perMinute: 60},
+		{name: "small budget", perMinute: 3},
+		{name: "large budget", perMinute: 600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewPerMinuteLimiter(tt.perMinute)
+			clock := time.Now()
+			l.now = func() time.Time { return clock }
+			l.last = clock
+
+			// Call as fast as the limiter allows through the first minute,
+			// when a full bucket would let twice the budget through.
+			allowed := 0
+			for end := clock.Add(time.Minute); clock.Before(end); clock = clock.Add(10 * time.Millisecond) {
+				for l.Allow() {
+					allowed++
+				}
+			}
+			if limit := tt.perMinute + perMinuteBurst; allowed > limit {
+				t.Errorf("allowed %d calls in the first minute, want at most %d", allowed, limit)
+			}
+			if allowed < tt.perMinute-1 {
+				t.Errorf("allowed %d calls in the first minute, want about %d", allowed, tt.perMinute)
+			}
+		})
+	}
+}
+
+func TestNewPerMinuteLimiterDisabled(t *testing.T) {
+	if l := NewPerMinuteLimiter(0); l != nil {
+		t.Errorf("NewPerMinuteLimiter(0) = %v, want nil", l)
+	}
+}
+
+// TestRateLimiterPerInstance checks that calls to one Jacad instance do not
+// spend the budget of another.
+func TestRateLimiterPerInstance(t *testing.T) {
+	jacad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer jacad.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer other.Close()
+
+	cfg := config.Defaults()
+	cfg.APIBase = jacad.URL
+	cfg.JacadProfiles = map[string]config.JacadProfile{"other": {APIBase: other.URL}}
+	cfg.JacadRateLimitRPS = 0.01
+	cfg.JacadRateLimitBurst = 1
+	c := NewJacadClient(&cfg, NewFakeSheetWriter(), nil, nil, nil)
+
+	if _, err := c.MakeRequest(context.Background(), http.MethodGet, jacad.URL, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.MakeRequest(ctx, http.MethodGet, jacad.URL, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("second request to the default instance = %v, want it to wait for the limiter", err)
+	}
+	otherCtx, cancelOther := context.WithTimeout(WithTenant(context.Background(), "other"), 50*time.Millisecond)
+	defer cancelOther()
+	if _, err := c.MakeRequest(otherCtx, http.MethodGet, other.URL, nil, nil); err != nil {
+		t.Errorf("request to another instance = %v, want it sent at once", err)
+	}
+}
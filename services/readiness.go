@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+)
+
+// ReadinessProbe runs SelfCheck on an interval and caches the latest results so
+// readiness requests never wait on Jacad or the writer.
+type ReadinessProbe struct {
+	client    *JacadClient
+	interval  time.Duration
+	timeout   time.Duration
+	mu        sync.RWMutex
+	results   []CheckResult
+	checkedAt time.Time
+}
+
+func NewReadinessProbe(client *JacadClient, interval, timeout time.Duration) *ReadinessProbe {
+	return &ReadinessProbe{client: client, interval: interval, timeout: timeout}
+}
+
+// Run checks immediately and then on every interval until ctx is done.
+func (p *ReadinessProbe) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status reports whether every check passed on the latest run. It is false
+// until the first run completes.
+func (p *ReadinessProbe) Status() (bool, []CheckResult, time.Time) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.checkedAt.IsZero() {
+		return false, nil, p.checkedAt
+	}
+	return SelfCheckError(p.results) == nil, p.results, p.checkedAt
+}
+
+func (p *ReadinessProbe) refresh(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	results := p.client.SelfCheck(checkCtx)
+	if err := SelfCheckError(results); err != nil {
+		logging.FromContext(ctx).Warn("Readiness check failed", "error", err)
+	}
+
+	p.mu.Lock()
+	p.results = results
+	p.checkedAt = time.Now()
+	p.mu.Unlock()
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+// redactedHashLength is how many hex characters of the HMAC are kept; enough
+// to stay unique per student while keeping cells short.
+const redactedHashLength = 16
+
+type redactFunc func(value interface{}) interface{}
+
+// Redacted returns a copy of m that applies policy to the mapped columns.
+// Fields in policy that are not part of the column mapping are ignored.
+func (m *EnrollmentRowMapper) Redacted(policy map[string]string, salt string) (*EnrollmentRowMapper, error) {
+	redacted := *m
+	redacted.redactors = make([]redactFunc, len(m.columns))
+	for field, strategy := range policy {
+		if _, ok := enrollmentFields[field]; !ok {
+			return nil, fmt.Errorf("unknown enrollment field '%s' in redaction policy", field)
+		}
+		redact, err := newRedactor(strategy, salt)
+		if err != nil {
+			return nil, err
+		}
+		for i, col := range m.columns {
+			if col.Field == field {
+				redacted.redactors[i] = redact
+			}
+		}
+	}
+	return &redacted, nil
+}
+
+func newRedactor(strategy, salt string) (redactFunc, error) {
+	switch strategy {
+	case config.RedactHash:
+		return func(value interface{}) interface{} {
+			s := redactableString(value)
+			if s == "" {
+				return value
+			}
+			mac := hmac.New(sha256.New, []byte(salt))
+			mac.Write([]byte(s))
+			return hex.EncodeToString(mac.Sum(nil))[:redactedHashLength]
+		}, nil
+	case config.RedactMask:
+		return func(value interface{}) interface{} {
+			s := redactableString(value)
+			if s == "" {
+				return value
+			}
+			return maskWords(s)
+		}, nil
+	case config.RedactRemove:
+		return func(value interface{}) interface{} { return "" }, nil
+	}
+	return nil, fmt.Errorf("unknown redaction strategy '%s'", strategy)
+}
+
+func redactableString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case time.Time:
+		return v.Format("2006-01-02")
+	}
+	return fmt.Sprint(value)
+}
+
+// maskWords keeps the first letter of each word, e.g. "Maria da Silva" becomes
+// "M**** d* S****".
+func maskWords(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		runes := []rune(word)
+		for j := 1; j < len(runes); j++ {
+			runes[j] = '*'
+		}
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// maxRetryAfter caps the wait a Retry-After header can impose, so a server
+// asking for hours does not hold a job's slot that long; the retry then
+// simply fails sooner.
+const maxRetryAfter = 5 * time.Minute
+
+// retryAfter returns the wait a 429 or 503 response asks for in its
+// Retry-After header, given as seconds or as an HTTP date, capped at
+// maxRetryAfter. It reports false when the response did not ask for one.
+func retryAfter(status int, header http.Header, now time.Time) (time.Duration, bool) {
+	if status != http.StatusTooManyRequests && status != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		wait = at.Sub(now)
+	} else {
+		return 0, false
+	}
+	return min(max(wait, 0), maxRetryAfter), true
+}
+
+// googleRetryAfter is retryAfter for the *googleapi.Error a Google API call
+// returned.
+func googleRetryAfter(err error, now time.Time) (time.Duration, bool) {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return 0, false
+	}
+	return retryAfter(apiErr.Code, apiErr.Header, now)
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, time.March, 3, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		status int
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "seconds", status: http.StatusTooManyRequests, value: "7", want: 7 * time.Second, wantOK: true},
+		{name: "http date", status: http.StatusServiceUnavailable, value: "Mon, 03 Mar 2025 10:00:30 GMT", want: 30 * time.Second, wantOK: true},
+		{name: "date in the past", status: http.StatusServiceUnavailable, value: "Mon, 03 Mar 2025 09:59:00 GMT", want: 0, wantOK: true},
+		{name: "capped", status: http.StatusTooManyRequests, value: "86400", want: maxRetryAfter, wantOK: true},
+		{name: "missing", status: http.StatusTooManyRequests},
+		{name: "unparseable", status: http.StatusTooManyRequests, value: "soon"},
+		{name: "other status", status: http.StatusInternalServerError, value: "7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.value != "" {
+				header.Set("Retry-After", tt.value)
+			}
+			got, ok := retryAfter(tt.status, header, now)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("retryAfter(%d, %q) = %s, %v, want %s, %v", tt.status, tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGoogleRetryAfter(t *testing.T) {
+	apiErr := &googleapi.Error{Code: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"12"}}}
+	if got, ok := googleRetryAfter(fmt.Errorf("append: %w", apiErr), time.Now()); !ok || got != 12*time.Second {
+		t.Errorf("googleRetryAfter() = %s, %v, want 12s, true", got, ok)
+	}
+	if _, ok := googleRetryAfter(fmt.Errorf("plain error"), time.Now()); ok {
+		t.Error("googleRetryAfter() of a non-API error asked for a wait")
+	}
+}
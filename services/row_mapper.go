@@ -0,0 +1,185 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+// enrollmentFields indexes models.Enrollment struct fields by their JSON tag,
+// which is the name operators use in the column mapping.
+var enrollmentFields = indexFieldsByJSONTag(reflect.TypeOf(models.Enrollment{}))
+
+type EnrollmentRowMapper struct {
+	columns     []config.Column
+	fieldIndex  []int
+	headerIndex map[string]string
+	// redactors holds the per-column redaction set by Redacted, if any.
+	redactors []redactFunc
+	// transforms holds the field rewrites set by Transformed, if any.
+	transforms []fieldTransform
+	// computes holds the ComputeFunc of each computed column, nil for plain
+	// columns; computes itself is nil without computed columns.
+	computes []ComputeFunc
+	// coercers holds the per-column type coercion set by Coerced, if any.
+	coercers []coerceFunc
+}
+
+func NewEnrollmentRowMapper(columns []config.Column) (*EnrollmentRowMapper, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("column mapping has no columns")
+	}
+
+	m := &EnrollmentRowMapper{
+		columns:     columns,
+		fieldIndex:  make([]int, len(columns)),
+		headerIndex: make(map[string]string, len(columns)),
+	}
+	for i, col := range columns {
+		if col.Compute != "" {
+			compute, err := newComputeFunc(col)
+			if err != nil {
+				return nil, err
+			}
+			if m.computes == nil {
+				m.computes = make([]ComputeFunc, len(columns))
+			}
+			m.computes[i] = compute
+			m.fieldIndex[i] = enrollmentFields[col.Source]
+			m.headerIndex[col.Field] = col.Header
+			continue
+		}
+		idx, ok := enrollmentFields[col.Field]
+		if !ok {
+			return nil, fmt.Errorf("unknown enrollment field '%s' in column mapping", col.Field)
+		}
+		m.fieldIndex[i] = idx
+		m.headerIndex[col.Field] = col.Header
+	}
+	return m, nil
+}
+
+// Select returns a mapper with only fields, in the given order. Fields missing
+// from the column mapping use their name as header; computed columns can be
+// selected by name.
+func (m *EnrollmentRowMapper) Select(fields []string) (*EnrollmentRowMapper, error) {
+	if err := ValidateEnrollmentFields(fields, m.columns); err != nil {
+		return nil, err
+	}
+	mapped := make(map[string]config.Column, len(m.columns))
+	for _, col := range m.columns {
+		mapped[col.Field] = col
+	}
+	columns := make([]config.Column, len(fields))
+	for i, field := range fields {
+		if col, ok := mapped[field]; ok {
+			columns[i] = col
+			continue
+		}
+		columns[i] = config.Column{Field: field, Header: field}
+	}
+	return NewEnrollmentRowMapper(columns)
+}
+
+// ValidateEnrollmentFields reports the first name in fields that is neither
+// an enrollment field nor a computed column of mapping.
+func ValidateEnrollmentFields(fields []string, mapping []config.Column) error {
+	for _, field := range fields {
+		if _, ok := enrollmentFields[field]; ok {
+			continue
+		}
+		if !slices.ContainsFunc(mapping, func(col config.Column) bool { return col.Compute != "" && col.Field == field }) {
+			return fmt.Errorf("unknown enrollment field '%s'", field)
+		}
+	}
+	return nil
+}
+
+func (m *EnrollmentRowMapper) Headers() []string {
+	headers := make([]string, len(m.columns))
+	for i, col := range m.columns {
+		headers[i] = col.Header
+	}
+	return headers
+}
+
+// Fields returns the enrollment field of each column, in order.
+func (m *EnrollmentRowMapper) Fields() []string {
+	fields := make([]string, len(m.columns))
+	for i, col := range m.columns {
+		fields[i] = col.Field
+	}
+	return fields
+}
+
+// HeaderFor returns the sheet header configured for an enrollment field.
+func (m *EnrollmentRowMapper) HeaderFor(field string) (string, bool) {
+	header, ok := m.headerIndex[field]
+	return header, ok
+}
+
+func (m *EnrollmentRowMapper) Row(item models.Enrollment) []interface{} {
+	if len(m.transforms) > 0 {
+		item = m.transform(item)
+	}
+	v := reflect.ValueOf(item)
+	row := make([]interface{}, len(m.fieldIndex))
+	for i, idx := range m.fieldIndex {
+		if m.computes != nil && m.computes[i] != nil {
+			row[i] = m.computes[i](v.Field(idx).Interface())
+		} else {
+			row[i] = cellValue(v.Field(idx))
+		}
+		if m.redactors != nil && m.redactors[i] != nil {
+			row[i] = m.redactors[i](row[i])
+		}
+		if m.coercers != nil && m.coercers[i] != nil {
+			row[i] = m.coercers[i](row[i])
+		}
+	}
+	return row
+}
+
+func (m *EnrollmentRowMapper) Rows(data []models.Enrollment) [][]interface{} {
+	rows := make([][]interface{}, len(data))
+	for i, item := range data {
+		rows[i] = m.Row(item)
+	}
+	return rows
+}
+
+func cellValue(v reflect.Value) interface{} {
+	switch val := v.Interface().(type) {
+	case *string:
+		return utils.GetStringOrEmpty(val)
+	case *utils.Date:
+		return utils.GetTimeOrNilDate(val)
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		return v.Elem().Interface()
+	}
+	return v.Interface()
+}
+
+func indexFieldsByJSONTag(t reflect.Type) map[string]int {
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		fields[name] = i
+	}
+	return fields
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestEnrollmentRowMapperRow(t *testing.T) {
+	matricula := time.Date(2025, time.February, 10, 0, 0, 0, 0, time.UTC)
+	enrollment := models.Enrollment{
+		IdMatricula:   7,
+		Aluno:         ptr("Ana"),
+		OrgID:         20,
+		DataMatricula: ptr(utils.Date(matricula)),
+		DataAtivacao:  ptr(utils.Date(time.Time{})),
+		PeriodoLetivo: ptr("2025/1"),
+	}
+	now := computeNow
+	computeNow = func() time.Time { return time.Date(2026, time.February, 9, 15, 0, 0, 0, time.UTC) }
+	defer func() { computeNow = now }()
+
+	tests := []struct {
+		name    string
+		columns []config.Column
+		headers []string
+		row     []interface{}
+	}{
+		{
+			name:    "maps fields in column order with custom headers",
+			columns: []config.Column{{Field: "aluno", Header: "Aluno"}, {Field: "idMatricula", Header: "ID"}, {Field: "idOrg", Header: "Org"}},
+			headers: []string{"Aluno", "ID", "Org"},
+			row:     []interface{}{"Ana", 7, 20},
+		},
+		{
+			name:    "nil strings become empty",
+			columns: []config.Column{{Field: "ra", Header: "ra"}, {Field: "curso", Header: "curso"}},
+			headers: []string{"ra", "curso"},
+			row:     []interface{}{"", ""},
+		},
+		{
+			name:    "dates become times and missing dates nil",
+			columns: []config.Column{{Field: "dataMatricula", Header: "m"}, {Field: "dataAtivacao", Header: "a"}, {Field: "dataCadastro", Header: "c"}},
+			headers: []string{"m", "a", "c"},
+			row:     []interface{}{matricula, nil, nil},
+		},
+		{
+			name: "computed columns derive from their source",
+			columns: []config.Column{
+				{Field: "diasDesdeMatricula", Header: "Dias", Compute: "daysSince", Source: "dataMatricula"},
+				{Field: "anosDesdeMatricula", Header: "Anos", Compute: "yearsSince", Source: "dataMatricula"},
+				{Field: "anoPeriodo", Header: "Ano", Compute: "year", Source: "periodoLetivo"},
+				{Field: "diasDesdeAtivacao", Header: "Ativação", Compute: "daysSince", Source: "dataAtivacao"},
+			},
+			headers: []string{"Dias", "Anos", "Ano", "Ativação"},
+			row:     []interface{}{364, 0, 2025, nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewEnrollmentRowMapper(tt.columns)
+			if err != nil {
+				t.Fatalf("NewEnrollmentRowMapper() = %v", err)
+			}
+			if got := m.Headers(); !slices.Equal(got, tt.headers) {
+				t.Errorf("Headers() = %v, want %v", got, tt.headers)
+			}
+			got := m.Row(enrollment)
+			if len(got) != len(tt.row) {
+				t.Fatalf("Row() = %v, want %v", got, tt.row)
+			}
+			for i := range got {
+				if got[i] != tt.row[i] {
+					t.Errorf("Row()[%d] = %#v, want %#v", i, got[i], tt.row[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewEnrollmentRowMapperErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []config.Column
+	}{
+		{name: "no columns"},
+		{name: "unknown field", columns: []config.Column{{Field: "nota", Header: "Nota"}}},
+		{name: "unknown compute op", columns: []config.Column{{Field: "x", Header: "X", Compute: "median", Source: "dataMatricula"}}},
+		{name: "unknown source", columns: []config.Column{{Field: "x", Header: "X", Compute: "year", Source: "nota"}}},
+		{name: "date op on text", columns: []config.Column{{Field: "x", Header: "X", Compute: "daysSince", Source: "aluno"}}},
+		{name: "shadows a field", columns: []config.Column{{Field: "aluno", Header: "X", Compute: "year", Source: "dataMatricula"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewEnrollmentRowMapper(tt.columns); err == nil {
+				t.Error("NewEnrollmentRowMapper() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestEnrollmentRowMapperSelect(t *testing.T) {
+	m, err := NewEnrollmentRowMapper([]config.Column{{Field: "aluno", Header: "Aluno"}, {Field: "ra", Header: "RA"}, {Field: "anoPeriodo", Header: "Ano", Compute: "year", Source: "periodoLetivo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		fields  []string
+		headers []string
+		wantErr bool
+	}{
+		{name: "reorders and keeps configured headers", fields: []string{"ra", "aluno"}, headers: []string{"RA", "Aluno"}},
+		{name: "unconfigured field uses its name", fields: []string{"curso", "aluno"}, headers: []string{"curso", "Aluno"}},
+		{name: "computed column by name", fields: []string{"anoPeriodo", "ra"}, headers: []string{"Ano", "RA"}},
+		{name: "unknown field", fields: []string{"nota"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := m.Select(tt.fields)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Select() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Select() = %v", err)
+			}
+			if got := selected.Headers(); !slices.Equal(got, tt.headers) {
+				t.Errorf("Headers() = %v, want %v", got, tt.headers)
+			}
+			if got := selected.Fields(); !slices.Equal(got, tt.fields) {
+				t.Errorf("Fields() = %v, want %v", got, tt.fields)
+			}
+		})
+	}
+}
+
+// TestEnrollmentDecodingToleratesUnknownFields decodes a full Jacad payload,
+// including fields the model does not know, and maps the optional columns.
+func TestEnrollmentDecodingToleratesUnknownFields(t *testing.T) {
+	enrollment := `{
+		"idMatricula": 51234,
+		"aluno": "Ana Beatriz",
+		"idAluno": 9001,
+		"idPeriodoLetivo": 87,
+		"modalidade": "EAD",
+		"polo": "Polo Centro",
+		"formaIngresso": "ENEM",
+		"cpf": "123.456.789-09",
+		"dataNascimento": "2001-05-17",
+		"responsavelFinanceiro": {"nome": "Maria", "parentesco": "Mãe"},
+		"disciplinas": [{"id": 1}, {"id": 2}],
+		"bolsista": true
+	}`
+	bodies := map[string]string{
+		models.APIV1: `{"page": {"currentPage": 0, "pageSize": 1, "totalElements": 1, "totalPages": 1}, "elements": [` + enrollment + `], "links": {"next": null}}`,
+		models.APIV2: `{"meta": {"page": 1, "size": 1, "totalItems": 1, "totalPages": 1, "cursor": "abc"}, "data": [` + enrollment + `]}`,
+	}
+	m, err := NewEnrollmentRowMapper([]config.Column{
+		{Field: "idMatricula", Header: "Matrícula"},
+		{Field: "idAluno", Header: "Aluno ID"},
+		{Field: "idPeriodoLetivo", Header: "Período ID"},
+		{Field: "modalidade", Header: "Modalidade"},
+		{Field: "polo", Header: "Polo"},
+		{Field: "formaIngresso", Header: "Forma de ingresso"},
+		{Field: "turno", Header: "Turno"},
+		{Field: "cpf", Header: "CPF"},
+		{Field: "dataNascimento", Header: "Nascimento"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{51234, 9001, 87, "EAD", "Polo Centro", "ENEM", "", "123.456.789-09", time.Date(2001, time.May, 17, 0, 0, 0, 0, time.UTC)}
+
+	for version, body := range bodies {
+		t.Run(version, func(t *testing.T) {
+			elements, page, err := models.DecodePage[models.Enrollment](version, []byte(body))
+			if err != nil {
+				t.Fatalf("DecodePage() = %v", err)
+			}
+			if len(elements) != 1 || page == nil || page.TotalElements != 1 || page.CurrentPage != 0 {
+				t.Fatalf("DecodePage() = %d elements, page %+v; want one element on page 0", len(elements), page)
+			}
+			row := m.Row(elements[0])
+			for i := range want {
+				if got, ok := row[i].(time.Time); ok {
+					if !got.Equal(want[i].(time.Time)) {
+						t.Errorf("column %d = %v, want %v", i, got, want[i])
+					}
+				} else if row[i] != want[i] {
+					t.Errorf("column %d = %#v, want %#v", i, row[i], want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"google.golang.org/api/secretmanager/v1"
+)
+
+// SecretProvider reads secrets by name from a secret store.
+type SecretProvider interface {
+	Secret(ctx context.Context, name string) ([]byte, error)
+}
+
+// NewSecretProvider builds the provider SECRETS_PROVIDER selects, or nil for
+// none. Secret Manager is called with creds, which must not depend on a
+// secret it holds.
+func NewSecretProvider(ctx context.Context, cfg *config.Config, creds GoogleCredentials) (SecretProvider, error) {
+	switch cfg.SecretsProvider {
+	case config.SecretsProviderGCP:
+		return NewGCPSecretProvider(ctx, cfg.SecretsGCPProject, creds)
+	case config.SecretsProviderVault:
+		return NewVaultSecretProvider(cfg.VaultAddr, cfg.VaultToken, cfg.VaultKVMount), nil
+	case config.SecretsProviderNone, "":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown secrets provider '%s'", cfg.SecretsProvider)
+}
+
+// GCPSecretProvider reads secrets from Google Secret Manager.
+type GCPSecretProvider struct {
+	service *secretmanager.Service
+	project string
+}
+
+func NewGCPSecretProvider(ctx context.Context, project string, creds GoogleCredentials) (*GCPSecretProvider, error) {
+	opts, credSourceDescription, err := googleClientOptions(ctx, creds, secretmanager.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	service, err := secretmanager.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Secret Manager client (source: %s): %w", credSourceDescription, err)
+	}
+	return &GCPSecretProvider{service: service, project: project}, nil
+}
+
+// Secret returns the latest version of the secret with ID name, or the
+// version name refers to when it is a full "projects/..." resource name.
+func (p *GCPSecretProvider) Secret(ctx context.Context, name string) ([]byte, error) {
+	resource := name
+	if !strings.HasPrefix(name, "projects/") {
+		resource = fmt.Sprintf("projects/%s/secrets/%s/versions/latest", p.project, name)
+	}
+	resp, err := p.service.Projects.Secrets.Versions.Access(resource).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to access secret '%s': %w", resource, err)
+	}
+	if resp.Payload == nil {
+		return nil, fmt.Errorf("secret '%s' has no payload", resource)
+	}
+	data, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode secret '%s': %w", resource, err)
+	}
+	return data, nil
+}
+
+// VaultSecretProvider reads secrets from a HashiCorp Vault KV v2 engine.
+type VaultSecretProvider struct {
+	addr   string
+	token  string
+	mount  string
+	client *http.Client
+}
+
+func NewVaultSecretProvider(addr, token, mount string) *VaultSecretProvider {
+	return &VaultSecretProvider{
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		mount:  strings.Trim(mount, "/"),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Secret returns a field of the latest version of the secret at a path.
+// name is "path#field"; a bare path reads the field "value". Fields holding
+// JSON objects, such as Google credentials stored as-is, are returned as
+// JSON.
+func (p *VaultSecretProvider) Secret(ctx context.Context, name string) ([]byte, error) {
+	path, field, ok := strings.Cut(name, "#")
+	if !ok {
+		field = "value"
+	}
+	endpoint := fmt.Sprintf("%s/v1/%s/data/%s", p.addr, p.mount, strings.TrimLeft(path, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Vault request for '%s': %w", path, err)
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret '%s' from Vault: %w", path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret '%s' from Vault: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned status %d for secret '%s': %s", resp.StatusCode, path, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, fmt.Errorf("failed to parse secret '%s' from Vault: %w", path, err)
+	}
+	raw, ok := secret.Data.Data[field]
+	if !ok {
+		return nil, fmt.Errorf("secret '%s' has no field '%s'", path, field)
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []byte(text), nil
+	}
+	return raw, nil
+}
+
+// Secret is a secret value kept up to date by Secrets.Run. The zero value
+// and a nil *Secret hold nothing.
+type Secret struct {
+	name    string
+	mu      sync.RWMutex
+	value   []byte
+	version int
+}
+
+// Value returns the current value and a version that changes whenever the
+// secret is rotated.
+func (s *Secret) Value() ([]byte, int) {
+	if s == nil {
+		return nil, 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.value, s.version
+}
+
+// Text returns the current value as text, without surrounding whitespace.
+func (s *Secret) Text() string {
+	value, _ := s.Value()
+	return strings.TrimSpace(string(value))
+}
+
+// set stores value and reports whether it differs from the previous one.
+func (s *Secret) set(value []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.version > 0 && bytes.Equal(s.value, value) {
+		return false
+	}
+	s.value = value
+	s.version++
+	return true
+}
+
+// Secrets holds the secrets read from a SecretProvider in place of plain
+// configuration. Secrets not configured are nil.
+type Secrets struct {
+	provider SecretProvider
+	interval time.Duration
+	// UserToken replaces USER_TOKEN for the default Jacad instance.
+	UserToken *Secret
+	// GoogleCredentials replaces the Google credentials JSON.
+	GoogleCredentials *Secret
+}
+
+// LoadSecrets reads the secrets named by userTokenName and
+// googleCredentialsName, skipping empty names. It fails if any cannot be
+// read, since the process cannot start without them.
+func LoadSecrets(ctx context.Context, provider SecretProvider, userTokenName, googleCredentialsName string, interval time.Duration) (*Secrets, error) {
+	s := &Secrets{provider: provider, interval: interval}
+	if userTokenName != "" {
+		s.UserToken = &Secret{name: userTokenName}
+	}
+	if googleCredentialsName != "" {
+		s.GoogleCredentials = &Secret{name: googleCredentialsName}
+	}
+	for _, secret := range s.all() {
+		if err := s.refresh(ctx, secret); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Secrets) all() []*Secret {
+	var all []*Secret
+	for _, secret := range []*Secret{s.UserToken, s.GoogleCredentials} {
+		if secret != nil {
+			all = append(all, secret)
+		}
+	}
+	return all
+}
+
+// refresh reads secret from the provider.
+func (s *Secrets) refresh(ctx context.Context, secret *Secret) error {
+	value, err := s.provider.Secret(ctx, secret.name)
+	if err == nil && len(bytes.TrimSpace(value)) == 0 {
+		err = errors.New("secret is empty")
+	}
+	if err != nil {
+		secretRefreshesTotal.Inc(secret.name, "error")
+		return fmt.Errorf("failed to load secret '%s': %w", secret.name, err)
+	}
+	secretRefreshesTotal.Inc(secret.name, "ok")
+	if _, version := secret.Value(); secret.set(value) && version > 0 {
+		logging.FromContext(ctx).Info("Secret rotated. The new value is used from now on.", "secret", secret.name)
+	}
+	return nil
+}
+
+// Run reads every secret again each interval until ctx is done, so rotated
+// secrets are picked up without a restart. A failed read keeps the current
+// value.
+func (s *Secrets) Run(ctx context.Context) {
+	if s == nil || s.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, secret := range s.all() {
+			if err := s.refresh(ctx, secret); err != nil {
+				logging.FromContext(ctx).Warn("Could not refresh secret. The current value is kept.", "secret", secret.name, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+func TestVaultSecretProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/data/jacad":
+			w.Write([]byte(`{"data":{"data":{"value":"user-token","other":"x"},"metadata":{"version":3}}}`))
+		case "/v1/kv/data/google":
+			w.Write([]byte(`{"data":{"data":{"credentials":{"type":"service_account"}}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewVaultSecretProvider(server.URL+"/", "root", "/kv/")
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "jacad", want: "user-token"},
+		{name: "jacad#other", want: "x"},
+		{name: "google#credentials", want: `{"type":"service_account"}`},
+		{name: "jacad#missing", wantErr: true},
+		{name: "unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := provider.Secret(context.Background(), tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Secret(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("Secret(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := NewVaultSecretProvider(server.URL, "wrong", "kv").Secret(context.Background(), "jacad"); err == nil {
+		t.Error("Secret with a rejected token did not fail")
+	}
+}
+
+// fakeSecretProvider serves values from a map that tests change to rotate
+// secrets.
+type fakeSecretProvider struct {
+	mu     sync.Mutex
+	values map[string]string
+	err    error
+}
+
+func (p *fakeSecretProvider) Secret(ctx context.Context, name string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	value, ok := p.values[name]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return []byte(value), nil
+}
+
+func (p *fakeSecretProvider) set(name, value string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.values[name] = value
+	p.err = err
+}
+
+func TestSecretsRotation(t *testing.T) {
+	provider := &fakeSecretProvider{values: map[string]string{"jacad-token": "first\n"}}
+	if _, err := LoadSecrets(context.Background(), provider, "jacad-token", "google-credentials", time.Hour); err == nil {
+		t.Fatal("LoadSecrets with a missing secret did not fail")
+	}
+
+	secrets, err := LoadSecrets(context.Background(), provider, "jacad-token", "", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secrets.GoogleCredentials != nil {
+		t.Error("GoogleCredentials is set without a secret name")
+	}
+	cfg := config.Defaults()
+	cfg.UserToken = "from-env"
+	client := &JacadClient{Config: &cfg, UserToken: secrets.UserToken}
+	profile, _ := client.profile(context.Background())
+	if profile.UserToken != "first" {
+		t.Errorf("UserToken = %q, want the secret", profile.UserToken)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go secrets.Run(ctx)
+
+	// A failed refresh keeps the current value.
+	provider.set("jacad-token", "ignored", errors.New("unavailable"))
+	time.Sleep(30 * time.Millisecond)
+	if got := secrets.UserToken.Text(); got != "first" {
+		t.Errorf("after a failed refresh UserToken = %q, want first", got)
+	}
+
+	provider.set("jacad-token", "second", nil)
+	deadline := time.Now().Add(time.Second)
+	for secrets.UserToken.Text() != "second" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	profile, _ = client.profile(context.Background())
+	if profile.UserToken != "second" {
+		t.Errorf("after rotation UserToken = %q, want second", profile.UserToken)
+	}
+	if _, version := secrets.UserToken.Value(); version != 2 {
+		t.Errorf("version = %d, want 2", version)
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HealthChecker is implemented by writers that can verify their destination is
+// reachable with the configured credentials.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// SelfCheck verifies that a Jacad token can be obtained for every tenant and that the writer's
+// destination is reachable.
+func (c *JacadClient) SelfCheck(ctx context.Context) []CheckResult {
+	return []CheckResult{
+		runCheck("jacad_auth", func() error {
+			if _, err := c.GetAuthToken(ctx); err != nil {
+				return err
+			}
+			for _, tenant := range c.Config.TenantNames() {
+				if _, err := c.GetAuthToken(WithTenant(ctx, tenant)); err != nil {
+					return fmt.Errorf("tenant '%s': %w", tenant, err)
+				}
+			}
+			return nil
+		}),
+		runCheck("writer", func() error {
+			if c.Writer == nil {
+				return fmt.Errorf("no writer configured")
+			}
+			checker, ok := c.Writer.(HealthChecker)
+			if !ok {
+				return nil
+			}
+			if err := checker.CheckHealth(ctx); err != nil {
+				return err
+			}
+			if _, ok := primaryWriter(c.Writer).(*GoogleSheetsWriter); ok {
+				for _, org := range c.Config.Organizations.All() {
+					if org.SpreadsheetID == "" {
+						continue
+					}
+					if err := checker.CheckHealth(WithSpreadsheetID(ctx, org.SpreadsheetID)); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}),
+	}
+}
+
+// SelfCheckError summarises the failed checks in a single report.
+func SelfCheckError(results []CheckResult) error {
+	var failed []string
+	for _, result := range results {
+		if !result.OK {
+			failed = append(failed, fmt.Sprintf("%s: %s", result.Name, result.Error))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("self-check failed (%d check(s)):\n  - %s", len(failed), strings.Join(failed, "\n  - "))
+}
+
+func runCheck(name string, check func() error) CheckResult {
+	started := time.Now()
+	err := check()
+	result := CheckResult{Name: name, OK: err == nil, Duration: time.Since(started).String()}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/api/sheets/v4"
+)
+
+// Google Sheets rejects request payloads above roughly 2MB; writes are split
+// well below that and below maxRowsPerWrite rows. Overwrites are further split
+// into value ranges of maxRowsPerValueRange rows, the unit retried when a
+// request fails.
+const (
+	maxRowsPerWrite      = 10000
+	maxBytesPerWrite     = 2 << 20
+	maxRowsPerValueRange = 1000
+)
+
+// sheetsWriteOperations are the executeSheetsCall operations that count
+// against the per-minute write quota.
+var sheetsWriteOperations = map[string]bool{
+	"append":       true,
+	"overwrite":    true,
+	"upsert":       true,
+	"clear":        true,
+	"set_headers":  true,
+	"create_sheet": true,
+	"delete_sheet": true,
+	"swap_sheet":   true,
+}
+
+type appendBufferKey struct {
+	spreadsheetID string
+	sheet         string
+}
+
+// pendingAppendKey keys buffered appends on the job that queued them too, so
+// a cancelled job's rows can be dropped without touching other jobs'.
+type pendingAppendKey struct {
+	appendBufferKey
+	jobID string
+}
+
+func (w *GoogleSheetsWriter) pendingKey(ctx context.Context, sheetName string) pendingAppendKey {
+	return pendingAppendKey{
+		appendBufferKey: appendBufferKey{spreadsheetID: w.spreadsheetFor(ctx), sheet: sheetName},
+		jobID:           logging.JobID(ctx),
+	}
+}
+
+// bufferAppend queues rows for sheetName and returns the buffered rows once
+// they reach coalesceRows, leaving the caller to write them.
+func (w *GoogleSheetsWriter) bufferAppend(ctx context.Context, sheetName string, rows [][]interface{}) [][]interface{} {
+	key := w.pendingKey(ctx, sheetName)
+
+	w.muBuffers.Lock()
+	defer w.muBuffers.Unlock()
+	buffered := append(w.appendBuffers[key], rows...)
+	if len(buffered) < w.coalesceRows {
+		w.appendBuffers[key] = buffered
+		return nil
+	}
+	delete(w.appendBuffers, key)
+	return buffered
+}
+
+// takeBuffered removes and returns the rows the job in ctx queued for
+// sheetName.
+func (w *GoogleSheetsWriter) takeBuffered(ctx context.Context, sheetName string) [][]interface{} {
+	key := w.pendingKey(ctx, sheetName)
+
+	w.muBuffers.Lock()
+	defer w.muBuffers.Unlock()
+	rows := w.appendBuffers[key]
+	delete(w.appendBuffers, key)
+	return rows
+}
+
+// flushSheet writes the rows queued for sheetName, if any.
+func (w *GoogleSheetsWriter) flushSheet(ctx context.Context, sheetName string) error {
+	return w.appendNow(ctx, sheetName, w.takeBuffered(ctx, sheetName))
+}
+
+// Flush writes every queued append. It implements Flusher.
+func (w *GoogleSheetsWriter) Flush(ctx context.Context) error {
+	w.muBuffers.Lock()
+	buffers := w.appendBuffers
+	w.appendBuffers = make(map[pendingAppendKey][][]interface{})
+	w.muBuffers.Unlock()
+
+	var errs []error
+	for key, rows := range buffers {
+		if err := w.appendNow(WithSpreadsheetID(ctx, key.spreadsheetID), key.sheet, rows); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DiscardBuffered drops the rows the job in ctx queued and not yet wrote, and
+// returns how many there were. It implements BufferDiscarder.
+func (w *GoogleSheetsWriter) DiscardBuffered(ctx context.Context) int {
+	jobID := logging.JobID(ctx)
+
+	w.muBuffers.Lock()
+	defer w.muBuffers.Unlock()
+	discarded := 0
+	for key, rows := range w.appendBuffers {
+		if key.jobID == jobID {
+			discarded += len(rows)
+			delete(w.appendBuffers, key)
+		}
+	}
+	return discarded
+}
+
+// waitWriteQuota blocks until the per-minute write budget allows another call.
+func (w *GoogleSheetsWriter) waitWriteQuota(ctx context.Context, operation string) error {
+	if !sheetsWriteOperations[operation] {
+		return nil
+	}
+	if w.writeLimiter.Allow() {
+		return nil
+	}
+	logging.FromContext(ctx).Debug("API Sheets: Aguardando cota de escrita por minuto...", "operation", operation)
+	sheetsQuotaWaitsTotal.Inc(operation)
+	ctx, span := tracing.Start(ctx, "sheets quota wait", attribute.String("sheets.operation", operation))
+	err := w.writeLimiter.Wait(ctx)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("espera pela cota de escrita cancelada: %w", err)
+	}
+	return nil
+}
+
+// splitRowsForWrite splits rows into chunks of at most maxRows rows and
+// roughly maxBytes of JSON each. A single row larger than maxBytes gets its
+// own chunk.
+func splitRowsForWrite(rows [][]interface{}, maxRows, maxBytes int) [][][]interface{} {
+	var chunks [][][]interface{}
+	start, size := 0, 0
+	for i, row := range rows {
+		rowSize := estimateRowBytes(row)
+		if i > start && (i-start >= maxRows || size+rowSize > maxBytes) {
+			chunks = append(chunks, rows[start:i])
+			start, size = i, 0
+		}
+		size += rowSize
+	}
+	if start < len(rows) {
+		chunks = append(chunks, rows[start:])
+	}
+	return chunks
+}
+
+// batchValueRanges splits rows, written to sheetName from A1, into value
+// ranges of at most rangeRows rows, grouped into batches of at most maxRows
+// rows and about maxBytes. A row larger than maxBytes gets a batch of its own.
+func batchValueRanges(sheetName string, rows [][]interface{}, rangeRows, maxRows, maxBytes int) [][]*sheets.ValueRange {
+	var batches [][]*sheets.ValueRange
+	var batch []*sheets.ValueRange
+	start, batchRows, batchBytes := 0, 0, 0
+	closeRange := func(end int) {
+		if end > start {
+			batch = append(batch, &sheets.ValueRange{Range: fmt.Sprintf("'%s'!A%d", sheetName, start+1), Values: rows[start:end]})
+		}
+		start = end
+	}
+	for i, row := range rows {
+		rowSize := estimateRowBytes(row)
+		if batchRows > 0 && (batchRows >= maxRows || batchBytes+rowSize > maxBytes) {
+			closeRange(i)
+			batches = append(batches, batch)
+			batch, batchRows, batchBytes = nil, 0, 0
+		} else if i-start >= rangeRows {
+			closeRange(i)
+		}
+		batchRows++
+		batchBytes += rowSize
+	}
+	closeRange(len(rows))
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+func estimateRowBytes(row []interface{}) int {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return len(fmt.Sprint(row))
+	}
+	return len(data) + 1
+}
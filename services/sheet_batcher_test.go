@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestDiscardBufferedKeepsOtherJobs(t *testing.T) {
+	w := &GoogleSheetsWriter{spreadsheetID: "sheet", coalesceRows: 10, appendBuffers: make(map[pendingAppendKey][][]interface{})}
+	cancelled := logging.WithJobID(context.Background(), "job-a")
+	other := logging.WithJobID(context.Background(), "job-b")
+
+	w.bufferAppend(cancelled, "EAD", [][]interface{}{{1}, {2}})
+	w.bufferAppend(cancelled, "POS", [][]interface{}{{3}})
+	w.bufferAppend(other, "EAD", [][]interface{}{{4}})
+
+	if got := w.DiscardBuffered(cancelled); got != 3 {
+		t.Errorf("DiscardBuffered() = %d, want 3", got)
+	}
+	if rows := w.takeBuffered(cancelled, "EAD"); len(rows) != 0 {
+		t.Errorf("cancelled job still has %d buffered rows", len(rows))
+	}
+	if rows := w.takeBuffered(other, "EAD"); len(rows) != 1 {
+		t.Errorf("other job has %d buffered rows, want 1", len(rows))
+	}
+}
+
+func TestBatchValueRanges(t *testing.T) {
+	rows := make([][]interface{}, 7)
+	for i := range rows {
+		rows[i] = []interface{}{i}
+	}
+	var got [][]string
+	for _, batch := range batchValueRanges("EAD", rows, 2, 5, 1<<20) {
+		var ranges []string
+		for _, valueRange := range batch {
+			ranges = append(ranges, valueRange.Range)
+		}
+		got = append(got, ranges)
+	}
+	want := [][]string{{"'EAD'!A1", "'EAD'!A3", "'EAD'!A5"}, {"'EAD'!A6"}}
+	if !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Errorf("batchValueRanges() = %v, want %v", got, want)
+	}
+
+	// Each row takes 4 bytes ("[0]" plus a newline), so 10 bytes fit two.
+	if batches := batchValueRanges("EAD", rows[:5], 10, 10, 10); len(batches) != 3 {
+		t.Errorf("batchValueRanges() made %d batches under the byte limit, want 3", len(batches))
+	}
+}
+
+func TestWriteValuesRetriesFailedRanges(t *testing.T) {
+	var mu sync.Mutex
+	var requests [][]string
+	failed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/spreadsheets/dest/values:batchUpdate") {
+			http.NotFound(w, r)
+			return
+		}
+		var req sheets.BatchUpdateValuesRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		defer mu.Unlock()
+		var ranges []string
+		rows := 0
+		for _, valueRange := range req.Data {
+			ranges = append(ranges, valueRange.Range)
+			rows += len(valueRange.Values)
+		}
+		requests = append(requests, ranges)
+		// The second batch fails once.
+		if len(requests) == 2 && !failed {
+			failed = true
+			http.Error(w, `{"error": {"code": 400, "message": "bad range"}}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(sheets.BatchUpdateValuesResponse{TotalUpdatedRows: int64(rows)})
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	service, err := sheets.NewService(ctx, option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &GoogleSheetsWriter{sheetsService: service, spreadsheetID: "dest", writeLimiter: NewPerMinuteLimiter(0)}
+
+	rows := make([][]interface{}, 2*maxRowsPerWrite+1)
+	for i := range rows {
+		rows[i] = []interface{}{i}
+	}
+	updated, err := w.writeValues(ctx, "EAD", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != len(rows) {
+		t.Errorf("writeValues() = %d rows, want %d", updated, len(rows))
+	}
+	// Three batches, then the ten ranges of the failed second batch alone.
+	if len(requests) != 3+maxRowsPerWrite/maxRowsPerValueRange {
+		t.Fatalf("sent %d requests, want %d", len(requests), 3+maxRowsPerWrite/maxRowsPerValueRange)
+	}
+	if retried := requests[3]; len(retried) != 1 || retried[0] != requests[1][0] {
+		t.Errorf("first retry sent %v, want the failed batch's first range %s", retried, requests[1][0])
+	}
+}
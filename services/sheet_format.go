@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"google.golang.org/api/sheets/v4"
+)
+
+// sheetDateLayout is parsed as a date by USER_ENTERED writes, unlike the
+// RFC 3339 text time.Time values are otherwise sent as.
+const sheetDateLayout = "2006-01-02 15:04:05"
+
+const sheetDatePattern = "dd/mm/yyyy"
+
+var (
+	bandHeaderColor = &sheets.Color{Red: 0.85, Green: 0.89, Blue: 0.95}
+	bandFirstColor  = &sheets.Color{Red: 1, Green: 1, Blue: 1}
+	bandSecondColor = &sheets.Color{Red: 0.95, Green: 0.96, Blue: 0.98}
+)
+
+// sheetDateCells returns rows with time.Time and SerialDate cells written as
+// sheet dates and the indexes of the columns holding them. Rows with dates are
+// copied, so the caller's rows are left untouched.
+func sheetDateCells(rows [][]interface{}) ([][]interface{}, []int) {
+	isDate := make(map[int]bool)
+	converted := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		converted[i] = row
+		copied := false
+		for j, cell := range row {
+			var value interface{}
+			switch v := cell.(type) {
+			case time.Time:
+				value = v.Format(sheetDateLayout)
+			case SerialDate:
+				value = float64(v)
+			default:
+				continue
+			}
+			if !copied {
+				converted[i] = append([]interface{}(nil), row...)
+				copied = true
+			}
+			converted[i][j] = value
+			isDate[j] = true
+		}
+	}
+
+	var columns []int
+	for col := 0; col < maxRowLength(rows); col++ {
+		if isDate[col] {
+			columns = append(columns, col)
+		}
+	}
+	return converted, columns
+}
+
+func maxRowLength(rows [][]interface{}) int {
+	n := 0
+	for _, row := range rows {
+		n = max(n, len(row))
+	}
+	return n
+}
+
+// FormatSheet bolds and freezes the header row, formats dateColumns as DATE,
+// bands the rows and auto-resizes the columns of sheetName, in one
+// batchUpdate. Banding left by a previous run is replaced.
+func (w *GoogleSheetsWriter) FormatSheet(ctx context.Context, sheetName string, columns, rows int, dateColumns []int) error {
+	ctx, unlock, err := w.lockSheet(ctx, "format_sheet", sheetName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	logger := logging.FromContext(ctx).With("sheet", sheetName)
+
+	spreadsheet, err := w.sheetsService.Spreadsheets.Get(w.spreadsheetFor(ctx)).
+		Fields("sheets(properties(sheetId,title),bandedRanges(bandedRangeId))").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return fmt.Errorf("falha ao obter detalhes da planilha '%s' para formatar a aba '%s': %w", w.spreadsheetFor(ctx), sheetName, err)
+	}
+	var sheet *sheets.Sheet
+	for _, s := range spreadsheet.Sheets {
+		if s.Properties.Title == sheetName {
+			sheet = s
+			break
+		}
+	}
+	if sheet == nil {
+		return fmt.Errorf("aba '%s' não encontrada na planilha '%s'", sheetName, w.spreadsheetFor(ctx))
+	}
+	sheetID := sheet.Properties.SheetId
+
+	var reqs []*sheets.Request
+	for _, banded := range sheet.BandedRanges {
+		reqs = append(reqs, &sheets.Request{DeleteBanding: &sheets.DeleteBandingRequest{BandedRangeId: banded.BandedRangeId}})
+	}
+	reqs = append(reqs,
+		&sheets.Request{UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: &sheets.SheetProperties{SheetId: sheetID, GridProperties: &sheets.GridProperties{FrozenRowCount: 1}},
+			Fields:     "gridProperties.frozenRowCount",
+		}},
+		&sheets.Request{RepeatCell: &sheets.RepeatCellRequest{
+			Range:  &sheets.GridRange{SheetId: sheetID, StartRowIndex: 0, EndRowIndex: 1, StartColumnIndex: 0, EndColumnIndex: int64(columns)},
+			Cell:   &sheets.CellData{UserEnteredFormat: &sheets.CellFormat{TextFormat: &sheets.TextFormat{Bold: true}}},
+			Fields: "userEnteredFormat.textFormat.bold",
+		}},
+	)
+	if rows > 0 {
+		for _, col := range dateColumns {
+			reqs = append(reqs, &sheets.Request{RepeatCell: &sheets.RepeatCellRequest{
+				Range: &sheets.GridRange{SheetId: sheetID, StartRowIndex: 1, EndRowIndex: int64(rows) + 1, StartColumnIndex: int64(col), EndColumnIndex: int64(col) + 1},
+				Cell: &sheets.CellData{UserEnteredFormat: &sheets.CellFormat{
+					NumberFormat: &sheets.NumberFormat{Type: "DATE", Pattern: sheetDatePattern},
+				}},
+				Fields: "userEnteredFormat.numberFormat",
+			}})
+		}
+	}
+	reqs = append(reqs,
+		&sheets.Request{AddBanding: &sheets.AddBandingRequest{BandedRange: &sheets.BandedRange{
+			Range: &sheets.GridRange{SheetId: sheetID, StartRowIndex: 0, EndRowIndex: int64(rows) + 1, StartColumnIndex: 0, EndColumnIndex: int64(columns)},
+			RowProperties: &sheets.BandingProperties{
+				HeaderColor:     bandHeaderColor,
+				FirstBandColor:  bandFirstColor,
+				SecondBandColor: bandSecondColor,
+			},
+		}}},
+		&sheets.Request{AutoResizeDimensions: &sheets.AutoResizeDimensionsRequest{
+			Dimensions: &sheets.DimensionRange{SheetId: sheetID, Dimension: "COLUMNS", StartIndex: 0, EndIndex: int64(columns)},
+		}},
+	)
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{Requests: reqs}
+	batchUpdateCallFunc := func() error {
+		logger.Debug("API Sheets: Executando BatchUpdate para formatar a aba...", "requests", len(reqs))
+		_, err := w.sheetsService.Spreadsheets.BatchUpdate(w.spreadsheetFor(ctx), batchUpdateRequest).Context(ctx).Do()
+		return err
+	}
+	if err := w.executeSheetsCall(ctx, "format_sheet", batchUpdateCallFunc, fmt.Sprintf("formatar aba '%s'", sheetName)); err != nil {
+		return fmt.Errorf("falha ao formatar a aba '%s': %w", sheetName, err)
+	}
+
+	logger.Info("API Sheets: Aba formatada com sucesso.", "dateColumns", len(dateColumns))
+	return nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestSheetDateCells(t *testing.T) {
+	matricula := time.Date(2025, time.February, 10, 8, 30, 0, 0, time.UTC)
+	plain := []interface{}{"Ana", 7}
+	rows := [][]interface{}{
+		plain,
+		{"Bia", 8, matricula},
+		{"Caio", 9, nil, SerialDate(45698)},
+	}
+
+	converted, columns := sheetDateCells(rows)
+
+	want := [][]interface{}{
+		{"Ana", 7},
+		{"Bia", 8, "2025-02-10 08:30:00"},
+		{"Caio", 9, nil, float64(45698)},
+	}
+	if !reflect.DeepEqual(converted, want) {
+		t.Errorf("sheetDateCells() rows = %v, want %v", converted, want)
+	}
+	if !slices.Equal(columns, []int{2, 3}) {
+		t.Errorf("sheetDateCells() columns = %v, want [2 3]", columns)
+	}
+	if rows[1][2] != matricula {
+		t.Errorf("sheetDateCells() rewrote the caller's row: %v", rows[1])
+	}
+	if &converted[0][0] != &plain[0] {
+		t.Error("sheetDateCells() copied a row without dates")
+	}
+}
+
+func TestOverwriteSheetDataFormatsSheet(t *testing.T) {
+	api := newFakeSheetsAPI()
+	writer := newFakeSheetsWriter(t, api)
+	writer.formatSheets = true
+	ctx := context.Background()
+	matricula := time.Date(2025, time.February, 10, 0, 0, 0, 0, time.UTC)
+
+	headers := []string{"aluno", "idMatricula", "dataMatricula"}
+	rows := [][]interface{}{{"Ana", 7, matricula}, {"Bia", 8, nil}}
+	for run := 0; run < 2; run++ {
+		if err := writer.OverwriteSheetData(ctx, "EAD", headers, rows); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	values, _ := api.Tab("book", "EAD")
+	if got := values[1][2]; got != "2025-02-10 00:00:00" {
+		t.Errorf("date cell = %v, want a USER_ENTERED date", got)
+	}
+
+	formats, banded := api.Formats("book", "EAD")
+	if len(banded) != 1 {
+		t.Errorf("tab has %d banded ranges after two runs, want the previous one replaced", len(banded))
+	}
+	var deleted, frozen, bold, dates, bands, resized int
+	for _, req := range formats {
+		switch {
+		case req.DeleteBanding != nil:
+			deleted++
+		case req.UpdateSheetProperties != nil:
+			if req.UpdateSheetProperties.Properties.GridProperties.FrozenRowCount == 1 {
+				frozen++
+			}
+		case req.RepeatCell != nil && req.RepeatCell.Cell.UserEnteredFormat.TextFormat != nil:
+			r := req.RepeatCell.Range
+			if r.StartRowIndex == 0 && r.EndRowIndex == 1 && r.EndColumnIndex == 3 {
+				bold++
+			}
+		case req.RepeatCell != nil:
+			r, format := req.RepeatCell.Range, req.RepeatCell.Cell.UserEnteredFormat.NumberFormat
+			if r.StartColumnIndex == 2 && r.EndColumnIndex == 3 && r.StartRowIndex == 1 && r.EndRowIndex == 3 &&
+				reflect.DeepEqual(format, &sheets.NumberFormat{Type: "DATE", Pattern: sheetDatePattern}) {
+				dates++
+			}
+		case req.AddBanding != nil:
+			if r := req.AddBanding.BandedRange.Range; r.EndRowIndex == 3 && r.EndColumnIndex == 3 {
+				bands++
+			}
+		case req.AutoResizeDimensions != nil:
+			if d := req.AutoResizeDimensions.Dimensions; d.Dimension == "COLUMNS" && d.EndIndex == 3 {
+				resized++
+			}
+		}
+	}
+	if got, want := []int{deleted, frozen, bold, dates, bands, resized}, []int{1, 2, 2, 2, 2, 2}; !slices.Equal(got, want) {
+		t.Errorf("format requests (deleted banding, frozen, bold, date, banding, resize) = %v, want %v", got, want)
+	}
+}
+
+func TestOverwriteSheetDataWithoutFormatting(t *testing.T) {
+	api := newFakeSheetsAPI()
+	writer := newFakeSheetsWriter(t, api)
+	matricula := time.Date(2025, time.February, 10, 0, 0, 0, 0, time.UTC)
+
+	if err := writer.OverwriteSheetData(context.Background(), "EAD", []string{"dataMatricula"}, [][]interface{}{{matricula}}); err != nil {
+		t.Fatal(err)
+	}
+	if formats, banded := api.Formats("book", "EAD"); len(formats) != 0 || len(banded) != 0 {
+		t.Errorf("formatting disabled sent %d format requests", len(formats))
+	}
+	values, _ := api.Tab("book", "EAD")
+	if got := values[1][0]; got != matricula.Format(time.RFC3339) {
+		t.Errorf("date cell = %v, want the RFC 3339 text", got)
+	}
+}
+
+func TestFormatSheetFailureKeepsData(t *testing.T) {
+	api := newFakeSheetsAPI()
+	writer := newFakeSheetsWriter(t, api)
+	writer.formatSheets = true
+	api.SetTab("book", "EAD", nil)
+	calls := 0
+	api.failOn = func(method, path string) error {
+		if path == "/v4/spreadsheets/book:batchUpdate" {
+			calls++
+			return errors.New("formatting not allowed")
+		}
+		return nil
+	}
+
+	if err := writer.OverwriteSheetData(context.Background(), "EAD", []string{"aluno"}, [][]interface{}{{"Ana"}}); err != nil {
+		t.Fatalf("OverwriteSheetData() = %v, want the write to succeed without formatting", err)
+	}
+	if values, _ := api.Tab("book", "EAD"); len(values) != 2 {
+		t.Errorf("tab = %v, want the header and one row", values)
+	}
+	if calls == 0 {
+		t.Error("formatting was not attempted")
+	}
+}
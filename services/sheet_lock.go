@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+)
+
+// SheetLocker serializes writes to a tab so two jobs targeting the same sheet
+// cannot interleave their Clear and Append calls. LocalSheetLocker covers a
+// single process; replicas writing to the same spreadsheet need a shared
+// implementation such as a Redis lease.
+type SheetLocker interface {
+	// Lock blocks until key is free or ctx is done. The returned unlock must
+	// be called once the write is finished.
+	Lock(ctx context.Context, key string) (unlock func(), err error)
+}
+
+// LocalSheetLocker is an in-process SheetLocker. Locks are created on first
+// use and dropped once nobody holds or waits for them.
+type LocalSheetLocker struct {
+	mu    sync.Mutex
+	locks map[string]*sheetLock
+}
+
+type sheetLock struct {
+	held chan struct{}
+	// refs counts the holder and the waiters.
+	refs int
+}
+
+func NewLocalSheetLocker() *LocalSheetLocker {
+	return &LocalSheetLocker{locks: make(map[string]*sheetLock)}
+}
+
+func (l *LocalSheetLocker) Lock(ctx context.Context, key string) (func(), error) {
+	l.mu.Lock()
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &sheetLock{held: make(chan struct{}, 1)}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	select {
+	case lock.held <- struct{}{}:
+	case <-ctx.Done():
+		l.release(key, lock)
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-lock.held
+			l.release(key, lock)
+		})
+	}, nil
+}
+
+func (l *LocalSheetLocker) release(key string, lock *sheetLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, key)
+	}
+}
+
+type heldSheetLocksKey struct{}
+
+// lockSheet takes the writer's lock on sheetName in the spreadsheet ctx
+// routes to and returns a context marking it as held, so the writer's own
+// nested calls on the same tab (e.g. OverwriteSheetData clearing it) do not
+// wait for themselves.
+func (w *GoogleSheetsWriter) lockSheet(ctx context.Context, operation, sheetName string) (context.Context, func(), error) {
+	if w.locker == nil {
+		return ctx, func() {}, nil
+	}
+	key := w.spreadsheetFor(ctx) + "/" + sheetName
+	held, _ := ctx.Value(heldSheetLocksKey{}).(map[string]bool)
+	if held[key] {
+		return ctx, func() {}, nil
+	}
+
+	start := time.Now()
+	unlock, err := w.locker.Lock(ctx, key)
+	waited := time.Since(start)
+	sheetsLockWait.Observe(waited.Seconds(), operation)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("espera pelo bloqueio da aba '%s' cancelada: %w", sheetName, err)
+	}
+	if waited >= time.Second {
+		logging.FromContext(ctx).Info("API Sheets: Escrita aguardou outra escrita na mesma aba.", "sheet", sheetName, "operation", operation, "waited", waited.String())
+	}
+
+	withKey := make(map[string]bool, len(held)+1)
+	for k := range held {
+		withKey[k] = true
+	}
+	withKey[key] = true
+	return context.WithValue(ctx, heldSheetLocksKey{}, withKey), unlock, nil
+}
+
+// LeaseSheet takes the lock on sheetName for a whole write session; the
+// writer's calls made with the returned context reuse it instead of locking
+// the tab per call.
+func (w *GoogleSheetsWriter) LeaseSheet(ctx context.Context, sheetName string, wait time.Duration) (context.Context, func(), error) {
+	waitCtx := ctx
+	if wait > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, wait)
+		defer cancel()
+	}
+	held, unlock, err := w.lockSheet(waitCtx, "lease", sheetName)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return context.WithValue(ctx, heldSheetLocksKey{}, held.Value(heldSheetLocksKey{})), unlock, nil
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+)
+
+func TestLocalSheetLockerSerializes(t *testing.T) {
+	locker := NewLocalSheetLocker()
+	unlock, err := locker.Lock(context.Background(), "sheet/EAD")
+	if err != nil {
+		t.Fatalf("Lock() = %v", err)
+	}
+
+	other, err := locker.Lock(context.Background(), "sheet/POS")
+	if err != nil {
+		t.Fatalf("Lock() of another tab = %v", err)
+	}
+	other()
+
+	acquired := make(chan func(), 1)
+	go func() {
+		next, err := locker.Lock(context.Background(), "sheet/EAD")
+		if err != nil {
+			t.Errorf("second Lock() = %v", err)
+		}
+		acquired <- next
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second Lock() of the same tab did not wait for the first")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	unlock()
+	unlock()
+	(<-acquired)()
+	if len(locker.locks) != 0 {
+		t.Errorf("locker keeps %d locks after every holder released", len(locker.locks))
+	}
+}
+
+func TestLocalSheetLockerCancelledWait(t *testing.T) {
+	locker := NewLocalSheetLocker()
+	unlock, err := locker.Lock(context.Background(), "sheet/EAD")
+	if err != nil {
+		t.Fatalf("Lock() = %v", err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := locker.Lock(ctx, "sheet/EAD"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Lock() of a held tab = %v, want DeadlineExceeded", err)
+	}
+	if refs := locker.locks["sheet/EAD"].refs; refs != 1 {
+		t.Errorf("lock refs = %d after the waiter gave up, want 1", refs)
+	}
+}
+
+func TestLockSheetIsReentrant(t *testing.T) {
+	w := &GoogleSheetsWriter{spreadsheetID: "sheet", locker: NewLocalSheetLocker()}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	held, unlock, err := w.lockSheet(ctx, "overwrite", "EAD")
+	if err != nil {
+		t.Fatalf("lockSheet() = %v", err)
+	}
+	defer unlock()
+
+	_, nested, err := w.lockSheet(held, "clear", "EAD")
+	if err != nil {
+		t.Fatalf("nested lockSheet() = %v, want the held lock to be reused", err)
+	}
+	nested()
+
+	waitCtx, cancelWait := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelWait()
+	if _, _, err := w.lockSheet(waitCtx, "clear", "EAD"); err == nil {
+		t.Error("lockSheet() without the holder's context did not wait for the lock")
+	}
+}
+
+func TestLeaseSheetWaitsAndTimesOut(t *testing.T) {
+	w := &GoogleSheetsWriter{spreadsheetID: "sheet", locker: NewLocalSheetLocker()}
+	ctx := context.Background()
+
+	leased, release, err := w.LeaseSheet(ctx, "EAD", 0)
+	if err != nil {
+		t.Fatalf("LeaseSheet() = %v", err)
+	}
+	if _, nested, err := w.lockSheet(leased, "append", "EAD"); err != nil {
+		t.Fatalf("lockSheet() under the lease = %v", err)
+	} else {
+		nested()
+	}
+
+	if _, _, err := w.LeaseSheet(ctx, "EAD", 10*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("LeaseSheet() of a leased tab = %v, want DeadlineExceeded", err)
+	}
+	if leased.Err() != nil {
+		t.Errorf("leased context done after the wait: %v", leased.Err())
+	}
+	release()
+	if _, again, err := w.LeaseSheet(ctx, "EAD", 10*time.Millisecond); err != nil {
+		t.Errorf("LeaseSheet() after release = %v", err)
+	} else {
+		again()
+	}
+}
+
+// TestConcurrentStreamedJobsDoNotInterleave streams two jobs into the same tab
+// at once. Each holds the tab from its header to its row count check, so
+// neither clears the tab under the other's appends.
+func TestConcurrentStreamedJobsDoNotInterleave(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer server.Close()
+
+	api := newFakeSheetsAPI()
+	writer := newFakeSheetsWriter(t, api)
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.PageSize = 10
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	client := NewJacadClient(&cfg, writer, NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+
+	var wg sync.WaitGroup
+	results := make([]*FetchResult, 2)
+	errs := make([]error, 2)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := logging.WithJobID(context.Background(), fmt.Sprintf("job-%d", i))
+			results[i], errs[i] = client.FetchEnrollmentsFiltered(ctx, &requests.FetchEnrollmentsRequest{
+				IdPeriodoLetivo: 87,
+				StatusMatricula: "ATIVA",
+				Mode:            requests.SyncModeFull,
+				WriteMode:       requests.WriteModeStream,
+				GroupBy:         requests.GroupByNone,
+			})
+		}()
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		if errs[i] != nil {
+			t.Fatalf("job %d: %v", i, errs[i])
+		}
+		for _, org := range result.Organizations {
+			if org.Error != "" {
+				t.Errorf("job %d, sheet %q: %s", i, org.Sheet, org.Error)
+			}
+		}
+	}
+	sheet := results[0].Organizations[0].Sheet
+	if values, _ := api.Tab("book", sheet); len(values) != 181 {
+		t.Errorf("tab %q has %d rows, want the header and 180 enrollments", sheet, len(values))
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"google.golang.org/api/sheets/v4"
+)
+
+// maxCellsPerSpreadsheet is the Google Sheets limit on cells across all tabs
+// of a spreadsheet.
+const maxCellsPerSpreadsheet = 10_000_000
+
+func splitTabName(sheetName string, part int) string {
+	return fmt.Sprintf("%s (%d)", sheetName, part)
+}
+
+// overwriteSplit writes rows to sheetName when they fit in maxRowsPerTab and
+// to numbered tabs otherwise. Tabs left from an earlier, larger write are
+// cleared, as is sheetName itself when the data is split, so no stale rows
+// remain next to the new ones.
+func (w *GoogleSheetsWriter) overwriteSplit(ctx context.Context, sheetName string, headers []string, rows [][]interface{}) error {
+	logger := logging.FromContext(ctx).With("sheet", sheetName)
+
+	existing, err := w.splitTabs(ctx, sheetName)
+	if err != nil {
+		return err
+	}
+
+	parts := 0
+	if len(rows) <= w.maxRowsPerTab {
+		if err := w.overwriteTab(ctx, sheetName, headers, rows); err != nil {
+			return err
+		}
+	} else {
+		logger.Info("API Sheets: Dados excedem o limite de linhas por aba. Dividindo em abas numeradas.", "rows", len(rows), "maxRowsPerTab", w.maxRowsPerTab)
+		for start := 0; start < len(rows); start += w.maxRowsPerTab {
+			parts++
+			end := min(start+w.maxRowsPerTab, len(rows))
+			if err := w.overwriteTab(ctx, splitTabName(sheetName, parts), headers, rows[start:end]); err != nil {
+				return err
+			}
+		}
+		if existing.base {
+			if err := w.Clear(ctx, sheetName); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, part := range existing.parts {
+		if part > parts {
+			logger.Info("API Sheets: Limpando aba numerada que não recebeu dados nesta escrita.", "part", part)
+			if err := w.Clear(ctx, splitTabName(sheetName, part)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MaxRowsPerTab returns the row limit past which overwrites are split, or 0.
+// It implements TabSplitter.
+func (w *GoogleSheetsWriter) MaxRowsPerTab() int {
+	return w.maxRowsPerTab
+}
+
+// SplitTab renames sheetName to its first numbered tab, replacing a tab left
+// under that name by an earlier write, so a streamed write that outgrows
+// maxRowsPerTab ends up laid out like a split overwrite. It implements
+// TabSplitter.
+func (w *GoogleSheetsWriter) SplitTab(ctx context.Context, sheetName string) error {
+	ctx, unlock, err := w.lockSheet(ctx, "split_sheet", sheetName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	logger := logging.FromContext(ctx).With("sheet", sheetName)
+
+	if err := w.flushSheet(ctx, sheetName); err != nil {
+		return err
+	}
+
+	first := splitTabName(sheetName, 1)
+	spreadsheet, err := w.sheetsService.Spreadsheets.Get(w.spreadsheetFor(ctx)).Fields("sheets.properties(sheetId,title)").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("falha ao listar as abas da planilha '%s': %w", w.spreadsheetFor(ctx), err)
+	}
+	var requests []*sheets.Request
+	var base *sheets.SheetProperties
+	for _, sheet := range spreadsheet.Sheets {
+		switch sheet.Properties.Title {
+		case sheetName:
+			base = sheet.Properties
+		case first:
+			requests = append(requests, &sheets.Request{DeleteSheet: &sheets.DeleteSheetRequest{SheetId: sheet.Properties.SheetId}})
+		}
+	}
+	if base == nil {
+		return fmt.Errorf("aba '%s' não encontrada na planilha '%s'", sheetName, w.spreadsheetFor(ctx))
+	}
+	requests = append(requests, &sheets.Request{UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+		Properties: &sheets.SheetProperties{SheetId: base.SheetId, Title: first},
+		Fields:     "title",
+	}})
+
+	splitCallFunc := func() error {
+		logger.Info("API Sheets: Aba atingiu o limite de linhas. Renomeando para a primeira aba numerada...", "newSheet", first)
+		_, err := w.sheetsService.Spreadsheets.BatchUpdate(w.spreadsheetFor(ctx), &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}).Context(ctx).Do()
+		return err
+	}
+	if err := w.executeSheetsCall(ctx, "split_sheet", splitCallFunc, fmt.Sprintf("renomear aba '%s'", sheetName)); err != nil {
+		return fmt.Errorf("falha ao renomear a aba '%s' para '%s': %w", sheetName, first, err)
+	}
+
+	w.muBuffers.Lock()
+	from := appendBufferKey{spreadsheetID: w.spreadsheetFor(ctx), sheet: sheetName}
+	if count, ok := w.writtenRows[from]; ok {
+		w.writtenRows[appendBufferKey{spreadsheetID: from.spreadsheetID, sheet: first}] = count
+		delete(w.writtenRows, from)
+	}
+	w.muBuffers.Unlock()
+	return nil
+}
+
+type existingSplitTabs struct {
+	base  bool
+	parts []int
+}
+
+// splitTabs lists which of sheetName and its numbered tabs exist.
+func (w *GoogleSheetsWriter) splitTabs(ctx context.Context, sheetName string) (existingSplitTabs, error) {
+	var tabs existingSplitTabs
+	spreadsheet, err := w.sheetsService.Spreadsheets.Get(w.spreadsheetFor(ctx)).Fields("sheets.properties.title").Context(ctx).Do()
+	if err != nil {
+		return tabs, fmt.Errorf("falha ao listar as abas da planilha '%s': %w", w.spreadsheetFor(ctx), err)
+	}
+
+	numbered := regexp.MustCompile(`^` + regexp.QuoteMeta(sheetName) + ` \((\d+)\)$`)
+	for _, sheet := range spreadsheet.Sheets {
+		title := sheet.Properties.Title
+		if title == sheetName {
+			tabs.base = true
+		} else if m := numbered.FindStringSubmatch(title); m != nil {
+			if part, err := strconv.Atoi(m[1]); err == nil {
+				tabs.parts = append(tabs.parts, part)
+			}
+		}
+	}
+	return tabs, nil
+}
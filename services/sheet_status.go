@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Where SheetStatuses read the refreshes from.
+const (
+	SheetStatusSourceAudit   = "audit"
+	SheetStatusSourceHistory = "history"
+)
+
+// SheetStatus is when a sheet was last refreshed and by which job.
+type SheetStatus struct {
+	Sheet       string    `json:"sheet"`
+	RefreshedAt time.Time `json:"refreshedAt"`
+	JobID       string    `json:"jobId"`
+	Job         string    `json:"job,omitempty"`
+	JobStatus   string    `json:"jobStatus,omitempty" doc:"success, partial, failed or cancelled."`
+	AgeSeconds  int64     `json:"ageSeconds"`
+	Stale       bool      `json:"stale"`
+}
+
+// SheetStatusReport lists the last refresh of every sheet a job wrote,
+// stalest first.
+type SheetStatusReport struct {
+	Source            string        `json:"source" doc:"audit when read from the audit tab, history when read from the job history."`
+	StaleAfterSeconds int64         `json:"staleAfterSeconds" doc:"0 when no sheet is flagged stale."`
+	Stale             int           `json:"stale"`
+	Sheets            []SheetStatus `json:"sheets"`
+}
+
+// sheetsEpoch is day 0 of Google Sheets date serial numbers, which count
+// wall-clock days without a time zone.
+var sheetsEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// SheetStatuses reports when each sheet was last refreshed and flags those
+// refreshed more than staleAfter before now; staleAfter <= 0 flags none. The
+// audit tab is read when jobs write one, since it is shared by every
+// instance writing the spreadsheet; otherwise the local job history is used.
+func (c *JacadClient) SheetStatuses(ctx context.Context, staleAfter time.Duration, now time.Time) (*SheetStatusReport, error) {
+	report := &SheetStatusReport{StaleAfterSeconds: int64(max(staleAfter, 0).Seconds())}
+	var refreshes map[string]SheetStatus
+	if writer, ok := primaryWriter(c.Writer).(*GoogleSheetsWriter); ok && c.Config.AuditTab && c.Config.AuditSheet != "" {
+		rows, err := writer.ReadRows(ctx, c.Config.AuditSheet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit tab '%s': %w", c.Config.AuditSheet, err)
+		}
+		report.Source = SheetStatusSourceAudit
+		refreshes = auditRefreshes(rows)
+	} else if c.History != nil {
+		records, err := c.History.List(JobHistoryFilter{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read job history: %w", err)
+		}
+		report.Source = SheetStatusSourceHistory
+		refreshes = historyRefreshes(records)
+	} else {
+		return nil, fmt.Errorf("sheet refreshes are only known with AUDIT_TAB and the sheets writer, or a job history")
+	}
+
+	report.Sheets = make([]SheetStatus, 0, len(refreshes))
+	for _, status := range refreshes {
+		age := now.Sub(status.RefreshedAt)
+		status.AgeSeconds = int64(age.Seconds())
+		status.Stale = staleAfter > 0 && age > staleAfter
+		if status.Stale {
+			report.Stale++
+		}
+		report.Sheets = append(report.Sheets, status)
+	}
+	sort.Slice(report.Sheets, func(i, j int) bool {
+		a, b := report.Sheets[i], report.Sheets[j]
+		if !a.RefreshedAt.Equal(b.RefreshedAt) {
+			return a.RefreshedAt.Before(b.RefreshedAt)
+		}
+		return a.Sheet < b.Sheet
+	})
+	return report, nil
+}
+
+// auditRefreshes finds the latest audit row of every sheet. Columns are
+// looked up by header, and rows whose time cannot be read are skipped.
+func auditRefreshes(rows [][]interface{}) map[string]SheetStatus {
+	refreshes := make(map[string]SheetStatus)
+	if len(rows) == 0 {
+		return refreshes
+	}
+	columns := make(map[string]int, len(rows[0]))
+	for i, header := range rows[0] {
+		columns[fmt.Sprint(header)] = i
+	}
+	raw := func(row []interface{}, header string) interface{} {
+		if i, ok := columns[header]; ok {
+			return cellAt(row, i)
+		}
+		return nil
+	}
+	cell := func(row []interface{}, header string) string {
+		if value := raw(row, header); value != nil {
+			return strings.TrimSpace(fmt.Sprint(value))
+		}
+		return ""
+	}
+
+	// auditHeaders: Job ID, Atualizado em, Job, Status, Abas.
+	for _, row := range rows[1:] {
+		updatedAt, ok := auditTime(raw(row, auditHeaders[1]))
+		if !ok {
+			continue
+		}
+		for _, sheet := range strings.Split(cell(row, auditHeaders[4]), "\n") {
+			sheet = strings.TrimSpace(sheet)
+			if sheet == "" {
+				continue
+			}
+			if latest, ok := refreshes[sheet]; ok && !updatedAt.After(latest.RefreshedAt) {
+				continue
+			}
+			refreshes[sheet] = SheetStatus{
+				Sheet:       sheet,
+				RefreshedAt: updatedAt,
+				JobID:       cell(row, auditHeaders[0]),
+				Job:         cell(row, auditHeaders[2]),
+				JobStatus:   cell(row, auditHeaders[3]),
+			}
+		}
+	}
+	return refreshes
+}
+
+// auditTime reads an "Atualizado em" cell: a date serial number when Sheets
+// recognized the date, or the text auditRow wrote otherwise. Either way it is
+// the server's local time.
+func auditTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case float64:
+		t := sheetsEpoch.Add(time.Duration(v * float64(24*time.Hour))).Round(time.Second)
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local), true
+	case string:
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", strings.TrimSpace(v), time.Local)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// historyRefreshes finds the latest job of every sheet in records.
+func historyRefreshes(records []JobRecord) map[string]SheetStatus {
+	refreshes := make(map[string]SheetStatus)
+	for _, record := range records {
+		for _, sheet := range record.Sheets {
+			if latest, ok := refreshes[sheet]; ok && !record.FinishedAt.After(latest.RefreshedAt) {
+				continue
+			}
+			refreshes[sheet] = SheetStatus{
+				Sheet:       sheet,
+				RefreshedAt: record.FinishedAt,
+				JobID:       record.ID,
+				Job:         record.Job,
+				JobStatus:   record.Status,
+			}
+		}
+	}
+	return refreshes
+}
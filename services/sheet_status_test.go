@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+func TestAuditRefreshes(t *testing.T) {
+	rows := [][]interface{}{
+		{"Job ID", "Atualizado em", "Job", "Status", "Abas", "Filtros", "Linhas", "Duração", "Versão"},
+		{"job-1", "2025-03-01 08:00:00", "fetch-enrollments", "success", "EAD\nPÓS EAD", "", 10, "1m", "dev"},
+		// 2025-03-02 12:00:00 as a date serial number.
+		{"job-2", 45718.5, "fetch-enrollments", "partial", "EAD", "", 5, "1m", "dev"},
+		{"job-3", "not a date", "fetch-enrollments", "success", "Cursos", "", 1, "1s", "dev"},
+		{"job-4", "2025-02-28 08:00:00", "fetch-enrollments", "success", "PÓS EAD", "", 1, "1s", "dev"},
+	}
+	refreshes := auditRefreshes(rows)
+
+	want := map[string]SheetStatus{
+		"EAD":     {Sheet: "EAD", RefreshedAt: time.Date(2025, time.March, 2, 12, 0, 0, 0, time.Local), JobID: "job-2", Job: "fetch-enrollments", JobStatus: "partial"},
+		"PÓS EAD": {Sheet: "PÓS EAD", RefreshedAt: time.Date(2025, time.March, 1, 8, 0, 0, 0, time.Local), JobID: "job-1", Job: "fetch-enrollments", JobStatus: "success"},
+	}
+	if len(refreshes) != len(want) {
+		t.Fatalf("auditRefreshes() = %v, want %v", refreshes, want)
+	}
+	for sheet, status := range want {
+		got := refreshes[sheet]
+		if !got.RefreshedAt.Equal(status.RefreshedAt) || got.JobID != status.JobID || got.JobStatus != status.JobStatus {
+			t.Errorf("sheet %q = %+v, want %+v", sheet, got, status)
+		}
+	}
+}
+
+func TestSheetStatusesFromHistory(t *testing.T) {
+	history := newTestJobHistory(t)
+	now := time.Date(2025, time.March, 3, 12, 0, 0, 0, time.UTC)
+	for _, record := range []JobRecord{
+		{ID: "old", Job: "fetch-enrollments", Status: "success", FinishedAt: now.Add(-72 * time.Hour), Sheets: []string{"EAD", "PÓS EAD"}},
+		{ID: "new", Job: "fetch-enrollments", Status: "success", FinishedAt: now.Add(-time.Hour), Sheets: []string{"EAD"}},
+	} {
+		record.StartedAt = record.FinishedAt
+		if err := history.Record(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.Defaults()
+	client := &JacadClient{Config: &cfg, Writer: NewFakeSheetWriter(), History: history}
+
+	report, err := client.SheetStatuses(context.Background(), 26*time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Source != SheetStatusSourceHistory || report.Stale != 1 || len(report.Sheets) != 2 {
+		t.Fatalf("report = %+v, want two sheets from the history, one stale", report)
+	}
+	stalest, fresh := report.Sheets[0], report.Sheets[1]
+	if stalest.Sheet != "PÓS EAD" || !stalest.Stale || stalest.JobID != "old" || stalest.AgeSeconds != 72*3600 {
+		t.Errorf("stalest sheet = %+v, want PÓS EAD from job old, stale for 72h", stalest)
+	}
+	if fresh.Sheet != "EAD" || fresh.Stale || fresh.JobID != "new" {
+		t.Errorf("freshest sheet = %+v, want EAD from job new, not stale", fresh)
+	}
+
+	if report, _ := client.SheetStatuses(context.Background(), 0, now); report.Stale != 0 {
+		t.Errorf("with no threshold %d sheets are stale, want none", report.Stale)
+	}
+}
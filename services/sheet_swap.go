@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"google.golang.org/api/sheets/v4"
+)
+
+// maxSheetTitleLength is the Google Sheets limit on tab titles.
+const maxSheetTitleLength = 100
+
+// tempTabName names the tab an overwrite of sheetName is written to before
+// it replaces sheetName.
+func tempTabName(sheetName string) string {
+	suffix := fmt.Sprintf(" (tmp %d)", time.Now().UnixNano())
+	title := []rune(sheetName)
+	if keep := maxSheetTitleLength - len(suffix); len(title) > keep {
+		title = title[:keep]
+	}
+	return string(title) + suffix
+}
+
+// overwriteViaTempTab writes the data to a new tab and, once the API reports
+// every row written, deletes sheetName and renames the new tab in its place
+// in a single batchUpdate. If anything fails before the swap the temporary
+// tab is deleted and sheetName is left as it was, so readers never see a
+// blank or half-written sheet. Formulas in other tabs that reference
+// sheetName are not carried over to the new tab.
+func (w *GoogleSheetsWriter) overwriteViaTempTab(ctx context.Context, sheetName string, headers []string, rows [][]interface{}) error {
+	logger := logging.FromContext(ctx).With("sheet", sheetName)
+
+	if discarded := w.takeBuffered(ctx, sheetName); len(discarded) > 0 {
+		logger.Info("API Sheets: Descartando linhas pendentes da aba que será substituída.", "rows", len(discarded))
+	}
+
+	spreadsheet, err := w.sheetsService.Spreadsheets.Get(w.spreadsheetFor(ctx)).Fields("sheets.properties(sheetId,title,index)").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("falha ao obter detalhes da planilha '%s' para substituir a aba '%s': %w", w.spreadsheetFor(ctx), sheetName, err)
+	}
+	var target *sheets.SheetProperties
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.Title == sheetName {
+			target = sheet.Properties
+			break
+		}
+	}
+
+	tempName := tempTabName(sheetName)
+	tempProperties := &sheets.SheetProperties{Title: tempName}
+	if target != nil {
+		tempProperties.Index = target.Index
+		tempProperties.ForceSendFields = []string{"Index"}
+	}
+	var tempID int64
+	if w.template.SpreadsheetID != "" {
+		if tempID, err = w.copyTemplateTab(ctx, tempProperties); err != nil {
+			return fmt.Errorf("falha ao criar a aba temporária '%s' na planilha '%s': %w", tempName, w.spreadsheetFor(ctx), err)
+		}
+	} else {
+		addCallFunc := func() error {
+			logger.Info("API Sheets: Criando aba temporária para a escrita...", "tempSheet", tempName)
+			resp, err := w.sheetsService.Spreadsheets.BatchUpdate(w.spreadsheetFor(ctx), &sheets.BatchUpdateSpreadsheetRequest{
+				Requests: []*sheets.Request{{AddSheet: &sheets.AddSheetRequest{Properties: tempProperties}}},
+			}).Context(ctx).Do()
+			if err != nil {
+				return err
+			}
+			tempID = resp.Replies[0].AddSheet.Properties.SheetId
+			return nil
+		}
+		if err := w.executeSheetsCall(ctx, "create_sheet", addCallFunc, fmt.Sprintf("criar aba temporária '%s'", tempName)); err != nil {
+			return fmt.Errorf("falha ao criar a aba temporária '%s' na planilha '%s': %w", tempName, w.spreadsheetFor(ctx), err)
+		}
+	}
+
+	allData, rows, dateColumns := w.sheetValues(headers, rows)
+	if err := w.fillTempTab(ctx, tempName, allData); err != nil {
+		w.deleteTempTab(ctx, tempName, tempID)
+		return err
+	}
+	if err := w.verifyRowCount(ctx, tempName, allData); err != nil {
+		w.deleteTempTab(ctx, tempName, tempID)
+		return err
+	}
+
+	if w.formatSheets && len(headers) > 0 {
+		if err := w.FormatSheet(ctx, tempName, len(headers), len(rows), dateColumns); err != nil {
+			logger.Warn("API Sheets: Falha ao formatar a aba. Os dados foram escritos sem formatação.", "error", err)
+		}
+	}
+
+	swap := []*sheets.Request{{UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+		Properties: &sheets.SheetProperties{SheetId: tempID, Title: sheetName},
+		Fields:     "title",
+	}}}
+	if target != nil {
+		swap = append([]*sheets.Request{{DeleteSheet: &sheets.DeleteSheetRequest{SheetId: target.SheetId}}}, swap...)
+	}
+	swapCallFunc := func() error {
+		logger.Info("API Sheets: Substituindo a aba pela aba temporária...", "tempSheet", tempName)
+		_, err := w.sheetsService.Spreadsheets.BatchUpdate(w.spreadsheetFor(ctx), &sheets.BatchUpdateSpreadsheetRequest{Requests: swap}).Context(ctx).Do()
+		return err
+	}
+	if err := w.executeSheetsCall(ctx, "swap_sheet", swapCallFunc, fmt.Sprintf("substituir aba '%s'", sheetName)); err != nil {
+		w.deleteTempTab(ctx, tempName, tempID)
+		return fmt.Errorf("falha ao substituir a aba '%s' pela aba temporária '%s': %w", sheetName, tempName, err)
+	}
+
+	w.recordWrittenRows(ctx, sheetName, len(rows), true)
+	sheetsRowsWrittenTotal.Add(float64(len(rows)), "overwrite")
+	logger.Info("API Sheets: Aba sobrescrita com sucesso.", "rows", len(allData))
+	return nil
+}
+
+// fillTempTab writes allData to the temporary tab and checks that the API
+// reports every row as written.
+func (w *GoogleSheetsWriter) fillTempTab(ctx context.Context, tempName string, allData [][]interface{}) error {
+	if len(allData) == 0 {
+		return nil
+	}
+	written, err := w.writeValues(ctx, tempName, allData)
+	if err != nil {
+		return err
+	}
+	if written != len(allData) {
+		return fmt.Errorf("a aba temporária '%s' recebeu %d de %d linhas", tempName, written, len(allData))
+	}
+	return nil
+}
+
+// deleteTempTab removes a temporary tab after a failed overwrite. It runs
+// even when ctx was cancelled, so the failure does not leave the tab behind.
+func (w *GoogleSheetsWriter) deleteTempTab(ctx context.Context, tempName string, tempID int64) {
+	logger := logging.FromContext(ctx).With("tempSheet", tempName)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	deleteCallFunc := func() error {
+		_, err := w.sheetsService.Spreadsheets.BatchUpdate(w.spreadsheetFor(ctx), &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{{DeleteSheet: &sheets.DeleteSheetRequest{SheetId: tempID}}},
+		}).Context(ctx).Do()
+		return err
+	}
+	if err := w.executeSheetsCall(ctx, "delete_sheet", deleteCallFunc, fmt.Sprintf("excluir aba temporária '%s'", tempName)); err != nil {
+		logger.Error("API Sheets: Falha ao excluir a aba temporária. Exclua-a manualmente.", "error", err)
+		return
+	}
+	logger.Info("API Sheets: Escrita desfeita. A aba original foi mantida.")
+}
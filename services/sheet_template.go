@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"google.golang.org/api/sheets/v4"
+)
+
+// SheetTemplate is the tab copied in place of a blank one whenever
+// GoogleSheetsWriter creates a tab, so generated tabs carry the report's
+// formatting, formulas and pivot tables. A stats area kept in columns to the
+// right of the data survives overwrites, which then clear only the data
+// columns.
+type SheetTemplate struct {
+	// SpreadsheetID is the spreadsheet holding the template; empty disables
+	// templates.
+	SpreadsheetID string
+	// Sheet is the title of the template tab; empty uses the first tab.
+	Sheet string
+}
+
+// templateSheetID resolves the sheetId of the template tab once and caches
+// it for the life of the writer.
+func (w *GoogleSheetsWriter) templateSheetID(ctx context.Context) (int64, error) {
+	w.muTemplate.Lock()
+	defer w.muTemplate.Unlock()
+	if w.templateID != nil {
+		return *w.templateID, nil
+	}
+
+	var spreadsheet *sheets.Spreadsheet
+	getCallFunc := func() error {
+		var err error
+		spreadsheet, err = w.sheetsService.Spreadsheets.Get(w.template.SpreadsheetID).Fields("sheets.properties(sheetId,title)").Context(ctx).Do()
+		return err
+	}
+	if err := w.executeSheetsCall(ctx, "template", getCallFunc, "obter a aba modelo"); err != nil {
+		return 0, fmt.Errorf("falha ao obter a planilha modelo '%s': %w", w.template.SpreadsheetID, err)
+	}
+	for _, sheet := range spreadsheet.Sheets {
+		if w.template.Sheet == "" || sheet.Properties.Title == w.template.Sheet {
+			id := sheet.Properties.SheetId
+			w.templateID = &id
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("a aba modelo '%s' não existe na planilha modelo '%s'", w.template.Sheet, w.template.SpreadsheetID)
+}
+
+// copyTemplateTab copies the template tab into the current spreadsheet and
+// gives the copy the title, and the index when set, of properties. It
+// returns the sheetId of the new tab.
+func (w *GoogleSheetsWriter) copyTemplateTab(ctx context.Context, properties *sheets.SheetProperties) (int64, error) {
+	logger := logging.FromContext(ctx).With("sheet", properties.Title, "templateSpreadsheetId", w.template.SpreadsheetID)
+
+	templateID, err := w.templateSheetID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var copied *sheets.SheetProperties
+	copyCallFunc := func() error {
+		logger.Info("API Sheets: Copiando a aba modelo...")
+		resp, err := w.sheetsService.Spreadsheets.Sheets.CopyTo(w.template.SpreadsheetID, templateID, &sheets.CopySheetToAnotherSpreadsheetRequest{
+			DestinationSpreadsheetId: w.spreadsheetFor(ctx),
+		}).Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		copied = resp
+		return nil
+	}
+	if err := w.executeSheetsCall(ctx, "copy_template", copyCallFunc, fmt.Sprintf("copiar a aba modelo para '%s'", properties.Title)); err != nil {
+		return 0, fmt.Errorf("falha ao copiar a aba modelo para a planilha '%s': %w", w.spreadsheetFor(ctx), err)
+	}
+
+	renamed := &sheets.SheetProperties{SheetId: copied.SheetId, Title: properties.Title}
+	fields := "title"
+	if properties.Index > 0 || slices.Contains(properties.ForceSendFields, "Index") {
+		renamed.Index, renamed.ForceSendFields = properties.Index, []string{"Index"}
+		fields = "title,index"
+	}
+	renameCallFunc := func() error {
+		_, err := w.sheetsService.Spreadsheets.BatchUpdate(w.spreadsheetFor(ctx), &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{{UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{Properties: renamed, Fields: fields}}},
+		}).Context(ctx).Do()
+		return err
+	}
+	if err := w.executeSheetsCall(ctx, "create_sheet", renameCallFunc, fmt.Sprintf("renomear a cópia da aba modelo para '%s'", properties.Title)); err != nil {
+		w.deleteTemplateCopy(ctx, copied)
+		return 0, fmt.Errorf("falha ao renomear a cópia da aba modelo '%s' para '%s': %w", copied.Title, properties.Title, err)
+	}
+
+	logger.Info("API Sheets: Aba criada a partir da aba modelo.")
+	return copied.SheetId, nil
+}
+
+// deleteTemplateCopy removes a template copy that could not be renamed, so
+// the next attempt does not leave "Cópia de ..." tabs behind.
+func (w *GoogleSheetsWriter) deleteTemplateCopy(ctx context.Context, copied *sheets.SheetProperties) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	deleteCallFunc := func() error {
+		_, err := w.sheetsService.Spreadsheets.BatchUpdate(w.spreadsheetFor(ctx), &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{{DeleteSheet: &sheets.DeleteSheetRequest{SheetId: copied.SheetId}}},
+		}).Context(ctx).Do()
+		return err
+	}
+	if err := w.executeSheetsCall(ctx, "delete_sheet", deleteCallFunc, fmt.Sprintf("excluir a cópia da aba modelo '%s'", copied.Title)); err != nil {
+		logging.FromContext(ctx).Error("API Sheets: Falha ao excluir a cópia da aba modelo. Exclua-a manualmente.", "sheet", copied.Title, "error", err)
+	}
+}
+
+// clearDataColumns clears the first columns columns of sheetName, leaving a
+// template's stats area to their right in place.
+func (w *GoogleSheetsWriter) clearDataColumns(ctx context.Context, sheetName string, columns int) error {
+	logger := logging.FromContext(ctx).With("sheet", sheetName, "spreadsheetId", w.spreadsheetFor(ctx))
+	if discarded := w.takeBuffered(ctx, sheetName); len(discarded) > 0 {
+		logger.Info("API Sheets: Descartando linhas pendentes da aba que será limpa.", "rows", len(discarded))
+	}
+
+	clearRange := fmt.Sprintf("'%s'!A:%s", sheetName, xlsxColumnName(columns-1))
+	clearCallFunc := func() error {
+		logger.Info("API Sheets: Limpando as colunas de dados da aba...", "range", clearRange)
+		_, err := w.sheetsService.Spreadsheets.Values.Clear(w.spreadsheetFor(ctx), clearRange, &sheets.ClearValuesRequest{}).Context(ctx).Do()
+		return err
+	}
+	if err := w.executeSheetsCall(ctx, "clear", clearCallFunc, fmt.Sprintf("limpar colunas de dados da aba '%s'", sheetName)); err != nil {
+		return fmt.Errorf("falha ao limpar as colunas de dados da aba '%s' na planilha '%s': %w", sheetName, w.spreadsheetFor(ctx), err)
+	}
+	w.recordWrittenRows(ctx, sheetName, 0, true)
+	return nil
+}
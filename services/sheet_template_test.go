@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestCopyTemplateTab(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	var renamed *sheets.UpdateSheetPropertiesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/spreadsheets/template"):
+			json.NewEncoder(w).Encode(sheets.Spreadsheet{Sheets: []*sheets.Sheet{
+				{Properties: &sheets.SheetProperties{SheetId: 1, Title: "Capa"}},
+				{Properties: &sheets.SheetProperties{SheetId: 7, Title: "Modelo"}},
+			}})
+		case strings.HasSuffix(r.URL.Path, "/spreadsheets/template/sheets/7:copyTo"):
+			var req sheets.CopySheetToAnotherSpreadsheetRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.DestinationSpreadsheetId != "dest" {
+				t.Errorf("copyTo destination = %q, want dest", req.DestinationSpreadsheetId)
+			}
+			json.NewEncoder(w).Encode(sheets.SheetProperties{SheetId: 99, Title: "Cópia de Modelo"})
+		case strings.HasSuffix(r.URL.Path, "/spreadsheets/dest:batchUpdate"):
+			var req sheets.BatchUpdateSpreadsheetRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			renamed = req.Requests[0].UpdateSheetProperties
+			json.NewEncoder(w).Encode(sheets.BatchUpdateSpreadsheetResponse{})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	service, err := sheets.NewService(ctx, option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &GoogleSheetsWriter{
+		sheetsService: service,
+		spreadsheetID: "dest",
+		writeLimiter:  NewPerMinuteLimiter(0),
+		template:      SheetTemplate{SpreadsheetID: "template", Sheet: "Modelo"},
+	}
+
+	for _, title := range []string{"EAD", "POS"} {
+		id, err := w.copyTemplateTab(ctx, &sheets.SheetProperties{Title: title})
+		if err != nil {
+			t.Fatalf("copyTemplateTab(%s) error = %v", title, err)
+		}
+		if id != 99 {
+			t.Errorf("copyTemplateTab(%s) = %d, want 99", title, id)
+		}
+		if renamed == nil || renamed.Properties.SheetId != 99 || renamed.Properties.Title != title || renamed.Fields != "title" {
+			t.Errorf("rename request = %+v, want sheet 99 titled %s", renamed, title)
+		}
+	}
+
+	gets := 0
+	for _, call := range calls {
+		if strings.HasPrefix(call, http.MethodGet) {
+			gets++
+		}
+	}
+	if gets != 1 {
+		t.Errorf("template spreadsheet read %d times, want 1 (calls: %v)", gets, calls)
+	}
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+)
+
+// verifyRowCount reads back one column of sheetName and fails when the tab
+// does not hold as many rows as allData, the values just written to it. The
+// column is one filled in the last non-empty row, since the API leaves
+// trailing empty cells out of the column it returns.
+func (w *GoogleSheetsWriter) verifyRowCount(ctx context.Context, sheetName string, allData [][]interface{}) error {
+	if !w.verifyWrites {
+		return nil
+	}
+	want, column, ok := verificationColumn(allData)
+	if !ok {
+		return nil
+	}
+
+	letter := xlsxColumnName(column)
+	columnRange := fmt.Sprintf("'%s'!%s:%s", sheetName, letter, letter)
+	var got int
+	readCallFunc := func() error {
+		resp, err := w.sheetsService.Spreadsheets.Values.Get(w.spreadsheetFor(ctx), columnRange).
+			MajorDimension("ROWS").
+			Context(ctx).
+			Do()
+		if err != nil {
+			return err
+		}
+		got = len(resp.Values)
+		return nil
+	}
+	if err := w.executeSheetsCall(ctx, "verify", readCallFunc, fmt.Sprintf("verificar linhas da aba '%s'", sheetName)); err != nil {
+		return fmt.Errorf("falha ao verificar as linhas escritas na aba '%s': %w", sheetName, err)
+	}
+
+	if got != want {
+		return fmt.Errorf("a aba '%s' tem %d linhas após a escrita, mas %d foram enviadas; os dados da aba podem estar incompletos", sheetName, got, want)
+	}
+	logging.FromContext(ctx).Debug("API Sheets: Linhas escritas verificadas.", "sheet", sheetName, "rows", got)
+	return nil
+}
+
+// verificationColumn returns how many rows reading a column of allData back
+// should return, up to its last row with a filled cell, and the index of the
+// first filled column of that row. ok is false when every cell is empty.
+func verificationColumn(allData [][]interface{}) (rows, column int, ok bool) {
+	for i := len(allData) - 1; i >= 0; i-- {
+		for j, cell := range allData[i] {
+			if cell != nil && cell != "" {
+				return i + 1, j, true
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,39 @@
+package services
+
+import "testing"
+
+func TestVerificationColumn(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       [][]interface{}
+		wantRows   int
+		wantColumn int
+		wantOK     bool
+	}{
+		{name: "empty", data: nil},
+		{name: "only empty cells", data: [][]interface{}{{"", nil}, {nil}}},
+		{
+			name:     "filled first column",
+			data:     [][]interface{}{{"id", "nome"}, {1, "Ana"}, {2, "Bruno"}},
+			wantRows: 3, wantColumn: 0, wantOK: true,
+		},
+		{
+			name:     "last row starts empty",
+			data:     [][]interface{}{{"id", "nome", "email"}, {1, "Ana", ""}, {nil, "", "b@x.com"}},
+			wantRows: 3, wantColumn: 2, wantOK: true,
+		},
+		{
+			name:     "trailing empty rows",
+			data:     [][]interface{}{{"id"}, {1}, {""}, {}},
+			wantRows: 2, wantColumn: 0, wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, column, ok := verificationColumn(tt.data)
+			if rows != tt.wantRows || column != tt.wantColumn || ok != tt.wantOK {
+				t.Errorf("verificationColumn() = %d, %d, %v; want %d, %d, %v", rows, column, ok, tt.wantRows, tt.wantColumn, tt.wantOK)
+			}
+		})
+	}
+}
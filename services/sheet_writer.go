@@ -2,16 +2,20 @@ package services
 
 import (
 	"context"
-	"encoding/base64"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"golang.org/x/oauth2/google"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
-	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
@@ -20,103 +24,279 @@ type GoogleSheetsWriter struct {
 	spreadsheetID    string
 	retryMaxAttempts int
 	retryDelay       time.Duration
+	// writeLimiter keeps write calls within the per-minute quota.
+	writeLimiter *RateLimiter
+	// coalesceRows is how many appended rows are buffered per sheet before
+	// they are written together; 0 writes every AppendRows immediately.
+	coalesceRows  int
+	muBuffers     sync.Mutex
+	appendBuffers map[pendingAppendKey][][]interface{}
+	// writtenRows counts the data rows the API confirmed writing to each tab
+	// since it was last cleared or overwritten; see CountRows. It is guarded
+	// by muBuffers.
+	writtenRows map[appendBufferKey]int
+	// formatSheets makes OverwriteSheetData call FormatSheet after writing.
+	formatSheets bool
+	// maxRowsPerTab splits overwrites into numbered tabs; 0 disables.
+	maxRowsPerTab int
+	// safeOverwrite writes overwrites to a temporary tab that replaces the
+	// target once complete; see overwriteViaTempTab.
+	safeOverwrite bool
+	// verifyWrites reads back the row count of overwritten tabs; see
+	// verifyRowCount.
+	verifyWrites bool
+	// template is copied in place of blank tabs; see copyTemplateTab.
+	// templateID caches its sheetId and is guarded by muTemplate.
+	template   SheetTemplate
+	muTemplate sync.Mutex
+	templateID *int64
+	// driveService and driveFolderID back CreateSpreadsheet; both are unset
+	// without a Drive folder.
+	driveService  *drive.Service
+	driveFolderID string
+	// locker serializes writes to the same tab across jobs; see lockSheet.
+	locker SheetLocker
 }
 
-func NewGoogleSheetsWriter(ctx context.Context, spreadsheetID string, CredentialsJSONBase64 string, retryMaxAttempts int, retryDelay time.Duration) (*GoogleSheetsWriter, error) {
-	var err error
-	var credentialsJSON []byte
-	var credSourceDescription string
+// GoogleSheetsWriterOptions configures NewGoogleSheetsWriter. The zero value
+// of each optional field leaves its feature off.
+type GoogleSheetsWriterOptions struct {
+	SpreadsheetID    string
+	Credentials      GoogleCredentials
+	RetryMaxAttempts int
+	RetryDelay       time.Duration
+	// WritesPerMinute caps write calls per minute; 0 sets no limit.
+	WritesPerMinute int
+	// CoalesceRows queues appends to a tab until this many rows are
+	// waiting; 0 writes each append at once.
+	CoalesceRows int
+	FormatSheets bool
+	// MaxRowsPerTab splits overwrites into numbered tabs past this many
+	// rows; 0 never splits.
+	MaxRowsPerTab int
+	SafeOverwrite bool
+	VerifyWrites  bool
+	Template      SheetTemplate
+	// DriveFolderID is the Drive folder CreateSpreadsheet creates
+	// spreadsheets in; empty disables it.
+	DriveFolderID string
+}
 
-	envCredsBase64 := os.Getenv("GOOGLE_CREDENTIALS_JSON_BASE64")
-	if envCredsBase64 != "" {
-		log.Println("INFO: Variável de ambiente GOOGLE_CREDENTIALS_JSON_BASE64 encontrada. Usando-a.")
-		credentialsJSON, err = base64.StdEncoding.DecodeString(envCredsBase64)
-		if err != nil {
-			return nil, fmt.Errorf("falha ao decodificar GOOGLE_CREDENTIALS_JSON_BASE64: %w", err)
-		}
-		credSourceDescription = "variável de ambiente GOOGLE_CREDENTIALS_JSON_BASE64"
-	} else if CredentialsJSONBase64 != "" {
-		log.Printf("INFO: GOOGLE_CREDENTIALS_JSON_BASE64 não definida. Tentando arquivo de credenciais: %s", CredentialsJSONBase64)
-		credentialsJSON, err = os.ReadFile(CredentialsJSONBase64)
-		if err != nil {
-			if os.IsNotExist(err) {
-				log.Printf("WARN: Arquivo de credenciais '%s' não encontrado. Tentará Application Default Credentials.", CredentialsJSONBase64)
-				credentialsJSON = nil
-			} else {
-				return nil, fmt.Errorf("falha ao ler arquivo de credenciais '%s': %w", CredentialsJSONBase64, err)
-			}
-		} else {
-			credSourceDescription = fmt.Sprintf("arquivo ('%s')", CredentialsJSONBase64)
-		}
-	} else {
-		log.Println("INFO: Nem GOOGLE_CREDENTIALS_JSON_BASE64 nem CredentialsJSONBase64 fornecidos. Tentando Application Default Credentials.")
+func NewGoogleSheetsWriter(ctx context.Context, options GoogleSheetsWriterOptions) (*GoogleSheetsWriter, error) {
+	logger := logging.FromContext(ctx)
 
-		credSourceDescription = "Application Default Credentials"
+	scopes := []string{sheets.SpreadsheetsScope}
+	if options.DriveFolderID != "" {
+		scopes = append(scopes, drive.DriveScope)
+	}
+	opts, credSourceDescription, err := googleClientOptions(ctx, options.Credentials, scopes...)
+	if err != nil {
+		return nil, err
 	}
 
-	var sheetsService *sheets.Service
-	if credentialsJSON != nil {
-		log.Printf("INFO: Configurando cliente Google Sheets com credenciais JSON de: %s", credSourceDescription)
-		config, err := google.JWTConfigFromJSON(credentialsJSON, sheets.SpreadsheetsScope)
-		if err != nil {
-			return nil, fmt.Errorf("falha ao configurar JWT a partir das credenciais JSON (fonte: %s): %w", credSourceDescription, err)
-		}
-		client := config.Client(ctx)
-		sheetsService, err = sheets.NewService(ctx, option.WithHTTPClient(client))
-		if err != nil {
-			return nil, fmt.Errorf("falha ao criar cliente da API Google Sheets usando JWT (fonte: %s): %w", credSourceDescription, err)
-		}
-	} else {
-		log.Println("INFO: Configurando cliente Google Sheets com Application Default Credentials.")
-		sheetsService, err = sheets.NewService(ctx)
+	logger.Info("Configurando cliente Google Sheets.", "source", credSourceDescription)
+	sheetsService, err := sheets.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao criar cliente da API Google Sheets (fonte: %s): %w", credSourceDescription, err)
+	}
+
+	var driveService *drive.Service
+	if options.DriveFolderID != "" {
+		driveService, err = drive.NewService(ctx, opts...)
 		if err != nil {
-			return nil, fmt.Errorf("falha ao criar cliente da API Google Sheets usando Application Default Credentials: %w. Verifique se ADC estão configuradas se nenhuma credencial explícita foi fornecida.", err)
+			return nil, fmt.Errorf("falha ao criar cliente da API Google Drive (fonte: %s): %w", credSourceDescription, err)
 		}
 	}
 
-	log.Println("INFO: Cliente do Google Sheets inicializado com sucesso.")
+	logger.Info("Cliente do Google Sheets inicializado com sucesso.")
 	return &GoogleSheetsWriter{
 		sheetsService:    sheetsService,
-		spreadsheetID:    spreadsheetID,
-		retryMaxAttempts: retryMaxAttempts,
-		retryDelay:       retryDelay,
+		spreadsheetID:    options.SpreadsheetID,
+		retryMaxAttempts: options.RetryMaxAttempts,
+		retryDelay:       options.RetryDelay,
+		writeLimiter:     NewPerMinuteLimiter(options.WritesPerMinute),
+		coalesceRows:     options.CoalesceRows,
+		appendBuffers:    make(map[pendingAppendKey][][]interface{}),
+		writtenRows:      make(map[appendBufferKey]int),
+		formatSheets:     options.FormatSheets,
+		maxRowsPerTab:    options.MaxRowsPerTab,
+		safeOverwrite:    options.SafeOverwrite,
+		verifyWrites:     options.VerifyWrites,
+		template:         options.Template,
+		driveService:     driveService,
+		driveFolderID:    options.DriveFolderID,
+		locker:           NewLocalSheetLocker(),
 	}, nil
 }
 
+type spreadsheetIDKey struct{}
+
+// WithSpreadsheetID routes GoogleSheetsWriter calls made with ctx to
+// spreadsheetID instead of the writer's default spreadsheet.
+func WithSpreadsheetID(ctx context.Context, spreadsheetID string) context.Context {
+	if spreadsheetID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, spreadsheetIDKey{}, spreadsheetID)
+}
+
+// spreadsheetIDFrom returns the spreadsheet set with WithSpreadsheetID, or ""
+// when ctx writes to the writer's default one.
+func spreadsheetIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(spreadsheetIDKey{}).(string)
+	return id
+}
+
+func (w *GoogleSheetsWriter) spreadsheetFor(ctx context.Context) string {
+	if id := spreadsheetIDFrom(ctx); id != "" {
+		return id
+	}
+	return w.spreadsheetID
+}
+
+func (w *GoogleSheetsWriter) CheckHealth(ctx context.Context) error {
+	getCallFunc := func() error {
+		_, err := w.sheetsService.Spreadsheets.Get(w.spreadsheetFor(ctx)).Fields("spreadsheetId").Context(ctx).Do()
+		return err
+	}
+	if err := w.executeSheetsCall(ctx, "health", getCallFunc, "verificar acesso à planilha"); err != nil {
+		return fmt.Errorf("planilha '%s' inacessível: %w", w.spreadsheetFor(ctx), err)
+	}
+	return nil
+}
+
+// AppendRows appends rows to sheetName. With coalescing enabled rows may only
+// be buffered; they are written once enough accumulate, before the sheet is
+// read or upserted, or on Flush.
 func (w *GoogleSheetsWriter) AppendRows(ctx context.Context, sheetName string, rows [][]interface{}) error {
 	if len(rows) == 0 {
 		return nil
 	}
+	if w.coalesceRows > 0 {
+		if rows = w.bufferAppend(ctx, sheetName, rows); rows == nil {
+			return nil
+		}
+	}
+	return w.appendNow(ctx, sheetName, rows)
+}
+
+// appendNow writes rows immediately, split into payloads the API accepts.
+func (w *GoogleSheetsWriter) appendNow(ctx context.Context, sheetName string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	ctx, unlock, err := w.lockSheet(ctx, "append", sheetName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, chunk := range splitRowsForWrite(rows, maxRowsPerWrite, maxBytesPerWrite) {
+		if err := w.appendChunk(ctx, sheetName, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *GoogleSheetsWriter) appendChunk(ctx context.Context, sheetName string, rows [][]interface{}) error {
 	appendRange := fmt.Sprintf("'%s'", sheetName)
 	valueInputOption := "USER_ENTERED"
 	insertDataOption := "INSERT_ROWS"
 
 	appendCallFunc := func() error {
-		log.Printf("API Sheets: Anexando %d linhas na aba '%s'...", len(rows), sheetName)
-		_, err := w.sheetsService.Spreadsheets.Values.Append(w.spreadsheetID, appendRange, &sheets.ValueRange{Values: rows}).
+		logging.FromContext(ctx).Info("API Sheets: Anexando linhas na aba...", "sheet", sheetName, "rows", len(rows))
+		resp, err := w.sheetsService.Spreadsheets.Values.Append(w.spreadsheetFor(ctx), appendRange, &sheets.ValueRange{Values: rows}).
 			ValueInputOption(valueInputOption).
 			InsertDataOption(insertDataOption).
 			Context(ctx).
 			Do()
-		return err
+		if err != nil {
+			return err
+		}
+		if resp.Updates != nil {
+			w.recordWrittenRows(ctx, sheetName, int(resp.Updates.UpdatedRows), false)
+		}
+		return nil
 	}
 
-	err := w.executeSheetsCall(ctx, appendCallFunc, fmt.Sprintf("anexar linhas na aba '%s'", sheetName))
+	err := w.executeSheetsCall(ctx, "append", appendCallFunc, fmt.Sprintf("anexar linhas na aba '%s'", sheetName))
 	if err != nil {
 		return fmt.Errorf("falha ao anexar %d linhas na aba '%s': %w", len(rows), sheetName, err)
 	}
+	sheetsRowsWrittenTotal.Add(float64(len(rows)), "append")
 	return nil
 }
 
+// OverwriteSheetData replaces the contents of sheetName. Datasets above
+// maxRowsPerTab rows are split into numbered tabs, "sheetName (1)", "(2)"...,
+// each with the headers; see overwriteSplit.
 func (w *GoogleSheetsWriter) OverwriteSheetData(ctx context.Context, sheetName string, headers []string, rows [][]interface{}) error {
+	ctx, unlock, err := w.lockSheet(ctx, "overwrite", sheetName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if cells := (len(rows) + 1) * len(headers); cells > maxCellsPerSpreadsheet {
+		logging.FromContext(ctx).Warn("API Sheets: Os dados excedem o limite de células de uma planilha do Google Sheets. A escrita provavelmente falhará.", "sheet", sheetName, "cells", cells, "limit", maxCellsPerSpreadsheet)
+	}
+	if w.maxRowsPerTab > 0 {
+		return w.overwriteSplit(ctx, sheetName, headers, rows)
+	}
+	return w.overwriteTab(ctx, sheetName, headers, rows)
+}
+
+func (w *GoogleSheetsWriter) overwriteTab(ctx context.Context, sheetName string, headers []string, rows [][]interface{}) error {
+	if w.safeOverwrite {
+		return w.overwriteViaTempTab(ctx, sheetName, headers, rows)
+	}
+
+	logger := logging.FromContext(ctx).With("sheet", sheetName)
+
 	if err := w.EnsureSheetExists(ctx, sheetName); err != nil {
 		return err
 	}
 
-	if err := w.Clear(ctx, sheetName); err != nil {
+	var err error
+	if w.template.SpreadsheetID != "" && len(headers) > 0 {
+		err = w.clearDataColumns(ctx, sheetName, len(headers))
+	} else {
+		err = w.Clear(ctx, sheetName)
+	}
+	if err != nil {
 		return err
 	}
 
+	allData, rows, dateColumns := w.sheetValues(headers, rows)
+	if len(allData) == 0 {
+		logger.Info("Nenhum dado (cabeçalhos ou linhas) para escrever na aba.")
+		return nil
+	}
+
+	updated, err := w.writeValues(ctx, sheetName, allData)
+	if err != nil {
+		return err
+	}
+	w.recordWrittenRows(ctx, sheetName, updated-(len(allData)-len(rows)), true)
+	if err := w.verifyRowCount(ctx, sheetName, allData); err != nil {
+		return err
+	}
+
+	sheetsRowsWrittenTotal.Add(float64(len(rows)), "overwrite")
+	logger.Info("API Sheets: Aba sobrescrita com sucesso.", "rows", len(allData))
+
+	if w.formatSheets && len(headers) > 0 {
+		if err := w.FormatSheet(ctx, sheetName, len(headers), len(rows), dateColumns); err != nil {
+			logger.Warn("API Sheets: Falha ao formatar a aba. Os dados foram escritos sem formatação.", "error", err)
+		}
+	}
+	return nil
+}
+
+// sheetValues returns the header row followed by rows, with dates converted
+// to sheet cells and their columns when formatting is enabled.
+func (w *GoogleSheetsWriter) sheetValues(headers []string, rows [][]interface{}) ([][]interface{}, [][]interface{}, []int) {
 	allData := make([][]interface{}, 0, 1+len(rows))
 	if len(headers) > 0 {
 		headerRow := make([]interface{}, len(headers))
@@ -125,54 +305,289 @@ func (w *GoogleSheetsWriter) OverwriteSheetData(ctx context.Context, sheetName s
 		}
 		allData = append(allData, headerRow)
 	}
-	allData = append(allData, rows...)
+	var dateColumns []int
+	if w.formatSheets {
+		rows, dateColumns = sheetDateCells(rows)
+	}
+	return append(allData, rows...), rows, dateColumns
+}
 
-	if len(allData) == 0 {
-		log.Printf("INFO: Nenhum dado (cabeçalhos ou linhas) para escrever na aba '%s'.", sheetName)
-		return nil
+// writeValues writes allData to sheetName from A1 and returns the number of
+// rows the API reports as updated. The rows are split into value ranges sent
+// together with values.batchUpdate in requests within the payload limits; the
+// ranges of a request that fails are then sent again one at a time, so only
+// the failed chunks are retried.
+func (w *GoogleSheetsWriter) writeValues(ctx context.Context, sheetName string, allData [][]interface{}) (int, error) {
+	logger := logging.FromContext(ctx).With("sheet", sheetName)
+
+	batches := batchValueRanges(sheetName, allData, maxRowsPerValueRange, maxRowsPerWrite, maxBytesPerWrite)
+	updatedRows := 0
+	var failed []*sheets.ValueRange
+	for _, batch := range batches {
+		updated, err := w.batchUpdateValues(ctx, sheetName, batch, len(allData))
+		updatedRows += updated
+		if err != nil {
+			if ctx.Err() != nil {
+				return updatedRows, err
+			}
+			logger.Warn("API Sheets: Falha ao escrever um lote de intervalos. Os intervalos serão reenviados um a um.", "ranges", len(batch), "error", err)
+			failed = append(failed, batch...)
+		}
+	}
+	if len(failed) == 0 {
+		return updatedRows, nil
 	}
 
-	writeRange := fmt.Sprintf("'%s'!A1", sheetName)
-	updateReq := &sheets.ValueRange{Values: allData}
+	var stillFailed []string
+	var lastErr error
+	for _, valueRange := range failed {
+		updated, err := w.batchUpdateValues(ctx, sheetName, []*sheets.ValueRange{valueRange}, len(allData))
+		updatedRows += updated
+		if err != nil {
+			if ctx.Err() != nil {
+				return updatedRows, err
+			}
+			stillFailed = append(stillFailed, valueRange.Range)
+			lastErr = err
+		}
+	}
+	if len(stillFailed) > 0 {
+		return updatedRows, fmt.Errorf("falha ao escrever %d intervalos na aba '%s' (%s): %w", len(stillFailed), sheetName, strings.Join(stillFailed, ", "), lastErr)
+	}
+	logger.Info("API Sheets: Intervalos reenviados com sucesso.", "ranges", len(failed))
+	return updatedRows, nil
+}
 
+// batchUpdateValues writes data with one values.batchUpdate call and returns
+// the number of rows the API reports as updated.
+func (w *GoogleSheetsWriter) batchUpdateValues(ctx context.Context, sheetName string, data []*sheets.ValueRange, totalRows int) (int, error) {
+	logger := logging.FromContext(ctx).With("sheet", sheetName)
+	rows := 0
+	for _, valueRange := range data {
+		rows += len(valueRange.Values)
+	}
+	batchReq := &sheets.BatchUpdateValuesRequest{
+		ValueInputOption: "USER_ENTERED",
+		Data:             data,
+	}
+
+	updatedRows := 0
 	updateCallFunc := func() error {
-		log.Printf("API Sheets: Escrevendo %d linhas totais (cabeçalhos + dados) na aba '%s'...", len(allData), sheetName)
-		_, err := w.sheetsService.Spreadsheets.Values.Update(w.spreadsheetID, writeRange, updateReq).
-			ValueInputOption("USER_ENTERED").
+		logger.Info("API Sheets: Escrevendo linhas (cabeçalhos + dados) na aba...", "rows", rows, "ranges", len(data), "startRange", data[0].Range, "totalRows", totalRows)
+		resp, err := w.sheetsService.Spreadsheets.Values.BatchUpdate(w.spreadsheetFor(ctx), batchReq).Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		updatedRows = int(resp.TotalUpdatedRows)
+		return nil
+	}
+
+	if err := w.executeSheetsCall(ctx, "overwrite", updateCallFunc, fmt.Sprintf("escrever dados na aba '%s'", sheetName)); err != nil {
+		return 0, fmt.Errorf("falha ao escrever %d linhas na aba '%s' a partir de %s: %w", rows, sheetName, data[0].Range, err)
+	}
+	return updatedRows, nil
+}
+
+func (w *GoogleSheetsWriter) UpsertRows(ctx context.Context, sheetName string, headers []string, keyColumn string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	logger := logging.FromContext(ctx).With("sheet", sheetName)
+	keyIndex := -1
+	for i, h := range headers {
+		if h == keyColumn {
+			keyIndex = i
+			break
+		}
+	}
+	if keyIndex < 0 {
+		return fmt.Errorf("coluna chave '%s' não encontrada nos cabeçalhos da aba '%s'", keyColumn, sheetName)
+	}
+	ctx, unlock, err := w.lockSheet(ctx, "upsert", sheetName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := w.EnsureSheetExists(ctx, sheetName); err != nil {
+		return err
+	}
+	if err := w.flushSheet(ctx, sheetName); err != nil {
+		return err
+	}
+
+	var existing *sheets.ValueRange
+	readCallFunc := func() error {
+		logger.Info("API Sheets: Lendo dados existentes da aba para upsert...")
+		resp, err := w.sheetsService.Spreadsheets.Values.Get(w.spreadsheetFor(ctx), fmt.Sprintf("'%s'", sheetName)).
 			Context(ctx).
 			Do()
+		if err != nil {
+			return err
+		}
+		existing = resp
+		return nil
+	}
+
+	if err := w.executeSheetsCall(ctx, "read", readCallFunc, fmt.Sprintf("ler dados da aba '%s'", sheetName)); err != nil {
+		return fmt.Errorf("falha ao ler dados existentes da aba '%s': %w", sheetName, err)
+	}
+
+	if existing == nil || len(existing.Values) == 0 {
+		logger.Info("Aba vazia. Escrevendo cabeçalhos e linhas.", "rows", len(rows))
+		return w.OverwriteSheetData(ctx, sheetName, headers, rows)
+	}
+
+	rowByKey := make(map[string]int, len(existing.Values))
+	for i, existingRow := range existing.Values[1:] {
+		if keyIndex < len(existingRow) {
+			rowByKey[fmt.Sprint(existingRow[keyIndex])] = i + 2
+		}
+	}
+
+	var updates []*sheets.ValueRange
+	var newRows [][]interface{}
+	for _, row := range rows {
+		if rowNumber, ok := rowByKey[fmt.Sprint(row[keyIndex])]; ok {
+			updates = append(updates, &sheets.ValueRange{
+				Range:  fmt.Sprintf("'%s'!A%d", sheetName, rowNumber),
+				Values: [][]interface{}{row},
+			})
+		} else {
+			newRows = append(newRows, row)
+		}
+	}
+
+	for start := 0; start < len(updates); start += maxRowsPerWrite {
+		chunk := updates[start:min(start+maxRowsPerWrite, len(updates))]
+		batchReq := &sheets.BatchUpdateValuesRequest{
+			ValueInputOption: "USER_ENTERED",
+			Data:             chunk,
+		}
+		updateCallFunc := func() error {
+			logger.Info("API Sheets: Atualizando linhas existentes na aba...", "rows", len(chunk), "totalRows", len(updates))
+			_, err := w.sheetsService.Spreadsheets.Values.BatchUpdate(w.spreadsheetFor(ctx), batchReq).Context(ctx).Do()
+			return err
+		}
+		if err := w.executeSheetsCall(ctx, "upsert", updateCallFunc, fmt.Sprintf("atualizar linhas na aba '%s'", sheetName)); err != nil {
+			return fmt.Errorf("falha ao atualizar %d linhas na aba '%s': %w", len(chunk), sheetName, err)
+		}
+		sheetsRowsWrittenTotal.Add(float64(len(chunk)), "upsert")
+	}
+
+	if err := w.appendNow(ctx, sheetName, newRows); err != nil {
 		return err
 	}
 
-	err := w.executeSheetsCall(ctx, updateCallFunc, fmt.Sprintf("escrever dados na aba '%s'", sheetName))
+	logger.Info("API Sheets: Upsert na aba concluído.", "updatedRows", len(updates), "newRows", len(newRows))
+	return nil
+}
+
+// ReadRows returns the sheet's contents, header row included, with numbers
+// unformatted so they compare equal to the values that were written.
+func (w *GoogleSheetsWriter) ReadRows(ctx context.Context, sheetName string) ([][]interface{}, error) {
+	if err := w.flushSheet(ctx, sheetName); err != nil {
+		return nil, err
+	}
+
+	var values [][]interface{}
+	readCallFunc := func() error {
+		resp, err := w.sheetsService.Spreadsheets.Values.Get(w.spreadsheetFor(ctx), fmt.Sprintf("'%s'", sheetName)).
+			ValueRenderOption("UNFORMATTED_VALUE").
+			Context(ctx).
+			Do()
+		if err != nil {
+			return err
+		}
+		values = resp.Values
+		return nil
+	}
+
+	if err := w.executeSheetsCall(ctx, "read", readCallFunc, fmt.Sprintf("ler dados da aba '%s'", sheetName)); err != nil {
+		if isNotFoundError(err) || isMissingRangeError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("falha ao ler dados da aba '%s': %w", sheetName, err)
+	}
+	return values, nil
+}
+
+// CountRows returns the number of data rows the API confirmed writing to
+// sheetName since it was last cleared or overwritten by this writer. Counting
+// cells in the sheet instead would miss rows whose first columns are empty.
+// Sheets this writer has not written are counted by reading them.
+func (w *GoogleSheetsWriter) CountRows(ctx context.Context, sheetName string) (int, error) {
+	if err := w.flushSheet(ctx, sheetName); err != nil {
+		return 0, err
+	}
+
+	w.muBuffers.Lock()
+	count, ok := w.writtenRows[appendBufferKey{spreadsheetID: w.spreadsheetFor(ctx), sheet: sheetName}]
+	w.muBuffers.Unlock()
+	if ok {
+		return count, nil
+	}
+
+	values, err := w.ReadRows(ctx, sheetName)
 	if err != nil {
-		return fmt.Errorf("falha ao escrever dados na aba '%s': %w", sheetName, err)
+		return 0, fmt.Errorf("falha ao contar linhas da aba '%s': %w", sheetName, err)
 	}
+	return max(len(values)-1, 0), nil
+}
 
-	log.Printf("API Sheets: Aba '%s' sobrescrita com sucesso com %d linhas totais.", sheetName, len(allData))
-	return nil
+// recordWrittenRows adds n confirmed data rows to sheetName's count, or
+// restarts the count at n when reset is set.
+func (w *GoogleSheetsWriter) recordWrittenRows(ctx context.Context, sheetName string, n int, reset bool) {
+	key := appendBufferKey{spreadsheetID: w.spreadsheetFor(ctx), sheet: sheetName}
+
+	w.muBuffers.Lock()
+	defer w.muBuffers.Unlock()
+	if reset {
+		w.writtenRows[key] = 0
+	}
+	w.writtenRows[key] += max(n, 0)
 }
 
 func (w *GoogleSheetsWriter) Clear(ctx context.Context, sheetName string) error {
+	ctx, unlock, err := w.lockSheet(ctx, "clear", sheetName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	logger := logging.FromContext(ctx).With("sheet", sheetName, "spreadsheetId", w.spreadsheetFor(ctx))
 	clearRange := fmt.Sprintf("'%s'", sheetName)
 	req := sheets.ClearValuesRequest{}
 
+	if discarded := w.takeBuffered(ctx, sheetName); len(discarded) > 0 {
+		logger.Info("API Sheets: Descartando linhas pendentes da aba que será limpa.", "rows", len(discarded))
+	}
+
 	clearCallFunc := func() error {
-		log.Printf("API Sheets: Limpando a aba '%s' na planilha '%s'...", sheetName, w.spreadsheetID)
-		_, err := w.sheetsService.Spreadsheets.Values.Clear(w.spreadsheetID, clearRange, &req).Context(ctx).Do()
+		logger.Info("API Sheets: Limpando a aba na planilha...")
+		_, err := w.sheetsService.Spreadsheets.Values.Clear(w.spreadsheetFor(ctx), clearRange, &req).Context(ctx).Do()
 		return err
 	}
 
-	err := w.executeSheetsCall(ctx, clearCallFunc, fmt.Sprintf("limpar aba '%s'", sheetName))
+	err = w.executeSheetsCall(ctx, "clear", clearCallFunc, fmt.Sprintf("limpar aba '%s'", sheetName))
 	if err != nil {
-		return fmt.Errorf("falha ao limpar a aba '%s' na planilha '%s': %w", sheetName, w.spreadsheetID, err)
+		return fmt.Errorf("falha ao limpar a aba '%s' na planilha '%s': %w", sheetName, w.spreadsheetFor(ctx), err)
 	}
+	w.recordWrittenRows(ctx, sheetName, 0, true)
 
-	log.Printf("API Sheets: Aba '%s' limpa com sucesso.", sheetName)
+	logger.Info("API Sheets: Aba limpa com sucesso.")
 	return nil
 }
 
 func (w *GoogleSheetsWriter) SetHeaders(ctx context.Context, sheetName string, headers []string) error {
+	ctx, unlock, err := w.lockSheet(ctx, "set_headers", sheetName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	logger := logging.FromContext(ctx).With("sheet", sheetName, "spreadsheetId", w.spreadsheetFor(ctx))
 	writeRange := fmt.Sprintf("'%s'!A1", sheetName)
 	var values [][]interface{}
 	var headerInterfaces []interface{}
@@ -183,38 +598,51 @@ func (w *GoogleSheetsWriter) SetHeaders(ctx context.Context, sheetName string, h
 
 	updateReq := &sheets.ValueRange{Values: values}
 	updateCallFunc := func() error {
-		log.Printf("API Sheets: Definindo cabeçalhos em '%s'!A1 na planilha '%s'...", sheetName, w.spreadsheetID)
-		_, err := w.sheetsService.Spreadsheets.Values.Update(w.spreadsheetID, writeRange, updateReq).
+		logger.Info("API Sheets: Definindo cabeçalhos na linha A1 da aba...")
+		_, err := w.sheetsService.Spreadsheets.Values.Update(w.spreadsheetFor(ctx), writeRange, updateReq).
 			ValueInputOption("USER_ENTERED").
 			Context(ctx).
 			Do()
 		return err
 	}
 
-	err := w.executeSheetsCall(ctx, updateCallFunc, fmt.Sprintf("definir cabeçalhos na aba '%s'", sheetName))
+	err = w.executeSheetsCall(ctx, "set_headers", updateCallFunc, fmt.Sprintf("definir cabeçalhos na aba '%s'", sheetName))
 	if err != nil {
 		return fmt.Errorf("falha ao definir cabeçalhos em '%s'!A1: %w", sheetName, err)
 	}
 
-	log.Printf("API Sheets: Cabeçalhos definidos com sucesso na aba '%s'.", sheetName)
+	logger.Info("API Sheets: Cabeçalhos definidos com sucesso na aba.")
 	return nil
 }
 
 func (w *GoogleSheetsWriter) EnsureSheetExists(ctx context.Context, sheetName string) error {
-	log.Printf("API Sheets: Verificando se a aba '%s' existe na planilha '%s'...", sheetName, w.spreadsheetID)
-	spreadsheet, err := w.sheetsService.Spreadsheets.Get(w.spreadsheetID).Fields("sheets.properties.title").Context(ctx).Do()
+	ctx, unlock, err := w.lockSheet(ctx, "create_sheet", sheetName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	logger := logging.FromContext(ctx).With("sheet", sheetName, "spreadsheetId", w.spreadsheetFor(ctx))
+	logger.Debug("API Sheets: Verificando se a aba existe na planilha...")
+	spreadsheet, err := w.sheetsService.Spreadsheets.Get(w.spreadsheetFor(ctx)).Fields("sheets.properties.title").Context(ctx).Do()
 	if err != nil {
-		return fmt.Errorf("falha ao obter detalhes da planilha '%s' para verificar a aba '%s': %w", w.spreadsheetID, sheetName, err)
+		return fmt.Errorf("falha ao obter detalhes da planilha '%s' para verificar a aba '%s': %w", w.spreadsheetFor(ctx), sheetName, err)
 	}
 
 	for _, sheet := range spreadsheet.Sheets {
 		if sheet.Properties.Title == sheetName {
-			log.Printf("API Sheets: A aba '%s' já existe na planilha '%s'.", sheetName, w.spreadsheetID)
+			logger.Debug("API Sheets: A aba já existe na planilha.")
 			return nil
 		}
 	}
 
-	log.Printf("API Sheets: A aba '%s' não existe na planilha '%s'. Criando...", sheetName, w.spreadsheetID)
+	logger.Info("API Sheets: A aba não existe na planilha. Criando...")
+	if w.template.SpreadsheetID != "" {
+		if _, err := w.copyTemplateTab(ctx, &sheets.SheetProperties{Title: sheetName}); err != nil {
+			return fmt.Errorf("falha ao criar a aba '%s' na planilha '%s': %w", sheetName, w.spreadsheetFor(ctx), err)
+		}
+		return nil
+	}
 	addSheetRequest := &sheets.Request{
 		AddSheet: &sheets.AddSheetRequest{
 			Properties: &sheets.SheetProperties{
@@ -228,44 +656,60 @@ func (w *GoogleSheetsWriter) EnsureSheetExists(ctx context.Context, sheetName st
 	}
 
 	batchUpdateCallFunc := func() error {
-		log.Printf("API Sheets: Executando BatchUpdate para criar a aba '%s'...", sheetName)
-		_, err := w.sheetsService.Spreadsheets.BatchUpdate(w.spreadsheetID, batchUpdateRequest).Context(ctx).Do()
+		logger.Debug("API Sheets: Executando BatchUpdate para criar a aba...")
+		_, err := w.sheetsService.Spreadsheets.BatchUpdate(w.spreadsheetFor(ctx), batchUpdateRequest).Context(ctx).Do()
 		return err
 	}
 
-	err = w.executeSheetsCall(ctx, batchUpdateCallFunc, fmt.Sprintf("criar aba '%s'", sheetName))
+	err = w.executeSheetsCall(ctx, "create_sheet", batchUpdateCallFunc, fmt.Sprintf("criar aba '%s'", sheetName))
 	if err != nil {
-		return fmt.Errorf("falha ao criar a aba '%s' na planilha '%s': %w", sheetName, w.spreadsheetID, err)
+		return fmt.Errorf("falha ao criar a aba '%s' na planilha '%s': %w", sheetName, w.spreadsheetFor(ctx), err)
 	}
 
-	log.Printf("API Sheets: Aba '%s' criada com sucesso.", sheetName)
+	logger.Info("API Sheets: Aba criada com sucesso.")
 	return nil
 }
 
-func (w *GoogleSheetsWriter) executeSheetsCall(ctx context.Context, callFunc func() error, operationDesc string) error {
+func (w *GoogleSheetsWriter) executeSheetsCall(ctx context.Context, operation string, callFunc func() error, operationDesc string) (err error) {
+	ctx, span := tracing.Start(ctx, "sheets "+operation, attribute.String("sheets.operation", operation))
+	defer func() { tracing.End(span, err) }()
+
 	baseDelay := w.retryDelay
 	maxAttempts := w.retryMaxAttempts
+	logger := logging.FromContext(ctx).With("operation", operationDesc)
 
 	for attempt := 0; attempt <= maxAttempts; attempt++ {
 		select {
 		case <-ctx.Done():
-			log.Printf("Operação da API Sheets '%s' cancelada via contexto antes da tentativa %d: %v", operationDesc, attempt+1, ctx.Err())
+			logger.Warn("Operação da API Sheets cancelada via contexto antes da tentativa.", "attempt", attempt+1, "error", ctx.Err())
 			return fmt.Errorf("operação '%s' cancelada via contexto: %w", operationDesc, ctx.Err())
 		default:
 		}
 
+		if err := w.waitWriteQuota(ctx, operation); err != nil {
+			return fmt.Errorf("operação '%s' cancelada: %w", operationDesc, err)
+		}
+
 		err := callFunc()
 		if err == nil {
 			return nil
 		}
+		code := sheetsErrorCode(err)
+		sheetsAPIErrorsTotal.Inc(operation, code)
 
 		if isRetryableSheetsError(err) && attempt < maxAttempts {
 			delay := baseDelay * time.Duration(1<<attempt)
-			log.Printf("Operação da API Sheets '%s' falhou (tentativa %d/%d): %v. Aguardando %s antes de tentar novamente...", operationDesc, attempt+1, maxAttempts+1, err, delay)
+			wait, asked := googleRetryAfter(err, time.Now())
+			if asked {
+				delay = wait
+			}
+			logger.Warn("Operação da API Sheets falhou. Aguardando antes de tentar novamente...", "attempt", attempt+1, "maxAttempts", maxAttempts+1, "error", err, "delay", delay.String(), "retryAfter", asked)
+			sheetsQuotaRetriesTotal.Inc(code)
+			span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt+1), attribute.String("code", code)))
 			select {
 			case <-time.After(delay):
 			case <-ctx.Done():
-				log.Printf("Operação da API Sheets '%s' cancelada via contexto durante a espera.", operationDesc)
+				logger.Warn("Operação da API Sheets cancelada via contexto durante a espera.")
 				return fmt.Errorf("operação '%s' cancelada via contexto durante a espera da nova tentativa: %w", operationDesc, ctx.Err())
 			}
 		} else {
@@ -284,16 +728,30 @@ func isRetryableSheetsError(err error) bool {
 		return false
 	}
 	if apiErr.Code >= 500 && apiErr.Code < 600 {
-		log.Printf("Google API 5xx error (%d): %s. Tentando novamente...", apiErr.Code, apiErr.Message)
+		slog.Warn("Google API 5xx error. Tentando novamente...", "code", apiErr.Code, "message", apiErr.Message)
 		return true
 	}
 	if apiErr.Code == 429 {
-		log.Printf("Google API 429 error (Resource Exhausted / Quota Limit). Tentando novamente...")
+		slog.Warn("Google API 429 error (Resource Exhausted / Quota Limit). Tentando novamente...")
 		return true
 	}
 	if apiErr.Code == 403 && strings.Contains(strings.ToLower(apiErr.Message), "ratelimitexceeded") {
-		log.Printf("Google API 403 error (Rate Limit Exceeded). Tentando novamente...")
+		slog.Warn("Google API 403 error (Rate Limit Exceeded). Tentando novamente...")
 		return true
 	}
 	return false
 }
+
+// isMissingRangeError reports whether err is the 400 the Sheets API returns
+// when the requested tab does not exist.
+func isMissingRangeError(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == 400 && strings.Contains(apiErr.Message, "Unable to parse range")
+}
+
+func sheetsErrorCode(err error) string {
+	if apiErr, ok := err.(*googleapi.Error); ok {
+		return strconv.Itoa(apiErr.Code)
+	}
+	return "unknown"
+}
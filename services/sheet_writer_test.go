@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestUpsertRowsMatchesKeys(t *testing.T) {
+	api := newFakeSheetsAPI()
+	api.SetTab("book", "Alunos", [][]interface{}{
+		{"Nome", "ID", "Status"},
+		{"Ana", 10.0, "Ativo"},
+		{"Bruno", 20.0, "Ativo"},
+	})
+	writer := newFakeSheetsWriter(t, api)
+
+	rows := [][]interface{}{
+		{"Bruno", 20, "Trancado"},
+		{"Carla", 30, "Ativo"},
+	}
+	if err := writer.UpsertRows(context.Background(), "Alunos", []string{"Nome", "ID", "Status"}, "ID", rows); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := api.Tab("book", "Alunos")
+	want := [][]interface{}{
+		{"Nome", "ID", "Status"},
+		{"Ana", 10.0, "Ativo"},
+		{"Bruno", 20.0, "Trancado"},
+		{"Carla", 30.0, "Ativo"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sheet after UpsertRows = %v, want %v", got, want)
+	}
+}
+
+func TestCountRowsUsesConfirmedRows(t *testing.T) {
+	api := newFakeSheetsAPI()
+	api.SetTab("book", "Alunos", nil)
+	writer := newFakeSheetsWriter(t, api)
+	ctx := context.Background()
+
+	headers := []string{"Observação", "ID"}
+	if err := writer.OverwriteSheetData(ctx, "Alunos", headers, [][]interface{}{{"", 10}, {"ok", 20}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.AppendRows(ctx, "Alunos", [][]interface{}{{"", 30}, {"", 40}}); err != nil {
+		t.Fatal(err)
+	}
+
+	before := len(api.Calls())
+	if count, err := writer.CountRows(ctx, "Alunos"); err != nil || count != 4 {
+		t.Errorf("CountRows() = %d, %v; want 4 rows even with an empty first column", count, err)
+	}
+	if calls := api.Calls()[before:]; len(calls) != 0 {
+		t.Errorf("CountRows() read the sheet: %v", calls)
+	}
+
+	if err := writer.Clear(ctx, "Alunos"); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := writer.CountRows(ctx, "Alunos"); err != nil || count != 0 {
+		t.Errorf("CountRows() after Clear = %d, %v; want 0", count, err)
+	}
+}
+
+func TestCountRowsReadsUnwrittenSheets(t *testing.T) {
+	api := newFakeSheetsAPI()
+	api.SetTab("book", "Alunos", [][]interface{}{{"Nome", "ID"}, {"", 10.0}, {"Bruno", 20.0}})
+	writer := newFakeSheetsWriter(t, api)
+
+	if count, err := writer.CountRows(context.Background(), "Alunos"); err != nil || count != 2 {
+		t.Errorf("CountRows() = %d, %v; want 2", count, err)
+	}
+}
+
+func TestOverwriteKeepsSheetIDUnlessSafeOverwrite(t *testing.T) {
+	for _, safe := range []bool{false, true} {
+		api := newFakeSheetsAPI()
+		api.SetTab("book", "Alunos", [][]interface{}{{"Nome"}, {"Ana"}})
+		id := api.tab("book", "Alunos").id
+		writer := newFakeSheetsWriter(t, api)
+		writer.safeOverwrite = safe
+
+		if err := writer.OverwriteSheetData(context.Background(), "Alunos", []string{"Nome"}, [][]interface{}{{"Bruno"}}); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := api.Tab("book", "Alunos"); !reflect.DeepEqual(got, [][]interface{}{{"Nome"}, {"Bruno"}}) {
+			t.Errorf("safeOverwrite=%v: sheet = %v", safe, got)
+		}
+		if kept := api.tab("book", "Alunos").id == id; kept == safe {
+			t.Errorf("safeOverwrite=%v: sheetId kept = %v, want %v", safe, kept, !safe)
+		}
+	}
+}
+
+func TestGoogleSheetsWriterWritesToContextSpreadsheet(t *testing.T) {
+	api := newFakeSheetsAPI()
+	api.SetTab("book", "EAD", nil)
+	api.SetTab("ead-book", "EAD", nil)
+	writer := newFakeSheetsWriter(t, api)
+
+	ctx := WithSpreadsheetID(context.Background(), "ead-book")
+	if err := writer.OverwriteSheetData(ctx, "EAD", []string{"ID"}, [][]interface{}{{1}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := api.Tab("ead-book", "EAD"); len(got) != 2 {
+		t.Errorf("routed spreadsheet holds %v, want the header and one row", got)
+	}
+	if got, _ := api.Tab("book", "EAD"); len(got) != 0 {
+		t.Errorf("default spreadsheet holds %v, want it untouched", got)
+	}
+}
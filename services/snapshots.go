@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"google.golang.org/api/sheets/v4"
+)
+
+// snapshotDateLayout dates snapshot tabs, e.g. "Matrículas EAD 2024-08-01".
+const snapshotDateLayout = "2006-01-02"
+
+// SheetPruner is implemented by writers that can list and delete tabs, used
+// to prune old snapshots.
+type SheetPruner interface {
+	ListSheets(ctx context.Context) ([]string, error)
+	DeleteSheets(ctx context.Context, sheetNames []string) error
+}
+
+// snapshotSheetName suffixes name with day, shortening name so the result
+// still fits in a tab title. Runs on the same day share a snapshot.
+func snapshotSheetName(name string, day time.Time) string {
+	suffix := " " + day.Format(snapshotDateLayout)
+	return truncateRunes(name, maxSheetNameLength-len(suffix)) + suffix
+}
+
+// snapshotName dates name with today when params asks for a snapshot.
+func snapshotName(name string, params *requests.FetchEnrollmentsRequest) string {
+	if !params.Snapshot {
+		return name
+	}
+	return snapshotSheetName(name, time.Now())
+}
+
+// snapshotSuffix matches the date snapshotSheetName appends, followed by the
+// number overwriteSplit gives the tabs of a split snapshot.
+var snapshotSuffix = regexp.MustCompile(` (\d{4}-\d{2}-\d{2})(?: \(\d+\))?$`)
+
+// expiredSnapshots returns the titles that are snapshots of the same sheets
+// as written, dated before cutoff.
+func expiredSnapshots(titles, written []string, cutoff time.Time) []string {
+	bases := make(map[string]bool)
+	for _, name := range written {
+		if loc := snapshotSuffix.FindStringIndex(name); loc != nil {
+			bases[name[:loc[0]]] = true
+		}
+	}
+
+	var expired []string
+	for _, title := range titles {
+		match := snapshotSuffix.FindStringSubmatchIndex(title)
+		if match == nil || !bases[title[:match[0]]] {
+			continue
+		}
+		day, err := time.ParseInLocation(snapshotDateLayout, title[match[2]:match[3]], cutoff.Location())
+		if err == nil && day.Before(cutoff) {
+			expired = append(expired, title)
+		}
+	}
+	return expired
+}
+
+// snapshotSet lists the snapshot tabs a job wrote to one spreadsheet, with
+// the context that routes writes to it.
+type snapshotSet struct {
+	ctx    context.Context
+	sheets []string
+}
+
+// snapshotSets groups written snapshot tabs by spreadsheet.
+type snapshotSets struct {
+	order []string
+	sets  map[string]*snapshotSet
+}
+
+func (s *snapshotSets) add(ctx context.Context, sheet string) {
+	key, _ := ctx.Value(spreadsheetIDKey{}).(string)
+	if s.sets == nil {
+		s.sets = make(map[string]*snapshotSet)
+	}
+	set, ok := s.sets[key]
+	if !ok {
+		set = &snapshotSet{ctx: ctx}
+		s.sets[key] = set
+		s.order = append(s.order, key)
+	}
+	set.sheets = append(set.sheets, sheet)
+}
+
+// pruneSnapshots deletes the snapshots of the written sheets dated more than
+// SNAPSHOT_RETENTION_DAYS before startTime. Failures are reported in result
+// rather than failing the fetch, since the new snapshots were written.
+func (c *JacadClient) pruneSnapshots(logger *slog.Logger, written *snapshotSets, startTime time.Time, result *FetchResult) {
+	if c.Config.SnapshotRetentionDays <= 0 || len(written.order) == 0 {
+		return
+	}
+	pruner, ok := c.Writer.(SheetPruner)
+	if !ok {
+		logger.Info("The writer cannot delete tabs. Old snapshots are kept.")
+		return
+	}
+	y, m, d := startTime.Date()
+	cutoff := time.Date(y, m, d-c.Config.SnapshotRetentionDays, 0, 0, 0, 0, startTime.Location())
+
+	var errs []string
+	for _, key := range written.order {
+		set := written.sets[key]
+		titles, err := pruner.ListSheets(set.ctx)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		expired := expiredSnapshots(titles, set.sheets, cutoff)
+		if len(expired) == 0 {
+			continue
+		}
+		logger.Info("Deleting snapshots older than the retention window", "snapshots", expired, "retentionDays", c.Config.SnapshotRetentionDays)
+		if err := pruner.DeleteSheets(set.ctx, expired); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		result.PrunedSnapshots = append(result.PrunedSnapshots, expired...)
+	}
+	if len(errs) > 0 {
+		logger.Warn("Failed to prune old snapshots", "error", strings.Join(errs, "; "))
+		result.PruneError = strings.Join(errs, "; ")
+	}
+}
+
+// ListSheets returns the titles of the spreadsheet's tabs.
+func (w *GoogleSheetsWriter) ListSheets(ctx context.Context) ([]string, error) {
+	properties, err := w.sheetProperties(ctx)
+	if err != nil {
+		return nil, err
+	}
+	titles := make([]string, len(properties))
+	for i, p := range properties {
+		titles[i] = p.Title
+	}
+	return titles, nil
+}
+
+// DeleteSheets deletes the named tabs in a single batchUpdate, ignoring
+// names no tab has.
+func (w *GoogleSheetsWriter) DeleteSheets(ctx context.Context, sheetNames []string) error {
+	properties, err := w.sheetProperties(ctx)
+	if err != nil {
+		return err
+	}
+	remove := make(map[string]bool, len(sheetNames))
+	for _, name := range sheetNames {
+		remove[name] = true
+	}
+	var deletes []*sheets.Request
+	for _, p := range properties {
+		if remove[p.Title] {
+			deletes = append(deletes, &sheets.Request{DeleteSheet: &sheets.DeleteSheetRequest{SheetId: p.SheetId}})
+		}
+	}
+	if len(deletes) == 0 {
+		return nil
+	}
+
+	deleteCallFunc := func() error {
+		logging.FromContext(ctx).Info("API Sheets: Excluindo abas...", "sheets", len(deletes))
+		_, err := w.sheetsService.Spreadsheets.BatchUpdate(w.spreadsheetFor(ctx), &sheets.BatchUpdateSpreadsheetRequest{Requests: deletes}).Context(ctx).Do()
+		return err
+	}
+	if err := w.executeSheetsCall(ctx, "delete_sheet", deleteCallFunc, fmt.Sprintf("excluir %d abas", len(deletes))); err != nil {
+		return fmt.Errorf("falha ao excluir abas da planilha '%s': %w", w.spreadsheetFor(ctx), err)
+	}
+	return nil
+}
+
+func (w *GoogleSheetsWriter) sheetProperties(ctx context.Context) ([]*sheets.SheetProperties, error) {
+	var spreadsheet *sheets.Spreadsheet
+	getCallFunc := func() error {
+		var err error
+		spreadsheet, err = w.sheetsService.Spreadsheets.Get(w.spreadsheetFor(ctx)).Fields("sheets.properties(sheetId,title)").Context(ctx).Do()
+		return err
+	}
+	if err := w.executeSheetsCall(ctx, "list_sheets", getCallFunc, "listar abas da planilha"); err != nil {
+		return nil, fmt.Errorf("falha ao listar as abas da planilha '%s': %w", w.spreadsheetFor(ctx), err)
+	}
+	properties := make([]*sheets.SheetProperties, len(spreadsheet.Sheets))
+	for i, sheet := range spreadsheet.Sheets {
+		properties[i] = sheet.Properties
+	}
+	return properties, nil
+}
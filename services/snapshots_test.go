@@ -0,0 +1,40 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotSheetName(t *testing.T) {
+	day := time.Date(2024, 8, 1, 15, 0, 0, 0, time.UTC)
+	if got := snapshotSheetName("Matrículas EAD", day); got != "Matrículas EAD 2024-08-01" {
+		t.Errorf("snapshotSheetName = %q, want %q", got, "Matrículas EAD 2024-08-01")
+	}
+	long := snapshotSheetName(strings.Repeat("á", maxSheetNameLength), day)
+	if n := len([]rune(long)); n != maxSheetNameLength || !strings.HasSuffix(long, " 2024-08-01") {
+		t.Errorf("snapshotSheetName of a long name = %q (%d runes), want it dated within %d runes", long, n, maxSheetNameLength)
+	}
+}
+
+func TestExpiredSnapshots(t *testing.T) {
+	titles := []string{
+		"Matrículas EAD",
+		"Matrículas EAD 2024-06-30",
+		"Matrículas EAD 2024-06-30 (2)",
+		"Matrículas EAD 2024-07-02",
+		"Matrículas EAD 2024-08-01",
+		"Matrículas POS 2024-06-01",
+		"Matrículas EAD 2024-13-01",
+		"Changes 2024-06-01",
+	}
+	written := []string{"Matrículas EAD 2024-08-01"}
+	cutoff := time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC)
+
+	got := expiredSnapshots(titles, written, cutoff)
+	want := []string{"Matrículas EAD 2024-06-30", "Matrículas EAD 2024-06-30 (2)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expiredSnapshots = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// summaryHeaders heads the summary tab; each section below it starts with its
+// own title row.
+var summaryHeaders = []string{"Resumo", "Matrículas"}
+
+const noSummaryDate = "Sem data"
+
+// EnrollmentSummary counts a fetch's enrollments per course, status, unidade
+// física and month of dataMatricula.
+type EnrollmentSummary struct {
+	Total           int            `json:"total"`
+	ByCourse        map[string]int `json:"byCourse"`
+	ByStatus        map[string]int `json:"byStatus"`
+	ByUnidadeFisica map[string]int `json:"byUnidadeFisica"`
+	// ByMonth is keyed on YYYY-MM.
+	ByMonth map[string]int `json:"byMonth"`
+
+	// orgs, when set, limits the counts to the organizations written.
+	orgs map[int]bool
+}
+
+// newEnrollmentSummary returns nil unless params asks for a summary tab. Only
+// enrollments of the organizations targets write are counted, since Jacad
+// returns every organization's.
+func newEnrollmentSummary(params *requests.FetchEnrollmentsRequest, targets []sheetTarget) *EnrollmentSummary {
+	if !params.SummaryTab {
+		return nil
+	}
+	summary := &EnrollmentSummary{
+		ByCourse:        make(map[string]int),
+		ByStatus:        make(map[string]int),
+		ByUnidadeFisica: make(map[string]int),
+		ByMonth:         make(map[string]int),
+	}
+	for _, target := range targets {
+		if !target.PartitionByOrg {
+			return summary
+		}
+		if summary.orgs == nil {
+			summary.orgs = make(map[int]bool)
+		}
+		summary.orgs[target.OrgID] = true
+	}
+	return summary
+}
+
+// wrap returns a sink that counts every enrollment before forwarding it to
+// sink. A nil summary forwards everything uncounted.
+func (s *EnrollmentSummary) wrap(sink func([]models.Enrollment) error) func([]models.Enrollment) error {
+	if s == nil {
+		return sink
+	}
+	return func(data []models.Enrollment) error {
+		s.add(data)
+		return sink(data)
+	}
+}
+
+func (s *EnrollmentSummary) add(data []models.Enrollment) {
+	for _, item := range data {
+		if s.orgs != nil && !s.orgs[item.OrgID] {
+			continue
+		}
+		s.Total++
+		s.ByCourse[summaryLabel(item.Curso, "Sem curso")]++
+		s.ByStatus[summaryLabel(item.Status, "Sem status")]++
+		s.ByUnidadeFisica[summaryLabel(item.UnidadeFisica, "Sem unidade física")]++
+		month := noSummaryDate
+		if item.DataMatricula != nil && !time.Time(*item.DataMatricula).IsZero() {
+			month = time.Time(*item.DataMatricula).Format("2006-01")
+		}
+		s.ByMonth[month]++
+	}
+}
+
+func summaryLabel(value *string, empty string) string {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return empty
+	}
+	return strings.TrimSpace(*value)
+}
+
+// rows renders the summary as stacked sections: the total, then the counts per
+// course, status and unidade física from most to fewest enrollments, then per
+// month in calendar order.
+func (s *EnrollmentSummary) rows(generatedAt time.Time) [][]interface{} {
+	rows := [][]interface{}{
+		{"Gerado em", generatedAt.Format("2006-01-02 15:04")},
+		{"Total de matrículas", s.Total},
+	}
+	section := func(title string, keys []string, counts map[string]int) {
+		rows = append(rows, []interface{}{}, []interface{}{title, "Matrículas"})
+		for _, key := range keys {
+			rows = append(rows, []interface{}{key, counts[key]})
+		}
+	}
+	section("Curso", keysByCount(s.ByCourse), s.ByCourse)
+	section("Status", keysByCount(s.ByStatus), s.ByStatus)
+	section("Unidade física", keysByCount(s.ByUnidadeFisica), s.ByUnidadeFisica)
+
+	months := make([]string, 0, len(s.ByMonth))
+	for month := range s.ByMonth {
+		months = append(months, month)
+	}
+	sort.Slice(months, func(i, j int) bool {
+		if (months[i] == noSummaryDate) != (months[j] == noSummaryDate) {
+			return months[j] == noSummaryDate
+		}
+		return months[i] < months[j]
+	})
+	section("Mês da matrícula", months, s.ByMonth)
+	return rows
+}
+
+func keysByCount(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func summarySheetName(params *requests.FetchEnrollmentsRequest) string {
+	name := fmt.Sprintf("Resumo STATUS: %s | Período ID %s", params.StatusLabel(), params.PeriodLabel())
+	return truncateRunes(name, maxSheetNameLength)
+}
+
+// writeSummary attaches summary to result and, unless this is a dry run,
+// writes it to the summary tab. Failures are reported in result rather than
+// failing the fetch, since the data tabs were already written.
+func (c *JacadClient) writeSummary(ctx context.Context, logger *slog.Logger, summary *EnrollmentSummary, params *requests.FetchEnrollmentsRequest, startTime time.Time, result *FetchResult) {
+	if summary == nil {
+		return
+	}
+	result.Summary = summary
+	if params.DryRun {
+		return
+	}
+
+	name := summarySheetName(params)
+	logger.Info("Writing summary sheet", "sheet", name, "enrollments", summary.Total)
+	if err := c.Writer.OverwriteSheetData(ctx, name, summaryHeaders, summary.rows(startTime)); err != nil {
+		logger.Error("Failed to write summary sheet", "sheet", name, "error", err)
+		result.SummaryError = err.Error()
+		return
+	}
+	result.SummarySheet = name
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+func TestEnrollmentSummaryRows(t *testing.T) {
+	if newEnrollmentSummary(&requests.FetchEnrollmentsRequest{}, nil) != nil {
+		t.Fatal("newEnrollmentSummary() without summaryTab is not nil")
+	}
+	summary := newEnrollmentSummary(&requests.FetchEnrollmentsRequest{SummaryTab: true}, nil)
+	feb := utils.Date(time.Date(2025, time.February, 10, 0, 0, 0, 0, time.UTC))
+	jan := utils.Date(time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC))
+	var forwarded int
+	sink := summary.wrap(func(data []models.Enrollment) error {
+		forwarded += len(data)
+		return nil
+	})
+	sink([]models.Enrollment{
+		{Curso: ptr("Direito"), Status: ptr("ATIVA"), UnidadeFisica: ptr("Centro"), DataMatricula: &feb},
+		{Curso: ptr("Direito"), Status: ptr("ATIVA"), UnidadeFisica: ptr(" "), DataMatricula: &jan},
+	})
+	sink([]models.Enrollment{
+		{Curso: ptr("Administração"), Status: ptr("CANCELADA"), UnidadeFisica: ptr("Centro")},
+	})
+
+	if forwarded != 3 {
+		t.Errorf("forwarded %d enrollments, want 3", forwarded)
+	}
+	want := [][]interface{}{
+		{"Gerado em", "2025-03-01 09:30"},
+		{"Total de matrículas", 3},
+		{},
+		{"Curso", "Matrículas"},
+		{"Direito", 2},
+		{"Administração", 1},
+		{},
+		{"Status", "Matrículas"},
+		{"ATIVA", 2},
+		{"CANCELADA", 1},
+		{},
+		{"Unidade física", "Matrículas"},
+		{"Centro", 2},
+		{"Sem unidade física", 1},
+		{},
+		{"Mês da matrícula", "Matrículas"},
+		{"2025-01", 1},
+		{"2025-02", 1},
+		{"Sem data", 1},
+	}
+	if got := summary.rows(time.Date(2025, time.March, 1, 9, 30, 0, 0, time.UTC)); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestFetchEnrollmentsSummaryTab(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer server.Close()
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.PageSize = 50
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	writer := NewFakeSheetWriter()
+	client := NewJacadClient(&cfg, writer, NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+
+	params := func(dryRun bool) *requests.FetchEnrollmentsRequest {
+		return &requests.FetchEnrollmentsRequest{
+			OrgIds:          "20",
+			IdPeriodoLetivo: 87,
+			StatusMatricula: "ATIVA",
+			Mode:            requests.SyncModeFull,
+			WriteMode:       requests.WriteModeAtomic,
+			GroupBy:         requests.GroupByNone,
+			SummaryTab:      true,
+			DryRun:          dryRun,
+		}
+	}
+
+	result, err := client.FetchEnrollmentsFiltered(context.Background(), params(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const name = "Resumo STATUS: ATIVA | Período ID 87"
+	if result.SummarySheet != name || result.Summary == nil || result.Summary.Total != 60 || result.Summary.ByStatus["ATIVA"] != 60 {
+		t.Fatalf("result summary = %q %+v, want organization 20's 60 enrollments in %q", result.SummarySheet, result.Summary, name)
+	}
+	sheet, ok := writer.Sheet(name)
+	if !ok || !reflect.DeepEqual(sheet.Headers, summaryHeaders) || !reflect.DeepEqual(sheet.Rows[1], []interface{}{"Total de matrículas", 60}) {
+		t.Errorf("summary tab = %+v, want the totals under %v", sheet, summaryHeaders)
+	}
+
+	dry := NewFakeSheetWriter()
+	client.Writer = dry
+	if result, err = client.FetchEnrollmentsFiltered(context.Background(), params(true)); err != nil {
+		t.Fatal(err)
+	}
+	if result.Summary == nil || result.Summary.Total != 60 || result.SummarySheet != "" {
+		t.Errorf("dry run summary = %q %+v, want the counts without a tab", result.SummarySheet, result.Summary)
+	}
+	if _, ok := dry.Sheet(name); ok {
+		t.Error("dry run wrote the summary tab")
+	}
+
+	failing := NewFakeSheetWriter()
+	failing.FailOn = func(method, sheet string) error {
+		if sheet == name {
+			return errors.New("quota exceeded")
+		}
+		return nil
+	}
+	client.Writer = failing
+	if result, err = client.FetchEnrollmentsFiltered(context.Background(), params(false)); err != nil {
+		t.Fatalf("FetchEnrollmentsFiltered() = %v, want the data tabs kept when the summary fails", err)
+	}
+	if result.SummaryError == "" || result.SummarySheet != "" || result.Summary == nil {
+		t.Errorf("result = %q %q, want the summary error reported", result.SummarySheet, result.SummaryError)
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SyncState is the watermark recorded after each complete write to a sheet.
+// Incremental runs against writers that cannot read sheets back only upsert
+// enrollments registered on or after these dates.
+type SyncState struct {
+	LastDataCadastro  time.Time `json:"lastDataCadastro"`
+	LastDataMatricula time.Time `json:"lastDataMatricula"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// syncStateKey identifies the sync state of sheetName in the spreadsheet and
+// Jacad tenant of ctx, so same-named tabs of per-request, per-organization or
+// default spreadsheets, and of different tenants, keep their own watermarks.
+func (c *JacadClient) syncStateKey(ctx context.Context, sheetName string) string {
+	spreadsheetID := spreadsheetIDFrom(ctx)
+	if spreadsheetID == "" {
+		spreadsheetID = c.Config.SpreadsheetID
+	}
+	key := sheetName
+	if spreadsheetID != "" {
+		key = spreadsheetID + "!" + sheetName
+	}
+	return tenantScoped(ctx, key)
+}
+
+type SyncStateStore interface {
+	Load(key string) (*SyncState, error)
+	Save(key string, state SyncState) error
+}
+
+type FileSyncStateStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSyncStateStore(path string) *FileSyncStateStore {
+	return &FileSyncStateStore{path: path}
+}
+
+func (s *FileSyncStateStore) Load(key string) (*SyncState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	state, ok := states[key]
+	if !ok {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+func (s *FileSyncStateStore) Save(key string, state SyncState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states, err := s.readAll()
+	if err != nil {
+		return err
+	}
+	states[key] = state
+
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sync state: %w", err)
+	}
+
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create sync state directory '%s': %w", dir, err)
+		}
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write sync state file '%s': %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace sync state file '%s': %w", s.path, err)
+	}
+	return nil
+}
+
+func (s *FileSyncStateStore) readAll() (map[string]SyncState, error) {
+	states := make(map[string]SyncState)
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return states, nil
+		}
+		return nil, fmt.Errorf("failed to read sync state file '%s': %w", s.path, err)
+	}
+	if len(data) == 0 {
+		return states, nil
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to parse sync state file '%s': %w", s.path, err)
+	}
+	return states, nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+func testDate(s string) *utils.Date {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	d := utils.Date(t)
+	return &d
+}
+
+func TestFileSyncStateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "sync_state.json")
+	store := NewFileSyncStateStore(path)
+
+	state, err := store.Load("Alunos")
+	if err != nil || state != nil {
+		t.Fatalf("Load() on a missing file = %v, %v, want nil, nil", state, err)
+	}
+
+	saved := SyncState{LastDataCadastro: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), LastDataMatricula: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)}
+	if err := store.Save("Alunos", saved); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("Inativos", SyncState{}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err = NewFileSyncStateStore(path).Load("Alunos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state == nil || !state.LastDataCadastro.Equal(saved.LastDataCadastro) || !state.LastDataMatricula.Equal(saved.LastDataMatricula) {
+		t.Errorf("Load() = %+v, want %+v", state, saved)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load("Alunos"); err == nil {
+		t.Error("Load() of a corrupt file succeeded, want an error")
+	}
+}
+
+func TestFilterEnrollmentsSince(t *testing.T) {
+	data := []models.Enrollment{
+		{IdMatricula: 1, DataCadastro: testDate("2024-01-10"), DataMatricula: testDate("2024-01-10")},
+		{IdMatricula: 2, DataCadastro: testDate("2024-03-01")},
+		{IdMatricula: 3, DataCadastro: testDate("2024-01-05"), DataMatricula: testDate("2024-03-05")},
+		{IdMatricula: 4},
+	}
+	state := &SyncState{LastDataCadastro: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), LastDataMatricula: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)}
+
+	var got []int
+	for _, item := range filterEnrollmentsSince(data, state) {
+		got = append(got, item.IdMatricula)
+	}
+	if len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("filterEnrollmentsSince() = %v, want [2 3]", got)
+	}
+	if n := len(filterEnrollmentsSince(data, nil)); n != len(data) {
+		t.Errorf("filterEnrollmentsSince(nil state) kept %d, want all %d", n, len(data))
+	}
+}
+
+func TestBuildSyncState(t *testing.T) {
+	data := []models.Enrollment{
+		{DataCadastro: testDate("2024-02-01"), DataMatricula: testDate("2024-01-20")},
+		{DataCadastro: testDate("2024-01-15")},
+	}
+
+	state := buildSyncState(data, nil)
+	if want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC); !state.LastDataCadastro.Equal(want) {
+		t.Errorf("LastDataCadastro = %v, want %v", state.LastDataCadastro, want)
+	}
+	if want := time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC); !state.LastDataMatricula.Equal(want) {
+		t.Errorf("LastDataMatricula = %v, want %v", state.LastDataMatricula, want)
+	}
+
+	previous := &SyncState{LastDataCadastro: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+	state = buildSyncState(data, previous)
+	if !state.LastDataCadastro.Equal(previous.LastDataCadastro) {
+		t.Errorf("LastDataCadastro = %v, want the previous watermark %v kept", state.LastDataCadastro, previous.LastDataCadastro)
+	}
+}
+
+func TestSyncStateKey(t *testing.T) {
+	c := &JacadClient{Config: &config.Config{SpreadsheetID: "default"}}
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "default spreadsheet", ctx: ctx, want: "default!Alunos"},
+		{name: "organization spreadsheet", ctx: WithSpreadsheetID(ctx, "org-20"), want: "org-20!Alunos"},
+		{name: "job spreadsheet", ctx: withJobSpreadsheet(ctx, &CreatedSpreadsheet{ID: "job"}), want: "job!Alunos"},
+		{name: "tenant", ctx: WithSpreadsheetID(WithTenant(ctx, "campus"), "org-20"), want: "campus:org-20!Alunos"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.syncStateKey(tt.ctx, "Alunos"); got != tt.want {
+				t.Errorf("syncStateKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := (&JacadClient{Config: &config.Config{}}).syncStateKey(ctx, "Alunos"); got != "Alunos" {
+		t.Errorf("syncStateKey() without a spreadsheet = %q, want the sheet name", got)
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+)
+
+type tenantKey struct{}
+
+// WithTenant routes Jacad calls made with ctx to the named profile. The empty
+// tenant is the default instance.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	if tenant == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+func tenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// tenantScoped prefixes key with the tenant in ctx so cached responses and
+// checkpoints of different Jacad instances never mix.
+func tenantScoped(ctx context.Context, key string) string {
+	if tenant := tenantFrom(ctx); tenant != "" {
+		return tenant + ":" + key
+	}
+	return key
+}
+
+// authState caches the Jacad token of one profile. From refreshAt on, a
+// background login replaces the token; refreshing is set while it runs.
+type authState struct {
+	mu         sync.Mutex
+	token      string
+	expiry     time.Time
+	refreshAt  time.Time
+	refreshing bool
+	// failures counts background refreshes that failed since the last
+	// successful login; see retryRefresh.
+	failures int
+}
+
+// profile returns the Jacad profile selected by ctx.
+func (c *JacadClient) profile(ctx context.Context) (config.JacadProfile, error) {
+	tenant := tenantFrom(ctx)
+	profile, err := c.Config.JacadProfile(tenant)
+	if err == nil && tenant == "" && c.UserToken != nil {
+		profile.UserToken = c.UserToken.Text()
+	}
+	return profile, err
+}
+
+// apiBase returns the API base URL of the Jacad instance ctx's calls go to,
+// which keys the state kept per instance. Tenants sharing a deployment share
+// it; an unknown tenant falls back to its name.
+func (c *JacadClient) apiBase(ctx context.Context) string {
+	tenant := tenantFrom(ctx)
+	profile, err := c.Config.JacadProfile(tenant)
+	if err != nil {
+		return tenant
+	}
+	return profile.APIBase
+}
+
+// perInstance holds one value per Jacad instance, keyed by API base URL and
+// made by create on first use, so one deployment failing or being throttled
+// does not affect calls to the others.
+type perInstance[T any] struct {
+	create func(apiBase string) T
+	mu     sync.Mutex
+	values map[string]T
+}
+
+func newPerInstance[T any](create func(apiBase string) T) *perInstance[T] {
+	return &perInstance[T]{create: create, values: make(map[string]T)}
+}
+
+// get returns the value of apiBase. A nil *perInstance, as in clients built
+// without NewJacadClient, returns the zero T.
+func (p *perInstance[T]) get(apiBase string) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	value, ok := p.values[apiBase]
+	if !ok {
+		value = p.create(apiBase)
+		p.values[apiBase] = value
+	}
+	return value
+}
+
+// authFor returns the token cache of tenant, creating it on first use.
+func (c *JacadClient) authFor(tenant string) *authState {
+	c.muAuth.Lock()
+	defer c.muAuth.Unlock()
+	state, ok := c.auth[tenant]
+	if !ok {
+		state = &authState{}
+		c.auth[tenant] = state
+	}
+	return state
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+)
+
+func TestTenantScoped(t *testing.T) {
+	ctx := context.Background()
+	if got := tenantScoped(ctx, "page"); got != "page" {
+		t.Errorf("tenantScoped() without a tenant = %q, want %q", got, "page")
+	}
+	if got := tenantScoped(WithTenant(ctx, "colegio"), "page"); got != "colegio:page" {
+		t.Errorf("tenantScoped() = %q, want %q", got, "colegio:page")
+	}
+	if got := tenantFrom(WithTenant(ctx, "")); got != "" {
+		t.Errorf("WithTenant(\"\") selected tenant %q, want the default instance", got)
+	}
+}
+
+// TestFetchEnrollmentsForTenant fetches the same query from the default
+// instance and a tenant's, sharing one response cache, and checks each run
+// logs in to and reads from its own instance only.
+func TestFetchEnrollmentsForTenant(t *testing.T) {
+	jacad := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer jacad.Close()
+	colegio := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(72), UserToken: "colegio-token"})
+	defer colegio.Close()
+
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.APIBase = jacad.URL
+	cfg.UserToken = jacad.UserToken
+	cfg.JacadProfiles = map[string]config.JacadProfile{"colegio": {APIBase: colegio.URL, UserToken: "colegio-token"}}
+	cfg.PageSize = 50
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	cfg.CacheTTL = time.Minute
+	client := NewJacadClient(&cfg, NewDiscardWriter(), NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), NewLRUCache(100))
+
+	fetch := func(tenant string) *FetchResult {
+		t.Helper()
+		result, err := client.FetchEnrollmentsFiltered(context.Background(), &requests.FetchEnrollmentsRequest{
+			OrgIds:          "20",
+			IdPeriodoLetivo: 87,
+			StatusMatricula: "ATIVA",
+			Mode:            requests.SyncModeFull,
+			WriteMode:       requests.WriteModeAtomic,
+			GroupBy:         requests.GroupByNone,
+			Tenant:          tenant,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if got := fetch("").TotalFetched; got != 180 {
+		t.Errorf("default instance fetched %d enrollments, want 180", got)
+	}
+	if got := fetch("colegio").TotalFetched; got != 18 {
+		t.Errorf("tenant fetched %d enrollments, want the 18 of its instance", got)
+	}
+	for name, server := range map[string]*jacadmock.Server{"default": jacad, "colegio": colegio} {
+		if logins := server.Requests("AUTH"); logins != 1 {
+			t.Errorf("%s instance got %d logins, want 1", name, logins)
+		}
+		if pages := server.Requests("ENROLLMENTS"); pages == 0 {
+			t.Errorf("%s instance served no enrollment pages", name)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/api/googleapi"
+)
+
+// recordSpans sends the spans of the test to the returned recorder.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestJacadRequestSpan(t *testing.T) {
+	recorder := recordSpans(t)
+	var mu sync.Mutex
+	var traceparents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		if len(traceparents) == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	client := NewJacadClient(&cfg, NewFakeSheetWriter(), nil, nil, nil)
+	if _, err := client.MakeRequest(context.Background(), http.MethodGet, server.URL+cfg.Endpoints["COURSES"], nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want one for the whole call", len(spans))
+	}
+	span := spans[0]
+	if !strings.HasPrefix(span.Name(), "jacad GET ") || span.Status().Code == codes.Error {
+		t.Errorf("span = %q %+v, want a successful Jacad GET", span.Name(), span.Status())
+	}
+	var attempts int
+	for _, event := range span.Events() {
+		if event.Name == "attempt" {
+			attempts++
+		}
+	}
+	if attempts != 2 {
+		t.Errorf("span has %d attempt events, want 2", attempts)
+	}
+	traceID := span.SpanContext().TraceID().String()
+	for i, traceparent := range traceparents {
+		if !strings.Contains(traceparent, traceID) {
+			t.Errorf("attempt %d sent traceparent %q, want trace %s", i+1, traceparent, traceID)
+		}
+	}
+}
+
+func TestSheetsCallSpan(t *testing.T) {
+	recorder := recordSpans(t)
+	writer := newFakeSheetsWriter(t, newFakeSheetsAPI())
+
+	calls := 0
+	err := writer.executeSheetsCall(context.Background(), "overwrite", func() error {
+		calls++
+		if calls == 1 {
+			return &googleapi.Error{Code: http.StatusTooManyRequests, Message: "quota"}
+		}
+		return nil
+	}, "sobrescrever aba")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.executeSheetsCall(context.Background(), "append", func() error {
+		return errors.New("invalid range")
+	}, "anexar linhas")
+	if err == nil {
+		t.Fatal("executeSheetsCall() hid the failure")
+	}
+
+	var overwrite, appendSpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "sheets overwrite":
+			overwrite = span
+		case "sheets append":
+			appendSpan = span
+		}
+	}
+	if overwrite == nil || appendSpan == nil {
+		t.Fatalf("recorded spans %v, want one per call", recorder.Ended())
+	}
+	if events := overwrite.Events(); len(events) != 1 || events[0].Name != "retry" || overwrite.Status().Code == codes.Error {
+		t.Errorf("retried call span = %v %+v, want one retry event and no error", events, overwrite.Status())
+	}
+	if appendSpan.Status().Code != codes.Error {
+		t.Errorf("failed call span status = %+v, want an error", appendSpan.Status())
+	}
+}
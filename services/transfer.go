@@ -0,0 +1,168 @@
+package services
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+)
+
+// TransferStats counts the Jacad response bytes a job received and what
+// compression and conditional requests saved it.
+type TransferStats struct {
+	Requests int `json:"requests"`
+	// NotModified counts pages Jacad answered with 304, reusing the body
+	// kept from an earlier job.
+	NotModified int `json:"notModified,omitempty"`
+	// BytesReceived is what came over the wire; BytesDecoded is the same
+	// responses decompressed.
+	BytesReceived int64 `json:"bytesReceived"`
+	BytesDecoded  int64 `json:"bytesDecoded"`
+	// BytesSaved adds the compression savings and the size of the bodies
+	// 304 responses did not resend.
+	BytesSaved int64 `json:"bytesSaved"`
+}
+
+// transferTracker accumulates TransferStats per job ID.
+type transferTracker struct {
+	mu   sync.Mutex
+	jobs map[string]*TransferStats
+}
+
+func (t *transferTracker) add(ctx context.Context, update func(*TransferStats)) {
+	jobID := logging.JobID(ctx)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.jobs == nil {
+		t.jobs = make(map[string]*TransferStats)
+	}
+	stats, ok := t.jobs[jobID]
+	if !ok {
+		stats = &TransferStats{}
+		t.jobs[jobID] = stats
+	}
+	update(stats)
+}
+
+// take returns and forgets the stats of the job in ctx.
+func (t *transferTracker) take(ctx context.Context) TransferStats {
+	jobID := logging.JobID(ctx)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats, ok := t.jobs[jobID]
+	if !ok {
+		return TransferStats{}
+	}
+	delete(t.jobs, jobID)
+	return *stats
+}
+
+// peek returns the stats of the job in ctx so far.
+func (t *transferTracker) peek(ctx context.Context) TransferStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if stats, ok := t.jobs[logging.JobID(ctx)]; ok {
+		return *stats
+	}
+	return TransferStats{}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// readResponseBody reads resp's body, decompressing it when Jacad gzipped it
+// because the request asked for it, and returns the body with its size on
+// the wire.
+func readResponseBody(resp *http.Response) ([]byte, int64, error) {
+	wire := &countingReader{r: resp.Body}
+	var body io.Reader = wire
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") && !resp.Uncompressed {
+		gz, err := gzip.NewReader(wire)
+		if err != nil {
+			return nil, wire.n, fmt.Errorf("invalid gzip response body: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+	data, err := io.ReadAll(body)
+	return data, wire.n, err
+}
+
+// conditionalEntry is a page body kept with the validators Jacad sent for it.
+type conditionalEntry struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Body         []byte `json:"body"`
+}
+
+func conditionalKey(cacheKey string) string {
+	return "conditional:" + cacheKey
+}
+
+// conditionalEntry returns the validated body kept for cacheKey, or nil when
+// conditional requests are off or nothing was kept.
+func (c *JacadClient) conditionalEntry(ctx context.Context, cacheKey string) *conditionalEntry {
+	if !c.Config.JacadConditionalRequests || c.Cache == nil {
+		return nil
+	}
+	data, ok, err := c.Cache.Get(ctx, conditionalKey(cacheKey))
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to read Jacad validators from cache", "key", cacheKey, "error", err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	var entry conditionalEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// storeConditional keeps body with the ETag or Last-Modified header of the
+// response it came in, if Jacad sent either.
+func (c *JacadClient) storeConditional(ctx context.Context, cacheKey string, header http.Header, body []byte) {
+	if !c.Config.JacadConditionalRequests || c.Cache == nil {
+		return
+	}
+	entry := conditionalEntry{ETag: header.Get("ETag"), LastModified: header.Get("Last-Modified"), Body: body}
+	if entry.ETag == "" && entry.LastModified == "" {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := c.Cache.Set(ctx, conditionalKey(cacheKey), data, c.Config.JacadConditionalTTL); err != nil {
+		logging.FromContext(ctx).Warn("Failed to store Jacad validators in cache", "key", cacheKey, "error", err)
+	}
+}
+
+// headers adds the entry's validators to a request's headers.
+func (e *conditionalEntry) headers(headers map[string]string) {
+	if e == nil {
+		return
+	}
+	if e.ETag != "" {
+		headers["If-None-Match"] = e.ETag
+	}
+	if e.LastModified != "" {
+		headers["If-Modified-Since"] = e.LastModified
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+)
+
+func TestReadResponseBody(t *testing.T) {
+	payload := strings.Repeat(`{"idMatricula":1,"nome":"Aluno"}`, 50)
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte(payload))
+	gz.Close()
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		wantWire int64
+	}{
+		{name: "plain", body: []byte(payload), wantWire: int64(len(payload))},
+		{name: "gzip", body: gzipped.Bytes(), encoding: "gzip", wantWire: int64(gzipped.Len())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(tt.body))}
+			if tt.encoding != "" {
+				resp.Header.Set("Content-Encoding", tt.encoding)
+			}
+			body, wire, err := readResponseBody(resp)
+			if err != nil {
+				t.Fatalf("readResponseBody() error = %v", err)
+			}
+			if string(body) != payload {
+				t.Errorf("readResponseBody() body = %q, want the decoded payload", body)
+			}
+			if wire != tt.wantWire {
+				t.Errorf("readResponseBody() wire bytes = %d, want %d", wire, tt.wantWire)
+			}
+		})
+	}
+}
+
+func TestTransferTrackerPerJob(t *testing.T) {
+	var tracker transferTracker
+	jobA := logging.WithJobID(context.Background(), "job-a")
+	jobB := logging.WithJobID(context.Background(), "job-b")
+
+	tracker.add(jobA, func(s *TransferStats) { s.Requests++; s.BytesReceived += 100; s.BytesSaved += 400 })
+	tracker.add(jobA, func(s *TransferStats) { s.NotModified++; s.BytesSaved += 500 })
+	tracker.add(jobB, func(s *TransferStats) { s.Requests++ })
+
+	want := TransferStats{Requests: 1, NotModified: 1, BytesReceived: 100, BytesSaved: 900}
+	if got := tracker.take(jobA); !reflect.DeepEqual(got, want) {
+		t.Errorf("take(job-a) = %+v, want %+v", got, want)
+	}
+	if got := tracker.take(jobA); got != (TransferStats{}) {
+		t.Errorf("take(job-a) after take = %+v, want zero", got)
+	}
+	if got := tracker.peek(jobB); got.Requests != 1 {
+		t.Errorf("peek(job-b) = %+v, want 1 request", got)
+	}
+}
+
+func TestConditionalEntryHeaders(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry *conditionalEntry
+		want  map[string]string
+	}{
+		{name: "nothing kept", want: map[string]string{}},
+		{name: "etag", entry: &conditionalEntry{ETag: `"v1"`}, want: map[string]string{"If-None-Match": `"v1"`}},
+		{
+			name:  "both",
+			entry: &conditionalEntry{ETag: `"v1"`, LastModified: "Mon, 03 Mar 2025 10:00:00 GMT"},
+			want:  map[string]string{"If-None-Match": `"v1"`, "If-Modified-Since": "Mon, 03 Mar 2025 10:00:00 GMT"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			tt.entry.headers(headers)
+			if !reflect.DeepEqual(headers, tt.want) {
+				t.Errorf("headers() = %v, want %v", headers, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+// TransformFunc rewrites the value of a text field of an enrollment.
+type TransformFunc func(value string) string
+
+// TransformFactory builds the TransformFunc for a configured rule, using its
+// Arg and Values as parameters.
+type TransformFactory func(rule config.Transform) (TransformFunc, error)
+
+var (
+	transformsMu sync.RWMutex
+	transformOps = map[string]TransformFactory{
+		"trim":    simpleTransform(strings.TrimSpace),
+		"upper":   simpleTransform(strings.ToUpper),
+		"lower":   simpleTransform(strings.ToLower),
+		"title":   simpleTransform(titleWords),
+		"digits":  simpleTransform(onlyDigits),
+		"pad":     padTransform,
+		"replace": replaceTransform,
+		"map":     mapTransform,
+	}
+)
+
+// RegisterTransform makes op available to transformation rules, replacing a
+// built-in op of the same name. Register ops before the first fetch, e.g. from
+// an init function.
+func RegisterTransform(op string, factory TransformFactory) {
+	transformsMu.Lock()
+	defer transformsMu.Unlock()
+	transformOps[strings.ToLower(op)] = factory
+}
+
+type fieldTransform struct {
+	index int
+	apply TransformFunc
+}
+
+var stringPtrType = reflect.TypeOf((*string)(nil))
+
+// Transformed returns a copy of m that runs rules, in order, on each
+// enrollment before it is mapped. Rules apply to text fields only and run
+// before redaction.
+func (m *EnrollmentRowMapper) Transformed(rules []config.Transform) (*EnrollmentRowMapper, error) {
+	if len(rules) == 0 {
+		return m, nil
+	}
+	enrollmentType := reflect.TypeOf(models.Enrollment{})
+
+	transformed := *m
+	transformed.transforms = make([]fieldTransform, 0, len(rules))
+	for _, rule := range rules {
+		idx, ok := enrollmentFields[rule.Field]
+		if !ok {
+			return nil, fmt.Errorf("unknown enrollment field '%s' in transform", rule.Field)
+		}
+		if enrollmentType.Field(idx).Type != stringPtrType {
+			return nil, fmt.Errorf("enrollment field '%s' is not a text field and cannot be transformed", rule.Field)
+		}
+
+		transformsMu.RLock()
+		factory, ok := transformOps[rule.Op]
+		transformsMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown transform op '%s' for field '%s'", rule.Op, rule.Field)
+		}
+		apply, err := factory(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid '%s' transform for field '%s': %w", rule.Op, rule.Field, err)
+		}
+		transformed.transforms = append(transformed.transforms, fieldTransform{index: idx, apply: apply})
+	}
+	return &transformed, nil
+}
+
+// transform returns item with the mapper's transforms applied. Fields are
+// replaced rather than written through, so the caller's data is unchanged.
+func (m *EnrollmentRowMapper) transform(item models.Enrollment) models.Enrollment {
+	v := reflect.ValueOf(&item).Elem()
+	for _, t := range m.transforms {
+		field := v.Field(t.index)
+		if field.IsNil() {
+			continue
+		}
+		value := t.apply(field.Elem().String())
+		field.Set(reflect.ValueOf(&value))
+	}
+	return item
+}
+
+func simpleTransform(fn func(string) string) TransformFactory {
+	return func(config.Transform) (TransformFunc, error) {
+		return fn, nil
+	}
+}
+
+// padTransform left-pads values with zeros to Arg characters, e.g. RA "123"
+// with arg 6 becomes "000123".
+func padTransform(rule config.Transform) (TransformFunc, error) {
+	width, err := strconv.Atoi(rule.Arg)
+	if err != nil || width <= 0 {
+		return nil, fmt.Errorf("arg must be a positive width, got '%s'", rule.Arg)
+	}
+	return func(value string) string {
+		if value == "" || len(value) >= width {
+			return value
+		}
+		return strings.Repeat("0", width-len(value)) + value
+	}, nil
+}
+
+// replaceTransform replaces every occurrence of old with new, given as
+// "old>new" in Arg.
+func replaceTransform(rule config.Transform) (TransformFunc, error) {
+	old, replacement, ok := strings.Cut(rule.Arg, ">")
+	if !ok || old == "" {
+		return nil, fmt.Errorf("arg must be 'old>new', got '%s'", rule.Arg)
+	}
+	return func(value string) string {
+		return strings.ReplaceAll(value, old, replacement)
+	}, nil
+}
+
+// mapTransform replaces whole values using Values, plus any "from>to" pairs
+// separated by '|' in Arg. Values without an entry are kept.
+func mapTransform(rule config.Transform) (TransformFunc, error) {
+	values := make(map[string]string, len(rule.Values))
+	for from, to := range rule.Values {
+		values[from] = to
+	}
+	if rule.Arg != "" {
+		for _, pair := range strings.Split(rule.Arg, "|") {
+			from, to, ok := strings.Cut(pair, ">")
+			if !ok {
+				return nil, fmt.Errorf("invalid pair '%s': expected from>to", pair)
+			}
+			values[strings.TrimSpace(from)] = strings.TrimSpace(to)
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no values to map")
+	}
+	return func(value string) string {
+		if mapped, ok := values[value]; ok {
+			return mapped
+		}
+		return value
+	}, nil
+}
+
+// titleWords capitalizes the first letter of each word and lowercases the
+// rest, e.g. "MARIA DA SILVA" becomes "Maria Da Silva".
+func titleWords(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+func onlyDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
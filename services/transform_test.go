@@ -0,0 +1,126 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+)
+
+func TestEnrollmentRowMapperTransformed(t *testing.T) {
+	enrollment := models.Enrollment{
+		IdMatricula: 7,
+		Aluno:       ptr("  MARIA DA SILVA "),
+		RA:          ptr("12.34-5"),
+		Curso:       ptr("Direito - Noturno"),
+		Status:      ptr("CANCELADA"),
+		Turma:       ptr("T1"),
+	}
+	columns := []config.Column{
+		{Field: "aluno", Header: "aluno"},
+		{Field: "ra", Header: "ra"},
+		{Field: "curso", Header: "curso"},
+		{Field: "status", Header: "status"},
+		{Field: "turma", Header: "turma"},
+		{Field: "organizacao", Header: "organizacao"},
+	}
+	mapper, err := NewEnrollmentRowMapper(columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transformed, err := mapper.Transformed([]config.Transform{
+		{Field: "aluno", Op: "trim"},
+		{Field: "aluno", Op: "title"},
+		{Field: "ra", Op: "digits"},
+		{Field: "ra", Op: "pad", Arg: "8"},
+		{Field: "curso", Op: "replace", Arg: " - >/"},
+		{Field: "status", Op: "map", Arg: "ATIVA>Ativa", Values: map[string]string{"CANCELADA": "Cancelada"}},
+		{Field: "turma", Op: "lower"},
+		{Field: "organizacao", Op: "upper"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []interface{}{"Maria Da Silva", "00012345", "Direito/Noturno", "Cancelada", "t1", ""}
+	if got := transformed.Row(enrollment); !slices.Equal(got, want) {
+		t.Errorf("Row() = %q, want %q", got, want)
+	}
+	if *enrollment.Aluno != "  MARIA DA SILVA " || *enrollment.RA != "12.34-5" {
+		t.Errorf("Row() rewrote the caller's enrollment: %q %q", *enrollment.Aluno, *enrollment.RA)
+	}
+	if got := mapper.Row(enrollment)[1]; got != "12.34-5" {
+		t.Errorf("untransformed mapper Row()[ra] = %q, want the raw value", got)
+	}
+}
+
+func TestTransformRunsBeforeRedaction(t *testing.T) {
+	mapper, err := NewEnrollmentRowMapper([]config.Column{{Field: "aluno", Header: "aluno"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapper, err = mapper.Transformed([]config.Transform{{Field: "aluno", Op: "map", Arg: "ANA>Maria Clara"}}); err != nil {
+		t.Fatal(err)
+	}
+	if mapper, err = mapper.Redacted(map[string]string{"aluno": config.RedactMask}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := mapper.Row(models.Enrollment{Aluno: ptr("ANA")})[0]; got != "M**** C****" {
+		t.Errorf("Row() = %q, want the transformed value masked", got)
+	}
+}
+
+func TestTransformedErrors(t *testing.T) {
+	mapper, err := NewEnrollmentRowMapper([]config.Column{{Field: "ra", Header: "ra"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rule config.Transform
+		want string
+	}{
+		{rule: config.Transform{Field: "nome", Op: "upper"}, want: "unknown enrollment field 'nome'"},
+		{rule: config.Transform{Field: "idOrg", Op: "upper"}, want: "'idOrg' is not a text field"},
+		{rule: config.Transform{Field: "dataMatricula", Op: "upper"}, want: "'dataMatricula' is not a text field"},
+		{rule: config.Transform{Field: "ra", Op: "reverse"}, want: "unknown transform op 'reverse'"},
+		{rule: config.Transform{Field: "ra", Op: "pad", Arg: "six"}, want: "invalid 'pad' transform for field 'ra'"},
+		{rule: config.Transform{Field: "ra", Op: "replace", Arg: "abc"}, want: "arg must be 'old>new'"},
+		{rule: config.Transform{Field: "status", Op: "map"}, want: "no values to map"},
+		{rule: config.Transform{Field: "status", Op: "map", Arg: "ATIVA"}, want: "invalid pair 'ATIVA'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule.Field+":"+tt.rule.Op, func(t *testing.T) {
+			_, err := mapper.Transformed([]config.Transform{tt.rule})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Transformed(%+v) = %v, want an error containing %q", tt.rule, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterTransform(t *testing.T) {
+	RegisterTransform("Reverse", simpleTransform(func(s string) string {
+		runes := []rune(s)
+		slices.Reverse(runes)
+		return string(runes)
+	}))
+	defer func() {
+		transformsMu.Lock()
+		delete(transformOps, "reverse")
+		transformsMu.Unlock()
+	}()
+
+	mapper, err := NewEnrollmentRowMapper([]config.Column{{Field: "ra", Header: "ra"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapper, err = mapper.Transformed([]config.Transform{{Field: "ra", Op: "reverse"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := mapper.Row(models.Enrollment{RA: ptr("123")})[0]; got != "321" {
+		t.Errorf("Row() = %q, want the registered op applied", got)
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/logging"
+	"github.com/SamuelLeutner/fetch-student-data/models"
+	"github.com/SamuelLeutner/fetch-student-data/utils"
+)
+
+var classHeaders = []string{"idTurma", "turma", "idCurso", "curso", "periodoLetivo", "turno", "idOrg", "organizacao", "vagas", "matriculas"}
+
+// ClassesResult reports a class sheet write. UnmatchedEnrollments counts
+// enrollments whose turma did not match any fetched class.
+type ClassesResult struct {
+	SheetResult
+	UnmatchedEnrollments int `json:"unmatchedEnrollments"`
+}
+
+// TurmasService writes the classes of a period with how many enrollments
+// each one has.
+type TurmasService struct {
+	client *JacadClient
+}
+
+func NewTurmasService(client *JacadClient) *TurmasService {
+	return &TurmasService{client: client}
+}
+
+// FetchClasses fetches the period's classes and enrollments, counts the
+// enrollments per class and overwrites the period's class sheet. Enrollments
+// only carry the class name, so they are joined on organization and name.
+func (s *TurmasService) FetchClasses(ctx context.Context, params *requests.FetchClassesRequest) (*ClassesResult, error) {
+	startedAt := time.Now()
+	result, err := s.fetchClasses(ctx, params)
+	var sheet *SheetResult
+	if result != nil {
+		sheet = &result.SheetResult
+	}
+	s.client.finishJob(ctx, sheetSummary("fetch-classes", params.Tenant, sheet, err), params, sheet.writtenSheets(err), nil, params.DryRun, startedAt, err)
+	return result, err
+}
+
+func (s *TurmasService) fetchClasses(ctx context.Context, params *requests.FetchClassesRequest) (*ClassesResult, error) {
+	c := s.client
+	logger := logging.FromContext(ctx).With("idPeriodoLetivo", params.IdPeriodoLetivo, "statusMatricula", params.StatusMatricula, "orgId", params.OrgId)
+	logger.Info("Starting class fetch")
+	startTime := time.Now()
+
+	if params.IdPeriodoLetivo == 0 {
+		return nil, fmt.Errorf("idPeriodoLetivo is required")
+	}
+	if params.BypassCache {
+		ctx = WithCacheBypass(ctx)
+	}
+	ctx = WithTenant(ctx, params.Tenant)
+
+	periodParams := map[string]string{"idPeriodoLetivo": strconv.Itoa(params.IdPeriodoLetivo)}
+	turmas, err := fetchAllPagesOf[models.Turma](ctx, c, c.Config.Endpoints["CLASSES"], periodParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch classes: %w", err)
+	}
+
+	enrollmentParams := enrollmentFetchParams(&requests.FetchEnrollmentsRequest{IdPeriodoLetivo: params.IdPeriodoLetivo, StatusMatricula: params.StatusMatricula})
+	counts := make(map[string]int)
+	count := func(data []models.Enrollment) error {
+		for _, item := range data {
+			counts[classKey(item.OrgID, item.Turma)]++
+		}
+		return nil
+	}
+	_, failed, _, err := c.fetchEnrollmentQueries(ctx, logger, enrollmentParams, startTime, false, false, newEnrollmentDeduper().wrap(count))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch enrollments for class counts: %w", err)
+	}
+	if failed.batches > 0 {
+		return nil, fmt.Errorf("failed to fetch %d batch(es) of enrollments; class counts would be incomplete", failed.batches)
+	}
+
+	result := &ClassesResult{SheetResult: SheetResult{
+		Sheet:  fmt.Sprintf("%s | Período ID %d", c.Config.ClassesSheet, params.IdPeriodoLetivo),
+		DryRun: params.DryRun,
+	}}
+
+	rows := make([][]interface{}, 0, len(turmas))
+	for _, turma := range turmas {
+		if params.OrgId != 0 && turma.OrgID != params.OrgId {
+			continue
+		}
+		key := classKey(turma.OrgID, turma.Descricao)
+		rows = append(rows, classRow(turma, counts[key]))
+		delete(counts, key)
+	}
+	for key, n := range counts {
+		if params.OrgId == 0 || strings.HasPrefix(key, strconv.Itoa(params.OrgId)+"|") {
+			result.UnmatchedEnrollments += n
+		}
+	}
+	if result.UnmatchedEnrollments > 0 {
+		logger.Warn("Some enrollments did not match any fetched class", "enrollments", result.UnmatchedEnrollments)
+	}
+	result.Rows = len(rows)
+
+	if params.DryRun {
+		limit := params.PreviewRows
+		if limit <= 0 {
+			limit = c.Config.DryRunPreviewRows
+		}
+		result.Preview = previewRows(classHeaders, rows[:min(limit, len(rows))])
+		return result, nil
+	}
+
+	if err := c.Writer.OverwriteSheetData(ctx, result.Sheet, classHeaders, rows); err != nil {
+		return result, fmt.Errorf("failed to write classes to sheet: %w", err)
+	}
+
+	logger.Info("Classes written", "sheet", result.Sheet, "rows", len(rows), "duration", time.Since(startTime).String())
+	return result, nil
+}
+
+func classKey(orgID int, name *string) string {
+	key := strconv.Itoa(orgID) + "|"
+	if name != nil {
+		key += strings.ToLower(strings.TrimSpace(*name))
+	}
+	return key
+}
+
+func classRow(turma models.Turma, enrollments int) []interface{} {
+	var vagas interface{} = ""
+	if turma.Vagas != nil {
+		vagas = *turma.Vagas
+	}
+	return []interface{}{
+		turma.IdTurma,
+		utils.GetStringOrEmpty(turma.Descricao),
+		turma.IdCurso,
+		utils.GetStringOrEmpty(turma.Curso),
+		utils.GetStringOrEmpty(turma.PeriodoLetivo),
+		utils.GetStringOrEmpty(turma.Turno),
+		turma.OrgID,
+		utils.GetStringOrEmpty(turma.Organizacao),
+		vagas,
+		enrollments,
+	}
+}
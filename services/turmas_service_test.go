@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	requests "github.com/SamuelLeutner/fetch-student-data/api/Requests"
+	"github.com/SamuelLeutner/fetch-student-data/config"
+	"github.com/SamuelLeutner/fetch-student-data/internal/jacadmock"
+)
+
+func TestFetchClasses(t *testing.T) {
+	server := jacadmock.NewServer(jacadmock.Options{Data: jacadmock.DemoDataset(720)})
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.PageSize = 50
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	writer := NewFakeSheetWriter()
+	client := NewJacadClient(&cfg, writer, NewFileSyncStateStore(filepath.Join(dir, "sync_state.json")), NewCheckpointStore(filepath.Join(dir, "checkpoints")), nil)
+
+	result, err := NewTurmasService(client).FetchClasses(context.Background(), &requests.FetchClassesRequest{IdPeriodoLetivo: 87, StatusMatricula: "ATIVA", OrgId: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := cfg.ClassesSheet + " | Período ID 87"; result.Sheet != want || result.Rows != 4 {
+		t.Errorf("result = %+v, want 4 rows in %q", result.SheetResult, want)
+	}
+	if result.UnmatchedEnrollments != 0 {
+		t.Errorf("UnmatchedEnrollments = %d, want 0", result.UnmatchedEnrollments)
+	}
+
+	// Period 87's ATIVA enrollments of organization 20 are split between
+	// T04 and T10.
+	sheet, ok := writer.Sheet(result.Sheet)
+	if !ok {
+		t.Fatalf("sheet %q not written", result.Sheet)
+	}
+	want := map[string]int{"T01": 0, "T04": 40, "T07": 0, "T10": 20}
+	for _, row := range sheet.Rows {
+		name, enrollments := row[1].(string), row[len(row)-1].(int)
+		if n, ok := want[name]; !ok || n != enrollments || row[6] != 20 {
+			t.Errorf("class %v of org %v has %d enrollments, want %d", name, row[6], enrollments, n)
+		}
+	}
+}
+
+func TestFetchClassesCountsUnmatchedEnrollments(t *testing.T) {
+	data := jacadmock.DemoDataset(720)
+	data.Classes = data.Classes[:1]
+	server := jacadmock.NewServer(jacadmock.Options{Data: data})
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.APIBase = server.URL
+	cfg.UserToken = server.UserToken
+	cfg.PageSize = 50
+	cfg.RetryDelay = 0
+	cfg.JacadRateLimitRPS = 0
+	writer := NewFakeSheetWriter()
+	client := NewJacadClient(&cfg, writer, nil, nil, nil)
+
+	result, err := NewTurmasService(client).FetchClasses(context.Background(), &requests.FetchClassesRequest{IdPeriodoLetivo: 87, StatusMatricula: "ATIVA", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only one class of period 86 is left, so none of the 180 enrollments
+	// of period 87 match.
+	if result.Rows != 0 || result.UnmatchedEnrollments != 180 {
+		t.Errorf("result = %d rows, %d unmatched enrollments; want 0, 180", result.Rows, result.UnmatchedEnrollments)
+	}
+	if calls := writer.Calls(); len(calls) != 0 {
+		t.Errorf("dry run made %d writer calls, want none", len(calls))
+	}
+}
+
+func TestFetchClassesRequiresPeriod(t *testing.T) {
+	cfg := config.Defaults()
+	client := NewJacadClient(&cfg, NewFakeSheetWriter(), nil, nil, nil)
+	if _, err := NewTurmasService(client).FetchClasses(context.Background(), &requests.FetchClassesRequest{}); err == nil {
+		t.Error("FetchClasses() without idPeriodoLetivo succeeded, want an error")
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxXLSXSheetNameLength is the Excel limit for worksheet names.
+const maxXLSXSheetNameLength = 31
+
+// excelEpoch is day zero of the 1900 date system for dates after March 1900.
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+const xlsxContentTypesHead = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// xlsxStyles defines three cell formats: 0 default, 1 date, 2 bold header.
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+const (
+	xlsxStyleDate   = 1
+	xlsxStyleHeader = 2
+)
+
+// WriteXLSX encodes sheets as an Excel workbook with one worksheet per sheet,
+// a bold frozen header row and an autofilter over the data.
+func WriteXLSX(w io.Writer, sheets []ExportSheet) error {
+	zw := zip.NewWriter(w)
+	names := xlsxSheetNames(sheets)
+
+	var contentTypes, workbook, workbookRels strings.Builder
+	contentTypes.WriteString(xlsxContentTypesHead)
+	workbook.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	workbookRels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+
+	var definedNames strings.Builder
+	for i, sheet := range sheets {
+		n := i + 1
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		fmt.Fprintf(&workbook, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(names[i]), n, n)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+		if len(sheet.Headers) > 0 {
+			fmt.Fprintf(&definedNames, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">'%s'!%s</definedName>`,
+				i, xmlEscape(strings.ReplaceAll(names[i], "'", "''")), xlsxAbsoluteRange(len(sheet.Headers), len(sheet.Rows)+1))
+		}
+	}
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets>`)
+	if definedNames.Len() > 0 {
+		workbook.WriteString(`<definedNames>` + definedNames.String() + `</definedNames>`)
+	}
+	workbook.WriteString(`</workbook>`)
+	fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+	workbookRels.WriteString(`</Relationships>`)
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", workbookRels.String()},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return fmt.Errorf("failed to create xlsx part '%s': %w", part.name, err)
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return fmt.Errorf("failed to write xlsx part '%s': %w", part.name, err)
+		}
+	}
+
+	for i, sheet := range sheets {
+		name := fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
+		f, err := zw.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to create xlsx part '%s': %w", name, err)
+		}
+		if err := writeXLSXWorksheet(f, sheet); err != nil {
+			return fmt.Errorf("failed to write worksheet '%s': %w", names[i], err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish xlsx archive: %w", err)
+	}
+	return nil
+}
+
+func writeXLSXWorksheet(w io.Writer, sheet ExportSheet) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(sheet.Headers) > 0 {
+		bw.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	bw.WriteString(`<sheetData>`)
+
+	if len(sheet.Headers) > 0 {
+		bw.WriteString(`<row r="1">`)
+		for col, header := range sheet.Headers {
+			writeXLSXCell(bw, xlsxCellRef(col, 1), header, xlsxStyleHeader)
+		}
+		bw.WriteString(`</row>`)
+	}
+	for i, row := range sheet.Rows {
+		rowNum := i + 2
+		fmt.Fprintf(bw, `<row r="%d">`, rowNum)
+		for col, value := range row {
+			writeXLSXCell(bw, xlsxCellRef(col, rowNum), value, 0)
+		}
+		bw.WriteString(`</row>`)
+	}
+
+	bw.WriteString(`</sheetData>`)
+	if len(sheet.Headers) > 0 {
+		fmt.Fprintf(bw, `<autoFilter ref="%s:%s"/>`, xlsxCellRef(0, 1), xlsxCellRef(len(sheet.Headers)-1, len(sheet.Rows)+1))
+	}
+	bw.WriteString(`</worksheet>`)
+	return bw.Flush()
+}
+
+func writeXLSXCell(w *bufio.Writer, ref string, value interface{}, style int) {
+	styleAttr := ""
+	if style != 0 {
+		styleAttr = fmt.Sprintf(` s="%d"`, style)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return
+	case string:
+		if v == "" {
+			return
+		}
+		fmt.Fprintf(w, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, styleAttr, xmlEscape(v))
+	case bool:
+		b := 0
+		if v {
+			b = 1
+		}
+		fmt.Fprintf(w, `<c r="%s"%s t="b"><v>%d</v></c>`, ref, styleAttr, b)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		fmt.Fprintf(w, `<c r="%s"%s><v>%d</v></c>`, ref, styleAttr, v)
+	case float32:
+		fmt.Fprintf(w, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr, strconv.FormatFloat(float64(v), 'f', -1, 32))
+	case float64:
+		fmt.Fprintf(w, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr, strconv.FormatFloat(v, 'f', -1, 64))
+	case time.Time:
+		if v.IsZero() {
+			return
+		}
+		day := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)
+		fmt.Fprintf(w, `<c r="%s" s="%d"><v>%d</v></c>`, ref, xlsxStyleDate, int(day.Sub(excelEpoch).Hours()/24))
+	case SerialDate:
+		fmt.Fprintf(w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleDate, strconv.FormatFloat(float64(v), 'f', -1, 64))
+	default:
+		writeXLSXCell(w, ref, fmt.Sprint(v), style)
+	}
+}
+
+// xlsxSheetNames makes sheet names valid and unique for Excel: no []:*?/\
+// characters, at most 31 characters and case-insensitively distinct.
+func xlsxSheetNames(sheets []ExportSheet) []string {
+	names := make([]string, len(sheets))
+	seen := make(map[string]bool, len(sheets))
+	for i, sheet := range sheets {
+		base := strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`[]:*?/\`, r) {
+				return '_'
+			}
+			return r
+		}, strings.TrimSpace(sheet.Name))
+		base = strings.Trim(base, "'")
+		if base == "" {
+			base = fmt.Sprintf("Sheet%d", i+1)
+		}
+
+		name := truncateRunes(base, maxXLSXSheetNameLength)
+		for n := 2; seen[strings.ToLower(name)]; n++ {
+			suffix := fmt.Sprintf(" (%d)", n)
+			name = truncateRunes(base, maxXLSXSheetNameLength-len(suffix)) + suffix
+		}
+		seen[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
+
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+// xlsxCellRef converts a zero-based column and one-based row into an A1 reference.
+func xlsxCellRef(col, row int) string {
+	return xlsxColumnName(col) + strconv.Itoa(row)
+}
+
+func xlsxAbsoluteRange(cols, rows int) string {
+	return fmt.Sprintf("$A$1:$%s$%d", xlsxColumnName(cols-1), rows)
+}
+
+func xlsxColumnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestXLSXColumnName(t *testing.T) {
+	tests := []struct {
+		col  int
+		want string
+	}{
+		{0, "A"}, {25, "Z"}, {26, "AA"}, {51, "AZ"}, {52, "BA"}, {701, "ZZ"}, {702, "AAA"},
+	}
+	for _, tt := range tests {
+		if got := xlsxColumnName(tt.col); got != tt.want {
+			t.Errorf("xlsxColumnName(%d) = %q, want %q", tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestXLSXSheetNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		sheets []string
+		want   []string
+	}{
+		{name: "invalid characters", sheets: []string{"Matrículas [EAD] 2025/1"}, want: []string{"Matrículas _EAD_ 2025_1"}},
+		{name: "blank names", sheets: []string{" ", "''"}, want: []string{"Sheet1", "Sheet2"}},
+		{name: "truncated to 31 characters", sheets: []string{strings.Repeat("x", 40)}, want: []string{strings.Repeat("x", 31)}},
+		{name: "case-insensitive duplicates", sheets: []string{"Cursos", "CURSOS", "cursos"}, want: []string{"Cursos", "CURSOS (2)", "cursos (3)"}},
+		{name: "duplicates of long names stay within the limit", sheets: []string{strings.Repeat("y", 40), strings.Repeat("y", 35)}, want: []string{strings.Repeat("y", 31), strings.Repeat("y", 27) + " (2)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheets := make([]ExportSheet, len(tt.sheets))
+			for i, name := range tt.sheets {
+				sheets[i] = ExportSheet{Name: name}
+			}
+			got := xlsxSheetNames(sheets)
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("name %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteXLSXCell(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "nil", value: nil, want: ""},
+		{name: "empty string", value: "", want: ""},
+		{name: "escaped string", value: "A & <B>", want: `<c r="A1" t="inlineStr"><is><t xml:space="preserve">A &amp; &lt;B&gt;</t></is></c>`},
+		{name: "int", value: 42, want: `<c r="A1"><v>42</v></c>`},
+		{name: "float", value: 1.5, want: `<c r="A1"><v>1.5</v></c>`},
+		{name: "bool", value: true, want: `<c r="A1" t="b"><v>1</v></c>`},
+		{name: "date", value: time.Date(2025, time.March, 1, 15, 30, 0, 0, time.UTC), want: `<c r="A1" s="1"><v>45717</v></c>`},
+		{name: "zero date", value: time.Time{}, want: ""},
+		{name: "other types as text", value: []int{1}, want: `<c r="A1" t="inlineStr"><is><t xml:space="preserve">[1]</t></is></c>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeXLSXCell(w, "A1", tt.value, 0)
+			w.Flush()
+			if got := buf.String(); got != tt.want {
+				t.Errorf("writeXLSXCell(%v) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	sheets := []ExportSheet{
+		{Name: "EAD", Headers: []string{"aluno", "idOrg"}, Rows: [][]interface{}{{"Ana", 20}, {"Bruno", 20}}},
+		{Name: "Vazia"},
+	}
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, sheets); err != nil {
+		t.Fatalf("WriteXLSX() = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("output is not a zip archive: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if err := checkWellFormed(data); err != nil {
+			t.Errorf("%s is not well-formed XML: %v", f.Name, err)
+		}
+		parts[f.Name] = string(data)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+	if sheet := parts["xl/worksheets/sheet1.xml"]; !strings.Contains(sheet, `<autoFilter ref="A1:B3"/>`) || !strings.Contains(sheet, `state="frozen"`) {
+		t.Errorf("sheet1 lacks the autofilter or frozen header: %s", sheet)
+	}
+	if workbook := parts["xl/workbook.xml"]; !strings.Contains(workbook, `'EAD'!$A$1:$B$3`) {
+		t.Errorf("workbook lacks the filter database name: %s", workbook)
+	}
+	if sheet := parts["xl/worksheets/sheet2.xml"]; strings.Contains(sheet, "autoFilter") {
+		t.Errorf("sheet without headers has an autofilter: %s", sheet)
+	}
+}
+
+func checkWellFormed(data []byte) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans sends the spans of the test to the returned recorder.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestStartAndEnd(t *testing.T) {
+	recorder := recordSpans(t)
+
+	ctx, parent := Start(context.Background(), "fetch", attribute.String("job", "fetch-enrollments"))
+	_, child := Start(ctx, "jacad GET ENROLLMENTS")
+	End(child, errors.New("HTTP 503"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	childSpan, parentSpan := spans[0], spans[1]
+	if childSpan.Parent().SpanID() != parentSpan.SpanContext().SpanID() || childSpan.SpanContext().TraceID() != parentSpan.SpanContext().TraceID() {
+		t.Error("child span is not part of its parent's trace")
+	}
+	if childSpan.Status().Code != codes.Error || childSpan.Status().Description != "HTTP 503" || len(childSpan.Events()) != 1 || childSpan.Events()[0].Name != "exception" {
+		t.Errorf("failed span status = %+v with events %v, want the error recorded", childSpan.Status(), childSpan.Events())
+	}
+	if parentSpan.Status().Code != codes.Unset {
+		t.Errorf("successful span status = %+v, want unset", parentSpan.Status())
+	}
+	if attrs := parentSpan.Attributes(); len(attrs) != 1 || attrs[0] != attribute.String("job", "fetch-enrollments") {
+		t.Errorf("span attributes = %v", attrs)
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	recordSpans(t)
+	ctx, span := Start(context.Background(), "outgoing")
+	defer span.End()
+
+	header := http.Header{}
+	Inject(ctx, propagation.HeaderCarrier(header))
+	if header.Get("traceparent") == "" {
+		t.Fatal("Inject() set no traceparent header")
+	}
+	_, remote := Start(Extract(context.Background(), propagation.HeaderCarrier(header)), "incoming")
+	defer remote.End()
+	if remote.SpanContext().TraceID() != span.SpanContext().TraceID() {
+		t.Error("span started from extracted headers is not part of the injected trace")
+	}
+}
+
+func TestShutdownWithoutInit(t *testing.T) {
+	if err := Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() without Init = %v, want nil", err)
+	}
+}